@@ -0,0 +1,382 @@
+// Control and event stream API of the gift buyer.
+//
+// The service mirrors the local HTTP control API (pause, resume, pause-buying,
+// reload, stop) and adds a server stream of gift and purchase events. Every call
+// must carry the control token in the "authorization: Bearer <token>" or
+// "x-control-token" metadata.
+//
+// The Go code in controlpb is generated with protoc-gen-go and protoc-gen-go-grpc:
+//
+//	protoc --go_out=. --go_opt=module=gift-buyer \
+//	  --go-grpc_out=. --go-grpc_opt=module=gift-buyer \
+//	  api/proto/control.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: api/proto/control.proto
+
+package controlpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Event_Type int32
+
+const (
+	Event_TYPE_UNSPECIFIED Event_Type = 0
+	// A new eligible gift was found.
+	Event_TYPE_GIFT_FOUND Event_Type = 1
+	// A purchase attempt finished, see success and error.
+	Event_TYPE_PURCHASE_RESULT Event_Type = 2
+)
+
+// Enum value maps for Event_Type.
+var (
+	Event_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "TYPE_GIFT_FOUND",
+		2: "TYPE_PURCHASE_RESULT",
+	}
+	Event_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED":     0,
+		"TYPE_GIFT_FOUND":      1,
+		"TYPE_PURCHASE_RESULT": 2,
+	}
+)
+
+func (x Event_Type) Enum() *Event_Type {
+	p := new(Event_Type)
+	*p = x
+	return p
+}
+
+func (x Event_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Event_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_control_proto_enumTypes[0].Descriptor()
+}
+
+func (Event_Type) Type() protoreflect.EnumType {
+	return &file_api_proto_control_proto_enumTypes[0]
+}
+
+func (x Event_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Event_Type.Descriptor instead.
+func (Event_Type) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_control_proto_rawDescGZIP(), []int{3, 0}
+}
+
+type ControlRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlRequest) Reset() {
+	*x = ControlRequest{}
+	mi := &file_api_proto_control_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlRequest) ProtoMessage() {}
+
+func (x *ControlRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_control_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlRequest.ProtoReflect.Descriptor instead.
+func (*ControlRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_control_proto_rawDescGZIP(), []int{0}
+}
+
+type ControlResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlResponse) Reset() {
+	*x = ControlResponse{}
+	mi := &file_api_proto_control_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlResponse) ProtoMessage() {}
+
+func (x *ControlResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_control_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlResponse.ProtoReflect.Descriptor instead.
+func (*ControlResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_control_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ControlResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type EventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventsRequest) Reset() {
+	*x = EventsRequest{}
+	mi := &file_api_proto_control_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventsRequest) ProtoMessage() {}
+
+func (x *EventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_control_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventsRequest.ProtoReflect.Descriptor instead.
+func (*EventsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_control_proto_rawDescGZIP(), []int{2}
+}
+
+type Event struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Type    Event_Type             `protobuf:"varint,1,opt,name=type,proto3,enum=giftbuyer.control.v1.Event_Type" json:"type,omitempty"`
+	GiftId  int64                  `protobuf:"varint,2,opt,name=gift_id,json=giftId,proto3" json:"gift_id,omitempty"`
+	Stars   int64                  `protobuf:"varint,3,opt,name=stars,proto3" json:"stars,omitempty"`
+	Success bool                   `protobuf:"varint,4,opt,name=success,proto3" json:"success,omitempty"`
+	Error   string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	// Unix time in milliseconds.
+	Timestamp     int64 `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_api_proto_control_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_control_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_api_proto_control_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Event) GetType() Event_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Event_TYPE_UNSPECIFIED
+}
+
+func (x *Event) GetGiftId() int64 {
+	if x != nil {
+		return x.GiftId
+	}
+	return 0
+}
+
+func (x *Event) GetStars() int64 {
+	if x != nil {
+		return x.Stars
+	}
+	return 0
+}
+
+func (x *Event) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *Event) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Event) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+var File_api_proto_control_proto protoreflect.FileDescriptor
+
+const file_api_proto_control_proto_rawDesc = "" +
+	"\n" +
+	"\x17api/proto/control.proto\x12\x14giftbuyer.control.v1\"\x10\n" +
+	"\x0eControlRequest\")\n" +
+	"\x0fControlResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\"\x0f\n" +
+	"\rEventsRequest\"\x87\x02\n" +
+	"\x05Event\x124\n" +
+	"\x04type\x18\x01 \x01(\x0e2 .giftbuyer.control.v1.Event.TypeR\x04type\x12\x17\n" +
+	"\agift_id\x18\x02 \x01(\x03R\x06giftId\x12\x14\n" +
+	"\x05stars\x18\x03 \x01(\x03R\x05stars\x12\x18\n" +
+	"\asuccess\x18\x04 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x1c\n" +
+	"\ttimestamp\x18\x06 \x01(\x03R\ttimestamp\"K\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fTYPE_GIFT_FOUND\x10\x01\x12\x18\n" +
+	"\x14TYPE_PURCHASE_RESULT\x10\x022\x8c\x04\n" +
+	"\aControl\x12T\n" +
+	"\x05Pause\x12$.giftbuyer.control.v1.ControlRequest\x1a%.giftbuyer.control.v1.ControlResponse\x12U\n" +
+	"\x06Resume\x12$.giftbuyer.control.v1.ControlRequest\x1a%.giftbuyer.control.v1.ControlResponse\x12Z\n" +
+	"\vPauseBuying\x12$.giftbuyer.control.v1.ControlRequest\x1a%.giftbuyer.control.v1.ControlResponse\x12U\n" +
+	"\x06Reload\x12$.giftbuyer.control.v1.ControlRequest\x1a%.giftbuyer.control.v1.ControlResponse\x12S\n" +
+	"\x04Stop\x12$.giftbuyer.control.v1.ControlRequest\x1a%.giftbuyer.control.v1.ControlResponse\x12L\n" +
+	"\x06Events\x12#.giftbuyer.control.v1.EventsRequest\x1a\x1b.giftbuyer.control.v1.Event0\x01B Z\x1egift-buyer/api/proto/controlpbb\x06proto3"
+
+var (
+	file_api_proto_control_proto_rawDescOnce sync.Once
+	file_api_proto_control_proto_rawDescData []byte
+)
+
+func file_api_proto_control_proto_rawDescGZIP() []byte {
+	file_api_proto_control_proto_rawDescOnce.Do(func() {
+		file_api_proto_control_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_proto_control_proto_rawDesc), len(file_api_proto_control_proto_rawDesc)))
+	})
+	return file_api_proto_control_proto_rawDescData
+}
+
+var file_api_proto_control_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_proto_control_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_api_proto_control_proto_goTypes = []any{
+	(Event_Type)(0),         // 0: giftbuyer.control.v1.Event.Type
+	(*ControlRequest)(nil),  // 1: giftbuyer.control.v1.ControlRequest
+	(*ControlResponse)(nil), // 2: giftbuyer.control.v1.ControlResponse
+	(*EventsRequest)(nil),   // 3: giftbuyer.control.v1.EventsRequest
+	(*Event)(nil),           // 4: giftbuyer.control.v1.Event
+}
+var file_api_proto_control_proto_depIdxs = []int32{
+	0, // 0: giftbuyer.control.v1.Event.type:type_name -> giftbuyer.control.v1.Event.Type
+	1, // 1: giftbuyer.control.v1.Control.Pause:input_type -> giftbuyer.control.v1.ControlRequest
+	1, // 2: giftbuyer.control.v1.Control.Resume:input_type -> giftbuyer.control.v1.ControlRequest
+	1, // 3: giftbuyer.control.v1.Control.PauseBuying:input_type -> giftbuyer.control.v1.ControlRequest
+	1, // 4: giftbuyer.control.v1.Control.Reload:input_type -> giftbuyer.control.v1.ControlRequest
+	1, // 5: giftbuyer.control.v1.Control.Stop:input_type -> giftbuyer.control.v1.ControlRequest
+	3, // 6: giftbuyer.control.v1.Control.Events:input_type -> giftbuyer.control.v1.EventsRequest
+	2, // 7: giftbuyer.control.v1.Control.Pause:output_type -> giftbuyer.control.v1.ControlResponse
+	2, // 8: giftbuyer.control.v1.Control.Resume:output_type -> giftbuyer.control.v1.ControlResponse
+	2, // 9: giftbuyer.control.v1.Control.PauseBuying:output_type -> giftbuyer.control.v1.ControlResponse
+	2, // 10: giftbuyer.control.v1.Control.Reload:output_type -> giftbuyer.control.v1.ControlResponse
+	2, // 11: giftbuyer.control.v1.Control.Stop:output_type -> giftbuyer.control.v1.ControlResponse
+	4, // 12: giftbuyer.control.v1.Control.Events:output_type -> giftbuyer.control.v1.Event
+	7, // [7:13] is the sub-list for method output_type
+	1, // [1:7] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_control_proto_init() }
+func file_api_proto_control_proto_init() {
+	if File_api_proto_control_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_control_proto_rawDesc), len(file_api_proto_control_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_proto_control_proto_goTypes,
+		DependencyIndexes: file_api_proto_control_proto_depIdxs,
+		EnumInfos:         file_api_proto_control_proto_enumTypes,
+		MessageInfos:      file_api_proto_control_proto_msgTypes,
+	}.Build()
+	File_api_proto_control_proto = out.File
+	file_api_proto_control_proto_goTypes = nil
+	file_api_proto_control_proto_depIdxs = nil
+}
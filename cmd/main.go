@@ -9,22 +9,44 @@
 //
 //	go run cmd/main.go
 //
-// Configuration is loaded from internal/config/config.json file.
+// Configuration is loaded from internal/config/config.json file, or from config.yaml /
+// config.yml in the same directory when there is no JSON config.
 package main
 
 import (
 	"context"
+	"fmt"
 	"gift-buyer/internal/config"
 	"gift-buyer/internal/usecase"
 	"gift-buyer/pkg/logger"
+	"gift-buyer/pkg/metrics"
+	"net"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 )
 
+// configNames are the configuration file names looked up in order of preference.
+var configNames = []string{"config.json", "config.yaml", "config.yml"}
+
+// findConfig returns the first existing configuration file in dir, falling back to
+// config.json so that the load error names the default file.
+func findConfig(dir string) string {
+	for _, name := range configNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, configNames[0])
+}
+
 // main is the entry point of the Gift Buyer application.
 // It initializes the logger, loads configuration, creates the gift service,
 // and handles graceful shutdown on system signals.
@@ -33,7 +55,7 @@ func main() {
 
 	_, b, _, _ := runtime.Caller(0)
 	basepath := filepath.Dir(b)
-	configPath := filepath.Join(basepath, "..", "internal", "config", "config.json")
+	configPath := findConfig(filepath.Join(basepath, "..", "internal", "config"))
 
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
@@ -43,7 +65,12 @@ func main() {
 	logLevel := logger.ParseLevel(cfg.LoggerLevel)
 	logger.Init(logLevel)
 
-	service, err := usecase.NewFactory(&cfg.SoftConfig).CreateSystem()
+	factory := usecase.NewFactory(&cfg.SoftConfig)
+	factory.SetConfigPath(configPath)
+	// A termination signal while waiting for an initialization retry aborts the startup
+	initCtx, stopInit := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	service, err := factory.CreateSystem(initCtx)
+	stopInit()
 	if err != nil {
 		logger.GlobalLogger.Fatalf("Failed to init telegram client: %v", err)
 	}
@@ -62,23 +89,81 @@ func main() {
 		service.CheckForUpdates()
 	}()
 
+	// serversCtx stops the config watcher and the metrics server on shutdown
+	serversCtx, cancelServers := context.WithCancel(context.Background())
+	var servers sync.WaitGroup
+	stopServers := func() {
+		cancelServers()
+		servers.Wait()
+	}
+
+	if cfg.SoftConfig.ConfigWatchInterval > 0 {
+		watcher := config.NewWatcher(configPath, time.Duration(cfg.SoftConfig.ConfigWatchInterval*1000)*time.Millisecond)
+		go watcher.Run(serversCtx)
+		go func() {
+			logger.GlobalLogger.Infof("Watching %s for changes", configPath)
+			for reloaded := range watcher.Updates() {
+				if err := service.Reload(&reloaded.SoftConfig); err != nil {
+					logger.GlobalLogger.Errorf("Failed to apply reloaded config: %v", err)
+				}
+			}
+		}()
+	}
+
+	if cfg.SoftConfig.MetricsPort > 0 {
+		servers.Add(1)
+		go func() {
+			defer servers.Done()
+			host := cfg.SoftConfig.MetricsHost
+			if host == "" {
+				host = "127.0.0.1"
+			}
+			addr := net.JoinHostPort(host, strconv.Itoa(cfg.SoftConfig.MetricsPort))
+			logger.GlobalLogger.Infof("Serving metrics on %s/metrics", addr)
+			if err := metrics.Serve(serversCtx, addr); err != nil {
+				logger.GlobalLogger.Errorf("Metrics server error: %v", err)
+			}
+		}()
+	}
+
 	logger.GlobalLogger.Info("Gift buyer service started. Press Ctrl+C to stop.")
-	gracefulShutdown(service)
+	if restart := gracefulShutdown(service, stopServers); restart {
+		if err := restartProcess(); err != nil {
+			logger.GlobalLogger.Errorf("Failed to restart after update: %v", err)
+		}
+	}
 	logger.GlobalLogger.Info("Application terminated")
 }
 
 // gracefulShutdown handles the graceful shutdown of the gift service.
-// It listens for SIGINT and SIGTERM signals and provides a 30-second timeout
-// for the service to stop gracefully before forcing termination.
+// It listens for SIGINT and SIGTERM signals, a stop request from the control or gRPC API,
+// a restart after an installed update or the service stopping on its own after an
+// unrecoverable error, and provides a 30-second timeout for the service to stop
+// gracefully before forcing termination. The metrics server is stopped after the service,
+// so that a restarted process can bind its port again.
 //
 // Parameters:
 //   - service: The GiftService instance to be stopped gracefully
-func gracefulShutdown(service usecase.UseCase) {
+//   - stopServers: closes the listener of the metrics server and waits for it
+//
+// Returns:
+//   - bool: true when the service stopped gracefully to restart with an installed update
+func gracefulShutdown(service usecase.UseCase, stopServers func()) bool {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	<-sigChan
-	logger.GlobalLogger.Info("Received shutdown signal, stopping service...")
+	restart := false
+	select {
+	case <-sigChan:
+		logger.GlobalLogger.Info("Received shutdown signal, stopping service...")
+	case <-service.StopRequested():
+		logger.GlobalLogger.Info("Received stop request from control or gRPC API, stopping service...")
+	case <-service.RestartRequested():
+		logger.GlobalLogger.Info("Update installed, stopping service to restart...")
+		restart = true
+	case <-service.Done():
+		logger.GlobalLogger.Error("Service stopped after an unrecoverable error, shutting down...")
+	}
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
@@ -93,6 +178,31 @@ func gracefulShutdown(service usecase.UseCase) {
 	case <-done:
 		logger.GlobalLogger.Info("Service stopped gracefully")
 	case <-shutdownCtx.Done():
+		// The service may still hold the session file, a restarted process could not use it
 		logger.GlobalLogger.Warn("Shutdown timeout exceeded, forcing exit")
+		if restart {
+			logger.GlobalLogger.Warn("Skipping restart, start the application again to run the update")
+			restart = false
+		}
+	}
+
+	stopServers()
+	return restart
+}
+
+// restartProcess starts the updated executable with the same arguments. The new process
+// inherits the standard streams and the working directory of the current one. It must
+// only be called once the service and the metrics server are stopped, since the new
+// process opens the same session file and binds the same ports.
+func restartProcess() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
 	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Start()
 }
@@ -3,6 +3,12 @@
 // for Telegram settings, gift criteria, and operational parameters.
 package config
 
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
 // AppConfig represents the main application configuration structure.
 // It contains logger settings and software-specific configuration.
 type AppConfig struct {
@@ -28,24 +34,68 @@ type SoftConfig struct {
 	// ApiLink is the link to the API
 	ApiLink string `json:"api_link"`
 
+	// AutoUpdate downloads the binary of a new release, verifies its SHA256, replaces the
+	// running executable and restarts. The release must provide the asset
+	// "<repo_name>-<os>-<arch>" (".exe" on Windows) with a GitHub digest or a ".sha256" file
+	AutoUpdate bool `json:"auto_update"`
+
 	// TgSettings contains Telegram API and bot configuration
 	TgSettings TgSettings `json:"tg_settings"`
 
+	// Accounts are additional Telegram accounts buying in parallel with the TgSettings account.
+	// Every account needs its own phone and session file, unset app_id, api_hash, datacenter,
+	// proxy, session passphrase and reconnect settings are taken from TgSettings. Every account
+	// reconnects on its own and /healthz reports the account with the oldest API check. Gift units are spread across all accounts round-robin
+	Accounts []TgSettings `json:"accounts"`
+
 	// Criterias defines the list of criteria for gift validation
 	Criterias []Criterias `json:"criterias"`
 
+	// CriteriaDefaults holds criteria fields, e.g. receiver_type, hide or count, applied to
+	// every criteria that omits them, so each criteria only lists what differs. It is
+	// merged into Criterias by LoadConfig
+	CriteriaDefaults map[string]json.RawMessage `json:"criteria_defaults"`
+
 	// Receiver specifies the target recipient for purchased gifts
 	Receiver ReceiverParams `json:"receiver"`
 
 	// Ticker is the monitoring interval in seconds
 	Ticker float64 `json:"ticker"`
 
+	// MaxMonitorBackoff is the maximum interval in seconds between gift checks while they keep
+	// failing. The interval doubles with every failed check and resets after a successful one.
+	// Default is 30 seconds when not set, a negative value disables the backoff
+	MaxMonitorBackoff float64 `json:"max_monitor_backoff"`
+
+	// GiftFetchTimeout is the timeout in seconds of a single gift catalog request made by
+	// the monitor. A timed out request counts as a failed check and triggers the backoff.
+	// Default is 10 seconds when not set
+	GiftFetchTimeout float64 `json:"gift_fetch_timeout"`
+
+	// InitialCheckBurst runs a burst of rapid checks right at startup before the
+	// regular Ticker cadence takes over (disabled when Count is 0)
+	InitialCheckBurst InitialCheckBurst `json:"initial_check_burst"`
+
 	// RetryCount is the number of retries for failed purchases
 	RetryCount int `json:"retry_count"`
 
 	// RetryDelay is the delay between retries in seconds
 	RetryDelay float64 `json:"retry_delay"`
 
+	// BatchResolveReceivers resolves numeric user receiver IDs at startup with grouped
+	// users.getUsers calls instead of one call per receiver. Startup fails listing every
+	// receiver that can't be resolved
+	BatchResolveReceivers bool `json:"batch_resolve_receivers"`
+
+	// WarmGiftIDs lists the IDs of gifts expected to drop. Their purchase path is prepared
+	// at startup: the receivers are resolved in advance and the invoices are built from
+	// cached templates, so the purchase starts with minimal setup latency
+	WarmGiftIDs []int64 `json:"warm_gift_ids"`
+
+	// InitRetries is the number of times the whole system initialization is retried with
+	// backoff after a startup failure such as a brief network outage (0 disables retries)
+	InitRetries int `json:"init_retries"`
+
 	// MaxBuyCount is the maximum number of gifts that can be purchased
 	MaxBuyCount int64 `json:"max_buy_count"`
 
@@ -61,14 +111,258 @@ type SoftConfig struct {
 	// RPCRateLimit is the rate limit for RPC requests
 	RPCRateLimit int `json:"rpc_rate_limit"`
 
+	// RPCRateLimits overrides RPCRateLimit for single Telegram methods, each limited by its
+	// own bucket: "payment_form", "send_payment", "get_gifts" and "default" for other calls.
+	// Methods not listed use RPCRateLimit; 0 disables the limit of a method
+	RPCRateLimits map[string]int `json:"rpc_rate_limits"`
+
 	// LogFlag controls whether logs should be written to both file and console.
 	// When true: logs are written to both log files (info_logs.jsonl, error_logs.jsonl) AND displayed in console
 	// When false: logs are written ONLY to log files, console output is disabled
 	// This flag is useful for production environments where console output should be minimized
 	LogFlag bool `json:"log_flag"`
 
+	// MaxLogSizeMB is the size in megabytes after which a log file is rotated:
+	// it is renamed with a timestamp suffix and a fresh file is started (0 disables rotation)
+	MaxLogSizeMB float64 `json:"max_log_size_mb"`
+
+	// MaxLogBackups is the number of rotated log files kept per log level (0 keeps all of them)
+	MaxLogBackups int `json:"max_log_backups"`
+
 	// Prioritization disables prioritization between users and channels
 	Prioritization bool `json:"prioritization"`
+
+	// RotateReceivers spreads the units of every gift batch evenly across the configured
+	// receivers (round-robin) instead of choosing a random receiver for each purchase
+	RotateReceivers bool `json:"rotate_receivers"`
+
+	// MaxCachedGifts limits the number of gifts kept in the in-memory cache, 0 means unlimited.
+	// Least recently seen gifts are evicted first
+	MaxCachedGifts int `json:"max_cached_gifts"`
+
+	// CacheEvictionWindow is how long in seconds an evicted gift is still treated as
+	// already seen, preventing a repeated purchase after eviction (default 3600)
+	CacheEvictionWindow float64 `json:"cache_eviction_window"`
+
+	// CacheTTL is how long in seconds a gift that is no longer listed stays cached and in
+	// cache.json. Listed gifts are seen every cycle and never expire (0 keeps gifts forever)
+	CacheTTL float64 `json:"cache_ttl"`
+
+	// CacheBackend is the storage of the gift cache: "file" (default) keeps gifts in cache.json,
+	// "sqlite" stores full gifts in cache.db. MaxCachedGifts and CacheTTL apply to "file" only
+	CacheBackend string `json:"cache_backend"`
+
+	// StartupSnapshotNotification sends a summary of available and matching gifts on startup
+	StartupSnapshotNotification bool `json:"startup_snapshot_notification"`
+
+	// VerifyPurchase confirms every purchase by polling the receiver's saved gifts after payment
+	// until the bought gift shows up. An unconfirmed purchase is reported as failed but never
+	// bought again, since its payment already went through
+	VerifyPurchase bool `json:"verify_purchase"`
+
+	// VerifyPurchaseTimeout is how long in seconds a purchase is polled for confirmation (default 10)
+	VerifyPurchaseTimeout float64 `json:"verify_purchase_timeout"`
+
+	// PurchaseSchedule staggers purchases over time instead of buying all eligible gifts at once
+	PurchaseSchedule PurchaseSchedule `json:"purchase_schedule"`
+
+	// BalanceReserve is the stars balance that is never spent: purchases stop when buying
+	// a gift would bring the balance below it (0 for no reserve)
+	BalanceReserve int64 `json:"balance_reserve"`
+
+	// CheckBalanceBeforeBuy refreshes the stars balance at the start of every batch and
+	// skips purchases it can't cover instead of sending payment requests that would fail
+	CheckBalanceBeforeBuy bool `json:"check_balance_before_buy"`
+
+	// CircuitBreakerThreshold is the number of payment failures in a row after which purchase
+	// attempts are paused for CircuitBreakerCooldown (0 to disable)
+	CircuitBreakerThreshold int `json:"circuit_breaker_threshold"`
+
+	// CircuitBreakerCooldown is how long in seconds purchases stay paused before a single
+	// trial purchase tests whether payments work again (default 60)
+	CircuitBreakerCooldown float64 `json:"circuit_breaker_cooldown"`
+
+	// SessionStateFile is the path of the file persisting claimed, bought and notified gifts
+	// of the current session, so a restart during a drop doesn't re-notify or re-buy (empty to disable)
+	SessionStateFile string `json:"session_state_file"`
+
+	// SessionStateTTL is how long in seconds after its last change the session state is still
+	// resumed. An older state belongs to an earlier drop and is discarded (default 3600)
+	SessionStateTTL float64 `json:"session_state_ttl"`
+
+	// SessionReportFile is the JSON report written at shutdown with the stats of the
+	// session: gifts seen, bought per gift, failures and stars spent (empty to disable)
+	SessionReportFile string `json:"session_report_file"`
+
+	// MaxConcurrentBatches limits how many discovery batches are bought at the same time.
+	// Batches over the limit are queued, 1 buys batches strictly one by one (0 for unlimited)
+	MaxConcurrentBatches int `json:"max_concurrent_batches"`
+
+	// BatchTimeout is the maximum wall-clock time of a purchase batch in seconds. When it
+	// elapses the remaining attempts are aborted and the summary is sent (0 to disable)
+	BatchTimeout float64 `json:"batch_timeout"`
+
+	// PurchaseSpacingMs is the delay in milliseconds between launching the purchases of
+	// the same gift, so that its units aren't sent all at once. Different gifts are still
+	// bought concurrently (0 to disable)
+	PurchaseSpacingMs int `json:"purchase_spacing_ms"`
+
+	// LaunchJitterMs is the maximum random delay in milliseconds before each gift of a batch
+	// starts buying, so the purchases don't hit the rate limiter all at once. Only used
+	// without prioritization, gifts are still bought concurrently (0 to disable)
+	LaunchJitterMs int `json:"launch_jitter_ms"`
+
+	// DrainOnShutdown lets the purchase attempts in flight finish on shutdown, within the
+	// shutdown timeout, instead of cutting them off. The batch summary is still sent
+	DrainOnShutdown bool `json:"drain_on_shutdown"`
+
+	// BatchSuccessThreshold is the percentage of requested gifts (0-100) a purchase batch
+	// must buy to count as successful. The summary reports pass or fail (0 to disable)
+	BatchSuccessThreshold float64 `json:"batch_success_threshold"`
+
+	// BatchThresholdAction is the reaction to a batch missing BatchSuccessThreshold:
+	// "alert" sends an error notification, "stop" also pauses buying, empty only reports it
+	BatchThresholdAction string `json:"batch_threshold_action"`
+
+	// NotificationRateLimit is the notification sends limit per second, independent
+	// of RPCRateLimit used for purchase API calls (0 for unlimited)
+	NotificationRateLimit int `json:"notification_rate_limit"`
+
+	// MaxNotificationsPerRun caps the notifications sent during one run. Once reached a single
+	// final message is sent and further notifications are suppressed while buying continues (0 for unlimited)
+	MaxNotificationsPerRun int64 `json:"max_notifications_per_run"`
+
+	// ErrorNotifyCooldown is the window in seconds in which repeats of the same error are
+	// not notified again. The first repeat after the window is sent as a "still failing
+	// (N times)" summary (0 to notify every error)
+	ErrorNotifyCooldown float64 `json:"error_notify_cooldown"`
+
+	// NotificationFallbackThreshold is the number of notifications failing in a row after which
+	// notifications are written to the error logs until delivery recovers (0 to disable)
+	NotificationFallbackThreshold int64 `json:"notification_fallback_threshold"`
+
+	// NotificationFallbackFile is an optional file undelivered notifications are also appended to
+	NotificationFallbackFile string `json:"notification_fallback_file"`
+
+	// ShuffleEqualPriority buys gifts with the same priority in random order every cycle
+	// (applies when Prioritization is enabled)
+	ShuffleEqualPriority bool `json:"shuffle_equal_priority"`
+
+	// NotifyOnLimitReached sends a one-time notification when MaxBuyCount is reached
+	NotifyOnLimitReached bool `json:"notify_on_limit_reached"`
+
+	// PriorityByCriteriaOrder buys gifts matched by earlier criteria first regardless of price
+	// (applies when Prioritization is enabled)
+	PriorityByCriteriaOrder bool `json:"priority_by_criteria_order"`
+
+	// PriorityStrategy is the order gifts are bought in: "stars_desc" (default, most expensive
+	// first), "stars_asc", "rarest_first" (fewest remaining units first) or "discovery"
+	// (as discovered). Applies when Prioritization is enabled
+	PriorityStrategy string `json:"priority_strategy"`
+
+	// ProgressNotificationInterval is the interval in seconds of interim "X of Y bought"
+	// notifications while a batch is being bought (0 disables them)
+	ProgressNotificationInterval float64 `json:"progress_notification_interval"`
+
+	// MaxGiftsPerReceiver caps how many units of the same gift a single receiver gets.
+	// When every receiver of a gift is capped the remaining units are skipped (0 for unlimited)
+	MaxGiftsPerReceiver int64 `json:"max_gifts_per_receiver"`
+
+	// MaxAttemptsPerSecond caps purchase attempts across the whole buyer by spacing them
+	// evenly, a coarser control than RPCRateLimit that never allows bursts (0 for unlimited)
+	MaxAttemptsPerSecond int `json:"max_attempts_per_second"`
+
+	// SkipFirstRunWithCache skips the first run suppression cycle after a restart when the
+	// cache restored from disk already holds the gift baseline. It requires an unbounded
+	// cache (MaxCachedGifts 0), evicted gifts would be missing from the baseline
+	SkipFirstRunWithCache bool `json:"skip_first_run_with_cache"`
+
+	// RecoverValidatorPanics makes the monitor skip a gift whose malformed API data makes the
+	// validator panic, logging it instead of crashing the monitoring loop
+	RecoverValidatorPanics bool `json:"recover_validator_panics"`
+
+	// VerboseApiLogging logs the payment requests and raw Telegram responses at debug level
+	// with sensitive fields redacted. Noisy, intended for debugging purchase failures only
+	VerboseApiLogging bool `json:"verbose_api_logging"`
+
+	// Tracing emits OpenTelemetry spans around monitor cycles, purchase attempts and API calls
+	Tracing TracingParams `json:"tracing"`
+
+	// CatalogSnapshotInterval is the interval in seconds between snapshots of the full gift
+	// catalog written for analytics, together with a diff to the previous one (0 to disable)
+	CatalogSnapshotInterval float64 `json:"catalog_snapshot_interval"`
+
+	// CatalogSnapshotDir is the directory of the catalog snapshots and diffs
+	CatalogSnapshotDir string `json:"catalog_snapshot_dir"`
+
+	// FixtureGiftsPath is a JSON file of recorded gifts returned instead of the Telegram
+	// gift catalog, for reproducible runs. Only used in test mode (empty to disable)
+	FixtureGiftsPath string `json:"fixture_gifts_path"`
+
+	// ControlPort is the port of the local HTTP control API bound to 127.0.0.1 (0 disables it)
+	ControlPort int `json:"control_port"`
+
+	// ControlToken is the secret token required by every control API request
+	ControlToken string `json:"control_token"`
+
+	// GRPC serves the control calls and a stream of gift and purchase events over gRPC,
+	// protected by ControlToken
+	GRPC GRPCParams `json:"grpc"`
+
+	// MetricsPort serves Prometheus metrics of discovered, bought and failed gifts and the
+	// balance on http://<host>:<port>/metrics (0 disables the endpoint)
+	MetricsPort int `json:"metrics_port"`
+
+	// MetricsHost is the address the metrics endpoint binds to, 127.0.0.1 when empty.
+	// Use 0.0.0.0 to let a Prometheus server on another host scrape it
+	MetricsHost string `json:"metrics_host"`
+
+	// HealthPort serves GET /healthz for container health probes: 200 while the Telegram
+	// client answers and the gift monitor runs, 503 with the reason otherwise (0 disables it)
+	HealthPort int `json:"health_port"`
+
+	// ConfigWatchInterval is the interval in seconds the config file is checked for changes.
+	// A changed file is reloaded live: criteria, gift parameters and MaxBuyCount are applied
+	// without reconnecting to Telegram (0 disables watching)
+	ConfigWatchInterval float64 `json:"config_watch_interval"`
+
+	// HeartbeatURL receives a periodic JSON heartbeat with the uptime, balance, purchases,
+	// paused state and last error of the service (empty to disable)
+	HeartbeatURL string `json:"heartbeat_url"`
+
+	// HeartbeatInterval is the interval in seconds between heartbeats (default 60)
+	HeartbeatInterval float64 `json:"heartbeat_interval"`
+
+	// DiscoveryWebhookURL receives every batch of newly discovered eligible gifts as a JSON
+	// array of {id, title, price, supply, count_for_buy}, without delaying buying (empty to disable)
+	DiscoveryWebhookURL string `json:"discovery_webhook_url"`
+}
+
+// Supported Telegram proxy types.
+const (
+	ProxyTypeSOCKS5  = "socks5"
+	ProxyTypeMTProxy = "mtproxy"
+)
+
+// ProxyParams configures the proxy the Telegram clients connect through.
+type ProxyParams struct {
+	// Type is the proxy type, "socks5" (default) or "mtproxy"
+	Type string `json:"type"`
+
+	// Address is the proxy address as host:port
+	Address string `json:"address"`
+
+	// Username and Password are the optional SOCKS5 credentials
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// Secret is the hex encoded MTProxy secret
+	Secret string `json:"secret"`
+}
+
+// Enabled reports whether a proxy is configured.
+func (p ProxyParams) Enabled() bool {
+	return p.Address != ""
 }
 
 type GiftParam struct {
@@ -86,8 +380,84 @@ type GiftParam struct {
 
 	// ReleaseBy is the type of release by
 	ReleaseBy bool `json:"release_by"`
+
+	// MissingAvailabilityPolicy defines how limited gifts without availability data are handled:
+	// "skip" (default), "eligible" or "recheck" on the next cycle
+	MissingAvailabilityPolicy string `json:"missing_availability_policy"`
+
+	// ScamDetection rejects gifts with suspicious attributes
+	ScamDetection ScamDetection `json:"scam_detection"`
+
+	// MinAvailabilityAtBuy re-checks the remaining supply of a limited gift right before
+	// every purchase attempt and skips it when fewer gifts remain (0 to disable)
+	MinAvailabilityAtBuy int `json:"min_availability_at_buy"`
+
+	// RevalidateBeforeBuy checks the live gift against the criteria again right before
+	// every purchase attempt and skips it when the gift no longer qualifies
+	RevalidateBeforeBuy bool `json:"revalidate_before_buy"`
+
+	// DryRun runs the whole pipeline against live gifts but only simulates purchases,
+	// no stars are spent
+	DryRun bool `json:"dry_run"`
+}
+
+// ScamDetection configures the heuristics rejecting suspicious (scam or fake) gifts.
+type ScamDetection struct {
+	// Enabled turns the scam detection heuristics on
+	Enabled bool `json:"enabled"`
+
+	// ZeroConvertMinPrice rejects gifts that can't be converted back to stars while costing
+	// at least this many stars (0 rejects every gift with a zero convert price)
+	ZeroConvertMinPrice int64 `json:"zero_convert_min_price"`
+
+	// MinConvertRatio rejects gifts whose convert price is below this fraction of the
+	// purchase price, e.g. 0.5 (0 disables the check)
+	MinConvertRatio float64 `json:"min_convert_ratio"`
 }
 
+// Missing availability policies for limited gifts whose availability data hasn't arrived yet
+const (
+	// MissingAvailabilitySkip treats such gifts as not eligible (default)
+	MissingAvailabilitySkip = "skip"
+
+	// MissingAvailabilityEligible treats such gifts as eligible regardless of the supply criteria
+	MissingAvailabilityEligible = "eligible"
+
+	// MissingAvailabilityRecheck skips such gifts but validates them again on the next cycle
+	MissingAvailabilityRecheck = "recheck"
+)
+
+// Notification delivery modes supported by TgSettings.NotificationMode.
+const (
+	// NotificationModeBot delivers notifications through the bot client
+	NotificationModeBot = "bot"
+
+	// NotificationModeSelf delivers notifications from the user account to Saved Messages
+	NotificationModeSelf = "self"
+)
+
+// CountMaxAffordable is the criteria count buying as many gifts as the stars balance
+// allows at buy time, capped by MaxBuyCount. It requires CheckBalanceBeforeBuy.
+const CountMaxAffordable = -1
+
+// Notification languages supported by TgSettings.Language.
+const (
+	// LanguageEnglish renders notifications in English
+	LanguageEnglish = "en"
+
+	// LanguageRussian renders notifications in Russian
+	LanguageRussian = "ru"
+)
+
+// User login methods supported by TgSettings.AuthMethod.
+const (
+	// AuthMethodCode logs in with the phone number and the code entered on stdin
+	AuthMethodCode = "code"
+
+	// AuthMethodQR logs in by scanning a QR login link with an already logged in Telegram app
+	AuthMethodQR = "qr"
+)
+
 // TgSettings contains all Telegram-related configuration parameters.
 // This includes API credentials, bot settings, and notification preferences.
 type TgSettings struct {
@@ -103,6 +473,15 @@ type TgSettings struct {
 	// Password is the 2FA password for the Telegram account (if enabled)
 	Password string `json:"password"`
 
+	// AuthMethod selects how a new session logs in: "code" (default) prompts for the code
+	// sent to Phone on stdin, "qr" prints a login link to confirm from another device,
+	// which needs no interactive input, e.g. in Docker
+	AuthMethod string `json:"auth_method"`
+
+	// ValidateCredentials checks the format of AppId, ApiHash and Phone on startup
+	// and fails fast with an explanation instead of a connection error
+	ValidateCredentials bool `json:"validate_credentials"`
+
 	// TgBotKey is the bot token for sending notifications
 	TgBotKey string `json:"tg_bot_key"`
 
@@ -110,8 +489,161 @@ type TgSettings struct {
 	// Default is 0 (auto-select). Use 4 for better performance when DC2 is lagging
 	Datacenter int `json:"datacenter"`
 
+	// Proxy routes the user and bot client connections through a SOCKS5 proxy or an MTProxy.
+	// Disabled when the address is empty
+	Proxy ProxyParams `json:"proxy"`
+
 	// NotificationChatID is the chat ID where notifications will be sent
 	NotificationChatID int64 `json:"notification_chat_id"`
+
+	// NotificationChatIDs are additional chat IDs notifications are fanned out to in bot mode,
+	// together with NotificationChatID
+	NotificationChatIDs []int64 `json:"notification_chat_ids"`
+
+	// NotificationMode selects how notifications are delivered: "bot" (default) sends them
+	// through the bot to NotificationChatID, "self" sends them from the user account to Saved Messages
+	NotificationMode string `json:"notification_mode"`
+
+	// NotificationRetryJitter is the random spread (fraction of the delay, 0-1) applied to
+	// notification retry delays so concurrent sends don't retry in lockstep. Default is 0.3 when not set,
+	// a negative value (e.g. -1) disables the jitter
+	NotificationRetryJitter float64 `json:"notification_retry_jitter"`
+
+	// NotificationTemplate is an optional Go text/template for new gift notifications.
+	// Available fields: .Title, .ID, .Price, .ConvertPrice, .Supply, .Available, .Percentage and .UpdatedAt.
+	// The built-in format is used when it is empty or fails to parse
+	NotificationTemplate string `json:"notification_template"`
+
+	// Language of the notifications: "en" (default) or "ru". Unknown languages fall back to English
+	Language string `json:"language"`
+
+	// ThousandsSeparator groups the digits of numbers in notifications, "," when empty
+	ThousandsSeparator string `json:"thousands_separator"`
+
+	// DiscordWebhookURL mirrors purchase and discovery notifications to a Discord channel
+	// through the webhook (empty to disable)
+	DiscordWebhookURL string `json:"discord_webhook_url"`
+
+	// NotifyReconnect sends notifications when a disconnect is detected and when
+	// a reconnect starts, succeeds or fails
+	NotifyReconnect bool `json:"notify_reconnect"`
+
+	// ApiStaleTimeout triggers a reconnect when no API check has succeeded for this many
+	// seconds, even if the checks fail without a critical error (0 disables it)
+	ApiStaleTimeout float64 `json:"api_stale_timeout"`
+
+	// CriticalErrorPatterns are the case-insensitive substrings of API check errors that
+	// trigger a reconnect. Default is AUTH_KEY_UNREGISTERED, CONNECTION_NOT_INITED and
+	// SESSION_REVOKED when empty
+	CriticalErrorPatterns []string `json:"critical_error_patterns"`
+
+	// ReconnectMaxAttempts is the number of reconnect attempts before the service shuts down.
+	// Default is 5 when not set
+	ReconnectMaxAttempts int `json:"reconnect_max_attempts"`
+
+	// ReconnectBaseDelay is the delay in seconds after the first failed reconnect attempt,
+	// doubled after every further failure up to a minute. Default is 2 seconds when not set
+	ReconnectBaseDelay float64 `json:"reconnect_base_delay"`
+
+	// AuthTimeout is the maximum time in seconds to wait for the user authentication flow.
+	// Default is 560 seconds when not set, which leaves room for interactive code entry
+	AuthTimeout float64 `json:"auth_timeout"`
+
+	// BotAuthTimeout is the maximum time in seconds to wait for the bot authentication.
+	// Default is 30 seconds when not set
+	BotAuthTimeout float64 `json:"bot_auth_timeout"`
+
+	// SessionPath is the file storing the user client session. Default is "session.json" when not set
+	SessionPath string `json:"session_path"`
+
+	// BotSessionPath is the file storing the bot client session. Default is "bot_session.json" when not set.
+	// It must differ from SessionPath, otherwise both clients overwrite each other's authorization
+	BotSessionPath string `json:"bot_session_path"`
+
+	// SessionPassphrase encrypts the user and bot session files with AES-GCM. The
+	// GIFT_BUYER_SESSION_PASSPHRASE environment variable is used when it is empty,
+	// the files are stored in plaintext when both are empty
+	SessionPassphrase string `json:"session_passphrase"`
+}
+
+// Default session files used when TgSettings leaves the paths empty.
+const (
+	// DefaultSessionPath is the default user client session file
+	DefaultSessionPath = "session.json"
+
+	// DefaultBotSessionPath is the default bot client session file
+	DefaultBotSessionPath = "bot_session.json"
+)
+
+// SessionPassphraseEnv is the environment variable holding the session passphrase
+// when TgSettings.SessionPassphrase is empty
+const SessionPassphraseEnv = "GIFT_BUYER_SESSION_PASSPHRASE"
+
+// SessionKey returns the passphrase encrypting the session files, empty when
+// the sessions are stored in plaintext.
+func (s *TgSettings) SessionKey() string {
+	if s.SessionPassphrase != "" {
+		return s.SessionPassphrase
+	}
+	return os.Getenv(SessionPassphraseEnv)
+}
+
+// UserSessionFile returns the configured user session file or the default one.
+func (s *TgSettings) UserSessionFile() string {
+	if s.SessionPath != "" {
+		return s.SessionPath
+	}
+	return DefaultSessionPath
+}
+
+// BotSessionFile returns the configured bot session file or the default one.
+func (s *TgSettings) BotSessionFile() string {
+	if s.BotSessionPath != "" {
+		return s.BotSessionPath
+	}
+	return DefaultBotSessionPath
+}
+
+// AccountSettings returns the settings of the additional accounts with the connection
+// and reconnect settings they leave unset taken from TgSettings. An account without a session file
+// gets "session_<n>.json", where n is its position counting the TgSettings account as 1.
+func (c *SoftConfig) AccountSettings() []TgSettings {
+	accounts := make([]TgSettings, 0, len(c.Accounts))
+	for i, account := range c.Accounts {
+		if account.AppId == 0 {
+			account.AppId = c.TgSettings.AppId
+		}
+		if account.ApiHash == "" {
+			account.ApiHash = c.TgSettings.ApiHash
+		}
+		if account.Datacenter == 0 {
+			account.Datacenter = c.TgSettings.Datacenter
+		}
+		if !account.Proxy.Enabled() {
+			account.Proxy = c.TgSettings.Proxy
+		}
+		if account.SessionPassphrase == "" {
+			account.SessionPassphrase = c.TgSettings.SessionPassphrase
+		}
+		if account.ApiStaleTimeout == 0 {
+			account.ApiStaleTimeout = c.TgSettings.ApiStaleTimeout
+		}
+		if len(account.CriticalErrorPatterns) == 0 {
+			account.CriticalErrorPatterns = c.TgSettings.CriticalErrorPatterns
+		}
+		if account.ReconnectMaxAttempts == 0 {
+			account.ReconnectMaxAttempts = c.TgSettings.ReconnectMaxAttempts
+		}
+		if account.ReconnectBaseDelay == 0 {
+			account.ReconnectBaseDelay = c.TgSettings.ReconnectBaseDelay
+		}
+		account.NotifyReconnect = account.NotifyReconnect || c.TgSettings.NotifyReconnect
+		if account.SessionPath == "" {
+			account.SessionPath = fmt.Sprintf("session_%d.json", i+2)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts
 }
 
 // Criterias defines the validation criteria for gift purchases.
@@ -126,19 +658,190 @@ type Criterias struct {
 	// TotalSupply is the minimum total supply required for limited gifts
 	TotalSupply int64 `json:"total_supply"`
 
-	// Count is the number of gifts to purchase when this criteria matches
+	// Count is the number of gifts to purchase when this criteria matches,
+	// CountMaxAffordable buys as many as the stars balance allows
 	Count int64 `json:"count"`
 
+	// StarBudget is the maximum stars spent on Count gifts of this criteria (0 for no budget):
+	// the sum of all stages for staged criteria, and a cap on the count for CountMaxAffordable.
+	// It is checked alongside the global TotalStarCap
+	StarBudget int64 `json:"star_budget"`
+
 	// ReceiverType is the type of receiver (1 for user, 2 for channel)
 	ReceiverType []int `json:"receiver_type"`
 
-	// ReceiverDistribution []DistributionParams `json:"receiver_distribution"`
+	// ReceiverDistribution distributes the gifts to the listed receivers proportionally to their
+	// Count weights. Only receivers configured for ReceiverType are used; empty keeps the
+	// rotation or random receiver selection
+	ReceiverDistribution []DistributionParams `json:"receiver_distribution"`
+
 	Hide bool `json:"hide"`
+
+	// Upgrade pays the upgrade of the gift together with the purchase, so the receiver gets
+	// the unique upgraded gift. Ignored for gifts that can't be upgraded
+	Upgrade bool `json:"upgrade"`
+
+	// BroadcastToAllReceivers buys one gift for every configured receiver of the
+	// receiver types in parallel instead of Count gifts for random receivers
+	BroadcastToAllReceivers bool `json:"broadcast_to_all_receivers"`
+
+	// BuyForAllReceiverTypes buys Count gifts for every listed receiver type concurrently
+	// instead of picking one receiver type per purchase
+	BuyForAllReceiverTypes bool `json:"buy_for_all_receiver_types"`
+
+	// ActiveWindows restricts the criteria to the listed daily time windows (UTC).
+	// Empty means the criteria is always active
+	ActiveWindows []ActiveWindow `json:"active_windows"`
+
+	// BuyAfter and BuyBefore restrict the criteria to a release window given as RFC3339
+	// timestamps, e.g. "2025-07-01T12:00:00Z". Either bound may be empty, a malformed
+	// bound is logged and ignored
+	BuyAfter  string `json:"buy_after"`
+	BuyBefore string `json:"buy_before"`
+
+	// Stages buys the gift in sequential stages, e.g. a small count first and more only
+	// after it was bought. When set, Count is replaced by the sum of the stage counts
+	// and BroadcastToAllReceivers is ignored
+	Stages []StageParams `json:"stages"`
+
+	// StickerIDs restricts the criteria to gifts whose sticker document ID is listed.
+	// Empty means no sticker filter
+	StickerIDs []int64 `json:"sticker_ids"`
+
+	// MinRarityPermille and MaxRarityPermille restrict the criteria to gifts with at least
+	// one model, backdrop or pattern attribute whose rarity (per mille) is inside the range.
+	// 0 disables the bound. Gifts without attribute data fail the filter when a bound is set
+	MinRarityPermille int `json:"min_rarity_permille"`
+	MaxRarityPermille int `json:"max_rarity_permille"`
+
+	// TitleAllowlist restricts the criteria to gifts whose title contains one of the
+	// entries (case-insensitive). Empty means any title
+	TitleAllowlist []string `json:"title_allowlist"`
+
+	// TitleBlocklist rejects gifts whose title contains any of the entries (case-insensitive)
+	TitleBlocklist []string `json:"title_blocklist"`
+
+	// RemainsBelow restricts the criteria to limited gifts with fewer remaining units than
+	// the threshold. A gift already seen by the monitor is evaluated again the moment its
+	// remaining supply drops below the threshold. 0 disables the filter
+	RemainsBelow int64 `json:"remains_below"`
+
+	// Notify sends a new gift notification for gifts matching this criteria (true when omitted)
+	Notify bool `json:"notify"`
+
+	// Buy buys gifts matching this criteria (true when omitted), false only alerts about them
+	Buy bool `json:"buy"`
 }
 
+// UnmarshalJSON decodes the criteria with Notify and Buy enabled unless they are
+// set explicitly, so configs written before the flags existed keep working.
+func (c *Criterias) UnmarshalJSON(data []byte) error {
+	type plain Criterias
+	criteria := plain{Notify: true, Buy: true}
+	if err := json.Unmarshal(data, &criteria); err != nil {
+		return err
+	}
+	*c = Criterias(criteria)
+	return nil
+}
+
+// StageParams is one stage of an escalating purchase.
+type StageParams struct {
+	// Count is the number of gifts bought in the stage
+	Count int64 `json:"count"`
+
+	// Condition to run the stage: "previous_succeeded" (default) runs it only if every gift of
+	// the previous stage was bought, "always" runs it regardless. Ignored for the first stage
+	Condition string `json:"condition"`
+}
+
+// Stage conditions
+const (
+	// StageConditionPreviousSucceeded runs the stage only after the previous stage fully succeeded (default)
+	StageConditionPreviousSucceeded = "previous_succeeded"
+
+	// StageConditionAlways runs the stage regardless of the previous stage result
+	StageConditionAlways = "always"
+)
+
+// Gift cache backends
+const (
+	// CacheBackendFile keeps the gift cache in cache.json (default)
+	CacheBackendFile = "file"
+
+	// CacheBackendSQLite keeps the gift cache in the cache.db SQLite database
+	CacheBackendSQLite = "sqlite"
+)
+
+// Priority strategies
+const (
+	// PriorityStarsDesc buys the most expensive gifts first (default)
+	PriorityStarsDesc = "stars_desc"
+
+	// PriorityStarsAsc buys the cheapest gifts first
+	PriorityStarsAsc = "stars_asc"
+
+	// PriorityRarestFirst buys limited gifts with the fewest remaining units first
+	PriorityRarestFirst = "rarest_first"
+
+	// PriorityDiscovery buys gifts in the order they were discovered
+	PriorityDiscovery = "discovery"
+)
+
+// ActiveWindow is a daily time window in UTC. A window whose end is before its start
+// spans midnight, e.g. 22:00-02:00
+type ActiveWindow struct {
+	// Start is the beginning of the window in "HH:MM" format
+	Start string `json:"start"`
+
+	// End is the end of the window in "HH:MM" format (exclusive)
+	End string `json:"end"`
+}
+
+// DistributionParams is the weight of a receiver in a weighted receiver distribution.
 type DistributionParams struct {
+	// Username is the receiver as configured in the receiver lists
 	Username string `json:"username"`
-	Count    int    `json:"count"`
+
+	// Count is the weight of the receiver, e.g. 3 and 1 send three gifts to the first
+	// receiver for every gift to the second one
+	Count int `json:"count"`
+}
+
+// InitialCheckBurst configures the checks run immediately when monitoring starts.
+type InitialCheckBurst struct {
+	// Count is the number of checks in the burst, the first one runs immediately
+	Count int `json:"count"`
+
+	// Interval is the delay between burst checks in seconds
+	Interval float64 `json:"interval"`
+}
+
+// TracingParams configures the OpenTelemetry span export.
+type TracingParams struct {
+	// Enabled turns the span export on
+	Enabled bool `json:"enabled"`
+
+	// Endpoint is the OTLP/HTTP collector URL, e.g. "http://localhost:4318"
+	Endpoint string `json:"endpoint"`
+
+	// ServiceName is the service.name of the exported spans. Default is "gift-buyer" when not set
+	ServiceName string `json:"service_name"`
+}
+
+// GRPCParams configures the gRPC control and event stream API, see api/proto/control.proto.
+type GRPCParams struct {
+	// Enabled turns the gRPC API on
+	Enabled bool `json:"enabled"`
+
+	// Port is the port of the gRPC API bound to 127.0.0.1
+	Port int `json:"port"`
+}
+
+// PurchaseSchedule configures the purchase queue between the monitor and the buyer.
+type PurchaseSchedule struct {
+	// PerMinute is the number of purchases dispatched to the buyer per minute (0 disables the queue)
+	PerMinute int `json:"per_minute"`
 }
 
 // ReceiverParams specifies the recipient configuration for purchased gifts.
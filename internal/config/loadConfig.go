@@ -1,23 +1,29 @@
 // Package config provides application configuration management.
-// It handles loading, parsing and validating application configuration from JSON files.
+// It handles loading, parsing and validating application configuration from JSON or YAML files.
 package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"gift-buyer/pkg/errors"
 	"gift-buyer/pkg/logger"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
-// LoadConfig loads and parses the application configuration from the specified JSON file.
-// It reads the configuration file, unmarshals the JSON content, and returns the parsed
+// LoadConfig loads and parses the application configuration from the specified JSON or YAML file.
+// It reads the configuration file, unmarshals the content, and returns the parsed
 // configuration structure.
 //
-// The configuration file should be in JSON format and contain all required settings
-// including Telegram credentials, gift criteria, and operational parameters.
+// The configuration file should be in JSON format, or in YAML format when its extension is
+// .yaml or .yml, and contain all required settings including Telegram credentials, gift
+// criteria, and operational parameters. YAML files use the same keys as the JSON ones.
 //
 // Parameters:
-//   - path: filesystem path to the configuration JSON file
+//   - path: filesystem path to the configuration JSON or YAML file
 //
 // Returns:
 //   - *AppConfig: parsed configuration structure containing all application settings
@@ -30,9 +36,12 @@ import (
 //	    log.Fatalf("Failed to load config: %v", err)
 //	}
 //
+// Secrets set in the environment (see applyEnvOverrides) take precedence over the file values.
+//
 // Possible errors:
 //   - ErrConfigRead: when the configuration file cannot be read
-//   - ErrConfigParse: when the JSON content cannot be parsed
+//   - ErrConfigParse: when the JSON or YAML content or the criteria defaults cannot be parsed
+//   - ErrInvalidConfig: when the configuration doesn't pass validation
 func LoadConfig(path string) (*AppConfig, error) {
 	logger.GlobalLogger.Debugf("Loading config from: %s", path)
 
@@ -42,10 +51,165 @@ func LoadConfig(path string) (*AppConfig, error) {
 		return nil, errors.Wrap(errors.ErrConfigRead, err.Error())
 	}
 
+	if isYAML(path) {
+		if data, err = yamlToJSON(data); err != nil {
+			logger.GlobalLogger.Errorf("Failed to convert YAML config: %v", err)
+			return nil, errors.Wrap(errors.ErrConfigParse, err.Error())
+		}
+	}
+
 	appConfig := &AppConfig{}
 	if err := json.Unmarshal(data, appConfig); err != nil {
 		logger.GlobalLogger.Errorf("Failed to unmarshal config: %v", err)
 		return nil, errors.Wrap(errors.ErrConfigParse, err.Error())
 	}
+
+	if err := applyCriteriaDefaults(data, appConfig); err != nil {
+		logger.GlobalLogger.Errorf("Failed to apply criteria defaults: %v", err)
+		return nil, errors.Wrap(errors.ErrConfigParse, err.Error())
+	}
+
+	applyEnvOverrides(appConfig)
+
+	if err := appConfig.Validate(); err != nil {
+		logger.GlobalLogger.Errorf("Invalid config: %v", err)
+		return nil, err
+	}
 	return appConfig, nil
 }
+
+// Environment variables overriding the Telegram credentials of the configuration file
+const (
+	ApiHashEnv  = "GIFTBUYER_API_HASH"
+	PasswordEnv = "GIFTBUYER_PASSWORD"
+	BotKeyEnv   = "GIFTBUYER_BOT_KEY"
+	PhoneEnv    = "GIFTBUYER_PHONE"
+)
+
+// applyEnvOverrides replaces the Telegram credentials with the values of the environment
+// variables that are set, so secrets don't have to be stored in the configuration file.
+// Unset variables leave the file values untouched.
+//
+// Parameters:
+//   - appConfig: parsed configuration whose credentials are overridden
+func applyEnvOverrides(appConfig *AppConfig) {
+	settings := &appConfig.SoftConfig.TgSettings
+	overrides := []struct {
+		env   string
+		field *string
+	}{
+		{ApiHashEnv, &settings.ApiHash},
+		{PasswordEnv, &settings.Password},
+		{BotKeyEnv, &settings.TgBotKey},
+		{PhoneEnv, &settings.Phone},
+	}
+
+	for _, override := range overrides {
+		if value, ok := os.LookupEnv(override.env); ok {
+			*override.field = value
+		}
+	}
+}
+
+// applyCriteriaDefaults merges the criteria defaults into every criteria. Fields a criteria
+// omits are taken from the defaults and fields it sets override them. The merge works on
+// the raw JSON, so an explicit zero value still overrides a default.
+//
+// Parameters:
+//   - data: raw configuration file content
+//   - appConfig: parsed configuration whose criteria are replaced with the merged ones
+//
+// Returns:
+//   - error: criteria that cannot be decoded with the defaults applied
+func applyCriteriaDefaults(data []byte, appConfig *AppConfig) error {
+	defaults := appConfig.SoftConfig.CriteriaDefaults
+	if len(defaults) == 0 {
+		return nil
+	}
+
+	var raw struct {
+		SoftConfig struct {
+			Criterias []map[string]json.RawMessage `json:"criterias"`
+		} `json:"soft_config"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	for i, fields := range raw.SoftConfig.Criterias {
+		merged := make(map[string]json.RawMessage, len(defaults)+len(fields))
+		for key, value := range defaults {
+			merged[key] = value
+		}
+		for key, value := range fields {
+			merged[key] = value
+		}
+
+		encoded, err := json.Marshal(merged)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("criteria %d", i+1))
+		}
+		var criteria Criterias
+		if err := json.Unmarshal(encoded, &criteria); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("criteria %d", i+1))
+		}
+		appConfig.SoftConfig.Criterias[i] = criteria
+	}
+	return nil
+}
+
+// isYAML reports whether the configuration file is a YAML file judging by its extension.
+func isYAML(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// yamlToJSON converts a YAML configuration to JSON, so that YAML files are decoded with
+// the same json tags, criteria defaults and custom unmarshalers as JSON files.
+//
+// Parameters:
+//   - data: raw YAML file content
+//
+// Returns:
+//   - []byte: equivalent JSON document
+//   - error: invalid or empty YAML
+func yamlToJSON(data []byte) ([]byte, error) {
+	var document any
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if document == nil {
+		return nil, errors.New("empty YAML document")
+	}
+
+	return json.Marshal(jsonCompatible(document))
+}
+
+// jsonCompatible replaces YAML maps with non-string keys by string keyed maps, which
+// encoding/json cannot marshal otherwise.
+func jsonCompatible(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = jsonCompatible(item)
+		}
+		return v
+	case map[any]any:
+		converted := make(map[string]any, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return converted
+	case []any:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
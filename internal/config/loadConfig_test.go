@@ -142,6 +142,7 @@ func TestLoadConfig_MinimalConfig(t *testing.T) {
 			"tg_settings": map[string]interface{}{
 				"app_id":   123456,
 				"api_hash": "test_hash",
+				"phone":    "+1234567890",
 			},
 			"ticker": 60.0,
 		},
@@ -240,6 +241,11 @@ func TestLoadConfig_ZeroValues(t *testing.T) {
 	config := &AppConfig{
 		LoggerLevel: "",
 		SoftConfig: SoftConfig{
+			TgSettings: TgSettings{
+				AppId:   123456,
+				ApiHash: "test_hash",
+				Phone:   "+1234567890",
+			},
 			Criterias: []Criterias{
 				{
 					MinPrice:    0,
@@ -278,6 +284,11 @@ func TestLoadConfig_BooleanFields(t *testing.T) {
 	config := &AppConfig{
 		LoggerLevel: "info",
 		SoftConfig: SoftConfig{
+			TgSettings: TgSettings{
+				AppId:   123456,
+				ApiHash: "test_hash",
+				Phone:   "+1234567890",
+			},
 			GiftParam: GiftParam{
 				TestMode:      true,
 				LimitedStatus: false,
@@ -304,6 +315,11 @@ func TestLoadConfig_ComplexReceiverParams(t *testing.T) {
 	config := &AppConfig{
 		LoggerLevel: "info",
 		SoftConfig: SoftConfig{
+			TgSettings: TgSettings{
+				AppId:   123456,
+				ApiHash: "test_hash",
+				Phone:   "+1234567890",
+			},
 			Receiver: ReceiverParams{
 				UserReceiverID:    []string{"111", "222", "333"},
 				ChannelReceiverID: []string{"444", "555", "666"},
@@ -440,3 +456,250 @@ func TestLoadConfig_AllFieldTypes(t *testing.T) {
 	// Verify Receiver
 	assert.Equal(t, config.SoftConfig.Receiver, loadedConfig.SoftConfig.Receiver)
 }
+
+func TestCriterias_NotifyBuyDefaults(t *testing.T) {
+	var criterias []Criterias
+	err := json.Unmarshal([]byte(`[
+		{"min_price": 1, "max_price": 10},
+		{"min_price": 1, "max_price": 10, "notify": false},
+		{"min_price": 1, "max_price": 10, "buy": false}
+	]`), &criterias)
+	require.NoError(t, err)
+	require.Len(t, criterias, 3)
+
+	assert.True(t, criterias[0].Notify)
+	assert.True(t, criterias[0].Buy)
+	assert.Equal(t, int64(10), criterias[0].MaxPrice)
+
+	assert.False(t, criterias[1].Notify)
+	assert.True(t, criterias[1].Buy)
+
+	assert.True(t, criterias[2].Notify)
+	assert.False(t, criterias[2].Buy)
+}
+
+func TestLoadConfig_CriteriaDefaults(t *testing.T) {
+	writeConfig := func(t *testing.T, softConfig string) string {
+		configPath := filepath.Join(t.TempDir(), "config.json")
+		data := `{"logger_level": "info", "soft_config": {"tg_settings": {"app_id": 123456, "api_hash": "test_hash", "phone": "+1234567890"}, ` +
+			`"receiver": {"user_receiver_id": ["user"], "channel_receiver_id": ["channel"]}, ` + softConfig + `}}`
+		require.NoError(t, os.WriteFile(configPath, []byte(data), 0644))
+		return configPath
+	}
+
+	t.Run("значения по умолчанию заполняют пропущенные поля", func(t *testing.T) {
+		config, err := LoadConfig(writeConfig(t, `
+			"criteria_defaults": {"receiver_type": [1, 2], "hide": true, "count": 5},
+			"criterias": [
+				{"min_price": 10, "max_price": 100},
+				{"min_price": 100, "max_price": 500, "receiver_type": [0]}
+			]`))
+		require.NoError(t, err)
+		require.Len(t, config.SoftConfig.Criterias, 2)
+
+		first := config.SoftConfig.Criterias[0]
+		assert.Equal(t, int64(10), first.MinPrice)
+		assert.Equal(t, int64(100), first.MaxPrice)
+		assert.Equal(t, []int{1, 2}, first.ReceiverType)
+		assert.True(t, first.Hide)
+		assert.Equal(t, int64(5), first.Count)
+		assert.True(t, first.Notify)
+		assert.True(t, first.Buy)
+
+		second := config.SoftConfig.Criterias[1]
+		assert.Equal(t, []int{0}, second.ReceiverType)
+		assert.True(t, second.Hide)
+		assert.Equal(t, int64(5), second.Count)
+	})
+
+	t.Run("явные значения переопределяют значения по умолчанию", func(t *testing.T) {
+		config, err := LoadConfig(writeConfig(t, `
+			"criteria_defaults": {"hide": true, "count": 5, "buy": false},
+			"criterias": [
+				{"min_price": 10, "max_price": 100, "hide": false, "count": 1, "buy": true}
+			]`))
+		require.NoError(t, err)
+		require.Len(t, config.SoftConfig.Criterias, 1)
+
+		criteria := config.SoftConfig.Criterias[0]
+		assert.False(t, criteria.Hide)
+		assert.Equal(t, int64(1), criteria.Count)
+		assert.True(t, criteria.Buy)
+	})
+
+	t.Run("без значений по умолчанию критерии не меняются", func(t *testing.T) {
+		config, err := LoadConfig(writeConfig(t, `
+			"criterias": [{"min_price": 10, "max_price": 100, "count": 2}]`))
+		require.NoError(t, err)
+
+		criteria := config.SoftConfig.Criterias[0]
+		assert.Equal(t, int64(2), criteria.Count)
+		assert.False(t, criteria.Hide)
+		assert.Empty(t, criteria.ReceiverType)
+	})
+
+	t.Run("неверный тип значения по умолчанию", func(t *testing.T) {
+		config, err := LoadConfig(writeConfig(t, `
+			"criteria_defaults": {"count": "five"},
+			"criterias": [{"min_price": 10, "max_price": 100}]`))
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+	})
+}
+
+func TestLoadConfig_YAML(t *testing.T) {
+	jsonConfig := `{
+		"logger_level": "debug",
+		"soft_config": {
+			"update_ticker": 30,
+			"ticker": 1.5,
+			"tg_settings": {
+				"app_id": 123456,
+				"api_hash": "test_hash",
+				"phone": "+1234567890",
+				"notification_chat_id": -1001234567890,
+				"notification_chat_ids": [111, 222],
+				"proxy": {"type": "socks5", "address": "127.0.0.1:1080"}
+			},
+			"accounts": [{"phone": "+1987654321", "session_path": "second.json"}],
+			"criteria_defaults": {"hide": true, "count": 5},
+			"criterias": [
+				{"min_price": 10, "max_price": 100, "receiver_type": [1, 2]},
+				{"min_price": 100, "max_price": 500, "buy": false}
+			],
+			"receiver": {"user_receiver_id": ["user"], "channel_receiver_id": ["channel"]},
+			"gift_param": {"total_star_cap": 10000, "limited_status": true},
+			"warm_gift_ids": [5170233102089322756],
+			"log_flag": true
+		}
+	}`
+	yamlConfig := `
+logger_level: debug
+soft_config:
+  update_ticker: 30
+  ticker: 1.5
+  tg_settings:
+    app_id: 123456
+    api_hash: test_hash
+    phone: "+1234567890"
+    notification_chat_id: -1001234567890
+    notification_chat_ids: [111, 222]
+    proxy:
+      type: socks5
+      address: 127.0.0.1:1080
+  accounts:
+    - phone: "+1987654321"
+      session_path: second.json
+  criteria_defaults:
+    hide: true
+    count: 5
+  criterias:
+    - min_price: 10
+      max_price: 100
+      receiver_type: [1, 2]
+    - min_price: 100
+      max_price: 500
+      buy: false
+  receiver:
+    user_receiver_id: [user]
+    channel_receiver_id: [channel]
+  gift_param:
+    total_star_cap: 10000
+    limited_status: true
+  warm_gift_ids: [5170233102089322756]
+  log_flag: true
+`
+	tempDir := t.TempDir()
+	jsonPath := filepath.Join(tempDir, "config.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(jsonConfig), 0644))
+
+	expected, err := LoadConfig(jsonPath)
+	require.NoError(t, err)
+
+	for _, name := range []string{"config.yaml", "config.yml", "config.YAML"} {
+		t.Run("совпадает с JSON: "+name, func(t *testing.T) {
+			yamlPath := filepath.Join(tempDir, name)
+			require.NoError(t, os.WriteFile(yamlPath, []byte(yamlConfig), 0644))
+
+			config, err := LoadConfig(yamlPath)
+			require.NoError(t, err)
+			assert.Equal(t, expected, config)
+		})
+	}
+
+	t.Run("неверный YAML", func(t *testing.T) {
+		yamlPath := filepath.Join(tempDir, "invalid.yaml")
+		require.NoError(t, os.WriteFile(yamlPath, []byte("soft_config:\n  ticker: [1, 2\n"), 0644))
+
+		config, err := LoadConfig(yamlPath)
+		assert.Error(t, err)
+		assert.Nil(t, config)
+	})
+
+	t.Run("пустой YAML", func(t *testing.T) {
+		yamlPath := filepath.Join(tempDir, "empty.yaml")
+		require.NoError(t, os.WriteFile(yamlPath, nil, 0644))
+
+		config, err := LoadConfig(yamlPath)
+		assert.Error(t, err)
+		assert.Nil(t, config)
+	})
+
+	t.Run("файл .json с YAML не разбирается", func(t *testing.T) {
+		yamlPath := filepath.Join(tempDir, "yaml_content.json")
+		require.NoError(t, os.WriteFile(yamlPath, []byte(yamlConfig), 0644))
+
+		config, err := LoadConfig(yamlPath)
+		assert.Error(t, err)
+		assert.Nil(t, config)
+	})
+}
+
+func TestLoadConfig_EnvOverrides(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	data := `{"logger_level": "info", "soft_config": {"tg_settings": {
+		"app_id": 123456, "api_hash": "file_hash", "password": "file_password",
+		"tg_bot_key": "file_bot_key", "phone": "+1111111111"}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(data), 0644))
+
+	t.Run("переменные окружения имеют приоритет", func(t *testing.T) {
+		t.Setenv(ApiHashEnv, "env_hash")
+		t.Setenv(PasswordEnv, "env_password")
+		t.Setenv(BotKeyEnv, "env_bot_key")
+		t.Setenv(PhoneEnv, "+2222222222")
+
+		config, err := LoadConfig(configPath)
+		require.NoError(t, err)
+
+		settings := config.SoftConfig.TgSettings
+		assert.Equal(t, "env_hash", settings.ApiHash)
+		assert.Equal(t, "env_password", settings.Password)
+		assert.Equal(t, "env_bot_key", settings.TgBotKey)
+		assert.Equal(t, "+2222222222", settings.Phone)
+		assert.Equal(t, 123456, settings.AppId)
+	})
+
+	t.Run("незаданные переменные не меняют значения из файла", func(t *testing.T) {
+		t.Setenv(PasswordEnv, "env_password")
+
+		config, err := LoadConfig(configPath)
+		require.NoError(t, err)
+
+		settings := config.SoftConfig.TgSettings
+		assert.Equal(t, "file_hash", settings.ApiHash)
+		assert.Equal(t, "env_password", settings.Password)
+		assert.Equal(t, "file_bot_key", settings.TgBotKey)
+		assert.Equal(t, "+1111111111", settings.Phone)
+	})
+
+	t.Run("секрет можно не хранить в файле", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"logger_level": "info", "soft_config": {"tg_settings": {"app_id": 123456, "phone": "+1111111111"}}}`), 0644))
+		t.Setenv(ApiHashEnv, "env_hash")
+
+		config, err := LoadConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, "env_hash", config.SoftConfig.TgSettings.ApiHash)
+	})
+}
@@ -0,0 +1,343 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"gift-buyer/pkg/errors"
+	"net"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	// apiHashPattern matches the 32 hex characters API hash issued by my.telegram.org
+	apiHashPattern = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+	// phonePattern matches a phone number in international format, e.g. +71234567890
+	phonePattern = regexp.MustCompile(`^\+[0-9]{7,15}$`)
+
+	// rateLimitBuckets are the method buckets accepted in rpc_rate_limits
+	rateLimitBuckets = []string{"default", "payment_form", "send_payment", "get_gifts"}
+)
+
+// Validate checks the loaded configuration for common mistakes so that the
+// application fails fast with an actionable message instead of a connection error.
+//
+// Returns:
+//   - error: ErrInvalidConfig describing every detected problem, nil if the configuration is valid
+func (c *AppConfig) Validate() error {
+	var problems []string
+
+	if c.SoftConfig.TgSettings.ValidateCredentials {
+		problems = append(problems, c.SoftConfig.TgSettings.credentialProblems()...)
+	} else {
+		problems = append(problems, c.SoftConfig.TgSettings.missingCredentials()...)
+	}
+
+	if c.SoftConfig.TgSettings.sharesSessionFile() {
+		problems = append(problems, fmt.Sprintf("tg_settings.session_path and tg_settings.bot_session_path must point to different files, both use %q",
+			c.SoftConfig.TgSettings.UserSessionFile()))
+	}
+
+	problems = append(problems, c.SoftConfig.TgSettings.Proxy.problems()...)
+	problems = append(problems, c.SoftConfig.TgSettings.intervalProblems()...)
+	for i, pattern := range c.SoftConfig.TgSettings.CriticalErrorPatterns {
+		if strings.TrimSpace(pattern) == "" {
+			problems = append(problems, fmt.Sprintf("tg_settings.critical_error_patterns[%d] must not be empty, it would match every error", i))
+		}
+	}
+	problems = append(problems, c.SoftConfig.accountProblems()...)
+	problems = append(problems, c.SoftConfig.intervalProblems()...)
+	problems = append(problems, c.SoftConfig.rateLimitProblems()...)
+
+	switch c.SoftConfig.PriorityStrategy {
+	case "", PriorityStarsDesc, PriorityStarsAsc, PriorityRarestFirst, PriorityDiscovery:
+	default:
+		problems = append(problems, fmt.Sprintf("soft_config.priority_strategy must be one of %q, %q, %q or %q, got %q",
+			PriorityStarsDesc, PriorityStarsAsc, PriorityRarestFirst, PriorityDiscovery, c.SoftConfig.PriorityStrategy))
+	}
+
+	switch c.SoftConfig.CacheBackend {
+	case "", CacheBackendFile, CacheBackendSQLite:
+	default:
+		problems = append(problems, fmt.Sprintf("soft_config.cache_backend must be %q or %q, got %q",
+			CacheBackendFile, CacheBackendSQLite, c.SoftConfig.CacheBackend))
+	}
+
+	if c.SoftConfig.GRPC.Enabled {
+		if c.SoftConfig.GRPC.Port <= 0 || c.SoftConfig.GRPC.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("soft_config.grpc.port must be between 1 and 65535 when grpc is enabled, got %d", c.SoftConfig.GRPC.Port))
+		}
+		if c.SoftConfig.ControlToken == "" {
+			problems = append(problems, "soft_config.grpc requires soft_config.control_token, the gRPC API is never served without a token")
+		}
+	}
+
+	if c.SoftConfig.ControlPort > 0 && c.SoftConfig.ControlToken == "" {
+		problems = append(problems, "soft_config.control_port requires soft_config.control_token, the control API is never served without a token")
+	}
+
+	if c.SoftConfig.FixtureGiftsPath != "" && !c.SoftConfig.GiftParam.TestMode {
+		problems = append(problems, "soft_config.fixture_gifts_path requires gift_param.test_mode, recorded gifts are never used for real purchases")
+	}
+
+	if c.SoftConfig.SkipFirstRunWithCache && c.SoftConfig.MaxCachedGifts > 0 && c.SoftConfig.CacheBackend != CacheBackendSQLite {
+		problems = append(problems, fmt.Sprintf("soft_config.skip_first_run_with_cache requires an unbounded cache, got max_cached_gifts %d: "+
+			"evicted gifts are not persisted and would be bought again after a restart", c.SoftConfig.MaxCachedGifts))
+	}
+
+	for i, criteria := range c.SoftConfig.Criterias {
+		problems = append(problems, criteria.problems(i, c.SoftConfig.Receiver)...)
+		if criteria.Buy && criteria.Count == CountMaxAffordable && !c.SoftConfig.CheckBalanceBeforeBuy {
+			problems = append(problems, fmt.Sprintf("criterias[%d].count %d buys as many gifts as the balance allows and requires soft_config.check_balance_before_buy", i, CountMaxAffordable))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.Wrap(errors.ErrInvalidConfig, strings.Join(problems, "; "))
+}
+
+// credentialProblems checks the format of the Telegram credentials.
+//
+// Returns:
+//   - []string: description of every malformed credential with a hint how to fix it
+func (s *TgSettings) credentialProblems() []string {
+	var problems []string
+
+	if s.AppId <= 0 {
+		problems = append(problems, fmt.Sprintf("tg_settings.app_id must be a positive number from my.telegram.org, got %d", s.AppId))
+	}
+
+	switch {
+	case strings.TrimSpace(s.ApiHash) != s.ApiHash:
+		problems = append(problems, "tg_settings.api_hash contains leading or trailing whitespace, remove it")
+	case !apiHashPattern.MatchString(s.ApiHash):
+		problems = append(problems, "tg_settings.api_hash must be the 32 character hex string from my.telegram.org")
+	}
+
+	switch {
+	case strings.TrimSpace(s.Phone) != s.Phone:
+		problems = append(problems, "tg_settings.phone contains leading or trailing whitespace, remove it")
+	case !strings.HasPrefix(s.Phone, "+"):
+		problems = append(problems, fmt.Sprintf("tg_settings.phone must start with + and the country code, e.g. +71234567890, got %q", s.Phone))
+	case !phonePattern.MatchString(s.Phone):
+		problems = append(problems, fmt.Sprintf("tg_settings.phone must contain only digits after +, without spaces or dashes, got %q", s.Phone))
+	}
+
+	return problems
+}
+
+// missingCredentials checks that the Telegram credentials required to connect are set.
+// The phone is not required for the QR code login.
+//
+// Returns:
+//   - []string: description of every missing credential
+func (s *TgSettings) missingCredentials() []string {
+	var problems []string
+
+	if s.AppId <= 0 {
+		problems = append(problems, fmt.Sprintf("tg_settings.app_id must be set to the positive number from my.telegram.org, got %d", s.AppId))
+	}
+	if strings.TrimSpace(s.ApiHash) == "" {
+		problems = append(problems, fmt.Sprintf("tg_settings.api_hash must be set to the hash from my.telegram.org or the %s environment variable", ApiHashEnv))
+	}
+	if strings.TrimSpace(s.Phone) == "" && !strings.EqualFold(s.AuthMethod, AuthMethodQR) {
+		problems = append(problems, fmt.Sprintf("tg_settings.phone must be set, e.g. +71234567890, or provided with the %s environment variable", PhoneEnv))
+	}
+
+	return problems
+}
+
+// sharesSessionFile reports whether the user and bot clients are configured with the same session file.
+func (s *TgSettings) sharesSessionFile() bool {
+	return filepath.Clean(s.UserSessionFile()) == filepath.Clean(s.BotSessionFile())
+}
+
+// accountProblems checks that every additional account has a phone and a session
+// file of its own, so the accounts never overwrite each other's authorization.
+//
+// Returns:
+//   - []string: description of every invalid account
+func (c *SoftConfig) accountProblems() []string {
+	var problems []string
+
+	sessions := map[string]string{
+		filepath.Clean(c.TgSettings.UserSessionFile()): "tg_settings.session_path",
+		filepath.Clean(c.TgSettings.BotSessionFile()):  "tg_settings.bot_session_path",
+	}
+	for i, account := range c.AccountSettings() {
+		if account.Phone == "" {
+			problems = append(problems, fmt.Sprintf("accounts[%d].phone must be set", i))
+		}
+
+		session := filepath.Clean(account.UserSessionFile())
+		if owner, ok := sessions[session]; ok {
+			problems = append(problems, fmt.Sprintf("accounts[%d].session_path must point to its own file, %q is already used by %s", i, account.UserSessionFile(), owner))
+			continue
+		}
+		sessions[session] = fmt.Sprintf("accounts[%d].session_path", i)
+	}
+	return problems
+}
+
+// problems checks the proxy settings.
+//
+// Returns:
+//   - []string: description of every invalid proxy setting
+func (p ProxyParams) problems() []string {
+	if !p.Enabled() {
+		return nil
+	}
+
+	var problems []string
+	if _, _, err := net.SplitHostPort(p.Address); err != nil {
+		problems = append(problems, fmt.Sprintf("tg_settings.proxy.address must be host:port, got %q", p.Address))
+	}
+
+	switch strings.ToLower(p.Type) {
+	case "", ProxyTypeSOCKS5:
+	case ProxyTypeMTProxy:
+		if _, err := hex.DecodeString(p.Secret); err != nil || p.Secret == "" {
+			problems = append(problems, "tg_settings.proxy.secret must be the hex encoded MTProxy secret")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("tg_settings.proxy.type must be %q or %q, got %q", ProxyTypeSOCKS5, ProxyTypeMTProxy, p.Type))
+	}
+	return problems
+}
+
+// intervalProblems checks that the API check and reconnect settings are not negative.
+//
+// Returns:
+//   - []string: description of every negative setting
+func (s *TgSettings) intervalProblems() []string {
+	settings := []struct {
+		name  string
+		value float64
+	}{
+		{"api_stale_timeout", s.ApiStaleTimeout},
+		{"reconnect_max_attempts", float64(s.ReconnectMaxAttempts)},
+		{"reconnect_base_delay", s.ReconnectBaseDelay},
+	}
+
+	var problems []string
+	for _, setting := range settings {
+		if setting.value < 0 {
+			problems = append(problems, fmt.Sprintf("tg_settings.%s must not be negative, got %v", setting.name, setting.value))
+		}
+	}
+	return problems
+}
+
+// intervalProblems checks that the tickers and retry settings are not negative.
+//
+// Returns:
+//   - []string: description of every negative setting
+func (c *SoftConfig) intervalProblems() []string {
+	settings := []struct {
+		name  string
+		value float64
+	}{
+		{"ticker", c.Ticker},
+		{"update_ticker", c.UpdateTicker},
+		{"max_monitor_backoff", c.MaxMonitorBackoff},
+		{"gift_fetch_timeout", c.GiftFetchTimeout},
+		{"retry_count", float64(c.RetryCount)},
+		{"retry_delay", c.RetryDelay},
+		{"init_retries", float64(c.InitRetries)},
+		{"purchase_spacing_ms", float64(c.PurchaseSpacingMs)},
+		{"launch_jitter_ms", float64(c.LaunchJitterMs)},
+		{"cache_ttl", c.CacheTTL},
+		{"error_notify_cooldown", c.ErrorNotifyCooldown},
+		{"circuit_breaker_threshold", float64(c.CircuitBreakerThreshold)},
+		{"circuit_breaker_cooldown", c.CircuitBreakerCooldown},
+		{"session_state_ttl", c.SessionStateTTL},
+	}
+
+	var problems []string
+	for _, setting := range settings {
+		if setting.value < 0 {
+			problems = append(problems, fmt.Sprintf("soft_config.%s must not be negative, got %v", setting.name, setting.value))
+		}
+	}
+	return problems
+}
+
+// problems checks the price range of the criteria and, for criteria that buy gifts,
+// the purchase count and the receivers of every referenced receiver type.
+//
+// Parameters:
+//   - index: position of the criteria in soft_config.criterias, used in the messages
+//   - receiver: configured receivers
+//
+// Returns:
+//   - []string: description of every invalid criteria setting
+func (c Criterias) problems(index int, receiver ReceiverParams) []string {
+	var problems []string
+
+	if c.MinPrice < 0 || c.MaxPrice < 0 {
+		problems = append(problems, fmt.Sprintf("criterias[%d] prices must not be negative, got min_price %d and max_price %d", index, c.MinPrice, c.MaxPrice))
+	} else if c.MinPrice > c.MaxPrice {
+		problems = append(problems, fmt.Sprintf("criterias[%d].min_price %d must not exceed max_price %d", index, c.MinPrice, c.MaxPrice))
+	}
+
+	if !c.Buy {
+		return problems
+	}
+
+	if c.Count <= 0 && c.Count != CountMaxAffordable && len(c.Stages) == 0 && !c.BroadcastToAllReceivers {
+		problems = append(problems, fmt.Sprintf("criterias[%d].count must be positive to buy gifts, got %d; set buy to false to only get notifications", index, c.Count))
+	}
+
+	for i, stage := range c.Stages {
+		if stage.Count < 1 {
+			problems = append(problems, fmt.Sprintf("criterias[%d].stages[%d].count must be at least 1, got %d", index, i, stage.Count))
+		}
+		switch stage.Condition {
+		case "", StageConditionPreviousSucceeded, StageConditionAlways:
+		default:
+			problems = append(problems, fmt.Sprintf("criterias[%d].stages[%d].condition must be %q or %q, got %q",
+				index, i, StageConditionPreviousSucceeded, StageConditionAlways, stage.Condition))
+		}
+	}
+
+	seen := make(map[int]bool, len(c.ReceiverType))
+	for _, receiverType := range c.ReceiverType {
+		if seen[receiverType] {
+			continue
+		}
+		seen[receiverType] = true
+
+		switch {
+		case receiverType == 1 && len(receiver.UserReceiverID) == 0:
+			problems = append(problems, fmt.Sprintf("criterias[%d].receiver_type 1 requires at least one receiver in receiver.user_receiver_id", index))
+		case receiverType == 2 && len(receiver.ChannelReceiverID) == 0:
+			problems = append(problems, fmt.Sprintf("criterias[%d].receiver_type 2 requires at least one receiver in receiver.channel_receiver_id", index))
+		}
+	}
+	return problems
+}
+
+// rateLimitProblems checks that rpc_rate_limits only names known buckets.
+//
+// Returns:
+//   - []string: description of every unknown bucket or negative limit
+func (c *SoftConfig) rateLimitProblems() []string {
+	var problems []string
+	for name, limit := range c.RPCRateLimits {
+		if !slices.Contains(rateLimitBuckets, name) {
+			problems = append(problems, fmt.Sprintf("soft_config.rpc_rate_limits has unknown method %q, expected one of %s", name, strings.Join(rateLimitBuckets, ", ")))
+			continue
+		}
+		if limit < 0 {
+			problems = append(problems, fmt.Sprintf("soft_config.rpc_rate_limits.%s must not be negative, got %d", name, limit))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
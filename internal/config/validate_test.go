@@ -0,0 +1,565 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gift-buyer/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validCredentials() TgSettings {
+	return TgSettings{
+		AppId:               123456,
+		ApiHash:             "0123456789abcdef0123456789ABCDEF",
+		Phone:               "+71234567890",
+		ValidateCredentials: true,
+	}
+}
+
+func TestAppConfig_Validate_Credentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(s *TgSettings)
+		message string
+	}{
+		{
+			name:    "нулевой app_id",
+			modify:  func(s *TgSettings) { s.AppId = 0 },
+			message: "app_id must be a positive number",
+		},
+		{
+			name:    "отрицательный app_id",
+			modify:  func(s *TgSettings) { s.AppId = -5 },
+			message: "app_id must be a positive number",
+		},
+		{
+			name:    "api_hash с пробелами",
+			modify:  func(s *TgSettings) { s.ApiHash = " 0123456789abcdef0123456789abcdef\n" },
+			message: "api_hash contains leading or trailing whitespace",
+		},
+		{
+			name:    "короткий api_hash",
+			modify:  func(s *TgSettings) { s.ApiHash = "0123456789abcdef" },
+			message: "api_hash must be the 32 character hex string",
+		},
+		{
+			name:    "api_hash не в hex",
+			modify:  func(s *TgSettings) { s.ApiHash = "qwertyuiopasdfghjklzxcvbnmqwerty" },
+			message: "api_hash must be the 32 character hex string",
+		},
+		{
+			name:    "телефон без кода страны",
+			modify:  func(s *TgSettings) { s.Phone = "71234567890" },
+			message: "phone must start with + and the country code",
+		},
+		{
+			name:    "телефон с дефисами",
+			modify:  func(s *TgSettings) { s.Phone = "+7-123-456-78-90" },
+			message: "phone must contain only digits after +",
+		},
+		{
+			name:    "телефон с пробелами",
+			modify:  func(s *TgSettings) { s.Phone = "+71234567890 " },
+			message: "phone contains leading or trailing whitespace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := validCredentials()
+			tt.modify(&settings)
+			cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: settings}}
+
+			err := cfg.Validate()
+
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestAppConfig_Validate_ValidCredentials(t *testing.T) {
+	cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials()}}
+
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestAppConfig_Validate_AllProblemsReported(t *testing.T) {
+	cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: TgSettings{ValidateCredentials: true}}}
+
+	err := cfg.Validate()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "app_id")
+	assert.Contains(t, err.Error(), "api_hash")
+	assert.Contains(t, err.Error(), "phone")
+}
+
+func TestAppConfig_Validate_CredentialsCheckDisabled(t *testing.T) {
+	settings := validCredentials()
+	settings.ValidateCredentials = false
+	settings.ApiHash = "not a hash"
+	cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: settings}}
+
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoadConfig_InvalidCredentials(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	data := `{"soft_config": {"tg_settings": {"app_id": 1, "api_hash": "abc", "phone": "+71234567890", "validate_credentials": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(data), 0644))
+
+	cfg, err := LoadConfig(configPath)
+
+	assert.Nil(t, cfg)
+	assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+}
+
+func TestAppConfig_Validate_SessionPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		bot     string
+		wantErr bool
+	}{
+		{name: "пути по умолчанию", wantErr: false},
+		{name: "разные пути", user: "user.json", bot: "bot.json", wantErr: false},
+		{name: "одинаковые пути", user: "shared.json", bot: "shared.json", wantErr: true},
+		{name: "одинаковые пути в разной записи", user: "./sessions/shared.json", bot: "sessions//shared.json", wantErr: true},
+		{name: "бот использует путь аккаунта по умолчанию", bot: DefaultSessionPath, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := validCredentials()
+			settings.SessionPath, settings.BotSessionPath = tt.user, tt.bot
+			cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: settings}}
+
+			err := cfg.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+				assert.Contains(t, err.Error(), "bot_session_path")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAppConfig_Validate_Proxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxy   ProxyParams
+		message string
+	}{
+		{name: "прокси не задан", proxy: ProxyParams{}},
+		{name: "socks5 по умолчанию", proxy: ProxyParams{Address: "127.0.0.1:1080"}},
+		{name: "mtproxy", proxy: ProxyParams{Type: ProxyTypeMTProxy, Address: "proxy.example.com:443", Secret: "00112233445566778899aabbccddeeff"}},
+		{name: "адрес без порта", proxy: ProxyParams{Address: "127.0.0.1"}, message: "tg_settings.proxy.address"},
+		{name: "секрет не в hex", proxy: ProxyParams{Type: ProxyTypeMTProxy, Address: "proxy.example.com:443", Secret: "secret"}, message: "tg_settings.proxy.secret"},
+		{name: "неизвестный тип", proxy: ProxyParams{Type: "http", Address: "127.0.0.1:8080"}, message: "tg_settings.proxy.type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := validCredentials()
+			settings.Proxy = tt.proxy
+			cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: settings}}
+
+			err := cfg.Validate()
+			if tt.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestAppConfig_Validate_CriticalErrorPatterns(t *testing.T) {
+	t.Run("настроенные шаблоны", func(t *testing.T) {
+		settings := validCredentials()
+		settings.CriticalErrorPatterns = []string{"USER_DEACTIVATED", "connection reset"}
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: settings}}
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("пустой шаблон", func(t *testing.T) {
+		settings := validCredentials()
+		settings.CriticalErrorPatterns = []string{"USER_DEACTIVATED", " "}
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: settings}}
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "tg_settings.critical_error_patterns[1] must not be empty")
+	})
+}
+
+func TestAppConfig_Validate_Accounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		accounts []TgSettings
+		message  string
+	}{
+		{name: "без дополнительных аккаунтов"},
+		{name: "пути сессий по умолчанию", accounts: []TgSettings{{Phone: "+71111111111"}, {Phone: "+72222222222"}}},
+		{name: "аккаунт без телефона", accounts: []TgSettings{{SessionPath: "second.json"}}, message: "accounts[0].phone"},
+		{name: "сессия основного аккаунта", accounts: []TgSettings{{Phone: "+71111111111", SessionPath: DefaultSessionPath}}, message: "accounts[0].session_path"},
+		{
+			name:     "одинаковые сессии аккаунтов",
+			accounts: []TgSettings{{Phone: "+71111111111", SessionPath: "shared.json"}, {Phone: "+72222222222", SessionPath: "./shared.json"}},
+			message:  "accounts[1].session_path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), Accounts: tt.accounts}}
+
+			err := cfg.Validate()
+			if tt.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestSoftConfig_AccountSettings(t *testing.T) {
+	cfg := &SoftConfig{
+		TgSettings: TgSettings{AppId: 1, ApiHash: "hash", Datacenter: 4, Proxy: ProxyParams{Address: "127.0.0.1:1080"},
+			ApiStaleTimeout: 60, ReconnectMaxAttempts: 3, ReconnectBaseDelay: 5, NotifyReconnect: true},
+		Accounts: []TgSettings{
+			{Phone: "+71111111111"},
+			{Phone: "+72222222222", AppId: 2, ApiHash: "other", SessionPath: "second.json", ReconnectMaxAttempts: 10},
+		},
+	}
+
+	accounts := cfg.AccountSettings()
+
+	require.Len(t, accounts, 2)
+	assert.Equal(t, 1, accounts[0].AppId)
+	assert.Equal(t, "hash", accounts[0].ApiHash)
+	assert.Equal(t, 4, accounts[0].Datacenter)
+	assert.Equal(t, "127.0.0.1:1080", accounts[0].Proxy.Address)
+	assert.Equal(t, "session_2.json", accounts[0].UserSessionFile())
+	assert.Equal(t, 2, accounts[1].AppId)
+	assert.Equal(t, "other", accounts[1].ApiHash)
+	assert.Equal(t, "second.json", accounts[1].UserSessionFile())
+	assert.Equal(t, 60.0, accounts[0].ApiStaleTimeout)
+	assert.Equal(t, 3, accounts[0].ReconnectMaxAttempts)
+	assert.Equal(t, 5.0, accounts[0].ReconnectBaseDelay)
+	assert.True(t, accounts[0].NotifyReconnect)
+	assert.Equal(t, 10, accounts[1].ReconnectMaxAttempts)
+	assert.Empty(t, cfg.Accounts[0].SessionPath, "original accounts must not change")
+}
+
+func TestAppConfig_Validate_RequiredCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings TgSettings
+		message  string
+	}{
+		{name: "все данные заданы", settings: TgSettings{AppId: 1, ApiHash: "hash", Phone: "+71234567890"}},
+		{name: "вход по QR коду без телефона", settings: TgSettings{AppId: 1, ApiHash: "hash", AuthMethod: AuthMethodQR}},
+		{name: "нет app_id", settings: TgSettings{ApiHash: "hash", Phone: "+71234567890"}, message: "tg_settings.app_id must be set"},
+		{name: "нет api_hash", settings: TgSettings{AppId: 1, ApiHash: "  ", Phone: "+71234567890"}, message: "tg_settings.api_hash must be set"},
+		{name: "нет телефона", settings: TgSettings{AppId: 1, ApiHash: "hash"}, message: "tg_settings.phone must be set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: tt.settings}}
+
+			err := cfg.Validate()
+			if tt.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestAppConfig_Validate_Criterias(t *testing.T) {
+	buying := func(modify func(c *Criterias)) Criterias {
+		criteria := Criterias{MinPrice: 10, MaxPrice: 100, Count: 1, ReceiverType: []int{1, 2}, Notify: true, Buy: true}
+		modify(&criteria)
+		return criteria
+	}
+	receivers := ReceiverParams{UserReceiverID: []string{"user"}, ChannelReceiverID: []string{"channel"}}
+
+	tests := []struct {
+		name     string
+		criteria Criterias
+		receiver ReceiverParams
+		message  string
+	}{
+		{name: "корректный критерий", criteria: buying(func(c *Criterias) {}), receiver: receivers},
+		{name: "равные цены", criteria: buying(func(c *Criterias) { c.MinPrice = 100 }), receiver: receivers},
+		{name: "отрицательная цена", criteria: buying(func(c *Criterias) { c.MinPrice = -1 }), receiver: receivers, message: "criterias[0] prices must not be negative"},
+		{name: "min_price больше max_price", criteria: buying(func(c *Criterias) { c.MinPrice = 500 }), receiver: receivers, message: "criterias[0].min_price 500 must not exceed max_price 100"},
+		{name: "нулевой count", criteria: buying(func(c *Criterias) { c.Count = 0 }), receiver: receivers, message: "criterias[0].count must be positive"},
+		{name: "отрицательный count", criteria: buying(func(c *Criterias) { c.Count = -2 }), receiver: receivers, message: "criterias[0].count must be positive"},
+		{name: "count из этапов", criteria: buying(func(c *Criterias) { c.Count = 0; c.Stages = []StageParams{{Count: 2}} }), receiver: receivers},
+		{name: "условия этапов", criteria: buying(func(c *Criterias) {
+			c.Stages = []StageParams{{Count: 1}, {Count: 2, Condition: StageConditionAlways}, {Count: 3, Condition: StageConditionPreviousSucceeded}}
+		}), receiver: receivers},
+		{name: "пустой этап", criteria: buying(func(c *Criterias) { c.Stages = []StageParams{{Count: 1}, {Count: 0}} }), receiver: receivers, message: "criterias[0].stages[1].count must be at least 1, got 0"},
+		{name: "неизвестное условие этапа", criteria: buying(func(c *Criterias) { c.Stages = []StageParams{{Count: 1, Condition: "sometimes"}} }), receiver: receivers, message: `criterias[0].stages[0].condition must be "previous_succeeded" or "always", got "sometimes"`},
+		{name: "покупка всем получателям без count", criteria: buying(func(c *Criterias) { c.Count = 0; c.BroadcastToAllReceivers = true }), receiver: receivers},
+		{name: "только уведомления без count и получателей", criteria: buying(func(c *Criterias) { c.Count = 0; c.Buy = false })},
+		{name: "нет пользователей", criteria: buying(func(c *Criterias) {}), receiver: ReceiverParams{ChannelReceiverID: []string{"channel"}}, message: "criterias[0].receiver_type 1 requires at least one receiver in receiver.user_receiver_id"},
+		{name: "нет каналов", criteria: buying(func(c *Criterias) {}), receiver: ReceiverParams{UserReceiverID: []string{"user"}}, message: "criterias[0].receiver_type 2 requires at least one receiver in receiver.channel_receiver_id"},
+		{name: "покупка себе без получателей", criteria: buying(func(c *Criterias) { c.ReceiverType = []int{0} })},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), Criterias: []Criterias{tt.criteria}, Receiver: tt.receiver}}
+
+			err := cfg.Validate()
+			if tt.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestAppConfig_Validate_MaxAffordableCount(t *testing.T) {
+	criteria := Criterias{MinPrice: 10, MaxPrice: 100, Count: CountMaxAffordable, ReceiverType: []int{0}, Notify: true, Buy: true}
+
+	t.Run("с проверкой баланса", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), Criterias: []Criterias{criteria}, CheckBalanceBeforeBuy: true}}
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("без проверки баланса", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), Criterias: []Criterias{criteria}}}
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "criterias[0].count -1 buys as many gifts as the balance allows and requires soft_config.check_balance_before_buy")
+	})
+}
+
+func TestAppConfig_Validate_Intervals(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *SoftConfig)
+		message string
+	}{
+		{name: "нулевые значения", modify: func(c *SoftConfig) {}},
+		{name: "отрицательный ticker", modify: func(c *SoftConfig) { c.Ticker = -1 }, message: "soft_config.ticker must not be negative"},
+		{name: "отрицательный update_ticker", modify: func(c *SoftConfig) { c.UpdateTicker = -60 }, message: "soft_config.update_ticker must not be negative"},
+		{name: "отрицательный max_monitor_backoff", modify: func(c *SoftConfig) { c.MaxMonitorBackoff = -5 }, message: "soft_config.max_monitor_backoff must not be negative"},
+		{name: "отрицательный gift_fetch_timeout", modify: func(c *SoftConfig) { c.GiftFetchTimeout = -1 }, message: "soft_config.gift_fetch_timeout must not be negative"},
+		{name: "отрицательный retry_count", modify: func(c *SoftConfig) { c.RetryCount = -3 }, message: "soft_config.retry_count must not be negative"},
+		{name: "отрицательный retry_delay", modify: func(c *SoftConfig) { c.RetryDelay = -0.5 }, message: "soft_config.retry_delay must not be negative"},
+		{name: "отрицательный init_retries", modify: func(c *SoftConfig) { c.InitRetries = -1 }, message: "soft_config.init_retries must not be negative"},
+		{name: "отрицательный purchase_spacing_ms", modify: func(c *SoftConfig) { c.PurchaseSpacingMs = -100 }, message: "soft_config.purchase_spacing_ms must not be negative"},
+		{name: "отрицательный launch_jitter_ms", modify: func(c *SoftConfig) { c.LaunchJitterMs = -10 }, message: "soft_config.launch_jitter_ms must not be negative"},
+		{name: "отрицательный cache_ttl", modify: func(c *SoftConfig) { c.CacheTTL = -1 }, message: "soft_config.cache_ttl must not be negative"},
+		{name: "отрицательный error_notify_cooldown", modify: func(c *SoftConfig) { c.ErrorNotifyCooldown = -30 }, message: "soft_config.error_notify_cooldown must not be negative"},
+		{name: "отрицательный circuit_breaker_threshold", modify: func(c *SoftConfig) { c.CircuitBreakerThreshold = -1 }, message: "soft_config.circuit_breaker_threshold must not be negative"},
+		{name: "отрицательный circuit_breaker_cooldown", modify: func(c *SoftConfig) { c.CircuitBreakerCooldown = -60 }, message: "soft_config.circuit_breaker_cooldown must not be negative"},
+		{name: "отрицательный session_state_ttl", modify: func(c *SoftConfig) { c.SessionStateTTL = -1 }, message: "soft_config.session_state_ttl must not be negative"},
+		{name: "отрицательный api_stale_timeout", modify: func(c *SoftConfig) { c.TgSettings.ApiStaleTimeout = -5 }, message: "tg_settings.api_stale_timeout must not be negative"},
+		{name: "отрицательный reconnect_max_attempts", modify: func(c *SoftConfig) { c.TgSettings.ReconnectMaxAttempts = -1 }, message: "tg_settings.reconnect_max_attempts must not be negative"},
+		{name: "отрицательный reconnect_base_delay", modify: func(c *SoftConfig) { c.TgSettings.ReconnectBaseDelay = -2 }, message: "tg_settings.reconnect_base_delay must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials()}}
+			tt.modify(&cfg.SoftConfig)
+
+			err := cfg.Validate()
+			if tt.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestLoadConfig_InvalidCriterias(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	data := `{"soft_config": {"tg_settings": {"app_id": 1, "api_hash": "hash", "phone": "+71234567890"},
+		"criterias": [{"min_price": 1000, "max_price": 100, "count": 1}, {"min_price": 10, "max_price": 100, "receiver_type": [1]}]}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(data), 0644))
+
+	cfg, err := LoadConfig(configPath)
+
+	assert.Nil(t, cfg)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+	assert.Contains(t, err.Error(), "criterias[0].min_price 1000 must not exceed max_price 100")
+	assert.Contains(t, err.Error(), "criterias[1].count must be positive")
+	assert.Contains(t, err.Error(), "criterias[1].receiver_type 1 requires")
+}
+
+func TestAppConfig_Validate_PriorityStrategy(t *testing.T) {
+	for _, strategy := range []string{"", PriorityStarsDesc, PriorityStarsAsc, PriorityRarestFirst, PriorityDiscovery} {
+		t.Run("допустимая стратегия "+strategy, func(t *testing.T) {
+			cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), PriorityStrategy: strategy}}
+
+			assert.NoError(t, cfg.Validate())
+		})
+	}
+
+	t.Run("неизвестная стратегия", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), PriorityStrategy: "random"}}
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+		assert.Contains(t, err.Error(), "soft_config.priority_strategy")
+	})
+}
+
+func TestAppConfig_Validate_CacheBackend(t *testing.T) {
+	for _, backend := range []string{"", CacheBackendFile, CacheBackendSQLite} {
+		t.Run("допустимое хранилище "+backend, func(t *testing.T) {
+			cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), CacheBackend: backend}}
+
+			assert.NoError(t, cfg.Validate())
+		})
+	}
+
+	t.Run("неизвестное хранилище", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), CacheBackend: "redis"}}
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+		assert.Contains(t, err.Error(), "soft_config.cache_backend")
+	})
+}
+
+func TestAppConfig_Validate_FixtureGiftsPath(t *testing.T) {
+	t.Run("в тестовом режиме", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), FixtureGiftsPath: "gifts.json", GiftParam: GiftParam{TestMode: true}}}
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("без тестового режима", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), FixtureGiftsPath: "gifts.json"}}
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "soft_config.fixture_gifts_path requires gift_param.test_mode")
+	})
+}
+
+func TestAppConfig_Validate_GRPC(t *testing.T) {
+	t.Run("с портом и токеном", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), ControlToken: "secret", GRPC: GRPCParams{Enabled: true, Port: 9090}}}
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("выключен без порта", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials()}}
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("без порта и токена", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), GRPC: GRPCParams{Enabled: true}}}
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "soft_config.grpc.port must be between 1 and 65535")
+		assert.Contains(t, err.Error(), "soft_config.grpc requires soft_config.control_token")
+	})
+}
+
+func TestAppConfig_Validate_ControlPort(t *testing.T) {
+	t.Run("с портом и токеном", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), ControlPort: 8081, ControlToken: "secret"}}
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("порт без токена", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), ControlPort: 8081}}
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "soft_config.control_port requires soft_config.control_token")
+	})
+}
+
+func TestAppConfig_Validate_SkipFirstRunWithCache(t *testing.T) {
+	t.Run("с неограниченным кэшем", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), SkipFirstRunWithCache: true}}
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("с ограниченным кэшем", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), SkipFirstRunWithCache: true, MaxCachedGifts: 100}}
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "soft_config.skip_first_run_with_cache requires an unbounded cache")
+	})
+
+	t.Run("sqlite не ограничивается max_cached_gifts", func(t *testing.T) {
+		cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), SkipFirstRunWithCache: true, MaxCachedGifts: 100, CacheBackend: CacheBackendSQLite}}
+
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestAppConfig_Validate_RPCRateLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  map[string]int
+		message string
+	}{
+		{name: "без лимитов методов"},
+		{name: "известные методы", limits: map[string]int{"payment_form": 10, "send_payment": 5, "get_gifts": 0, "default": 20}},
+		{name: "неизвестный метод", limits: map[string]int{"buy_gift": 5}, message: `soft_config.rpc_rate_limits has unknown method "buy_gift"`},
+		{name: "отрицательный лимит", limits: map[string]int{"send_payment": -1}, message: "soft_config.rpc_rate_limits.send_payment must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AppConfig{SoftConfig: SoftConfig{TgSettings: validCredentials(), RPCRateLimits: tt.limits}}
+
+			err := cfg.Validate()
+			if tt.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errors.ErrInvalidConfig))
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
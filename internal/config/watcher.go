@@ -0,0 +1,103 @@
+package config
+
+import (
+	"context"
+	"gift-buyer/pkg/logger"
+	"os"
+	"time"
+)
+
+// Watcher polls the configuration file and delivers the reloaded configuration
+// every time the file changes.
+type Watcher struct {
+	// path is the watched configuration file
+	path string
+
+	// interval is the polling interval of the file modification time
+	interval time.Duration
+
+	// updates delivers the reloaded configuration
+	updates chan *AppConfig
+
+	// modTime and size identify the last seen version of the file
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher creates a watcher of the configuration file. The current version of the
+// file is treated as already loaded, only later changes are delivered.
+//
+// Parameters:
+//   - path: filesystem path to the configuration JSON file
+//   - interval: polling interval of the file modification time
+//
+// Returns:
+//   - *Watcher: configuration watcher, start it with Run
+func NewWatcher(path string, interval time.Duration) *Watcher {
+	w := &Watcher{
+		path:     path,
+		interval: interval,
+		updates:  make(chan *AppConfig, 1),
+	}
+	if info, err := os.Stat(path); err == nil {
+		w.modTime, w.size = info.ModTime(), info.Size()
+	}
+	return w
+}
+
+// Updates returns the channel delivering the reloaded configuration.
+func (w *Watcher) Updates() <-chan *AppConfig {
+	return w.updates
+}
+
+// Run polls the configuration file until the context is cancelled. A changed file
+// is loaded with LoadConfig; a file that fails to load is logged and skipped, so a
+// half-written or broken file never replaces the running configuration.
+//
+// Parameters:
+//   - ctx: context stopping the watcher
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cfg, ok := w.check()
+			if !ok {
+				continue
+			}
+			select {
+			case w.updates <- cfg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// check reloads the configuration file when its modification time or size changed.
+//
+// Returns:
+//   - *AppConfig: reloaded configuration
+//   - bool: true if the file changed and was loaded successfully
+func (w *Watcher) check() (*AppConfig, bool) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		logger.GlobalLogger.Warnf("Failed to check config file: %v", err)
+		return nil, false
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return nil, false
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+
+	cfg, err := LoadConfig(w.path)
+	if err != nil {
+		logger.GlobalLogger.Errorf("Config file changed but can't be applied: %v", err)
+		return nil, false
+	}
+	return cfg, true
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeWatchedConfig(t *testing.T, path, criterias string, maxBuyCount int, modTime time.Time) {
+	data := `{"logger_level": "info", "soft_config": {"tg_settings": {"app_id": 123456, "api_hash": "test_hash", "phone": "+1234567890"}, ` +
+		`"max_buy_count": ` + strconv.Itoa(maxBuyCount) + `, "criterias": ` + criterias + `}}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	// Some filesystems keep a coarse modification time, move it explicitly
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestWatcher(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+
+	t.Run("изменение файла доставляет новую конфигурацию", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeWatchedConfig(t, path, `[{"min_price": 10, "max_price": 100, "count": 1}]`, 5, start)
+
+		watcher := NewWatcher(path, 10*time.Millisecond)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go watcher.Run(ctx)
+
+		writeWatchedConfig(t, path, `[{"min_price": 10, "max_price": 100, "count": 1}, {"min_price": 500, "max_price": 1000, "count": 3}]`, 20, start.Add(time.Minute))
+
+		select {
+		case cfg := <-watcher.Updates():
+			require.Len(t, cfg.SoftConfig.Criterias, 2)
+			assert.Equal(t, int64(500), cfg.SoftConfig.Criterias[1].MinPrice)
+			assert.Equal(t, int64(3), cfg.SoftConfig.Criterias[1].Count)
+			assert.Equal(t, int64(20), cfg.SoftConfig.MaxBuyCount)
+		case <-time.After(time.Second):
+			t.Fatal("reloaded config was not delivered")
+		}
+	})
+
+	t.Run("без изменений ничего не доставляется", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeWatchedConfig(t, path, `[]`, 5, start)
+
+		watcher := NewWatcher(path, 10*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		watcher.Run(ctx)
+
+		assert.Empty(t, watcher.Updates())
+	})
+
+	t.Run("сломанный файл пропускается", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeWatchedConfig(t, path, `[]`, 5, start)
+		watcher := NewWatcher(path, time.Hour)
+
+		require.NoError(t, os.WriteFile(path, []byte(`{"soft_config": {`), 0644))
+		require.NoError(t, os.Chtimes(path, start.Add(time.Minute), start.Add(time.Minute)))
+		_, ok := watcher.check()
+		assert.False(t, ok)
+
+		// The fixed file is picked up on the next change
+		writeWatchedConfig(t, path, `[]`, 7, start.Add(2*time.Minute))
+		cfg, ok := watcher.check()
+		require.True(t, ok)
+		assert.Equal(t, int64(7), cfg.SoftConfig.MaxBuyCount)
+	})
+}
@@ -0,0 +1,224 @@
+// Package controlServer provides a small authenticated local HTTP API for scripted
+// control of the gift buying service: pausing, resuming, reloading and stopping it,
+// and querying its status.
+package controlServer
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"gift-buyer/pkg/errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Controller defines the service actions exposed through the control API.
+type Controller interface {
+	// Pause pauses gift monitoring and buying.
+	Pause()
+
+	// Resume resumes gift monitoring and buying.
+	Resume()
+
+	// PauseBuying keeps monitoring and notifications running but stops buying gifts.
+	PauseBuying()
+
+	// Reload reloads the configuration from disk.
+	Reload() error
+
+	// Stop gracefully stops the application.
+	Stop()
+
+	// Status returns the current state of the service.
+	Status() Status
+}
+
+// Status is the service state returned by GET /status.
+type Status struct {
+	// Paused reports whether gift monitoring is paused
+	Paused bool `json:"paused"`
+
+	// BuyingPaused reports whether buying is paused while monitoring continues
+	BuyingPaused bool `json:"buying_paused"`
+
+	// Bought is the number of gifts bought in the current session
+	Bought int64 `json:"bought"`
+
+	// MaxBuyCount is the configured purchase limit
+	MaxBuyCount int64 `json:"max_buy_count"`
+}
+
+// InfoLogger logs informational messages
+type InfoLogger interface {
+	LogInfo(message string)
+}
+
+// ErrorLogger logs error messages
+type ErrorLogger interface {
+	LogError(message string)
+}
+
+// tokenHeader is an alternative to the "Authorization: Bearer <token>" header
+const tokenHeader = "X-Control-Token"
+
+// ControlServer serves the control API on a local address.
+type ControlServer struct {
+	addr       string
+	token      string
+	controller Controller
+	mux        *http.ServeMux
+
+	infoLogsWriter  InfoLogger
+	errorLogsWriter ErrorLogger
+}
+
+// NewControlServer creates a new control API server.
+//
+// Parameters:
+//   - addr: listen address, e.g. "127.0.0.1:8081"
+//   - token: secret token required in every request
+//   - controller: service actions triggered by the endpoints
+//   - infoLogsWriter: logger for informational messages
+//   - errorLogsWriter: logger for error messages
+//
+// Returns:
+//   - *ControlServer: configured control server
+func NewControlServer(addr, token string, controller Controller, infoLogsWriter InfoLogger, errorLogsWriter ErrorLogger) *ControlServer {
+	cs := &ControlServer{
+		addr:            addr,
+		token:           token,
+		controller:      controller,
+		mux:             http.NewServeMux(),
+		infoLogsWriter:  infoLogsWriter,
+		errorLogsWriter: errorLogsWriter,
+	}
+
+	cs.handleAction("/pause", func() error {
+		cs.controller.Pause()
+		return nil
+	})
+	cs.handleAction("/resume", func() error {
+		cs.controller.Resume()
+		return nil
+	})
+	cs.handleAction("/pause-buying", func() error {
+		cs.controller.PauseBuying()
+		return nil
+	})
+	cs.handleAction("/reload", func() error {
+		return cs.controller.Reload()
+	})
+	cs.handleAction("/stop", func() error {
+		// Stop asynchronously so the response reaches the client before shutdown
+		go cs.controller.Stop()
+		return nil
+	})
+	cs.mux.HandleFunc("/status", cs.handleStatus)
+
+	return cs
+}
+
+// Handler returns the HTTP handler of the control API.
+func (cs *ControlServer) Handler() http.Handler {
+	return cs.mux
+}
+
+// Start serves the control API until the context is cancelled.
+// The server refuses to start without a token so that the API is never left unprotected.
+//
+// Parameters:
+//   - ctx: context controlling the server lifetime
+//
+// Returns:
+//   - error: listen error or missing token
+func (cs *ControlServer) Start(ctx context.Context) error {
+	if cs.token == "" {
+		return errors.Wrap(errors.ErrInvalidParams, "control API token is not configured")
+	}
+
+	listener, err := net.Listen("tcp", cs.addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to start control API")
+	}
+
+	server := &http.Server{
+		Handler:           cs.mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	cs.infoLogsWriter.LogInfo(fmt.Sprintf("Control API listening on %s", listener.Addr()))
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return errors.Wrap(err, "control API stopped")
+	}
+	return nil
+}
+
+// handleAction registers a POST endpoint that runs the action for authorized requests.
+func (cs *ControlServer) handleAction(path string, action func() error) {
+	cs.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		if !cs.authorized(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		if err := action(); err != nil {
+			cs.errorLogsWriter.LogError(fmt.Sprintf("control API %s failed: %v", path, err))
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+
+		cs.infoLogsWriter.LogInfo(fmt.Sprintf("control API: %s", path))
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+}
+
+// handleStatus serves GET /status with the current state of the service.
+func (cs *ControlServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !cs.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cs.controller.Status())
+}
+
+// authorized checks the request token in constant time.
+func (cs *ControlServer) authorized(r *http.Request) bool {
+	if cs.token == "" {
+		return false
+	}
+
+	token := r.Header.Get(tokenHeader)
+	if auth := r.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(cs.token)) == 1
+}
+
+// writeJSON writes a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,199 @@
+package controlServer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeController records the actions triggered through the API
+type fakeController struct {
+	mu        sync.Mutex
+	actions   []string
+	reloadErr error
+	stopped   chan struct{}
+	paused    bool
+}
+
+func newFakeController() *fakeController {
+	return &fakeController{stopped: make(chan struct{}, 1)}
+}
+
+func (c *fakeController) record(action string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = append(c.actions, action)
+}
+
+func (c *fakeController) recorded() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.actions...)
+}
+
+func (c *fakeController) PauseBuying() { c.record("pause-buying") }
+
+func (c *fakeController) Pause() {
+	c.record("pause")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = true
+}
+
+func (c *fakeController) Resume() {
+	c.record("resume")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = false
+}
+
+func (c *fakeController) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Status{Paused: c.paused, Bought: 2, MaxBuyCount: 5}
+}
+
+func (c *fakeController) Reload() error {
+	c.record("reload")
+	return c.reloadErr
+}
+
+func (c *fakeController) Stop() {
+	c.record("stop")
+	c.stopped <- struct{}{}
+}
+
+type mockLogsWriter struct{}
+
+func (m *mockLogsWriter) LogInfo(message string)  {}
+func (m *mockLogsWriter) LogError(message string) {}
+
+const testToken = "secret"
+
+func doRequest(server *ControlServer, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestControlServer_Endpoints(t *testing.T) {
+	for _, action := range []string{"pause", "resume", "pause-buying", "reload"} {
+		t.Run(action, func(t *testing.T) {
+			controller := newFakeController()
+			server := NewControlServer("", testToken, controller, &mockLogsWriter{}, &mockLogsWriter{})
+
+			rec := doRequest(server, http.MethodPost, "/"+action, testToken)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+			assert.Equal(t, []string{action}, controller.recorded())
+		})
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		controller := newFakeController()
+		server := NewControlServer("", testToken, controller, &mockLogsWriter{}, &mockLogsWriter{})
+
+		rec := doRequest(server, http.MethodPost, "/stop", testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		select {
+		case <-controller.stopped:
+		case <-time.After(time.Second):
+			t.Fatal("stop was not triggered")
+		}
+		assert.Equal(t, []string{"stop"}, controller.recorded())
+	})
+}
+
+func TestControlServer_AuthEnforced(t *testing.T) {
+	controller := newFakeController()
+	server := NewControlServer("", testToken, controller, &mockLogsWriter{}, &mockLogsWriter{})
+
+	for _, path := range []string{"/pause", "/resume", "/pause-buying", "/reload", "/stop"} {
+		rec := doRequest(server, http.MethodPost, path, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, path)
+
+		rec = doRequest(server, http.MethodPost, path, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, path)
+	}
+	rec := doRequest(server, http.MethodGet, "/status", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	assert.Empty(t, controller.recorded())
+}
+
+func TestControlServer_TokenHeader(t *testing.T) {
+	controller := newFakeController()
+	server := NewControlServer("", testToken, controller, &mockLogsWriter{}, &mockLogsWriter{})
+
+	req := httptest.NewRequest(http.MethodPost, "/pause", nil)
+	req.Header.Set(tokenHeader, testToken)
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"pause"}, controller.recorded())
+}
+
+func TestControlServer_EmptyTokenRejectsEverything(t *testing.T) {
+	controller := newFakeController()
+	server := NewControlServer("", "", controller, &mockLogsWriter{}, &mockLogsWriter{})
+
+	rec := doRequest(server, http.MethodPost, "/pause", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, controller.recorded())
+
+	assert.Error(t, server.Start(context.Background()))
+}
+
+func TestControlServer_MethodNotAllowed(t *testing.T) {
+	controller := newFakeController()
+	server := NewControlServer("", testToken, controller, &mockLogsWriter{}, &mockLogsWriter{})
+
+	rec := doRequest(server, http.MethodGet, "/pause", testToken)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Empty(t, controller.recorded())
+}
+
+func TestControlServer_ReloadError(t *testing.T) {
+	controller := newFakeController()
+	controller.reloadErr = errors.New("bad config")
+	server := NewControlServer("", testToken, controller, &mockLogsWriter{}, &mockLogsWriter{})
+
+	rec := doRequest(server, http.MethodPost, "/reload", testToken)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "bad config")
+}
+
+func TestControlServer_Status(t *testing.T) {
+	controller := newFakeController()
+	server := NewControlServer("", testToken, controller, &mockLogsWriter{}, &mockLogsWriter{})
+
+	rec := doRequest(server, http.MethodGet, "/status", testToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"paused":false,"buying_paused":false,"bought":2,"max_buy_count":5}`, rec.Body.String())
+
+	doRequest(server, http.MethodPost, "/pause", testToken)
+	rec = doRequest(server, http.MethodGet, "/status", testToken)
+	assert.Contains(t, rec.Body.String(), `"paused":true`)
+
+	doRequest(server, http.MethodPost, "/resume", testToken)
+	rec = doRequest(server, http.MethodGet, "/status", testToken)
+	assert.Contains(t, rec.Body.String(), `"paused":false`)
+
+	rec = doRequest(server, http.MethodPost, "/status", testToken)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
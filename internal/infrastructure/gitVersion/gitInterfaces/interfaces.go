@@ -6,4 +6,8 @@ type GitVersionController interface {
 	GetLatestVersion() (*gittypes.GitHubRelease, error)
 	GetCurrentVersion() (string, error)
 	CompareVersions(localVersion, remoteVersion string) (bool, error)
+
+	// DownloadAsset downloads the named release asset to a temporary file after verifying
+	// its SHA256 and returns the file path.
+	DownloadAsset(release *gittypes.GitHubRelease, assetName string) (string, error)
 }
@@ -1,9 +1,20 @@
 package gittypes
 
 type GitHubRelease struct {
-	TagName string `json:"tag_name"`
-	Name    string `json:"name"`
-	Body    string `json:"body"`
-	HTMLURL string `json:"html_url"`
-	Draft   bool   `json:"draft"`
+	TagName string        `json:"tag_name"`
+	Name    string        `json:"name"`
+	Body    string        `json:"body"`
+	HTMLURL string        `json:"html_url"`
+	Draft   bool          `json:"draft"`
+	Assets  []GitHubAsset `json:"assets"`
+}
+
+// GitHubAsset is a file attached to a release
+type GitHubAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	Size               int64  `json:"size"`
+
+	// Digest is the checksum computed by GitHub, e.g. "sha256:<hex>" (empty for older releases)
+	Digest string `json:"digest"`
 }
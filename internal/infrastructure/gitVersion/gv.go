@@ -1,11 +1,16 @@
 package gitVersion
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"gift-buyer/internal/infrastructure/gitVersion/gitInterfaces"
 	gittypes "gift-buyer/internal/infrastructure/gitVersion/gitTypes"
+	"io"
 	"net/http"
+	"os"
+	"runtime"
 	"sort"
 	"strings"
 
@@ -14,10 +19,17 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 )
 
+// checksumSuffix is the suffix of the release asset holding the SHA256 of an asset
+// without a GitHub digest, in the sha256sum format
+const checksumSuffix = ".sha256"
+
 type GitVersionControllerImpl struct {
 	owner    string
 	repoName string
 	apiLink  string
+
+	// client downloads release assets
+	client *http.Client
 }
 
 func NewGitVersionController(owner, repoName, apiLink string) gitInterfaces.GitVersionController {
@@ -25,7 +37,132 @@ func NewGitVersionController(owner, repoName, apiLink string) gitInterfaces.GitV
 		owner:    owner,
 		repoName: repoName,
 		apiLink:  apiLink,
+		client:   http.DefaultClient,
+	}
+}
+
+// PlatformAssetName returns the name of the release asset built for the running platform,
+// e.g. "gift-buyer-linux-amd64" or "gift-buyer-windows-amd64.exe".
+//
+// Parameters:
+//   - binaryName: name of the binary the release assets start with
+//
+// Returns:
+//   - string: asset name for runtime.GOOS and runtime.GOARCH
+func PlatformAssetName(binaryName string) string {
+	name := fmt.Sprintf("%s-%s-%s", binaryName, runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// DownloadAsset downloads the named release asset to a temporary executable file and
+// verifies its SHA256. The expected checksum is the GitHub digest of the asset or, for
+// releases without digests, the "<assetName>.sha256" asset of the release. An asset
+// without a checksum is never downloaded.
+//
+// Parameters:
+//   - release: release holding the asset
+//   - assetName: name of the asset, see PlatformAssetName
+//
+// Returns:
+//   - string: path of the downloaded file, removed by the caller once used
+//   - error: missing asset or checksum, download error or checksum mismatch
+func (gvc *GitVersionControllerImpl) DownloadAsset(release *gittypes.GitHubRelease, assetName string) (string, error) {
+	asset, ok := findAsset(release, assetName)
+	if !ok {
+		return "", fmt.Errorf("release %s has no asset %s", release.TagName, assetName)
+	}
+
+	expected, err := gvc.assetChecksum(release, asset)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := gvc.fetch(asset.BrowserDownloadURL)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	file, err := os.CreateTemp("", assetName+"-*")
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to download %s: %v", assetName, err)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s, got %s", assetName, expected, actual)
+	}
+
+	if err := os.Chmod(file.Name(), 0755); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// assetChecksum returns the expected lowercase hex SHA256 of the asset.
+func (gvc *GitVersionControllerImpl) assetChecksum(release *gittypes.GitHubRelease, asset gittypes.GitHubAsset) (string, error) {
+	if digest, ok := strings.CutPrefix(asset.Digest, "sha256:"); ok {
+		return strings.ToLower(digest), nil
+	}
+
+	checksumAsset, ok := findAsset(release, asset.Name+checksumSuffix)
+	if !ok {
+		return "", fmt.Errorf("release %s has no checksum for %s", release.TagName, asset.Name)
+	}
+
+	body, err := gvc.fetch(checksumAsset.BrowserDownloadURL)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, 1024))
+	if err != nil {
+		return "", err
+	}
+	// sha256sum format: "<hex>  <file name>"
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid checksum file %s", checksumAsset.Name)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// fetch performs a GET request and returns the body of a successful response.
+func (gvc *GitVersionControllerImpl) fetch(url string) (io.ReadCloser, error) {
+	resp, err := gvc.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d downloading %s", resp.StatusCode, url)
+	}
+	return resp.Body, nil
+}
+
+// findAsset returns the release asset with the given name.
+func findAsset(release *gittypes.GitHubRelease, name string) (gittypes.GitHubAsset, bool) {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
 	}
+	return gittypes.GitHubAsset{}, false
 }
 
 func (gvc *GitVersionControllerImpl) GetLatestVersion() (*gittypes.GitHubRelease, error) {
@@ -0,0 +1,130 @@
+package gitVersion
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	gittypes "gift-buyer/internal/infrastructure/gitVersion/gitTypes"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// releaseServer serves the files of a fake release
+func releaseServer(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func asset(server *httptest.Server, name string) gittypes.GitHubAsset {
+	return gittypes.GitHubAsset{Name: name, BrowserDownloadURL: server.URL + "/" + name}
+}
+
+func TestGitVersionController_DownloadAsset(t *testing.T) {
+	const (
+		name    = "gift-buyer-linux-amd64"
+		content = "new binary"
+	)
+	gvc := NewGitVersionController("owner", "repo", "").(*GitVersionControllerImpl)
+
+	download := func(t *testing.T, release *gittypes.GitHubRelease) (string, error) {
+		path, err := gvc.DownloadAsset(release, name)
+		if err == nil {
+			t.Cleanup(func() { os.Remove(path) })
+		}
+		return path, err
+	}
+
+	t.Run("файл с контрольной суммой в релизе", func(t *testing.T) {
+		server := releaseServer(t, map[string]string{
+			name:             content,
+			name + ".sha256": checksum(content) + "  " + name + "\n",
+		})
+		release := &gittypes.GitHubRelease{TagName: "v1.1.0", Assets: []gittypes.GitHubAsset{asset(server, name), asset(server, name+".sha256")}}
+
+		path, err := download(t, release)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+		}
+	})
+
+	t.Run("digest от GitHub", func(t *testing.T) {
+		server := releaseServer(t, map[string]string{name: content})
+		withDigest := asset(server, name)
+		withDigest.Digest = "sha256:" + strings.ToUpper(checksum(content))
+		release := &gittypes.GitHubRelease{TagName: "v1.1.0", Assets: []gittypes.GitHubAsset{withDigest}}
+
+		_, err := download(t, release)
+		assert.NoError(t, err)
+	})
+
+	t.Run("несовпадение контрольной суммы", func(t *testing.T) {
+		server := releaseServer(t, map[string]string{
+			name:             "tampered binary",
+			name + ".sha256": checksum(content),
+		})
+		release := &gittypes.GitHubRelease{TagName: "v1.1.0", Assets: []gittypes.GitHubAsset{asset(server, name), asset(server, name+".sha256")}}
+
+		path, err := download(t, release)
+		assert.ErrorContains(t, err, "checksum mismatch")
+		assert.Empty(t, path)
+	})
+
+	t.Run("релиз без контрольной суммы", func(t *testing.T) {
+		server := releaseServer(t, map[string]string{name: content})
+		release := &gittypes.GitHubRelease{TagName: "v1.1.0", Assets: []gittypes.GitHubAsset{asset(server, name)}}
+
+		_, err := download(t, release)
+		assert.ErrorContains(t, err, "no checksum")
+	})
+
+	t.Run("нет файла для платформы", func(t *testing.T) {
+		release := &gittypes.GitHubRelease{TagName: "v1.1.0", Assets: []gittypes.GitHubAsset{{Name: "gift-buyer-plan9-arm"}}}
+
+		_, err := download(t, release)
+		assert.ErrorContains(t, err, "has no asset")
+	})
+
+	t.Run("ошибка загрузки", func(t *testing.T) {
+		server := releaseServer(t, map[string]string{name + ".sha256": checksum(content)})
+		release := &gittypes.GitHubRelease{TagName: "v1.1.0", Assets: []gittypes.GitHubAsset{asset(server, name), asset(server, name+".sha256")}}
+
+		_, err := download(t, release)
+		assert.ErrorContains(t, err, "unexpected status 404")
+	})
+}
+
+func TestPlatformAssetName(t *testing.T) {
+	name := PlatformAssetName("gift-buyer")
+
+	assert.True(t, strings.HasPrefix(name, "gift-buyer-"+runtime.GOOS+"-"+runtime.GOARCH))
+	assert.Equal(t, runtime.GOOS == "windows", strings.HasSuffix(name, ".exe"))
+}
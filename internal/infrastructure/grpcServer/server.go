@@ -0,0 +1,317 @@
+// Package grpcServer provides the gRPC control API of the gift buying service: the
+// control calls of the local HTTP control API and a stream of gift and purchase events.
+package grpcServer
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"gift-buyer/api/proto/controlpb"
+	"gift-buyer/pkg/errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Controller defines the service actions exposed through the gRPC control API.
+type Controller interface {
+	// Pause pauses gift monitoring and buying.
+	Pause()
+
+	// Resume resumes gift monitoring and buying.
+	Resume()
+
+	// PauseBuying keeps monitoring and notifications running but stops buying gifts.
+	PauseBuying()
+
+	// Reload reloads the configuration from disk.
+	Reload() error
+
+	// Stop gracefully stops the application.
+	Stop()
+}
+
+// InfoLogger logs informational messages
+type InfoLogger interface {
+	LogInfo(message string)
+}
+
+// ErrorLogger logs error messages
+type ErrorLogger interface {
+	LogError(message string)
+}
+
+// tokenHeader is an alternative to the "authorization: Bearer <token>" metadata
+const tokenHeader = "x-control-token"
+
+// eventBuffer is the number of events buffered for every stream, newer events are
+// dropped for a client that doesn't keep up
+const eventBuffer = 64
+
+// GrpcServer serves the gRPC control API and streams the gift and purchase events
+// published to it to every connected client.
+type GrpcServer struct {
+	controlpb.UnimplementedControlServer
+
+	addr       string
+	token      string
+	controller Controller
+
+	// subscribers are the event channels of the open event streams
+	subscribers map[chan *controlpb.Event]struct{}
+	mu          sync.Mutex
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+
+	infoLogsWriter  InfoLogger
+	errorLogsWriter ErrorLogger
+}
+
+// NewGrpcServer creates a new gRPC control API server.
+//
+// Parameters:
+//   - addr: listen address, e.g. "127.0.0.1:9090"
+//   - token: secret token required in the metadata of every call
+//   - controller: service actions triggered by the control calls
+//   - infoLogsWriter: logger for informational messages
+//   - errorLogsWriter: logger for error messages
+//
+// Returns:
+//   - *GrpcServer: configured gRPC server
+func NewGrpcServer(addr, token string, controller Controller, infoLogsWriter InfoLogger, errorLogsWriter ErrorLogger) *GrpcServer {
+	return &GrpcServer{
+		addr:            addr,
+		token:           token,
+		controller:      controller,
+		subscribers:     make(map[chan *controlpb.Event]struct{}),
+		now:             time.Now,
+		infoLogsWriter:  infoLogsWriter,
+		errorLogsWriter: errorLogsWriter,
+	}
+}
+
+// Start serves the gRPC control API on the configured address until the context is cancelled.
+// The server refuses to start without a token so that the API is never left unprotected.
+//
+// Parameters:
+//   - ctx: context controlling the server lifetime
+//
+// Returns:
+//   - error: listen error or missing token
+func (gs *GrpcServer) Start(ctx context.Context) error {
+	if gs.token == "" {
+		return errors.Wrap(errors.ErrInvalidParams, "control API token is not configured")
+	}
+
+	listener, err := net.Listen("tcp", gs.addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to start gRPC control API")
+	}
+
+	gs.infoLogsWriter.LogInfo(fmt.Sprintf("gRPC control API listening on %s", listener.Addr()))
+	return gs.Serve(ctx, listener)
+}
+
+// Serve serves the gRPC control API on the listener until the context is cancelled.
+// Open event streams are closed on cancellation.
+//
+// Parameters:
+//   - ctx: context controlling the server lifetime
+//   - listener: listener accepting the client connections
+//
+// Returns:
+//   - error: serve error
+func (gs *GrpcServer) Serve(ctx context.Context, listener net.Listener) error {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(gs.authorizeUnary),
+		grpc.StreamInterceptor(gs.authorizeStream),
+	)
+	controlpb.RegisterControlServer(server, gs)
+
+	go func() {
+		<-ctx.Done()
+		// Event streams only end with the client, so there is nothing to wait for
+		server.Stop()
+	}()
+
+	if err := server.Serve(listener); err != nil && err != grpc.ErrServerStopped {
+		return errors.Wrap(err, "gRPC control API stopped")
+	}
+	return nil
+}
+
+// Pause pauses gift monitoring and buying.
+func (gs *GrpcServer) Pause(ctx context.Context, req *controlpb.ControlRequest) (*controlpb.ControlResponse, error) {
+	return gs.run("Pause", func() error {
+		gs.controller.Pause()
+		return nil
+	})
+}
+
+// Resume resumes gift monitoring and buying.
+func (gs *GrpcServer) Resume(ctx context.Context, req *controlpb.ControlRequest) (*controlpb.ControlResponse, error) {
+	return gs.run("Resume", func() error {
+		gs.controller.Resume()
+		return nil
+	})
+}
+
+// PauseBuying keeps monitoring and notifications running but stops buying gifts.
+func (gs *GrpcServer) PauseBuying(ctx context.Context, req *controlpb.ControlRequest) (*controlpb.ControlResponse, error) {
+	return gs.run("PauseBuying", func() error {
+		gs.controller.PauseBuying()
+		return nil
+	})
+}
+
+// Reload reloads the configuration from disk.
+func (gs *GrpcServer) Reload(ctx context.Context, req *controlpb.ControlRequest) (*controlpb.ControlResponse, error) {
+	return gs.run("Reload", gs.controller.Reload)
+}
+
+// Stop gracefully stops the application.
+func (gs *GrpcServer) Stop(ctx context.Context, req *controlpb.ControlRequest) (*controlpb.ControlResponse, error) {
+	return gs.run("Stop", func() error {
+		// Stop asynchronously so the response reaches the client before shutdown
+		go gs.controller.Stop()
+		return nil
+	})
+}
+
+// Events streams the published gift and purchase events until the client disconnects
+// or the server stops.
+func (gs *GrpcServer) Events(req *controlpb.EventsRequest, stream grpc.ServerStreamingServer[controlpb.Event]) error {
+	events := gs.subscribe()
+	defer gs.unsubscribe(events)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// GiftFound publishes a newly found eligible gift to the event streams.
+//
+// Parameters:
+//   - giftID: ID of the found gift
+//   - stars: price of the gift in stars
+func (gs *GrpcServer) GiftFound(giftID, stars int64) {
+	gs.publish(&controlpb.Event{
+		Type:      controlpb.Event_TYPE_GIFT_FOUND,
+		GiftId:    giftID,
+		Stars:     stars,
+		Timestamp: gs.now().UnixMilli(),
+	})
+}
+
+// PurchaseResult publishes the result of a purchase attempt to the event streams.
+//
+// Parameters:
+//   - giftID: ID of the gift
+//   - stars: price of the gift in stars
+//   - err: purchase error, nil if the purchase succeeded
+func (gs *GrpcServer) PurchaseResult(giftID, stars int64, err error) {
+	event := &controlpb.Event{
+		Type:      controlpb.Event_TYPE_PURCHASE_RESULT,
+		GiftId:    giftID,
+		Stars:     stars,
+		Success:   err == nil,
+		Timestamp: gs.now().UnixMilli(),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	gs.publish(event)
+}
+
+// publish hands the event to every open stream without blocking, a stream whose
+// buffer is full misses the event.
+func (gs *GrpcServer) publish(event *controlpb.Event) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	for events := range gs.subscribers {
+		select {
+		case events <- event:
+		default:
+			gs.errorLogsWriter.LogError(fmt.Sprintf("gRPC event stream is too slow, dropped %s event of gift %d", event.Type, event.GiftId))
+		}
+	}
+}
+
+// subscribe opens the event channel of a new stream.
+func (gs *GrpcServer) subscribe() chan *controlpb.Event {
+	events := make(chan *controlpb.Event, eventBuffer)
+	gs.mu.Lock()
+	gs.subscribers[events] = struct{}{}
+	gs.mu.Unlock()
+	return events
+}
+
+// unsubscribe removes the event channel of a closed stream.
+func (gs *GrpcServer) unsubscribe(events chan *controlpb.Event) {
+	gs.mu.Lock()
+	delete(gs.subscribers, events)
+	gs.mu.Unlock()
+}
+
+// run runs a control action and converts its error to a gRPC status.
+func (gs *GrpcServer) run(method string, action func() error) (*controlpb.ControlResponse, error) {
+	if err := action(); err != nil {
+		gs.errorLogsWriter.LogError(fmt.Sprintf("gRPC control API %s failed: %v", method, err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	gs.infoLogsWriter.LogInfo(fmt.Sprintf("gRPC control API: %s", method))
+	return &controlpb.ControlResponse{Status: "ok"}, nil
+}
+
+// authorizeUnary rejects control calls without a valid token.
+func (gs *GrpcServer) authorizeUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !gs.authorized(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return handler(ctx, req)
+}
+
+// authorizeStream rejects event streams without a valid token.
+func (gs *GrpcServer) authorizeStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !gs.authorized(stream.Context()) {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return handler(srv, stream)
+}
+
+// authorized checks the call token in constant time.
+func (gs *GrpcServer) authorized(ctx context.Context) bool {
+	if gs.token == "" {
+		return false
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	var token string
+	if values := md.Get(tokenHeader); len(values) > 0 {
+		token = values[0]
+	} else if values := md.Get("authorization"); len(values) > 0 && strings.HasPrefix(values[0], "Bearer ") {
+		token = strings.TrimPrefix(values[0], "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(gs.token)) == 1
+}
@@ -0,0 +1,220 @@
+package grpcServer
+
+import (
+	"context"
+	"errors"
+	"gift-buyer/api/proto/controlpb"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeController records the actions triggered through the API
+type fakeController struct {
+	mu        sync.Mutex
+	actions   []string
+	reloadErr error
+	stopped   chan struct{}
+}
+
+func newFakeController() *fakeController {
+	return &fakeController{stopped: make(chan struct{}, 1)}
+}
+
+func (c *fakeController) record(action string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = append(c.actions, action)
+}
+
+func (c *fakeController) recorded() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.actions...)
+}
+
+func (c *fakeController) Pause()       { c.record("pause") }
+func (c *fakeController) Resume()      { c.record("resume") }
+func (c *fakeController) PauseBuying() { c.record("pause-buying") }
+
+func (c *fakeController) Reload() error {
+	c.record("reload")
+	return c.reloadErr
+}
+
+func (c *fakeController) Stop() {
+	c.record("stop")
+	c.stopped <- struct{}{}
+}
+
+type mockLogsWriter struct{}
+
+func (m *mockLogsWriter) LogInfo(message string)  {}
+func (m *mockLogsWriter) LogError(message string) {}
+
+const testToken = "secret"
+
+// startServer serves the API on an in-memory listener and returns a client connected to it
+func startServer(t *testing.T, controller Controller) (*GrpcServer, controlpb.ControlClient) {
+	t.Helper()
+
+	server := NewGrpcServer("", testToken, controller, &mockLogsWriter{}, &mockLogsWriter{})
+	server.now = func() time.Time { return time.UnixMilli(1700000000000) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	listener := bufconn.Listen(1 << 20)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, listener) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		assert.NoError(t, <-served)
+	})
+	return server, controlpb.NewControlClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestGrpcServer_Control(t *testing.T) {
+	controller := newFakeController()
+	_, client := startServer(t, controller)
+	ctx := withToken(testToken)
+
+	for _, call := range []func(context.Context, *controlpb.ControlRequest, ...grpc.CallOption) (*controlpb.ControlResponse, error){
+		client.Pause, client.PauseBuying, client.Resume, client.Reload,
+	} {
+		resp, err := call(ctx, &controlpb.ControlRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.GetStatus())
+	}
+	assert.Equal(t, []string{"pause", "pause-buying", "resume", "reload"}, controller.recorded())
+
+	t.Run("остановка", func(t *testing.T) {
+		_, err := client.Stop(ctx, &controlpb.ControlRequest{})
+		require.NoError(t, err)
+
+		select {
+		case <-controller.stopped:
+		case <-time.After(time.Second):
+			t.Fatal("controller was not stopped")
+		}
+	})
+
+	t.Run("ошибка перезагрузки", func(t *testing.T) {
+		controller.reloadErr = errors.New("invalid config")
+
+		_, err := client.Reload(ctx, &controlpb.ControlRequest{})
+
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Contains(t, err.Error(), "invalid config")
+	})
+}
+
+func TestGrpcServer_Unauthorized(t *testing.T) {
+	controller := newFakeController()
+	_, client := startServer(t, controller)
+
+	_, err := client.Pause(context.Background(), &controlpb.ControlRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Pause(withToken("wrong"), &controlpb.ControlRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream, err := client.Events(withToken("wrong"), &controlpb.EventsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	assert.Empty(t, controller.recorded())
+}
+
+func TestGrpcServer_TokenHeader(t *testing.T) {
+	_, client := startServer(t, newFakeController())
+	ctx := metadata.AppendToOutgoingContext(context.Background(), tokenHeader, testToken)
+
+	_, err := client.Resume(ctx, &controlpb.ControlRequest{})
+
+	assert.NoError(t, err)
+}
+
+func TestGrpcServer_Events(t *testing.T) {
+	server, client := startServer(t, newFakeController())
+	ctx, cancel := context.WithCancel(withToken(testToken))
+	defer cancel()
+
+	stream, err := client.Events(ctx, &controlpb.EventsRequest{})
+	require.NoError(t, err)
+	// The stream is subscribed once the server handler runs
+	require.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.subscribers) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	server.GiftFound(42, 500)
+	server.PurchaseResult(42, 500, nil)
+	server.PurchaseResult(42, 500, errors.New("BALANCE_TOO_LOW"))
+
+	found, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, controlpb.Event_TYPE_GIFT_FOUND, found.GetType())
+	assert.Equal(t, int64(42), found.GetGiftId())
+	assert.Equal(t, int64(500), found.GetStars())
+	assert.Equal(t, int64(1700000000000), found.GetTimestamp())
+
+	bought, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, controlpb.Event_TYPE_PURCHASE_RESULT, bought.GetType())
+	assert.True(t, bought.GetSuccess())
+	assert.Empty(t, bought.GetError())
+
+	failed, err := stream.Recv()
+	require.NoError(t, err)
+	assert.False(t, failed.GetSuccess())
+	assert.Equal(t, "BALANCE_TOO_LOW", failed.GetError())
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.subscribers) == 0
+	}, time.Second, 5*time.Millisecond, "closed stream must unsubscribe")
+}
+
+func TestGrpcServer_SlowStreamDropsEvents(t *testing.T) {
+	server := NewGrpcServer("", testToken, newFakeController(), &mockLogsWriter{}, &mockLogsWriter{})
+	events := server.subscribe()
+
+	for i := 0; i < eventBuffer+5; i++ {
+		server.GiftFound(int64(i), 1)
+	}
+
+	assert.Len(t, events, eventBuffer, "publishing must not block on a full stream")
+}
+
+func TestGrpcServer_StartWithoutToken(t *testing.T) {
+	server := NewGrpcServer("127.0.0.1:0", "", newFakeController(), &mockLogsWriter{}, &mockLogsWriter{})
+
+	err := server.Start(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "token is not configured")
+}
@@ -0,0 +1,154 @@
+// Package healthServer serves a /healthz endpoint for container orchestration, reporting
+// whether the Telegram client is connected and the gift monitor is running.
+package healthServer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gift-buyer/pkg/errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultMaxStaleness is how long after the last successful API check the Telegram
+// client is still considered connected. The API checker runs every two seconds.
+const DefaultMaxStaleness = 30 * time.Second
+
+// Response is the JSON body of a /healthz response.
+type Response struct {
+	// Status is "ok" or "unhealthy"
+	Status string `json:"status"`
+
+	// Reason explains why the service is unhealthy
+	Reason string `json:"reason,omitempty"`
+}
+
+// InfoLogger logs informational messages
+type InfoLogger interface {
+	LogInfo(message string)
+}
+
+// HealthServer serves the health endpoint.
+type HealthServer struct {
+	addr string
+
+	// lastSuccess returns the time of the last successful Telegram API check
+	lastSuccess func() time.Time
+
+	// monitorRunning reports whether the gift monitor loop is running
+	monitorRunning func() bool
+
+	// maxStaleness is how old the last successful API check may be
+	maxStaleness time.Duration
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+
+	mux            *http.ServeMux
+	infoLogsWriter InfoLogger
+}
+
+// NewHealthServer creates a new health endpoint server.
+//
+// Parameters:
+//   - addr: listen address, e.g. ":8082"
+//   - lastSuccess: time of the last successful Telegram API check
+//   - monitorRunning: whether the gift monitor loop is running
+//   - maxStaleness: how old the last successful API check may be (defaults to DefaultMaxStaleness)
+//   - infoLogsWriter: logger for informational messages
+//
+// Returns:
+//   - *HealthServer: configured health server
+func NewHealthServer(addr string, lastSuccess func() time.Time, monitorRunning func() bool, maxStaleness time.Duration, infoLogsWriter InfoLogger) *HealthServer {
+	if maxStaleness <= 0 {
+		maxStaleness = DefaultMaxStaleness
+	}
+
+	hs := &HealthServer{
+		addr:           addr,
+		lastSuccess:    lastSuccess,
+		monitorRunning: monitorRunning,
+		maxStaleness:   maxStaleness,
+		now:            time.Now,
+		mux:            http.NewServeMux(),
+		infoLogsWriter: infoLogsWriter,
+	}
+	hs.mux.HandleFunc("/healthz", hs.handleHealth)
+	return hs
+}
+
+// Handler returns the HTTP handler of the health endpoint.
+func (hs *HealthServer) Handler() http.Handler {
+	return hs.mux
+}
+
+// Start serves the health endpoint until the context is cancelled.
+//
+// Parameters:
+//   - ctx: context controlling the server lifetime
+//
+// Returns:
+//   - error: listen error
+func (hs *HealthServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", hs.addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to start health endpoint")
+	}
+
+	server := &http.Server{
+		Handler:           hs.mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	hs.infoLogsWriter.LogInfo(fmt.Sprintf("Health endpoint listening on %s", listener.Addr()))
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return errors.Wrap(err, "health endpoint stopped")
+	}
+	return nil
+}
+
+// unhealthyReason returns why the service is unhealthy, empty if it is healthy.
+func (hs *HealthServer) unhealthyReason() string {
+	lastSuccess := hs.lastSuccess()
+	switch {
+	case lastSuccess.IsZero():
+		return "telegram client has not connected yet"
+	case hs.now().Sub(lastSuccess) > hs.maxStaleness:
+		return fmt.Sprintf("no successful telegram API check since %s", lastSuccess.UTC().Format(time.RFC3339))
+	case !hs.monitorRunning():
+		return "gift monitor is not running"
+	default:
+		return ""
+	}
+}
+
+// handleHealth serves GET /healthz with 200 when healthy and 503 otherwise.
+func (hs *HealthServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if reason := hs.unhealthyReason(); reason != "" {
+		writeJSON(w, http.StatusServiceUnavailable, Response{Status: "unhealthy", Reason: reason})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Status: "ok"})
+}
+
+// writeJSON writes a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
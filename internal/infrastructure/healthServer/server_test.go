@@ -0,0 +1,97 @@
+package healthServer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockLogsWriter struct{}
+
+func (m *mockLogsWriter) LogInfo(message string) {}
+
+// healthState is the state reported to the server, flipped by the tests
+type healthState struct {
+	lastSuccess time.Time
+	running     bool
+}
+
+func newTestServer(state *healthState, now time.Time) *HealthServer {
+	server := NewHealthServer("",
+		func() time.Time { return state.lastSuccess },
+		func() bool { return state.running },
+		time.Minute, &mockLogsWriter{})
+	server.now = func() time.Time { return now }
+	return server
+}
+
+func getHealth(t *testing.T, server *HealthServer) (int, Response) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	var body Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec.Code, body
+}
+
+func TestHealthServer_Healthz(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("статус меняется вместе с последней успешной проверкой", func(t *testing.T) {
+		state := &healthState{running: true}
+		server := newTestServer(state, now)
+
+		code, body := getHealth(t, server)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "unhealthy", body.Status)
+		assert.Contains(t, body.Reason, "has not connected")
+
+		state.lastSuccess = now.Add(-10 * time.Second)
+		code, body = getHealth(t, server)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, Response{Status: "ok"}, body)
+
+		state.lastSuccess = now.Add(-2 * time.Minute)
+		code, body = getHealth(t, server)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Contains(t, body.Reason, "no successful telegram API check since 2025-01-01T11:58:00Z")
+
+		state.lastSuccess = now
+		code, _ = getHealth(t, server)
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("мониторинг не запущен", func(t *testing.T) {
+		state := &healthState{lastSuccess: now}
+		server := newTestServer(state, now)
+
+		code, body := getHealth(t, server)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "gift monitor is not running", body.Reason)
+
+		state.running = true
+		code, _ = getHealth(t, server)
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("только GET", func(t *testing.T) {
+		server := newTestServer(&healthState{lastSuccess: now, running: true}, now)
+
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+
+	t.Run("допустимая давность по умолчанию", func(t *testing.T) {
+		server := NewHealthServer("", time.Now, func() bool { return true }, 0, &mockLogsWriter{})
+		assert.Equal(t, DefaultMaxStaleness, server.maxStaleness)
+	})
+}
@@ -0,0 +1,177 @@
+// Package heartbeat periodically posts the health of the gift buying service to an
+// external URL, so several instances can be watched from one monitoring system.
+package heartbeat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gift-buyer/pkg/errors"
+	"gift-buyer/pkg/logger"
+	"net/http"
+	"time"
+)
+
+// Status is the health of the service at the moment a heartbeat is sent.
+type Status struct {
+	// Paused reports whether gift monitoring is paused
+	Paused bool
+
+	// BuyingPaused reports whether buying is paused while monitoring continues
+	BuyingPaused bool
+
+	// Purchases is the number of gifts bought in the current session
+	Purchases int64
+
+	// MaxPurchases is the configured purchase limit
+	MaxPurchases int64
+
+	// Balance is the stars balance of the account, nil when it is unknown
+	Balance *int64
+
+	// LastError is the message of the last error, empty if none occurred
+	LastError string
+
+	// LastErrorAt is the time of the last error
+	LastErrorAt time.Time
+}
+
+// StatusProvider reports the current health of the service.
+type StatusProvider interface {
+	Status(ctx context.Context) Status
+}
+
+// StatusFunc adapts a function to the StatusProvider interface.
+type StatusFunc func(ctx context.Context) Status
+
+// Status calls f.
+func (f StatusFunc) Status(ctx context.Context) Status {
+	return f(ctx)
+}
+
+// payload is the JSON body of a heartbeat request.
+type payload struct {
+	Timestamp     string `json:"timestamp"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	Paused        bool   `json:"paused"`
+	BuyingPaused  bool   `json:"buying_paused"`
+	Purchases     int64  `json:"purchases"`
+	MaxPurchases  int64  `json:"max_purchases"`
+	Balance       *int64 `json:"balance,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+	LastErrorAt   string `json:"last_error_at,omitempty"`
+}
+
+// Reporter posts a heartbeat with the service health at a fixed interval.
+type Reporter struct {
+	// url receives the heartbeat requests
+	url string
+
+	// interval is the time between two heartbeats
+	interval time.Duration
+
+	// source provides the reported health
+	source StatusProvider
+
+	// client sends the heartbeat requests
+	client *http.Client
+
+	// started is the time the reporter was created, the uptime is counted from it
+	started time.Time
+
+	// now returns the current time, overridden in tests
+	now func() time.Time
+}
+
+// NewReporter creates a heartbeat reporter.
+//
+// Parameters:
+//   - url: URL the heartbeats are posted to
+//   - interval: time between two heartbeats
+//   - source: provider of the reported health
+//
+// Returns:
+//   - *Reporter: heartbeat reporter, start it with Run
+func NewReporter(url string, interval time.Duration, source StatusProvider) *Reporter {
+	return &Reporter{
+		url:      url,
+		interval: interval,
+		source:   source,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		started:  time.Now(),
+		now:      time.Now,
+	}
+}
+
+// Run sends a heartbeat right away and then every interval until the context is
+// cancelled. Failed heartbeats are logged and never stop the reporter.
+//
+// Parameters:
+//   - ctx: context stopping the reporter
+func (r *Reporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Send(ctx); err != nil && ctx.Err() == nil {
+			logger.GlobalLogger.Warnf("Failed to send heartbeat: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Send posts a single heartbeat with the current health.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//
+// Returns:
+//   - error: encoding, network or non-2xx response error
+func (r *Reporter) Send(ctx context.Context) error {
+	body, err := json.Marshal(r.payload(r.source.Status(ctx)))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode heartbeat")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create heartbeat request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send heartbeat")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New(fmt.Sprintf("heartbeat endpoint returned status %d", resp.StatusCode))
+	}
+	return nil
+}
+
+// payload converts the status to the heartbeat request body.
+func (r *Reporter) payload(status Status) payload {
+	now := r.now()
+	p := payload{
+		Timestamp:     now.UTC().Format(time.RFC3339),
+		UptimeSeconds: int64(now.Sub(r.started).Seconds()),
+		Paused:        status.Paused,
+		BuyingPaused:  status.BuyingPaused,
+		Purchases:     status.Purchases,
+		MaxPurchases:  status.MaxPurchases,
+		Balance:       status.Balance,
+		LastError:     status.LastError,
+	}
+	if !status.LastErrorAt.IsZero() {
+		p.LastErrorAt = status.LastErrorAt.UTC().Format(time.RFC3339)
+	}
+	return p
+}
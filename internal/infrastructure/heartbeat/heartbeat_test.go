@@ -0,0 +1,134 @@
+package heartbeat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// received is a heartbeat captured by the stub endpoint
+type received struct {
+	body map[string]interface{}
+	at   time.Time
+}
+
+// stubEndpoint starts a server capturing heartbeats, failing the first failures requests
+func stubEndpoint(t *testing.T, failures int32) (*httptest.Server, <-chan received) {
+	heartbeats := make(chan received, 16)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		select {
+		case heartbeats <- received{body: body, at: time.Now()}:
+		default:
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, heartbeats
+}
+
+func TestReporter_Run(t *testing.T) {
+	balance := int64(1500)
+	status := StatusFunc(func(ctx context.Context) Status {
+		return Status{
+			BuyingPaused: true,
+			Purchases:    3,
+			MaxPurchases: 10,
+			Balance:      &balance,
+			LastError:    "FLOOD_WAIT",
+			LastErrorAt:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+	})
+
+	t.Run("периодическая отправка", func(t *testing.T) {
+		server, heartbeats := stubEndpoint(t, 0)
+		interval := 50 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go NewReporter(server.URL, interval, status).Run(ctx)
+
+		var beats []received
+		for len(beats) < 3 {
+			select {
+			case beat := <-heartbeats:
+				beats = append(beats, beat)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("received %d heartbeats, want 3", len(beats))
+			}
+		}
+
+		body := beats[0].body
+		assert.Equal(t, false, body["paused"])
+		assert.Equal(t, true, body["buying_paused"])
+		assert.Equal(t, float64(3), body["purchases"])
+		assert.Equal(t, float64(10), body["max_purchases"])
+		assert.Equal(t, float64(1500), body["balance"])
+		assert.Equal(t, "FLOOD_WAIT", body["last_error"])
+		assert.Equal(t, "2025-01-02T03:04:05Z", body["last_error_at"])
+		assert.Contains(t, body, "uptime_seconds")
+		assert.Contains(t, body, "timestamp")
+
+		for i := 1; i < len(beats); i++ {
+			assert.GreaterOrEqual(t, beats[i].at.Sub(beats[i-1].at), interval/2)
+		}
+	})
+
+	t.Run("ошибка отправки не останавливает отчеты", func(t *testing.T) {
+		server, heartbeats := stubEndpoint(t, 2)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go NewReporter(server.URL, 10*time.Millisecond, status).Run(ctx)
+
+		select {
+		case beat := <-heartbeats:
+			assert.Equal(t, float64(3), beat.body["purchases"])
+		case <-time.After(2 * time.Second):
+			t.Fatal("heartbeat not received after failed attempts")
+		}
+	})
+}
+
+func TestReporter_Send(t *testing.T) {
+	t.Run("ошибочный статус ответа", func(t *testing.T) {
+		server, _ := stubEndpoint(t, 1)
+
+		err := NewReporter(server.URL, time.Minute, StatusFunc(func(ctx context.Context) Status { return Status{} })).Send(context.Background())
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "500")
+	})
+
+	t.Run("неизвестный баланс и отсутствие ошибок", func(t *testing.T) {
+		server, heartbeats := stubEndpoint(t, 0)
+		reporter := NewReporter(server.URL, time.Minute, StatusFunc(func(ctx context.Context) Status { return Status{Paused: true} }))
+		reporter.now = func() time.Time { return reporter.started.Add(90 * time.Second) }
+
+		require.NoError(t, reporter.Send(context.Background()))
+
+		beat := <-heartbeats
+		assert.Equal(t, true, beat.body["paused"])
+		assert.Equal(t, float64(90), beat.body["uptime_seconds"])
+		assert.NotContains(t, beat.body, "balance")
+		assert.NotContains(t, beat.body, "last_error")
+		assert.NotContains(t, beat.body, "last_error_at")
+	})
+}
@@ -7,18 +7,41 @@ import (
 	"gift-buyer/internal/infrastructure/logsWriter/logWriterInterface"
 	"gift-buyer/pkg/logger"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 )
 
+// backupTimeFormat is the timestamp suffix of rotated log files, sortable by name
+const backupTimeFormat = "20060102-150405.000000"
+
 type writerImpl struct {
 	File      *os.File
 	mu        sync.Mutex
 	level     string
 	formatter logWriterInterface.LogFormatter
+
+	// path is the log file, size its current size in bytes
+	path string
+	size int64
+
+	// maxSize is the size in bytes that triggers a rotation (0 disables rotation),
+	// maxBackups the number of rotated files kept (0 keeps all of them)
+	maxSize    int64
+	maxBackups int
+
+	// now returns the time used for the backup suffix, replaceable in tests
+	now func() time.Time
 }
 
 func NewLogsWriter(level string, formatter logWriterInterface.LogFormatter) *writerImpl {
-	file, err := os.OpenFile(fmt.Sprintf("%s_logs.jsonl", level), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	return newLogsWriterAt(fmt.Sprintf("%s_logs.jsonl", level), level, formatter)
+}
+
+// newLogsWriterAt creates a writer appending to the log file at path.
+func newLogsWriterAt(path, level string, formatter logWriterInterface.LogFormatter) *writerImpl {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		logger.GlobalLogger.Fatalf("Failed to open log file: %v", err)
 	}
@@ -27,11 +50,30 @@ func NewLogsWriter(level string, formatter logWriterInterface.LogFormatter) *wri
 		File:      file,
 		level:     level,
 		formatter: formatter,
+		path:      path,
+		now:       time.Now,
+	}
+	if info, err := file.Stat(); err == nil {
+		writer.size = info.Size()
 	}
 
 	return writer
 }
 
+// SetRotation enables size-based rotation: once the log file would grow past maxSize
+// bytes it is renamed with a timestamp suffix and a fresh file is started. Only the
+// newest maxBackups rotated files are kept.
+//
+// Parameters:
+//   - maxSize: log file size in bytes that triggers a rotation (0 disables rotation)
+//   - maxBackups: number of rotated files to keep (0 keeps all of them)
+func (l *writerImpl) SetRotation(maxSize int64, maxBackups int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxSize = maxSize
+	l.maxBackups = maxBackups
+}
+
 func (l *writerImpl) WriteToFile(entry *logTypes.LogEntry) (err error) {
 	bytes, err := l.formatter.Format(entry)
 	if err != nil {
@@ -48,9 +90,67 @@ func (l *writerImpl) WriteToFile(entry *logTypes.LogEntry) (err error) {
 func (l *writerImpl) write(bytes []byte) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	_, err := l.File.Write(bytes)
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(bytes)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			logger.GlobalLogger.Warnf("Failed to rotate log file %s: %v", l.path, err)
+		}
+	}
+
+	n, err := l.File.Write(bytes)
+	l.size += int64(n)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// rotate renames the current log file with a timestamp suffix, opens a fresh one
+// and prunes the oldest backups. Must be called with mu held.
+func (l *writerImpl) rotate() error {
+	if err := l.File.Close(); err != nil {
+		return err
+	}
+
+	backup := fmt.Sprintf("%s.%s", l.path, l.now().Format(backupTimeFormat))
+	renameErr := os.Rename(l.path, backup)
+
+	// Reopen the log file even if the rename failed, so logging continues
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	l.File = file
+	l.size = 0
+	if info, err := file.Stat(); err == nil {
+		l.size = info.Size()
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	return l.prune()
+}
+
+// prune removes the oldest rotated files beyond maxBackups.
+func (l *writerImpl) prune() error {
+	if l.maxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(l.path + ".*")
+	if err != nil {
+		return err
+	}
+	if len(backups) <= l.maxBackups {
+		return nil
+	}
+
+	sort.Strings(backups)
+	for _, backup := range backups[:len(backups)-l.maxBackups] {
+		if err := os.Remove(backup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
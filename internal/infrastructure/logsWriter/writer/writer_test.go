@@ -0,0 +1,101 @@
+package writer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gift-buyer/internal/infrastructure/logsWriter/logTypes"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plainFormatter writes the message as a line
+type plainFormatter struct{}
+
+func (plainFormatter) Format(entry *logTypes.LogEntry) ([]byte, error) {
+	return []byte(entry.Message + "\n"), nil
+}
+
+// newTestWriter creates a writer in a temporary directory with a clock advancing a second per call
+func newTestWriter(t *testing.T) (*writerImpl, string) {
+	path := filepath.Join(t.TempDir(), "info_logs.jsonl")
+	writer := newLogsWriterAt(path, "info", plainFormatter{})
+	t.Cleanup(func() { writer.File.Close() })
+
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	writer.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	return writer, path
+}
+
+func backups(t *testing.T, path string) []string {
+	matches, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	return matches
+}
+
+func TestWriter_Rotation(t *testing.T) {
+	line := strings.Repeat("x", 9) // 10 bytes with the newline
+
+	t.Run("без ротации файл растет", func(t *testing.T) {
+		writer, path := newTestWriter(t)
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, writer.WriteToFile(&logTypes.LogEntry{Message: line}))
+		}
+
+		assert.Empty(t, backups(t, path))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), info.Size())
+	})
+
+	t.Run("превышение размера создает резервную копию", func(t *testing.T) {
+		writer, path := newTestWriter(t)
+		writer.SetRotation(25, 0)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, writer.WriteToFile(&logTypes.LogEntry{Message: line}))
+		}
+
+		files := backups(t, path)
+		require.Len(t, files, 1)
+		assert.Equal(t, path+".20250101-000001.000000", files[0])
+		rotated, err := os.ReadFile(files[0])
+		require.NoError(t, err)
+		assert.Equal(t, strings.Repeat(line+"\n", 2), string(rotated))
+		current, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, line+"\n", string(current))
+	})
+
+	t.Run("старые копии удаляются", func(t *testing.T) {
+		writer, path := newTestWriter(t)
+		writer.SetRotation(15, 2)
+
+		for i := 0; i < 6; i++ {
+			require.NoError(t, writer.WriteToFile(&logTypes.LogEntry{Message: line}))
+		}
+
+		files := backups(t, path)
+		assert.Equal(t, []string{path + ".20250101-000004.000000", path + ".20250101-000005.000000"}, files)
+	})
+
+	t.Run("размер существующего файла учитывается", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "error_logs.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("y", 20)), 0644))
+		writer := newLogsWriterAt(path, "error", plainFormatter{})
+		defer writer.File.Close()
+		writer.SetRotation(25, 1)
+
+		require.NoError(t, writer.WriteToFile(&logTypes.LogEntry{Message: line}))
+
+		assert.Len(t, backups(t, path), 1)
+	})
+}
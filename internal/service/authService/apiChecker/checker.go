@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"gift-buyer/pkg/logger"
+	"sync/atomic"
 	"time"
 
 	"github.com/gotd/td/tg"
@@ -12,6 +13,9 @@ import (
 type apiCheckerImpl struct {
 	api    *tg.Client
 	ticker *time.Ticker
+
+	// lastSuccess is the time of the last successful check in Unix nanoseconds, 0 if none
+	lastSuccess atomic.Int64
 }
 
 func NewApiChecker(api *tg.Client, ticker *time.Ticker) *apiCheckerImpl {
@@ -22,7 +26,21 @@ func NewApiChecker(api *tg.Client, ticker *time.Ticker) *apiCheckerImpl {
 }
 
 func (f *apiCheckerImpl) Run(ctx context.Context) error {
-	return f.ping(ctx)
+	if err := f.ping(ctx); err != nil {
+		return err
+	}
+	f.lastSuccess.Store(time.Now().UnixNano())
+	return nil
+}
+
+// LastSuccess returns the time of the last successful check, the zero time if
+// no check has succeeded yet.
+func (f *apiCheckerImpl) LastSuccess() time.Time {
+	nanos := f.lastSuccess.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
 }
 
 func (f *apiCheckerImpl) ping(ctx context.Context) error {
@@ -2,6 +2,7 @@ package authInterfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/tg"
@@ -77,6 +78,11 @@ type ApiChecker interface {
 	//   - error: error if the API is not working
 	Run(ctx context.Context) error
 
+	// LastSuccess returns the time of the last successful check
+	// Returns:
+	//   - time.Time: time of the last successful check, zero if none succeeded yet
+	LastSuccess() time.Time
+
 	// // CheckApi checks the Telegram API once
 	// // Parameters:
 	// //   - ctx: context for cancellation and timeout control
@@ -102,6 +108,20 @@ type GiftMonitorAndAuthController interface {
 	IsPaused() bool
 }
 
+// ReconnectNotifier defines the interface for notifying about reconnect lifecycle events.
+type ReconnectNotifier interface {
+	// SendReconnectNotification sends a notification about a reconnect event.
+	//
+	// Parameters:
+	//   - ctx: context for request cancellation
+	//   - event: human readable description of the event
+	//   - err: error that caused or ended the event, nil if none
+	//
+	// Returns:
+	//   - error: notification sending error
+	SendReconnectNotification(ctx context.Context, event string, err error) error
+}
+
 // InfoLogger defines the interface for logging information.
 // It provides methods to log information messages.
 type InfoLogger interface {
@@ -3,10 +3,11 @@ package authService
 import (
 	"context"
 	"errors"
+	"fmt"
 	"gift-buyer/internal/config"
 	"gift-buyer/internal/service/authService/apiChecker"
 	"gift-buyer/internal/service/authService/authInterfaces"
-	"os"
+	"gift-buyer/internal/service/authService/sessions"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +16,28 @@ import (
 	"github.com/gotd/td/tg"
 )
 
+// Reconnect lifecycle events reported to the reconnect notifier
+const (
+	reconnectEventDisconnected = "disconnect detected"
+	reconnectEventStarted      = "reconnect started"
+	reconnectEventSucceeded    = "reconnect succeeded"
+	reconnectEventFailed       = "reconnect failed"
+)
+
+// Reconnect retry settings used when the settings leave them unset
+const (
+	defaultReconnectAttempts  = 5
+	defaultReconnectBaseDelay = 2 * time.Second
+	maxReconnectDelay         = time.Minute
+)
+
+// reconnectNotificationTimeout bounds the delivery of a reconnect notification, which
+// outlives the cancellation of the service context
+const reconnectNotificationTimeout = 30 * time.Second
+
+// defaultCriticalErrorPatterns trigger a reconnect when no critical error patterns are configured
+var defaultCriticalErrorPatterns = []string{"auth_key_unregistered", "connection_not_inited", "session_revoked"}
+
 type AuthManagerImpl struct {
 	api             *tg.Client
 	botApi          *tg.Client
@@ -26,22 +49,56 @@ type AuthManagerImpl struct {
 	stopCh          chan struct{}
 	wg              sync.WaitGroup
 	monitor         authInterfaces.GiftMonitorAndAuthController
+	notifier        authInterfaces.ReconnectNotifier
+	globalCancel    context.CancelFunc
 	infoLogsWriter  authInterfaces.InfoLogger
 	errorLogsWriter authInterfaces.ErrorLogger
+
+	// criticalPatterns are the lowercased error substrings that trigger a reconnect
+	criticalPatterns []string
+
+	// lastNotification is closed once the latest reconnect notification was delivered,
+	// the next notification waits for it to keep the events in order
+	lastNotification chan struct{}
+	notificationMu   sync.Mutex
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// updateHandlerProvider is implemented by session managers whose login flow needs the user client updates
+type updateHandlerProvider interface {
+	UpdateHandler() telegram.UpdateHandler
 }
 
 func NewAuthManager(sessionManager authInterfaces.SessionManager, apiChecker authInterfaces.ApiChecker, cfg *config.TgSettings, infoLogsWriter authInterfaces.InfoLogger, errorLogsWriter authInterfaces.ErrorLogger) *AuthManagerImpl {
 	return &AuthManagerImpl{
-		sessionManager:  sessionManager,
-		apiChecker:      apiChecker,
-		cfg:             cfg,
-		reconnect:       make(chan struct{}, 1),
-		stopCh:          make(chan struct{}),
-		infoLogsWriter:  infoLogsWriter,
-		errorLogsWriter: errorLogsWriter,
+		sessionManager:   sessionManager,
+		apiChecker:       apiChecker,
+		cfg:              cfg,
+		reconnect:        make(chan struct{}, 1),
+		stopCh:           make(chan struct{}),
+		infoLogsWriter:   infoLogsWriter,
+		errorLogsWriter:  errorLogsWriter,
+		criticalPatterns: criticalErrorPatterns(cfg),
+		now:              time.Now,
 	}
 }
 
+// criticalErrorPatterns returns the lowercased critical error patterns of the settings,
+// the defaults when none are configured.
+func criticalErrorPatterns(cfg *config.TgSettings) []string {
+	if cfg == nil || len(cfg.CriticalErrorPatterns) == 0 {
+		return defaultCriticalErrorPatterns
+	}
+
+	patterns := make([]string, 0, len(cfg.CriticalErrorPatterns))
+	for _, pattern := range cfg.CriticalErrorPatterns {
+		patterns = append(patterns, strings.ToLower(pattern))
+	}
+	return patterns
+}
+
 func (f *AuthManagerImpl) InitClient(ctx context.Context) (*tg.Client, error) {
 	if f.cfg == nil {
 		return nil, errors.New("configuration is nil")
@@ -51,15 +108,15 @@ func (f *AuthManagerImpl) InitClient(ctx context.Context) (*tg.Client, error) {
 		return nil, errors.New("session manager is nil")
 	}
 
-	opts := telegram.Options{
-		SessionStorage: &telegram.FileSessionStorage{
-			Path: "session.json",
-		},
+	opts, err := sessions.ClientOptions(f.cfg, f.cfg.UserSessionFile())
+	if err != nil {
+		return nil, err
 	}
 
-	// Set datacenter if specified
-	if f.cfg.Datacenter > 0 {
-		opts.DC = f.cfg.Datacenter
+	if provider, ok := f.sessionManager.(updateHandlerProvider); ok {
+		if handler := provider.UpdateHandler(); handler != nil {
+			opts.UpdateHandler = handler
+		}
 	}
 
 	client := telegram.NewClient(f.cfg.AppId, f.cfg.ApiHash, opts)
@@ -88,6 +145,7 @@ func (f *AuthManagerImpl) RunApiChecker(ctx context.Context) {
 		ticker := time.NewTicker(2 * time.Second)
 		defer ticker.Stop()
 
+		since := f.now()
 		for {
 			select {
 			case <-ctx.Done():
@@ -97,21 +155,7 @@ func (f *AuthManagerImpl) RunApiChecker(ctx context.Context) {
 				f.infoLogsWriter.LogInfo("API monitoring stopped due to stop signal")
 				return
 			case <-ticker.C:
-				if err := f.apiChecker.Run(ctx); err != nil {
-					f.errorLogsWriter.LogErrorf("API check failed: %v", err)
-					if f.isCriticalError(err) {
-						f.errorLogsWriter.LogErrorf("Critical API error detected, triggering reconnect: %v", err)
-						select {
-						case f.reconnect <- struct{}{}:
-							f.stopCh <- struct{}{}
-							f.infoLogsWriter.LogInfo("Reconnect signal sent")
-						default:
-							f.errorLogsWriter.LogError("Reconnect channel is full")
-						}
-					}
-				} else {
-					f.infoLogsWriter.LogInfo("API check successful")
-				}
+				since = f.checkApi(ctx, since)
 			}
 		}
 	}()
@@ -123,16 +167,84 @@ func (f *AuthManagerImpl) RunApiChecker(ctx context.Context) {
 	}()
 }
 
+// triggerReconnect signals the reconnect handler and then reports the detected disconnect,
+// so that a slow notification never delays the reconnect.
+func (f *AuthManagerImpl) triggerReconnect(ctx context.Context, err error) {
+	f.errorLogsWriter.LogErrorf("API connection lost, triggering reconnect: %v", err)
+	select {
+	case f.reconnect <- struct{}{}:
+		f.stopCh <- struct{}{}
+		f.infoLogsWriter.LogInfo("Reconnect signal sent")
+	default:
+		f.errorLogsWriter.LogError("Reconnect channel is full")
+	}
+	f.notifyReconnect(ctx, reconnectEventDisconnected, err)
+}
+
+// checkApi runs one API check and triggers a reconnect on a critical error or when
+// no check has succeeded within ApiStaleTimeout.
+//
+// Parameters:
+//   - ctx: context for cancellation
+//   - since: start of the staleness window, used while the last success is older
+//
+// Returns:
+//   - time.Time: start of the next staleness window, reset when a reconnect is triggered
+func (f *AuthManagerImpl) checkApi(ctx context.Context, since time.Time) time.Time {
+	f.mu.RLock()
+	checker := f.apiChecker
+	f.mu.RUnlock()
+
+	if err := checker.Run(ctx); err != nil {
+		f.errorLogsWriter.LogErrorf("API check failed: %v", err)
+		if f.isCriticalError(err) {
+			f.triggerReconnect(ctx, err)
+			return f.now()
+		}
+	} else {
+		f.infoLogsWriter.LogInfo("API check successful")
+	}
+
+	if err := f.staleError(checker, since); err != nil {
+		f.triggerReconnect(ctx, err)
+		return f.now()
+	}
+	return since
+}
+
+// staleError reports that no API check has succeeded within ApiStaleTimeout, counted
+// from the last success or from since if the last success is older.
+//
+// Returns:
+//   - error: description of the stale connection, nil if it is fresh or the check is disabled
+func (f *AuthManagerImpl) staleError(checker authInterfaces.ApiChecker, since time.Time) error {
+	if f.cfg == nil || f.cfg.ApiStaleTimeout <= 0 {
+		return nil
+	}
+
+	timeout := time.Duration(f.cfg.ApiStaleTimeout*1000) * time.Millisecond
+	last := checker.LastSuccess()
+	if last.Before(since) {
+		last = since
+	}
+	if f.now().Sub(last) < timeout {
+		return nil
+	}
+	return fmt.Errorf("no successful API check within %v", timeout)
+}
+
 func (f *AuthManagerImpl) isCriticalError(err error) bool {
 	if err == nil {
 		return false
 	}
 
 	errStr := strings.ToLower(err.Error())
-
-	return strings.Contains(errStr, "auth_key_unregistered") ||
-		strings.Contains(errStr, "connection_not_inited") ||
-		strings.Contains(errStr, "session_revoked")
+	for _, pattern := range f.criticalPatterns {
+		if strings.Contains(errStr, pattern) {
+			return true
+		}
+	}
+	return false
 }
 
 func (f *AuthManagerImpl) handleReconnectSignals(ctx context.Context) {
@@ -146,13 +258,13 @@ func (f *AuthManagerImpl) handleReconnectSignals(ctx context.Context) {
 				f.monitor.Pause()
 			}
 
-			if _, err := f.Reconnect(ctx); err != nil {
+			f.notifyReconnect(ctx, reconnectEventStarted, nil)
+			if err := f.reconnectWithRetry(ctx); err != nil {
 				f.errorLogsWriter.LogErrorf("Reconnect failed: %v", err)
-				if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "deadline") {
-					f.errorLogsWriter.LogError("Reconnection timeout, exiting program")
-					os.Exit(1)
-				}
+				<-f.notifyReconnect(ctx, reconnectEventFailed, err)
+				f.cancelGlobal()
 			} else {
+				f.notifyReconnect(ctx, reconnectEventSucceeded, nil)
 				if f.monitor != nil {
 					f.infoLogsWriter.LogInfo("Resuming gift monitoring after reconnection")
 					f.monitor.Resume()
@@ -170,6 +282,96 @@ func (f *AuthManagerImpl) handleReconnectSignals(ctx context.Context) {
 	}
 }
 
+// reconnectWithRetry reconnects the client, retrying failed attempts with an exponential
+// backoff up to ReconnectMaxAttempts attempts.
+//
+// Parameters:
+//   - ctx: context for cancellation
+//
+// Returns:
+//   - error: last reconnect error once every attempt failed, or the context error
+func (f *AuthManagerImpl) reconnectWithRetry(ctx context.Context) error {
+	attempts, delay := defaultReconnectAttempts, defaultReconnectBaseDelay
+	if f.cfg != nil && f.cfg.ReconnectMaxAttempts > 0 {
+		attempts = f.cfg.ReconnectMaxAttempts
+	}
+	if f.cfg != nil && f.cfg.ReconnectBaseDelay > 0 {
+		delay = time.Duration(f.cfg.ReconnectBaseDelay*1000) * time.Millisecond
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if _, err = f.Reconnect(ctx); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		f.errorLogsWriter.LogErrorf("Reconnect attempt %d/%d failed, retrying in %v: %v", attempt, attempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+	return fmt.Errorf("reconnect failed after %d attempts: %w", attempts, err)
+}
+
+// cancelGlobal stops the whole service through the global cancel function once the
+// reconnect attempts are exhausted.
+func (f *AuthManagerImpl) cancelGlobal() {
+	f.mu.RLock()
+	cancel := f.globalCancel
+	f.mu.RUnlock()
+
+	if cancel == nil {
+		f.errorLogsWriter.LogError("Reconnect attempts exhausted, global cancel is not set")
+		return
+	}
+	f.errorLogsWriter.LogError("Reconnect attempts exhausted, stopping the service")
+	cancel()
+}
+
+// notifyReconnect sends a reconnect lifecycle notification in the background if reconnect
+// notifications are enabled and a notifier is set, so that notification retries never
+// hold up the reconnect. Notifications are delivered in the order of the events and are
+// not cut short by the cancellation of ctx, so the failure reported right before the
+// service stops still arrives. Notification errors are only logged.
+//
+// Returns:
+//   - <-chan struct{}: closed once the notification was delivered or skipped
+func (f *AuthManagerImpl) notifyReconnect(ctx context.Context, event string, err error) <-chan struct{} {
+	f.mu.RLock()
+	notifier := f.notifier
+	f.mu.RUnlock()
+
+	delivered := make(chan struct{})
+	if notifier == nil || f.cfg == nil || !f.cfg.NotifyReconnect {
+		close(delivered)
+		return delivered
+	}
+
+	f.notificationMu.Lock()
+	previous := f.lastNotification
+	f.lastNotification = delivered
+	f.notificationMu.Unlock()
+
+	go func() {
+		defer close(delivered)
+		if previous != nil {
+			<-previous
+		}
+		sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reconnectNotificationTimeout)
+		defer cancel()
+		if notifyErr := notifier.SendReconnectNotification(sendCtx, event, err); notifyErr != nil {
+			f.errorLogsWriter.LogErrorf("Failed to send reconnect notification: %v", notifyErr)
+		}
+	}()
+	return delivered
+}
+
 func (f *AuthManagerImpl) InitBotClient(ctx context.Context) (*tg.Client, error) {
 	if f.sessionManager == nil {
 		return nil, errors.New("session manager is nil")
@@ -237,9 +439,40 @@ func (f *AuthManagerImpl) SetApiChecker(apiChecker authInterfaces.ApiChecker) {
 	f.apiChecker = apiChecker
 }
 
+// LastApiSuccess returns the time of the last successful check of the current API checker.
+// The checker is replaced on every reconnect, so callers must not keep the checker itself.
+//
+// Returns:
+//   - time.Time: time of the last successful check, the zero time if there is none
+func (f *AuthManagerImpl) LastApiSuccess() time.Time {
+	f.mu.RLock()
+	checker := f.apiChecker
+	f.mu.RUnlock()
+
+	if checker == nil {
+		return time.Time{}
+	}
+	return checker.LastSuccess()
+}
+
 func (f *AuthManagerImpl) SetMonitor(monitor authInterfaces.GiftMonitorAndAuthController) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.monitor = monitor
 	f.infoLogsWriter.LogInfo("Gift monitor set for auth manager")
 }
+
+// SetGlobalCancel sets the function stopping the whole service once reconnecting fails.
+func (f *AuthManagerImpl) SetGlobalCancel(cancel context.CancelFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.globalCancel = cancel
+}
+
+// SetReconnectNotifier sets the notifier used for reconnect lifecycle notifications.
+// Notifications are sent only when NotifyReconnect is enabled in the settings.
+func (f *AuthManagerImpl) SetReconnectNotifier(notifier authInterfaces.ReconnectNotifier) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.notifier = notifier
+}
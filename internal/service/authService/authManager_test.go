@@ -2,14 +2,22 @@ package authService
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"gift-buyer/internal/config"
+	"gift-buyer/internal/infrastructure/healthServer"
 	"gift-buyer/internal/infrastructure/logsWriter/logTypes"
+	"gift-buyer/internal/service/authService/authInterfaces"
 
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockLogsWriter для тестирования
@@ -255,6 +263,10 @@ func (m *MockApiChecker) Run(ctx context.Context) error {
 	return nil
 }
 
+func (m *MockApiChecker) LastSuccess() time.Time {
+	return time.Time{}
+}
+
 func (m *MockApiChecker) Stop() {
 	// Mock implementation
 }
@@ -268,3 +280,380 @@ func (m *MockGiftMonitor) Resume() {}
 func (m *MockGiftMonitor) IsPaused() bool {
 	return false
 }
+
+// recordingNotifier records reconnect events in the order they are sent, like a real
+// notifier it fails to send on a cancelled context
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []string
+	errs   []error
+	sent   chan struct{}
+}
+
+func newRecordingNotifier() *recordingNotifier {
+	return &recordingNotifier{sent: make(chan struct{}, 10)}
+}
+
+func (n *recordingNotifier) SendReconnectNotification(ctx context.Context, event string, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	n.mu.Lock()
+	n.events = append(n.events, event)
+	n.errs = append(n.errs, err)
+	n.mu.Unlock()
+	n.sent <- struct{}{}
+	return nil
+}
+
+func (n *recordingNotifier) wait(t *testing.T, count int) ([]string, []error) {
+	for i := 0; i < count; i++ {
+		select {
+		case <-n.sent:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected %d notifications, got %d", count, i)
+		}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.events...), append([]error(nil), n.errs...)
+}
+
+// blockingNotifier holds every reconnect notification until release is closed
+type blockingNotifier struct {
+	release chan struct{}
+	sent    chan string
+}
+
+func (n *blockingNotifier) SendReconnectNotification(ctx context.Context, event string, err error) error {
+	<-n.release
+	n.sent <- event
+	return nil
+}
+
+type connectingSessionManager struct {
+	MockSessionManager
+}
+
+func (m *connectingSessionManager) InitUserAPI(client *telegram.Client, ctx context.Context) (*tg.Client, error) {
+	return nil, nil
+}
+
+func TestAuthManagerImpl_ReconnectNotifications(t *testing.T) {
+	newManager := func(sessionManager authInterfaces.SessionManager, notify bool) (*AuthManagerImpl, *recordingNotifier) {
+		manager := NewAuthManager(sessionManager, nil, &config.TgSettings{NotifyReconnect: notify, ReconnectBaseDelay: 0.001}, &MockLogsWriter{}, &MockLogsWriter{})
+		notifier := newRecordingNotifier()
+		manager.SetReconnectNotifier(notifier)
+		manager.SetMonitor(&MockGiftMonitor{})
+		return manager, notifier
+	}
+
+	t.Run("обрыв соединения", func(t *testing.T) {
+		manager, notifier := newManager(&MockSessionManager{}, true)
+		go func() { <-manager.stopCh }()
+
+		manager.triggerReconnect(context.Background(), assert.AnError)
+
+		events, errs := notifier.wait(t, 1)
+		assert.Equal(t, []string{reconnectEventDisconnected}, events)
+		assert.Equal(t, assert.AnError, errs[0])
+	})
+
+	t.Run("медленное уведомление не задерживает переподключение", func(t *testing.T) {
+		manager := NewAuthManager(&MockSessionManager{}, nil, &config.TgSettings{NotifyReconnect: true}, &MockLogsWriter{}, &MockLogsWriter{})
+		notifier := &blockingNotifier{release: make(chan struct{}), sent: make(chan string, 2)}
+		manager.SetReconnectNotifier(notifier)
+		go func() { <-manager.stopCh }()
+
+		manager.triggerReconnect(context.Background(), assert.AnError)
+		assert.Len(t, manager.reconnect, 1, "reconnect must be signalled before the notification is delivered")
+
+		manager.notifyReconnect(context.Background(), reconnectEventStarted, nil)
+		close(notifier.release)
+		assert.Equal(t, reconnectEventDisconnected, <-notifier.sent)
+		assert.Equal(t, reconnectEventStarted, <-notifier.sent)
+	})
+
+	t.Run("неудачное переподключение", func(t *testing.T) {
+		manager, notifier := newManager(&MockSessionManager{}, true)
+		done := make(chan struct{})
+		go func() {
+			manager.handleReconnectSignals(context.Background())
+			close(done)
+		}()
+
+		manager.reconnect <- struct{}{}
+		events, errs := notifier.wait(t, 2)
+		assert.Equal(t, []string{reconnectEventStarted, reconnectEventFailed}, events)
+		assert.Error(t, errs[1])
+
+		close(manager.stopCh)
+		<-done
+	})
+
+	t.Run("успешное переподключение", func(t *testing.T) {
+		manager, notifier := newManager(&connectingSessionManager{}, true)
+		done := make(chan struct{})
+		go func() {
+			manager.handleReconnectSignals(context.Background())
+			close(done)
+		}()
+
+		manager.reconnect <- struct{}{}
+		events, _ := notifier.wait(t, 2)
+		assert.Equal(t, []string{reconnectEventStarted, reconnectEventSucceeded}, events)
+
+		close(manager.stopCh)
+		<-done
+	})
+
+	t.Run("без флага уведомления не отправляются", func(t *testing.T) {
+		manager, notifier := newManager(&MockSessionManager{}, false)
+		done := make(chan struct{})
+		go func() {
+			manager.handleReconnectSignals(context.Background())
+			close(done)
+		}()
+
+		manager.reconnect <- struct{}{}
+		// The failed attempt is followed by the next loop iteration, stop it
+		time.Sleep(50 * time.Millisecond)
+		close(manager.stopCh)
+		<-done
+
+		notifier.mu.Lock()
+		defer notifier.mu.Unlock()
+		assert.Empty(t, notifier.events)
+	})
+}
+
+// scriptedApiChecker returns the scripted check results in order and records the
+// time of every successful check on the test clock
+type scriptedApiChecker struct {
+	results     []error
+	clock       *time.Time
+	lastSuccess time.Time
+}
+
+func (c *scriptedApiChecker) Run(ctx context.Context) error {
+	err := c.results[0]
+	c.results = c.results[1:]
+	if err == nil {
+		c.lastSuccess = *c.clock
+	}
+	return err
+}
+
+func (c *scriptedApiChecker) LastSuccess() time.Time {
+	return c.lastSuccess
+}
+
+func (c *scriptedApiChecker) Stop() {}
+
+func TestAuthManagerImpl_LastApiSuccess(t *testing.T) {
+	manager := NewAuthManager(&MockSessionManager{}, nil, &config.TgSettings{}, &MockLogsWriter{}, &MockLogsWriter{})
+	assert.True(t, manager.LastApiSuccess().IsZero())
+
+	t.Run("healthz восстанавливается после замены проверки", func(t *testing.T) {
+		clock := time.Now()
+		manager.SetApiChecker(&scriptedApiChecker{clock: &clock, lastSuccess: clock.Add(-time.Hour)})
+		server := healthServer.NewHealthServer("", manager.LastApiSuccess, func() bool { return true }, time.Minute, &MockLogsWriter{})
+		healthz := func() int {
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			return rec.Code
+		}
+		assert.Equal(t, http.StatusServiceUnavailable, healthz())
+
+		// Reconnect installs a new checker, the old one never succeeds again
+		reconnected := &scriptedApiChecker{results: []error{nil}, clock: &clock}
+		manager.SetApiChecker(reconnected)
+		require.NoError(t, reconnected.Run(context.Background()))
+
+		assert.Equal(t, clock, manager.LastApiSuccess())
+		assert.Equal(t, http.StatusOK, healthz())
+	})
+}
+
+func TestAuthManagerImpl_CheckApi_Staleness(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	failure := errors.New("rpc error code 500: INTERNAL")
+
+	// runChecks runs one check every 2 seconds of the test clock and returns the
+	// number of the check that triggered a reconnect, 0 if none did
+	runChecks := func(t *testing.T, staleTimeout float64, results ...error) int {
+		t.Helper()
+
+		clock := start
+		checker := &scriptedApiChecker{results: results, clock: &clock}
+		manager := NewAuthManager(&MockSessionManager{}, checker, &config.TgSettings{ApiStaleTimeout: staleTimeout}, &MockLogsWriter{}, &MockLogsWriter{})
+		manager.now = func() time.Time { return clock }
+		go func() { <-manager.stopCh }()
+
+		since := clock
+		for i := range results {
+			clock = clock.Add(2 * time.Second)
+			since = manager.checkApi(context.Background(), since)
+			if len(manager.reconnect) > 0 {
+				return i + 1
+			}
+		}
+		return 0
+	}
+
+	t.Run("успешные проверки", func(t *testing.T) {
+		assert.Zero(t, runChecks(t, 5, nil, nil, nil, nil, nil, nil))
+	})
+
+	t.Run("редкие успехи между ошибками", func(t *testing.T) {
+		assert.Zero(t, runChecks(t, 5, failure, nil, failure, failure, nil, failure))
+	})
+
+	t.Run("ошибки дольше таймаута", func(t *testing.T) {
+		assert.Equal(t, 4, runChecks(t, 5, nil, failure, failure, failure, failure, failure))
+	})
+
+	t.Run("ни одной успешной проверки", func(t *testing.T) {
+		assert.Equal(t, 3, runChecks(t, 5, failure, failure, failure, failure))
+	})
+
+	t.Run("выключено", func(t *testing.T) {
+		assert.Zero(t, runChecks(t, 0, failure, failure, failure, failure, failure, failure))
+	})
+
+	t.Run("критическая ошибка сразу", func(t *testing.T) {
+		assert.Equal(t, 1, runChecks(t, 0, errors.New("rpc error code 401: AUTH_KEY_UNREGISTERED")))
+	})
+}
+
+func TestAuthManagerImpl_CriticalErrorPatterns(t *testing.T) {
+	triggers := func(cfg *config.TgSettings, checkErr error) bool {
+		checker := &scriptedApiChecker{results: []error{checkErr}, clock: &time.Time{}}
+		manager := NewAuthManager(&MockSessionManager{}, checker, cfg, &MockLogsWriter{}, &MockLogsWriter{})
+		go func() { <-manager.stopCh }()
+
+		manager.checkApi(context.Background(), time.Time{})
+		return len(manager.reconnect) > 0
+	}
+
+	t.Run("шаблоны по умолчанию", func(t *testing.T) {
+		cfg := &config.TgSettings{}
+
+		assert.True(t, triggers(cfg, errors.New("rpc error code 401: AUTH_KEY_UNREGISTERED")))
+		assert.True(t, triggers(cfg, errors.New("rpc error code 401: SESSION_REVOKED")))
+		assert.False(t, triggers(cfg, errors.New("rpc error code 500: INTERNAL")))
+	})
+
+	t.Run("настроенные шаблоны без учета регистра", func(t *testing.T) {
+		cfg := &config.TgSettings{CriticalErrorPatterns: []string{"USER_DEACTIVATED", "connection reset"}}
+
+		assert.True(t, triggers(cfg, errors.New("rpc error code 401: USER_DEACTIVATED_BAN")))
+		assert.True(t, triggers(cfg, errors.New("read tcp: Connection Reset by peer")))
+		assert.False(t, triggers(cfg, errors.New("rpc error code 401: AUTH_KEY_UNREGISTERED")), "defaults are replaced")
+		assert.False(t, triggers(cfg, errors.New("rpc error code 500: INTERNAL")))
+	})
+
+	t.Run("без настроек", func(t *testing.T) {
+		assert.True(t, triggers(nil, errors.New("CONNECTION_NOT_INITED")))
+	})
+}
+
+// flakySessionManager fails the first failures logins and counts the attempts
+type flakySessionManager struct {
+	MockSessionManager
+	mu       sync.Mutex
+	failures int
+	attempts int
+}
+
+func (m *flakySessionManager) InitUserAPI(client *telegram.Client, ctx context.Context) (*tg.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts++
+	if m.attempts <= m.failures {
+		return nil, errors.New("dial tcp: i/o timeout")
+	}
+	return nil, nil
+}
+
+func (m *flakySessionManager) attemptCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.attempts
+}
+
+func TestAuthManagerImpl_ReconnectRetry(t *testing.T) {
+	newManager := func(sessionManager authInterfaces.SessionManager) (*AuthManagerImpl, *recordingNotifier, context.Context) {
+		manager := NewAuthManager(sessionManager, nil, &config.TgSettings{NotifyReconnect: true, ReconnectMaxAttempts: 3, ReconnectBaseDelay: 0.001}, &MockLogsWriter{}, &MockLogsWriter{})
+		notifier := newRecordingNotifier()
+		manager.SetReconnectNotifier(notifier)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		manager.SetGlobalCancel(cancel)
+		return manager, notifier, ctx
+	}
+
+	t.Run("временная ошибка проходит после повтора", func(t *testing.T) {
+		sessionManager := &flakySessionManager{failures: 2}
+		manager, notifier, ctx := newManager(sessionManager)
+		done := make(chan struct{})
+		go func() {
+			manager.handleReconnectSignals(ctx)
+			close(done)
+		}()
+
+		manager.reconnect <- struct{}{}
+		events, _ := notifier.wait(t, 2)
+		assert.Equal(t, []string{reconnectEventStarted, reconnectEventSucceeded}, events)
+		assert.Equal(t, 3, sessionManager.attemptCount())
+
+		close(manager.stopCh)
+		<-done
+		assert.NoError(t, ctx.Err(), "service cancelled after a successful reconnect")
+	})
+
+	t.Run("исчерпанные попытки останавливают сервис", func(t *testing.T) {
+		sessionManager := &flakySessionManager{failures: 10}
+		manager, notifier, ctx := newManager(sessionManager)
+		done := make(chan struct{})
+		go func() {
+			manager.handleReconnectSignals(ctx)
+			close(done)
+		}()
+
+		manager.reconnect <- struct{}{}
+		select {
+		case <-ctx.Done():
+		case <-time.After(2 * time.Second):
+			t.Fatal("global cancel was not called")
+		}
+		events, errs := notifier.wait(t, 2)
+		assert.Equal(t, []string{reconnectEventStarted, reconnectEventFailed}, events)
+		require.Error(t, errs[1])
+		assert.Contains(t, errs[1].Error(), "after 3 attempts")
+		assert.Equal(t, 3, sessionManager.attemptCount())
+
+		<-done
+	})
+
+	t.Run("уведомление доставляется после отмены контекста", func(t *testing.T) {
+		manager, notifier, ctx := newManager(&flakySessionManager{})
+		manager.cancelGlobal()
+		require.Error(t, ctx.Err())
+
+		<-manager.notifyReconnect(ctx, reconnectEventFailed, assert.AnError)
+
+		events, _ := notifier.wait(t, 1)
+		assert.Equal(t, []string{reconnectEventFailed}, events)
+	})
+
+	t.Run("отмена контекста прерывает ожидание", func(t *testing.T) {
+		manager := NewAuthManager(&flakySessionManager{failures: 10}, nil, &config.TgSettings{ReconnectBaseDelay: 60}, &MockLogsWriter{}, &MockLogsWriter{})
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		err := manager.reconnectWithRetry(ctx)
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,84 @@
+package sessions
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"gift-buyer/internal/config"
+	"gift-buyer/pkg/logger"
+	"os"
+	"strings"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/telegram/auth/qrlogin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// UpdateHandler returns the handler the user client must receive its updates through,
+// nil when the configured login method doesn't need updates. The QR login learns from
+// the updates that the login link was confirmed on another device.
+func (f *sessionManagerImpl) UpdateHandler() telegram.UpdateHandler {
+	if f.dispatcher == nil {
+		return nil
+	}
+	return f.dispatcher
+}
+
+// authenticate logs the client in with the method selected by AuthMethod,
+// the phone code flow when it is not set.
+//
+// Parameters:
+//   - ctx: context for cancellation control
+//   - client: running Telegram client without an authorized session
+//
+// Returns:
+//   - error: login error or an unsupported login method
+func (f *sessionManagerImpl) authenticate(ctx context.Context, client *telegram.Client) error {
+	switch method := strings.ToLower(f.cfg.AuthMethod); method {
+	case "", config.AuthMethodCode:
+		return f.codeLogin(ctx, client)
+	case config.AuthMethodQR:
+		return f.qrLogin(ctx, client)
+	default:
+		return fmt.Errorf("unsupported auth method %q, use %q or %q", f.cfg.AuthMethod, config.AuthMethodCode, config.AuthMethodQR)
+	}
+}
+
+// loginWithCode logs in with the phone number and the code entered on stdin.
+func (f *sessionManagerImpl) loginWithCode(ctx context.Context, client *telegram.Client) error {
+	// codePrompt provides interactive code input for 2FA verification
+	codePrompt := func(ctx context.Context, sentCode *tg.AuthSentCode) (string, error) {
+		fmt.Print("Enter code: ")
+		code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(code), nil
+	}
+
+	return auth.NewFlow(
+		auth.Constant(f.cfg.Phone, f.cfg.Password, auth.CodeAuthenticatorFunc(codePrompt)),
+		auth.SendCodeOptions{},
+	).Run(ctx, client.Auth())
+}
+
+// loginWithQR prints a login link and waits until it is confirmed from a logged in
+// Telegram app (Settings > Devices > Link Desktop Device). The link is refreshed
+// whenever it expires. The 2FA password is sent afterwards if the account has one.
+func (f *sessionManagerImpl) loginWithQR(ctx context.Context, client *telegram.Client) error {
+	if f.loginTokens == nil {
+		return fmt.Errorf("qr login requires the client to receive updates through the session manager")
+	}
+
+	_, err := client.QR().Auth(ctx, f.loginTokens, func(ctx context.Context, token qrlogin.Token) error {
+		fmt.Printf("Open this link or scan it as a QR code in Telegram (Settings > Devices > Link Desktop Device):\n%s\n", token.URL())
+		logger.GlobalLogger.Infof("QR login link expires at %s", token.Expires().Format("15:04:05"))
+		return nil
+	})
+	if tgerr.Is(err, "SESSION_PASSWORD_NEEDED") {
+		_, err = client.Auth().Password(ctx, f.cfg.Password)
+	}
+	return err
+}
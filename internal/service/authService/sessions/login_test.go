@@ -0,0 +1,73 @@
+package sessions
+
+import (
+	"context"
+	"testing"
+
+	"gift-buyer/internal/config"
+
+	"github.com/gotd/td/telegram"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubLogins replaces both login flows and records which one ran
+func stubLogins(manager *sessionManagerImpl) *[]string {
+	var calls []string
+	manager.codeLogin = func(ctx context.Context, client *telegram.Client) error {
+		calls = append(calls, config.AuthMethodCode)
+		return nil
+	}
+	manager.qrLogin = func(ctx context.Context, client *telegram.Client) error {
+		calls = append(calls, config.AuthMethodQR)
+		return nil
+	}
+	return &calls
+}
+
+func TestSessionManager_Authenticate(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		want    []string
+		wantErr bool
+	}{
+		{name: "по умолчанию вход по коду", method: "", want: []string{config.AuthMethodCode}},
+		{name: "вход по коду", method: config.AuthMethodCode, want: []string{config.AuthMethodCode}},
+		{name: "вход по QR", method: config.AuthMethodQR, want: []string{config.AuthMethodQR}},
+		{name: "регистр не важен", method: "QR", want: []string{config.AuthMethodQR}},
+		{name: "неизвестный способ", method: "sms", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewSessionManager(&config.TgSettings{AuthMethod: tt.method})
+			calls := stubLogins(manager)
+
+			err := manager.authenticate(context.Background(), nil)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, *calls)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, *calls)
+		})
+	}
+}
+
+func TestSessionManager_UpdateHandler(t *testing.T) {
+	t.Run("вход по коду не требует обновлений", func(t *testing.T) {
+		manager := NewSessionManager(&config.TgSettings{})
+
+		assert.Nil(t, manager.UpdateHandler())
+		assert.Error(t, manager.loginWithQR(context.Background(), nil))
+	})
+
+	t.Run("вход по QR получает обновления", func(t *testing.T) {
+		manager := NewSessionManager(&config.TgSettings{AuthMethod: config.AuthMethodQR})
+
+		assert.NotNil(t, manager.UpdateHandler())
+		assert.NotNil(t, manager.loginTokens)
+	})
+}
@@ -0,0 +1,81 @@
+package sessions
+
+import (
+	"encoding/hex"
+	"fmt"
+	"gift-buyer/internal/config"
+	"strings"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/dcs"
+	"golang.org/x/net/proxy"
+)
+
+// ClientOptions builds the Telegram client options shared by the user and bot clients:
+// the session storage, encrypted when a passphrase is set, the datacenter and, when
+// configured, the proxy resolver. The
+// reconnect path creates its clients through the same options, so it uses the proxy too.
+//
+// Parameters:
+//   - cfg: Telegram settings
+//   - sessionPath: session file of the client
+//
+// Returns:
+//   - telegram.Options: client options
+//   - error: invalid proxy settings
+func ClientOptions(cfg *config.TgSettings, sessionPath string) (telegram.Options, error) {
+	opts := telegram.Options{
+		SessionStorage: &telegram.FileSessionStorage{
+			Path: sessionPath,
+		},
+	}
+
+	// Encrypt the session file if a passphrase is configured
+	if passphrase := cfg.SessionKey(); passphrase != "" {
+		opts.SessionStorage = NewEncryptedSessionStorage(sessionPath, passphrase)
+	}
+
+	// Set datacenter if specified
+	if cfg.Datacenter > 0 {
+		opts.DC = cfg.Datacenter
+	}
+
+	if cfg.Proxy.Enabled() {
+		resolver, err := proxyResolver(cfg.Proxy)
+		if err != nil {
+			return telegram.Options{}, fmt.Errorf("invalid proxy settings: %w", err)
+		}
+		opts.Resolver = resolver
+	}
+
+	return opts, nil
+}
+
+// proxyResolver creates the datacenter resolver connecting through the configured proxy.
+func proxyResolver(params config.ProxyParams) (dcs.Resolver, error) {
+	switch strings.ToLower(params.Type) {
+	case "", config.ProxyTypeSOCKS5:
+		var auth *proxy.Auth
+		if params.Username != "" {
+			auth = &proxy.Auth{User: params.Username, Password: params.Password}
+		}
+
+		dialer, err := proxy.SOCKS5("tcp", params.Address, auth, proxy.Direct)
+		if err != nil {
+			return nil, err
+		}
+		contextDialer, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("socks5 dialer does not support contexts")
+		}
+		return dcs.Plain(dcs.PlainOptions{Dial: contextDialer.DialContext}), nil
+	case config.ProxyTypeMTProxy:
+		secret, err := hex.DecodeString(params.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("mtproxy secret must be hex encoded: %w", err)
+		}
+		return dcs.MTProxy(params.Address, secret, dcs.MTProxyOptions{})
+	default:
+		return nil, fmt.Errorf("unsupported proxy type %q", params.Type)
+	}
+}
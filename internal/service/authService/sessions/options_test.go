@@ -0,0 +1,87 @@
+package sessions
+
+import (
+	"testing"
+
+	"gift-buyer/internal/config"
+
+	"github.com/gotd/td/telegram"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientOptions(t *testing.T) {
+	t.Run("без прокси", func(t *testing.T) {
+		opts, err := ClientOptions(&config.TgSettings{Datacenter: 2}, "session.json")
+
+		require.NoError(t, err)
+		assert.Nil(t, opts.Resolver)
+		assert.Equal(t, 2, opts.DC)
+		assert.Equal(t, "session.json", opts.SessionStorage.(*telegram.FileSessionStorage).Path)
+	})
+
+	t.Run("шифрование сессии", func(t *testing.T) {
+		opts, err := ClientOptions(&config.TgSettings{SessionPassphrase: "passphrase"}, "session.json")
+
+		require.NoError(t, err)
+		storage, ok := opts.SessionStorage.(*EncryptedSessionStorage)
+		require.True(t, ok)
+		assert.Equal(t, "session.json", storage.Path)
+	})
+
+	t.Run("пароль сессии из переменной окружения", func(t *testing.T) {
+		t.Setenv(config.SessionPassphraseEnv, "passphrase")
+
+		opts, err := ClientOptions(&config.TgSettings{}, "session.json")
+
+		require.NoError(t, err)
+		assert.IsType(t, &EncryptedSessionStorage{}, opts.SessionStorage)
+	})
+
+	t.Run("socks5 прокси", func(t *testing.T) {
+		cfg := &config.TgSettings{Proxy: config.ProxyParams{
+			Type:     config.ProxyTypeSOCKS5,
+			Address:  "127.0.0.1:1080",
+			Username: "user",
+			Password: "pass",
+		}}
+
+		opts, err := ClientOptions(cfg, "session.json")
+
+		require.NoError(t, err)
+		assert.NotNil(t, opts.Resolver)
+	})
+
+	t.Run("mtproxy", func(t *testing.T) {
+		cfg := &config.TgSettings{Proxy: config.ProxyParams{
+			Type:    config.ProxyTypeMTProxy,
+			Address: "proxy.example.com:443",
+			Secret:  "00112233445566778899aabbccddeeff",
+		}}
+
+		opts, err := ClientOptions(cfg, "session.json")
+
+		require.NoError(t, err)
+		assert.NotNil(t, opts.Resolver)
+	})
+
+	t.Run("неверный секрет mtproxy", func(t *testing.T) {
+		cfg := &config.TgSettings{Proxy: config.ProxyParams{
+			Type:    config.ProxyTypeMTProxy,
+			Address: "proxy.example.com:443",
+			Secret:  "not-hex",
+		}}
+
+		_, err := ClientOptions(cfg, "session.json")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("неизвестный тип прокси", func(t *testing.T) {
+		cfg := &config.TgSettings{Proxy: config.ProxyParams{Type: "http", Address: "127.0.0.1:8080"}}
+
+		_, err := ClientOptions(cfg, "session.json")
+
+		assert.Error(t, err)
+	})
+}
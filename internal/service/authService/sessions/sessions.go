@@ -1,28 +1,57 @@
 package sessions
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"gift-buyer/internal/config"
 	"gift-buyer/pkg/logger"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gotd/td/telegram"
-	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/telegram/auth/qrlogin"
 	"github.com/gotd/td/tg"
 )
 
+const (
+	// defaultUserAuthTimeout bounds the user authentication flow when AuthTimeout is not set
+	defaultUserAuthTimeout = 560 * time.Second
+
+	// defaultBotAuthTimeout bounds the bot authentication when BotAuthTimeout is not set
+	defaultBotAuthTimeout = 30 * time.Second
+)
+
 type sessionManagerImpl struct {
 	cfg *config.TgSettings
+
+	// after creates the timer channel used to bound authentication, replaceable in tests
+	after func(d time.Duration) <-chan time.Time
+
+	// codeLogin and qrLogin run the login flows selected by AuthMethod, replaceable in tests
+	codeLogin func(ctx context.Context, client *telegram.Client) error
+	qrLogin   func(ctx context.Context, client *telegram.Client) error
+
+	// dispatcher receives the user client updates and signals loginTokens
+	// once a QR login is confirmed (QR login only)
+	dispatcher  *tg.UpdateDispatcher
+	loginTokens qrlogin.LoggedIn
 }
 
 func NewSessionManager(cfg *config.TgSettings) *sessionManagerImpl {
-	return &sessionManagerImpl{
-		cfg: cfg,
+	f := &sessionManagerImpl{
+		cfg:   cfg,
+		after: time.After,
+	}
+	f.codeLogin = f.loginWithCode
+	f.qrLogin = f.loginWithQR
+	if cfg != nil && strings.EqualFold(cfg.AuthMethod, config.AuthMethodQR) {
+		dispatcher := tg.NewUpdateDispatcher()
+		f.dispatcher = &dispatcher
+		f.loginTokens = qrlogin.OnLoginToken(f.dispatcher)
 	}
+	return f
 }
 
 // initClient initializes and authenticates the main Telegram user client.
@@ -33,7 +62,7 @@ func NewSessionManager(cfg *config.TgSettings) *sessionManagerImpl {
 //  1. Checks for existing valid session
 //  2. Initiates authentication flow if needed
 //  3. Handles phone number and password authentication
-//  4. Prompts for verification code interactively
+//  4. Prompts for verification code interactively or waits for a QR login confirmation
 //  5. Manages session persistence and recovery
 //
 // Parameters:
@@ -58,25 +87,11 @@ func (f *sessionManagerImpl) InitUserAPI(client *telegram.Client, ctx context.Co
 			}
 
 			logger.GlobalLogger.Info("Starting Telegram authentication...")
-			// codePrompt provides interactive code input for 2FA verification
-			codePrompt := func(ctx context.Context, sentCode *tg.AuthSentCode) (string, error) {
-				fmt.Print("Enter code: ")
-				code, err := bufio.NewReader(os.Stdin).ReadString('\n')
-				if err != nil {
-					return "", err
-				}
-				return strings.TrimSpace(code), nil
-			}
-
-			err = auth.NewFlow(
-				auth.Constant(f.cfg.Phone, f.cfg.Password, auth.CodeAuthenticatorFunc(codePrompt)),
-				auth.SendCodeOptions{},
-			).Run(ctx, client.Auth())
-			if err != nil {
+			if err := f.authenticate(ctx, client); err != nil {
 				logger.GlobalLogger.Errorf("Authentication failed: %v", err)
 				if strings.Contains(err.Error(), "AUTH_RESTART") {
 					logger.GlobalLogger.Warn("AUTH_RESTART received, clearing session file")
-					if removeErr := os.Remove("session.json"); removeErr != nil {
+					if removeErr := os.Remove(f.cfg.UserSessionFile()); removeErr != nil {
 						logger.GlobalLogger.Warnf("Failed to remove session file: %v", removeErr)
 					}
 				}
@@ -93,17 +108,12 @@ func (f *sessionManagerImpl) InitUserAPI(client *telegram.Client, ctx context.Co
 		}
 	}()
 
-	select {
-	case api := <-authDone:
-		logger.GlobalLogger.Info("Ready to start gift service")
-		return api, nil
-	case err := <-errCh:
-		return nil, fmt.Errorf("telegram client initialization failed: %w", err)
-	case <-ctx.Done():
-		return nil, fmt.Errorf("context cancelled during authentication")
-	case <-time.After(560 * time.Second):
-		return nil, fmt.Errorf("authentication timeout")
+	api, err := f.waitForAuth(ctx, authDone, errCh, f.userAuthTimeout(), "telegram client", "authentication")
+	if err != nil {
+		return nil, err
 	}
+	logger.GlobalLogger.Info("Ready to start gift service")
+	return api, nil
 }
 
 // createBotClient creates and authenticates a Telegram bot client for notifications.
@@ -127,15 +137,14 @@ func (f *sessionManagerImpl) InitBotAPI(ctx context.Context) (*tg.Client, error)
 		return nil, fmt.Errorf("bot token is not configured")
 	}
 
-	opts := telegram.Options{
-		SessionStorage: &telegram.FileSessionStorage{
-			Path: "bot_session.json",
-		},
+	if sharedSessionStorage(f.cfg.UserSessionFile(), f.cfg.BotSessionFile()) {
+		logger.GlobalLogger.Warnf("User and bot clients share the session file %s, they will overwrite each other's authorization; set distinct session_path and bot_session_path",
+			f.cfg.BotSessionFile())
 	}
 
-	// Set datacenter if specified
-	if f.cfg.Datacenter > 0 {
-		opts.DC = f.cfg.Datacenter
+	opts, err := ClientOptions(f.cfg, f.cfg.BotSessionFile())
+	if err != nil {
+		return nil, err
 	}
 
 	botClient := telegram.NewClient(f.cfg.AppId, f.cfg.ApiHash, opts)
@@ -162,15 +171,76 @@ func (f *sessionManagerImpl) InitBotAPI(ctx context.Context) (*tg.Client, error)
 		}
 	}()
 
+	api, err := f.waitForAuth(ctx, botAPI, errCh, f.botAuthTimeout(), "bot client", "bot authentication")
+	if err != nil {
+		return nil, err
+	}
+	logger.GlobalLogger.Info("Bot ready for notifications")
+	return api, nil
+}
+
+// waitForAuth blocks until the client goroutine delivers an authenticated API,
+// reports an error, the context is cancelled or the timeout elapses.
+//
+// Parameters:
+//   - ctx: context for cancellation control
+//   - apiCh: channel receiving the authenticated API client
+//   - errCh: channel receiving the client run error
+//   - timeout: hard limit for the whole authentication flow
+//   - clientName: client name used in initialization error messages
+//   - authName: flow name used in cancellation and timeout error messages
+//
+// Returns:
+//   - *tg.Client: authenticated Telegram API client
+//   - error: initialization error, cancellation or timeout
+func (f *sessionManagerImpl) waitForAuth(ctx context.Context, apiCh <-chan *tg.Client, errCh <-chan error, timeout time.Duration, clientName, authName string) (*tg.Client, error) {
+	after := f.after
+	if after == nil {
+		after = time.After
+	}
+
 	select {
-	case api := <-botAPI:
-		logger.GlobalLogger.Info("Bot ready for notifications")
+	case api := <-apiCh:
 		return api, nil
 	case err := <-errCh:
-		return nil, fmt.Errorf("bot client initialization failed: %w", err)
+		return nil, fmt.Errorf("%s initialization failed: %w", clientName, err)
 	case <-ctx.Done():
-		return nil, fmt.Errorf("context cancelled during bot authentication")
-	case <-time.After(30 * time.Second):
-		return nil, fmt.Errorf("bot authentication timeout")
+		return nil, fmt.Errorf("context cancelled during %s", authName)
+	case <-after(timeout):
+		return nil, fmt.Errorf("%s timeout", authName)
+	}
+}
+
+// userAuthTimeout returns the configured user authentication timeout or the default one.
+func (f *sessionManagerImpl) userAuthTimeout() time.Duration {
+	if f.cfg != nil && f.cfg.AuthTimeout > 0 {
+		return time.Duration(f.cfg.AuthTimeout*1000) * time.Millisecond
+	}
+	return defaultUserAuthTimeout
+}
+
+// botAuthTimeout returns the configured bot authentication timeout or the default one.
+func (f *sessionManagerImpl) botAuthTimeout() time.Duration {
+	if f.cfg != nil && f.cfg.BotAuthTimeout > 0 {
+		return time.Duration(f.cfg.BotAuthTimeout*1000) * time.Millisecond
+	}
+	return defaultBotAuthTimeout
+}
+
+// sharedSessionStorage reports whether both session paths resolve to the same file,
+// including links pointing to one file under different names.
+func sharedSessionStorage(userPath, botPath string) bool {
+	if filepath.Clean(userPath) == filepath.Clean(botPath) {
+		return true
+	}
+
+	userInfo, err := os.Stat(userPath)
+	if err != nil {
+		return false
+	}
+	botInfo, err := os.Stat(botPath)
+	if err != nil {
+		return false
 	}
+	return os.SameFile(userInfo, botInfo)
 }
@@ -0,0 +1,139 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gift-buyer/internal/config"
+
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stalledAfter returns a timer stub that fires immediately and records the requested duration
+func stalledAfter(requested *time.Duration) func(time.Duration) <-chan time.Time {
+	return func(d time.Duration) <-chan time.Time {
+		*requested = d
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+}
+
+func TestSessionManager_DefaultAuthTimeouts(t *testing.T) {
+	manager := NewSessionManager(&config.TgSettings{})
+
+	assert.Equal(t, defaultUserAuthTimeout, manager.userAuthTimeout())
+	assert.Equal(t, defaultBotAuthTimeout, manager.botAuthTimeout())
+}
+
+func TestSessionManager_ConfiguredAuthTimeouts(t *testing.T) {
+	manager := NewSessionManager(&config.TgSettings{
+		AuthTimeout:    120,
+		BotAuthTimeout: 2.5,
+	})
+
+	assert.Equal(t, 120*time.Second, manager.userAuthTimeout())
+	assert.Equal(t, 2500*time.Millisecond, manager.botAuthTimeout())
+}
+
+func TestSessionManager_WaitForAuth_UserTimeoutTriggers(t *testing.T) {
+	var requested time.Duration
+	manager := NewSessionManager(&config.TgSettings{AuthTimeout: 45})
+	manager.after = stalledAfter(&requested)
+
+	// Auth stalls: nothing is ever sent to the api or error channels
+	apiCh := make(chan *tg.Client)
+	errCh := make(chan error)
+
+	api, err := manager.waitForAuth(context.Background(), apiCh, errCh, manager.userAuthTimeout(), "telegram client", "authentication")
+
+	assert.Nil(t, api)
+	assert.EqualError(t, err, "authentication timeout")
+	assert.Equal(t, 45*time.Second, requested)
+}
+
+func TestSessionManager_WaitForAuth_BotTimeoutTriggers(t *testing.T) {
+	var requested time.Duration
+	manager := NewSessionManager(&config.TgSettings{BotAuthTimeout: 5})
+	manager.after = stalledAfter(&requested)
+
+	apiCh := make(chan *tg.Client)
+	errCh := make(chan error)
+
+	api, err := manager.waitForAuth(context.Background(), apiCh, errCh, manager.botAuthTimeout(), "bot client", "bot authentication")
+
+	assert.Nil(t, api)
+	assert.EqualError(t, err, "bot authentication timeout")
+	assert.Equal(t, 5*time.Second, requested)
+}
+
+func TestSessionManager_WaitForAuth_Success(t *testing.T) {
+	manager := NewSessionManager(&config.TgSettings{})
+
+	apiCh := make(chan *tg.Client, 1)
+	errCh := make(chan error)
+	client := &tg.Client{}
+	apiCh <- client
+
+	api, err := manager.waitForAuth(context.Background(), apiCh, errCh, time.Minute, "telegram client", "authentication")
+
+	assert.NoError(t, err)
+	assert.Same(t, client, api)
+}
+
+func TestSessionManager_WaitForAuth_ClientError(t *testing.T) {
+	manager := NewSessionManager(&config.TgSettings{})
+
+	apiCh := make(chan *tg.Client)
+	errCh := make(chan error, 1)
+	errCh <- errors.New("dial failed")
+
+	api, err := manager.waitForAuth(context.Background(), apiCh, errCh, time.Minute, "bot client", "bot authentication")
+
+	assert.Nil(t, api)
+	assert.EqualError(t, err, "bot client initialization failed: dial failed")
+}
+
+func TestSessionManager_WaitForAuth_ContextCancelled(t *testing.T) {
+	manager := NewSessionManager(&config.TgSettings{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	api, err := manager.waitForAuth(ctx, make(chan *tg.Client), make(chan error), time.Minute, "telegram client", "authentication")
+
+	assert.Nil(t, api)
+	assert.EqualError(t, err, "context cancelled during authentication")
+}
+
+func TestSharedSessionStorage(t *testing.T) {
+	dir := t.TempDir()
+	userPath := filepath.Join(dir, "session.json")
+	botPath := filepath.Join(dir, "bot_session.json")
+	require.NoError(t, os.WriteFile(userPath, []byte("{}"), 0600))
+	require.NoError(t, os.WriteFile(botPath, []byte("{}"), 0600))
+
+	t.Run("разные файлы", func(t *testing.T) {
+		assert.False(t, sharedSessionStorage(userPath, botPath))
+	})
+
+	t.Run("одинаковый путь", func(t *testing.T) {
+		assert.True(t, sharedSessionStorage(userPath, filepath.Join(dir, ".", "session.json")))
+	})
+
+	t.Run("ссылка на файл аккаунта", func(t *testing.T) {
+		link := filepath.Join(dir, "link.json")
+		require.NoError(t, os.Symlink(userPath, link))
+		assert.True(t, sharedSessionStorage(userPath, link))
+	})
+
+	t.Run("файлы еще не созданы", func(t *testing.T) {
+		assert.False(t, sharedSessionStorage(filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")))
+	})
+}
@@ -0,0 +1,155 @@
+package sessions
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"gift-buyer/pkg/logger"
+	"os"
+	"sync"
+
+	"github.com/gotd/td/session"
+)
+
+const (
+	// encryptedSessionMagic prefixes every encrypted session file
+	encryptedSessionMagic = "GBSESS1"
+
+	// sessionSaltSize is the size of the random salt the key is derived with
+	sessionSaltSize = 16
+
+	// sessionKeyIterations is the number of PBKDF2 iterations deriving the key from the passphrase
+	sessionKeyIterations = 600000
+)
+
+// EncryptedSessionStorage implements telegram.SessionStorage and keeps the session file
+// encrypted with AES-256-GCM. The key is derived from the passphrase with PBKDF2-SHA256 and
+// a random salt stored in the file header, next to the nonce.
+type EncryptedSessionStorage struct {
+	// Path is the session file
+	Path string
+
+	// passphrase is the secret the encryption key is derived from
+	passphrase string
+
+	// salt and key are the last derived key and its salt, reused to avoid
+	// running the key derivation on every session write
+	salt []byte
+	key  []byte
+	mu   sync.Mutex
+}
+
+// NewEncryptedSessionStorage creates a session storage encrypting the file with the passphrase.
+//
+// Parameters:
+//   - path: session file
+//   - passphrase: secret the encryption key is derived from
+//
+// Returns:
+//   - *EncryptedSessionStorage: encrypted session storage
+func NewEncryptedSessionStorage(path, passphrase string) *EncryptedSessionStorage {
+	return &EncryptedSessionStorage{Path: path, passphrase: passphrase}
+}
+
+// LoadSession reads and decrypts the session file. A plaintext session file written
+// before encryption was enabled is returned as is and encrypted on the next write.
+//
+// Returns:
+//   - []byte: session data
+//   - error: session.ErrNotFound if there is no session file, or a decryption error
+//     when the passphrase is wrong or the file is corrupted
+func (s *EncryptedSessionStorage) LoadSession(ctx context.Context) ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, session.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte(encryptedSessionMagic)) {
+		logger.GlobalLogger.Warnf("Session file %s is not encrypted yet, it will be encrypted on the next write", s.Path)
+		return data, nil
+	}
+
+	data = data[len(encryptedSessionMagic):]
+	if len(data) < sessionSaltSize {
+		return nil, fmt.Errorf("session file %s is corrupted", s.Path)
+	}
+	salt, sealed := data[:sessionSaltSize], data[sessionSaltSize:]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	gcm, err := s.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("session file %s is corrupted", s.Path)
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(encryptedSessionMagic))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt session file %s: wrong passphrase or corrupted file", s.Path)
+	}
+	return plain, nil
+}
+
+// StoreSession encrypts the session data and writes it to the session file.
+func (s *EncryptedSessionStorage) StoreSession(ctx context.Context, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	salt := s.salt
+	if salt == nil {
+		salt = make([]byte, sessionSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate session salt: %w", err)
+		}
+	}
+
+	gcm, err := s.aead(salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate session nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(encryptedSessionMagic)+len(salt)+len(nonce)+len(data)+gcm.Overhead())
+	out = append(out, encryptedSessionMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	out = gcm.Seal(out, nonce, data, []byte(encryptedSessionMagic))
+
+	if err := os.WriteFile(s.Path, out, 0600); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	return nil
+}
+
+// aead returns the AES-GCM cipher keyed with the passphrase and salt, deriving
+// the key only when the salt changed. Must be called with mu held.
+func (s *EncryptedSessionStorage) aead(salt []byte) (cipher.AEAD, error) {
+	if s.key == nil || !bytes.Equal(s.salt, salt) {
+		key, err := pbkdf2.Key(sha256.New, s.passphrase, salt, sessionKeyIterations, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive session key: %w", err)
+		}
+		s.salt, s.key = append([]byte(nil), salt...), key
+	}
+
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
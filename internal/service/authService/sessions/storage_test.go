@@ -0,0 +1,74 @@
+package sessions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gotd/td/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedSessionStorage(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(`{"Version":1,"Data":{"DC":2,"AuthKey":"c2VjcmV0"}}`)
+
+	t.Run("сохранение и загрузка сессии", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session.json")
+		storage := NewEncryptedSessionStorage(path, "passphrase")
+
+		require.NoError(t, storage.StoreSession(ctx, data))
+
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), "AuthKey")
+
+		loaded, err := NewEncryptedSessionStorage(path, "passphrase").LoadSession(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, data, loaded)
+	})
+
+	t.Run("неверный пароль", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session.json")
+		require.NoError(t, NewEncryptedSessionStorage(path, "passphrase").StoreSession(ctx, data))
+
+		loaded, err := NewEncryptedSessionStorage(path, "wrong").LoadSession(ctx)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "wrong passphrase")
+		assert.Nil(t, loaded)
+	})
+
+	t.Run("поврежденный файл", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session.json")
+		require.NoError(t, os.WriteFile(path, []byte(encryptedSessionMagic+"short"), 0600))
+
+		_, err := NewEncryptedSessionStorage(path, "passphrase").LoadSession(ctx)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("файл сессии отсутствует", func(t *testing.T) {
+		_, err := NewEncryptedSessionStorage(filepath.Join(t.TempDir(), "missing.json"), "passphrase").LoadSession(ctx)
+
+		assert.ErrorIs(t, err, session.ErrNotFound)
+	})
+
+	t.Run("незашифрованная сессия шифруется при записи", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session.json")
+		require.NoError(t, os.WriteFile(path, data, 0600))
+		storage := NewEncryptedSessionStorage(path, "passphrase")
+
+		loaded, err := storage.LoadSession(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, data, loaded)
+
+		require.NoError(t, storage.StoreSession(ctx, loaded))
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Greater(t, len(raw), len(encryptedSessionMagic))
+		assert.Equal(t, encryptedSessionMagic, string(raw[:len(encryptedSessionMagic)]))
+	})
+}
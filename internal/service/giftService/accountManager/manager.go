@@ -6,16 +6,24 @@ import (
 	"fmt"
 	"gift-buyer/pkg/errors"
 	"gift-buyer/pkg/logger"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gotd/td/tg"
 )
 
+// maxUsersPerRequest is the maximum number of users accepted by a single users.getUsers call
+const maxUsersPerRequest = 100
+
 type accountManagerImpl struct {
 	api                     *tg.Client
 	usernames, channelNames []string
 	userCache               UserCache
 	channelCache            ChannelCache
+
+	// batchResolve resolves the user receivers with ResolveReceivers
+	batchResolve bool
 }
 
 func NewAccountManager(api *tg.Client, usernames, channelNames []string, userCache UserCache, channelCache ChannelCache) *accountManagerImpl {
@@ -28,6 +36,15 @@ func NewAccountManager(api *tg.Client, usernames, channelNames []string, userCac
 	}
 }
 
+// SetBatchResolve enables resolving the user receivers with ResolveReceivers: numeric
+// IDs through grouped users.getUsers calls instead of resolving every receiver separately.
+//
+// Parameters:
+//   - enabled: resolve numeric user IDs in batches
+func (am *accountManagerImpl) SetBatchResolve(enabled bool) {
+	am.batchResolve = enabled
+}
+
 func (am *accountManagerImpl) SetIds(ctx context.Context) error {
 	if am.api == nil {
 		return errors.New("API client is nil")
@@ -53,6 +70,10 @@ func (am *accountManagerImpl) loadUsersToCache(ctx context.Context) error {
 		return errors.New("API client is nil")
 	}
 
+	if am.batchResolve {
+		return am.ResolveReceivers(ctx, am.usernames)
+	}
+
 	for _, username := range am.usernames {
 		withoutTag := strings.TrimPrefix(username, "@")
 
@@ -72,6 +93,159 @@ func (am *accountManagerImpl) loadUsersToCache(ctx context.Context) error {
 	return nil
 }
 
+// ResolveReceivers resolves the receivers and stores them with their access hashes in
+// the caches, keyed by the receiver without the leading "@". Numeric IDs are resolved
+// with grouped users.getUsers calls, @handles with contacts.resolveUsername and cached
+// as a user or, for a public channel, as a channel.
+//
+// Every receiver is attempted before failing, so the resolvable ones are cached even
+// when some aren't.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - receivers: configured receivers, numeric IDs or usernames with or without "@"
+//
+// Returns:
+//   - error: users.getUsers API error, or ErrReceiverNotResolved listing the receivers
+//     that were not found or are inaccessible to the session
+func (am *accountManagerImpl) ResolveReceivers(ctx context.Context, receivers []string) error {
+	if am.api == nil {
+		return errors.New("API client is nil")
+	}
+
+	handles, ids := splitNumericIDs(receivers)
+	notFound, err := am.loadUsersByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	unresolved := make([]string, 0, len(notFound))
+	for _, id := range notFound {
+		unresolved = append(unresolved, strconv.FormatInt(id, 10))
+	}
+	for _, handle := range handles {
+		withoutTag := strings.TrimPrefix(handle, "@")
+		if err := am.resolveHandle(ctx, withoutTag); err != nil {
+			logger.GlobalLogger.Warnf("failed to resolve receiver %s: %v", handle, err)
+			unresolved = append(unresolved, handle)
+		}
+	}
+
+	if len(unresolved) > 0 {
+		return errors.Wrap(errors.ErrReceiverNotResolved, fmt.Sprintf("failed to resolve %s", strings.Join(unresolved, ", ")))
+	}
+	return nil
+}
+
+// resolveHandle resolves a username and caches the user or channel it belongs to.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - username: username without the leading "@"
+//
+// Returns:
+//   - error: API error or a response without a user or channel
+func (am *accountManagerImpl) resolveHandle(ctx context.Context, username string) error {
+	res, err := am.api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
+		Username: username,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve username")
+	}
+
+	for _, user := range res.Users {
+		if u, ok := user.(*tg.User); ok {
+			am.userCache.SetUser(username, u)
+			return nil
+		}
+	}
+	for _, chat := range res.Chats {
+		if c, ok := chat.(*tg.Channel); ok {
+			am.channelCache.SetChannel(username, c)
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("username %s not found in response", username))
+}
+
+// splitNumericIDs separates numeric user IDs from usernames.
+//
+// Parameters:
+//   - receivers: configured user receivers
+//
+// Returns:
+//   - []string: receivers that have to be resolved by username
+//   - map[int64]string: numeric user IDs mapped to their cache keys
+func splitNumericIDs(receivers []string) ([]string, map[int64]string) {
+	usernames := make([]string, 0, len(receivers))
+	ids := make(map[int64]string)
+	for _, receiver := range receivers {
+		withoutTag := strings.TrimPrefix(receiver, "@")
+		if id, err := strconv.ParseInt(withoutTag, 10, 64); err == nil && id > 0 {
+			ids[id] = withoutTag
+			continue
+		}
+		usernames = append(usernames, receiver)
+	}
+	return usernames, ids
+}
+
+// loadUsersByIDs resolves users by numeric ID with grouped users.getUsers calls of at
+// most maxUsersPerRequest users each and stores them in the user cache.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - ids: numeric user IDs mapped to their cache keys
+//
+// Returns:
+//   - []int64: sorted IDs of the users missing from the responses
+//   - error: API error of a users.getUsers call
+func (am *accountManagerImpl) loadUsersByIDs(ctx context.Context, ids map[int64]string) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	sorted := make([]int64, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	resolved := make(map[int64]bool, len(ids))
+	for start := 0; start < len(sorted); start += maxUsersPerRequest {
+		end := start + maxUsersPerRequest
+		if end > len(sorted) {
+			end = len(sorted)
+		}
+
+		request := make([]tg.InputUserClass, 0, end-start)
+		for _, id := range sorted[start:end] {
+			request = append(request, &tg.InputUser{UserID: id})
+		}
+
+		users, err := am.api.UsersGetUsers(ctx, request)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get users")
+		}
+		for _, user := range users {
+			if u, ok := user.(*tg.User); ok {
+				if key, ok := ids[u.ID]; ok {
+					am.userCache.SetUser(key, u)
+					resolved[u.ID] = true
+				}
+			}
+		}
+	}
+
+	var notFound []int64
+	for _, id := range sorted {
+		if !resolved[id] {
+			notFound = append(notFound, id)
+		}
+	}
+	return notFound, nil
+}
+
 func (am *accountManagerImpl) loadChannelsToCache(ctx context.Context) error {
 	cachedCount := 0
 	notFoundChannels := []string{}
@@ -2,8 +2,13 @@ package accountManager
 
 import (
 	"context"
+	"fmt"
+	"gift-buyer/pkg/errors"
+	"strconv"
+	"sync"
 	"testing"
 
+	"github.com/gotd/td/bin"
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
 )
@@ -148,3 +153,183 @@ func (m *MockChannelCache) SetChannel(key string, channel *tg.Channel) {
 func (m *MockChannelCache) GetChannel(id string) (*tg.Channel, error) {
 	return nil, assert.AnError
 }
+
+// usersInvoker answers users.getUsers and contacts.resolveUsername and records the requests
+type usersInvoker struct {
+	mu           sync.Mutex
+	batches      [][]int64
+	resolved     []string
+	missingUsers map[int64]bool
+
+	// getUsersErr fails every users.getUsers call
+	getUsersErr error
+
+	// missingUsernames fail to resolve, channelUsernames resolve to a channel
+	missingUsernames, channelUsernames map[string]bool
+}
+
+func (i *usersInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	switch request := input.(type) {
+	case *tg.UsersGetUsersRequest:
+		if i.getUsersErr != nil {
+			return i.getUsersErr
+		}
+		batch := make([]int64, 0, len(request.ID))
+		users := make([]tg.UserClass, 0, len(request.ID))
+		for _, inputUser := range request.ID {
+			id := inputUser.(*tg.InputUser).UserID
+			batch = append(batch, id)
+			if !i.missingUsers[id] {
+				users = append(users, &tg.User{ID: id, AccessHash: id * 10})
+			}
+		}
+		i.batches = append(i.batches, batch)
+		output.(*tg.UserClassVector).Elems = users
+	case *tg.ContactsResolveUsernameRequest:
+		i.resolved = append(i.resolved, request.Username)
+		switch {
+		case i.missingUsernames[request.Username]:
+			return fmt.Errorf("USERNAME_NOT_OCCUPIED")
+		case i.channelUsernames[request.Username]:
+			output.(*tg.ContactsResolvedPeer).Chats = []tg.ChatClass{&tg.Channel{ID: 2, AccessHash: 20, Username: request.Username}}
+		default:
+			output.(*tg.ContactsResolvedPeer).Users = []tg.UserClass{&tg.User{ID: 1, AccessHash: 10, Username: request.Username}}
+		}
+	default:
+		return fmt.Errorf("unexpected request %T", input)
+	}
+	return nil
+}
+
+// recordingUserCache stores the cached users in a map
+type recordingUserCache struct {
+	users map[string]*tg.User
+}
+
+func (c *recordingUserCache) SetUser(key string, user *tg.User) {
+	c.users[key] = user
+}
+
+func (c *recordingUserCache) GetUser(key string) (*tg.User, error) {
+	return c.users[key], nil
+}
+
+func TestAccountManager_SetIds_BatchResolve(t *testing.T) {
+	t.Run("числовые ID загружаются пачками", func(t *testing.T) {
+		receivers := []string{"@alice"}
+		for id := 1; id <= 250; id++ {
+			receivers = append(receivers, strconv.Itoa(1000+id))
+		}
+
+		invoker := &usersInvoker{missingUsers: map[int64]bool{1250: true}}
+		cache := &recordingUserCache{users: map[string]*tg.User{}}
+		manager := NewAccountManager(tg.NewClient(invoker), receivers, nil, cache, &MockChannelCache{})
+		manager.SetBatchResolve(true)
+
+		err := manager.SetIds(context.Background())
+		assert.True(t, errors.Is(err, errors.ErrReceiverNotResolved))
+		assert.ErrorContains(t, err, "failed to resolve 1250")
+
+		if assert.Len(t, invoker.batches, 3) {
+			assert.Len(t, invoker.batches[0], maxUsersPerRequest)
+			assert.Len(t, invoker.batches[1], maxUsersPerRequest)
+			assert.Len(t, invoker.batches[2], 50)
+		}
+		assert.Equal(t, []string{"alice"}, invoker.resolved)
+
+		assert.Len(t, cache.users, 250)
+		assert.Equal(t, int64(1001), cache.users["1001"].ID)
+		assert.Equal(t, int64(12490), cache.users["1249"].AccessHash)
+		assert.Nil(t, cache.users["1250"])
+		assert.Equal(t, "alice", cache.users["alice"].Username)
+	})
+
+	t.Run("без флага каждый получатель загружается отдельно", func(t *testing.T) {
+		invoker := &usersInvoker{}
+		cache := &recordingUserCache{users: map[string]*tg.User{}}
+		manager := NewAccountManager(tg.NewClient(invoker), []string{"1001", "1002"}, nil, cache, &MockChannelCache{})
+
+		assert.NoError(t, manager.SetIds(context.Background()))
+
+		assert.Empty(t, invoker.batches)
+		assert.Equal(t, []string{"1001", "1002"}, invoker.resolved)
+	})
+}
+
+// recordingChannelCache stores the cached channels in a map
+type recordingChannelCache struct {
+	channels map[string]*tg.Channel
+}
+
+func (c *recordingChannelCache) SetChannel(key string, channel *tg.Channel) {
+	c.channels[key] = channel
+}
+
+func (c *recordingChannelCache) GetChannel(key string) (*tg.Channel, error) {
+	return c.channels[key], nil
+}
+
+func TestAccountManager_ResolveReceivers(t *testing.T) {
+	newManager := func(invoker *usersInvoker) (*accountManagerImpl, *recordingUserCache, *recordingChannelCache) {
+		users := &recordingUserCache{users: map[string]*tg.User{}}
+		channels := &recordingChannelCache{channels: map[string]*tg.Channel{}}
+		return NewAccountManager(tg.NewClient(invoker), nil, nil, users, channels), users, channels
+	}
+
+	t.Run("ID и юзернеймы попадают в кэш с access hash", func(t *testing.T) {
+		invoker := &usersInvoker{channelUsernames: map[string]bool{"news": true}}
+		manager, users, channels := newManager(invoker)
+
+		assert.NoError(t, manager.ResolveReceivers(context.Background(), []string{"1001", "@alice", "@news", "1002"}))
+
+		assert.Equal(t, [][]int64{{1001, 1002}}, invoker.batches)
+		assert.Equal(t, []string{"alice", "news"}, invoker.resolved)
+		assert.Equal(t, int64(10010), users.users["1001"].AccessHash)
+		assert.Equal(t, int64(10020), users.users["1002"].AccessHash)
+		assert.Equal(t, int64(10), users.users["alice"].AccessHash)
+		assert.Equal(t, int64(20), channels.channels["news"].AccessHash)
+		assert.NotContains(t, users.users, "news")
+	})
+
+	t.Run("нерезолвящиеся получатели возвращают ошибку", func(t *testing.T) {
+		invoker := &usersInvoker{missingUsers: map[int64]bool{1002: true}, missingUsernames: map[string]bool{"ghost": true}}
+		manager, users, _ := newManager(invoker)
+
+		err := manager.ResolveReceivers(context.Background(), []string{"1001", "1002", "@ghost", "@alice"})
+
+		assert.True(t, errors.Is(err, errors.ErrReceiverNotResolved))
+		assert.ErrorContains(t, err, "failed to resolve 1002, @ghost")
+		// Resolvable receivers are still cached
+		assert.Contains(t, users.users, "1001")
+		assert.Contains(t, users.users, "alice")
+		assert.NotContains(t, users.users, "1002")
+	})
+
+	t.Run("ошибка API прерывает загрузку", func(t *testing.T) {
+		invoker := &usersInvoker{getUsersErr: fmt.Errorf("FLOOD_WAIT_30")}
+		manager, users, _ := newManager(invoker)
+
+		err := manager.ResolveReceivers(context.Background(), []string{"1001", "@alice"})
+
+		assert.ErrorContains(t, err, "failed to get users")
+		assert.False(t, errors.Is(err, errors.ErrReceiverNotResolved))
+		assert.Empty(t, invoker.resolved)
+		assert.Empty(t, users.users)
+	})
+
+	t.Run("без API возвращается ошибка", func(t *testing.T) {
+		manager := NewAccountManager(nil, nil, nil, &MockUserCache{}, &MockChannelCache{})
+
+		assert.Error(t, manager.ResolveReceivers(context.Background(), []string{"1001"}))
+	})
+}
+
+func TestSplitNumericIDs(t *testing.T) {
+	usernames, ids := splitNumericIDs([]string{"@alice", "123", "@456", "bob", "-5"})
+
+	assert.Equal(t, []string{"@alice", "bob", "-5"}, usernames)
+	assert.Equal(t, map[int64]string{123: "123", 456: "456"}, ids)
+}
@@ -0,0 +1,94 @@
+// Package balanceCache keeps the last known stars balance of the account so that
+// purchases the balance can't cover are skipped before any payment request is made.
+package balanceCache
+
+import (
+	"context"
+	"gift-buyer/pkg/errors"
+	"sync"
+
+	"github.com/gotd/td/tg"
+)
+
+// balanceCacheImpl caches the stars balance and trims it locally after every purchase.
+type balanceCacheImpl struct {
+	// api is the Telegram client used to refresh the balance
+	api *tg.Client
+
+	balance int64
+	mu      sync.RWMutex
+
+	// balanceFunc overrides the stars balance lookup, used in tests
+	balanceFunc func(ctx context.Context) (int64, error)
+}
+
+// NewBalanceCache creates a balance cache refreshed through the specified client.
+// The balance is 0 until the first RefreshBalance call.
+//
+// Parameters:
+//   - api: Telegram client used to request the stars status
+//
+// Returns:
+//   - *balanceCacheImpl: balance cache instance
+func NewBalanceCache(api *tg.Client) *balanceCacheImpl {
+	return &balanceCacheImpl{api: api}
+}
+
+// SetBalance replaces the cached balance.
+func (bc *balanceCacheImpl) SetBalance(balance int64) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.balance = balance
+}
+
+// GetBalance returns the cached balance.
+func (bc *balanceCacheImpl) GetBalance() int64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.balance
+}
+
+// TrimBalance subtracts spent stars from the cached balance, never going below zero.
+func (bc *balanceCacheImpl) TrimBalance(deduction int64) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.balance -= deduction
+	if bc.balance < 0 {
+		bc.balance = 0
+	}
+}
+
+// RefreshBalance requests the current balance with payments.getStarsStatus and caches it.
+// On failure the previously cached balance is kept.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//
+// Returns:
+//   - error: stars status request error
+func (bc *balanceCacheImpl) RefreshBalance(ctx context.Context) error {
+	balance, err := bc.fetchBalance(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to refresh stars balance")
+	}
+	bc.SetBalance(balance)
+	return nil
+}
+
+// fetchBalance returns the current stars balance of the account.
+func (bc *balanceCacheImpl) fetchBalance(ctx context.Context) (int64, error) {
+	if bc.balanceFunc != nil {
+		return bc.balanceFunc(ctx)
+	}
+	if bc.api == nil {
+		return 0, errors.New("api client not configured")
+	}
+
+	status, err := bc.api.PaymentsGetStarsStatus(ctx, &tg.PaymentsGetStarsStatusRequest{
+		Peer: &tg.InputPeerSelf{},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return status.Balance.GetAmount(), nil
+}
@@ -0,0 +1,43 @@
+package balanceCache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceCache_TrimBalance(t *testing.T) {
+	cache := NewBalanceCache(nil)
+	cache.SetBalance(500)
+
+	cache.TrimBalance(200)
+	assert.Equal(t, int64(300), cache.GetBalance())
+
+	cache.TrimBalance(1000)
+	assert.Equal(t, int64(0), cache.GetBalance())
+}
+
+func TestBalanceCache_RefreshBalance(t *testing.T) {
+	t.Run("баланс обновляется", func(t *testing.T) {
+		cache := NewBalanceCache(nil)
+		cache.balanceFunc = func(ctx context.Context) (int64, error) { return 1200, nil }
+
+		assert.NoError(t, cache.RefreshBalance(context.Background()))
+		assert.Equal(t, int64(1200), cache.GetBalance())
+	})
+
+	t.Run("ошибка сохраняет прежний баланс", func(t *testing.T) {
+		cache := NewBalanceCache(nil)
+		cache.SetBalance(300)
+		cache.balanceFunc = func(ctx context.Context) (int64, error) { return 0, errors.New("network error") }
+
+		assert.Error(t, cache.RefreshBalance(context.Background()))
+		assert.Equal(t, int64(300), cache.GetBalance())
+	})
+
+	t.Run("без клиента", func(t *testing.T) {
+		assert.Error(t, NewBalanceCache(nil).RefreshBalance(context.Background()))
+	})
+}
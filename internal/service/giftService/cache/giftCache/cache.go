@@ -4,7 +4,9 @@
 package giftCache
 
 import (
+	"container/list"
 	"gift-buyer/internal/service/giftService/giftInterfaces"
+	"gift-buyer/pkg/logger"
 	"sync"
 	"time"
 
@@ -26,8 +28,37 @@ type GiftCacheImpl struct {
 
 	// mu provides thread-safe access to the cache map
 	mu sync.RWMutex
+
+	// maxGifts bounds the number of gifts kept in memory, 0 means unbounded
+	maxGifts int
+
+	// lru orders cached gift IDs from most to least recently seen
+	lru *list.List
+
+	// lruIndex maps gift IDs to their position in lru
+	lruIndex map[int64]*list.Element
+
+	// evicted holds IDs of evicted gifts with the time they were last seen.
+	// Evicted gifts are still reported as known during evictionWindow so that
+	// eviction never makes an already processed gift look new again.
+	evicted map[int64]time.Time
+
+	// evictionWindow defines how long an evicted gift is still reported as known
+	evictionWindow time.Duration
+
+	// ttl is how long a gift that is no longer seen stays cached, 0 keeps gifts forever
+	ttl time.Duration
+
+	// seenAt holds the time every cached gift was last stored or checked (only with ttl)
+	seenAt map[int64]time.Time
+
+	// now returns the current time, replaceable in tests
+	now func() time.Time
 }
 
+// defaultEvictionWindow is used when a bounded cache is created without an eviction window
+const defaultEvictionWindow = time.Hour
+
 // NewGiftCache creates a new GiftCache instance with automatic persistence.
 // It initializes the cache, loads existing data from disk, and starts
 // a background goroutine for periodic saving.
@@ -40,10 +71,56 @@ type GiftCacheImpl struct {
 // Returns:
 //   - giftInterfaces.GiftCache: configured and initialized gift cache instance
 func NewGiftCache() giftInterfaces.GiftCache {
-	gc := &GiftCacheImpl{
-		cache:    make(map[int64]*tg.StarGift),
-		stopCh:   make(chan struct{}),
-		interval: 5 * time.Second,
+	return NewGiftCacheWithLimit(0, 0)
+}
+
+// NewGiftCacheWithLimit creates a new GiftCache instance that keeps at most maxGifts
+// gifts in memory, evicting the least recently seen ones when the limit is exceeded.
+//
+// Evicted gifts are remembered by ID only: HasGift keeps reporting them as known
+// while they are seen again within evictionWindow, so eviction never triggers
+// a repeated purchase of a gift that is still listed.
+//
+// Parameters:
+//   - maxGifts: maximum number of gifts kept in memory (0 for unbounded)
+//   - evictionWindow: how long an evicted gift is still reported as known (defaults to 1 hour)
+//
+// Returns:
+//   - giftInterfaces.GiftCache: configured and initialized gift cache instance
+func NewGiftCacheWithLimit(maxGifts int, evictionWindow time.Duration) giftInterfaces.GiftCache {
+	return NewGiftCacheWithLimitAndTTL(maxGifts, evictionWindow, 0)
+}
+
+// NewGiftCacheWithTTL creates a new unbounded GiftCache instance that evicts gifts not
+// stored or checked with HasGift for longer than ttl. Expired gifts are evicted by the
+// periodic save, which also drops them from cache.json.
+//
+// A gift that is still listed is checked every monitoring cycle and never expires, so
+// the TTL only forgets gifts that disappeared from the catalog.
+//
+// Parameters:
+//   - ttl: how long an unseen gift stays cached (0 keeps gifts forever)
+//
+// Returns:
+//   - giftInterfaces.GiftCache: configured and initialized gift cache instance
+func NewGiftCacheWithTTL(ttl time.Duration) giftInterfaces.GiftCache {
+	return NewGiftCacheWithLimitAndTTL(0, 0, ttl)
+}
+
+// NewGiftCacheWithLimitAndTTL creates a new GiftCache instance combining the size limit
+// of NewGiftCacheWithLimit with the TTL of NewGiftCacheWithTTL.
+//
+// Parameters:
+//   - maxGifts: maximum number of gifts kept in memory (0 for unbounded)
+//   - evictionWindow: how long an evicted gift is still reported as known (defaults to 1 hour)
+//   - ttl: how long an unseen gift stays cached (0 keeps gifts forever)
+//
+// Returns:
+//   - giftInterfaces.GiftCache: configured and initialized gift cache instance
+func NewGiftCacheWithLimitAndTTL(maxGifts int, evictionWindow, ttl time.Duration) giftInterfaces.GiftCache {
+	gc := newGiftCache(maxGifts, evictionWindow)
+	if ttl > 0 {
+		gc.ttl = ttl
 	}
 
 	gc.loadFromFile()
@@ -53,6 +130,29 @@ func NewGiftCache() giftInterfaces.GiftCache {
 	return gc
 }
 
+// newGiftCache creates the in-memory part of the cache without touching the disk.
+func newGiftCache(maxGifts int, evictionWindow time.Duration) *GiftCacheImpl {
+	if maxGifts < 0 {
+		maxGifts = 0
+	}
+	if evictionWindow <= 0 {
+		evictionWindow = defaultEvictionWindow
+	}
+
+	return &GiftCacheImpl{
+		cache:          make(map[int64]*tg.StarGift),
+		stopCh:         make(chan struct{}),
+		interval:       5 * time.Second,
+		maxGifts:       maxGifts,
+		lru:            list.New(),
+		lruIndex:       make(map[int64]*list.Element),
+		evicted:        make(map[int64]time.Time),
+		evictionWindow: evictionWindow,
+		seenAt:         make(map[int64]time.Time),
+		now:            time.Now,
+	}
+}
+
 // startPeriodicSave runs a background goroutine that periodically saves the cache to disk.
 // It saves the cache at the configured interval and performs a final save when stopped.
 // This goroutine runs until the stopCh channel is closed.
@@ -63,7 +163,11 @@ func (gc *GiftCacheImpl) startPeriodicSave() {
 	for {
 		select {
 		case <-ticker.C:
+			if expired := gc.evictExpired(); expired > 0 {
+				logger.GlobalLogger.Infof("Evicted %d expired gifts from cache", expired)
+			}
 			gc.saveToFile()
+			gc.pruneEvicted()
 		case <-gc.stopCh:
 			gc.saveToFile()
 			return
@@ -82,6 +186,14 @@ func (gc *GiftCacheImpl) SetGift(id int64, gift *tg.StarGift) {
 	defer gc.mu.Unlock()
 
 	gc.cache[id] = gift
+	if gc.expiring() {
+		gc.seenAt[id] = gc.now()
+	}
+	if gc.bounded() {
+		delete(gc.evicted, id)
+		gc.touch(id)
+		gc.evictOverflow()
+	}
 }
 
 // GetGift retrieves a cached gift by its ID.
@@ -94,6 +206,18 @@ func (gc *GiftCacheImpl) SetGift(id int64, gift *tg.StarGift) {
 //   - *tg.StarGift: the cached gift object, nil if not found
 //   - error: always nil in current implementation
 func (gc *GiftCacheImpl) GetGift(id int64) (*tg.StarGift, error) {
+	if gc.bounded() {
+		gc.mu.Lock()
+		defer gc.mu.Unlock()
+
+		gift, exists := gc.cache[id]
+		if !exists {
+			return nil, nil
+		}
+		gc.touch(id)
+		return gift, nil
+	}
+
 	gc.mu.RLock()
 	defer gc.mu.RUnlock()
 
@@ -122,6 +246,9 @@ func (gc *GiftCacheImpl) GetAllGifts() map[int64]*tg.StarGift {
 
 // HasGift checks if a gift with the specified ID exists in the cache.
 // This operation is thread-safe and uses a read lock for optimal performance.
+// For a bounded cache a gift evicted within the eviction window is still
+// reported as known, and every check refreshes its last-seen time. With a TTL
+// every check of a cached gift refreshes the time it expires from.
 //
 // Parameters:
 //   - id: unique identifier of the gift to check
@@ -129,6 +256,36 @@ func (gc *GiftCacheImpl) GetAllGifts() map[int64]*tg.StarGift {
 // Returns:
 //   - bool: true if the gift exists in cache, false otherwise
 func (gc *GiftCacheImpl) HasGift(id int64) bool {
+	if gc.bounded() || gc.expiring() {
+		gc.mu.Lock()
+		defer gc.mu.Unlock()
+
+		if _, exists := gc.cache[id]; exists {
+			if gc.bounded() {
+				gc.touch(id)
+			}
+			if gc.expiring() {
+				gc.seenAt[id] = gc.now()
+			}
+			return true
+		}
+		if !gc.bounded() {
+			return false
+		}
+
+		seenAt, evicted := gc.evicted[id]
+		if !evicted {
+			return false
+		}
+		now := gc.now()
+		if now.Sub(seenAt) > gc.evictionWindow {
+			delete(gc.evicted, id)
+			return false
+		}
+		gc.evicted[id] = now
+		return true
+	}
+
 	gc.mu.RLock()
 	defer gc.mu.RUnlock()
 
@@ -146,6 +303,12 @@ func (gc *GiftCacheImpl) DeleteGift(id int64) {
 	defer gc.mu.Unlock()
 
 	delete(gc.cache, id)
+	delete(gc.evicted, id)
+	delete(gc.seenAt, id)
+	if element, ok := gc.lruIndex[id]; ok {
+		gc.lru.Remove(element)
+		delete(gc.lruIndex, id)
+	}
 }
 
 // Clear removes all gifts from the cache.
@@ -155,4 +318,92 @@ func (gc *GiftCacheImpl) Clear() {
 	defer gc.mu.Unlock()
 
 	gc.cache = make(map[int64]*tg.StarGift)
+	gc.lru = list.New()
+	gc.lruIndex = make(map[int64]*list.Element)
+	gc.evicted = make(map[int64]time.Time)
+	gc.seenAt = make(map[int64]time.Time)
+}
+
+// bounded reports whether the cache enforces a maximum size.
+func (gc *GiftCacheImpl) bounded() bool {
+	return gc.maxGifts > 0
+}
+
+// expiring reports whether cached gifts expire after the TTL.
+func (gc *GiftCacheImpl) expiring() bool {
+	return gc.ttl > 0
+}
+
+// touch marks the gift as most recently seen. The caller must hold the write lock.
+func (gc *GiftCacheImpl) touch(id int64) {
+	if element, ok := gc.lruIndex[id]; ok {
+		gc.lru.MoveToFront(element)
+		return
+	}
+	gc.lruIndex[id] = gc.lru.PushFront(id)
+}
+
+// evictOverflow evicts least recently seen gifts until the cache fits its limit.
+// Evicted IDs are remembered for the eviction window. The caller must hold the write lock.
+func (gc *GiftCacheImpl) evictOverflow() {
+	now := gc.now()
+	for len(gc.cache) > gc.maxGifts {
+		oldest := gc.lru.Back()
+		if oldest == nil {
+			return
+		}
+		id := oldest.Value.(int64)
+		gc.lru.Remove(oldest)
+		delete(gc.lruIndex, id)
+		delete(gc.cache, id)
+		delete(gc.seenAt, id)
+		gc.evicted[id] = now
+	}
+}
+
+// pruneEvicted forgets evicted gifts that were not seen within the eviction window.
+func (gc *GiftCacheImpl) pruneEvicted() {
+	if !gc.bounded() {
+		return
+	}
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	now := gc.now()
+	for id, seenAt := range gc.evicted {
+		if now.Sub(seenAt) > gc.evictionWindow {
+			delete(gc.evicted, id)
+		}
+	}
+}
+
+// evictExpired removes gifts that were not stored or checked within the TTL. Unlike
+// size eviction the expired gifts are forgotten entirely.
+//
+// Returns:
+//   - int: number of evicted gifts
+func (gc *GiftCacheImpl) evictExpired() int {
+	if !gc.expiring() {
+		return 0
+	}
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	now := gc.now()
+	expired := 0
+	for id, seenAt := range gc.seenAt {
+		if now.Sub(seenAt) <= gc.ttl {
+			continue
+		}
+		delete(gc.cache, id)
+		delete(gc.seenAt, id)
+		if element, ok := gc.lruIndex[id]; ok {
+			gc.lru.Remove(element)
+			delete(gc.lruIndex, id)
+		}
+		expired++
+	}
+	return expired
 }
@@ -1,11 +1,15 @@
 package giftCache
 
 import (
+	"encoding/json"
+	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewGiftCache(t *testing.T) {
@@ -263,3 +267,202 @@ func TestGiftCache_NegativeIDGift(t *testing.T) {
 	assert.NotNil(t, retrievedGift)
 	assert.Equal(t, int64(-1), retrievedGift.ID)
 }
+
+func TestGiftCache_LimitEvictsLeastRecentlySeen(t *testing.T) {
+	cache := newGiftCache(2, time.Minute)
+
+	cache.SetGift(1, &tg.StarGift{ID: 1})
+	cache.SetGift(2, &tg.StarGift{ID: 2})
+
+	// Seeing gift 1 again makes gift 2 the least recently seen one
+	assert.True(t, cache.HasGift(1))
+
+	cache.SetGift(3, &tg.StarGift{ID: 3})
+
+	gift, err := cache.GetGift(2)
+	assert.NoError(t, err)
+	assert.Nil(t, gift)
+
+	gift, err = cache.GetGift(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, gift)
+
+	gift, err = cache.GetGift(3)
+	assert.NoError(t, err)
+	assert.NotNil(t, gift)
+}
+
+func TestGiftCache_LimitBoundsMemory(t *testing.T) {
+	cache := newGiftCache(10, time.Minute)
+
+	for i := int64(0); i < 1000; i++ {
+		cache.SetGift(i, &tg.StarGift{ID: i})
+	}
+
+	assert.Len(t, cache.GetAllGifts(), 10)
+	assert.Equal(t, 10, cache.lru.Len())
+	assert.Len(t, cache.lruIndex, 10)
+
+	// The most recently added gifts are the ones kept in memory
+	for i := int64(990); i < 1000; i++ {
+		gift, err := cache.GetGift(i)
+		assert.NoError(t, err)
+		assert.NotNil(t, gift)
+	}
+}
+
+func TestGiftCache_EvictedGiftKnownWithinWindow(t *testing.T) {
+	now := time.Now()
+	cache := newGiftCache(1, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.SetGift(1, &tg.StarGift{ID: 1})
+	cache.SetGift(2, &tg.StarGift{ID: 2})
+
+	// Evicted gift is still reported as known and is not bought again
+	now = now.Add(30 * time.Second)
+	assert.True(t, cache.HasGift(1))
+
+	// The check above refreshed the last-seen time, so the gift stays known
+	now = now.Add(50 * time.Second)
+	assert.True(t, cache.HasGift(1))
+
+	// Not seen for longer than the window: the gift is forgotten
+	now = now.Add(2 * time.Minute)
+	assert.False(t, cache.HasGift(1))
+	assert.Empty(t, cache.evicted)
+}
+
+func TestGiftCache_PruneEvicted(t *testing.T) {
+	now := time.Now()
+	cache := newGiftCache(1, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.SetGift(1, &tg.StarGift{ID: 1})
+	cache.SetGift(2, &tg.StarGift{ID: 2})
+	assert.Len(t, cache.evicted, 1)
+
+	now = now.Add(2 * time.Minute)
+	cache.pruneEvicted()
+	assert.Empty(t, cache.evicted)
+}
+
+func TestGiftCache_UnboundedByDefault(t *testing.T) {
+	cache := newGiftCache(0, 0)
+
+	for i := int64(0); i < 100; i++ {
+		cache.SetGift(i, &tg.StarGift{ID: i})
+	}
+
+	assert.Len(t, cache.GetAllGifts(), 100)
+	assert.Equal(t, 0, cache.lru.Len())
+	assert.Equal(t, defaultEvictionWindow, cache.evictionWindow)
+}
+
+func TestGiftCache_TTLEvictsUnseenGifts(t *testing.T) {
+	cache := newGiftCache(0, 0)
+	cache.ttl = 20 * time.Millisecond
+
+	cache.SetGift(1, &tg.StarGift{ID: 1})
+	assert.True(t, cache.HasGift(1))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, cache.evictExpired())
+
+	assert.False(t, cache.HasGift(1))
+	gift, err := cache.GetGift(1)
+	assert.NoError(t, err)
+	assert.Nil(t, gift)
+}
+
+func TestGiftCache_TTLRefreshedBySeenGifts(t *testing.T) {
+	now := time.Now()
+	cache := newGiftCache(0, 0)
+	cache.ttl = time.Minute
+	cache.now = func() time.Time { return now }
+
+	cache.SetGift(1, &tg.StarGift{ID: 1})
+	cache.SetGift(2, &tg.StarGift{ID: 2})
+
+	// Gift 1 is still listed and checked every cycle, gift 2 disappeared
+	for i := 0; i < 3; i++ {
+		now = now.Add(40 * time.Second)
+		assert.True(t, cache.HasGift(1))
+	}
+
+	assert.Equal(t, 1, cache.evictExpired())
+	assert.True(t, cache.HasGift(1))
+	assert.False(t, cache.HasGift(2))
+	assert.Len(t, cache.seenAt, 1)
+}
+
+func TestGiftCache_TTLWithLimit(t *testing.T) {
+	now := time.Now()
+	cache := newGiftCache(2, time.Minute)
+	cache.ttl = time.Minute
+	cache.now = func() time.Time { return now }
+
+	cache.SetGift(1, &tg.StarGift{ID: 1})
+	now = now.Add(2 * time.Minute)
+	cache.SetGift(2, &tg.StarGift{ID: 2})
+
+	assert.Equal(t, 1, cache.evictExpired())
+	assert.Equal(t, 1, cache.lru.Len())
+	assert.NotContains(t, cache.lruIndex, int64(1))
+	assert.False(t, cache.HasGift(1))
+	assert.True(t, cache.HasGift(2))
+}
+
+func TestGiftCache_NoTTLKeepsGifts(t *testing.T) {
+	now := time.Now()
+	cache := newGiftCache(0, 0)
+	cache.now = func() time.Time { return now }
+
+	cache.SetGift(1, &tg.StarGift{ID: 1})
+	now = now.Add(24 * time.Hour)
+
+	assert.Equal(t, 0, cache.evictExpired())
+	assert.True(t, cache.HasGift(1))
+	assert.Empty(t, cache.seenAt)
+}
+
+func TestGiftCache_TTLFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	now := time.Now()
+
+	saved := map[string]CachedGift{
+		"1": {ID: 1, Stars: 100, SeenAt: now.Add(-2 * time.Hour).Unix()},
+		"2": {ID: 2, Stars: 200, SeenAt: now.Add(-10 * time.Minute).Unix()},
+		"3": {ID: 3, Stars: 300},
+	}
+	data, err := json.Marshal(saved)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile("cache.json", data, 0600))
+
+	cache := newGiftCache(0, 0)
+	cache.ttl = time.Hour
+	cache.now = func() time.Time { return now }
+
+	t.Run("просроченные подарки не загружаются", func(t *testing.T) {
+		cache.loadFromFile()
+
+		assert.False(t, cache.HasGift(1))
+		assert.True(t, cache.HasGift(2))
+		// Gifts saved without a last-seen time count as seen at startup
+		assert.True(t, cache.HasGift(3))
+	})
+
+	t.Run("просроченные подарки удаляются из файла", func(t *testing.T) {
+		now = now.Add(2 * time.Hour)
+		cache.SetGift(4, &tg.StarGift{ID: 4, Stars: 400})
+		assert.Equal(t, 2, cache.evictExpired())
+		cache.saveToFile()
+
+		data, err := os.ReadFile("cache.json")
+		require.NoError(t, err)
+		var file map[string]CachedGift
+		require.NoError(t, json.Unmarshal(data, &file))
+
+		assert.Equal(t, map[string]CachedGift{"4": {ID: 4, Stars: 400, SeenAt: now.Unix()}}, file)
+	})
+}
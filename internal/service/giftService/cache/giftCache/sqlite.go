@@ -0,0 +1,197 @@
+package giftCache
+
+import (
+	"database/sql"
+	"gift-buyer/pkg/errors"
+	"gift-buyer/pkg/logger"
+	"sync"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+
+	// Registers the pure Go "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+// DefaultSQLiteFile is the database file of the SQLite gift cache
+const DefaultSQLiteFile = "cache.db"
+
+// SQLiteGiftCache implements the GiftCache interface on top of a SQLite database.
+// Gifts are stored as serialized tg.StarGift blobs keyed by gift ID, so the whole
+// gift survives a restart, not only its ID and price. An in-memory map is kept as a
+// write-through layer: reads never touch the database, writes go to both.
+type SQLiteGiftCache struct {
+	// db is the database holding the gifts table
+	db *sql.DB
+
+	// cache mirrors the gifts table for fast reads
+	cache map[int64]*tg.StarGift
+
+	// mu provides thread-safe access to the cache map and keeps the map and the
+	// table in the same order of writes
+	mu sync.RWMutex
+}
+
+// NewSQLiteGiftCache opens (or creates) the SQLite gift cache at path and loads the
+// stored gifts into memory.
+//
+// Parameters:
+//   - path: database file, created if missing
+//
+// Returns:
+//   - *SQLiteGiftCache: cache with the previously stored gifts loaded
+//   - error: database open, schema or load error
+func NewSQLiteGiftCache(path string) (*SQLiteGiftCache, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open gift cache database")
+	}
+	// A single connection serializes the writers of the process, SQLite allows one anyway
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS gifts (id INTEGER PRIMARY KEY, data BLOB NOT NULL)`); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to create gift cache table")
+	}
+
+	gc := &SQLiteGiftCache{
+		db:    db,
+		cache: make(map[int64]*tg.StarGift),
+	}
+	if err := gc.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return gc, nil
+}
+
+// load reads every stored gift into the in-memory map. Rows that can't be decoded
+// are skipped with a warning instead of failing the startup.
+func (gc *SQLiteGiftCache) load() error {
+	rows, err := gc.db.Query(`SELECT id, data FROM gifts`)
+	if err != nil {
+		return errors.Wrap(err, "failed to load gift cache")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   int64
+			data []byte
+		)
+		if err := rows.Scan(&id, &data); err != nil {
+			return errors.Wrap(err, "failed to read gift cache row")
+		}
+
+		gift := &tg.StarGift{}
+		if err := gift.Decode(&bin.Buffer{Buf: data}); err != nil {
+			logger.GlobalLogger.Warnf("Failed to decode cached gift %d: %v", id, err)
+			continue
+		}
+		gc.cache[id] = gift
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "failed to load gift cache")
+	}
+
+	logger.GlobalLogger.Infof("Loaded %d gifts from cache database", len(gc.cache))
+	return nil
+}
+
+// SetGift stores a gift in memory and in the database. A database error is logged,
+// the gift stays cached in memory for the current run.
+//
+// Parameters:
+//   - id: unique identifier for the gift (typically gift.ID)
+//   - gift: the star gift object to cache
+func (gc *SQLiteGiftCache) SetGift(id int64, gift *tg.StarGift) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	gc.cache[id] = gift
+
+	var buf bin.Buffer
+	if err := gift.Encode(&buf); err != nil {
+		logger.GlobalLogger.Errorf("Failed to encode gift %d for cache: %v", id, err)
+		return
+	}
+	if _, err := gc.db.Exec(`INSERT INTO gifts (id, data) VALUES (?, ?) ON CONFLICT(id) DO UPDATE SET data = excluded.data`, id, buf.Buf); err != nil {
+		logger.GlobalLogger.Errorf("Failed to store gift %d in cache database: %v", id, err)
+	}
+}
+
+// GetGift retrieves a cached gift by its ID from memory.
+//
+// Parameters:
+//   - id: unique identifier of the gift to retrieve
+//
+// Returns:
+//   - *tg.StarGift: the cached gift object, nil if not found
+//   - error: always nil in current implementation
+func (gc *SQLiteGiftCache) GetGift(id int64) (*tg.StarGift, error) {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+
+	return gc.cache[id], nil
+}
+
+// GetAllGifts returns a copy of all cached gifts.
+//
+// Returns:
+//   - map[int64]*tg.StarGift: map of gift IDs to gift objects (copy of internal cache)
+func (gc *SQLiteGiftCache) GetAllGifts() map[int64]*tg.StarGift {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+
+	result := make(map[int64]*tg.StarGift, len(gc.cache))
+	for id, gift := range gc.cache {
+		result[id] = gift
+	}
+	return result
+}
+
+// HasGift checks if a gift with the specified ID exists in the cache.
+//
+// Parameters:
+//   - id: unique identifier of the gift to check
+//
+// Returns:
+//   - bool: true if the gift exists in cache, false otherwise
+func (gc *SQLiteGiftCache) HasGift(id int64) bool {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+
+	_, exists := gc.cache[id]
+	return exists
+}
+
+// DeleteGift removes a gift from memory and from the database.
+//
+// Parameters:
+//   - id: unique identifier of the gift to remove
+func (gc *SQLiteGiftCache) DeleteGift(id int64) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	delete(gc.cache, id)
+	if _, err := gc.db.Exec(`DELETE FROM gifts WHERE id = ?`, id); err != nil {
+		logger.GlobalLogger.Errorf("Failed to delete gift %d from cache database: %v", id, err)
+	}
+}
+
+// Clear removes all gifts from memory and from the database.
+func (gc *SQLiteGiftCache) Clear() {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	gc.cache = make(map[int64]*tg.StarGift)
+	if _, err := gc.db.Exec(`DELETE FROM gifts`); err != nil {
+		logger.GlobalLogger.Errorf("Failed to clear cache database: %v", err)
+	}
+}
+
+// Close closes the database. The cache must not be used afterwards.
+func (gc *SQLiteGiftCache) Close() error {
+	return gc.db.Close()
+}
@@ -0,0 +1,117 @@
+package giftCache
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLiteCache(t *testing.T, path string) *SQLiteGiftCache {
+	t.Helper()
+
+	cache, err := NewSQLiteGiftCache(path)
+	require.NoError(t, err)
+	return cache
+}
+
+// storedGift creates a gift that can be serialized, Telegram always sends the sticker
+func storedGift(id, stars int64) *tg.StarGift {
+	return &tg.StarGift{ID: id, Stars: stars, Sticker: &tg.DocumentEmpty{ID: id}}
+}
+
+func TestSQLiteGiftCache(t *testing.T) {
+	t.Run("сохранение и получение подарка", func(t *testing.T) {
+		cache := newTestSQLiteCache(t, filepath.Join(t.TempDir(), "cache.db"))
+		defer cache.Close()
+
+		gift := storedGift(123, 500)
+		gift.SetAvailabilityRemains(7)
+		cache.SetGift(123, gift)
+
+		stored, err := cache.GetGift(123)
+		assert.NoError(t, err)
+		assert.Equal(t, gift, stored)
+		assert.True(t, cache.HasGift(123))
+		assert.False(t, cache.HasGift(456))
+		assert.Len(t, cache.GetAllGifts(), 1)
+	})
+
+	t.Run("отсутствующий подарок", func(t *testing.T) {
+		cache := newTestSQLiteCache(t, filepath.Join(t.TempDir(), "cache.db"))
+		defer cache.Close()
+
+		gift, err := cache.GetGift(999)
+		assert.NoError(t, err)
+		assert.Nil(t, gift)
+	})
+
+	t.Run("удаление подарка", func(t *testing.T) {
+		cache := newTestSQLiteCache(t, filepath.Join(t.TempDir(), "cache.db"))
+		defer cache.Close()
+
+		cache.SetGift(1, storedGift(1, 100))
+		cache.SetGift(2, storedGift(2, 200))
+		cache.DeleteGift(1)
+
+		assert.False(t, cache.HasGift(1))
+		assert.True(t, cache.HasGift(2))
+	})
+
+	t.Run("очистка кэша", func(t *testing.T) {
+		cache := newTestSQLiteCache(t, filepath.Join(t.TempDir(), "cache.db"))
+		defer cache.Close()
+
+		cache.SetGift(1, storedGift(1, 100))
+		cache.SetGift(2, storedGift(2, 200))
+		cache.Clear()
+
+		assert.Empty(t, cache.GetAllGifts())
+		assert.False(t, cache.HasGift(1))
+	})
+
+	t.Run("копия всех подарков изолирована", func(t *testing.T) {
+		cache := newTestSQLiteCache(t, filepath.Join(t.TempDir(), "cache.db"))
+		defer cache.Close()
+
+		cache.SetGift(1, storedGift(1, 100))
+		all := cache.GetAllGifts()
+		delete(all, 1)
+
+		assert.True(t, cache.HasGift(1))
+	})
+}
+
+func TestSQLiteGiftCache_Restart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	cache := newTestSQLiteCache(t, path)
+	limited := storedGift(1, 100)
+	limited.Limited = true
+	limited.SetAvailabilityRemains(3)
+	limited.SetAvailabilityTotal(10)
+	cache.SetGift(1, limited)
+	cache.SetGift(2, storedGift(2, 200))
+	cache.SetGift(2, storedGift(2, 250))
+	cache.SetGift(3, storedGift(3, 300))
+	cache.DeleteGift(3)
+	require.NoError(t, cache.Close())
+
+	reopened := newTestSQLiteCache(t, path)
+	defer reopened.Close()
+
+	// Full gifts survive the restart, updates and deletions included
+	assert.Equal(t, map[int64]*tg.StarGift{
+		1: limited,
+		2: storedGift(2, 250),
+	}, reopened.GetAllGifts())
+
+	reopened.Clear()
+	require.NoError(t, reopened.Close())
+
+	cleared := newTestSQLiteCache(t, path)
+	defer cleared.Close()
+	assert.Empty(t, cleared.GetAllGifts())
+}
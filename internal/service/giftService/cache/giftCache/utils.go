@@ -6,6 +6,7 @@ import (
 	"gift-buyer/pkg/logger"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/gotd/td/tg"
 )
@@ -19,6 +20,9 @@ type CachedGift struct {
 
 	// Stars is the price of the gift in Telegram stars
 	Stars int64 `json:"stars"`
+
+	// SeenAt is the Unix time the gift was last seen, stored only when the cache has a TTL
+	SeenAt int64 `json:"seen_at,omitempty"`
 }
 
 // loadFromFile loads cached gift data from the cache.json file.
@@ -28,6 +32,8 @@ type CachedGift struct {
 //
 // The method is called during cache initialization to restore previously cached gifts.
 // It reconstructs StarGift objects from the simplified CachedGift structures.
+// With a TTL gifts that expired while the application was stopped are skipped,
+// gifts saved without a last-seen time count as seen at startup.
 func (gc *GiftCacheImpl) loadFromFile() {
 	data, err := os.ReadFile("cache.json")
 	if err != nil {
@@ -44,15 +50,32 @@ func (gc *GiftCacheImpl) loadFromFile() {
 	}
 
 	gc.mu.Lock()
+	now := gc.now()
 	count := 0
 	for _, cached := range cachedGifts {
+		if gc.expiring() {
+			seenAt := now
+			if cached.SeenAt > 0 {
+				seenAt = time.Unix(cached.SeenAt, 0)
+			}
+			if now.Sub(seenAt) > gc.ttl {
+				continue
+			}
+			gc.seenAt[cached.ID] = seenAt
+		}
 		gift := &tg.StarGift{
 			ID:    cached.ID,
 			Stars: cached.Stars,
 		}
 		gc.cache[cached.ID] = gift
+		if gc.bounded() {
+			gc.touch(cached.ID)
+		}
 		count++
 	}
+	if gc.bounded() {
+		gc.evictOverflow()
+	}
 	gc.mu.Unlock()
 
 	logger.GlobalLogger.Infof("Loaded %d gifts from cache file", count)
@@ -69,7 +92,9 @@ func (gc *GiftCacheImpl) loadFromFile() {
 //  4. Writes the complete dataset to cache.json
 //
 // Only new gifts are added to the file to optimize I/O operations.
-// If no new gifts are found, the save operation is skipped.
+// With a TTL gifts no longer cached whose last-seen time expired are removed from
+// the file, and the last-seen times of the remaining gifts are refreshed.
+// If no gifts are added or removed, the save operation is skipped.
 func (gc *GiftCacheImpl) saveToFile() {
 	var existingCache map[string]CachedGift
 	if data, err := os.ReadFile("cache.json"); err == nil {
@@ -82,20 +107,39 @@ func (gc *GiftCacheImpl) saveToFile() {
 	}
 
 	gc.mu.RLock()
+	now := gc.now()
+	expiredGifts := 0
+	if gc.expiring() {
+		for key, cached := range existingCache {
+			if _, cachedNow := gc.cache[cached.ID]; cachedNow {
+				continue
+			}
+			if cached.SeenAt == 0 || now.Sub(time.Unix(cached.SeenAt, 0)) > gc.ttl {
+				delete(existingCache, key)
+				expiredGifts++
+			}
+		}
+	}
+
 	newGifts := 0
 	for id, gift := range gc.cache {
 		key := strconv.FormatInt(id, 10)
-		if _, exists := existingCache[key]; !exists {
-			existingCache[key] = CachedGift{
+		cached, exists := existingCache[key]
+		if !exists {
+			cached = CachedGift{
 				ID:    gift.ID,
 				Stars: gift.Stars,
 			}
 			newGifts++
 		}
+		if seenAt, ok := gc.seenAt[id]; ok {
+			cached.SeenAt = seenAt.Unix()
+		}
+		existingCache[key] = cached
 	}
 	gc.mu.RUnlock()
 
-	if newGifts == 0 {
+	if newGifts == 0 && expiredGifts == 0 {
 		return
 	}
 
@@ -110,5 +154,9 @@ func (gc *GiftCacheImpl) saveToFile() {
 		return
 	}
 
+	if expiredGifts > 0 {
+		logger.GlobalLogger.Infof("Saved %d new gifts to cache file, removed %d expired", newGifts, expiredGifts)
+		return
+	}
 	logger.GlobalLogger.Infof("Saved %d new gifts to cache file", newGifts)
 }
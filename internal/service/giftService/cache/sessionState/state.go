@@ -0,0 +1,197 @@
+// Package sessionState persists the minimal runtime state of a buying session.
+// It allows a crash-restart within the same drop to continue without notifying
+// about or buying the same gifts again. A state left untouched for longer than
+// its TTL belongs to an earlier drop and is discarded on load.
+package sessionState
+
+import (
+	"encoding/json"
+	"gift-buyer/pkg/logger"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long after its last change a session state is still resumed
+// when no TTL is configured.
+const DefaultTTL = time.Hour
+
+// State is the on-disk representation of the session state.
+type State struct {
+	// BoughtCount is the total number of gifts bought in this session
+	BoughtCount int64 `json:"bought_count"`
+
+	// Bought maps gift IDs to the number of units bought in this session
+	Bought map[int64]int64 `json:"bought"`
+
+	// Claimed contains IDs of gifts already handed over for purchase
+	Claimed map[int64]bool `json:"claimed"`
+
+	// Notified contains IDs of gifts the user was already notified about
+	Notified map[int64]bool `json:"notified"`
+
+	// UpdatedAt is the time of the last state change
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// sessionStateImpl keeps the session state in memory and writes it to disk on every change.
+type sessionStateImpl struct {
+	path string
+
+	// ttl is how long after its last change the persisted state is still resumed
+	ttl time.Duration
+
+	state State
+	mu    sync.Mutex
+}
+
+// NewSessionState creates a session state backed by the specified file.
+// An existing file changed within the TTL is loaded so the session continues where
+// it stopped; a missing, unreadable or expired file starts an empty session, so the
+// bought count and the claimed gifts of an earlier drop never carry over.
+//
+// Parameters:
+//   - path: path of the session state file
+//   - ttl: how long after its last change the state is resumed (defaults to DefaultTTL)
+//
+// Returns:
+//   - *sessionStateImpl: session state instance
+func NewSessionState(path string, ttl time.Duration) *sessionStateImpl {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	ss := &sessionStateImpl{
+		path:  path,
+		ttl:   ttl,
+		state: newState(),
+	}
+	ss.load()
+	return ss
+}
+
+func newState() State {
+	return State{
+		Bought:   make(map[int64]int64),
+		Claimed:  make(map[int64]bool),
+		Notified: make(map[int64]bool),
+	}
+}
+
+// IsClaimed reports whether the gift was already handed over for purchase.
+func (ss *sessionStateImpl) IsClaimed(id int64) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.state.Claimed[id]
+}
+
+// Claim marks the gift as handed over for purchase.
+func (ss *sessionStateImpl) Claim(id int64) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.state.Claimed[id] {
+		return
+	}
+	ss.state.Claimed[id] = true
+	ss.saveLocked()
+}
+
+// IsNotified reports whether the user was already notified about the gift.
+func (ss *sessionStateImpl) IsNotified(id int64) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.state.Notified[id]
+}
+
+// MarkNotified records that the user was notified about the gift.
+func (ss *sessionStateImpl) MarkNotified(id int64) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.state.Notified[id] {
+		return
+	}
+	ss.state.Notified[id] = true
+	ss.saveLocked()
+}
+
+// RecordPurchase records one successfully bought unit of the gift.
+func (ss *sessionStateImpl) RecordPurchase(id int64) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.state.Bought[id]++
+	ss.state.BoughtCount++
+	ss.saveLocked()
+}
+
+// BoughtCount returns the number of gifts bought in this session.
+func (ss *sessionStateImpl) BoughtCount() int64 {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.state.BoughtCount
+}
+
+// load reads the session state file if it exists.
+func (ss *sessionStateImpl) load() {
+	data, err := os.ReadFile(ss.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.GlobalLogger.Warnf("Failed to read session state file: %v", err)
+		}
+		return
+	}
+
+	state := newState()
+	if err := json.Unmarshal(data, &state); err != nil {
+		logger.GlobalLogger.Warnf("Failed to unmarshal session state file: %v", err)
+		return
+	}
+	if age := time.Since(state.UpdatedAt); age > ss.ttl {
+		logger.GlobalLogger.Infof("Session state was last changed %s ago, starting a new session", age.Round(time.Second))
+		return
+	}
+	if state.Bought == nil {
+		state.Bought = make(map[int64]int64)
+	}
+	if state.Claimed == nil {
+		state.Claimed = make(map[int64]bool)
+	}
+	if state.Notified == nil {
+		state.Notified = make(map[int64]bool)
+	}
+
+	ss.state = state
+	logger.GlobalLogger.Infof("Restored session state: %d bought, %d claimed gifts", state.BoughtCount, len(state.Claimed))
+}
+
+// saveLocked writes the session state to disk through a temporary file so that
+// a crash during the write never leaves a truncated state. The caller must hold mu.
+func (ss *sessionStateImpl) saveLocked() {
+	ss.state.UpdatedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(ss.state, "", "  ")
+	if err != nil {
+		logger.GlobalLogger.Errorf("Failed to marshal session state: %v", err)
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(ss.path), filepath.Base(ss.path)+".*.tmp")
+	if err != nil {
+		logger.GlobalLogger.Errorf("Failed to write session state: %v", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		logger.GlobalLogger.Errorf("Failed to write session state: %v", err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		logger.GlobalLogger.Errorf("Failed to write session state: %v", err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), ss.path); err != nil {
+		logger.GlobalLogger.Errorf("Failed to write session state: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package sessionState
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionState_EmptyWhenFileMissing(t *testing.T) {
+	state := NewSessionState(filepath.Join(t.TempDir(), "session_state.json"), 0)
+
+	assert.Equal(t, int64(0), state.BoughtCount())
+	assert.False(t, state.IsClaimed(1))
+	assert.False(t, state.IsNotified(1))
+}
+
+func TestSessionState_RoundTripAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session_state.json")
+
+	state := NewSessionState(path, 0)
+	state.Claim(1)
+	state.Claim(2)
+	state.MarkNotified(1)
+	state.RecordPurchase(1)
+	state.RecordPurchase(1)
+
+	// Simulated restart: a fresh instance reads the same file
+	restored := NewSessionState(path, 0)
+
+	assert.Equal(t, int64(2), restored.BoughtCount())
+	assert.Equal(t, int64(2), restored.state.Bought[1])
+	assert.True(t, restored.IsClaimed(1))
+	assert.True(t, restored.IsClaimed(2))
+	assert.False(t, restored.IsClaimed(3))
+	assert.True(t, restored.IsNotified(1))
+	assert.False(t, restored.IsNotified(2))
+	assert.False(t, restored.state.UpdatedAt.IsZero())
+
+	// The restored session keeps counting from the persisted state
+	restored.RecordPurchase(2)
+	assert.Equal(t, int64(3), NewSessionState(path, 0).BoughtCount())
+}
+
+func TestSessionState_CorruptedFileStartsEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session_state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0600))
+
+	state := NewSessionState(path, 0)
+
+	assert.Equal(t, int64(0), state.BoughtCount())
+	state.Claim(1)
+	assert.True(t, NewSessionState(path, 0).IsClaimed(1))
+}
+
+func TestSessionState_NoTemporaryFilesLeft(t *testing.T) {
+	dir := t.TempDir()
+	state := NewSessionState(filepath.Join(dir, "session_state.json"), 0)
+
+	state.Claim(1)
+	state.RecordPurchase(1)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestSessionState_ExpiredStateStartsEmpty(t *testing.T) {
+	writeState := func(t *testing.T, updatedAt time.Time) string {
+		path := filepath.Join(t.TempDir(), "session_state.json")
+		data, err := json.Marshal(State{
+			BoughtCount: 5,
+			Bought:      map[int64]int64{1: 5},
+			Claimed:     map[int64]bool{1: true},
+			Notified:    map[int64]bool{1: true},
+			UpdatedAt:   updatedAt,
+		})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0600))
+		return path
+	}
+
+	t.Run("состояние прошлого дропа сбрасывается", func(t *testing.T) {
+		state := NewSessionState(writeState(t, time.Now().Add(-2*time.Hour)), time.Hour)
+
+		assert.Equal(t, int64(0), state.BoughtCount())
+		assert.False(t, state.IsClaimed(1))
+		assert.False(t, state.IsNotified(1))
+	})
+
+	t.Run("свежее состояние восстанавливается", func(t *testing.T) {
+		state := NewSessionState(writeState(t, time.Now().Add(-10*time.Minute)), time.Hour)
+
+		assert.Equal(t, int64(5), state.BoughtCount())
+		assert.True(t, state.IsClaimed(1))
+	})
+
+	t.Run("срок по умолчанию", func(t *testing.T) {
+		state := NewSessionState(writeState(t, time.Now().Add(-DefaultTTL-time.Minute)), 0)
+
+		assert.Equal(t, DefaultTTL, state.ttl)
+		assert.Equal(t, int64(0), state.BoughtCount())
+	})
+}
@@ -0,0 +1,237 @@
+// Package catalogSnapshot periodically saves the full gift catalog to disk for analytics.
+// Every snapshot is compared with the previous one and the added, removed and changed
+// gifts are written as a diff. It runs independently of monitoring and buying.
+package catalogSnapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+	"gift-buyer/pkg/errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+// fileTimeLayout is the timestamp format used in snapshot and diff file names
+const fileTimeLayout = "20060102-150405"
+
+// GiftInfo is the snapshot record of a single catalog gift.
+type GiftInfo struct {
+	ID                  int64  `json:"id"`
+	Title               string `json:"title,omitempty"`
+	Stars               int64  `json:"stars"`
+	ConvertStars        int64  `json:"convert_stars"`
+	Limited             bool   `json:"limited"`
+	SoldOut             bool   `json:"sold_out"`
+	AvailabilityRemains int    `json:"availability_remains"`
+	AvailabilityTotal   int    `json:"availability_total"`
+}
+
+// Snapshot is the state of the gift catalog at a point in time.
+type Snapshot struct {
+	TakenAt time.Time          `json:"taken_at"`
+	Gifts   map[int64]GiftInfo `json:"gifts"`
+}
+
+// GiftChange is a gift whose record differs between two snapshots.
+type GiftChange struct {
+	Before GiftInfo `json:"before"`
+	After  GiftInfo `json:"after"`
+}
+
+// Diff lists the differences between two snapshots, ordered by gift ID.
+type Diff struct {
+	From    time.Time    `json:"from"`
+	To      time.Time    `json:"to"`
+	Added   []GiftInfo   `json:"added"`
+	Removed []GiftInfo   `json:"removed"`
+	Changed []GiftChange `json:"changed"`
+}
+
+// Empty reports whether the snapshots are identical.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// NewSnapshot builds a snapshot of the catalog gifts.
+//
+// Parameters:
+//   - gifts: gifts of the catalog
+//   - takenAt: time the catalog was fetched
+//
+// Returns:
+//   - Snapshot: snapshot of the catalog
+func NewSnapshot(gifts []*tg.StarGift, takenAt time.Time) Snapshot {
+	snapshot := Snapshot{TakenAt: takenAt, Gifts: make(map[int64]GiftInfo, len(gifts))}
+	for _, gift := range gifts {
+		title, _ := gift.GetTitle()
+		remains, _ := gift.GetAvailabilityRemains()
+		total, _ := gift.GetAvailabilityTotal()
+		snapshot.Gifts[gift.ID] = GiftInfo{
+			ID:                  gift.ID,
+			Title:               title,
+			Stars:               gift.Stars,
+			ConvertStars:        gift.ConvertStars,
+			Limited:             gift.Limited,
+			SoldOut:             gift.SoldOut,
+			AvailabilityRemains: remains,
+			AvailabilityTotal:   total,
+		}
+	}
+	return snapshot
+}
+
+// DiffSnapshots compares two snapshots.
+//
+// Parameters:
+//   - previous: the older snapshot
+//   - current: the newer snapshot
+//
+// Returns:
+//   - Diff: gifts added, removed and changed since the previous snapshot
+func DiffSnapshots(previous, current Snapshot) Diff {
+	diff := Diff{From: previous.TakenAt, To: current.TakenAt}
+
+	for id, after := range current.Gifts {
+		before, ok := previous.Gifts[id]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, after)
+		case before != after:
+			diff.Changed = append(diff.Changed, GiftChange{Before: before, After: after})
+		}
+	}
+	for id, before := range previous.Gifts {
+		if _, ok := current.Gifts[id]; !ok {
+			diff.Removed = append(diff.Removed, before)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].ID < diff.Added[j].ID })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].ID < diff.Removed[j].ID })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].After.ID < diff.Changed[j].After.ID })
+	return diff
+}
+
+// catalogSnapshotterImpl takes catalog snapshots on a fixed interval.
+type catalogSnapshotterImpl struct {
+	// manager fetches the gift catalog
+	manager giftInterfaces.Giftmanager
+
+	// dir is the directory the snapshots and diffs are written to
+	dir string
+
+	// interval is the time between two snapshots
+	interval time.Duration
+
+	infoLogsWriter  giftInterfaces.InfoLogger
+	errorLogsWriter giftInterfaces.ErrorLogger
+
+	// previous is the last taken snapshot, nil before the first one
+	previous *Snapshot
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// NewCatalogSnapshotter creates a periodic catalog snapshotter.
+//
+// Parameters:
+//   - manager: gift manager fetching the catalog
+//   - dir: output directory of the snapshots and diffs
+//   - interval: time between two snapshots
+//   - infoLogsWriter: logger for informational messages
+//   - errorLogsWriter: logger for error messages
+//
+// Returns:
+//   - *catalogSnapshotterImpl: catalog snapshotter instance
+func NewCatalogSnapshotter(manager giftInterfaces.Giftmanager, dir string, interval time.Duration, infoLogsWriter giftInterfaces.InfoLogger, errorLogsWriter giftInterfaces.ErrorLogger) *catalogSnapshotterImpl {
+	return &catalogSnapshotterImpl{
+		manager:         manager,
+		dir:             dir,
+		interval:        interval,
+		infoLogsWriter:  infoLogsWriter,
+		errorLogsWriter: errorLogsWriter,
+		now:             time.Now,
+	}
+}
+
+// Run takes a snapshot right away and then on every interval until the context is cancelled.
+// Failed snapshots are logged and retried on the next interval.
+func (cs *catalogSnapshotterImpl) Run(ctx context.Context) {
+	ticker := time.NewTicker(cs.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := cs.TakeSnapshot(ctx); err != nil && ctx.Err() == nil {
+			cs.errorLogsWriter.LogError(fmt.Sprintf("Catalog snapshot failed: %v", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// TakeSnapshot fetches the catalog, writes the snapshot and, if anything changed since
+// the previous snapshot, the diff to the output directory.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//
+// Returns:
+//   - Diff: differences to the previous snapshot, empty for the first snapshot
+//   - error: catalog request or file write error
+func (cs *catalogSnapshotterImpl) TakeSnapshot(ctx context.Context) (Diff, error) {
+	gifts, err := cs.manager.GetAvailableGifts(ctx)
+	if err != nil {
+		return Diff{}, errors.Wrap(err, "failed to fetch gift catalog")
+	}
+
+	snapshot := NewSnapshot(gifts, cs.now().UTC())
+	stamp := snapshot.TakenAt.Format(fileTimeLayout)
+	if err := cs.writeJSON(fmt.Sprintf("catalog_%s.json", stamp), snapshot); err != nil {
+		return Diff{}, err
+	}
+
+	previous := cs.previous
+	cs.previous = &snapshot
+	if previous == nil {
+		cs.infoLogsWriter.LogInfo(fmt.Sprintf("Catalog snapshot taken: %d gifts", len(snapshot.Gifts)))
+		return Diff{}, nil
+	}
+
+	diff := DiffSnapshots(*previous, snapshot)
+	if diff.Empty() {
+		return diff, nil
+	}
+	if err := cs.writeJSON(fmt.Sprintf("diff_%s.json", stamp), diff); err != nil {
+		return diff, err
+	}
+	cs.infoLogsWriter.LogInfo(fmt.Sprintf("Catalog changed: %d added, %d removed, %d changed gifts",
+		len(diff.Added), len(diff.Removed), len(diff.Changed)))
+	return diff, nil
+}
+
+// writeJSON writes the value as indented JSON to the named file of the output directory.
+func (cs *catalogSnapshotterImpl) writeJSON(name string, value interface{}) error {
+	if err := os.MkdirAll(cs.dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create catalog snapshot directory")
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal catalog snapshot")
+	}
+	if err := os.WriteFile(filepath.Join(cs.dir, name), data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write catalog snapshot")
+	}
+	return nil
+}
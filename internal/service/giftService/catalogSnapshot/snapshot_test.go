@@ -0,0 +1,122 @@
+package catalogSnapshot
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockLogsWriter struct{}
+
+func (m *mockLogsWriter) LogInfo(message string)                       {}
+func (m *mockLogsWriter) LogError(message string)                      {}
+func (m *mockLogsWriter) LogErrorf(format string, args ...interface{}) {}
+
+// fakeManager returns the queued catalogs one by one
+type fakeManager struct {
+	catalogs [][]*tg.StarGift
+}
+
+func (m *fakeManager) GetAvailableGifts(ctx context.Context) ([]*tg.StarGift, error) {
+	catalog := m.catalogs[0]
+	m.catalogs = m.catalogs[1:]
+	return catalog, nil
+}
+
+func newGift(id, stars int64, remains int) *tg.StarGift {
+	gift := &tg.StarGift{ID: id, Stars: stars, Limited: true}
+	gift.SetAvailabilityRemains(remains)
+	gift.SetAvailabilityTotal(1000)
+	return gift
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Minute)
+
+	previous := NewSnapshot([]*tg.StarGift{
+		newGift(1, 100, 500),
+		newGift(2, 200, 500),
+		newGift(3, 300, 500),
+	}, from)
+	current := NewSnapshot([]*tg.StarGift{
+		newGift(1, 100, 500),
+		newGift(2, 200, 120),
+		newGift(4, 400, 1000),
+		newGift(5, 500, 1000),
+	}, to)
+
+	diff := DiffSnapshots(previous, current)
+
+	assert.Equal(t, from, diff.From)
+	assert.Equal(t, to, diff.To)
+	if assert.Len(t, diff.Added, 2) {
+		assert.Equal(t, int64(4), diff.Added[0].ID)
+		assert.Equal(t, int64(5), diff.Added[1].ID)
+	}
+	if assert.Len(t, diff.Removed, 1) {
+		assert.Equal(t, int64(3), diff.Removed[0].ID)
+	}
+	if assert.Len(t, diff.Changed, 1) {
+		assert.Equal(t, 500, diff.Changed[0].Before.AvailabilityRemains)
+		assert.Equal(t, 120, diff.Changed[0].After.AvailabilityRemains)
+	}
+	assert.False(t, diff.Empty())
+
+	assert.True(t, DiffSnapshots(current, current).Empty())
+}
+
+func TestCatalogSnapshotter_TakeSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	manager := &fakeManager{catalogs: [][]*tg.StarGift{
+		{newGift(1, 100, 500), newGift(2, 200, 500)},
+		{newGift(1, 100, 500), newGift(2, 200, 500)},
+		{newGift(2, 250, 500), newGift(3, 300, 500)},
+	}}
+
+	snapshotter := NewCatalogSnapshotter(manager, dir, time.Minute, &mockLogsWriter{}, &mockLogsWriter{})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshotter.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+
+	// The first snapshot is the baseline
+	diff, err := snapshotter.TakeSnapshot(context.Background())
+	require.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	// An unchanged catalog writes no diff
+	diff, err = snapshotter.TakeSnapshot(context.Background())
+	require.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	diff, err = snapshotter.TakeSnapshot(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, diff.Added, 1)
+	assert.Len(t, diff.Removed, 1)
+	assert.Len(t, diff.Changed, 1)
+
+	catalogs, err := filepath.Glob(filepath.Join(dir, "catalog_*.json"))
+	require.NoError(t, err)
+	assert.Len(t, catalogs, 3)
+
+	diffs, err := filepath.Glob(filepath.Join(dir, "diff_*.json"))
+	require.NoError(t, err)
+	require.Len(t, diffs, 1)
+
+	data, err := os.ReadFile(diffs[0])
+	require.NoError(t, err)
+	var written Diff
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, int64(3), written.Added[0].ID)
+	assert.Equal(t, int64(1), written.Removed[0].ID)
+	assert.Equal(t, int64(250), written.Changed[0].After.Stars)
+}
@@ -0,0 +1,116 @@
+// Package accountPool spreads gift purchases across the buyers of several Telegram
+// accounts, so purchases are not limited by the rate limits and balance of one session.
+package accountPool
+
+import (
+	"context"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+	"gift-buyer/internal/service/giftService/giftTypes"
+	"sync"
+)
+
+// accountPoolImpl dispatches gift units to the account buyers round-robin and buys
+// with all accounts in parallel.
+type accountPoolImpl struct {
+	// buyers perform the purchases, one per account
+	buyers []giftInterfaces.GiftBuyer
+
+	// cursor is the account the next dispatched unit goes to
+	cursor int
+	mu     sync.Mutex
+}
+
+// NewAccountPool creates a pool buying with the specified account buyers.
+//
+// Parameters:
+//   - buyers: buyers bound to the sessions of the accounts
+//
+// Returns:
+//   - *accountPoolImpl: account pool implementing the GiftBuyer interface
+func NewAccountPool(buyers ...giftInterfaces.GiftBuyer) *accountPoolImpl {
+	return &accountPoolImpl{buyers: buyers}
+}
+
+// BuyGift distributes the units of the gifts across the accounts round-robin and
+// blocks until every account finished its share. Broadcast, staged and weighted
+// distribution gifts depend on all of their units and are bought by a single account.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - gifts: eligible gifts to buy
+func (ap *accountPoolImpl) BuyGift(ctx context.Context, gifts []*giftTypes.GiftRequire) {
+	batches := ap.distribute(gifts)
+
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		if len(batch) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(buyer giftInterfaces.GiftBuyer, batch []*giftTypes.GiftRequire) {
+			defer wg.Done()
+			buyer.BuyGift(ctx, batch)
+		}(ap.buyers[i], batch)
+	}
+	wg.Wait()
+}
+
+// Close closes the buyers of all accounts.
+func (ap *accountPoolImpl) Close() {
+	for _, buyer := range ap.buyers {
+		buyer.Close()
+	}
+}
+
+// distribute splits the gifts into one batch per account. The units of a gift are
+// assigned round-robin and the units landing on the same account are merged.
+//
+// Parameters:
+//   - gifts: eligible gifts to buy
+//
+// Returns:
+//   - [][]*giftTypes.GiftRequire: gifts to buy by every account, indexed like the buyers
+func (ap *accountPoolImpl) distribute(gifts []*giftTypes.GiftRequire) [][]*giftTypes.GiftRequire {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+
+	batches := make([][]*giftTypes.GiftRequire, len(ap.buyers))
+	if len(ap.buyers) == 0 {
+		return batches
+	}
+
+	for _, gift := range gifts {
+		if gift.BroadcastToAllReceivers || len(gift.Stages) > 0 || len(gift.ReceiverDistribution) > 0 || gift.CountForBuy <= 1 {
+			batches[ap.cursor] = append(batches[ap.cursor], gift)
+			ap.advance()
+			continue
+		}
+
+		counts := make([]int64, len(ap.buyers))
+		for i := int64(0); i < gift.CountForBuy; i++ {
+			counts[ap.cursor]++
+			ap.advance()
+		}
+		for account, count := range counts {
+			if count == 0 {
+				continue
+			}
+			batches[account] = append(batches[account], &giftTypes.GiftRequire{
+				Gift:                   gift.Gift,
+				ReceiverType:           gift.ReceiverType,
+				CountForBuy:            count,
+				Hide:                   gift.Hide,
+				Upgrade:                gift.Upgrade,
+				CriteriaIndex:          gift.CriteriaIndex,
+				BuyForAllReceiverTypes: gift.BuyForAllReceiverTypes,
+				MaxPerGift:             gift.MaxPerGift,
+			})
+		}
+	}
+	return batches
+}
+
+// advance moves the cursor to the next account.
+func (ap *accountPoolImpl) advance() {
+	ap.cursor = (ap.cursor + 1) % len(ap.buyers)
+}
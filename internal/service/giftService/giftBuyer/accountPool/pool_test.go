@@ -0,0 +1,93 @@
+package accountPool
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"gift-buyer/internal/service/giftService/giftTypes"
+
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingBuyer records the gifts dispatched to one account
+type recordingBuyer struct {
+	mu     sync.Mutex
+	gifts  []*giftTypes.GiftRequire
+	closed bool
+}
+
+func (b *recordingBuyer) BuyGift(ctx context.Context, gifts []*giftTypes.GiftRequire) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.gifts = append(b.gifts, gifts...)
+}
+
+func (b *recordingBuyer) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
+
+// units returns the number of units dispatched to the account per gift ID
+func (b *recordingBuyer) units() map[int64]int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	units := make(map[int64]int64)
+	for _, gift := range b.gifts {
+		units[gift.Gift.ID] += gift.CountForBuy
+	}
+	return units
+}
+
+func TestAccountPool_BuyGift(t *testing.T) {
+	t.Run("единицы подарка распределяются между аккаунтами", func(t *testing.T) {
+		first, second := &recordingBuyer{}, &recordingBuyer{}
+		pool := NewAccountPool(first, second)
+
+		pool.BuyGift(context.Background(), []*giftTypes.GiftRequire{
+			{Gift: &tg.StarGift{ID: 1}, CountForBuy: 5, ReceiverType: []int{1}, Hide: true},
+		})
+
+		assert.Equal(t, map[int64]int64{1: 3}, first.units())
+		assert.Equal(t, map[int64]int64{1: 2}, second.units())
+		for _, buyer := range []*recordingBuyer{first, second} {
+			assert.Len(t, buyer.gifts, 1)
+			assert.True(t, buyer.gifts[0].Hide)
+			assert.Equal(t, []int{1}, buyer.gifts[0].ReceiverType)
+		}
+	})
+
+	t.Run("очередь аккаунтов продолжается между вызовами", func(t *testing.T) {
+		first, second := &recordingBuyer{}, &recordingBuyer{}
+		pool := NewAccountPool(first, second)
+
+		pool.BuyGift(context.Background(), []*giftTypes.GiftRequire{{Gift: &tg.StarGift{ID: 1}, CountForBuy: 1}})
+		pool.BuyGift(context.Background(), []*giftTypes.GiftRequire{{Gift: &tg.StarGift{ID: 2}, CountForBuy: 1}})
+		pool.BuyGift(context.Background(), []*giftTypes.GiftRequire{{Gift: &tg.StarGift{ID: 3}, CountForBuy: 1}})
+
+		assert.Equal(t, map[int64]int64{1: 1, 3: 1}, first.units())
+		assert.Equal(t, map[int64]int64{2: 1}, second.units())
+	})
+
+	t.Run("рассылка всем получателям покупается одним аккаунтом", func(t *testing.T) {
+		first, second := &recordingBuyer{}, &recordingBuyer{}
+		pool := NewAccountPool(first, second)
+		broadcast := &giftTypes.GiftRequire{Gift: &tg.StarGift{ID: 1}, CountForBuy: 4, BroadcastToAllReceivers: true}
+
+		pool.BuyGift(context.Background(), []*giftTypes.GiftRequire{broadcast})
+
+		assert.Equal(t, []*giftTypes.GiftRequire{broadcast}, first.gifts)
+		assert.Empty(t, second.gifts)
+	})
+
+	t.Run("закрытие всех аккаунтов", func(t *testing.T) {
+		first, second := &recordingBuyer{}, &recordingBuyer{}
+
+		NewAccountPool(first, second).Close()
+
+		assert.True(t, first.closed)
+		assert.True(t, second.closed)
+	})
+}
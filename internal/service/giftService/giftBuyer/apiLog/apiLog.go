@@ -0,0 +1,100 @@
+// Package apiLog formats the Telegram payment requests and responses for verbose debug
+// logging. Sensitive fields such as access hashes, gift messages and verification URLs
+// are redacted so the output can be shared when investigating purchase failures.
+package apiLog
+
+import (
+	"fmt"
+
+	"github.com/gotd/td/tg"
+)
+
+// redacted replaces the value of a sensitive field
+const redacted = "<redacted>"
+
+// Logf logs a formatted debug message
+type Logf func(format string, args ...interface{})
+
+// Peer describes an input peer without its access hash.
+//
+// Parameters:
+//   - peer: input peer of an invoice
+//
+// Returns:
+//   - string: redacted peer description
+func Peer(peer tg.InputPeerClass) string {
+	switch p := peer.(type) {
+	case *tg.InputPeerSelf:
+		return "self"
+	case *tg.InputPeerUser:
+		return fmt.Sprintf("user{id=%d access_hash=%s}", p.UserID, redacted)
+	case *tg.InputPeerChannel:
+		return fmt.Sprintf("channel{id=%d access_hash=%s}", p.ChannelID, redacted)
+	case nil:
+		return "nil"
+	default:
+		return fmt.Sprintf("%T", peer)
+	}
+}
+
+// Invoice describes a star gift invoice without the peer access hash and the gift message.
+//
+// Parameters:
+//   - invoice: star gift invoice
+//
+// Returns:
+//   - string: redacted invoice description
+func Invoice(invoice *tg.InputInvoiceStarGift) string {
+	if invoice == nil {
+		return "nil"
+	}
+
+	message := ""
+	if invoice.Message.Text != "" {
+		message = redacted
+	}
+	return fmt.Sprintf("invoice{gift_id=%d peer=%s hide_name=%t message=%q}",
+		invoice.GiftID, Peer(invoice.Peer), invoice.HideName, message)
+}
+
+// PaymentForm describes a payment form response.
+//
+// Parameters:
+//   - form: payment form returned by Telegram
+//
+// Returns:
+//   - string: payment form description
+func PaymentForm(form tg.PaymentsPaymentFormClass) string {
+	switch f := form.(type) {
+	case *tg.PaymentsPaymentFormStars:
+		return fmt.Sprintf("payment_form_stars{form_id=%d bot_id=%d}", f.FormID, f.BotID)
+	case *tg.PaymentsPaymentFormStarGift:
+		return fmt.Sprintf("payment_form_star_gift{form_id=%d}", f.FormID)
+	case *tg.PaymentsPaymentForm:
+		return fmt.Sprintf("payment_form{form_id=%d bot_id=%d}", f.FormID, f.BotID)
+	case nil:
+		return "nil"
+	default:
+		return fmt.Sprintf("%T", form)
+	}
+}
+
+// PaymentResult describes a payment result response without the verification URL.
+//
+// Parameters:
+//   - result: payment result returned by Telegram
+//
+// Returns:
+//   - string: redacted payment result description
+func PaymentResult(result tg.PaymentsPaymentResultClass) string {
+	switch r := result.(type) {
+	case *tg.PaymentsPaymentResult:
+		return fmt.Sprintf("payment_result{updates=%T}", r.Updates)
+	case *tg.PaymentsPaymentVerificationNeeded:
+		return fmt.Sprintf("payment_verification_needed{url=%s}", redacted)
+	case nil:
+		return "nil"
+	default:
+		return fmt.Sprintf("%T", result)
+	}
+}
@@ -0,0 +1,69 @@
+package atomicCounter
+
+import (
+	"sync"
+)
+
+// capCounter counts reserved units by key and refuses reservations beyond a cap.
+// One instance is shared by the buyers of all accounts, so a cap holds for the whole
+// service instead of for every account on its own.
+type capCounter struct {
+	// counts stores the reserved units of every key
+	counts map[string]int64
+	mu     sync.Mutex
+}
+
+// NewCapCounter creates an empty cap counter.
+//
+// Returns:
+//   - *capCounter: initialized counter instance
+func NewCapCounter() *capCounter {
+	return &capCounter{counts: make(map[string]int64)}
+}
+
+// TryReserve reserves one unit of the key if fewer than max units are reserved.
+// This operation is thread-safe.
+//
+// Parameters:
+//   - key: identifies the capped units, e.g. a gift ID
+//   - max: maximum number of units of the key
+//
+// Returns:
+//   - bool: true if the unit was reserved, false if the cap is reached
+func (cc *capCounter) TryReserve(key string, max int64) bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.counts[key] >= max {
+		return false
+	}
+	cc.counts[key]++
+	return true
+}
+
+// Release gives back a unit reserved with TryReserve whose purchase did not happen.
+// This operation is thread-safe.
+//
+// Parameters:
+//   - key: identifies the capped units
+func (cc *capCounter) Release(key string) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.counts[key] > 0 {
+		cc.counts[key]--
+	}
+}
+
+// Get returns the number of reserved units of the key.
+//
+// Parameters:
+//   - key: identifies the capped units
+//
+// Returns:
+//   - int64: reserved units
+func (cc *capCounter) Get(key string) int64 {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.counts[key]
+}
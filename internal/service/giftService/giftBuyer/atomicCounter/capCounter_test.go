@@ -0,0 +1,54 @@
+package atomicCounter
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapCounter(t *testing.T) {
+	t.Run("резервирование до лимита", func(t *testing.T) {
+		counter := NewCapCounter()
+
+		assert.True(t, counter.TryReserve("1", 2))
+		assert.True(t, counter.TryReserve("1", 2))
+		assert.False(t, counter.TryReserve("1", 2))
+		assert.True(t, counter.TryReserve("2", 2), "keys are capped independently")
+
+		counter.Release("1")
+		assert.True(t, counter.TryReserve("1", 2))
+		assert.Equal(t, int64(2), counter.Get("1"))
+	})
+
+	t.Run("освобождение без резерва", func(t *testing.T) {
+		counter := NewCapCounter()
+
+		counter.Release("1")
+
+		assert.Equal(t, int64(0), counter.Get("1"))
+	})
+
+	t.Run("конкурентное резервирование", func(t *testing.T) {
+		counter := NewCapCounter()
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			reserved int
+		)
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if counter.TryReserve("1", 10) {
+					mu.Lock()
+					reserved++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 10, reserved)
+	})
+}
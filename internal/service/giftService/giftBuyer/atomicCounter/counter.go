@@ -12,7 +12,7 @@ type atomicCounter struct {
 	// count stores the current count value using atomic operations
 	count int64
 
-	// max defines the maximum allowed count value
+	// max defines the maximum allowed count value, accessed atomically so it can be changed at runtime
 	max int64
 }
 
@@ -30,6 +30,22 @@ func NewAtomicCounter(max int64) *atomicCounter {
 	}
 }
 
+// NewAtomicCounterFrom creates a new atomic counter that starts from a previously
+// reached count, e.g. restored from the persisted session state.
+//
+// Parameters:
+//   - max: maximum count value allowed
+//   - count: initial count value
+//
+// Returns:
+//   - *atomicCounter: initialized counter instance
+func NewAtomicCounterFrom(max, count int64) *atomicCounter {
+	return &atomicCounter{
+		count: count,
+		max:   max,
+	}
+}
+
 // TryIncrement attempts to increment the counter if it hasn't reached the maximum.
 // This operation is atomic and thread-safe, making it suitable for concurrent use.
 //
@@ -38,7 +54,7 @@ func NewAtomicCounter(max int64) *atomicCounter {
 func (ac *atomicCounter) TryIncrement() bool {
 	for {
 		current := atomic.LoadInt64(&ac.count)
-		if current >= ac.max {
+		if current >= atomic.LoadInt64(&ac.max) {
 			return false
 		}
 		if atomic.CompareAndSwapInt64(&ac.count, current, current+1) {
@@ -67,5 +83,15 @@ func (ac *atomicCounter) Get() int64 {
 // Returns:
 //   - int64: maximum count limit
 func (ac *atomicCounter) GetMax() int64 {
-	return ac.max
+	return atomic.LoadInt64(&ac.max)
+}
+
+// SetMax changes the maximum allowed count value, e.g. after a configuration reload.
+// Purchases already counted are kept; lowering the maximum below the current count
+// only blocks further increments.
+//
+// Parameters:
+//   - max: new maximum count limit
+func (ac *atomicCounter) SetMax(max int64) {
+	atomic.StoreInt64(&ac.max, max)
 }
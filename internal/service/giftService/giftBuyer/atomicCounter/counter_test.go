@@ -300,3 +300,27 @@ func BenchmarkAtomicCounter_Get(b *testing.B) {
 		}
 	})
 }
+
+func TestAtomicCounter_SetMax(t *testing.T) {
+	t.Run("увеличение максимума", func(t *testing.T) {
+		counter := NewAtomicCounter(1)
+		assert.True(t, counter.TryIncrement())
+		assert.False(t, counter.TryIncrement())
+
+		counter.SetMax(3)
+
+		assert.Equal(t, int64(3), counter.GetMax())
+		assert.True(t, counter.TryIncrement())
+		assert.True(t, counter.TryIncrement())
+		assert.False(t, counter.TryIncrement())
+	})
+
+	t.Run("уменьшение максимума ниже текущего значения", func(t *testing.T) {
+		counter := NewAtomicCounterFrom(10, 5)
+
+		counter.SetMax(2)
+
+		assert.Equal(t, int64(5), counter.Get())
+		assert.False(t, counter.TryIncrement())
+	})
+}
@@ -0,0 +1,116 @@
+// Package attemptPacer provides a global cap on purchase attempts per second.
+package attemptPacer
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// attemptPacerImpl spaces purchase attempts evenly so that no more than the configured
+// number of attempts per second is dispatched. Unlike the token bucket rate limiter it
+// never allows bursts: consecutive attempts are always at least one interval apart.
+type attemptPacerImpl struct {
+	// interval is the minimal time between two consecutive attempts
+	interval time.Duration
+
+	// next is the earliest time the next attempt may be dispatched
+	next time.Time
+
+	// released are the slots given back by cancelled waiters before next, reused by
+	// the following callers
+	released []time.Time
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewAttemptPacer creates a pacer that allows at most perSecond attempts per second.
+//
+// Parameters:
+//   - perSecond: maximum number of attempts per second (0 or less disables pacing)
+//
+// Returns:
+//   - *attemptPacerImpl: initialized pacer instance
+func NewAttemptPacer(perSecond int) *attemptPacerImpl {
+	var interval time.Duration
+	if perSecond > 0 {
+		interval = time.Second / time.Duration(perSecond)
+	}
+
+	return &attemptPacerImpl{
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Wait blocks until the next attempt slot is available. Slots are reserved in call
+// order, so concurrent callers are dispatched one interval apart. A caller cancelled
+// while waiting gives its slot back to the following callers.
+//
+// Parameters:
+//   - ctx: context for cancellation while waiting
+//
+// Returns:
+//   - error: context error if cancelled before the slot
+func (p *attemptPacerImpl) Wait(ctx context.Context) error {
+	if p.interval <= 0 {
+		return ctx.Err()
+	}
+
+	p.mu.Lock()
+	now := p.now()
+	slot := p.reserveLocked(now)
+	p.mu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		p.release(slot)
+		return ctx.Err()
+	}
+}
+
+// reserveLocked takes the earliest released slot that hasn't passed yet, or the next
+// slot. The caller must hold mu.
+func (p *attemptPacerImpl) reserveLocked(now time.Time) time.Time {
+	for len(p.released) > 0 {
+		slot := p.released[0]
+		p.released = p.released[1:]
+		if !slot.Before(now) {
+			return slot
+		}
+	}
+
+	slot := p.next
+	if slot.Before(now) {
+		slot = now
+	}
+	p.next = slot.Add(p.interval)
+	return slot
+}
+
+// release gives the slot of a cancelled waiter back. The last reserved slot moves next
+// back, an earlier one is kept for reuse so that the later waiters keep their slots.
+func (p *attemptPacerImpl) release(slot time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next.Equal(slot.Add(p.interval)) {
+		p.next = slot
+		return
+	}
+
+	i, _ := slices.BinarySearchFunc(p.released, slot, time.Time.Compare)
+	p.released = slices.Insert(p.released, i, slot)
+}
@@ -0,0 +1,137 @@
+package attemptPacer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttemptPacer_DispatchRateUnderCap(t *testing.T) {
+	const perSecond = 20
+	pacer := NewAttemptPacer(perSecond)
+
+	var (
+		mu    sync.Mutex
+		times []time.Time
+		wg    sync.WaitGroup
+	)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, pacer.Wait(context.Background()))
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// 10 attempts at 20 per second need at least 9 intervals of 50ms
+	assert.GreaterOrEqual(t, time.Since(start), 9*pacer.interval)
+
+	// Any window of one second never holds more attempts than the cap
+	for i := range times {
+		inWindow := 0
+		for j := range times {
+			if !times[j].Before(times[i]) && times[j].Sub(times[i]) < time.Second {
+				inWindow++
+			}
+		}
+		assert.LessOrEqual(t, inWindow, perSecond)
+	}
+}
+
+func TestAttemptPacer_Disabled(t *testing.T) {
+	pacer := NewAttemptPacer(0)
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, pacer.Wait(context.Background()))
+	}
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestAttemptPacer_ContextCancelled(t *testing.T) {
+	pacer := NewAttemptPacer(1)
+	require.NoError(t, pacer.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := pacer.Wait(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestAttemptPacer_IdleDoesNotAccumulateBurst(t *testing.T) {
+	pacer := NewAttemptPacer(10)
+	require.NoError(t, pacer.Wait(context.Background()))
+
+	time.Sleep(300 * time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, pacer.Wait(context.Background()))
+	}
+
+	// The first attempt after idling is immediate, the next ones are still spaced
+	assert.GreaterOrEqual(t, time.Since(start), 2*pacer.interval)
+}
+
+func TestAttemptPacer_CancelledSlotReleased(t *testing.T) {
+	now := time.Unix(1000, 0)
+	newPacer := func() *attemptPacerImpl {
+		pacer := NewAttemptPacer(1)
+		pacer.now = func() time.Time { return now }
+		return pacer
+	}
+	cancelled := func() context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx
+	}
+
+	t.Run("последний слот возвращается", func(t *testing.T) {
+		pacer := newPacer()
+		require.NoError(t, pacer.Wait(context.Background()))
+
+		assert.ErrorIs(t, pacer.Wait(cancelled()), context.Canceled)
+
+		assert.Equal(t, now.Add(time.Second), pacer.next)
+		assert.Empty(t, pacer.released)
+	})
+
+	t.Run("промежуточный слот переиспользуется", func(t *testing.T) {
+		pacer := newPacer()
+		require.NoError(t, pacer.Wait(context.Background()))
+		first := pacer.reserveLocked(now)
+		second := pacer.reserveLocked(now)
+
+		pacer.release(first)
+
+		assert.Equal(t, second.Add(time.Second), pacer.next, "later waiters keep their slots")
+		assert.Equal(t, first, pacer.reserveLocked(now))
+		assert.Equal(t, second.Add(time.Second), pacer.reserveLocked(now))
+	})
+
+	t.Run("прошедший слот не переиспользуется", func(t *testing.T) {
+		pacer := newPacer()
+		require.NoError(t, pacer.Wait(context.Background()))
+		first := pacer.reserveLocked(now)
+		second := pacer.reserveLocked(now)
+		pacer.release(first)
+
+		later := first.Add(time.Millisecond)
+		assert.Equal(t, second.Add(time.Second), pacer.reserveLocked(later))
+		assert.Empty(t, pacer.released)
+	})
+}
@@ -5,14 +5,26 @@ package giftBuyer
 
 import (
 	"context"
+	"fmt"
+	"gift-buyer/internal/config"
+	"gift-buyer/internal/service/giftService/giftBuyer/atomicCounter"
 	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/internal/service/giftService/giftTypes"
 	"gift-buyer/pkg/errors"
+	"gift-buyer/pkg/logger"
+	"gift-buyer/pkg/metrics"
+	"gift-buyer/pkg/tracing"
+	"gift-buyer/pkg/utils"
+	"math/rand"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gotd/td/tg"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // GiftBuyerImpl implements the GiftBuyer interface for purchasing Telegram star gifts.
@@ -46,6 +58,10 @@ type giftBuyerImpl struct {
 	// counter tracks and limits the total number of purchases
 	counter giftInterfaces.Counter
 
+	// giftCounts tracks the units bought of every gift ID to enforce GiftRequire.MaxPerGift,
+	// shared by the buyers of all accounts
+	giftCounts giftInterfaces.CapCounter
+
 	retryCount, concurrentGifts, concurrentOperations int
 	retryDelay                                        float64
 	// requestCounter provides unique identifiers for requests to avoid FormID duplicates
@@ -54,8 +70,67 @@ type giftBuyerImpl struct {
 
 	purchaseProcessor giftInterfaces.PurchaseProcessor
 	monitorProcessor  giftInterfaces.MonitorProcessor
+
+	// sessionState records successful purchases across restarts (optional)
+	sessionState giftInterfaces.SessionState
+
+	// batchSem bounds the number of discovery batches bought at the same time.
+	// Batches over the limit wait for a free slot; nil means no limit
+	batchSem chan struct{}
+
+	// shuffleRand shuffles gifts of equal priority when set; nil keeps the sort order
+	shuffleRand *rand.Rand
+	shuffleMu   sync.Mutex
+
+	// priorityByCriteriaOrder buys gifts of earlier criteria first regardless of price
+	priorityByCriteriaOrder bool
+
+	// priorityStrategy is the config.Priority* order of prioritized purchases
+	priorityStrategy string
+
+	// notifyOnLimitReached sends a notification the first time the purchase count cap is hit
+	notifyOnLimitReached bool
+	limitAnnounced       atomic.Bool
+
+	// attemptPacer caps purchase attempts per second across the whole buyer (optional)
+	attemptPacer giftInterfaces.AttemptPacer
+
+	// minAvailabilityAtBuy is the minimum remaining supply of a limited gift re-checked
+	// before every purchase attempt (0 to disable)
+	minAvailabilityAtBuy int
+
+	// revalidator checks the live gift against the criteria again before every purchase
+	// attempt (optional)
+	revalidator giftInterfaces.GiftValidator
+
+	// dryRun simulates purchases instead of spending stars
+	dryRun bool
+
+	// balanceCache skips purchases the cached stars balance can't cover (optional)
+	balanceCache giftInterfaces.BalanceCache
+
+	// batchTimeout bounds the wall-clock time of a purchase batch (0 to disable)
+	batchTimeout time.Duration
+
+	// purchaseSpacing delays launching each next purchase of the same gift (0 to disable)
+	purchaseSpacing time.Duration
+
+	// launchJitter is the maximum random delay before a gift of a batch starts buying (0 to disable)
+	launchJitter time.Duration
+
+	// drainOnShutdown lets the attempts in flight finish when the context is cancelled
+	drainOnShutdown bool
+
+	// drainTimeout bounds how long an attempt may keep running after the cancellation
+	drainTimeout time.Duration
+
+	// inflight tracks the running batches, including the summary of each batch
+	inflight sync.WaitGroup
 }
 
+// defaultDrainTimeout matches the shutdown timeout of the application
+const defaultDrainTimeout = 30 * time.Second
+
 // NewGiftBuyer creates a new GiftBuyer instance with the specified configuration.
 // It initializes the buyer with API client, recipient information, and purchase limits.
 //
@@ -68,6 +143,8 @@ type giftBuyerImpl struct {
 //   - maxBuyCount: maximum number of gifts that can be purchased
 //   - concurrentGifts: maximum number of concurrent gift purchases
 //   - concurrentOperations: maximum number of concurrent operations
+//   - maxConcurrentBatches: maximum number of discovery batches bought at the same time (0 for unlimited)
+//   - dryRun: simulate purchases without calling the purchase processor
 //
 // Returns:
 //   - giftInterfaces.GiftBuyer: configured gift buyer instance
@@ -90,7 +167,14 @@ func NewGiftBuyer(
 	monitorProcessor giftInterfaces.MonitorProcessor,
 	counter giftInterfaces.Counter,
 	errorLogsWriter giftInterfaces.ErrorLogger,
+	maxConcurrentBatches int,
+	dryRun bool,
 ) *giftBuyerImpl {
+	var batchSem chan struct{}
+	if maxConcurrentBatches > 0 {
+		batchSem = make(chan struct{}, maxConcurrentBatches)
+	}
+
 	return &giftBuyerImpl{
 		api:                  api,
 		userReceiver:         userIds,
@@ -110,6 +194,10 @@ func NewGiftBuyer(
 		purchaseProcessor:    purchaseProcessor,
 		monitorProcessor:     monitorProcessor,
 		errorLogsWriter:      errorLogsWriter,
+		batchSem:             batchSem,
+		dryRun:               dryRun,
+		drainTimeout:         defaultDrainTimeout,
+		giftCounts:           atomicCounter.NewCapCounter(),
 	}
 }
 
@@ -123,6 +211,9 @@ func NewGiftBuyer(
 //  4. Collects results and sends status notifications
 //  5. Returns success or aggregated error information
 //
+// When a batch limit is configured, BuyGift first waits for a free batch slot,
+// so batches discovered while others are still buying are queued.
+//
 // Parameters:
 //   - ctx: context for request cancellation and timeout control
 //   - gifts: map of gifts to their desired purchase quantities
@@ -136,42 +227,343 @@ func (gm *giftBuyerImpl) BuyGift(ctx context.Context, gifts []*giftTypes.GiftReq
 		resultsCh = make(chan giftTypes.GiftResult)
 		doneCh    = make(chan struct{})
 	)
-	go gm.monitorProcessor.MonitorProcess(ctx, resultsCh, doneCh, gifts)
+
+	if !gm.acquireBatch(ctx) {
+		return
+	}
+
+	if gm.balanceCache != nil {
+		if err := gm.balanceCache.RefreshBalance(ctx); err != nil {
+			gm.errorLogsWriter.LogError(fmt.Sprintf("%v, using the cached balance of %d stars", err, gm.balanceCache.GetBalance()))
+		}
+	}
+
+	gifts = splitReceiverTypes(gifts)
+	gm.expandBroadcast(gifts)
+	gm.resolveMaxAffordable(gifts)
+
+	// The summary is driven by the parent context so it is still sent when the batch times out.
+	// When draining it outlives the cancellation too and is sent once the last attempt ends.
+	monitorCtx := ctx
+	if gm.drainOnShutdown {
+		monitorCtx = context.WithoutCancel(ctx)
+	}
+	gm.inflight.Add(2)
+	go func() {
+		defer gm.inflight.Done()
+		gm.monitorProcessor.MonitorProcess(monitorCtx, resultsCh, doneCh, gifts)
+	}()
+
+	batchCtx, cancelBatch := gm.batchContext(ctx)
 
 	if gm.prioritization {
-		gm.prioritizationBuy(ctx, gifts, resultsCh)
+		gm.prioritizationBuy(batchCtx, gifts, resultsCh)
 	} else {
 		for _, require := range gifts {
 			wg.Add(1)
 			go func(gift *giftTypes.GiftRequire) {
 				defer wg.Done()
+				gm.waitLaunchJitter(batchCtx)
 				sem <- struct{}{}
 				defer func() { <-sem }()
 
-				gm.buyGift(ctx, gift, resultsCh)
+				gm.buyGift(batchCtx, gift, resultsCh)
 			}(require)
 		}
 	}
 
 	go func() {
 		wg.Wait()
+		if errors.Is(context.Cause(batchCtx), errors.ErrBatchTimeout) {
+			gm.errorLogsWriter.LogError(fmt.Sprintf("purchase batch timed out after %s, remaining attempts aborted", gm.batchTimeout))
+		}
+		cancelBatch()
+		gm.releaseBatch()
 		close(doneCh)
+		gm.inflight.Done()
 	}()
 }
 
+// purchaseGift runs one purchase attempt through the purchase processor inside a trace span,
+// logging through the attempt logger carried by the context.
+func (gm *giftBuyerImpl) purchaseGift(ctx context.Context, gift *giftTypes.GiftRequire, attempt int, requestID string) error {
+	log := logger.FromContext(ctx)
+
+	start := time.Now()
+	ctx, span := tracing.StartSpan(ctx, "purchase.attempt",
+		tracing.AttrGiftID.Int64(gift.Gift.ID),
+		attribute.Int("attempt", attempt),
+		attribute.String(logger.RequestIDField, requestID),
+	)
+	log.Debugf("purchase attempt started")
+	err := gm.purchaseProcessor.PurchaseGift(ctx, gift)
+	tracing.Finish(span, start, "", err)
+	if err != nil {
+		log.Warnf("purchase attempt failed after %s: %v", time.Since(start), err)
+	} else {
+		log.Debugf("purchase attempt succeeded after %s", time.Since(start))
+	}
+	return err
+}
+
+// newRequestID generates the ID that correlates the log lines of one purchase attempt.
+func newRequestID() string {
+	return strconv.FormatInt(utils.CryptoRandomInt63(), 36)
+}
+
+// batchContext derives the context of a purchase batch. With a batch timeout the
+// context is cancelled with ErrBatchTimeout once the timeout elapses.
+func (gm *giftBuyerImpl) batchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if gm.batchTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, gm.batchTimeout, errors.ErrBatchTimeout)
+}
+
+// splitReceiverTypes replaces every gift that is bought for all receiver types with one
+// gift per receiver type, so the purchases for each type run concurrently with their
+// own CountForBuy. Other gifts are kept as is.
+//
+// Parameters:
+//   - gifts: gifts of the batch
+//
+// Returns:
+//   - []*giftTypes.GiftRequire: gifts with one entry per receiver type where requested
+func splitReceiverTypes(gifts []*giftTypes.GiftRequire) []*giftTypes.GiftRequire {
+	result := make([]*giftTypes.GiftRequire, 0, len(gifts))
+	for _, gift := range gifts {
+		if !gift.BuyForAllReceiverTypes || len(gift.ReceiverType) < 2 {
+			result = append(result, gift)
+			continue
+		}
+
+		seen := make(map[int]bool, len(gift.ReceiverType))
+		for _, receiverType := range gift.ReceiverType {
+			if seen[receiverType] {
+				continue
+			}
+			seen[receiverType] = true
+
+			result = append(result, &giftTypes.GiftRequire{
+				Gift:                    gift.Gift,
+				ReceiverType:            []int{receiverType},
+				CountForBuy:             gift.CountForBuy,
+				Hide:                    gift.Hide,
+				Upgrade:                 gift.Upgrade,
+				CriteriaIndex:           gift.CriteriaIndex,
+				BroadcastToAllReceivers: gift.BroadcastToAllReceivers,
+				Stages:                  gift.Stages,
+				ReceiverDistribution:    gift.ReceiverDistribution,
+				MaxPerGift:              gift.MaxPerGift,
+				BuyMaxAffordable:        gift.BuyMaxAffordable,
+			})
+		}
+	}
+	return result
+}
+
+// receiverCounter is implemented by invoice creators that know the configured receivers
+type receiverCounter interface {
+	ReceiverCount(receiverTypes []int) int
+}
+
+// reservationReleaser is implemented by invoice creators capping the gifts per receiver
+type reservationReleaser interface {
+	ReleaseReservation(ctx context.Context)
+}
+
+// expandBroadcast sets the purchase count of broadcast gifts to the number of their
+// receivers, so that every receiver gets exactly one gift. The units of a gift are
+// bought in parallel, one invoice per receiver.
+//
+// Parameters:
+//   - gifts: gifts of the batch to update in place
+func (gm *giftBuyerImpl) expandBroadcast(gifts []*giftTypes.GiftRequire) {
+	counter, ok := gm.invoiceCreator.(receiverCounter)
+	if !ok {
+		return
+	}
+
+	for _, gift := range gifts {
+		// Staged gifts keep the counts of their stages
+		if !gift.BroadcastToAllReceivers || len(gift.Stages) > 0 {
+			continue
+		}
+		gift.CountForBuy = int64(counter.ReceiverCount(gift.ReceiverType))
+	}
+}
+
+// resolveMaxAffordable sets the purchase count of gifts bought for the whole balance to
+// the number of units the cached stars balance covers, capped by the star budget of
+// the criteria and the purchases left under the global limit. Gifts are resolved in order, so the units given to one gift
+// are no longer available to the next. Without a balance cache nothing is bought.
+//
+// Parameters:
+//   - gifts: gifts of the batch to update in place
+func (gm *giftBuyerImpl) resolveMaxAffordable(gifts []*giftTypes.GiftRequire) {
+	balance := int64(0)
+	if gm.balanceCache != nil {
+		balance = gm.balanceCache.GetBalance()
+	}
+	left := gm.counter.GetMax() - gm.counter.Get()
+
+	for _, gift := range gifts {
+		if !gift.BuyMaxAffordable || len(gift.Stages) > 0 {
+			continue
+		}
+		if gm.balanceCache == nil {
+			gm.errorLogsWriter.LogError(fmt.Sprintf("gift %d: buying as many as the balance allows requires check_balance_before_buy, skipping", gift.Gift.ID))
+			gift.CountForBuy = 0
+			continue
+		}
+
+		count := left
+		if gift.Gift.Stars > 0 {
+			count = min(balance/gift.Gift.Stars, left)
+			if gift.StarBudget > 0 {
+				count = min(count, gift.StarBudget/gift.Gift.Stars)
+			}
+		}
+		gift.CountForBuy = max(count, 0)
+		balance -= gift.CountForBuy * gift.Gift.Stars
+		left -= gift.CountForBuy
+	}
+}
+
+// acquireBatch waits for a free batch slot if the number of concurrent batches is limited.
+//
+// Returns:
+//   - bool: true if the batch may proceed, false if the context was cancelled while waiting
+func (gm *giftBuyerImpl) acquireBatch(ctx context.Context) bool {
+	if gm.batchSem == nil {
+		return true
+	}
+
+	select {
+	case gm.batchSem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// releaseBatch frees the batch slot taken by acquireBatch.
+func (gm *giftBuyerImpl) releaseBatch() {
+	if gm.batchSem != nil {
+		<-gm.batchSem
+	}
+}
+
 func (gm *giftBuyerImpl) prioritizationBuy(ctx context.Context, gifts []*giftTypes.GiftRequire, resChan chan<- giftTypes.GiftResult) {
-	sort.Slice(gifts, func(i, j int) bool {
-		return gifts[i].Gift.Stars > gifts[j].Gift.Stars
-	})
+	gm.sortByPriority(gifts)
+	base := slotOffset(ctx)
 
 	for _, gift := range gifts {
+		if len(gift.Stages) > 0 {
+			gm.buyStages(ctx, gift, resChan)
+			continue
+		}
 		for i := int64(0); i < gift.CountForBuy; i++ {
-			gm.buyGiftWithRetry(ctx, gift, resChan)
+			gm.buyGiftWithRetry(giftTypes.WithReceiverSlot(ctx, base+i), gift, resChan)
 		}
 	}
 
 }
 
+// slotOffset returns the receiver slot bound to the context by the caller, 0 if none.
+// The purchase scheduler dispatches the units of a batch one at a time and binds the
+// position of each unit, so the slots of the dispatched batch start at that position.
+func slotOffset(ctx context.Context) int64 {
+	slot, _ := giftTypes.ReceiverSlot(ctx)
+	return slot
+}
+
+// sortByPriority orders gifts by the priority strategy, from the most to the least
+// expensive by default. With criteria order priority, gifts matched by earlier criteria
+// go first and the strategy only breaks ties. When shuffling is enabled, gifts with the
+// same priority are put in random order so that purchases don't follow a predictable
+// pattern, while the order between tiers is kept.
+//
+// Parameters:
+//   - gifts: gifts to order in place
+func (gm *giftBuyerImpl) sortByPriority(gifts []*giftTypes.GiftRequire) {
+	if gm.shuffleRand != nil {
+		gm.shuffleMu.Lock()
+		gm.shuffleRand.Shuffle(len(gifts), func(i, j int) {
+			gifts[i], gifts[j] = gifts[j], gifts[i]
+		})
+		gm.shuffleMu.Unlock()
+	}
+
+	sortGifts(gifts, gm.priorityStrategy, gm.priorityByCriteriaOrder)
+}
+
+// sortGifts stably orders gifts with the comparator of the priority strategy, so gifts
+// the strategy considers equal keep their current order.
+//
+// Parameters:
+//   - gifts: gifts to order in place
+//   - strategy: one of the config.Priority* strategies, unknown ones sort by stars descending
+//   - byCriteriaOrder: put gifts of earlier criteria first and use the strategy only for ties
+func sortGifts(gifts []*giftTypes.GiftRequire, strategy string, byCriteriaOrder bool) {
+	higher := priorityComparator(strategy)
+	sort.SliceStable(gifts, func(i, j int) bool {
+		if byCriteriaOrder && gifts[i].CriteriaIndex != gifts[j].CriteriaIndex {
+			return gifts[i].CriteriaIndex < gifts[j].CriteriaIndex
+		}
+		return higher(gifts[i], gifts[j])
+	})
+}
+
+// priorityComparator returns the function reporting whether gift a is bought before gift b.
+func priorityComparator(strategy string) func(a, b *giftTypes.GiftRequire) bool {
+	switch strategy {
+	case config.PriorityStarsAsc:
+		return func(a, b *giftTypes.GiftRequire) bool {
+			return a.Gift.Stars < b.Gift.Stars
+		}
+	case config.PriorityRarestFirst:
+		return func(a, b *giftTypes.GiftRequire) bool {
+			// Unlimited gifts have no remaining supply and go after the limited ones
+			aRemains, aLimited := a.Gift.GetAvailabilityRemains()
+			bRemains, bLimited := b.Gift.GetAvailabilityRemains()
+			if aLimited != bLimited {
+				return aLimited
+			}
+			return aRemains < bRemains
+		}
+	case config.PriorityDiscovery:
+		return func(a, b *giftTypes.GiftRequire) bool {
+			return false
+		}
+	default:
+		return func(a, b *giftTypes.GiftRequire) bool {
+			return a.Gift.Stars > b.Gift.Stars
+		}
+	}
+}
+
+// SetPriorityByCriteriaOrder makes gifts matched by earlier criteria be bought first regardless of price.
+func (gm *giftBuyerImpl) SetPriorityByCriteriaOrder(enabled bool) {
+	gm.priorityByCriteriaOrder = enabled
+}
+
+// SetPriorityStrategy sets the order of prioritized purchases, one of the config.Priority*
+// strategies. An empty or unknown strategy buys the most expensive gifts first.
+func (gm *giftBuyerImpl) SetPriorityStrategy(strategy string) {
+	gm.priorityStrategy = strategy
+}
+
+// SetShuffleEqualPriority enables random ordering of gifts with equal priority
+// using the given randomness source. A nil source disables shuffling.
+func (gm *giftBuyerImpl) SetShuffleEqualPriority(source rand.Source) {
+	if source == nil {
+		gm.shuffleRand = nil
+		return
+	}
+	gm.shuffleRand = rand.New(source)
+}
+
 // buyGift attempts to purchase a specific gift multiple times with retry logic.
 // It handles individual gift purchases, manages the purchase counter, and implements
 // asynchronous retry logic where each attempt is a separate goroutine.
@@ -185,25 +577,92 @@ func (gm *giftBuyerImpl) prioritizationBuy(ctx context.Context, gifts []*giftTyp
 //   - int64: number of successful purchases completed
 //   - error: purchase error after all retry attempts exhausted
 func (gm *giftBuyerImpl) buyGift(ctx context.Context, gift *giftTypes.GiftRequire, resChan chan<- giftTypes.GiftResult) {
+	if len(gift.Stages) > 0 {
+		gm.buyStages(ctx, gift, resChan)
+		return
+	}
+	gm.buyUnits(ctx, gift, 0, gift.CountForBuy, resChan)
+}
+
+// buyUnits purchases count units of the gift concurrently and waits for all of them.
+// With a purchase spacing the units are launched one spacing apart. Every unit is bound
+// to its receiver slot, starting at first, so that its retries keep the same receiver.
+func (gm *giftBuyerImpl) buyUnits(ctx context.Context, gift *giftTypes.GiftRequire, first, count int64, resChan chan<- giftTypes.GiftResult) {
 	var (
 		wg  sync.WaitGroup
 		sem = make(chan struct{}, gm.concurrentOperations)
 	)
+	first += slotOffset(ctx)
 
-	for i := int64(0); i < gift.CountForBuy; i++ {
+	for i := int64(0); i < count; i++ {
+		if i > 0 {
+			gm.waitPurchaseSpacing(ctx)
+		}
 		wg.Add(1)
+		unitCtx := giftTypes.WithReceiverSlot(ctx, first+i)
 		go func() {
 			defer wg.Done()
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			gm.buyGiftWithRetry(ctx, gift, resChan)
+			gm.buyGiftWithRetry(unitCtx, gift, resChan)
 		}()
 	}
 
 	wg.Wait()
 }
 
+// buyStages purchases a staged gift stage by stage. A stage that requires the previous
+// stage to succeed is skipped, together with all later stages, once a unit of the previous
+// stage wasn't bought. The units of skipped stages are reported as failed so that the
+// batch summary still covers every requested unit.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - gift: the staged gift to purchase
+//   - resChan: channel receiving the result of every unit
+func (gm *giftBuyerImpl) buyStages(ctx context.Context, gift *giftTypes.GiftRequire, resChan chan<- giftTypes.GiftResult) {
+	var first int64
+	previousSucceeded := true
+	for index, stage := range gift.Stages {
+		if index > 0 && stage.RequirePreviousSuccess && !previousSucceeded {
+			gm.errorLogsWriter.LogError(fmt.Sprintf("gift %d: stage %d skipped, previous stage did not succeed", gift.Gift.ID, index+1))
+			for _, skipped := range gift.Stages[index:] {
+				for i := int64(0); i < skipped.Count; i++ {
+					resChan <- giftTypes.GiftResult{
+						GiftID:  gift.Gift.ID,
+						Success: false,
+						Err:     errors.New("previous purchase stage did not succeed"),
+					}
+				}
+			}
+			return
+		}
+
+		var (
+			succeeded int64
+			stageCh   = make(chan giftTypes.GiftResult)
+			forwarded = make(chan struct{})
+		)
+		go func() {
+			defer close(forwarded)
+			for result := range stageCh {
+				if result.Success {
+					succeeded++
+				}
+				resChan <- result
+			}
+		}()
+
+		gm.buyUnits(ctx, gift, first, stage.Count, stageCh)
+		close(stageCh)
+		<-forwarded
+		first += stage.Count
+
+		previousSucceeded = succeeded == stage.Count
+	}
+}
+
 func (gm *giftBuyerImpl) buyGiftWithRetry(ctx context.Context, gift *giftTypes.GiftRequire, resChan chan<- giftTypes.GiftResult) {
 	var lastErr error
 
@@ -213,13 +672,77 @@ func (gm *giftBuyerImpl) buyGiftWithRetry(ctx context.Context, gift *giftTypes.G
 			resChan <- giftTypes.GiftResult{
 				GiftID:  gift.Gift.ID,
 				Success: false,
-				Err:     ctx.Err(),
+				Err:     context.Cause(ctx),
 			}
 			return
 		default:
 		}
 
+		if atomic.LoadInt32(&gift.ReceiversSaturated) == 1 {
+			resChan <- giftTypes.GiftResult{
+				GiftID:  gift.Gift.ID,
+				Success: false,
+				Err:     errors.ErrAllReceiversSaturated,
+			}
+			return
+		}
+
+		if gm.attemptPacer != nil {
+			if err := gm.attemptPacer.Wait(ctx); err != nil {
+				resChan <- giftTypes.GiftResult{
+					GiftID:  gift.Gift.ID,
+					Success: false,
+					Err:     err,
+				}
+				return
+			}
+		}
+
+		if gm.balanceCache != nil {
+			balance := gm.balanceCache.GetBalance()
+			metrics.BalanceStars.Set(float64(balance))
+			if balance < gift.Gift.Stars {
+				resChan <- giftTypes.GiftResult{
+					GiftID:  gift.Gift.ID,
+					Success: false,
+					Err: errors.Wrap(errors.ErrInsufficientBalance,
+						fmt.Sprintf("gift %d costs %d stars, only %d left", gift.Gift.ID, gift.Gift.Stars, balance)),
+				}
+				return
+			}
+		}
+
+		live := &liveGift{manager: gm.manager, giftID: gift.Gift.ID}
+		if !gm.availableAtBuy(ctx, gift, live) {
+			resChan <- giftTypes.GiftResult{
+				GiftID:  gift.Gift.ID,
+				Success: false,
+				Err:     errors.ErrAvailabilityBelowThreshold,
+			}
+			return
+		}
+
+		if !gm.eligibleAtBuy(ctx, gift, live) {
+			resChan <- giftTypes.GiftResult{
+				GiftID:  gift.Gift.ID,
+				Success: false,
+				Err:     errors.ErrNoLongerEligible,
+			}
+			return
+		}
+
+		if !gm.reserveGiftUnit(gift) {
+			resChan <- giftTypes.GiftResult{
+				GiftID:  gift.Gift.ID,
+				Success: false,
+				Err:     errors.ErrGiftCapReached,
+			}
+			return
+		}
+
 		if !gm.counter.TryIncrement() {
+			gm.releaseGiftUnit(gift)
+			gm.announceLimitReached(ctx)
 			lastErr = errors.New("max buy count reached")
 			resChan <- giftTypes.GiftResult{
 				GiftID:  gift.Gift.ID,
@@ -229,8 +752,82 @@ func (gm *giftBuyerImpl) buyGiftWithRetry(ctx context.Context, gift *giftTypes.G
 			return
 		}
 
-		if err := gm.purchaseProcessor.PurchaseGift(ctx, gift); err != nil {
+		if gm.dryRun {
+			// The purchase passed every check before payment, report it without spending stars
+			resChan <- giftTypes.GiftResult{
+				GiftID:    gift.Gift.ID,
+				Success:   true,
+				Simulated: true,
+			}
+			return
+		}
+
+		// Every log line of this attempt carries the same request ID
+		requestID := newRequestID()
+		attemptCtx, releaseAttempt := gm.attemptContext(ctx)
+		attemptCtx = giftTypes.WithReservation(attemptCtx)
+		attemptCtx = logger.NewContext(attemptCtx, logger.WithRequestID(requestID).WithFields(logger.Fields{
+			"gift_id": gift.Gift.ID,
+			"attempt": j + 1,
+		}))
+		err := gm.purchaseGift(attemptCtx, gift, j+1, requestID)
+		releaseAttempt()
+		if errors.Is(err, errors.ErrPurchaseUnconfirmed) {
+			// The payment went through, only its confirmation is missing. The unit stays
+			// counted and is never retried, which could buy the gift twice
+			gm.errorLogsWriter.LogError(fmt.Sprintf("gift %d: %v, check the purchase manually", gift.Gift.ID, err))
+			if gm.sessionState != nil {
+				gm.sessionState.RecordPurchase(gift.Gift.ID)
+			}
+			if gm.balanceCache != nil {
+				gm.balanceCache.TrimBalance(gift.Gift.Stars)
+			}
+			metrics.BuyFailures.Inc()
+			resChan <- giftTypes.GiftResult{
+				GiftID:  gift.Gift.ID,
+				Success: false,
+				Err:     err,
+			}
+			return
+		}
+		if err != nil {
 			gm.counter.Decrement()
+			gm.releaseGiftUnit(gift)
+			if releaser, ok := gm.invoiceCreator.(reservationReleaser); ok {
+				// Nothing was paid, the receiver may still get this gift
+				releaser.ReleaseReservation(attemptCtx)
+			}
+			metrics.BuyFailures.Inc()
+			if errors.Is(err, errors.ErrAllReceiversSaturated) {
+				// Retrying can't help: no receiver accepts another unit of this gift
+				if atomic.CompareAndSwapInt32(&gift.ReceiversSaturated, 0, 1) {
+					gm.errorLogsWriter.LogError(fmt.Sprintf("gift %d: all receivers saturated, skipping remaining units", gift.Gift.ID))
+				}
+				resChan <- giftTypes.GiftResult{
+					GiftID:  gift.Gift.ID,
+					Success: false,
+					Err:     errors.ErrAllReceiversSaturated,
+				}
+				return
+			}
+			if errors.Is(err, errors.ErrBalanceReserveReached) {
+				// Retrying can't help until the balance is topped up
+				resChan <- giftTypes.GiftResult{
+					GiftID:  gift.Gift.ID,
+					Success: false,
+					Err:     errors.ErrBalanceReserveReached,
+				}
+				return
+			}
+			if classifyPurchaseError(err) == purchaseErrorTerminal {
+				// Telegram rejected the purchase for a reason another attempt won't change
+				resChan <- giftTypes.GiftResult{
+					GiftID:  gift.Gift.ID,
+					Success: false,
+					Err:     err,
+				}
+				return
+			}
 			lastErr = err
 			resChan <- giftTypes.GiftResult{
 				GiftID:  gift.Gift.ID,
@@ -238,11 +835,24 @@ func (gm *giftBuyerImpl) buyGiftWithRetry(ctx context.Context, gift *giftTypes.G
 				Err:     err,
 			}
 			if j < gm.retryCount-1 {
-				time.Sleep(time.Duration(gm.retryDelay) * time.Second)
+				metrics.BuyRetries.Inc()
+				// A cancellation ends the delay early, the next iteration reports it
+				select {
+				case <-time.After(time.Duration(gm.retryDelay) * time.Second):
+				case <-ctx.Done():
+				}
 			}
 			continue
 		}
 
+		if gm.sessionState != nil {
+			gm.sessionState.RecordPurchase(gift.Gift.ID)
+		}
+		if gm.balanceCache != nil {
+			gm.balanceCache.TrimBalance(gift.Gift.Stars)
+			metrics.BalanceStars.Set(float64(gm.balanceCache.GetBalance()))
+		}
+		metrics.GiftsBought.Inc()
 		resChan <- giftTypes.GiftResult{
 			GiftID:  gift.Gift.ID,
 			Success: true,
@@ -258,6 +868,331 @@ func (gm *giftBuyerImpl) buyGiftWithRetry(ctx context.Context, gift *giftTypes.G
 	}
 }
 
+// purchaseErrorClass tells whether a failed purchase is worth retrying.
+type purchaseErrorClass int
+
+const (
+	// purchaseErrorRetryable errors may go away on the next attempt
+	purchaseErrorRetryable purchaseErrorClass = iota
+
+	// purchaseErrorTerminal errors fail every attempt of the same purchase
+	purchaseErrorTerminal
+)
+
+// terminalPurchaseErrors are the Telegram error codes that end the retries of a purchase.
+var terminalPurchaseErrors = []string{
+	"STARGIFT_USAGE_LIMITED", // the gift sold out
+	"BALANCE_TOO_LOW",        // not enough stars for the gift
+	"PEER_ID_INVALID",        // the receiver can't be resolved
+}
+
+// classifyPurchaseError classifies a purchase error by the Telegram error code in its
+// message, errors without a known terminal code are retryable.
+func classifyPurchaseError(err error) purchaseErrorClass {
+	if errors.Is(err, errors.ErrInsufficientBalance) || errors.Is(err, errors.ErrCircuitOpen) {
+		return purchaseErrorTerminal
+	}
+	message := err.Error()
+	for _, code := range terminalPurchaseErrors {
+		if strings.Contains(message, code) {
+			return purchaseErrorTerminal
+		}
+	}
+	return purchaseErrorRetryable
+}
+
+// reserveGiftUnit reserves one unit of the gift against its per-gift cap. Gifts without
+// a cap are always reserved.
+//
+// Returns:
+//   - bool: true if the unit was reserved, false if the gift's cap is reached
+func (gm *giftBuyerImpl) reserveGiftUnit(gift *giftTypes.GiftRequire) bool {
+	if gift.MaxPerGift <= 0 {
+		return true
+	}
+	return gm.giftCounts.TryReserve(strconv.FormatInt(gift.Gift.ID, 10), gift.MaxPerGift)
+}
+
+// releaseGiftUnit returns a unit reserved with reserveGiftUnit whose purchase did not happen.
+func (gm *giftBuyerImpl) releaseGiftUnit(gift *giftTypes.GiftRequire) {
+	if gift.MaxPerGift <= 0 {
+		return
+	}
+	gm.giftCounts.Release(strconv.FormatInt(gift.Gift.ID, 10))
+}
+
+// SetGiftCounts sets the per-gift cap counts shared with the buyers of the other accounts,
+// so that GiftRequire.MaxPerGift holds across all accounts.
+//
+// Parameters:
+//   - counts: shared per-gift counts
+func (gm *giftBuyerImpl) SetGiftCounts(counts giftInterfaces.CapCounter) {
+	gm.giftCounts = counts
+}
+
+// SetSessionState sets the persisted session state used to record successful purchases.
+func (gm *giftBuyerImpl) SetSessionState(state giftInterfaces.SessionState) {
+	gm.sessionState = state
+}
+
+// SetAttemptPacer sets the global cap on purchase attempts per second.
+func (gm *giftBuyerImpl) SetAttemptPacer(pacer giftInterfaces.AttemptPacer) {
+	gm.attemptPacer = pacer
+}
+
+// SetBalanceCache sets the cached stars balance checked before every purchase attempt.
+// The balance is refreshed from Telegram at the start of every batch.
+func (gm *giftBuyerImpl) SetBalanceCache(cache giftInterfaces.BalanceCache) {
+	gm.balanceCache = cache
+}
+
+// SetBatchTimeout sets the maximum wall-clock time of a purchase batch. When it elapses
+// the remaining attempts of the batch are aborted and the summary is sent, while the
+// monitoring keeps running.
+//
+// Parameters:
+//   - timeout: maximum batch duration, 0 to disable the timeout
+func (gm *giftBuyerImpl) SetBatchTimeout(timeout time.Duration) {
+	gm.batchTimeout = timeout
+}
+
+// SetPurchaseSpacing sets the delay between launching the purchases of the same gift.
+// Purchases of different gifts are not delayed relative to each other.
+//
+// Parameters:
+//   - spacing: delay between launches, 0 to launch all purchases at once
+func (gm *giftBuyerImpl) SetPurchaseSpacing(spacing time.Duration) {
+	gm.purchaseSpacing = spacing
+}
+
+// waitPurchaseSpacing waits the purchase spacing. It returns early once the context is
+// done, so the remaining purchases are launched right away and fail with the context error.
+func (gm *giftBuyerImpl) waitPurchaseSpacing(ctx context.Context) {
+	if gm.purchaseSpacing <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(gm.purchaseSpacing)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// SetLaunchJitter sets the maximum random delay before each gift of a batch starts buying,
+// so the gifts don't all contend for the rate limiter at the same moment. The gifts are
+// still bought concurrently.
+//
+// Parameters:
+//   - jitter: maximum launch delay, 0 to launch all gifts at once
+func (gm *giftBuyerImpl) SetLaunchJitter(jitter time.Duration) {
+	gm.launchJitter = jitter
+}
+
+// waitLaunchJitter waits a random delay up to the launch jitter, returning early once
+// the context is done.
+func (gm *giftBuyerImpl) waitLaunchJitter(ctx context.Context) {
+	if gm.launchJitter <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(gm.launchJitter) + 1)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// SetDrainOnShutdown enables the drain mode. When the context of a batch is cancelled,
+// e.g. on shutdown, the attempts already sent to the purchase processor are allowed to
+// finish instead of being cut off, no new attempts or retries are started and the batch
+// summary is still sent. Close waits for the drained batches.
+func (gm *giftBuyerImpl) SetDrainOnShutdown(enabled bool) {
+	gm.drainOnShutdown = enabled
+}
+
+// attemptContext returns the context of a single purchase attempt. In drain mode the
+// attempt ignores the cancellation of ctx for up to drainTimeout, so a payment in flight
+// completes and the purchase counter matches the purchases actually made.
+//
+// Returns:
+//   - context.Context: context of the attempt
+//   - func(): releases the context once the attempt is over
+func (gm *giftBuyerImpl) attemptContext(ctx context.Context) (context.Context, func()) {
+	if !gm.drainOnShutdown {
+		return ctx, func() {}
+	}
+
+	attemptCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(gm.drainTimeout, func() { cancel(context.Cause(ctx)) })
+	})
+	return attemptCtx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+// SetMinAvailabilityAtBuy sets the minimum remaining supply of a limited gift required
+// right before every purchase attempt.
+//
+// Parameters:
+//   - min: minimum remaining supply, 0 to disable the check
+func (gm *giftBuyerImpl) SetMinAvailabilityAtBuy(min int) {
+	gm.minAvailabilityAtBuy = min
+}
+
+// liveGift fetches the current data of a gift at most once per purchase attempt, so the
+// availability and criteria checks right before the purchase share one catalog request.
+type liveGift struct {
+	manager giftInterfaces.Giftmanager
+	giftID  int64
+
+	fetched bool
+	current *tg.StarGift
+	err     error
+}
+
+// get returns the current data of the gift, fetching the catalog on the first call.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//
+// Returns:
+//   - *tg.StarGift: current gift data, nil if the gift is no longer listed
+//   - error: catalog fetch error
+func (lg *liveGift) get(ctx context.Context) (*tg.StarGift, error) {
+	if lg.fetched {
+		return lg.current, lg.err
+	}
+	lg.fetched = true
+
+	gifts, err := lg.manager.GetAvailableGifts(ctx)
+	if err != nil {
+		lg.err = err
+		return nil, err
+	}
+	for _, current := range gifts {
+		if current.ID == lg.giftID {
+			lg.current = current
+			break
+		}
+	}
+	return lg.current, nil
+}
+
+// availableAtBuy re-checks the live availability of a limited gift through the manager.
+// A gift that is no longer listed is treated as unavailable. When the catalog can't be
+// fetched or the gift has no availability data the purchase goes ahead.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - gift: the gift about to be purchased
+//   - live: current gift data shared by the checks of this attempt
+//
+// Returns:
+//   - bool: true if the purchase may proceed
+func (gm *giftBuyerImpl) availableAtBuy(ctx context.Context, gift *giftTypes.GiftRequire, live *liveGift) bool {
+	if gm.minAvailabilityAtBuy <= 0 || !gift.Gift.Limited {
+		return true
+	}
+
+	current, err := live.get(ctx)
+	if err != nil {
+		gm.errorLogsWriter.LogError(fmt.Sprintf("gift %d: failed to re-check availability, buying anyway: %v", gift.Gift.ID, err))
+		return true
+	}
+	if current == nil {
+		gm.errorLogsWriter.LogError(fmt.Sprintf("gift %d: no longer available, skipping", gift.Gift.ID))
+		return false
+	}
+
+	remains, ok := current.GetAvailabilityRemains()
+	if !ok || remains >= gm.minAvailabilityAtBuy {
+		return true
+	}
+	gm.errorLogsWriter.LogError(fmt.Sprintf("gift %d: only %d left, below the minimum of %d, skipping", gift.Gift.ID, remains, gm.minAvailabilityAtBuy))
+	return false
+}
+
+// SetRevalidator enables checking the freshest gift data against the criteria right before
+// every purchase attempt, so gifts whose price or availability changed during a batch
+// are skipped.
+//
+// Parameters:
+//   - validator: validator of the purchase criteria, nil to disable the check
+func (gm *giftBuyerImpl) SetRevalidator(validator giftInterfaces.GiftValidator) {
+	gm.revalidator = validator
+}
+
+// eligibleAtBuy re-runs the criteria on the live gift data fetched through the manager.
+// A gift that is no longer listed doesn't qualify. When the catalog can't be fetched
+// the purchase goes ahead.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - gift: the gift about to be purchased
+//   - live: current gift data shared by the checks of this attempt
+//
+// Returns:
+//   - bool: true if the purchase may proceed
+func (gm *giftBuyerImpl) eligibleAtBuy(ctx context.Context, gift *giftTypes.GiftRequire, live *liveGift) bool {
+	if gm.revalidator == nil {
+		return true
+	}
+
+	current, err := live.get(ctx)
+	if err != nil {
+		gm.errorLogsWriter.LogError(fmt.Sprintf("gift %d: failed to revalidate, buying anyway: %v", gift.Gift.ID, err))
+		return true
+	}
+	if current == nil {
+		gm.errorLogsWriter.LogError(fmt.Sprintf("gift %d: no longer available, skipping", gift.Gift.ID))
+		return false
+	}
+
+	if _, ok := gm.revalidator.IsEligible(current); ok {
+		return true
+	}
+	gm.errorLogsWriter.LogError(fmt.Sprintf("gift %d: no longer matches the criteria, skipping", gift.Gift.ID))
+	return false
+}
+
+// SetNotifyOnLimitReached enables a notification the first time the purchase count cap is hit.
+func (gm *giftBuyerImpl) SetNotifyOnLimitReached(enabled bool) {
+	gm.notifyOnLimitReached = enabled
+}
+
+// announceLimitReached logs, and optionally notifies, that the purchase count cap was
+// reached. It fires only once until ResetLimitReached so that every following attempt
+// stays silent.
+func (gm *giftBuyerImpl) announceLimitReached(ctx context.Context) {
+	if !gm.limitAnnounced.CompareAndSwap(false, true) {
+		return
+	}
+
+	max := gm.counter.GetMax()
+	gm.errorLogsWriter.LogErrorf("max buy count reached: %d gifts bought, no more purchases will be made", max)
+
+	if !gm.notifyOnLimitReached || gm.notification == nil {
+		return
+	}
+	if err := gm.notification.SendLimitReachedNotification(ctx, "max buy count", max); err != nil {
+		gm.errorLogsWriter.LogError(err.Error())
+	}
+}
+
+// ResetLimitReached re-arms the limit announcement, so that a cap changed by a
+// configuration reload is announced again when it is reached.
+func (gm *giftBuyerImpl) ResetLimitReached() {
+	gm.limitAnnounced.Store(false)
+}
+
 func (gm *giftBuyerImpl) Close() {
+	if gm.drainOnShutdown {
+		gm.inflight.Wait()
+	}
 	gm.rateLimiter.Close()
 }
@@ -2,16 +2,28 @@ package giftBuyer
 
 import (
 	"context"
+	"fmt"
+	"gift-buyer/internal/config"
 	"gift-buyer/internal/infrastructure/logsWriter/logTypes"
 	"gift-buyer/internal/service/giftService/giftBuyer/atomicCounter"
+	"gift-buyer/internal/service/giftService/giftBuyer/attemptPacer"
+	"gift-buyer/internal/service/giftService/giftBuyer/invoiceCreator"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
+	"gift-buyer/pkg/logger"
+	"gift-buyer/pkg/tracing"
+	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock implementations
@@ -53,6 +65,23 @@ func (m *MockNotificationService) SendUpdateNotification(ctx context.Context, ve
 	return args.Error(0)
 }
 
+func (m *MockNotificationService) SendStartupSnapshot(ctx context.Context, available, matching int) error {
+	args := m.Called(ctx, available, matching)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) SendLimitReachedNotification(ctx context.Context, limit string, max int64) error {
+	args := m.Called(ctx, limit, max)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) SendSoldOutNotification(ctx context.Context, gift *tg.StarGift) error {
+	args := m.Called(ctx, gift)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) Close() {}
+
 type MockUserCache struct {
 	mock.Mock
 }
@@ -105,8 +134,8 @@ type MockInvoiceCreator struct {
 	mock.Mock
 }
 
-func (m *MockInvoiceCreator) CreateInvoice(gift *giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error) {
-	args := m.Called(gift)
+func (m *MockInvoiceCreator) CreateInvoice(ctx context.Context, gift *giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error) {
+	args := m.Called(ctx, gift)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -155,6 +184,7 @@ func createMockBuyer() (*giftBuyerImpl, *MockGiftManager, *MockNotificationServi
 		userReceiver:         []string{"123456"},
 		channelReceiver:      []string{"789012"},
 		counter:              atomicCounter.NewAtomicCounter(100),
+		giftCounts:           atomicCounter.NewCapCounter(),
 		retryCount:           3,
 		retryDelay:           1.0,
 		concurrentGifts:      5,
@@ -201,6 +231,8 @@ func TestNewGiftBuyer(t *testing.T) {
 			mockMonitorProcessor,
 			mockCounter,
 			mockLogsWriter,
+			2,     // maxConcurrentBatches
+			false, // dryRun
 		)
 
 		assert.NotNil(t, buyer)
@@ -213,6 +245,7 @@ func TestNewGiftBuyer(t *testing.T) {
 		assert.NotNil(t, buyer.invoiceCreator)
 		assert.NotNil(t, buyer.purchaseProcessor)
 		assert.NotNil(t, buyer.monitorProcessor)
+		assert.Equal(t, 2, cap(buyer.batchSem))
 	})
 }
 
@@ -408,3 +441,2006 @@ func (m *MockLogsWriter) LogError(message string) {}
 func (m *MockLogsWriter) LogErrorf(format string, args ...interface{}) {}
 
 func (m *MockLogsWriter) LogInfo(message string) {}
+
+// drainingMonitorProcessor consumes purchase results until the batch is done
+type drainingMonitorProcessor struct{}
+
+func (m *drainingMonitorProcessor) MonitorProcess(ctx context.Context, resultsCh chan giftTypes.GiftResult, doneCh chan struct{}, gifts []*giftTypes.GiftRequire) {
+	for {
+		select {
+		case <-resultsCh:
+		case <-doneCh:
+			return
+		}
+	}
+}
+
+// blockingPurchaseProcessor holds every purchase until released and tracks how many run at once
+type blockingPurchaseProcessor struct {
+	mu        sync.Mutex
+	active    int
+	maxActive int
+	started   chan struct{}
+	release   chan struct{}
+}
+
+func (p *blockingPurchaseProcessor) PurchaseGift(ctx context.Context, gift *giftTypes.GiftRequire) error {
+	p.mu.Lock()
+	p.active++
+	if p.active > p.maxActive {
+		p.maxActive = p.active
+	}
+	p.mu.Unlock()
+
+	p.started <- struct{}{}
+	<-p.release
+
+	p.mu.Lock()
+	p.active--
+	p.mu.Unlock()
+	return nil
+}
+
+func TestGiftBuyerImpl_MaxConcurrentBatches(t *testing.T) {
+	runBatches := func(t *testing.T, maxBatches int) int {
+		processor := &blockingPurchaseProcessor{
+			started: make(chan struct{}, 3),
+			release: make(chan struct{}),
+		}
+		buyer := NewGiftBuyer(nil, nil, nil, nil, nil, 100, 1, 0, false, nil, 5, nil, 5, nil,
+			processor, &drainingMonitorProcessor{}, atomicCounter.NewAtomicCounter(100), &MockLogsWriter{}, maxBatches, false)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// Three overlapping discovery cycles, one purchase each
+		for i := int64(1); i <= 3; i++ {
+			go buyer.BuyGift(ctx, []*giftTypes.GiftRequire{
+				{Gift: createTestGift(i, 100), CountForBuy: 1, ReceiverType: []int{1}},
+			})
+		}
+
+		for i := 0; i < 3; i++ {
+			select {
+			case <-processor.started:
+			case <-time.After(2 * time.Second):
+				t.Fatal("purchase was not started")
+			}
+			// Give queued batches a chance to start before releasing the running ones
+			time.Sleep(50 * time.Millisecond)
+			if i == 0 || maxBatches == 1 {
+				processor.release <- struct{}{}
+			}
+		}
+		close(processor.release)
+
+		processor.mu.Lock()
+		defer processor.mu.Unlock()
+		return processor.maxActive
+	}
+
+	t.Run("батчи выполняются по очереди", func(t *testing.T) {
+		assert.Equal(t, 1, runBatches(t, 1))
+	})
+
+	t.Run("батчи выполняются параллельно до лимита", func(t *testing.T) {
+		assert.Equal(t, 2, runBatches(t, 2))
+	})
+
+	t.Run("без лимита все батчи выполняются параллельно", func(t *testing.T) {
+		assert.Equal(t, 3, runBatches(t, 0))
+	})
+}
+
+func TestGiftBuyerImpl_SortByPriority(t *testing.T) {
+	newGifts := func() []*giftTypes.GiftRequire {
+		return []*giftTypes.GiftRequire{
+			{Gift: createTestGift(1, 100)},
+			{Gift: createTestGift(2, 500)},
+			{Gift: createTestGift(3, 100)},
+			{Gift: createTestGift(4, 500)},
+			{Gift: createTestGift(5, 100)},
+			{Gift: createTestGift(6, 500)},
+			{Gift: createTestGift(7, 100)},
+			{Gift: createTestGift(8, 300)},
+		}
+	}
+	ids := func(gifts []*giftTypes.GiftRequire) []int64 {
+		result := make([]int64, 0, len(gifts))
+		for _, gift := range gifts {
+			result = append(result, gift.Gift.ID)
+		}
+		return result
+	}
+
+	t.Run("детерминированное перемешивание при фиксированном seed", func(t *testing.T) {
+		first, _, _, _, _, _, _, _ := createMockBuyer()
+		first.SetShuffleEqualPriority(rand.NewSource(42))
+		second, _, _, _, _, _, _, _ := createMockBuyer()
+		second.SetShuffleEqualPriority(rand.NewSource(42))
+
+		gifts1, gifts2 := newGifts(), newGifts()
+		first.sortByPriority(gifts1)
+		second.sortByPriority(gifts2)
+
+		assert.Equal(t, ids(gifts1), ids(gifts2))
+	})
+
+	t.Run("порядок между уровнями приоритета сохраняется", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		buyer.SetShuffleEqualPriority(rand.NewSource(7))
+
+		orders := make(map[string]struct{})
+		for i := 0; i < 20; i++ {
+			gifts := newGifts()
+			buyer.sortByPriority(gifts)
+
+			for j := 1; j < len(gifts); j++ {
+				assert.GreaterOrEqual(t, gifts[j-1].Gift.Stars, gifts[j].Gift.Stars)
+			}
+			assert.ElementsMatch(t, []int64{2, 4, 6}, ids(gifts[:3]))
+			assert.Equal(t, int64(8), gifts[3].Gift.ID)
+			assert.ElementsMatch(t, []int64{1, 3, 5, 7}, ids(gifts[4:]))
+
+			orders[fmt.Sprint(ids(gifts))] = struct{}{}
+		}
+		assert.Greater(t, len(orders), 1, "подарки одного уровня должны перемешиваться")
+	})
+
+	t.Run("без перемешивания сортировка по цене", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+
+		gifts := newGifts()
+		buyer.sortByPriority(gifts)
+
+		for j := 1; j < len(gifts); j++ {
+			assert.GreaterOrEqual(t, gifts[j-1].Gift.Stars, gifts[j].Gift.Stars)
+		}
+	})
+}
+
+func TestSortGifts(t *testing.T) {
+	limited := func(id, stars int64, remains int) *giftTypes.GiftRequire {
+		gift := createTestGift(id, stars)
+		gift.Limited = true
+		gift.SetAvailabilityRemains(remains)
+		return &giftTypes.GiftRequire{Gift: gift}
+	}
+	newGifts := func() []*giftTypes.GiftRequire {
+		return []*giftTypes.GiftRequire{
+			limited(1, 300, 50),
+			{Gift: createTestGift(2, 100)},
+			limited(3, 500, 5),
+			limited(4, 100, 500),
+			{Gift: createTestGift(5, 500)},
+		}
+	}
+	ids := func(gifts []*giftTypes.GiftRequire) []int64 {
+		result := make([]int64, 0, len(gifts))
+		for _, gift := range gifts {
+			result = append(result, gift.Gift.ID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		strategy string
+		expected []int64
+	}{
+		{name: "по умолчанию сначала дорогие", strategy: "", expected: []int64{3, 5, 1, 2, 4}},
+		{name: "сначала дорогие", strategy: config.PriorityStarsDesc, expected: []int64{3, 5, 1, 2, 4}},
+		{name: "сначала дешевые", strategy: config.PriorityStarsAsc, expected: []int64{2, 4, 1, 3, 5}},
+		{name: "сначала самые редкие", strategy: config.PriorityRarestFirst, expected: []int64{3, 1, 4, 2, 5}},
+		{name: "в порядке обнаружения", strategy: config.PriorityDiscovery, expected: []int64{1, 2, 3, 4, 5}},
+		{name: "неизвестная стратегия", strategy: "unknown", expected: []int64{3, 5, 1, 2, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gifts := newGifts()
+			sortGifts(gifts, tt.strategy, false)
+
+			assert.Equal(t, tt.expected, ids(gifts))
+		})
+	}
+
+	t.Run("порядок критериев важнее стратегии", func(t *testing.T) {
+		gifts := newGifts()
+		gifts[1].CriteriaIndex = 1
+		gifts[4].CriteriaIndex = 1
+		sortGifts(gifts, config.PriorityStarsAsc, true)
+
+		assert.Equal(t, []int64{4, 1, 3, 2, 5}, ids(gifts))
+	})
+
+	t.Run("стратегия покупателя применяется при сортировке", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		buyer.SetPriorityStrategy(config.PriorityRarestFirst)
+
+		gifts := newGifts()
+		buyer.sortByPriority(gifts)
+
+		assert.Equal(t, []int64{3, 1, 4, 2, 5}, ids(gifts))
+	})
+}
+
+func TestGiftBuyerImpl_NotifyOnLimitReached(t *testing.T) {
+	newBuyer := func(notify bool) (*giftBuyerImpl, *MockNotificationService, *MockPurchaseProcessor) {
+		buyer, _, mockNotification, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.counter = atomicCounter.NewAtomicCounter(2)
+		buyer.retryCount = 1
+		buyer.SetNotifyOnLimitReached(notify)
+		return buyer, mockNotification, mockPurchaseProcessor
+	}
+	drain := func(resChan chan giftTypes.GiftResult) {
+		for range resChan {
+		}
+	}
+
+	t.Run("одно уведомление при достижении лимита", func(t *testing.T) {
+		buyer, mockNotification, mockPurchaseProcessor := newBuyer(true)
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+		mockNotification.On("SendLimitReachedNotification", mock.Anything, "max buy count", int64(2)).Return(nil)
+
+		resChan := make(chan giftTypes.GiftResult)
+		go drain(resChan)
+
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 5, ReceiverType: []int{1}}
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				buyer.buyGiftWithRetry(context.Background(), gift, resChan)
+			}()
+		}
+		wg.Wait()
+		close(resChan)
+
+		mockPurchaseProcessor.AssertNumberOfCalls(t, "PurchaseGift", 2)
+		mockNotification.AssertNumberOfCalls(t, "SendLimitReachedNotification", 1)
+	})
+
+	t.Run("без флага уведомление не отправляется", func(t *testing.T) {
+		buyer, mockNotification, mockPurchaseProcessor := newBuyer(false)
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+
+		resChan := make(chan giftTypes.GiftResult)
+		go drain(resChan)
+
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1}}
+		for i := 0; i < 3; i++ {
+			buyer.buyGiftWithRetry(context.Background(), gift, resChan)
+		}
+		close(resChan)
+
+		mockNotification.AssertNotCalled(t, "SendLimitReachedNotification", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("после сброса лимит объявляется снова", func(t *testing.T) {
+		buyer, mockNotification, mockPurchaseProcessor := newBuyer(true)
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+		mockNotification.On("SendLimitReachedNotification", mock.Anything, "max buy count", mock.Anything).Return(nil)
+
+		resChan := make(chan giftTypes.GiftResult)
+		go drain(resChan)
+
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 4, ReceiverType: []int{1}}
+		for i := 0; i < 3; i++ {
+			buyer.buyGiftWithRetry(context.Background(), gift, resChan)
+		}
+
+		buyer.ResetLimitReached()
+		buyer.counter.(interface{ SetMax(max int64) }).SetMax(3)
+		for i := 0; i < 2; i++ {
+			buyer.buyGiftWithRetry(context.Background(), gift, resChan)
+		}
+		close(resChan)
+
+		mockPurchaseProcessor.AssertNumberOfCalls(t, "PurchaseGift", 3)
+		mockNotification.AssertNumberOfCalls(t, "SendLimitReachedNotification", 2)
+		mockNotification.AssertCalled(t, "SendLimitReachedNotification", mock.Anything, "max buy count", int64(3))
+	})
+}
+
+func TestGiftBuyerImpl_PriorityByCriteriaOrder(t *testing.T) {
+	newGifts := func() []*giftTypes.GiftRequire {
+		return []*giftTypes.GiftRequire{
+			{Gift: createTestGift(1, 1000), CriteriaIndex: 2, CountForBuy: 1, ReceiverType: []int{1}},
+			{Gift: createTestGift(2, 50), CriteriaIndex: 0, CountForBuy: 1, ReceiverType: []int{1}},
+			{Gift: createTestGift(3, 500), CriteriaIndex: 1, CountForBuy: 1, ReceiverType: []int{1}},
+			{Gift: createTestGift(4, 100), CriteriaIndex: 0, CountForBuy: 1, ReceiverType: []int{1}},
+		}
+	}
+
+	t.Run("подарки ранних критериев покупаются первыми", func(t *testing.T) {
+		buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.retryCount = 1
+		buyer.SetPriorityByCriteriaOrder(true)
+
+		var (
+			mu    sync.Mutex
+			order []int64
+		)
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, args.Get(1).(*giftTypes.GiftRequire).Gift.ID)
+		}).Return(nil)
+
+		resChan := make(chan giftTypes.GiftResult)
+		go func() {
+			for range resChan {
+			}
+		}()
+
+		buyer.prioritizationBuy(context.Background(), newGifts(), resChan)
+		close(resChan)
+
+		assert.Equal(t, []int64{4, 2, 3, 1}, order)
+	})
+
+	t.Run("без стратегии приоритет по цене", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+
+		gifts := newGifts()
+		buyer.sortByPriority(gifts)
+
+		assert.Equal(t, int64(1), gifts[0].Gift.ID)
+		assert.Equal(t, int64(2), gifts[3].Gift.ID)
+	})
+}
+
+// invoicingPurchaseProcessor creates a real invoice for every purchase and records its peer.
+// The first purchase for a peer in failOnce fails and is recorded in failed instead.
+type invoicingPurchaseProcessor struct {
+	creator  giftInterfaces.InvoiceCreator
+	mu       sync.Mutex
+	peers    []string
+	calls    int
+	failOnce map[string]bool
+	failed   []string
+}
+
+func (p *invoicingPurchaseProcessor) PurchaseGift(ctx context.Context, gift *giftTypes.GiftRequire) error {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+
+	invoice, err := p.creator.CreateInvoice(ctx, gift)
+	if err != nil {
+		return errors.Wrap(err, "failed to create invoice")
+	}
+
+	var name string
+	switch peer := invoice.Peer.(type) {
+	case *tg.InputPeerSelf:
+		name = "self"
+	case *tg.InputPeerUser:
+		name = fmt.Sprintf("user_%d", peer.UserID)
+	case *tg.InputPeerChannel:
+		name = fmt.Sprintf("channel_%d", peer.ChannelID)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failOnce[name] {
+		delete(p.failOnce, name)
+		p.failed = append(p.failed, name)
+		return errors.New("rpc error code 500: INTERNAL")
+	}
+	p.peers = append(p.peers, name)
+	return nil
+}
+
+// finishingMonitorProcessor drains purchase results and signals when the batch is done
+type finishingMonitorProcessor struct {
+	finished chan []*giftTypes.GiftRequire
+}
+
+func (m *finishingMonitorProcessor) MonitorProcess(ctx context.Context, resultsCh chan giftTypes.GiftResult, doneCh chan struct{}, gifts []*giftTypes.GiftRequire) {
+	for {
+		select {
+		case <-resultsCh:
+		case <-doneCh:
+			m.finished <- gifts
+			return
+		}
+	}
+}
+
+func TestGiftBuyerImpl_BroadcastToAllReceivers(t *testing.T) {
+	newBuyer := func(prioritization bool) (*giftBuyerImpl, *invoicingPurchaseProcessor, *finishingMonitorProcessor) {
+		userCache := &MockUserCache{}
+		userCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+		userCache.On("GetUser", "bob").Return(&tg.User{ID: 2}, nil)
+		userCache.On("GetUser", "carol").Return(&tg.User{ID: 3}, nil)
+		userCache.On("GetChannel", "news").Return(&tg.Channel{ID: 10}, nil)
+
+		creator := invoiceCreator.NewInvoiceCreator([]string{"alice", "bob", "carol"}, []string{"news"}, userCache, false)
+		processor := &invoicingPurchaseProcessor{creator: creator}
+		monitor := &finishingMonitorProcessor{finished: make(chan []*giftTypes.GiftRequire, 1)}
+
+		buyer := NewGiftBuyer(nil, nil, nil, nil, nil, 100, 1, 0, prioritization, nil, 5, nil, 5, creator,
+			processor, monitor, atomicCounter.NewAtomicCounter(100), &MockLogsWriter{}, 0, false)
+		return buyer, processor, monitor
+	}
+
+	for _, prioritization := range []bool{false, true} {
+		t.Run(fmt.Sprintf("одна покупка на каждого получателя, приоритизация %v", prioritization), func(t *testing.T) {
+			buyer, processor, monitor := newBuyer(prioritization)
+
+			buyer.BuyGift(context.Background(), []*giftTypes.GiftRequire{
+				{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{0, 1, 2}, BroadcastToAllReceivers: true},
+			})
+
+			var gifts []*giftTypes.GiftRequire
+			select {
+			case gifts = <-monitor.finished:
+			case <-time.After(2 * time.Second):
+				t.Fatal("batch was not finished")
+			}
+
+			assert.Equal(t, int64(5), gifts[0].CountForBuy)
+			assert.ElementsMatch(t, []string{"self", "user_1", "user_2", "user_3", "channel_10"}, processor.peers)
+		})
+	}
+
+	t.Run("без флага используется количество из критерия", func(t *testing.T) {
+		buyer, processor, monitor := newBuyer(false)
+
+		buyer.BuyGift(context.Background(), []*giftTypes.GiftRequire{
+			{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{1}},
+		})
+
+		select {
+		case <-monitor.finished:
+		case <-time.After(2 * time.Second):
+			t.Fatal("batch was not finished")
+		}
+
+		assert.Len(t, processor.peers, 2)
+	})
+}
+
+func TestGiftBuyerImpl_RetryKeepsReceiver(t *testing.T) {
+	newProcessor := func(rotate bool) *invoicingPurchaseProcessor {
+		userCache := &MockUserCache{}
+		userCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+		userCache.On("GetUser", "bob").Return(&tg.User{ID: 2}, nil)
+		userCache.On("GetUser", "carol").Return(&tg.User{ID: 3}, nil)
+
+		creator := invoiceCreator.NewInvoiceCreator([]string{"alice", "bob", "carol"}, nil, userCache, rotate)
+		return &invoicingPurchaseProcessor{creator: creator, failOnce: map[string]bool{"user_1": true}}
+	}
+	buy := func(processor *invoicingPurchaseProcessor, gift *giftTypes.GiftRequire) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		buyer.purchaseProcessor = processor
+		buyer.invoiceCreator = processor.creator
+		buyer.retryCount = 2
+		buyer.retryDelay = 0
+
+		resChan := make(chan giftTypes.GiftResult)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range resChan {
+			}
+		}()
+		buyer.buyGift(context.Background(), gift, resChan)
+		close(resChan)
+		<-done
+	}
+
+	t.Run("повтор рассылки уходит тому же получателю", func(t *testing.T) {
+		processor := newProcessor(false)
+		buy(processor, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1}, BroadcastToAllReceivers: true})
+
+		assert.Equal(t, []string{"user_1"}, processor.failed)
+		assert.ElementsMatch(t, []string{"user_1", "user_2", "user_3"}, processor.peers)
+	})
+
+	t.Run("повтор взвешенного распределения уходит тому же получателю", func(t *testing.T) {
+		processor := newProcessor(false)
+		buy(processor, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1},
+			ReceiverDistribution: []giftTypes.ReceiverShare{{Receiver: "alice", Weight: 1}, {Receiver: "bob", Weight: 2}}})
+
+		assert.Equal(t, []string{"user_1"}, processor.failed)
+		assert.ElementsMatch(t, []string{"user_1", "user_2", "user_2"}, processor.peers)
+	})
+
+	t.Run("повтор не расходует лимит получателя", func(t *testing.T) {
+		processor := newProcessor(true)
+		processor.creator.(*invoiceCreator.InvoiceCreatorImpl).SetMaxPerReceiver(1)
+		buy(processor, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1}})
+
+		assert.Equal(t, []string{"user_1"}, processor.failed)
+		assert.ElementsMatch(t, []string{"user_1", "user_2", "user_3"}, processor.peers)
+	})
+
+	t.Run("повтор при ротации уходит тому же получателю", func(t *testing.T) {
+		processor := newProcessor(true)
+		buy(processor, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1}})
+
+		assert.Equal(t, []string{"user_1"}, processor.failed)
+		assert.ElementsMatch(t, []string{"user_1", "user_2", "user_3"}, processor.peers)
+	})
+}
+
+func TestGiftBuyerImpl_BoundSlotOffsetsRotation(t *testing.T) {
+	userCache := &MockUserCache{}
+	userCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+	userCache.On("GetUser", "bob").Return(&tg.User{ID: 2}, nil)
+	userCache.On("GetUser", "carol").Return(&tg.User{ID: 3}, nil)
+
+	creator := invoiceCreator.NewInvoiceCreator([]string{"alice", "bob", "carol"}, nil, userCache, true)
+	processor := &invoicingPurchaseProcessor{creator: creator}
+
+	buyer, _, _, _, _, _, _, _ := createMockBuyer()
+	buyer.retryCount = 1
+	buyer.purchaseProcessor = processor
+	buyer.invoiceCreator = creator
+
+	// The purchase scheduler dispatches every unit as its own batch with its slot bound
+	for slot := int64(0); slot < 3; slot++ {
+		resChan := make(chan giftTypes.GiftResult, 1)
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}}
+		buyer.buyGift(giftTypes.WithReceiverSlot(context.Background(), slot), gift, resChan)
+	}
+
+	assert.Equal(t, []string{"user_1", "user_2", "user_3"}, processor.peers)
+}
+
+func TestGiftBuyerImpl_CapsSharedAcrossAccounts(t *testing.T) {
+	userCache := &MockUserCache{}
+	userCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+	giftCounts, receiverCounts := atomicCounter.NewCapCounter(), atomicCounter.NewCapCounter()
+
+	// Two accounts with their own buyer and invoice creator, wired like createSystem does
+	var processors []*invoicingPurchaseProcessor
+	var buyers []*giftBuyerImpl
+	for i := 0; i < 2; i++ {
+		creator := invoiceCreator.NewInvoiceCreator([]string{"alice"}, nil, userCache, false)
+		creator.SetMaxPerReceiver(2)
+		creator.SetReceiverCounts(receiverCounts)
+		processor := &invoicingPurchaseProcessor{creator: creator}
+
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		buyer.retryCount = 1
+		buyer.purchaseProcessor = processor
+		buyer.invoiceCreator = creator
+		buyer.SetGiftCounts(giftCounts)
+
+		processors = append(processors, processor)
+		buyers = append(buyers, buyer)
+	}
+
+	// The account pool hands every account its share of the units with the full caps
+	var wg sync.WaitGroup
+	for _, buyer := range buyers {
+		for _, gift := range []*giftTypes.GiftRequire{
+			{Gift: createTestGift(1, 100), CountForBuy: 2, MaxPerGift: 4, ReceiverType: []int{1}},
+			{Gift: createTestGift(2, 100), CountForBuy: 1, MaxPerGift: 1, ReceiverType: []int{1}},
+		} {
+			wg.Add(1)
+			go func(buyer *giftBuyerImpl, gift *giftTypes.GiftRequire) {
+				defer wg.Done()
+				resChan := make(chan giftTypes.GiftResult)
+				go func() {
+					for range resChan {
+					}
+				}()
+				buyer.buyGift(context.Background(), gift, resChan)
+				close(resChan)
+			}(buyer, gift)
+		}
+	}
+	wg.Wait()
+
+	bought := 0
+	for _, processor := range processors {
+		bought += len(processor.peers)
+	}
+	// Gift 1: 4 units requested, capped by max_gifts_per_receiver at 2 for the single receiver.
+	// Gift 2: 2 units requested, capped by its per-gift cap at 1
+	assert.Equal(t, 3, bought)
+	assert.Equal(t, int64(2), giftCounts.Get("1"))
+	assert.Equal(t, int64(1), giftCounts.Get("2"))
+}
+
+func TestGiftBuyerImpl_MaxAttemptsPerSecond(t *testing.T) {
+	buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+	buyer.retryCount = 1
+	buyer.SetAttemptPacer(attemptPacer.NewAttemptPacer(20))
+
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		mu.Lock()
+		defer mu.Unlock()
+		times = append(times, time.Now())
+	}).Return(nil)
+
+	resChan := make(chan giftTypes.GiftResult)
+	go func() {
+		for range resChan {
+		}
+	}()
+
+	gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 6, ReceiverType: []int{1}}
+	buyer.buyGift(context.Background(), gift, resChan)
+	close(resChan)
+
+	require.Len(t, times, 6)
+	first, last := times[0], times[0]
+	for _, at := range times {
+		if at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	// 6 attempts at 20 per second are spread over at least 5 intervals of 50ms
+	assert.GreaterOrEqual(t, last.Sub(first), 5*50*time.Millisecond-5*time.Millisecond)
+}
+
+func TestGiftBuyerImpl_AllReceiversSaturated(t *testing.T) {
+	userCache := &MockUserCache{}
+	userCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+	userCache.On("GetUser", "bob").Return(&tg.User{ID: 2}, nil)
+
+	creator := invoiceCreator.NewInvoiceCreator([]string{"alice", "bob"}, nil, userCache, true)
+	creator.SetMaxPerReceiver(1)
+	processor := &invoicingPurchaseProcessor{creator: creator}
+
+	buyer, _, _, _, _, _, _, _ := createMockBuyer()
+	buyer.purchaseProcessor = processor
+	buyer.retryCount = 3
+
+	var (
+		mu      sync.Mutex
+		results []giftTypes.GiftResult
+	)
+	resChan := make(chan giftTypes.GiftResult)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range resChan {
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}
+	}()
+
+	gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 5, ReceiverType: []int{1}}
+	buyer.buyGift(context.Background(), gift, resChan)
+	close(resChan)
+	<-done
+
+	assert.ElementsMatch(t, []string{"user_1", "user_2"}, processor.peers)
+	// Every unit is attempted at most once: saturation is never retried
+	assert.LessOrEqual(t, processor.calls, 5)
+	assert.Equal(t, int64(2), buyer.counter.Get())
+
+	saturated := 0
+	for _, result := range results {
+		if !result.Success {
+			assert.Equal(t, errors.ErrAllReceiversSaturated, result.Err)
+			saturated++
+		}
+	}
+	assert.Equal(t, 3, saturated)
+	assert.Equal(t, int32(1), gift.ReceiversSaturated)
+}
+
+func TestGiftBuyerImpl_BuyForAllReceiverTypes(t *testing.T) {
+	newBuyer := func(prioritization bool) (*giftBuyerImpl, *invoicingPurchaseProcessor, *finishingMonitorProcessor) {
+		userCache := &MockUserCache{}
+		userCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+		userCache.On("GetChannel", "news").Return(&tg.Channel{ID: 10}, nil)
+
+		creator := invoiceCreator.NewInvoiceCreator([]string{"alice"}, []string{"news"}, userCache, false)
+		processor := &invoicingPurchaseProcessor{creator: creator}
+		monitor := &finishingMonitorProcessor{finished: make(chan []*giftTypes.GiftRequire, 1)}
+
+		buyer := NewGiftBuyer(nil, nil, nil, nil, nil, 100, 1, 0, prioritization, nil, 5, nil, 5, creator,
+			processor, monitor, atomicCounter.NewAtomicCounter(100), &MockLogsWriter{}, 0, false)
+		return buyer, processor, monitor
+	}
+
+	for _, prioritization := range []bool{false, true} {
+		t.Run(fmt.Sprintf("покупка для каждого типа получателя, приоритизация %v", prioritization), func(t *testing.T) {
+			buyer, processor, monitor := newBuyer(prioritization)
+
+			buyer.BuyGift(context.Background(), []*giftTypes.GiftRequire{
+				{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{0, 1, 2, 1}, BuyForAllReceiverTypes: true},
+			})
+
+			var gifts []*giftTypes.GiftRequire
+			select {
+			case gifts = <-monitor.finished:
+			case <-time.After(2 * time.Second):
+				t.Fatal("batch was not finished")
+			}
+
+			require.Len(t, gifts, 3)
+			for i, receiverType := range []int{0, 1, 2} {
+				assert.Equal(t, []int{receiverType}, gifts[i].ReceiverType)
+				assert.Equal(t, int64(2), gifts[i].CountForBuy)
+			}
+			assert.ElementsMatch(t, []string{"self", "self", "user_1", "user_1", "channel_10", "channel_10"}, processor.peers)
+		})
+	}
+
+	t.Run("один тип получателя не разделяется", func(t *testing.T) {
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{1}, BuyForAllReceiverTypes: true}
+
+		gifts := splitReceiverTypes([]*giftTypes.GiftRequire{gift})
+
+		require.Len(t, gifts, 1)
+		assert.Same(t, gift, gifts[0])
+	})
+}
+
+func TestGiftBuyerImpl_BalanceReserveReached(t *testing.T) {
+	buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+	buyer.retryCount = 3
+
+	mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).
+		Return(errors.Wrap(errors.ErrBalanceReserveReached, "buying gift 1 for 100 stars would leave less than 500 stars"))
+
+	var results []giftTypes.GiftResult
+	resChan := make(chan giftTypes.GiftResult)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range resChan {
+			results = append(results, result)
+		}
+	}()
+
+	gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{1}}
+	buyer.buyGift(context.Background(), gift, resChan)
+	close(resChan)
+	<-done
+
+	// Every unit gives up on the first attempt instead of retrying
+	mockPurchaseProcessor.AssertNumberOfCalls(t, "PurchaseGift", 2)
+	assert.Equal(t, int64(0), buyer.counter.Get())
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.False(t, result.Success)
+		assert.Equal(t, errors.ErrBalanceReserveReached, result.Err)
+	}
+}
+
+// recordingLogger records the fields of every message logged through it. Only the methods
+// used by the buyer are implemented.
+type recordingLogger struct {
+	logger.Logger
+	fields logger.Fields
+	mu     *sync.Mutex
+	lines  *[]logger.Fields
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{fields: logger.Fields{}, mu: &sync.Mutex{}, lines: &[]logger.Fields{}}
+}
+
+func (l *recordingLogger) WithFields(fields logger.Fields) logger.Logger {
+	merged := logger.Fields{}
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &recordingLogger{fields: merged, mu: l.mu, lines: l.lines}
+}
+
+func (l *recordingLogger) record() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.lines = append(*l.lines, l.fields)
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) { l.record() }
+func (l *recordingLogger) Warnf(format string, args ...interface{})  { l.record() }
+
+func TestGiftBuyerImpl_AttemptRequestID(t *testing.T) {
+	recorder := newRecordingLogger()
+	previous := logger.GlobalLogger
+	logger.GlobalLogger = recorder
+	defer func() { logger.GlobalLogger = previous }()
+
+	buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+	buyer.retryCount = 2
+	buyer.retryDelay = 0
+
+	var ctxIDs []interface{}
+	mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		ctxLogger := logger.FromContext(args.Get(0).(context.Context)).(*recordingLogger)
+		ctxIDs = append(ctxIDs, ctxLogger.fields[logger.RequestIDField])
+	}).Return(errors.New("rpc error code 500: INTERNAL"))
+
+	resChan := make(chan giftTypes.GiftResult, 10)
+	gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}}
+	buyer.buyGift(context.Background(), gift, resChan)
+
+	// The processor sees the attempt logger and every line of an attempt shares its ID
+	require.Len(t, ctxIDs, 2)
+	assert.NotEqual(t, ctxIDs[0], ctxIDs[1])
+
+	linesPerID := map[interface{}]int{}
+	for _, fields := range *recorder.lines {
+		require.Contains(t, fields, logger.RequestIDField)
+		assert.NotEmpty(t, fields[logger.RequestIDField])
+		assert.Equal(t, int64(1), fields["gift_id"])
+		linesPerID[fields[logger.RequestIDField]]++
+	}
+	assert.Equal(t, map[interface{}]int{ctxIDs[0]: 2, ctxIDs[1]: 2}, linesPerID)
+}
+
+func TestGiftBuyerImpl_PurchaseUnconfirmed(t *testing.T) {
+	buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+	buyer.retryCount = 3
+	buyer.retryDelay = 0
+
+	mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).
+		Return(errors.Wrap(errors.ErrPurchaseUnconfirmed, "gift 1 not seen on the receiver's profile within 10s"))
+
+	resChan := make(chan giftTypes.GiftResult, 10)
+	gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}}
+	buyer.buyGift(context.Background(), gift, resChan)
+	close(resChan)
+
+	// The paid purchase is neither retried nor given back to the buy count
+	mockPurchaseProcessor.AssertNumberOfCalls(t, "PurchaseGift", 1)
+	assert.Equal(t, int64(1), buyer.counter.Get())
+
+	var results []giftTypes.GiftResult
+	for result := range resChan {
+		results = append(results, result)
+	}
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Success)
+	assert.ErrorIs(t, results[0].Err, errors.ErrPurchaseUnconfirmed)
+}
+
+func TestGiftBuyerImpl_PurchaseErrorClassification(t *testing.T) {
+	buy := func(t *testing.T, purchaseErr error) (*MockPurchaseProcessor, []giftTypes.GiftResult) {
+		buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.retryCount = 3
+		buyer.retryDelay = 0
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(purchaseErr)
+
+		var results []giftTypes.GiftResult
+		resChan := make(chan giftTypes.GiftResult)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for result := range resChan {
+				results = append(results, result)
+			}
+		}()
+
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}}
+		buyer.buyGift(context.Background(), gift, resChan)
+		close(resChan)
+		<-done
+
+		assert.Equal(t, int64(0), buyer.counter.Get())
+		return mockPurchaseProcessor, results
+	}
+
+	t.Run("терминальная ошибка без повторов", func(t *testing.T) {
+		purchaseErr := errors.New("rpc error code 400: STARGIFT_USAGE_LIMITED")
+		mockPurchaseProcessor, results := buy(t, purchaseErr)
+
+		mockPurchaseProcessor.AssertNumberOfCalls(t, "PurchaseGift", 1)
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Success)
+		assert.Equal(t, purchaseErr, results[0].Err)
+	})
+
+	t.Run("повторяемая ошибка использует все попытки", func(t *testing.T) {
+		purchaseErr := errors.New("rpc error code 500: INTERNAL")
+		mockPurchaseProcessor, results := buy(t, purchaseErr)
+
+		mockPurchaseProcessor.AssertNumberOfCalls(t, "PurchaseGift", 3)
+		// One result per attempt and the final one
+		require.Len(t, results, 4)
+		for _, result := range results {
+			assert.False(t, result.Success)
+			assert.Equal(t, purchaseErr, result.Err)
+		}
+	})
+}
+
+func TestClassifyPurchaseError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected purchaseErrorClass
+	}{
+		{"подарок распродан", errors.New("rpc error code 400: STARGIFT_USAGE_LIMITED"), purchaseErrorTerminal},
+		{"мало звезд", errors.Wrap(errors.New("rpc error code 400: BALANCE_TOO_LOW"), "payment failed"), purchaseErrorTerminal},
+		{"неверный получатель", errors.New("rpc error code 400: PEER_ID_INVALID"), purchaseErrorTerminal},
+		{"недостаточно баланса", errors.Wrap(errors.ErrInsufficientBalance, "gift 1"), purchaseErrorTerminal},
+		{"покупки приостановлены", errors.ErrCircuitOpen, purchaseErrorTerminal},
+		{"флуд", errors.New("rpc error code 420: FLOOD_WAIT_3"), purchaseErrorRetryable},
+		{"таймаут", context.DeadlineExceeded, purchaseErrorRetryable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifyPurchaseError(tt.err))
+		})
+	}
+}
+
+func TestGiftBuyerImpl_MinAvailabilityAtBuy(t *testing.T) {
+	newLimitedGift := func(remains int) *tg.StarGift {
+		gift := createTestGift(1, 100)
+		gift.Limited = true
+		gift.SetAvailabilityRemains(remains)
+		return gift
+	}
+
+	buy := func(buyer *giftBuyerImpl, gift *giftTypes.GiftRequire) []giftTypes.GiftResult {
+		var results []giftTypes.GiftResult
+		resChan := make(chan giftTypes.GiftResult)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for result := range resChan {
+				results = append(results, result)
+			}
+		}()
+
+		buyer.buyGift(context.Background(), gift, resChan)
+		close(resChan)
+		<-done
+		return results
+	}
+
+	t.Run("остаток упал ниже порога после обнаружения", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.SetMinAvailabilityAtBuy(50)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{newLimitedGift(10)}, nil)
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: newLimitedGift(1000), CountForBuy: 2, ReceiverType: []int{1}})
+
+		mockPurchaseProcessor.AssertNotCalled(t, "PurchaseGift", mock.Anything, mock.Anything)
+		assert.Equal(t, int64(0), buyer.counter.Get())
+		require.Len(t, results, 2)
+		for _, result := range results {
+			assert.False(t, result.Success)
+			assert.Equal(t, errors.ErrAvailabilityBelowThreshold, result.Err)
+		}
+	})
+
+	t.Run("подарок пропал из каталога", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.SetMinAvailabilityAtBuy(50)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{}, nil)
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: newLimitedGift(1000), CountForBuy: 1, ReceiverType: []int{1}})
+
+		mockPurchaseProcessor.AssertNotCalled(t, "PurchaseGift", mock.Anything, mock.Anything)
+		require.Len(t, results, 1)
+		assert.Equal(t, errors.ErrAvailabilityBelowThreshold, results[0].Err)
+	})
+
+	t.Run("достаточный остаток", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.SetMinAvailabilityAtBuy(50)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{newLimitedGift(50)}, nil)
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: newLimitedGift(1000), CountForBuy: 1, ReceiverType: []int{1}})
+
+		mockPurchaseProcessor.AssertNumberOfCalls(t, "PurchaseGift", 1)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Success)
+	})
+
+	t.Run("ошибка проверки не блокирует покупку", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.SetMinAvailabilityAtBuy(50)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift(nil), errors.New("network error"))
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: newLimitedGift(1000), CountForBuy: 1, ReceiverType: []int{1}})
+
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Success)
+	})
+
+	t.Run("без порога каталог не запрашивается", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: newLimitedGift(1), CountForBuy: 1, ReceiverType: []int{1}})
+
+		mockManager.AssertNotCalled(t, "GetAvailableGifts", mock.Anything)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Success)
+	})
+}
+
+// maxPriceValidator accepts gifts up to the configured price
+type maxPriceValidator struct {
+	maxPrice int64
+}
+
+func (v *maxPriceValidator) IsEligible(gift *tg.StarGift) (*giftTypes.GiftRequire, bool) {
+	if gift.Stars > v.maxPrice {
+		return nil, false
+	}
+	return &giftTypes.GiftRequire{Gift: gift, CountForBuy: 1}, true
+}
+
+func TestGiftBuyerImpl_RevalidateBeforeBuy(t *testing.T) {
+	buy := func(buyer *giftBuyerImpl, gift *giftTypes.GiftRequire) []giftTypes.GiftResult {
+		var results []giftTypes.GiftResult
+		resChan := make(chan giftTypes.GiftResult)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for result := range resChan {
+				results = append(results, result)
+			}
+		}()
+
+		buyer.buyGift(context.Background(), gift, resChan)
+		close(resChan)
+		<-done
+		return results
+	}
+
+	t.Run("цена выросла после обнаружения", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.SetRevalidator(&maxPriceValidator{maxPrice: 500})
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{createTestGift(1, 1000)}, nil)
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{1}})
+
+		mockPurchaseProcessor.AssertNotCalled(t, "PurchaseGift", mock.Anything, mock.Anything)
+		assert.Equal(t, int64(0), buyer.counter.Get())
+		require.Len(t, results, 2)
+		for _, result := range results {
+			assert.False(t, result.Success)
+			assert.Equal(t, errors.ErrNoLongerEligible, result.Err)
+		}
+	})
+
+	t.Run("подарок пропал из каталога", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.SetRevalidator(&maxPriceValidator{maxPrice: 500})
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{createTestGift(2, 100)}, nil)
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}})
+
+		mockPurchaseProcessor.AssertNotCalled(t, "PurchaseGift", mock.Anything, mock.Anything)
+		require.Len(t, results, 1)
+		assert.Equal(t, errors.ErrNoLongerEligible, results[0].Err)
+	})
+
+	t.Run("подарок по-прежнему подходит", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.SetRevalidator(&maxPriceValidator{maxPrice: 500})
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{createTestGift(1, 200)}, nil)
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}})
+
+		mockPurchaseProcessor.AssertNumberOfCalls(t, "PurchaseGift", 1)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Success)
+	})
+
+	t.Run("ошибка каталога не блокирует покупку", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.SetRevalidator(&maxPriceValidator{maxPrice: 500})
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift(nil), errors.New("network error"))
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}})
+
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Success)
+	})
+
+	t.Run("проверки остатка и критериев делят один запрос каталога", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.SetMinAvailabilityAtBuy(50)
+		buyer.SetRevalidator(&maxPriceValidator{maxPrice: 500})
+		current := createTestGift(1, 200)
+		current.Limited = true
+		current.SetAvailabilityRemains(100)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{current}, nil)
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+		gift := createTestGift(1, 100)
+		gift.Limited = true
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: gift, CountForBuy: 2, ReceiverType: []int{1}})
+
+		require.Len(t, results, 2)
+		for _, result := range results {
+			assert.True(t, result.Success)
+		}
+		// One catalog request per purchased unit, not one per check
+		mockManager.AssertNumberOfCalls(t, "GetAvailableGifts", 2)
+	})
+
+	t.Run("без флага каталог не запрашивается", func(t *testing.T) {
+		buyer, mockManager, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+
+		results := buy(buyer, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}})
+
+		mockManager.AssertNotCalled(t, "GetAvailableGifts", mock.Anything)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Success)
+	})
+}
+
+func TestGiftBuyerImpl_DryRun(t *testing.T) {
+	buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+	buyer.dryRun = true
+
+	var results []giftTypes.GiftResult
+	resChan := make(chan giftTypes.GiftResult)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range resChan {
+			results = append(results, result)
+		}
+	}()
+
+	gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{1}}
+	buyer.buyGift(context.Background(), gift, resChan)
+	close(resChan)
+	<-done
+
+	mockPurchaseProcessor.AssertNotCalled(t, "PurchaseGift", mock.Anything, mock.Anything)
+	assert.Equal(t, int64(2), buyer.counter.Get())
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.True(t, result.Success)
+		assert.True(t, result.Simulated)
+		assert.NoError(t, result.Err)
+	}
+}
+
+// fakeBalanceCache is a balance cache with a fixed refreshed balance
+type fakeBalanceCache struct {
+	mu        sync.Mutex
+	balance   int64
+	refreshed int64
+	refreshes int
+}
+
+func (c *fakeBalanceCache) SetBalance(balance int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.balance = balance
+}
+
+func (c *fakeBalanceCache) GetBalance() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.balance
+}
+
+func (c *fakeBalanceCache) TrimBalance(deduction int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.balance -= deduction
+}
+
+func (c *fakeBalanceCache) RefreshBalance(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshes++
+	c.balance = c.refreshed
+	return nil
+}
+
+func TestGiftBuyerImpl_BalanceCache(t *testing.T) {
+	collect := func(buyer *giftBuyerImpl, gift *giftTypes.GiftRequire) []giftTypes.GiftResult {
+		var results []giftTypes.GiftResult
+		resChan := make(chan giftTypes.GiftResult)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for result := range resChan {
+				results = append(results, result)
+			}
+		}()
+
+		buyer.buyGift(context.Background(), gift, resChan)
+		close(resChan)
+		<-done
+		return results
+	}
+
+	t.Run("недостаточный баланс пропускает покупку", func(t *testing.T) {
+		buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.SetBalanceCache(&fakeBalanceCache{balance: 50})
+
+		results := collect(buyer, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{1}})
+
+		mockPurchaseProcessor.AssertNotCalled(t, "PurchaseGift", mock.Anything, mock.Anything)
+		assert.Equal(t, int64(0), buyer.counter.Get())
+		require.Len(t, results, 2)
+		for _, result := range results {
+			assert.False(t, result.Success)
+			assert.True(t, errors.Is(result.Err, errors.ErrInsufficientBalance))
+			assert.Contains(t, result.Err.Error(), "only 50 left")
+		}
+	})
+
+	t.Run("баланс уменьшается после покупки", func(t *testing.T) {
+		buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.concurrentOperations = 1
+		cache := &fakeBalanceCache{balance: 250}
+		buyer.SetBalanceCache(cache)
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+
+		results := collect(buyer, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1}})
+
+		mockPurchaseProcessor.AssertNumberOfCalls(t, "PurchaseGift", 2)
+		assert.Equal(t, int64(50), cache.GetBalance())
+		require.Len(t, results, 3)
+		failed := 0
+		for _, result := range results {
+			if !result.Success {
+				failed++
+				assert.True(t, errors.Is(result.Err, errors.ErrInsufficientBalance))
+			}
+		}
+		assert.Equal(t, 1, failed)
+	})
+
+	t.Run("баланс обновляется перед пачкой", func(t *testing.T) {
+		processor := &invoicingPurchaseProcessor{}
+		monitor := &finishingMonitorProcessor{finished: make(chan []*giftTypes.GiftRequire, 1)}
+		buyer := NewGiftBuyer(nil, nil, nil, nil, nil, 100, 1, 0, false, nil, 5, nil, 5, nil,
+			processor, monitor, atomicCounter.NewAtomicCounter(100), &MockLogsWriter{}, 0, false)
+		cache := &fakeBalanceCache{refreshed: 10}
+		buyer.SetBalanceCache(cache)
+
+		buyer.BuyGift(context.Background(), []*giftTypes.GiftRequire{
+			{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}},
+		})
+
+		select {
+		case <-monitor.finished:
+		case <-time.After(time.Second):
+			t.Fatal("batch did not finish")
+		}
+		assert.Equal(t, 1, cache.refreshes)
+		// The refreshed balance doesn't cover the gift
+		assert.Equal(t, 0, processor.calls)
+	})
+}
+
+func TestGiftBuyerImpl_MaxAffordableCount(t *testing.T) {
+	affordable := func(id, stars int64) *giftTypes.GiftRequire {
+		return &giftTypes.GiftRequire{Gift: createTestGift(id, stars), BuyMaxAffordable: true, ReceiverType: []int{1}}
+	}
+
+	t.Run("количество по балансу", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		buyer.SetBalanceCache(&fakeBalanceCache{balance: 1050})
+		gift := affordable(1, 100)
+
+		buyer.resolveMaxAffordable([]*giftTypes.GiftRequire{gift})
+
+		assert.Equal(t, int64(10), gift.CountForBuy)
+	})
+
+	t.Run("ограничение max_buy_count", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		buyer.counter = atomicCounter.NewAtomicCounterFrom(10, 4)
+		buyer.SetBalanceCache(&fakeBalanceCache{balance: 5000})
+		gift := affordable(1, 100)
+
+		buyer.resolveMaxAffordable([]*giftTypes.GiftRequire{gift})
+
+		// 50 affordable, but only 6 purchases left under the limit
+		assert.Equal(t, int64(6), gift.CountForBuy)
+	})
+
+	t.Run("ограничение бюджетом критерия", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		buyer.SetBalanceCache(&fakeBalanceCache{balance: 5000})
+		gift := affordable(1, 100)
+		gift.StarBudget = 450
+
+		buyer.resolveMaxAffordable([]*giftTypes.GiftRequire{gift})
+
+		// 50 affordable, but the budget covers only 4
+		assert.Equal(t, int64(4), gift.CountForBuy)
+	})
+
+	t.Run("баланс делится между подарками пачки", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		buyer.SetBalanceCache(&fakeBalanceCache{balance: 1000})
+		expensive, cheap, fixed := affordable(1, 300), affordable(2, 150), &giftTypes.GiftRequire{Gift: createTestGift(3, 50), CountForBuy: 2}
+
+		buyer.resolveMaxAffordable([]*giftTypes.GiftRequire{expensive, fixed, cheap})
+
+		assert.Equal(t, int64(3), expensive.CountForBuy)
+		assert.Equal(t, int64(0), cheap.CountForBuy)
+		assert.Equal(t, int64(2), fixed.CountForBuy)
+	})
+
+	t.Run("без кэша баланса ничего не покупается", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		gift := affordable(1, 100)
+
+		buyer.resolveMaxAffordable([]*giftTypes.GiftRequire{gift})
+
+		assert.Equal(t, int64(0), gift.CountForBuy)
+	})
+
+	t.Run("пачка покупает рассчитанное количество", func(t *testing.T) {
+		processor := &stagedPurchaseProcessor{}
+		monitor := &finishingMonitorProcessor{finished: make(chan []*giftTypes.GiftRequire, 1)}
+		buyer := NewGiftBuyer(nil, nil, nil, nil, nil, 100, 1, 0, false, nil, 5, nil, 5, nil,
+			processor, monitor, atomicCounter.NewAtomicCounter(100), &MockLogsWriter{}, 0, false)
+		buyer.SetBalanceCache(&fakeBalanceCache{refreshed: 350})
+
+		buyer.BuyGift(context.Background(), []*giftTypes.GiftRequire{affordable(1, 100)})
+
+		select {
+		case gifts := <-monitor.finished:
+			require.Len(t, gifts, 1)
+			assert.Equal(t, int64(3), gifts[0].CountForBuy)
+		case <-time.After(time.Second):
+			t.Fatal("batch did not finish")
+		}
+		assert.Equal(t, 3, processor.calls)
+	})
+}
+
+// stagedPurchaseProcessor fails the configured calls and records whether a purchase
+// started while the first one was still running
+type stagedPurchaseProcessor struct {
+	mu                sync.Mutex
+	calls             int
+	fail              map[int]bool
+	firstRunning      bool
+	overlappedToFirst bool
+}
+
+func (p *stagedPurchaseProcessor) PurchaseGift(ctx context.Context, gift *giftTypes.GiftRequire) error {
+	p.mu.Lock()
+	p.calls++
+	call := p.calls
+	if call == 1 {
+		p.firstRunning = true
+	} else if p.firstRunning {
+		p.overlappedToFirst = true
+	}
+	p.mu.Unlock()
+
+	if call == 1 {
+		time.Sleep(50 * time.Millisecond)
+		p.mu.Lock()
+		p.firstRunning = false
+		p.mu.Unlock()
+	}
+
+	if p.fail[call] {
+		return errors.New("purchase failed")
+	}
+	return nil
+}
+
+func TestGiftBuyerImpl_Stages(t *testing.T) {
+	run := func(processor *stagedPurchaseProcessor, stages []giftTypes.PurchaseStage, total int64) []giftTypes.GiftResult {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		buyer.purchaseProcessor = processor
+		buyer.retryCount = 1
+
+		var results []giftTypes.GiftResult
+		resChan := make(chan giftTypes.GiftResult)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for result := range resChan {
+				results = append(results, result)
+			}
+		}()
+
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: total, ReceiverType: []int{0}, Stages: stages}
+		buyer.buyGift(context.Background(), gift, resChan)
+		close(resChan)
+		<-done
+		return results
+	}
+	successes := func(results []giftTypes.GiftResult) int {
+		count := 0
+		for _, result := range results {
+			if result.Success {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Run("следующий этап начинается после успешного предыдущего", func(t *testing.T) {
+		processor := &stagedPurchaseProcessor{}
+
+		results := run(processor, []giftTypes.PurchaseStage{
+			{Count: 1},
+			{Count: 3, RequirePreviousSuccess: true},
+		}, 4)
+
+		assert.Equal(t, 4, processor.calls)
+		assert.False(t, processor.overlappedToFirst)
+		assert.Len(t, results, 4)
+		assert.Equal(t, 4, successes(results))
+	})
+
+	t.Run("неудачный этап отменяет следующие", func(t *testing.T) {
+		processor := &stagedPurchaseProcessor{fail: map[int]bool{1: true}}
+
+		results := run(processor, []giftTypes.PurchaseStage{
+			{Count: 1},
+			{Count: 3, RequirePreviousSuccess: true},
+			{Count: 2, RequirePreviousSuccess: false},
+		}, 6)
+
+		assert.Equal(t, 1, processor.calls)
+		// Every requested unit is still reported, the failed one with its attempt and the final result
+		assert.Len(t, results, 7)
+		assert.Equal(t, 0, successes(results))
+	})
+
+	t.Run("этап с условием always выполняется после неудачи", func(t *testing.T) {
+		processor := &stagedPurchaseProcessor{fail: map[int]bool{1: true}}
+
+		results := run(processor, []giftTypes.PurchaseStage{
+			{Count: 1},
+			{Count: 2, RequirePreviousSuccess: false},
+		}, 3)
+
+		assert.Equal(t, 3, processor.calls)
+		assert.False(t, processor.overlappedToFirst)
+		// The failed unit reports its attempt and the final result
+		assert.Len(t, results, 4)
+		assert.Equal(t, 2, successes(results))
+	})
+}
+
+// slowPurchaseProcessor holds every purchase until the context is cancelled
+type slowPurchaseProcessor struct {
+	calls int32
+}
+
+func (p *slowPurchaseProcessor) PurchaseGift(ctx context.Context, gift *giftTypes.GiftRequire) error {
+	atomic.AddInt32(&p.calls, 1)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(5 * time.Second):
+		return nil
+	}
+}
+
+// recordingMonitorProcessor collects purchase results and hands them over when the batch is done
+type recordingMonitorProcessor struct {
+	finished chan []giftTypes.GiftResult
+}
+
+func (m *recordingMonitorProcessor) MonitorProcess(ctx context.Context, resultsCh chan giftTypes.GiftResult, doneCh chan struct{}, gifts []*giftTypes.GiftRequire) {
+	var results []giftTypes.GiftResult
+	for {
+		select {
+		case result := <-resultsCh:
+			results = append(results, result)
+		case <-doneCh:
+			m.finished <- results
+			return
+		}
+	}
+}
+
+func TestGiftBuyerImpl_BatchTimeout(t *testing.T) {
+	for _, prioritization := range []bool{false, true} {
+		t.Run(fmt.Sprintf("приоритизация=%v", prioritization), func(t *testing.T) {
+			buyer, _, _, _, _, _, _, _ := createMockBuyer()
+			processor := &slowPurchaseProcessor{}
+			monitor := &recordingMonitorProcessor{finished: make(chan []giftTypes.GiftResult, 1)}
+			buyer.purchaseProcessor = processor
+			buyer.monitorProcessor = monitor
+			buyer.prioritization = prioritization
+			buyer.retryDelay = 0
+			buyer.SetBatchTimeout(50 * time.Millisecond)
+
+			started := time.Now()
+			buyer.BuyGift(context.Background(), []*giftTypes.GiftRequire{
+				{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1}},
+			})
+
+			var results []giftTypes.GiftResult
+			select {
+			case results = <-monitor.finished:
+			case <-time.After(3 * time.Second):
+				t.Fatal("batch was not aborted at the timeout")
+			}
+
+			assert.Less(t, time.Since(started), 2*time.Second)
+			require.NotEmpty(t, results)
+			timedOut := 0
+			for _, result := range results {
+				assert.False(t, result.Success)
+				if errors.Is(result.Err, errors.ErrBatchTimeout) {
+					timedOut++
+				}
+			}
+			assert.Positive(t, timedOut)
+			assert.Equal(t, int64(0), buyer.counter.Get())
+		})
+	}
+}
+
+func TestGiftBuyerImpl_BatchTimeoutDisabled(t *testing.T) {
+	buyer, _, _, _, _, _, _, _ := createMockBuyer()
+
+	ctx, cancel := buyer.batchContext(context.Background())
+	defer cancel()
+
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+}
+
+func TestGiftBuyerImpl_PurchaseAttemptSpans(t *testing.T) {
+	exporter := tracing.NewInMemoryExporter()
+	provider := tracing.NewTracerProvider(exporter)
+	provider.Install()
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+	buyer.retryDelay = 0
+	mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(errors.New("FORM_EXPIRED")).Once()
+	mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil).Once()
+
+	resChan := make(chan giftTypes.GiftResult, 10)
+	buyer.buyGiftWithRetry(context.Background(), &giftTypes.GiftRequire{Gift: createTestGift(5, 100), CountForBuy: 1, ReceiverType: []int{1}}, resChan)
+	require.NoError(t, provider.ForceFlush(context.Background()))
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 2)
+	for i, outcome := range []string{tracing.OutcomeError, tracing.OutcomeSuccess} {
+		assert.Equal(t, "purchase.attempt", spans[i].Name)
+
+		giftID, _ := spans[i].Attribute(tracing.AttrGiftID)
+		assert.Equal(t, int64(5), giftID.AsInt64())
+		attempt, _ := spans[i].Attribute("attempt")
+		assert.Equal(t, int64(i+1), attempt.AsInt64())
+		recorded, _ := spans[i].Attribute(tracing.AttrOutcome)
+		assert.Equal(t, outcome, recorded.AsString())
+		_, hasDuration := spans[i].Attribute(tracing.AttrDurationMs)
+		assert.True(t, hasDuration)
+	}
+}
+
+// countingPurchaseProcessor counts successful purchases per gift ID
+type countingPurchaseProcessor struct {
+	mu     sync.Mutex
+	bought map[int64]int
+}
+
+func (p *countingPurchaseProcessor) PurchaseGift(ctx context.Context, gift *giftTypes.GiftRequire) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bought[gift.Gift.ID]++
+	return nil
+}
+
+func TestGiftBuyerImpl_MaxPerGift(t *testing.T) {
+	collect := func(buyer *giftBuyerImpl, gifts ...*giftTypes.GiftRequire) []giftTypes.GiftResult {
+		var (
+			results []giftTypes.GiftResult
+			wg      sync.WaitGroup
+		)
+		resChan := make(chan giftTypes.GiftResult)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for result := range resChan {
+				results = append(results, result)
+			}
+		}()
+
+		for _, gift := range gifts {
+			wg.Add(1)
+			go func(gift *giftTypes.GiftRequire) {
+				defer wg.Done()
+				buyer.buyGift(context.Background(), gift, resChan)
+			}(gift)
+		}
+		wg.Wait()
+		close(resChan)
+		<-done
+		return results
+	}
+
+	t.Run("ограничение каждого подарка соблюдается независимо", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		processor := &countingPurchaseProcessor{bought: make(map[int64]int)}
+		buyer.purchaseProcessor = processor
+
+		// Several concurrent batches of the same gifts ask for more units than their caps
+		var gifts []*giftTypes.GiftRequire
+		for i := 0; i < 4; i++ {
+			gifts = append(gifts,
+				&giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 3, MaxPerGift: 3, ReceiverType: []int{1}},
+				&giftTypes.GiftRequire{Gift: createTestGift(2, 100), CountForBuy: 5, MaxPerGift: 5, ReceiverType: []int{1}},
+			)
+		}
+		results := collect(buyer, gifts...)
+
+		assert.Equal(t, map[int64]int{1: 3, 2: 5}, processor.bought)
+		assert.Equal(t, int64(8), buyer.counter.Get())
+
+		capped := map[int64]int{}
+		for _, result := range results {
+			if !result.Success {
+				assert.True(t, errors.Is(result.Err, errors.ErrGiftCapReached))
+				capped[result.GiftID]++
+			}
+		}
+		assert.Equal(t, map[int64]int{1: 9, 2: 15}, capped)
+	})
+
+	t.Run("ограничение общее для всех аккаунтов", func(t *testing.T) {
+		counts := atomicCounter.NewCapCounter()
+		first, _, _, _, _, _, _, _ := createMockBuyer()
+		second, _, _, _, _, _, _, _ := createMockBuyer()
+		firstProcessor := &countingPurchaseProcessor{bought: make(map[int64]int)}
+		secondProcessor := &countingPurchaseProcessor{bought: make(map[int64]int)}
+		for buyer, processor := range map[*giftBuyerImpl]*countingPurchaseProcessor{first: firstProcessor, second: secondProcessor} {
+			buyer.SetGiftCounts(counts)
+			buyer.purchaseProcessor = processor
+		}
+
+		// The account pool hands every account its share of the units with the full cap
+		var wg sync.WaitGroup
+		for _, buyer := range []*giftBuyerImpl{first, second} {
+			wg.Add(1)
+			go func(buyer *giftBuyerImpl) {
+				defer wg.Done()
+				collect(buyer, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 3, MaxPerGift: 3, ReceiverType: []int{1}})
+			}(buyer)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 3, firstProcessor.bought[1]+secondProcessor.bought[1])
+		assert.Equal(t, int64(3), counts.Get("1"))
+	})
+
+	t.Run("неудачная покупка не расходует ограничение", func(t *testing.T) {
+		buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.retryCount = 1
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(errors.New("payment failed")).Once()
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 1, MaxPerGift: 1, ReceiverType: []int{1}}
+		failed := collect(buyer, gift)
+		bought := collect(buyer, gift)
+		capped := collect(buyer, gift)
+
+		// The failed unit reports its attempt and the final result
+		require.Len(t, failed, 2)
+		assert.False(t, failed[1].Success)
+		require.Len(t, bought, 1)
+		assert.True(t, bought[0].Success)
+		require.Len(t, capped, 1)
+		assert.True(t, errors.Is(capped[0].Err, errors.ErrGiftCapReached))
+	})
+
+	t.Run("глобальный лимит возвращает резерв подарка", func(t *testing.T) {
+		buyer, _, _, _, _, _, mockPurchaseProcessor, _ := createMockBuyer()
+		buyer.counter = atomicCounter.NewAtomicCounter(0)
+		counts := atomicCounter.NewCapCounter()
+		buyer.SetGiftCounts(counts)
+		mockPurchaseProcessor.On("PurchaseGift", mock.Anything, mock.Anything).Return(nil)
+
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 1, MaxPerGift: 1, ReceiverType: []int{1}}
+		results := collect(buyer, gift)
+
+		require.Len(t, results, 1)
+		assert.False(t, errors.Is(results[0].Err, errors.ErrGiftCapReached))
+		assert.Zero(t, counts.Get("1"))
+	})
+
+	t.Run("без ограничения количество не учитывается", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		counts := atomicCounter.NewCapCounter()
+		buyer.SetGiftCounts(counts)
+		processor := &countingPurchaseProcessor{bought: make(map[int64]int)}
+		buyer.purchaseProcessor = processor
+
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 4, ReceiverType: []int{1}}
+		collect(buyer, gift, gift)
+
+		assert.Equal(t, map[int64]int{1: 8}, processor.bought)
+		assert.Zero(t, counts.Get("1"))
+	})
+}
+
+// timingPurchaseProcessor records when each purchase of every gift started
+type timingPurchaseProcessor struct {
+	mu     sync.Mutex
+	starts map[int64][]time.Time
+}
+
+func (p *timingPurchaseProcessor) PurchaseGift(ctx context.Context, gift *giftTypes.GiftRequire) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.starts[gift.Gift.ID] = append(p.starts[gift.Gift.ID], time.Now())
+	return nil
+}
+
+func TestGiftBuyerImpl_PurchaseSpacing(t *testing.T) {
+	const spacing = 40 * time.Millisecond
+
+	buy := func(buyer *giftBuyerImpl, gifts ...*giftTypes.GiftRequire) {
+		var wg sync.WaitGroup
+		resChan := make(chan giftTypes.GiftResult, 16)
+		for _, gift := range gifts {
+			wg.Add(1)
+			go func(gift *giftTypes.GiftRequire) {
+				defer wg.Done()
+				buyer.buyGift(context.Background(), gift, resChan)
+			}(gift)
+		}
+		wg.Wait()
+	}
+
+	t.Run("покупки одного подарка запускаются с паузой", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		processor := &timingPurchaseProcessor{starts: make(map[int64][]time.Time)}
+		buyer.purchaseProcessor = processor
+		buyer.SetPurchaseSpacing(spacing)
+
+		start := time.Now()
+		buy(buyer, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 4, ReceiverType: []int{1}})
+
+		starts := processor.starts[1]
+		require.Len(t, starts, 4)
+		sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+		// The i-th unit is launched no earlier than i spacings after the first one
+		for i, started := range starts {
+			assert.GreaterOrEqual(t, started.Sub(start), time.Duration(i)*spacing)
+		}
+	})
+
+	t.Run("разные подарки не ждут друг друга", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		processor := &timingPurchaseProcessor{starts: make(map[int64][]time.Time)}
+		buyer.purchaseProcessor = processor
+		buyer.SetPurchaseSpacing(spacing)
+
+		start := time.Now()
+		buy(buyer,
+			&giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1}},
+			&giftTypes.GiftRequire{Gift: createTestGift(2, 100), CountForBuy: 3, ReceiverType: []int{1}},
+		)
+
+		for _, id := range []int64{1, 2} {
+			starts := processor.starts[id]
+			require.Len(t, starts, 3)
+			sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+			assert.Less(t, starts[0].Sub(start), spacing)
+		}
+	})
+
+	t.Run("без паузы все покупки запускаются сразу", func(t *testing.T) {
+		buyer, _, _, _, _, _, _, _ := createMockBuyer()
+		processor := &timingPurchaseProcessor{starts: make(map[int64][]time.Time)}
+		buyer.purchaseProcessor = processor
+
+		start := time.Now()
+		buy(buyer, &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 4, ReceiverType: []int{1}})
+
+		assert.Len(t, processor.starts[1], 4)
+		assert.Less(t, time.Since(start), spacing)
+	})
+}
+
+func TestGiftBuyerImpl_LaunchJitter(t *testing.T) {
+	const gifts = 20
+
+	buy := func(jitter time.Duration) []time.Time {
+		processor := &timingPurchaseProcessor{starts: make(map[int64][]time.Time)}
+		monitor := &finishingMonitorProcessor{finished: make(chan []*giftTypes.GiftRequire, 1)}
+		buyer := NewGiftBuyer(nil, nil, nil, nil, nil, 100, 1, 0, false, nil, gifts, nil, 5, nil,
+			processor, monitor, atomicCounter.NewAtomicCounter(100), &MockLogsWriter{}, 0, false)
+		buyer.SetLaunchJitter(jitter)
+
+		batch := make([]*giftTypes.GiftRequire, 0, gifts)
+		for id := int64(1); id <= gifts; id++ {
+			batch = append(batch, &giftTypes.GiftRequire{Gift: createTestGift(id, 100), CountForBuy: 1, ReceiverType: []int{0}})
+		}
+		buyer.BuyGift(context.Background(), batch)
+		select {
+		case <-monitor.finished:
+		case <-time.After(2 * time.Second):
+			t.Fatal("batch did not finish")
+		}
+
+		var starts []time.Time
+		for _, times := range processor.starts {
+			starts = append(starts, times...)
+		}
+		require.Len(t, starts, gifts)
+		sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+		return starts
+	}
+
+	t.Run("покупки распределены во времени", func(t *testing.T) {
+		const jitter = 200 * time.Millisecond
+		starts := buy(jitter)
+
+		// With 20 uniform delays up to 200ms they are practically never all in the first millisecond
+		late := 0
+		for _, started := range starts {
+			if started.Sub(starts[0]) > time.Millisecond {
+				late++
+			}
+		}
+		assert.Greater(t, late, gifts/2)
+		assert.Greater(t, starts[gifts-1].Sub(starts[0]), jitter/4)
+		assert.LessOrEqual(t, starts[gifts-1].Sub(starts[0]), jitter+50*time.Millisecond)
+	})
+
+	t.Run("без джиттера все покупки запускаются сразу", func(t *testing.T) {
+		starts := buy(0)
+
+		assert.Less(t, starts[gifts-1].Sub(starts[0]), 50*time.Millisecond)
+	})
+}
+
+// drainingPurchaseProcessor holds every purchase until released or until its context is done
+type drainingPurchaseProcessor struct {
+	started chan struct{}
+	release chan struct{}
+	fail    bool
+}
+
+func (p *drainingPurchaseProcessor) PurchaseGift(ctx context.Context, gift *giftTypes.GiftRequire) error {
+	p.started <- struct{}{}
+	if p.fail {
+		return errors.New("purchase failed")
+	}
+	select {
+	case <-p.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type batchSummary struct {
+	success int
+	failed  int
+}
+
+// summaryMonitor collects the results like the real monitor and reports the summary on doneCh
+type summaryMonitor struct {
+	summaries chan batchSummary
+}
+
+func (m *summaryMonitor) MonitorProcess(ctx context.Context, resultsCh chan giftTypes.GiftResult, doneCh chan struct{}, gifts []*giftTypes.GiftRequire) {
+	var summary batchSummary
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-doneCh:
+			m.summaries <- summary
+			return
+		case result := <-resultsCh:
+			if result.Success {
+				summary.success++
+			} else {
+				summary.failed++
+			}
+		}
+	}
+}
+
+func TestGiftBuyerImpl_DrainOnShutdown(t *testing.T) {
+	newDrainingBuyer := func(processor *drainingPurchaseProcessor) (*giftBuyerImpl, *summaryMonitor) {
+		buyer, _, _, _, mockRateLimiter, _, _, _ := createMockBuyer()
+		mockRateLimiter.On("Close").Return()
+		monitor := &summaryMonitor{summaries: make(chan batchSummary, 1)}
+		buyer.purchaseProcessor = processor
+		buyer.monitorProcessor = monitor
+		buyer.drainTimeout = time.Second
+		buyer.SetDrainOnShutdown(true)
+		return buyer, monitor
+	}
+
+	waitStarted := func(t *testing.T, processor *drainingPurchaseProcessor, count int) {
+		t.Helper()
+		for i := 0; i < count; i++ {
+			select {
+			case <-processor.started:
+			case <-time.After(time.Second):
+				t.Fatalf("only %d of %d purchases started", i, count)
+			}
+		}
+	}
+
+	t.Run("начатые покупки завершаются после отмены", func(t *testing.T) {
+		processor := &drainingPurchaseProcessor{started: make(chan struct{}, 3), release: make(chan struct{})}
+		buyer, monitor := newDrainingBuyer(processor)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		buyer.BuyGift(ctx, []*giftTypes.GiftRequire{{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1}}})
+		waitStarted(t, processor, 3)
+
+		cancel()
+		time.AfterFunc(20*time.Millisecond, func() { close(processor.release) })
+		buyer.Close()
+
+		// Every payment in flight went through and is counted once
+		assert.Equal(t, int64(3), buyer.counter.Get())
+		assert.Equal(t, batchSummary{success: 3}, <-monitor.summaries)
+	})
+
+	t.Run("зависшая покупка обрывается по таймауту", func(t *testing.T) {
+		processor := &drainingPurchaseProcessor{started: make(chan struct{}, 2), release: make(chan struct{})}
+		buyer, monitor := newDrainingBuyer(processor)
+		buyer.drainTimeout = 20 * time.Millisecond
+
+		ctx, cancel := context.WithCancel(context.Background())
+		buyer.BuyGift(ctx, []*giftTypes.GiftRequire{{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{1}}})
+		waitStarted(t, processor, 2)
+
+		cancel()
+		start := time.Now()
+		buyer.Close()
+
+		assert.Less(t, time.Since(start), time.Second)
+		// The cut off attempts are rolled back and not retried
+		assert.Empty(t, processor.started)
+		assert.Equal(t, int64(0), buyer.counter.Get())
+		// Each unit reports the cut off attempt and then the cancellation
+		assert.Equal(t, batchSummary{failed: 4}, <-monitor.summaries)
+	})
+
+	t.Run("отмена во время паузы перед повтором", func(t *testing.T) {
+		processor := &drainingPurchaseProcessor{started: make(chan struct{}, 3), fail: true}
+		buyer, monitor := newDrainingBuyer(processor)
+		buyer.retryDelay = 10
+
+		ctx, cancel := context.WithCancel(context.Background())
+		buyer.BuyGift(ctx, []*giftTypes.GiftRequire{{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}}})
+		waitStarted(t, processor, 1)
+
+		cancel()
+		start := time.Now()
+		buyer.Close()
+
+		// No retry is started after the cancellation and the delay isn't waited out
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Empty(t, processor.started)
+		assert.Equal(t, int64(0), buyer.counter.Get())
+		assert.Equal(t, batchSummary{failed: 2}, <-monitor.summaries)
+	})
+}
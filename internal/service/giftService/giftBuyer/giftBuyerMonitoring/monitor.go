@@ -6,6 +6,7 @@ import (
 	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/internal/service/giftService/giftTypes"
 	"gift-buyer/pkg/errors"
+	"time"
 
 	"github.com/gotd/td/tg"
 )
@@ -15,8 +16,38 @@ type GiftBuyerMonitoringImpl struct {
 	notification    giftInterfaces.NotificationService
 	infoLogsWriter  giftInterfaces.InfoLogger
 	errorLogsWriter giftInterfaces.ErrorLogger
+
+	// progressInterval is the interval of interim progress notifications during a batch, 0 disables them
+	progressInterval time.Duration
+
+	// successThreshold is the percentage of requested gifts a batch must buy to pass, 0 disables the check
+	successThreshold float64
+
+	// thresholdAction is the reaction to a batch missing the threshold: ThresholdActionAlert or ThresholdActionStop
+	thresholdAction string
+
+	// onThresholdMissed stops buying when a batch misses the threshold with ThresholdActionStop
+	onThresholdMissed func()
+
+	// recorders receive the result of every purchase attempt, e.g. the session stats (optional)
+	recorders []ResultRecorder
 }
 
+// ResultRecorder receives the result of every purchase attempt together with the price of
+// the gift, e.g. the session stats or the gRPC event stream.
+type ResultRecorder interface {
+	RecordResult(result giftTypes.GiftResult, stars int64)
+}
+
+// Reactions to a batch missing the success threshold, besides reporting it in the summary.
+const (
+	// ThresholdActionAlert sends an error notification
+	ThresholdActionAlert = "alert"
+
+	// ThresholdActionStop sends an error notification and stops buying
+	ThresholdActionStop = "stop"
+)
+
 func NewGiftBuyerMonitoring(api *tg.Client, notification giftInterfaces.NotificationService, infoLogsWriter giftInterfaces.InfoLogger, errorLogsWriter giftInterfaces.ErrorLogger) *GiftBuyerMonitoringImpl {
 	return &GiftBuyerMonitoringImpl{
 		api:             api,
@@ -29,7 +60,14 @@ func NewGiftBuyerMonitoring(api *tg.Client, notification giftInterfaces.Notifica
 func (gm *GiftBuyerMonitoringImpl) MonitorProcess(ctx context.Context, resultsCh chan giftTypes.GiftResult, doneChan chan struct{}, gifts []*giftTypes.GiftRequire) {
 	summaries := make(map[int64]*giftTypes.GiftSummary)
 	errorCounts := make(map[string]int64)
+	prices := make(map[int64]int64)
 	for _, require := range gifts {
+		prices[require.Gift.ID] = require.Gift.Stars
+		// A gift may be split into several entries, e.g. one per receiver type
+		if summary, ok := summaries[require.Gift.ID]; ok {
+			summary.Requested += require.CountForBuy
+			continue
+		}
 		summaries[require.Gift.ID] = &giftTypes.GiftSummary{
 			GiftID:    require.Gift.ID,
 			Requested: require.CountForBuy,
@@ -37,10 +75,19 @@ func (gm *GiftBuyerMonitoringImpl) MonitorProcess(ctx context.Context, resultsCh
 		}
 	}
 
+	var progressCh <-chan time.Time
+	if gm.progressInterval > 0 {
+		progressTicker := time.NewTicker(gm.progressInterval)
+		defer progressTicker.Stop()
+		progressCh = progressTicker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-progressCh:
+			gm.sendProgress(ctx, summaries)
 		case <-doneChan:
 			mostFrequentError := gm.getMostFrequentError(errorCounts)
 			gm.sendNotify(ctx, summaries, mostFrequentError)
@@ -50,7 +97,13 @@ func (gm *GiftBuyerMonitoringImpl) MonitorProcess(ctx context.Context, resultsCh
 				return
 			}
 
-			if result.Success {
+			for _, recorder := range gm.recorders {
+				recorder.RecordResult(result, prices[result.GiftID])
+			}
+			if result.Success && result.Simulated {
+				summaries[result.GiftID].Simulated++
+				gm.infoLogsWriter.LogInfo(fmt.Sprintf("Simulated purchase of gift %d (dry run)", result.GiftID))
+			} else if result.Success {
 				summaries[result.GiftID].Success++
 				gm.infoLogsWriter.LogInfo(fmt.Sprintf("Successfully purchased gift %d", result.GiftID))
 			} else if result.Err != nil {
@@ -62,48 +115,154 @@ func (gm *GiftBuyerMonitoringImpl) MonitorProcess(ctx context.Context, resultsCh
 	}
 }
 
+// SetProgressInterval enables interim progress notifications sent every interval
+// while a batch is being bought. A non-positive interval disables them.
+func (gm *GiftBuyerMonitoringImpl) SetProgressInterval(interval time.Duration) {
+	gm.progressInterval = interval
+}
+
+// SetSessionStats makes every batch add its results to the session stats.
+func (gm *GiftBuyerMonitoringImpl) SetSessionStats(stats *SessionStats) {
+	gm.AddResultRecorder(stats)
+}
+
+// AddResultRecorder makes every batch hand the result of each purchase attempt to the recorder.
+func (gm *GiftBuyerMonitoringImpl) AddResultRecorder(recorder ResultRecorder) {
+	gm.recorders = append(gm.recorders, recorder)
+}
+
+// SetSuccessThreshold sets the percentage of requested gifts a batch must buy to count as
+// successful. The batch summary reports whether the threshold was met.
+//
+// Parameters:
+//   - threshold: required success percentage (0-100), 0 disables the check
+//   - action: reaction to a missed threshold, ThresholdActionAlert, ThresholdActionStop or empty for none
+//   - onMissed: called to stop buying when action is ThresholdActionStop
+func (gm *GiftBuyerMonitoringImpl) SetSuccessThreshold(threshold float64, action string, onMissed func()) {
+	gm.successThreshold = threshold
+	gm.thresholdAction = action
+	gm.onThresholdMissed = onMissed
+}
+
+// thresholdResult checks the batch against the success threshold.
+//
+// Returns:
+//   - string: summary line describing the result, empty when the check is disabled
+//   - bool: true if the batch met the threshold or the check is disabled
+func (gm *GiftBuyerMonitoringImpl) thresholdResult(bought, requested int64) (string, bool) {
+	if gm.successThreshold <= 0 || requested <= 0 {
+		return "", true
+	}
+
+	rate := float64(bought) / float64(requested) * 100
+	if rate >= gm.successThreshold {
+		return fmt.Sprintf("🎯 Порог успеха %.0f%% пройден (%.0f%%)", gm.successThreshold, rate), true
+	}
+	return fmt.Sprintf("🎯 Порог успеха %.0f%% не пройден (%.0f%%)", gm.successThreshold, rate), false
+}
+
+// handleThresholdMissed runs the configured reaction to a batch missing the success threshold.
+func (gm *GiftBuyerMonitoringImpl) handleThresholdMissed(ctx context.Context, bought, requested int64) {
+	gm.errorLogsWriter.LogError(fmt.Sprintf("Batch missed the success threshold of %.0f%%: %d/%d gifts bought",
+		gm.successThreshold, bought, requested))
+
+	switch gm.thresholdAction {
+	case ThresholdActionAlert, ThresholdActionStop:
+	default:
+		return
+	}
+
+	err := errors.Wrap(errors.ErrBatchBelowThreshold,
+		fmt.Sprintf("%d/%d gifts bought, %.0f%% required", bought, requested, gm.successThreshold))
+	if gm.thresholdAction == ThresholdActionStop && gm.onThresholdMissed != nil {
+		gm.onThresholdMissed()
+		err = errors.Wrap(err, "buying paused")
+	}
+	if gm.notification.SetBot() {
+		if notifErr := gm.notification.SendErrorNotification(ctx, err); notifErr != nil {
+			gm.errorLogsWriter.LogError(notifErr.Error())
+		}
+	}
+}
+
+// sendProgress reports how many gifts of the running batch are already bought.
+func (gm *GiftBuyerMonitoringImpl) sendProgress(ctx context.Context, summaries map[int64]*giftTypes.GiftSummary) {
+	totalSuccess := int64(0)
+	totalRequested := int64(0)
+
+	for _, summary := range summaries {
+		totalSuccess += summary.Success + summary.Simulated
+		totalRequested += summary.Requested
+	}
+
+	if gm.notification.SetBot() {
+		gm.notification.SendBuyStatus(ctx,
+			fmt.Sprintf("⏳ В процессе: %d/%d подарков куплено", totalSuccess, totalRequested), nil)
+		return
+	}
+	gm.infoLogsWriter.LogInfo(fmt.Sprintf("⏳ In progress: %d/%d gifts bought", totalSuccess, totalRequested))
+}
+
 func (gm *GiftBuyerMonitoringImpl) getMostFrequentError(errorCounts map[string]int64) error {
 	if len(errorCounts) == 0 {
 		return nil
 	}
 
+	return errors.New(mostFrequent(errorCounts))
+}
+
+// mostFrequent returns the most frequent error message, ties go to the first message
+// in lexical order so the result doesn't depend on the map order.
+func mostFrequent(errorCounts map[string]int64) string {
 	var mostFrequentError string
 	var maxCount int64
 
 	for errorMsg, count := range errorCounts {
-		if count > maxCount {
+		if count > maxCount || (count == maxCount && errorMsg < mostFrequentError) {
 			maxCount = count
 			mostFrequentError = errorMsg
 		}
 	}
 
-	return errors.New(mostFrequentError)
+	return mostFrequentError
 }
 
 func (gm *GiftBuyerMonitoringImpl) sendNotify(ctx context.Context, summaries map[int64]*giftTypes.GiftSummary, mostFrequentError error) {
 	totalSuccess := int64(0)
+	totalSimulated := int64(0)
 	totalRequested := int64(0)
 
 	for _, summary := range summaries {
 		totalSuccess += summary.Success
+		totalSimulated += summary.Simulated
 		totalRequested += summary.Requested
 	}
 
+	thresholdNote, passed := gm.thresholdResult(totalSuccess+totalSimulated, totalRequested)
+	if !passed {
+		defer gm.handleThresholdMissed(ctx, totalSuccess+totalSimulated, totalRequested)
+	}
+
+	if totalSimulated > 0 {
+		gm.sendSimulatedNotify(ctx, summaries, totalSuccess, totalSimulated, totalRequested, thresholdNote)
+		return
+	}
+
 	if gm.notification.SetBot() {
 		if totalSuccess == totalRequested {
 			gm.notification.SendBuyStatus(ctx,
-				fmt.Sprintf("✅ Успешно куплено %d подарков", totalSuccess), nil)
+				withNote(fmt.Sprintf("✅ Успешно куплено %d подарков", totalSuccess), thresholdNote), nil)
 		} else if totalSuccess > 0 {
 			message := fmt.Sprintf("⚠️ Частично выполнено: %d/%d подарков куплено",
 				totalSuccess, totalRequested)
-			gm.notification.SendBuyStatus(ctx, message, nil)
+			gm.notification.SendBuyStatus(ctx, withNote(message, thresholdNote), nil)
 		} else {
 			message := fmt.Sprintf("❌ Не удалось купить ни одного подарка из %d", totalRequested)
 			errorToSend := mostFrequentError
 			if errorToSend == nil {
 				errorToSend = errors.New("все покупки неудачны")
 			}
-			gm.notification.SendBuyStatus(ctx, message, errorToSend)
+			gm.notification.SendBuyStatus(ctx, withNote(message, thresholdNote), errorToSend)
 		}
 	} else {
 		if totalSuccess == totalRequested {
@@ -113,6 +272,9 @@ func (gm *GiftBuyerMonitoringImpl) sendNotify(ctx context.Context, summaries map
 		} else {
 			gm.errorLogsWriter.LogError(fmt.Sprintf("❌ Failed to buy any gifts out of %d requested", totalRequested))
 		}
+		if thresholdNote != "" {
+			gm.infoLogsWriter.LogInfo(thresholdNote)
+		}
 
 		for _, summary := range summaries {
 			if summary.Success > 0 {
@@ -128,3 +290,30 @@ func (gm *GiftBuyerMonitoringImpl) sendNotify(ctx context.Context, summaries map
 		}
 	}
 }
+
+// sendSimulatedNotify reports a batch with dry run purchases, keeping simulated and real
+// purchases apart so a dry run is never mistaken for spent stars.
+func (gm *GiftBuyerMonitoringImpl) sendSimulatedNotify(ctx context.Context, summaries map[int64]*giftTypes.GiftSummary, totalSuccess, totalSimulated, totalRequested int64, thresholdNote string) {
+	if gm.notification.SetBot() {
+		gm.notification.SendBuyStatus(ctx,
+			withNote(fmt.Sprintf("🧪 Пробный режим: %d/%d подарков куплено бы, реально куплено %d", totalSimulated, totalRequested, totalSuccess), thresholdNote), nil)
+		return
+	}
+
+	gm.infoLogsWriter.LogInfo(fmt.Sprintf("🧪 Dry run: %d/%d gifts simulated, %d really bought", totalSimulated, totalRequested, totalSuccess))
+	if thresholdNote != "" {
+		gm.infoLogsWriter.LogInfo(thresholdNote)
+	}
+	for _, summary := range summaries {
+		gm.infoLogsWriter.LogInfo(fmt.Sprintf("Simulated %d/%d x gift %d (really bought %d)",
+			summary.Simulated, summary.Requested, summary.GiftID, summary.Success))
+	}
+}
+
+// withNote appends a note on a new line when it is not empty.
+func withNote(message, note string) string {
+	if note == "" {
+		return message
+	}
+	return message + "\n" + note
+}
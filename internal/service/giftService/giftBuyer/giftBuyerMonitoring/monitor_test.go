@@ -2,11 +2,13 @@ package giftBuyerMonitoring
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"gift-buyer/internal/infrastructure/logsWriter/logTypes"
 	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
 
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
@@ -43,6 +45,23 @@ func (m *MockNotificationService) SendUpdateNotification(ctx context.Context, ve
 	return args.Error(0)
 }
 
+func (m *MockNotificationService) SendStartupSnapshot(ctx context.Context, available, matching int) error {
+	args := m.Called(ctx, available, matching)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) SendLimitReachedNotification(ctx context.Context, limit string, max int64) error {
+	args := m.Called(ctx, limit, max)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) SendSoldOutNotification(ctx context.Context, gift *tg.StarGift) error {
+	args := m.Called(ctx, gift)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) Close() {}
+
 // MockLogsWriter для тестирования
 type MockLogsWriter struct{}
 
@@ -352,3 +371,197 @@ func TestGiftBuyerMonitoringImpl_GetMostFrequentError(t *testing.T) {
 		assert.Equal(t, "single error", err.Error())
 	})
 }
+
+func TestGiftBuyerMonitoringImpl_ProgressNotifications(t *testing.T) {
+	t.Run("промежуточный прогресс во время долгой покупки", func(t *testing.T) {
+		mockNotification := &MockNotificationService{}
+		monitor := NewGiftBuyerMonitoring(nil, mockNotification, &MockLogsWriter{}, &MockLogsWriter{})
+		monitor.SetProgressInterval(20 * time.Millisecond)
+
+		gifts := []*giftTypes.GiftRequire{
+			{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1}},
+		}
+
+		progress := make(chan string, 100)
+		isProgress := func(status string) bool { return strings.HasPrefix(status, "⏳") }
+		mockNotification.On("SetBot").Return(true)
+		mockNotification.On("SendBuyStatus", mock.Anything, mock.MatchedBy(isProgress), nil).Run(func(args mock.Arguments) {
+			progress <- args.String(1)
+		}).Return(nil)
+		mockNotification.On("SendBuyStatus", mock.Anything, mock.MatchedBy(func(status string) bool { return !isProgress(status) }), mock.Anything).Return(nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		resultsCh := make(chan giftTypes.GiftResult)
+		doneChan := make(chan struct{})
+		finished := make(chan struct{})
+		go func() {
+			monitor.MonitorProcess(ctx, resultsCh, doneChan, gifts)
+			close(finished)
+		}()
+
+		// Slow batch: one purchase, then a pause longer than the progress interval
+		resultsCh <- giftTypes.GiftResult{GiftID: 1, Success: true}
+
+		select {
+		case status := <-progress:
+			assert.Contains(t, status, "1/3")
+		case <-time.After(time.Second):
+			t.Fatal("progress notification was not sent")
+		}
+
+		resultsCh <- giftTypes.GiftResult{GiftID: 1, Success: true}
+		resultsCh <- giftTypes.GiftResult{GiftID: 1, Success: true}
+		close(doneChan)
+		<-finished
+
+		mockNotification.AssertCalled(t, "SendBuyStatus", mock.Anything, "✅ Успешно куплено 3 подарков", nil)
+	})
+
+	t.Run("без интервала прогресс не отправляется", func(t *testing.T) {
+		mockNotification := &MockNotificationService{}
+		monitor := NewGiftBuyerMonitoring(nil, mockNotification, &MockLogsWriter{}, &MockLogsWriter{})
+
+		gifts := []*giftTypes.GiftRequire{
+			{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}},
+		}
+
+		mockNotification.On("SetBot").Return(true)
+		mockNotification.On("SendBuyStatus", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return(nil)
+
+		resultsCh := make(chan giftTypes.GiftResult)
+		doneChan := make(chan struct{})
+		finished := make(chan struct{})
+		go func() {
+			monitor.MonitorProcess(context.Background(), resultsCh, doneChan, gifts)
+			close(finished)
+		}()
+
+		resultsCh <- giftTypes.GiftResult{GiftID: 1, Success: true}
+		time.Sleep(50 * time.Millisecond)
+		close(doneChan)
+		<-finished
+
+		mockNotification.AssertNumberOfCalls(t, "SendBuyStatus", 1)
+	})
+}
+
+func TestGiftBuyerMonitoringImpl_SimulatedPurchases(t *testing.T) {
+	mockNotification := &MockNotificationService{}
+	monitor := NewGiftBuyerMonitoring(nil, mockNotification, &MockLogsWriter{}, &MockLogsWriter{})
+
+	gifts := []*giftTypes.GiftRequire{
+		{Gift: createTestGift(1, 100), CountForBuy: 3, ReceiverType: []int{1}},
+	}
+
+	var status string
+	mockNotification.On("SetBot").Return(true)
+	mockNotification.On("SendBuyStatus", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Run(func(args mock.Arguments) { status = args.String(1) }).
+		Return(nil)
+
+	resultsCh := make(chan giftTypes.GiftResult)
+	doneChan := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		monitor.MonitorProcess(context.Background(), resultsCh, doneChan, gifts)
+	}()
+
+	resultsCh <- giftTypes.GiftResult{GiftID: 1, Success: true, Simulated: true}
+	resultsCh <- giftTypes.GiftResult{GiftID: 1, Success: true, Simulated: true}
+	resultsCh <- giftTypes.GiftResult{GiftID: 1, Success: true}
+	close(doneChan)
+	<-finished
+
+	mockNotification.AssertNumberOfCalls(t, "SendBuyStatus", 1)
+	assert.Contains(t, status, "Пробный режим")
+	assert.Contains(t, status, "2/3")
+	assert.Contains(t, status, "реально куплено 1")
+}
+
+func TestGiftBuyerMonitoringImpl_SuccessThreshold(t *testing.T) {
+	runBatch := func(monitor *GiftBuyerMonitoringImpl, requested, bought int64) {
+		gifts := []*giftTypes.GiftRequire{
+			{Gift: createTestGift(1, 100), CountForBuy: requested, ReceiverType: []int{1}},
+		}
+
+		resultsCh := make(chan giftTypes.GiftResult)
+		doneChan := make(chan struct{})
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			monitor.MonitorProcess(context.Background(), resultsCh, doneChan, gifts)
+		}()
+
+		for i := int64(0); i < requested; i++ {
+			if i < bought {
+				resultsCh <- giftTypes.GiftResult{GiftID: 1, Success: true}
+			} else {
+				resultsCh <- giftTypes.GiftResult{GiftID: 1, Err: assert.AnError}
+			}
+		}
+		close(doneChan)
+		<-finished
+	}
+
+	tests := []struct {
+		name        string
+		action      string
+		bought      int64
+		wantNote    string
+		wantAlert   bool
+		wantStopped bool
+	}{
+		{name: "порог пройден", action: ThresholdActionStop, bought: 8, wantNote: "Порог успеха 75% пройден (80%)"},
+		{name: "чуть ниже порога", action: ThresholdActionAlert, bought: 7, wantNote: "Порог успеха 75% не пройден (70%)", wantAlert: true},
+		{name: "порог не пройден без действия", bought: 5, wantNote: "Порог успеха 75% не пройден (50%)"},
+		{name: "порог не пройден с остановкой", action: ThresholdActionStop, bought: 0, wantNote: "Порог успеха 75% не пройден (0%)", wantAlert: true, wantStopped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockNotification := &MockNotificationService{}
+			monitor := NewGiftBuyerMonitoring(nil, mockNotification, &MockLogsWriter{}, &MockLogsWriter{})
+			stopped := false
+			monitor.SetSuccessThreshold(75, tt.action, func() { stopped = true })
+
+			var status string
+			var alert error
+			mockNotification.On("SetBot").Return(true)
+			mockNotification.On("SendBuyStatus", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+				Run(func(args mock.Arguments) { status = args.String(1) }).
+				Return(nil)
+			mockNotification.On("SendErrorNotification", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) { alert = args.Get(1).(error) }).
+				Return(nil)
+
+			runBatch(monitor, 10, tt.bought)
+
+			assert.Contains(t, status, tt.wantNote)
+			assert.Equal(t, tt.wantStopped, stopped)
+			if tt.wantAlert {
+				assert.True(t, errors.Is(alert, errors.ErrBatchBelowThreshold))
+			} else {
+				mockNotification.AssertNotCalled(t, "SendErrorNotification", mock.Anything, mock.Anything)
+			}
+		})
+	}
+
+	t.Run("порог выключен", func(t *testing.T) {
+		mockNotification := &MockNotificationService{}
+		monitor := NewGiftBuyerMonitoring(nil, mockNotification, &MockLogsWriter{}, &MockLogsWriter{})
+
+		var status string
+		mockNotification.On("SetBot").Return(true)
+		mockNotification.On("SendBuyStatus", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+			Run(func(args mock.Arguments) { status = args.String(1) }).
+			Return(nil)
+
+		runBatch(monitor, 4, 1)
+
+		assert.NotContains(t, status, "Порог успеха")
+		mockNotification.AssertNotCalled(t, "SendErrorNotification", mock.Anything, mock.Anything)
+	})
+}
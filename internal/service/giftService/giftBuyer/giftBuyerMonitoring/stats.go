@@ -0,0 +1,144 @@
+package giftBuyerMonitoring
+
+import (
+	"encoding/json"
+	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SessionReport is the machine-readable record of a session written at shutdown.
+type SessionReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// GiftsSeen is the number of new gifts found by the monitor
+	GiftsSeen int64 `json:"gifts_seen"`
+
+	// Bought is the number of bought units per gift ID
+	Bought map[int64]int64 `json:"bought"`
+
+	// TotalBought is the number of bought units of all gifts
+	TotalBought int64 `json:"total_bought"`
+
+	// Simulated is the number of purchases simulated in dry run mode
+	Simulated int64 `json:"simulated"`
+
+	// Failures is the number of failed purchase attempts
+	Failures int64 `json:"failures"`
+
+	// MostFrequentError is the most frequent purchase error, empty without failures
+	MostFrequentError string `json:"most_frequent_error"`
+
+	// StarsSpent is the price of all bought units in stars
+	StarsSpent int64 `json:"stars_spent"`
+}
+
+// SessionStats aggregates the purchase results of all buy cycles of a session.
+// It is safe for concurrent use, batches bought at the same time share it.
+type SessionStats struct {
+	mu          sync.Mutex
+	startedAt   time.Time
+	seen        int64
+	bought      map[int64]int64
+	simulated   int64
+	failures    int64
+	errorCounts map[string]int64
+	starsSpent  int64
+}
+
+// NewSessionStats creates empty session stats starting now.
+func NewSessionStats() *SessionStats {
+	return &SessionStats{
+		startedAt:   time.Now().UTC(),
+		bought:      make(map[int64]int64),
+		errorCounts: make(map[string]int64),
+	}
+}
+
+// RecordSeen counts new gifts found by the monitor.
+func (s *SessionStats) RecordSeen(count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seen += int64(count)
+}
+
+// RecordResult counts a purchase result.
+//
+// Parameters:
+//   - result: result of a purchase attempt
+//   - stars: price of the gift, counted as spent for real successful purchases
+func (s *SessionStats) RecordResult(result giftTypes.GiftResult, stars int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case result.Success && result.Simulated:
+		s.simulated++
+	case result.Success:
+		s.bought[result.GiftID]++
+		s.starsSpent += stars
+	case result.Err != nil:
+		s.failures++
+		s.errorCounts[result.Err.Error()]++
+	}
+}
+
+// Report returns the stats collected so far.
+func (s *SessionStats) Report() SessionReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := SessionReport{
+		StartedAt:         s.startedAt,
+		FinishedAt:        time.Now().UTC(),
+		GiftsSeen:         s.seen,
+		Bought:            make(map[int64]int64, len(s.bought)),
+		Simulated:         s.simulated,
+		Failures:          s.failures,
+		MostFrequentError: mostFrequent(s.errorCounts),
+		StarsSpent:        s.starsSpent,
+	}
+	for id, count := range s.bought {
+		report.Bought[id] = count
+		report.TotalBought += count
+	}
+	return report
+}
+
+// WriteReport writes the report to path through a temporary file, so a crash during
+// the write never leaves a truncated report.
+//
+// Parameters:
+//   - path: report file, replaced if it exists
+//
+// Returns:
+//   - error: marshal or write error
+func (s *SessionStats) WriteReport(path string) error {
+	data, err := json.MarshalIndent(s.Report(), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal session report")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to write session report")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to write session report")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to write session report")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "failed to write session report")
+	}
+	return nil
+}
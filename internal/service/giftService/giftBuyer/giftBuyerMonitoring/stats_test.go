@@ -0,0 +1,148 @@
+package giftBuyerMonitoring
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func readReport(t *testing.T, path string) SessionReport {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var report SessionReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	return report
+}
+
+func TestSessionStats(t *testing.T) {
+	t.Run("итоги по смешанным результатам", func(t *testing.T) {
+		stats := NewSessionStats()
+		stats.RecordSeen(3)
+		stats.RecordSeen(2)
+
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 1, Success: true}, 100)
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 1, Success: true}, 100)
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 2, Success: true}, 250)
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 2, Success: false, Err: errors.New("balance too low")}, 250)
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 3, Success: false, Err: errors.New("balance too low")}, 50)
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 3, Success: false, Err: errors.New("flood wait")}, 50)
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 4, Success: true, Simulated: true}, 500)
+
+		report := stats.Report()
+
+		assert.Equal(t, int64(5), report.GiftsSeen)
+		assert.Equal(t, map[int64]int64{1: 2, 2: 1}, report.Bought)
+		assert.Equal(t, int64(3), report.TotalBought)
+		assert.Equal(t, int64(1), report.Simulated)
+		assert.Equal(t, int64(3), report.Failures)
+		assert.Equal(t, "balance too low", report.MostFrequentError)
+		// Simulated purchases don't spend stars
+		assert.Equal(t, int64(450), report.StarsSpent)
+	})
+
+	t.Run("пустая сессия", func(t *testing.T) {
+		report := NewSessionStats().Report()
+
+		assert.Empty(t, report.Bought)
+		assert.Zero(t, report.Failures)
+		assert.Empty(t, report.MostFrequentError)
+		assert.Zero(t, report.StarsSpent)
+	})
+
+	t.Run("отчет записывается в файл", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session_report.json")
+		stats := NewSessionStats()
+		stats.RecordSeen(1)
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 7, Success: true}, 15)
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 7, Success: false, Err: errors.New("flood wait")}, 15)
+
+		require.NoError(t, stats.WriteReport(path))
+
+		report := readReport(t, path)
+		assert.Equal(t, int64(1), report.GiftsSeen)
+		assert.Equal(t, map[int64]int64{7: 1}, report.Bought)
+		assert.Equal(t, int64(1), report.Failures)
+		assert.Equal(t, "flood wait", report.MostFrequentError)
+		assert.Equal(t, int64(15), report.StarsSpent)
+		assert.False(t, report.FinishedAt.Before(report.StartedAt))
+	})
+
+	t.Run("ошибка записи в отсутствующий каталог", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing", "session_report.json")
+
+		assert.Error(t, NewSessionStats().WriteReport(path))
+	})
+}
+
+// priceRecorder records the price of every purchase attempt it receives
+type priceRecorder struct {
+	prices []int64
+}
+
+func (r *priceRecorder) RecordResult(result giftTypes.GiftResult, stars int64) {
+	r.prices = append(r.prices, stars)
+}
+
+func TestGiftBuyerMonitoringImpl_SessionStats(t *testing.T) {
+	mockNotification := &MockNotificationService{}
+	mockNotification.On("SetBot").Return(false)
+	monitor := NewGiftBuyerMonitoring(nil, mockNotification, &MockLogsWriter{}, &MockLogsWriter{})
+	stats := NewSessionStats()
+	monitor.SetSessionStats(stats)
+	recorder := &priceRecorder{}
+	monitor.AddResultRecorder(recorder)
+
+	buy := func(gifts []*giftTypes.GiftRequire, results ...giftTypes.GiftResult) {
+		resultsCh := make(chan giftTypes.GiftResult)
+		doneChan := make(chan struct{})
+		finished := make(chan struct{})
+		go func() {
+			monitor.MonitorProcess(context.Background(), resultsCh, doneChan, gifts)
+			close(finished)
+		}()
+
+		for _, result := range results {
+			resultsCh <- result
+		}
+		close(doneChan)
+		select {
+		case <-finished:
+		case <-time.After(time.Second):
+			t.Fatal("monitor didn't finish the batch")
+		}
+	}
+
+	// Two buy cycles add up in the same stats
+	buy([]*giftTypes.GiftRequire{{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{1}}},
+		giftTypes.GiftResult{GiftID: 1, Success: true},
+		giftTypes.GiftResult{GiftID: 1, Success: false, Err: errors.New("flood wait")},
+	)
+	buy([]*giftTypes.GiftRequire{
+		{Gift: createTestGift(1, 100), CountForBuy: 1, ReceiverType: []int{1}},
+		{Gift: createTestGift(2, 300), CountForBuy: 1, ReceiverType: []int{1}},
+	},
+		giftTypes.GiftResult{GiftID: 1, Success: true},
+		giftTypes.GiftResult{GiftID: 2, Success: true},
+	)
+
+	report := stats.Report()
+	assert.Equal(t, map[int64]int64{1: 2, 2: 1}, report.Bought)
+	assert.Equal(t, int64(3), report.TotalBought)
+	assert.Equal(t, int64(1), report.Failures)
+	assert.Equal(t, int64(500), report.StarsSpent)
+	assert.Equal(t, []int64{100, 100, 100, 300}, recorder.prices, "every recorder gets every result")
+	mockNotification.AssertCalled(t, "SetBot")
+	mockNotification.AssertNotCalled(t, "SendBuyStatus", mock.Anything, mock.Anything, mock.Anything)
+}
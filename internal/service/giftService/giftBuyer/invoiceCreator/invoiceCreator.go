@@ -3,10 +3,14 @@ package invoiceCreator
 import (
 	"context"
 	"fmt"
+	"gift-buyer/internal/service/giftService/giftBuyer/atomicCounter"
 	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/internal/service/giftService/giftTypes"
 	"gift-buyer/pkg/errors"
 	"gift-buyer/pkg/utils"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,13 +20,31 @@ import (
 type InvoiceCreatorImpl struct {
 	userReceiver, channelReceiver []string
 	idCache                       giftInterfaces.UserCache
+
+	// rotateReceivers spreads the units of a gift batch evenly across receivers
+	// instead of picking a random receiver for every invoice
+	rotateReceivers bool
+
+	// maxPerReceiver caps the invoices for the same gift per receiver, 0 means unlimited.
+	// received counts the reserved invoices by gift and receiver, failed purchases give theirs
+	// back. It is shared by the invoice creators of all accounts
+	maxPerReceiver int64
+	received       giftInterfaces.CapCounter
+
+	// peers caches the resolved receiver peers filled by Warm, prepared marks the gifts
+	// whose invoices are built from the cached peers without receiver lookups
+	peers    map[receiverTarget]tg.InputPeerClass
+	prepared map[int64]bool
+	warmMu   sync.RWMutex
 }
 
-func NewInvoiceCreator(userReceiver, channelReceiver []string, idCache giftInterfaces.UserCache) *InvoiceCreatorImpl {
+func NewInvoiceCreator(userReceiver, channelReceiver []string, idCache giftInterfaces.UserCache, rotateReceivers bool) *InvoiceCreatorImpl {
 	return &InvoiceCreatorImpl{
 		userReceiver:    userReceiver,
 		channelReceiver: channelReceiver,
 		idCache:         idCache,
+		rotateReceivers: rotateReceivers,
+		received:        atomicCounter.NewCapCounter(),
 	}
 }
 
@@ -35,33 +57,372 @@ func NewInvoiceCreator(userReceiver, channelReceiver []string, idCache giftInter
 //   - 1: User (specified by user ID)
 //   - 2: Channel (specified by channel ID with access hash)
 //
+// When the gift has a weighted receiver distribution, consecutive units of the batch
+// are spread across its receivers proportionally to their weights.
+//
+// When receiver rotation is enabled, consecutive units of the same batch walk
+// through every receiver type and receiver in turn, so the units of a gift are
+// spread evenly instead of relying on random selection.
+//
+// The receiver of a unit follows from the receiver slot bound to ctx with
+// giftTypes.WithReceiverSlot, so every retry of the unit goes to the same receiver.
+//
 // Parameters:
+//   - ctx: context of the purchased unit
 //   - gift: the star gift to create an invoice for
 //
 // Returns:
 //   - *tg.InputInvoiceStarGift: configured invoice for the gift purchase
 //   - error: invoice creation error or unsupported receiver type
-func (ic *InvoiceCreatorImpl) CreateInvoice(gift *giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error) {
-	randReceiverType := utils.SelectRandomElementFast(gift.ReceiverType)
+func (ic *InvoiceCreatorImpl) CreateInvoice(ctx context.Context, gift *giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error) {
+	target, err := ic.selectTarget(ctx, gift)
+	if err != nil {
+		return nil, err
+	}
 
-	switch randReceiverType {
+	if ic.maxPerReceiver > 0 {
+		if target, err = ic.reserve(ctx, gift, target); err != nil {
+			return nil, err
+		}
+	}
+
+	if peer, ok := ic.preparedPeer(gift.Gift.ID, target); ok {
+		return ic.preparedPurchase(gift, target, peer), nil
+	}
+
+	switch target.receiverType {
 	case 0:
 		return ic.selfPurchase(gift)
 	case 1:
-		return ic.userPurchase(gift)
+		return ic.userPurchase(gift, target.receiver)
+	default:
+		return ic.channelPurchase(gift, target.receiver)
+	}
+}
+
+// selectTarget picks the receiver of the next invoice: the next receiver of a broadcast
+// batch, the next receiver of a weighted distribution, the next receiver in rotation,
+// or a random receiver.
+func (ic *InvoiceCreatorImpl) selectTarget(ctx context.Context, gift *giftTypes.GiftRequire) (receiverTarget, error) {
+	if gift.BroadcastToAllReceivers {
+		return ic.broadcastTarget(ctx, gift)
+	}
+	if target, ok := ic.weightedTarget(ctx, gift); ok {
+		return target, nil
+	}
+
+	slot := ic.nextSlot(ctx, gift)
+	receiverType := ic.selectReceiverType(gift.ReceiverType, slot)
+
+	switch receiverType {
+	case 0:
+		return receiverTarget{receiverType: 0}, nil
+	case 1:
+		return receiverTarget{receiverType: 1, receiver: ic.selectReceiver(ic.userReceiver, len(gift.ReceiverType), slot)}, nil
 	case 2:
-		return ic.channelPurchase(gift)
+		return receiverTarget{receiverType: 2, receiver: ic.selectReceiver(ic.channelReceiver, len(gift.ReceiverType), slot)}, nil
+	default:
+		return receiverTarget{}, errors.Wrap(errors.New("unexpected receiver type"),
+			fmt.Sprintf("unexpected receiver type: %d", receiverType))
+	}
+}
+
+// SetMaxPerReceiver caps how many invoices for the same gift a single receiver gets.
+// When the selected receiver is capped another receiver of the batch is used, and when
+// every receiver is capped CreateInvoice returns errors.ErrAllReceiversSaturated.
+//
+// Parameters:
+//   - max: maximum number of units of one gift per receiver (0 for unlimited)
+func (ic *InvoiceCreatorImpl) SetMaxPerReceiver(max int64) {
+	ic.maxPerReceiver = max
+}
+
+// SetReceiverCounts sets the per-receiver counts shared with the invoice creators of the
+// other accounts, so that the per-receiver cap holds across all accounts.
+//
+// Parameters:
+//   - counts: shared per-receiver counts
+func (ic *InvoiceCreatorImpl) SetReceiverCounts(counts giftInterfaces.CapCounter) {
+	ic.received = counts
+}
+
+// Warm prepares the purchase path of gifts expected to drop. Every configured receiver
+// is resolved once and its peer is cached, so the invoices of the prepared gifts are
+// built from a ready template without any receiver lookups. Receivers that cannot be
+// resolved are reported and keep the regular lookup path.
+//
+// Parameters:
+//   - giftIDs: IDs of the anticipated gifts
+//
+// Returns:
+//   - error: receivers that could not be resolved, nil if all were cached
+func (ic *InvoiceCreatorImpl) Warm(giftIDs []int64) error {
+	peers := make(map[receiverTarget]tg.InputPeerClass)
+	var failed []string
+	for _, target := range ic.broadcastTargets([]int{0, 1, 2}) {
+		peer, err := ic.resolvePeer(target)
+		if err != nil {
+			failed = append(failed, target.receiver)
+			continue
+		}
+		peers[target] = peer
+	}
+
+	prepared := make(map[int64]bool, len(giftIDs))
+	for _, giftID := range giftIDs {
+		prepared[giftID] = true
+	}
+
+	ic.warmMu.Lock()
+	ic.peers = peers
+	ic.prepared = prepared
+	ic.warmMu.Unlock()
+
+	if len(failed) > 0 {
+		return errors.New(fmt.Sprintf("failed to resolve receivers: %s", strings.Join(failed, ", ")))
+	}
+	return nil
+}
+
+// preparedPeer returns the cached peer of the receiver when the gift was prepared by Warm.
+func (ic *InvoiceCreatorImpl) preparedPeer(giftID int64, target receiverTarget) (tg.InputPeerClass, bool) {
+	ic.warmMu.RLock()
+	defer ic.warmMu.RUnlock()
+
+	if !ic.prepared[giftID] {
+		return nil, false
+	}
+	peer, ok := ic.peers[target]
+	return peer, ok
+}
+
+// resolvePeer looks up the peer of the receiver.
+func (ic *InvoiceCreatorImpl) resolvePeer(target receiverTarget) (tg.InputPeerClass, error) {
+	switch target.receiverType {
+	case 0:
+		return &tg.InputPeerSelf{}, nil
+	case 1:
+		userInfo, err := ic.getUserInfo(context.Background(), target.receiver)
+		if err != nil {
+			return nil, err
+		}
+		return &tg.InputPeerUser{UserID: userInfo.ID, AccessHash: userInfo.AccessHash}, nil
 	default:
-		return nil, errors.Wrap(errors.New("unexpected receiver type"),
-			fmt.Sprintf("unexpected receiver type: %d", randReceiverType))
+		channelInfo, err := ic.getChannelInfo(context.Background(), target.receiver)
+		if err != nil {
+			return nil, err
+		}
+		return &tg.InputPeerChannel{
+			ChannelID:  ic.convertChannelID(channelInfo.ID),
+			AccessHash: channelInfo.AccessHash,
+		}, nil
+	}
+}
+
+// reserve takes one unit of the receiver's cap for the gift, falling back to the first
+// receiver of the batch that still has room. The reserved receiver is recorded in the
+// reservation of the attempt, see ReleaseReservation.
+func (ic *InvoiceCreatorImpl) reserve(ctx context.Context, gift *giftTypes.GiftRequire, target receiverTarget) (receiverTarget, error) {
+	candidates := append([]receiverTarget{target}, ic.broadcastTargets(gift.ReceiverType)...)
+	for _, candidate := range candidates {
+		key := receivedKey(gift.Gift.ID, candidate)
+		if !ic.received.TryReserve(key, ic.maxPerReceiver) {
+			continue
+		}
+		if reservation := giftTypes.AttemptReservation(ctx); reservation != nil {
+			reservation.Receiver = key
+		}
+		return candidate, nil
+	}
+
+	return receiverTarget{}, errors.ErrAllReceiversSaturated
+}
+
+// ReleaseReservation gives back the per-receiver cap unit reserved by the invoice of a
+// failed purchase attempt, so that the receiver can still get the gift.
+//
+// Parameters:
+//   - ctx: context of the attempt created with giftTypes.WithReservation
+func (ic *InvoiceCreatorImpl) ReleaseReservation(ctx context.Context) {
+	reservation := giftTypes.AttemptReservation(ctx)
+	if reservation == nil || reservation.Receiver == "" {
+		return
+	}
+
+	ic.received.Release(reservation.Receiver)
+	reservation.Receiver = ""
+}
+
+// receivedKey identifies the invoices of the gift for the receiver.
+func receivedKey(giftID int64, target receiverTarget) string {
+	return fmt.Sprintf("%d:%d:%s", giftID, target.receiverType, target.receiver)
+}
+
+// receiverTarget is the receiver of a single invoice
+type receiverTarget struct {
+	receiverType int
+	receiver     string
+}
+
+// ReceiverCount returns the number of distinct receivers configured for the receiver types.
+// It is the number of invoices a broadcast batch consists of.
+//
+// Parameters:
+//   - receiverTypes: receiver types of the batch
+//
+// Returns:
+//   - int: number of distinct receivers
+func (ic *InvoiceCreatorImpl) ReceiverCount(receiverTypes []int) int {
+	return len(ic.broadcastTargets(receiverTypes))
+}
+
+// broadcastTarget returns the receiver of the unit in a broadcast batch.
+// Consecutive units of the batch walk through every configured receiver exactly once.
+func (ic *InvoiceCreatorImpl) broadcastTarget(ctx context.Context, gift *giftTypes.GiftRequire) (receiverTarget, error) {
+	targets := ic.broadcastTargets(gift.ReceiverType)
+	if len(targets) == 0 {
+		return receiverTarget{}, errors.Wrap(errors.ErrInvalidParams, "no receivers configured for broadcast")
+	}
+
+	return targets[ic.slot(ctx, gift)%int64(len(targets))], nil
+}
+
+// broadcastTargets lists every receiver of the receiver types in a stable order.
+// Repeated receiver types and unknown types are ignored.
+func (ic *InvoiceCreatorImpl) broadcastTargets(receiverTypes []int) []receiverTarget {
+	var (
+		targets []receiverTarget
+		seen    = make(map[int]bool, len(receiverTypes))
+	)
+
+	for _, receiverType := range receiverTypes {
+		if seen[receiverType] {
+			continue
+		}
+		seen[receiverType] = true
+
+		switch receiverType {
+		case 0:
+			targets = append(targets, receiverTarget{receiverType: 0})
+		case 1:
+			for _, receiver := range ic.userReceiver {
+				targets = append(targets, receiverTarget{receiverType: 1, receiver: receiver})
+			}
+		case 2:
+			for _, receiver := range ic.channelReceiver {
+				targets = append(targets, receiverTarget{receiverType: 2, receiver: receiver})
+			}
+		}
+	}
+
+	return targets
+}
+
+// weightedTarget returns the receiver of the unit in a weighted distribution. Every window
+// of total weight consecutive units gives each receiver exactly its weight of units.
+// Shares of receivers not configured for the gift's receiver types and non-positive
+// weights are ignored; false is returned when no share remains.
+func (ic *InvoiceCreatorImpl) weightedTarget(ctx context.Context, gift *giftTypes.GiftRequire) (receiverTarget, bool) {
+	if len(gift.ReceiverDistribution) == 0 {
+		return receiverTarget{}, false
+	}
+
+	known := make(map[string]receiverTarget)
+	for _, target := range ic.broadcastTargets(gift.ReceiverType) {
+		if target.receiverType != 0 {
+			known[target.receiver] = target
+		}
+	}
+
+	var (
+		targets []receiverTarget
+		weights []int64
+		total   int64
+	)
+	for _, share := range gift.ReceiverDistribution {
+		target, ok := known[share.Receiver]
+		if !ok || share.Weight <= 0 {
+			continue
+		}
+		targets = append(targets, target)
+		weights = append(weights, int64(share.Weight))
+		total += int64(share.Weight)
+	}
+	if total == 0 {
+		return receiverTarget{}, false
+	}
+
+	position := ic.slot(ctx, gift) % total
+	for i, weight := range weights {
+		if position < weight {
+			return targets[i], true
+		}
+		position -= weight
+	}
+	return targets[len(targets)-1], true
+}
+
+// nextSlot returns the rotation slot of the unit in the batch,
+// or -1 when receiver rotation is disabled.
+func (ic *InvoiceCreatorImpl) nextSlot(ctx context.Context, gift *giftTypes.GiftRequire) int64 {
+	if !ic.rotateReceivers {
+		return -1
+	}
+	return ic.slot(ctx, gift)
+}
+
+// slot returns the receiver slot bound to the unit, or the next slot of the batch for
+// an invoice created outside of a unit.
+func (ic *InvoiceCreatorImpl) slot(ctx context.Context, gift *giftTypes.GiftRequire) int64 {
+	if slot, ok := giftTypes.ReceiverSlot(ctx); ok {
+		return slot
+	}
+	return atomic.AddInt64(&gift.ReceiverCursor, 1) - 1
+}
+
+// selectReceiverType picks the receiver type for the slot, falling back to random selection.
+func (ic *InvoiceCreatorImpl) selectReceiverType(receiverTypes []int, slot int64) int {
+	if slot < 0 || len(receiverTypes) == 0 {
+		return utils.SelectRandomElementFast(receiverTypes)
+	}
+	return receiverTypes[slot%int64(len(receiverTypes))]
+}
+
+// selectReceiver picks the receiver for the slot, falling back to random selection.
+// The slot is divided by the number of receiver types so that every type gets
+// its own round-robin over its receivers.
+func (ic *InvoiceCreatorImpl) selectReceiver(receivers []string, typesCount int, slot int64) string {
+	if slot < 0 || len(receivers) == 0 {
+		return utils.SelectRandomElementFast(receivers)
+	}
+	if typesCount < 1 {
+		typesCount = 1
+	}
+	return receivers[(slot/int64(typesCount))%int64(len(receivers))]
+}
+
+// preparedPurchase fills the invoice template of a prepared gift with the cached peer.
+func (ic *InvoiceCreatorImpl) preparedPurchase(gift *giftTypes.GiftRequire, target receiverTarget, peer tg.InputPeerClass) *tg.InputInvoiceStarGift {
+	text := fmt.Sprintf("By @earnfame %s_%d_%s", utils.RandString5(10), time.Now().UnixNano(), uuid.New().String()[:6])
+	if target.receiverType == 2 {
+		text = fmt.Sprintf("By @earnfame %s_%d", utils.RandString5(10), time.Now().UnixNano())
+	}
+
+	return &tg.InputInvoiceStarGift{
+		Peer:           peer,
+		GiftID:         gift.Gift.ID,
+		HideName:       gift.Hide,
+		IncludeUpgrade: includeUpgrade(gift),
+		Message:        tg.TextWithEntities{Text: text},
 	}
 }
 
 func (ic *InvoiceCreatorImpl) selfPurchase(gift *giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error) {
 	invoice := &tg.InputInvoiceStarGift{
-		Peer:     &tg.InputPeerSelf{},
-		GiftID:   gift.Gift.ID,
-		HideName: gift.Hide,
+		Peer:           &tg.InputPeerSelf{},
+		GiftID:         gift.Gift.ID,
+		HideName:       gift.Hide,
+		IncludeUpgrade: includeUpgrade(gift),
 		Message: tg.TextWithEntities{
 			Text: fmt.Sprintf("By @earnfame %s_%d_%s", utils.RandString5(10), time.Now().UnixNano(), uuid.New().String()[:6]),
 		},
@@ -69,16 +430,17 @@ func (ic *InvoiceCreatorImpl) selfPurchase(gift *giftTypes.GiftRequire) (*tg.Inp
 	return invoice, nil
 }
 
-func (ic *InvoiceCreatorImpl) userPurchase(gift *giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error) {
-	userInfo, err := ic.getUserInfo(context.Background(), utils.SelectRandomElementFast(ic.userReceiver))
+func (ic *InvoiceCreatorImpl) userPurchase(gift *giftTypes.GiftRequire, receiver string) (*tg.InputInvoiceStarGift, error) {
+	userInfo, err := ic.getUserInfo(context.Background(), receiver)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot create invoice without user access hash")
 	}
 
 	invoice := &tg.InputInvoiceStarGift{
-		Peer:     &tg.InputPeerUser{UserID: userInfo.ID, AccessHash: userInfo.AccessHash},
-		GiftID:   gift.Gift.ID,
-		HideName: gift.Hide,
+		Peer:           &tg.InputPeerUser{UserID: userInfo.ID, AccessHash: userInfo.AccessHash},
+		GiftID:         gift.Gift.ID,
+		HideName:       gift.Hide,
+		IncludeUpgrade: includeUpgrade(gift),
 		Message: tg.TextWithEntities{
 			Text: fmt.Sprintf("By @earnfame %s_%d_%s", utils.RandString5(10), time.Now().UnixNano(), uuid.New().String()[:6]),
 		},
@@ -86,8 +448,8 @@ func (ic *InvoiceCreatorImpl) userPurchase(gift *giftTypes.GiftRequire) (*tg.Inp
 	return invoice, nil
 }
 
-func (ic *InvoiceCreatorImpl) channelPurchase(gift *giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error) {
-	channelInfo, err := ic.getChannelInfo(context.Background(), utils.SelectRandomElementFast(ic.channelReceiver))
+func (ic *InvoiceCreatorImpl) channelPurchase(gift *giftTypes.GiftRequire, receiver string) (*tg.InputInvoiceStarGift, error) {
+	channelInfo, err := ic.getChannelInfo(context.Background(), receiver)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot create invoice without channel access hash")
 	}
@@ -97,8 +459,9 @@ func (ic *InvoiceCreatorImpl) channelPurchase(gift *giftTypes.GiftRequire) (*tg.
 			ChannelID:  ic.convertChannelID(channelInfo.ID),
 			AccessHash: channelInfo.AccessHash,
 		},
-		GiftID:   gift.Gift.ID,
-		HideName: gift.Hide,
+		GiftID:         gift.Gift.ID,
+		HideName:       gift.Hide,
+		IncludeUpgrade: includeUpgrade(gift),
 		Message: tg.TextWithEntities{
 			Text: fmt.Sprintf("By @earnfame %s_%d", utils.RandString5(10), time.Now().UnixNano()),
 		},
@@ -106,6 +469,16 @@ func (ic *InvoiceCreatorImpl) channelPurchase(gift *giftTypes.GiftRequire) (*tg.
 	return invoice, nil
 }
 
+// includeUpgrade reports whether the invoice pays the upgrade of the gift: the criteria
+// requested it and the gift can be upgraded.
+func includeUpgrade(gift *giftTypes.GiftRequire) bool {
+	if !gift.Upgrade {
+		return false
+	}
+	upgradeStars, ok := gift.Gift.GetUpgradeStars()
+	return ok && upgradeStars > 0
+}
+
 // getChannelInfo retrieves channel information including access hash for invoice creation.
 // It handles channel ID conversion and fetches the channel details required for
 // creating invoices for channel recipients.
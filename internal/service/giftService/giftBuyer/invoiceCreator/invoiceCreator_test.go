@@ -1,9 +1,12 @@
 package invoiceCreator
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
 
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
@@ -60,7 +63,7 @@ func TestNewInvoiceCreator(t *testing.T) {
 	userReceiver := []string{"123456"}
 	channelReceiver := []string{"789012"}
 
-	creator := NewInvoiceCreator(userReceiver, channelReceiver, mockCache)
+	creator := NewInvoiceCreator(userReceiver, channelReceiver, mockCache, false)
 
 	assert.NotNil(t, creator)
 	assert.Equal(t, userReceiver, creator.userReceiver)
@@ -76,13 +79,14 @@ func TestInvoiceCreatorImpl_CreateInvoice(t *testing.T) {
 			[]string{"123456"},
 			[]string{"789012"},
 			mockCache,
+			false,
 		)
 
 		gift := createTestGift(1, 100)
 		giftRequire := createTestGiftRequire(gift, []int{0})
 
 		// Тестируем создание инвойса для self (type 0)
-		invoice, err := creator.CreateInvoice(giftRequire)
+		invoice, err := creator.CreateInvoice(context.Background(), giftRequire)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, invoice)
@@ -97,7 +101,7 @@ func TestInvoiceCreatorImpl_CreateInvoice(t *testing.T) {
 func TestInvoiceCreatorImpl_SelfPurchase(t *testing.T) {
 	t.Run("создание инвойса для себя", func(t *testing.T) {
 		mockCache := &MockUserCache{}
-		creator := NewInvoiceCreator([]string{}, []string{}, mockCache)
+		creator := NewInvoiceCreator([]string{}, []string{}, mockCache, false)
 
 		gift := createTestGift(1, 100)
 		giftRequire := createTestGiftRequire(gift, []int{0})
@@ -114,3 +118,448 @@ func TestInvoiceCreatorImpl_SelfPurchase(t *testing.T) {
 		assert.True(t, ok)
 	})
 }
+
+func TestInvoiceCreatorImpl_ReceiverRotation(t *testing.T) {
+	t.Run("равномерное распределение по пользователям", func(t *testing.T) {
+		mockCache := &MockUserCache{}
+		receivers := []string{"alice", "bob", "carol"}
+		for i, username := range receivers {
+			mockCache.On("GetUser", username).Return(&tg.User{ID: int64(i + 1), AccessHash: int64(100 + i)}, nil)
+		}
+
+		creator := NewInvoiceCreator(receivers, []string{}, mockCache, true)
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{1})
+		giftRequire.CountForBuy = 6
+
+		perReceiver := make(map[int64]int)
+		for i := int64(0); i < giftRequire.CountForBuy; i++ {
+			invoice, err := creator.CreateInvoice(context.Background(), giftRequire)
+			assert.NoError(t, err)
+
+			peer, ok := invoice.Peer.(*tg.InputPeerUser)
+			assert.True(t, ok)
+			perReceiver[peer.UserID]++
+		}
+
+		assert.Len(t, perReceiver, len(receivers))
+		for userID, count := range perReceiver {
+			assert.Equal(t, 2, count, "user %d", userID)
+		}
+	})
+
+	t.Run("распределение по пользователям и каналам", func(t *testing.T) {
+		mockCache := &MockUserCache{}
+		mockCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+		mockCache.On("GetUser", "bob").Return(&tg.User{ID: 2}, nil)
+		mockCache.On("GetChannel", "news").Return(&tg.Channel{ID: 10}, nil)
+		mockCache.On("GetChannel", "chat").Return(&tg.Channel{ID: 20}, nil)
+
+		creator := NewInvoiceCreator([]string{"alice", "bob"}, []string{"news", "chat"}, mockCache, true)
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{1, 2})
+
+		perReceiver := make(map[string]int)
+		for i := 0; i < 8; i++ {
+			invoice, err := creator.CreateInvoice(context.Background(), giftRequire)
+			assert.NoError(t, err)
+
+			switch peer := invoice.Peer.(type) {
+			case *tg.InputPeerUser:
+				perReceiver[fmt.Sprintf("user_%d", peer.UserID)]++
+			case *tg.InputPeerChannel:
+				perReceiver[fmt.Sprintf("channel_%d", peer.ChannelID)]++
+			default:
+				t.Fatalf("unexpected peer type %T", peer)
+			}
+		}
+
+		assert.Equal(t, map[string]int{
+			"user_1":     2,
+			"user_2":     2,
+			"channel_10": 2,
+			"channel_20": 2,
+		}, perReceiver)
+	})
+
+	t.Run("ротация независима для каждой партии", func(t *testing.T) {
+		mockCache := &MockUserCache{}
+		mockCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+		mockCache.On("GetUser", "bob").Return(&tg.User{ID: 2}, nil)
+
+		creator := NewInvoiceCreator([]string{"alice", "bob"}, []string{}, mockCache, true)
+
+		firstBatch := createTestGiftRequire(createTestGift(1, 100), []int{1})
+		secondBatch := createTestGiftRequire(createTestGift(2, 100), []int{1})
+
+		invoice, err := creator.CreateInvoice(context.Background(), firstBatch)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), invoice.Peer.(*tg.InputPeerUser).UserID)
+
+		invoice, err = creator.CreateInvoice(context.Background(), secondBatch)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), invoice.Peer.(*tg.InputPeerUser).UserID)
+
+		invoice, err = creator.CreateInvoice(context.Background(), firstBatch)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), invoice.Peer.(*tg.InputPeerUser).UserID)
+	})
+}
+
+func TestInvoiceCreatorImpl_ReceiverDistribution(t *testing.T) {
+	newCache := func() *MockUserCache {
+		mockCache := &MockUserCache{}
+		mockCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+		mockCache.On("GetUser", "bob").Return(&tg.User{ID: 2}, nil)
+		mockCache.On("GetUser", "carol").Return(&tg.User{ID: 3}, nil)
+		mockCache.On("GetChannel", "news").Return(&tg.Channel{ID: 10}, nil)
+		return mockCache
+	}
+
+	countPeers := func(t *testing.T, creator *InvoiceCreatorImpl, giftRequire *giftTypes.GiftRequire, n int) map[string]int {
+		perReceiver := make(map[string]int)
+		for i := 0; i < n; i++ {
+			invoice, err := creator.CreateInvoice(context.Background(), giftRequire)
+			if !assert.NoError(t, err) {
+				return perReceiver
+			}
+			switch peer := invoice.Peer.(type) {
+			case *tg.InputPeerUser:
+				perReceiver[fmt.Sprintf("user_%d", peer.UserID)]++
+			case *tg.InputPeerChannel:
+				perReceiver[fmt.Sprintf("channel_%d", peer.ChannelID)]++
+			default:
+				t.Fatalf("unexpected peer type %T", peer)
+			}
+		}
+		return perReceiver
+	}
+
+	t.Run("распределение пропорционально весам", func(t *testing.T) {
+		creator := NewInvoiceCreator([]string{"alice", "bob", "carol"}, []string{"news"}, newCache(), false)
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{1, 2})
+		giftRequire.ReceiverDistribution = []giftTypes.ReceiverShare{
+			{Receiver: "alice", Weight: 3},
+			{Receiver: "bob", Weight: 1},
+			{Receiver: "news", Weight: 2},
+		}
+
+		assert.Equal(t, map[string]int{
+			"user_1":     300,
+			"user_2":     100,
+			"channel_10": 200,
+		}, countPeers(t, creator, giftRequire, 600))
+	})
+
+	t.Run("неизвестные получатели и нулевые веса игнорируются", func(t *testing.T) {
+		creator := NewInvoiceCreator([]string{"alice", "bob"}, []string{"news"}, newCache(), false)
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{1})
+		giftRequire.ReceiverDistribution = []giftTypes.ReceiverShare{
+			{Receiver: "alice", Weight: 1},
+			{Receiver: "bob", Weight: 0},
+			{Receiver: "news", Weight: 5},
+			{Receiver: "mallory", Weight: 5},
+		}
+
+		assert.Equal(t, map[string]int{"user_1": 50}, countPeers(t, creator, giftRequire, 50))
+	})
+
+	t.Run("без подходящих получателей используется случайный выбор", func(t *testing.T) {
+		creator := NewInvoiceCreator([]string{"alice", "bob"}, []string{}, newCache(), false)
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{1})
+		giftRequire.ReceiverDistribution = []giftTypes.ReceiverShare{{Receiver: "mallory", Weight: 1}}
+
+		perReceiver := countPeers(t, creator, giftRequire, 200)
+		assert.Equal(t, 200, perReceiver["user_1"]+perReceiver["user_2"])
+		assert.Greater(t, perReceiver["user_1"], 0)
+		assert.Greater(t, perReceiver["user_2"], 0)
+	})
+}
+
+func TestInvoiceCreatorImpl_Broadcast(t *testing.T) {
+	newCreator := func() *InvoiceCreatorImpl {
+		mockCache := &MockUserCache{}
+		mockCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+		mockCache.On("GetUser", "bob").Return(&tg.User{ID: 2}, nil)
+		mockCache.On("GetChannel", "news").Return(&tg.Channel{ID: 10}, nil)
+		return NewInvoiceCreator([]string{"alice", "bob"}, []string{"news"}, mockCache, false)
+	}
+
+	t.Run("один инвойс на каждого получателя", func(t *testing.T) {
+		creator := newCreator()
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{0, 1, 2})
+		giftRequire.BroadcastToAllReceivers = true
+
+		assert.Equal(t, 4, creator.ReceiverCount(giftRequire.ReceiverType))
+
+		peers := make(map[string]int)
+		for i := 0; i < creator.ReceiverCount(giftRequire.ReceiverType); i++ {
+			invoice, err := creator.CreateInvoice(context.Background(), giftRequire)
+			assert.NoError(t, err)
+
+			switch peer := invoice.Peer.(type) {
+			case *tg.InputPeerSelf:
+				peers["self"]++
+			case *tg.InputPeerUser:
+				peers[fmt.Sprintf("user_%d", peer.UserID)]++
+			case *tg.InputPeerChannel:
+				peers[fmt.Sprintf("channel_%d", peer.ChannelID)]++
+			}
+		}
+
+		assert.Equal(t, map[string]int{"self": 1, "user_1": 1, "user_2": 1, "channel_10": 1}, peers)
+	})
+
+	t.Run("повторяющиеся типы получателей не дублируются", func(t *testing.T) {
+		creator := newCreator()
+		assert.Equal(t, 2, creator.ReceiverCount([]int{1, 1}))
+		assert.Equal(t, 0, creator.ReceiverCount([]int{5}))
+	})
+
+	t.Run("ошибка без получателей", func(t *testing.T) {
+		creator := NewInvoiceCreator(nil, nil, &MockUserCache{}, false)
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{1})
+		giftRequire.BroadcastToAllReceivers = true
+
+		_, err := creator.CreateInvoice(context.Background(), giftRequire)
+		assert.Error(t, err)
+	})
+}
+
+func TestInvoiceCreatorImpl_MaxPerReceiver(t *testing.T) {
+	newCreator := func() *InvoiceCreatorImpl {
+		mockCache := &MockUserCache{}
+		mockCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+		mockCache.On("GetUser", "bob").Return(&tg.User{ID: 2}, nil)
+		creator := NewInvoiceCreator([]string{"alice", "bob"}, nil, mockCache, false)
+		creator.SetMaxPerReceiver(2)
+		return creator
+	}
+
+	t.Run("заполненный получатель заменяется свободным", func(t *testing.T) {
+		creator := newCreator()
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{1})
+
+		perReceiver := make(map[int64]int)
+		for i := 0; i < 4; i++ {
+			invoice, err := creator.CreateInvoice(context.Background(), giftRequire)
+			assert.NoError(t, err)
+			perReceiver[invoice.Peer.(*tg.InputPeerUser).UserID]++
+		}
+
+		assert.Equal(t, map[int64]int{1: 2, 2: 2}, perReceiver)
+	})
+
+	t.Run("все получатели заполнены", func(t *testing.T) {
+		creator := newCreator()
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{1})
+
+		for i := 0; i < 4; i++ {
+			_, err := creator.CreateInvoice(context.Background(), giftRequire)
+			assert.NoError(t, err)
+		}
+
+		_, err := creator.CreateInvoice(context.Background(), giftRequire)
+		assert.ErrorIs(t, err, errors.ErrAllReceiversSaturated)
+
+		// The cap is per gift, other gifts are not affected
+		_, err = creator.CreateInvoice(context.Background(), createTestGiftRequire(createTestGift(2, 100), []int{1}))
+		assert.NoError(t, err)
+	})
+
+	t.Run("неудачная покупка возвращает место получателю", func(t *testing.T) {
+		creator := newCreator()
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{1})
+
+		for i := 0; i < 3; i++ {
+			_, err := creator.CreateInvoice(context.Background(), giftRequire)
+			assert.NoError(t, err)
+		}
+		failed := giftTypes.WithReservation(context.Background())
+		_, err := creator.CreateInvoice(failed, giftRequire)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, giftTypes.AttemptReservation(failed).Receiver)
+
+		creator.ReleaseReservation(failed)
+		creator.ReleaseReservation(failed)
+
+		_, err = creator.CreateInvoice(context.Background(), giftRequire)
+		assert.NoError(t, err)
+		_, err = creator.CreateInvoice(context.Background(), giftRequire)
+		assert.ErrorIs(t, err, errors.ErrAllReceiversSaturated, "a reservation is given back only once")
+	})
+}
+
+func TestInvoiceCreatorImpl_Warm(t *testing.T) {
+	newCreator := func(channelErr error) (*InvoiceCreatorImpl, *MockUserCache) {
+		mockCache := &MockUserCache{}
+		mockCache.On("GetUser", "123").Return(&tg.User{ID: 123, AccessHash: 456}, nil)
+		if channelErr != nil {
+			mockCache.On("GetChannel", "-1001").Return(nil, channelErr)
+		} else {
+			mockCache.On("GetChannel", "-1001").Return(&tg.Channel{ID: 1, AccessHash: 789}, nil)
+		}
+		return NewInvoiceCreator([]string{"123"}, []string{"-1001"}, mockCache, false), mockCache
+	}
+
+	t.Run("подготовленный подарок без поиска получателей", func(t *testing.T) {
+		creator, mockCache := newCreator(nil)
+
+		assert.NoError(t, creator.Warm([]int64{1}))
+		mockCache.AssertNumberOfCalls(t, "GetUser", 1)
+		mockCache.AssertNumberOfCalls(t, "GetChannel", 1)
+
+		gift := createTestGiftRequire(createTestGift(1, 100), []int{1, 2})
+		for i := 0; i < 5; i++ {
+			invoice, err := creator.CreateInvoice(context.Background(), gift)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), invoice.GiftID)
+			assert.True(t, invoice.HideName)
+			assert.NotEmpty(t, invoice.Message.Text)
+			switch peer := invoice.Peer.(type) {
+			case *tg.InputPeerUser:
+				assert.Equal(t, int64(456), peer.AccessHash)
+			case *tg.InputPeerChannel:
+				assert.Equal(t, int64(789), peer.AccessHash)
+			default:
+				t.Fatalf("unexpected peer %T", peer)
+			}
+		}
+
+		// The invoices are built from the cached peers
+		mockCache.AssertNumberOfCalls(t, "GetUser", 1)
+		mockCache.AssertNumberOfCalls(t, "GetChannel", 1)
+	})
+
+	t.Run("неподготовленный подарок ищет получателя", func(t *testing.T) {
+		creator, mockCache := newCreator(nil)
+		assert.NoError(t, creator.Warm([]int64{1}))
+
+		gift := createTestGiftRequire(createTestGift(2, 100), []int{1})
+		for i := 0; i < 3; i++ {
+			_, err := creator.CreateInvoice(context.Background(), gift)
+			assert.NoError(t, err)
+		}
+
+		mockCache.AssertNumberOfCalls(t, "GetUser", 4)
+	})
+
+	t.Run("нерезолвленный получатель", func(t *testing.T) {
+		creator, mockCache := newCreator(errors.New("not found"))
+
+		err := creator.Warm([]int64{1})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "-1001")
+
+		invoice, err := creator.CreateInvoice(context.Background(), createTestGiftRequire(createTestGift(1, 100), []int{1}))
+		assert.NoError(t, err)
+		assert.IsType(t, &tg.InputPeerUser{}, invoice.Peer)
+		mockCache.AssertNumberOfCalls(t, "GetUser", 1)
+
+		// The channel keeps the regular lookup path
+		_, err = creator.CreateInvoice(context.Background(), createTestGiftRequire(createTestGift(1, 100), []int{2}))
+		assert.Error(t, err)
+		mockCache.AssertNumberOfCalls(t, "GetChannel", 2)
+	})
+}
+
+// staticIDCache resolves receivers from fixed maps
+type staticIDCache struct {
+	users    map[string]*tg.User
+	channels map[string]*tg.Channel
+}
+
+func (c *staticIDCache) SetUser(key string, user *tg.User) { c.users[key] = user }
+
+func (c *staticIDCache) GetUser(id string) (*tg.User, error) {
+	if user, ok := c.users[id]; ok {
+		return user, nil
+	}
+	return nil, errors.New("user not found")
+}
+
+func (c *staticIDCache) SetChannel(key string, channel *tg.Channel) { c.channels[key] = channel }
+
+func (c *staticIDCache) GetChannel(id string) (*tg.Channel, error) {
+	if channel, ok := c.channels[id]; ok {
+		return channel, nil
+	}
+	return nil, errors.New("channel not found")
+}
+
+func TestInvoiceCreatorImpl_Upgrade(t *testing.T) {
+	cache := &staticIDCache{
+		users:    map[string]*tg.User{"alice": {ID: 1, AccessHash: 100}},
+		channels: map[string]*tg.Channel{"news": {ID: 10, AccessHash: 200}},
+	}
+	upgradable := func() *tg.StarGift {
+		gift := createTestGift(1, 100)
+		gift.SetUpgradeStars(50)
+		return gift
+	}
+
+	for name, receiverType := range map[string]int{"себе": 0, "пользователю": 1, "в канал": 2} {
+		t.Run("улучшение запрошено "+name, func(t *testing.T) {
+			creator := NewInvoiceCreator([]string{"alice"}, []string{"news"}, cache, false)
+			giftRequire := createTestGiftRequire(upgradable(), []int{receiverType})
+			giftRequire.Upgrade = true
+
+			invoice, err := creator.CreateInvoice(context.Background(), giftRequire)
+
+			if assert.NoError(t, err) {
+				assert.True(t, invoice.IncludeUpgrade)
+			}
+		})
+
+		t.Run("без улучшения "+name, func(t *testing.T) {
+			creator := NewInvoiceCreator([]string{"alice"}, []string{"news"}, cache, false)
+
+			invoice, err := creator.CreateInvoice(context.Background(), createTestGiftRequire(upgradable(), []int{receiverType}))
+
+			if assert.NoError(t, err) {
+				assert.False(t, invoice.IncludeUpgrade)
+			}
+		})
+	}
+
+	t.Run("подарок нельзя улучшить", func(t *testing.T) {
+		creator := NewInvoiceCreator(nil, nil, cache, false)
+		giftRequire := createTestGiftRequire(createTestGift(1, 100), []int{0})
+		giftRequire.Upgrade = true
+
+		invoice, err := creator.CreateInvoice(context.Background(), giftRequire)
+
+		if assert.NoError(t, err) {
+			assert.False(t, invoice.IncludeUpgrade)
+		}
+	})
+}
+
+func TestInvoiceCreatorImpl_ReceiverSlot(t *testing.T) {
+	userCache := &MockUserCache{}
+	userCache.On("GetUser", "alice").Return(&tg.User{ID: 1}, nil)
+	userCache.On("GetUser", "bob").Return(&tg.User{ID: 2}, nil)
+	creator := NewInvoiceCreator([]string{"alice", "bob"}, nil, userCache, false)
+
+	t.Run("слот единицы не сдвигает курсор", func(t *testing.T) {
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{1}, BroadcastToAllReceivers: true}
+		ctx := giftTypes.WithReceiverSlot(context.Background(), 1)
+
+		for i := 0; i < 3; i++ {
+			invoice, err := creator.CreateInvoice(ctx, gift)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), invoice.Peer.(*tg.InputPeerUser).UserID)
+		}
+		assert.Equal(t, int64(0), gift.ReceiverCursor)
+	})
+
+	t.Run("без слота используется курсор партии", func(t *testing.T) {
+		gift := &giftTypes.GiftRequire{Gift: createTestGift(1, 100), CountForBuy: 2, ReceiverType: []int{1}, BroadcastToAllReceivers: true}
+
+		first, err := creator.CreateInvoice(context.Background(), gift)
+		assert.NoError(t, err)
+		second, err := creator.CreateInvoice(context.Background(), gift)
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(1), first.Peer.(*tg.InputPeerUser).UserID)
+		assert.Equal(t, int64(2), second.Peer.(*tg.InputPeerUser).UserID)
+	})
+}
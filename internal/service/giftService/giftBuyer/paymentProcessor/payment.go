@@ -2,9 +2,12 @@ package paymentProcessor
 
 import (
 	"context"
+	"gift-buyer/internal/service/giftService/giftBuyer/apiLog"
 	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/internal/service/giftService/rateLimiter"
 	"gift-buyer/pkg/errors"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -16,6 +19,26 @@ type PaymentProcessorImpl struct {
 	invoiceCreator giftInterfaces.InvoiceCreator
 	rateLimiter    giftInterfaces.RateLimiter
 	requestCounter int64
+
+	// debugf logs the redacted payment form requests and responses, nil when disabled
+	debugf apiLog.Logf
+
+	// warmed marks the gifts whose purchase path was prepared by Warm
+	warmed map[int64]bool
+	warmMu sync.RWMutex
+
+	// sleep waits out the request jitter, replaced in tests
+	sleep func(time.Duration)
+}
+
+// ratePenalizer is implemented by rate limiters that slow down after a FLOOD_WAIT
+type ratePenalizer interface {
+	Penalize(wait time.Duration)
+}
+
+// invoiceWarmer is implemented by invoice creators that can prepare invoices in advance
+type invoiceWarmer interface {
+	Warm(giftIDs []int64) error
 }
 
 func NewPaymentProcessor(api *tg.Client, invoiceCreator giftInterfaces.InvoiceCreator, rateLimiter giftInterfaces.RateLimiter) *PaymentProcessorImpl {
@@ -23,14 +46,61 @@ func NewPaymentProcessor(api *tg.Client, invoiceCreator giftInterfaces.InvoiceCr
 		api:            api,
 		invoiceCreator: invoiceCreator,
 		rateLimiter:    rateLimiter,
+		sleep:          time.Sleep,
+	}
+}
+
+// SetVerboseLogging enables logging of the redacted payment form requests and responses.
+//
+// Parameters:
+//   - debugf: debug logger, nil disables verbose logging
+func (pp *PaymentProcessorImpl) SetVerboseLogging(debugf apiLog.Logf) {
+	pp.debugf = debugf
+}
+
+// Warm prepares the purchase path of gifts expected to drop: the invoice creator caches
+// the resolved receivers, and payment forms of the prepared gifts are requested without
+// the staggering jitter. The rate limiter still spaces the requests out.
+//
+// Parameters:
+//   - giftIDs: IDs of the anticipated gifts
+//
+// Returns:
+//   - error: invoice preparation error, the gifts are marked as prepared anyway
+func (pp *PaymentProcessorImpl) Warm(giftIDs []int64) error {
+	warmed := make(map[int64]bool, len(giftIDs))
+	for _, giftID := range giftIDs {
+		warmed[giftID] = true
 	}
+	pp.warmMu.Lock()
+	pp.warmed = warmed
+	pp.warmMu.Unlock()
+
+	if warmer, ok := pp.invoiceCreator.(invoiceWarmer); ok {
+		if err := warmer.Warm(giftIDs); err != nil {
+			return errors.Wrap(err, "failed to prepare invoices")
+		}
+	}
+	return nil
+}
+
+// isWarmed reports whether the purchase path of the gift was prepared by Warm.
+func (pp *PaymentProcessorImpl) isWarmed(gift *giftTypes.GiftRequire) bool {
+	if gift.Gift == nil {
+		return false
+	}
+	pp.warmMu.RLock()
+	defer pp.warmMu.RUnlock()
+	return pp.warmed[gift.Gift.ID]
 }
 
 func (pp *PaymentProcessorImpl) CreatePaymentForm(ctx context.Context, gift *giftTypes.GiftRequire) (tg.PaymentsPaymentFormClass, *tg.InputInvoiceStarGift, error) {
-	jitter := time.Duration(atomic.AddInt64(&pp.requestCounter, 1)%100) * time.Millisecond
-	time.Sleep(jitter)
+	if !pp.isWarmed(gift) {
+		jitter := time.Duration(atomic.AddInt64(&pp.requestCounter, 1)%100) * time.Millisecond
+		pp.sleep(jitter)
+	}
 
-	invoice, err := pp.invoiceCreator.CreateInvoice(gift)
+	invoice, err := pp.invoiceCreator.CreateInvoice(ctx, gift)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to create invoice")
 	}
@@ -41,10 +111,32 @@ func (pp *PaymentProcessorImpl) CreatePaymentForm(ctx context.Context, gift *gif
 	paymentFormRequest := &tg.PaymentsGetPaymentFormRequest{
 		Invoice: invoice,
 	}
+	if pp.debugf != nil {
+		pp.debugf("payments.getPaymentForm request: %s", apiLog.Invoice(invoice))
+	}
 	paymentForm, err := pp.api.PaymentsGetPaymentForm(ctx, paymentFormRequest)
 	if err != nil {
+		if pp.debugf != nil {
+			pp.debugf("payments.getPaymentForm error: %v", err)
+		}
+		pp.penalizeFloodWait(err)
 		return nil, nil, errors.Wrap(err, "failed to get payment form")
 	}
+	if pp.debugf != nil {
+		pp.debugf("payments.getPaymentForm response: %s", apiLog.PaymentForm(paymentForm))
+	}
 
 	return paymentForm, invoice, nil
 }
+
+// penalizeFloodWait slows the rate limiter down when Telegram answered with FLOOD_WAIT,
+// if the rate limiter supports it.
+func (pp *PaymentProcessorImpl) penalizeFloodWait(err error) {
+	penalizer, ok := pp.rateLimiter.(ratePenalizer)
+	if !ok {
+		return
+	}
+	if wait, ok := rateLimiter.FloodWait(err); ok {
+		penalizer.Penalize(wait)
+	}
+}
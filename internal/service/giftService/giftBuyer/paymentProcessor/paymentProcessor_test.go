@@ -3,14 +3,18 @@ package paymentProcessor
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/internal/service/giftService/giftTypes"
 
+	"github.com/gotd/td/bin"
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock для RateLimiter
@@ -32,8 +36,8 @@ type MockInvoiceCreator struct {
 	mock.Mock
 }
 
-func (m *MockInvoiceCreator) CreateInvoice(gift *giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error) {
-	args := m.Called(gift)
+func (m *MockInvoiceCreator) CreateInvoice(ctx context.Context, gift *giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error) {
+	args := m.Called(ctx, gift)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -93,10 +97,10 @@ func TestPaymentProcessorImpl_InvoiceCreation_Success(t *testing.T) {
 		invoice := createTestInvoice(1)
 
 		// Настраиваем моки
-		mockInvoiceCreator.On("CreateInvoice", giftRequire).Return(invoice, nil)
+		mockInvoiceCreator.On("CreateInvoice", mock.Anything, giftRequire).Return(invoice, nil)
 
 		// Тестируем только создание инвойса
-		createdInvoice, err := processor.invoiceCreator.CreateInvoice(giftRequire)
+		createdInvoice, err := processor.invoiceCreator.CreateInvoice(context.Background(), giftRequire)
 		assert.NoError(t, err)
 		assert.Equal(t, invoice, createdInvoice)
 
@@ -156,10 +160,10 @@ func TestPaymentProcessorImpl_InvoiceCreation_Error(t *testing.T) {
 		giftRequire := createTestGiftRequire(gift)
 
 		// Настраиваем мок для возврата ошибки
-		mockInvoiceCreator.On("CreateInvoice", giftRequire).Return((*tg.InputInvoiceStarGift)(nil), errors.New("invoice creation failed"))
+		mockInvoiceCreator.On("CreateInvoice", mock.Anything, giftRequire).Return((*tg.InputInvoiceStarGift)(nil), errors.New("invoice creation failed"))
 
 		// Тестируем создание инвойса с ошибкой
-		createdInvoice, err := processor.invoiceCreator.CreateInvoice(giftRequire)
+		createdInvoice, err := processor.invoiceCreator.CreateInvoice(context.Background(), giftRequire)
 		assert.Error(t, err)
 		assert.Nil(t, createdInvoice)
 		assert.Contains(t, err.Error(), "invoice creation failed")
@@ -190,3 +194,153 @@ func TestPaymentProcessorImpl_ContextCancellation(t *testing.T) {
 		mockRateLimiter.AssertExpectations(t)
 	})
 }
+
+// paymentFormInvoker answers payments.getPaymentForm with a stars payment form
+type paymentFormInvoker struct{}
+
+func (paymentFormInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	output.(*tg.PaymentsPaymentFormBox).PaymentForm = &tg.PaymentsPaymentFormStars{FormID: 42}
+	return nil
+}
+
+func TestPaymentProcessorImpl_VerboseLogging(t *testing.T) {
+	newProcessor := func() (*PaymentProcessorImpl, *MockInvoiceCreator) {
+		mockInvoiceCreator := &MockInvoiceCreator{}
+		mockRateLimiter := &MockRateLimiter{}
+		mockRateLimiter.On("Acquire", mock.Anything).Return(nil)
+
+		return NewPaymentProcessor(tg.NewClient(paymentFormInvoker{}), mockInvoiceCreator, mockRateLimiter), mockInvoiceCreator
+	}
+
+	giftRequire := createTestGiftRequire(createTestGift(1, 100))
+	invoice := &tg.InputInvoiceStarGift{
+		Peer:    &tg.InputPeerUser{UserID: 7, AccessHash: 987654321},
+		GiftID:  1,
+		Message: tg.TextWithEntities{Text: "secret message"},
+	}
+
+	t.Run("запрос и ответ логируются без чувствительных полей", func(t *testing.T) {
+		processor, mockInvoiceCreator := newProcessor()
+		mockInvoiceCreator.On("CreateInvoice", mock.Anything, giftRequire).Return(invoice, nil)
+
+		var lines []string
+		processor.SetVerboseLogging(func(format string, args ...interface{}) {
+			lines = append(lines, fmt.Sprintf(format, args...))
+		})
+
+		form, _, err := processor.CreatePaymentForm(context.Background(), giftRequire)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), form.(*tg.PaymentsPaymentFormStars).FormID)
+
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[0], "gift_id=1")
+		assert.Contains(t, lines[0], "user{id=7")
+		assert.Contains(t, lines[1], "form_id=42")
+		for _, line := range lines {
+			assert.NotContains(t, line, "987654321")
+			assert.NotContains(t, line, "secret message")
+		}
+	})
+
+	t.Run("без флага ничего не логируется", func(t *testing.T) {
+		processor, mockInvoiceCreator := newProcessor()
+		mockInvoiceCreator.On("CreateInvoice", mock.Anything, giftRequire).Return(invoice, nil)
+
+		_, _, err := processor.CreatePaymentForm(context.Background(), giftRequire)
+
+		assert.NoError(t, err)
+		assert.Nil(t, processor.debugf)
+	})
+}
+
+// warmingInvoiceCreator records the gifts prepared by Warm
+type warmingInvoiceCreator struct {
+	MockInvoiceCreator
+	warmed []int64
+	err    error
+}
+
+func (w *warmingInvoiceCreator) Warm(giftIDs []int64) error {
+	w.warmed = giftIDs
+	return w.err
+}
+
+func TestPaymentProcessorImpl_Warm(t *testing.T) {
+	newProcessor := func(invoiceCreator giftInterfaces.InvoiceCreator) (*PaymentProcessorImpl, *[]time.Duration) {
+		mockRateLimiter := &MockRateLimiter{}
+		mockRateLimiter.On("Acquire", mock.Anything).Return(nil)
+
+		processor := NewPaymentProcessor(tg.NewClient(paymentFormInvoker{}), invoiceCreator, mockRateLimiter)
+		var sleeps []time.Duration
+		processor.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+		return processor, &sleeps
+	}
+
+	t.Run("подготовленный подарок без задержки", func(t *testing.T) {
+		creator := &warmingInvoiceCreator{}
+		processor, sleeps := newProcessor(creator)
+
+		warm := createTestGiftRequire(createTestGift(1, 100))
+		cold := createTestGiftRequire(createTestGift(2, 100))
+		creator.On("CreateInvoice", mock.Anything, mock.Anything).Return(createTestInvoice(1), nil)
+
+		require.NoError(t, processor.Warm([]int64{1}))
+		assert.Equal(t, []int64{1}, creator.warmed)
+
+		for i := 0; i < 3; i++ {
+			_, _, err := processor.CreatePaymentForm(context.Background(), warm)
+			require.NoError(t, err)
+		}
+		assert.Empty(t, *sleeps)
+
+		_, _, err := processor.CreatePaymentForm(context.Background(), cold)
+		require.NoError(t, err)
+		assert.Len(t, *sleeps, 1)
+	})
+
+	t.Run("ошибка подготовки инвойсов", func(t *testing.T) {
+		creator := &warmingInvoiceCreator{err: assert.AnError}
+		processor, _ := newProcessor(creator)
+
+		err := processor.Warm([]int64{1})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.True(t, processor.isWarmed(createTestGiftRequire(createTestGift(1, 100))))
+	})
+
+	t.Run("создатель инвойсов без подготовки", func(t *testing.T) {
+		processor, _ := newProcessor(&MockInvoiceCreator{})
+
+		assert.NoError(t, processor.Warm([]int64{1}))
+	})
+}
+
+// penalizingRateLimiter records the penalties of a FLOOD_WAIT
+type penalizingRateLimiter struct {
+	MockRateLimiter
+	penalties []time.Duration
+}
+
+func (m *penalizingRateLimiter) Penalize(wait time.Duration) {
+	m.penalties = append(m.penalties, wait)
+}
+
+func TestPaymentProcessorImpl_PenalizeFloodWait(t *testing.T) {
+	t.Run("FLOOD_WAIT замедляет ограничитель", func(t *testing.T) {
+		limiter := &penalizingRateLimiter{}
+		processor := NewPaymentProcessor((*tg.Client)(nil), &MockInvoiceCreator{}, limiter)
+
+		processor.penalizeFloodWait(errors.New("rpc error code 420: FLOOD_WAIT (7)"))
+		processor.penalizeFloodWait(errors.New("rpc error code 400: BALANCE_TOO_LOW"))
+
+		assert.Equal(t, []time.Duration{7 * time.Second}, limiter.penalties)
+	})
+
+	t.Run("ограничитель без штрафов не мешает", func(t *testing.T) {
+		processor := NewPaymentProcessor((*tg.Client)(nil), &MockInvoiceCreator{}, &MockRateLimiter{})
+
+		assert.NotPanics(t, func() {
+			processor.penalizeFloodWait(errors.New("rpc error code 420: FLOOD_WAIT (7)"))
+		})
+	})
+}
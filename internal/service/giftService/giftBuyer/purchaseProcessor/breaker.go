@@ -0,0 +1,139 @@
+package purchaseProcessor
+
+import (
+	"context"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
+	"sync"
+	"time"
+)
+
+// defaultBreakerCooldown is used when the circuit breaker is enabled without a cooldown
+const defaultBreakerCooldown = 60 * time.Second
+
+// breakerState is the state of the purchase circuit breaker.
+type breakerState int
+
+const (
+	// breakerClosed passes every purchase to the processor
+	breakerClosed breakerState = iota
+
+	// breakerOpen rejects purchases until the cooldown elapses
+	breakerOpen
+
+	// breakerHalfOpen lets a single trial purchase through to test recovery
+	breakerHalfOpen
+)
+
+// circuitBreaker wraps a purchase processor and pauses purchases after repeated payment
+// failures, instead of burning retries and running into flood waits. After threshold
+// failures in a row it opens and rejects purchases with ErrCircuitOpen for the cooldown.
+// The first purchase after the cooldown is let through as a trial: its success closes
+// the breaker, its failure opens it for another cooldown.
+type circuitBreaker struct {
+	processor giftInterfaces.PurchaseProcessor
+
+	// threshold is the number of failures in a row that opens the breaker
+	threshold int
+
+	// cooldown is how long the breaker stays open before the trial purchase
+	cooldown time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// NewCircuitBreaker wraps a purchase processor with a circuit breaker.
+//
+// Parameters:
+//   - processor: purchase processor making the payments
+//   - threshold: payment failures in a row that pause purchases
+//   - cooldown: how long purchases are paused (defaults to 60 seconds)
+//
+// Returns:
+//   - *circuitBreaker: purchase processor guarded by the breaker
+func NewCircuitBreaker(processor giftInterfaces.PurchaseProcessor, threshold int, cooldown time.Duration) *circuitBreaker {
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+
+	return &circuitBreaker{
+		processor: processor,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// PurchaseGift buys the gift through the wrapped processor unless the breaker is open.
+//
+// Returns:
+//   - error: ErrCircuitOpen while purchases are paused, otherwise the purchase error
+func (cb *circuitBreaker) PurchaseGift(ctx context.Context, gift *giftTypes.GiftRequire) error {
+	if !cb.allow() {
+		return errors.ErrCircuitOpen
+	}
+
+	err := cb.processor.PurchaseGift(ctx, gift)
+	cb.record(ctx, err)
+	return err
+}
+
+// allow reports whether a purchase may be attempted, moving an open breaker whose
+// cooldown elapsed to half-open with the caller as the trial purchase.
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case breakerOpen:
+		if cb.now().Sub(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// The trial purchase is still in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of a purchase. Cancelled purchases and
+// purchases refused before payment tell nothing about the payments and aren't counted.
+func (cb *circuitBreaker) record(ctx context.Context, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch {
+	case err == nil:
+		cb.state = breakerClosed
+		cb.failures = 0
+	case ctx.Err() != nil || errors.Is(err, errors.ErrBalanceReserveReached):
+		if cb.state == breakerHalfOpen {
+			// Let the next purchase run the trial again
+			cb.state = breakerOpen
+		}
+	case cb.state == breakerHalfOpen:
+		cb.open()
+	default:
+		cb.failures++
+		if cb.failures >= cb.threshold {
+			cb.open()
+		}
+	}
+}
+
+// open pauses purchases for the cooldown. The caller must hold mu.
+func (cb *circuitBreaker) open() {
+	cb.state = breakerOpen
+	cb.openedAt = cb.now()
+	cb.failures = 0
+}
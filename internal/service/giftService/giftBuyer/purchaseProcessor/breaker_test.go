@@ -0,0 +1,111 @@
+package purchaseProcessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scriptedProcessor fails purchases while err is set and counts the calls
+type scriptedProcessor struct {
+	calls int
+	err   error
+}
+
+func (p *scriptedProcessor) PurchaseGift(ctx context.Context, gift *giftTypes.GiftRequire) error {
+	p.calls++
+	return p.err
+}
+
+func newTestBreaker(threshold int, cooldown time.Duration) (*circuitBreaker, *scriptedProcessor, *time.Time) {
+	processor := &scriptedProcessor{}
+	breaker := NewCircuitBreaker(processor, threshold, cooldown)
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return clock }
+	return breaker, processor, &clock
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	gift := createTestGiftRequire(createTestGift(1, 100))
+	paymentErr := errors.New("rpc error code 420: FLOOD_WAIT_30")
+
+	t.Run("закрыт -> открыт -> полуоткрыт -> закрыт", func(t *testing.T) {
+		breaker, processor, clock := newTestBreaker(3, time.Minute)
+
+		// Closed: failures below the threshold reach the processor
+		processor.err = paymentErr
+		for i := 0; i < 3; i++ {
+			assert.ErrorIs(t, breaker.PurchaseGift(ctx, gift), paymentErr)
+		}
+		assert.Equal(t, breakerOpen, breaker.state)
+
+		// Open: purchases are rejected without calling the processor
+		*clock = clock.Add(30 * time.Second)
+		assert.ErrorIs(t, breaker.PurchaseGift(ctx, gift), errors.ErrCircuitOpen)
+		assert.Equal(t, 3, processor.calls)
+
+		// Half-open: the first purchase after the cooldown is the trial
+		*clock = clock.Add(30 * time.Second)
+		processor.err = nil
+		assert.True(t, breaker.allow())
+		assert.Equal(t, breakerHalfOpen, breaker.state)
+		assert.False(t, breaker.allow(), "only one trial purchase at a time")
+		assert.NoError(t, breaker.processor.PurchaseGift(ctx, gift))
+		breaker.record(ctx, nil)
+
+		// Closed again
+		assert.Equal(t, breakerClosed, breaker.state)
+		assert.NoError(t, breaker.PurchaseGift(ctx, gift))
+		assert.Equal(t, 5, processor.calls)
+	})
+
+	t.Run("неудачная пробная покупка снова открывает", func(t *testing.T) {
+		breaker, processor, clock := newTestBreaker(2, time.Minute)
+		processor.err = paymentErr
+
+		breaker.PurchaseGift(ctx, gift)
+		breaker.PurchaseGift(ctx, gift)
+		*clock = clock.Add(time.Minute)
+
+		assert.ErrorIs(t, breaker.PurchaseGift(ctx, gift), paymentErr)
+		assert.Equal(t, breakerOpen, breaker.state)
+		assert.ErrorIs(t, breaker.PurchaseGift(ctx, gift), errors.ErrCircuitOpen)
+		assert.Equal(t, 3, processor.calls)
+	})
+
+	t.Run("успех сбрасывает счетчик ошибок", func(t *testing.T) {
+		breaker, processor, _ := newTestBreaker(2, time.Minute)
+
+		processor.err = paymentErr
+		breaker.PurchaseGift(ctx, gift)
+		processor.err = nil
+		breaker.PurchaseGift(ctx, gift)
+		processor.err = paymentErr
+		breaker.PurchaseGift(ctx, gift)
+
+		assert.Equal(t, breakerClosed, breaker.state)
+	})
+
+	t.Run("отмена и резерв баланса не считаются", func(t *testing.T) {
+		breaker, processor, _ := newTestBreaker(1, time.Minute)
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		processor.err = context.Canceled
+		breaker.PurchaseGift(cancelled, gift)
+		processor.err = errors.Wrap(errors.ErrBalanceReserveReached, "gift 1")
+		breaker.PurchaseGift(ctx, gift)
+
+		assert.Equal(t, breakerClosed, breaker.state)
+	})
+
+	t.Run("кулдаун по умолчанию", func(t *testing.T) {
+		assert.Equal(t, defaultBreakerCooldown, NewCircuitBreaker(&scriptedProcessor{}, 3, 0).cooldown)
+	})
+}
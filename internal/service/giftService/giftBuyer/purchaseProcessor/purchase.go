@@ -3,25 +3,117 @@ package purchaseProcessor
 import (
 	"context"
 	"fmt"
+	"gift-buyer/internal/service/giftService/giftBuyer/apiLog"
 	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/internal/service/giftService/giftTypes"
 	"gift-buyer/pkg/errors"
+	"sync"
+	"time"
 
 	"github.com/gotd/td/tg"
 )
 
+const (
+	// defaultVerifyTimeout is used when purchase verification is enabled without a timeout
+	defaultVerifyTimeout = 10 * time.Second
+
+	// verifyPollInterval is the delay between saved gift checks during purchase verification
+	verifyPollInterval = 500 * time.Millisecond
+
+	// savedGiftsLimit is how many of the most recent saved gifts are checked for the bought one
+	savedGiftsLimit = 100
+)
+
+// savedGiftKey identifies a gift saved on a profile: by message ID for users and
+// by saved ID for channels.
+type savedGiftKey struct {
+	msgID   int
+	savedID int64
+}
+
 type PurchaseProcessorImpl struct {
 	api              *tg.Client
 	paymentProcessor giftInterfaces.PaymentProcessor
+
+	// verifyPurchase enables polling the receiver's saved gifts after a purchase to confirm it
+	verifyPurchase bool
+
+	// verifyTimeout bounds how long a purchase is polled for confirmation
+	verifyTimeout time.Duration
+
+	// pollInterval is the delay between confirmation polls
+	pollInterval time.Duration
+
+	// balanceFunc overrides the stars balance lookup, used in tests
+	balanceFunc func(ctx context.Context) (int64, error)
+
+	// savedGiftsFunc overrides the saved gifts lookup, used in tests
+	savedGiftsFunc func(ctx context.Context, peer tg.InputPeerClass) ([]tg.SavedStarGift, error)
+
+	// confirmed holds the saved gifts already matched to a purchase, so concurrent
+	// purchases of the same gift for the same receiver never confirm each other
+	confirmed   map[savedGiftKey]struct{}
+	confirmedMu sync.Mutex
+
+	// debugf logs the redacted payment requests and responses, nil when disabled
+	debugf apiLog.Logf
+
+	// balanceReserve is the stars balance never spent by purchases (0 for no reserve)
+	balanceReserve int64
+
+	// pendingSpend is the price of purchases in flight, not yet reflected in the balance
+	pendingSpend int64
+	spendMu      sync.Mutex
+
+	// rateLimiter limits the send payment requests (optional)
+	rateLimiter giftInterfaces.RateLimiter
 }
 
-func NewPurchaseProcessor(api *tg.Client, paymentProcessor giftInterfaces.PaymentProcessor) *PurchaseProcessorImpl {
+// NewPurchaseProcessor creates a new purchase processor.
+//
+// Parameters:
+//   - api: Telegram API client used for payments and balance checks
+//   - paymentProcessor: processor creating payment forms for gifts
+//   - verifyPurchase: confirm every purchase by polling the receiver's saved gifts
+//   - verifyTimeout: how long to wait for the confirmation (defaults to 10 seconds)
+//
+// Returns:
+//   - *PurchaseProcessorImpl: configured purchase processor
+func NewPurchaseProcessor(api *tg.Client, paymentProcessor giftInterfaces.PaymentProcessor, verifyPurchase bool, verifyTimeout time.Duration) *PurchaseProcessorImpl {
+	if verifyTimeout <= 0 {
+		verifyTimeout = defaultVerifyTimeout
+	}
+
 	return &PurchaseProcessorImpl{
 		api:              api,
 		paymentProcessor: paymentProcessor,
+		verifyPurchase:   verifyPurchase,
+		verifyTimeout:    verifyTimeout,
+		pollInterval:     verifyPollInterval,
 	}
 }
 
+// SetVerboseLogging enables logging of the redacted payment requests and responses.
+//
+// Parameters:
+//   - debugf: debug logger, nil disables verbose logging
+func (pp *PurchaseProcessorImpl) SetVerboseLogging(debugf apiLog.Logf) {
+	pp.debugf = debugf
+}
+
+// SetRateLimiter sets the rate limiter acquired before every send payment request.
+func (pp *PurchaseProcessorImpl) SetRateLimiter(rateLimiter giftInterfaces.RateLimiter) {
+	pp.rateLimiter = rateLimiter
+}
+
+// SetBalanceReserve sets the stars balance that purchases never spend.
+//
+// Parameters:
+//   - reserve: stars balance to keep, 0 for no reserve
+func (pp *PurchaseProcessorImpl) SetBalanceReserve(reserve int64) {
+	pp.balanceReserve = reserve
+}
+
 // purchaseGift executes the actual gift purchase through Telegram's payment API.
 // It creates an invoice, retrieves the payment form, and processes the star payment.
 //
@@ -30,6 +122,11 @@ func NewPurchaseProcessor(api *tg.Client, paymentProcessor giftInterfaces.Paymen
 //  2. Retrieves the payment form from Telegram
 //  3. Processes the payment based on form type
 //  4. Handles different payment form variations
+//  5. Optionally confirms the purchase by polling the receiver's saved gifts
+//
+// With a balance reserve configured the purchase is refused with ErrBalanceReserveReached
+// when it would bring the balance, minus purchases still in flight, below the reserve.
+// A payment that went through but couldn't be confirmed fails with ErrPurchaseUnconfirmed.
 //
 // Parameters:
 //   - ctx: context for request cancellation and timeout control
@@ -38,26 +135,183 @@ func NewPurchaseProcessor(api *tg.Client, paymentProcessor giftInterfaces.Paymen
 // Returns:
 //   - error: payment processing error or API communication failure
 func (pp *PurchaseProcessorImpl) PurchaseGift(ctx context.Context, gift *giftTypes.GiftRequire) error {
-	if !pp.validatePurchase(gift.Gift) {
+	balanceBefore, ok := pp.validatePurchase(ctx, gift.Gift)
+	if !ok {
 		return errors.New("insufficient balance to buy gift")
 	}
 
+	if pp.balanceReserve > 0 {
+		if !pp.reserveSpend(balanceBefore, gift.Gift.Stars) {
+			return errors.Wrap(errors.ErrBalanceReserveReached,
+				fmt.Sprintf("buying gift %d for %d stars would leave less than %d stars", gift.Gift.ID, gift.Gift.Stars, pp.balanceReserve))
+		}
+		defer pp.releaseSpend(gift.Gift.Stars)
+	}
+
 	paymentForm, invoice, err := pp.paymentProcessor.CreatePaymentForm(ctx, gift)
 	if err != nil {
 		return errors.Wrap(err, "failed to send stars form")
 	}
 
+	var formID int64
 	switch form := paymentForm.(type) {
 	case *tg.PaymentsPaymentFormStars:
-		return pp.sendStarsForm(ctx, invoice, form.FormID)
+		formID = form.FormID
 	case *tg.PaymentsPaymentFormStarGift:
-		return pp.sendStarsForm(ctx, invoice, form.FormID)
+		formID = form.FormID
 	case *tg.PaymentsPaymentForm:
 		return errors.New("regular payment form not supported for star gifts")
 	default:
 		return errors.Wrap(errors.New("unexpected payment form type"),
 			fmt.Sprintf("unexpected payment form type: %T", paymentForm))
 	}
+
+	var owned map[savedGiftKey]struct{}
+	if pp.verifyPurchase {
+		// The gifts already on the receiver's profile, only a new one confirms the purchase
+		owned, err = pp.ownedGifts(ctx, invoice.Peer, gift.Gift.ID)
+		if err != nil {
+			return errors.Wrap(err, "failed to list the receiver's gifts before payment")
+		}
+	}
+
+	if err := pp.sendStarsForm(ctx, invoice, formID); err != nil {
+		return err
+	}
+
+	if pp.verifyPurchase {
+		return pp.confirmPurchase(ctx, invoice.Peer, gift.Gift.ID, owned)
+	}
+	return nil
+}
+
+// reserveSpend books the gift price against the balance if the balance reserve stays intact.
+// Purchases in flight are booked too, so concurrent purchases can't overspend the reserve
+// together before the balance reflects them.
+//
+// Parameters:
+//   - balance: current stars balance
+//   - price: gift price in stars
+//
+// Returns:
+//   - bool: true if the price was booked, false if it would spend the reserve
+func (pp *PurchaseProcessorImpl) reserveSpend(balance, price int64) bool {
+	pp.spendMu.Lock()
+	defer pp.spendMu.Unlock()
+
+	if balance-pp.pendingSpend-price < pp.balanceReserve {
+		return false
+	}
+	pp.pendingSpend += price
+	return true
+}
+
+// releaseSpend removes a finished purchase from the spend booked by reserveSpend.
+func (pp *PurchaseProcessorImpl) releaseSpend(price int64) {
+	pp.spendMu.Lock()
+	defer pp.spendMu.Unlock()
+	pp.pendingSpend -= price
+}
+
+// confirmPurchase polls the receiver's saved gifts until a gift with the bought gift ID shows up
+// that wasn't there before the payment. Balance changes can't tell concurrent purchases apart,
+// the saved gifts can: every new gift confirms exactly one purchase.
+//
+// The payment has already gone through, so a purchase that isn't confirmed in time (for example
+// because the receiver doesn't show the gift on the profile) fails with ErrPurchaseUnconfirmed
+// and is never bought again.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - peer: receiver of the gift
+//   - giftID: ID of the bought gift
+//   - before: gifts with the same ID saved on the receiver's profile before the payment
+//
+// Returns:
+//   - error: ErrPurchaseUnconfirmed on timeout or context cancellation
+func (pp *PurchaseProcessorImpl) confirmPurchase(ctx context.Context, peer tg.InputPeerClass, giftID int64, before map[savedGiftKey]struct{}) error {
+	deadline := time.NewTimer(pp.verifyTimeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(pp.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if owned, err := pp.ownedGifts(ctx, peer, giftID); err == nil && pp.claimNewGift(owned, before) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(errors.ErrPurchaseUnconfirmed,
+				fmt.Sprintf("verification of gift %d cancelled: %v", giftID, ctx.Err()))
+		case <-deadline.C:
+			return errors.Wrap(errors.ErrPurchaseUnconfirmed,
+				fmt.Sprintf("gift %d not seen on the receiver's profile within %s", giftID, pp.verifyTimeout))
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimNewGift matches the purchase to a saved gift that wasn't on the profile before the
+// payment and isn't matched to another purchase yet.
+//
+// Returns:
+//   - bool: true if a new gift was claimed for the purchase
+func (pp *PurchaseProcessorImpl) claimNewGift(owned, before map[savedGiftKey]struct{}) bool {
+	pp.confirmedMu.Lock()
+	defer pp.confirmedMu.Unlock()
+
+	for key := range owned {
+		if _, old := before[key]; old {
+			continue
+		}
+		if _, taken := pp.confirmed[key]; taken {
+			continue
+		}
+		if pp.confirmed == nil {
+			pp.confirmed = make(map[savedGiftKey]struct{})
+		}
+		pp.confirmed[key] = struct{}{}
+		return true
+	}
+	return false
+}
+
+// ownedGifts returns the gifts with the given gift ID among the most recent gifts saved
+// on the profile of the peer.
+func (pp *PurchaseProcessorImpl) ownedGifts(ctx context.Context, peer tg.InputPeerClass, giftID int64) (map[savedGiftKey]struct{}, error) {
+	saved, err := pp.savedGifts(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make(map[savedGiftKey]struct{})
+	for _, savedGift := range saved {
+		if starGift, ok := savedGift.Gift.(*tg.StarGift); ok && starGift.ID == giftID {
+			owned[savedGiftKey{msgID: savedGift.MsgID, savedID: savedGift.SavedID}] = struct{}{}
+		}
+	}
+	return owned, nil
+}
+
+// savedGifts returns the most recent gifts saved on the profile of the peer.
+func (pp *PurchaseProcessorImpl) savedGifts(ctx context.Context, peer tg.InputPeerClass) ([]tg.SavedStarGift, error) {
+	if pp.savedGiftsFunc != nil {
+		return pp.savedGiftsFunc(ctx, peer)
+	}
+	if pp.api == nil {
+		return nil, errors.New("api client not configured")
+	}
+
+	result, err := pp.api.PaymentsGetSavedStarGifts(ctx, &tg.PaymentsGetSavedStarGiftsRequest{
+		Peer:  peer,
+		Limit: savedGiftsLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Gifts, nil
 }
 
 func (pp *PurchaseProcessorImpl) sendStarsForm(ctx context.Context, invoice *tg.InputInvoiceStarGift, id int64) error {
@@ -66,10 +320,24 @@ func (pp *PurchaseProcessorImpl) sendStarsForm(ctx context.Context, invoice *tg.
 		Invoice: invoice,
 	}
 
-	_, err := pp.api.PaymentsSendStarsForm(ctx, sendStarsRequest)
+	if pp.rateLimiter != nil {
+		if err := pp.rateLimiter.Acquire(ctx); err != nil {
+			return errors.Wrap(err, "failed to wait for rate limit")
+		}
+	}
+	if pp.debugf != nil {
+		pp.debugf("payments.sendStarsForm request: form_id=%d %s", id, apiLog.Invoice(invoice))
+	}
+	result, err := pp.api.PaymentsSendStarsForm(ctx, sendStarsRequest)
 	if err != nil {
+		if pp.debugf != nil {
+			pp.debugf("payments.sendStarsForm error: %v", err)
+		}
 		return errors.Wrap(err, "failed to send payment")
 	}
+	if pp.debugf != nil {
+		pp.debugf("payments.sendStarsForm response: %s", apiLog.PaymentResult(result))
+	}
 	return nil
 }
 
@@ -81,17 +349,30 @@ func (pp *PurchaseProcessorImpl) sendStarsForm(ctx context.Context, invoice *tg.
 //   - gift: the star gift to validate for purchase
 //
 // Returns:
-//   - error: validation error if insufficient balance or balance check fails
-func (pp *PurchaseProcessorImpl) validatePurchase(gift *tg.StarGift) bool {
+//   - int64: current stars balance
+//   - bool: true if the balance is sufficient, false if insufficient or the balance check fails
+func (pp *PurchaseProcessorImpl) validatePurchase(ctx context.Context, gift *tg.StarGift) (int64, bool) {
+	balance, err := pp.starsBalance(ctx)
+	if err != nil {
+		return 0, false
+	}
+	return balance, balance >= gift.Stars
+}
+
+// starsBalance returns the current stars balance of the account.
+func (pp *PurchaseProcessorImpl) starsBalance(ctx context.Context) (int64, error) {
+	if pp.balanceFunc != nil {
+		return pp.balanceFunc(ctx)
+	}
 	if pp.api == nil {
-		return false
+		return 0, errors.New("api client not configured")
 	}
 
-	balance, err := pp.api.PaymentsGetStarsStatus(context.Background(), &tg.PaymentsGetStarsStatusRequest{
+	status, err := pp.api.PaymentsGetStarsStatus(ctx, &tg.PaymentsGetStarsStatusRequest{
 		Peer: &tg.InputPeerSelf{},
 	})
 	if err != nil {
-		return false
+		return 0, err
 	}
-	return balance.Balance.GetAmount() >= gift.Stars
+	return status.Balance.GetAmount(), nil
 }
@@ -2,13 +2,19 @@ package purchaseProcessor
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
 
+	"github.com/gotd/td/bin"
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockPaymentProcessor для тестирования
@@ -53,11 +59,13 @@ func createTestInvoice(giftID int64) *tg.InputInvoiceStarGift {
 func TestNewPurchaseProcessor(t *testing.T) {
 	mockPaymentProcessor := &MockPaymentProcessor{}
 
-	processor := NewPurchaseProcessor(nil, mockPaymentProcessor)
+	processor := NewPurchaseProcessor(nil, mockPaymentProcessor, false, 0)
 
 	assert.NotNil(t, processor)
 	assert.Nil(t, processor.api)
 	assert.Equal(t, mockPaymentProcessor, processor.paymentProcessor)
+	assert.False(t, processor.verifyPurchase)
+	assert.Equal(t, defaultVerifyTimeout, processor.verifyTimeout)
 }
 
 func TestPurchaseProcessorImpl_PurchaseGift_ErrorCases(t *testing.T) {
@@ -111,7 +119,286 @@ func TestPurchaseProcessorImpl_ValidatePurchase_NilAPI(t *testing.T) {
 		gift := createTestGift(1, 100)
 
 		// С nil API валидация должна вернуть false
-		result := processor.validatePurchase(gift)
+		_, result := processor.validatePurchase(context.Background(), gift)
 		assert.False(t, result)
 	})
 }
+
+// savedGift returns a gift saved on a user profile with the given message ID
+func savedGift(giftID int64, msgID int) tg.SavedStarGift {
+	return tg.SavedStarGift{Gift: &tg.StarGift{ID: giftID}, MsgID: msgID}
+}
+
+func TestPurchaseProcessorImpl_ConfirmPurchase(t *testing.T) {
+	peer := &tg.InputPeerSelf{}
+
+	t.Run("покупка подтверждена", func(t *testing.T) {
+		var polls int32
+		processor := &PurchaseProcessorImpl{
+			verifyPurchase: true,
+			verifyTimeout:  time.Second,
+			pollInterval:   time.Millisecond,
+			savedGiftsFunc: func(ctx context.Context, peer tg.InputPeerClass) ([]tg.SavedStarGift, error) {
+				// The gift shows up on the third poll, next to an older copy and another gift
+				if atomic.AddInt32(&polls, 1) < 3 {
+					return []tg.SavedStarGift{savedGift(1, 10), savedGift(2, 11)}, nil
+				}
+				return []tg.SavedStarGift{savedGift(1, 12), savedGift(1, 10), savedGift(2, 11)}, nil
+			},
+		}
+
+		before, err := processor.ownedGifts(context.Background(), peer, 1)
+		require.NoError(t, err)
+		err = processor.confirmPurchase(context.Background(), peer, 1, before)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&polls))
+	})
+
+	t.Run("покупка не подтверждена за таймаут", func(t *testing.T) {
+		processor := &PurchaseProcessorImpl{
+			verifyPurchase: true,
+			verifyTimeout:  20 * time.Millisecond,
+			pollInterval:   time.Millisecond,
+			savedGiftsFunc: func(ctx context.Context, peer tg.InputPeerClass) ([]tg.SavedStarGift, error) {
+				return []tg.SavedStarGift{savedGift(1, 10)}, nil
+			},
+		}
+
+		err := processor.confirmPurchase(context.Background(), peer, 1, map[savedGiftKey]struct{}{{msgID: 10}: {}})
+
+		assert.ErrorIs(t, err, errors.ErrPurchaseUnconfirmed)
+		assert.Contains(t, err.Error(), "gift 1 not seen")
+	})
+
+	t.Run("ошибки получения подарков не подтверждают покупку", func(t *testing.T) {
+		processor := &PurchaseProcessorImpl{
+			verifyPurchase: true,
+			verifyTimeout:  20 * time.Millisecond,
+			pollInterval:   time.Millisecond,
+			savedGiftsFunc: func(ctx context.Context, peer tg.InputPeerClass) ([]tg.SavedStarGift, error) {
+				return nil, assert.AnError
+			},
+		}
+
+		err := processor.confirmPurchase(context.Background(), peer, 1, nil)
+
+		assert.ErrorIs(t, err, errors.ErrPurchaseUnconfirmed)
+	})
+
+	t.Run("один новый подарок подтверждает только одну покупку", func(t *testing.T) {
+		processor := &PurchaseProcessorImpl{
+			verifyPurchase: true,
+			verifyTimeout:  50 * time.Millisecond,
+			pollInterval:   time.Millisecond,
+			savedGiftsFunc: func(ctx context.Context, peer tg.InputPeerClass) ([]tg.SavedStarGift, error) {
+				return []tg.SavedStarGift{savedGift(1, 12)}, nil
+			},
+		}
+
+		results := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				results <- processor.confirmPurchase(context.Background(), peer, 1, nil)
+			}()
+		}
+
+		var confirmed, unconfirmed int
+		for i := 0; i < 2; i++ {
+			if err := <-results; err == nil {
+				confirmed++
+			} else if errors.Is(err, errors.ErrPurchaseUnconfirmed) {
+				unconfirmed++
+			}
+		}
+		assert.Equal(t, 1, confirmed)
+		assert.Equal(t, 1, unconfirmed)
+	})
+
+	t.Run("отмена во время проверки не повторяет покупку", func(t *testing.T) {
+		processor := &PurchaseProcessorImpl{
+			verifyPurchase: true,
+			verifyTimeout:  time.Second,
+			pollInterval:   time.Millisecond,
+			savedGiftsFunc: func(ctx context.Context, peer tg.InputPeerClass) ([]tg.SavedStarGift, error) {
+				return nil, nil
+			},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := processor.confirmPurchase(ctx, peer, 1, nil)
+
+		assert.ErrorIs(t, err, errors.ErrPurchaseUnconfirmed)
+	})
+}
+
+func TestPurchaseProcessorImpl_ValidatePurchase(t *testing.T) {
+	processor := &PurchaseProcessorImpl{
+		balanceFunc: func(ctx context.Context) (int64, error) {
+			return 500, nil
+		},
+	}
+
+	balance, ok := processor.validatePurchase(context.Background(), createTestGift(1, 100))
+	assert.True(t, ok)
+	assert.Equal(t, int64(500), balance)
+
+	_, ok = processor.validatePurchase(context.Background(), createTestGift(2, 1000))
+	assert.False(t, ok)
+}
+
+// sendStarsInvoker answers payments.sendStarsForm with a verification request
+type sendStarsInvoker struct {
+	calls int
+}
+
+func (i *sendStarsInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	i.calls++
+	output.(*tg.PaymentsPaymentResultBox).PaymentResult = &tg.PaymentsPaymentVerificationNeeded{URL: "https://pay.example/secret-token"}
+	return nil
+}
+
+func TestPurchaseProcessorImpl_VerboseLogging(t *testing.T) {
+	invoice := &tg.InputInvoiceStarGift{
+		Peer:    &tg.InputPeerChannel{ChannelID: 10, AccessHash: 123456789},
+		GiftID:  1,
+		Message: tg.TextWithEntities{Text: "secret message"},
+	}
+
+	t.Run("запрос и ответ логируются без чувствительных полей", func(t *testing.T) {
+		invoker := &sendStarsInvoker{}
+		processor := NewPurchaseProcessor(tg.NewClient(invoker), &MockPaymentProcessor{}, false, 0)
+
+		var lines []string
+		processor.SetVerboseLogging(func(format string, args ...interface{}) {
+			lines = append(lines, fmt.Sprintf(format, args...))
+		})
+
+		assert.NoError(t, processor.sendStarsForm(context.Background(), invoice, 12345))
+
+		assert.Equal(t, 1, invoker.calls)
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[0], "form_id=12345")
+		assert.Contains(t, lines[0], "channel{id=10")
+		assert.Contains(t, lines[1], "payment_verification_needed")
+		for _, line := range lines {
+			assert.NotContains(t, line, "123456789")
+			assert.NotContains(t, line, "secret message")
+			assert.NotContains(t, line, "secret-token")
+		}
+	})
+
+	t.Run("без флага ничего не логируется", func(t *testing.T) {
+		invoker := &sendStarsInvoker{}
+		processor := NewPurchaseProcessor(tg.NewClient(invoker), &MockPaymentProcessor{}, false, 0)
+
+		assert.NoError(t, processor.sendStarsForm(context.Background(), invoice, 12345))
+
+		assert.Equal(t, 1, invoker.calls)
+		assert.Nil(t, processor.debugf)
+	})
+}
+
+// chargingInvoker charges the gift price from the balance on every payments.sendStarsForm
+type chargingInvoker struct {
+	balance *int64
+	price   int64
+}
+
+func (i *chargingInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	atomic.AddInt64(i.balance, -i.price)
+	output.(*tg.PaymentsPaymentResultBox).PaymentResult = &tg.PaymentsPaymentResult{Updates: &tg.Updates{}}
+	return nil
+}
+
+func TestPurchaseProcessorImpl_BalanceReserve(t *testing.T) {
+	newProcessor := func(balance *int64, reserve int64) *PurchaseProcessorImpl {
+		mockPaymentProcessor := &MockPaymentProcessor{}
+		mockPaymentProcessor.On("CreatePaymentForm", mock.Anything, mock.Anything).
+			Return(&tg.PaymentsPaymentFormStars{FormID: 1}, createTestInvoice(1), nil)
+
+		processor := NewPurchaseProcessor(tg.NewClient(&chargingInvoker{balance: balance, price: 150}), mockPaymentProcessor, false, 0)
+		processor.balanceFunc = func(ctx context.Context) (int64, error) {
+			return atomic.LoadInt64(balance), nil
+		}
+		processor.SetBalanceReserve(reserve)
+		return processor
+	}
+
+	t.Run("покупки прекращаются у границы резерва", func(t *testing.T) {
+		balance := int64(1000)
+		processor := newProcessor(&balance, 600)
+		giftRequire := createTestGiftRequire(createTestGift(1, 150))
+
+		// 1000 -> 850 -> 700, the third purchase would leave 550 below the reserve
+		assert.NoError(t, processor.PurchaseGift(context.Background(), giftRequire))
+		assert.NoError(t, processor.PurchaseGift(context.Background(), giftRequire))
+
+		err := processor.PurchaseGift(context.Background(), giftRequire)
+		assert.True(t, errors.Is(err, errors.ErrBalanceReserveReached))
+		assert.Equal(t, int64(700), balance)
+		assert.Equal(t, int64(0), processor.pendingSpend)
+	})
+
+	t.Run("покупка, оставляющая ровно резерв, разрешена", func(t *testing.T) {
+		balance := int64(750)
+		processor := newProcessor(&balance, 600)
+
+		assert.NoError(t, processor.PurchaseGift(context.Background(), createTestGiftRequire(createTestGift(1, 150))))
+		assert.Equal(t, int64(600), balance)
+	})
+
+	t.Run("покупки в процессе учитываются", func(t *testing.T) {
+		processor := &PurchaseProcessorImpl{balanceReserve: 600}
+
+		assert.True(t, processor.reserveSpend(1000, 150))
+		assert.True(t, processor.reserveSpend(1000, 150))
+		assert.False(t, processor.reserveSpend(1000, 150))
+
+		processor.releaseSpend(150)
+		assert.True(t, processor.reserveSpend(1000, 150))
+	})
+
+	t.Run("без резерва тратится весь баланс", func(t *testing.T) {
+		balance := int64(300)
+		processor := newProcessor(&balance, 0)
+		giftRequire := createTestGiftRequire(createTestGift(1, 150))
+
+		assert.NoError(t, processor.PurchaseGift(context.Background(), giftRequire))
+		assert.NoError(t, processor.PurchaseGift(context.Background(), giftRequire))
+		assert.Equal(t, int64(0), balance)
+	})
+}
+
+// failingRateLimiter refuses every token
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Acquire(ctx context.Context) error {
+	return errors.New("rate limit exceeded")
+}
+
+func (failingRateLimiter) Close() {}
+
+func TestPurchaseProcessorImpl_SendStarsFormRateLimit(t *testing.T) {
+	invoice := &tg.InputInvoiceStarGift{Peer: &tg.InputPeerSelf{}, GiftID: 1}
+
+	t.Run("оплата ждет токен своего лимита", func(t *testing.T) {
+		invoker := &sendStarsInvoker{}
+		processor := NewPurchaseProcessor(tg.NewClient(invoker), &MockPaymentProcessor{}, false, 0)
+		processor.SetRateLimiter(failingRateLimiter{})
+
+		err := processor.sendStarsForm(context.Background(), invoice, 12345)
+
+		assert.ErrorContains(t, err, "failed to wait for rate limit")
+		assert.Equal(t, 0, invoker.calls)
+	})
+
+	t.Run("без лимита оплата отправляется сразу", func(t *testing.T) {
+		invoker := &sendStarsInvoker{}
+		processor := NewPurchaseProcessor(tg.NewClient(invoker), &MockPaymentProcessor{}, false, 0)
+
+		assert.NoError(t, processor.sendStarsForm(context.Background(), invoice, 12345))
+		assert.Equal(t, 1, invoker.calls)
+	})
+}
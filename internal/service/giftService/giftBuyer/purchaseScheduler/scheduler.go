@@ -0,0 +1,156 @@
+// Package purchaseScheduler provides a purchase queue that staggers purchases over time.
+// It sits between the gift monitor and the buyer and dispatches the queued gifts at a
+// configured rate instead of buying everything in a single burst.
+package purchaseScheduler
+
+import (
+	"context"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+	"gift-buyer/internal/service/giftService/giftTypes"
+	"sync"
+	"time"
+)
+
+// purchaseSchedulerImpl queues eligible gifts and hands them to the wrapped buyer one
+// purchase at a time, at most perMinute purchases per minute.
+type purchaseSchedulerImpl struct {
+	// buyer performs the dispatched purchases
+	buyer giftInterfaces.GiftBuyer
+
+	// interval is the time between two dispatched purchases
+	interval time.Duration
+
+	// queue holds the purchases waiting for dispatch
+	queue []scheduledPurchase
+	mu    sync.Mutex
+
+	// wake signals the dispatcher that the queue is not empty
+	wake chan struct{}
+
+	// stop terminates the dispatcher on Close
+	stop      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// scheduledPurchase is a queued purchase with the receiver slot of its first unit.
+type scheduledPurchase struct {
+	gift *giftTypes.GiftRequire
+
+	// slot is the position of the unit in the original batch, so that receiver
+	// rotation spreads the separately dispatched units like a whole batch
+	slot int64
+}
+
+// NewPurchaseScheduler creates a purchase queue in front of the buyer.
+//
+// Parameters:
+//   - buyer: buyer performing the dispatched purchases
+//   - perMinute: number of purchases dispatched per minute
+//
+// Returns:
+//   - *purchaseSchedulerImpl: scheduler implementing the GiftBuyer interface
+func NewPurchaseScheduler(buyer giftInterfaces.GiftBuyer, perMinute int) *purchaseSchedulerImpl {
+	if perMinute <= 0 {
+		perMinute = 1
+	}
+
+	return &purchaseSchedulerImpl{
+		buyer:    buyer,
+		interval: time.Minute / time.Duration(perMinute),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+}
+
+// BuyGift enqueues the gifts for scheduled dispatch and returns immediately.
+// Every unit of a gift is dispatched separately, except broadcast, staged and weighted
+// distribution gifts whose units depend on each other and are therefore dispatched as a whole.
+//
+// Parameters:
+//   - ctx: context of the dispatcher, cancelling it stops dispatching
+//   - gifts: eligible gifts to buy
+func (ps *purchaseSchedulerImpl) BuyGift(ctx context.Context, gifts []*giftTypes.GiftRequire) {
+	ps.startOnce.Do(func() {
+		go ps.dispatch(ctx)
+	})
+
+	ps.mu.Lock()
+	for _, gift := range gifts {
+		if gift.BroadcastToAllReceivers || len(gift.Stages) > 0 || len(gift.ReceiverDistribution) > 0 || gift.CountForBuy <= 1 {
+			ps.queue = append(ps.queue, scheduledPurchase{gift: gift})
+			continue
+		}
+		for i := int64(0); i < gift.CountForBuy; i++ {
+			unit := *gift
+			unit.CountForBuy = 1
+			unit.ReceiverCursor = 0
+			ps.queue = append(ps.queue, scheduledPurchase{gift: &unit, slot: i})
+		}
+	}
+	ps.mu.Unlock()
+
+	select {
+	case ps.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pending returns the number of queued purchases waiting for dispatch.
+func (ps *purchaseSchedulerImpl) Pending() int {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return len(ps.queue)
+}
+
+// Close stops dispatching and closes the wrapped buyer. Queued purchases are dropped.
+func (ps *purchaseSchedulerImpl) Close() {
+	ps.stopOnce.Do(func() {
+		close(ps.stop)
+	})
+	ps.buyer.Close()
+}
+
+// dispatch hands queued gifts to the buyer one at a time, interval apart.
+func (ps *purchaseSchedulerImpl) dispatch(ctx context.Context) {
+	for {
+		purchase, ok := ps.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ps.stop:
+				return
+			case <-ps.wake:
+				continue
+			}
+		}
+
+		ps.buyer.BuyGift(giftTypes.WithReceiverSlot(ctx, purchase.slot), []*giftTypes.GiftRequire{purchase.gift})
+
+		timer := time.NewTimer(ps.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-ps.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// next pops the oldest queued purchase, false if the queue is empty.
+func (ps *purchaseSchedulerImpl) next() (scheduledPurchase, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if len(ps.queue) == 0 {
+		return scheduledPurchase{}, false
+	}
+	purchase := ps.queue[0]
+	ps.queue[0] = scheduledPurchase{}
+	ps.queue = ps.queue[1:]
+	return purchase, true
+}
@@ -0,0 +1,149 @@
+package purchaseScheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gift-buyer/internal/service/giftService/giftTypes"
+
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingBuyer records every dispatched batch with its dispatch time
+type recordingBuyer struct {
+	mu      sync.Mutex
+	batches [][]*giftTypes.GiftRequire
+	times   []time.Time
+	slots   []int64
+	closed  bool
+}
+
+func (b *recordingBuyer) BuyGift(ctx context.Context, gifts []*giftTypes.GiftRequire) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batches = append(b.batches, gifts)
+	b.times = append(b.times, time.Now())
+	slot, _ := giftTypes.ReceiverSlot(ctx)
+	b.slots = append(b.slots, slot)
+}
+
+func (b *recordingBuyer) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
+
+func (b *recordingBuyer) dispatched() ([][]*giftTypes.GiftRequire, []time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][]*giftTypes.GiftRequire(nil), b.batches...), append([]time.Time(nil), b.times...)
+}
+
+func TestPurchaseScheduler_DispatchesPerSchedule(t *testing.T) {
+	buyer := &recordingBuyer{}
+	// 600 per minute is one purchase every 100ms
+	scheduler := NewPurchaseScheduler(buyer, 600)
+	defer scheduler.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduler.BuyGift(ctx, []*giftTypes.GiftRequire{
+		{Gift: &tg.StarGift{ID: 1, Stars: 100}, CountForBuy: 3, ReceiverType: []int{1}, Hide: true},
+		{Gift: &tg.StarGift{ID: 2, Stars: 200}, CountForBuy: 1, ReceiverType: []int{0}},
+	})
+
+	// Only the first purchase is dispatched right away, the rest waits in the queue
+	time.Sleep(30 * time.Millisecond)
+	batches, _ := buyer.dispatched()
+	assert.Len(t, batches, 1)
+	assert.Equal(t, 3, scheduler.Pending())
+
+	assert.Eventually(t, func() bool {
+		batches, _ := buyer.dispatched()
+		return len(batches) == 4
+	}, 2*time.Second, 10*time.Millisecond)
+
+	batches, times := buyer.dispatched()
+	for i, batch := range batches {
+		require.Len(t, batch, 1)
+		assert.Equal(t, int64(1), batch[0].CountForBuy)
+		if i > 0 {
+			assert.GreaterOrEqual(t, times[i].Sub(times[i-1]), 90*time.Millisecond)
+		}
+	}
+	assert.Equal(t, int64(1), batches[0][0].Gift.ID)
+	assert.True(t, batches[0][0].Hide)
+	assert.Equal(t, int64(2), batches[3][0].Gift.ID)
+}
+
+func TestPurchaseScheduler_UnitsKeepReceiverSlots(t *testing.T) {
+	buyer := &recordingBuyer{}
+	scheduler := NewPurchaseScheduler(buyer, 6000)
+	defer scheduler.Close()
+
+	// With receiver rotation every unit must keep its position in the original batch
+	scheduler.BuyGift(context.Background(), []*giftTypes.GiftRequire{
+		{Gift: &tg.StarGift{ID: 1, Stars: 100}, CountForBuy: 3, ReceiverType: []int{1}, StarBudget: 300, SkipNotify: true, ReceiverCursor: 7},
+	})
+
+	assert.Eventually(t, func() bool {
+		batches, _ := buyer.dispatched()
+		return len(batches) == 3
+	}, 2*time.Second, 10*time.Millisecond)
+
+	buyer.mu.Lock()
+	defer buyer.mu.Unlock()
+	assert.Equal(t, []int64{0, 1, 2}, buyer.slots)
+	for _, batch := range buyer.batches {
+		require.Len(t, batch, 1)
+		assert.Equal(t, int64(1), batch[0].CountForBuy)
+		assert.Equal(t, int64(300), batch[0].StarBudget)
+		assert.True(t, batch[0].SkipNotify)
+		assert.Zero(t, batch[0].ReceiverCursor)
+	}
+}
+
+func TestPurchaseScheduler_BroadcastDispatchedWhole(t *testing.T) {
+	buyer := &recordingBuyer{}
+	scheduler := NewPurchaseScheduler(buyer, 600)
+	defer scheduler.Close()
+
+	gift := &giftTypes.GiftRequire{Gift: &tg.StarGift{ID: 1}, CountForBuy: 5, ReceiverType: []int{1}, BroadcastToAllReceivers: true}
+	scheduler.BuyGift(context.Background(), []*giftTypes.GiftRequire{gift})
+
+	assert.Eventually(t, func() bool {
+		batches, _ := buyer.dispatched()
+		return len(batches) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	batches, _ := buyer.dispatched()
+	assert.Same(t, gift, batches[0][0])
+	assert.Equal(t, 0, scheduler.Pending())
+}
+
+func TestPurchaseScheduler_StopsOnCancel(t *testing.T) {
+	buyer := &recordingBuyer{}
+	scheduler := NewPurchaseScheduler(buyer, 60)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.BuyGift(ctx, []*giftTypes.GiftRequire{
+		{Gift: &tg.StarGift{ID: 1}, CountForBuy: 3, ReceiverType: []int{1}},
+	})
+
+	assert.Eventually(t, func() bool {
+		batches, _ := buyer.dispatched()
+		return len(batches) == 1
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	scheduler.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	batches, _ := buyer.dispatched()
+	assert.Len(t, batches, 1)
+	assert.True(t, buyer.closed)
+}
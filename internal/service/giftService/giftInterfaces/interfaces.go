@@ -182,6 +182,41 @@ type NotificationService interface {
 	// Returns:
 	//   - error: notification sending error or API communication error
 	SendUpdateNotification(ctx context.Context, version, message string) error
+
+	// SendStartupSnapshot sends a summary of the gift store taken on startup.
+	//
+	// Parameters:
+	//   - ctx: context for request cancellation and timeout control
+	//   - available: number of gifts currently available
+	//   - matching: number of available gifts matching the purchase criteria
+	//
+	// Returns:
+	//   - error: notification sending error or API communication error
+	SendStartupSnapshot(ctx context.Context, available, matching int) error
+
+	// SendLimitReachedNotification sends a notification that a purchase limit was reached.
+	//
+	// Parameters:
+	//   - ctx: context for request cancellation and timeout control
+	//   - limit: human-readable name of the reached limit
+	//   - max: configured value of the limit
+	//
+	// Returns:
+	//   - error: notification sending error or API communication error
+	SendLimitReachedNotification(ctx context.Context, limit string, max int64) error
+
+	// SendSoldOutNotification sends a notification that a known gift sold out.
+	//
+	// Parameters:
+	//   - ctx: context for request cancellation and timeout control
+	//   - gift: the gift that sold out
+	//
+	// Returns:
+	//   - error: notification sending error or API communication error
+	SendSoldOutNotification(ctx context.Context, gift *tg.StarGift) error
+
+	// Close releases any resources held by the notification service (e.g., rate limiter).
+	Close()
 }
 
 // UserCache defines the interface for caching user and channel information.
@@ -240,6 +275,58 @@ type RateLimiter interface {
 	Close()
 }
 
+// AttemptPacer defines the interface for a global cap on purchase attempts per second.
+// Unlike RateLimiter it spaces attempts evenly and never allows bursts.
+type AttemptPacer interface {
+	// Wait blocks until the next attempt may be dispatched or the context is cancelled.
+	//
+	// Parameters:
+	//   - ctx: context for request cancellation and timeout control
+	//
+	// Returns:
+	//   - error: context error if cancelled while waiting
+	Wait(ctx context.Context) error
+}
+
+// SessionState defines the interface for the persisted state of a buying session.
+// It allows a restarted instance to skip gifts that were already claimed, bought
+// or notified about during the same drop.
+type SessionState interface {
+	// IsClaimed reports whether the gift was already handed over for purchase.
+	IsClaimed(id int64) bool
+
+	// Claim marks the gift as handed over for purchase.
+	Claim(id int64)
+
+	// IsNotified reports whether the user was already notified about the gift.
+	IsNotified(id int64) bool
+
+	// MarkNotified records that the user was notified about the gift.
+	MarkNotified(id int64)
+
+	// RecordPurchase records one successfully bought unit of the gift.
+	RecordPurchase(id int64)
+
+	// BoughtCount returns the number of gifts bought in this session.
+	BoughtCount() int64
+}
+
+// BalanceCache defines the interface for the cached stars balance of the account.
+// It lets the buyer skip purchases the balance can't cover without a payment request.
+type BalanceCache interface {
+	// SetBalance replaces the cached balance.
+	SetBalance(balance int64)
+
+	// GetBalance returns the cached balance.
+	GetBalance() int64
+
+	// TrimBalance subtracts spent stars from the cached balance.
+	TrimBalance(deduction int64)
+
+	// RefreshBalance requests the current balance from Telegram and caches it.
+	RefreshBalance(ctx context.Context) error
+}
+
 type AccountManager interface {
 	SetIds(ctx context.Context) error
 }
@@ -253,9 +340,10 @@ type InvoiceCreator interface {
 	// and includes appropriate peer information and gift details.
 	//
 	// Parameters:
+	//   - ctx: context of the purchased unit, carrying its receiver slot
 	//   - gift: the star gift to create an invoice for
 	//
-	CreateInvoice(*giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error)
+	CreateInvoice(ctx context.Context, gift *giftTypes.GiftRequire) (*tg.InputInvoiceStarGift, error)
 }
 
 // PaymentProcessor defines the interface for processing purchases.
@@ -327,6 +415,26 @@ type Counter interface {
 	GetMax() int64
 }
 
+// CapCounter defines the interface for counting reserved units by key against a cap.
+// One instance is shared by the buyers of all accounts, so that a cap holds across them.
+type CapCounter interface {
+	// TryReserve reserves one unit of the key if fewer than max units are reserved.
+	//
+	// Parameters:
+	//   - key: identifies the capped units
+	//   - max: maximum number of units of the key
+	//
+	// Returns:
+	//   - bool: true if the unit was reserved, false if the cap is reached
+	TryReserve(key string, max int64) bool
+
+	// Release gives back a unit reserved with TryReserve whose purchase did not happen.
+	//
+	// Parameters:
+	//   - key: identifies the capped units
+	Release(key string)
+}
+
 // ErrorLogger defines the interface for logging errors.
 // It provides methods to log errors and formatted errors.
 type ErrorLogger interface {
@@ -0,0 +1,67 @@
+package giftManager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gift-buyer/pkg/errors"
+	"os"
+
+	"github.com/gotd/td/tg"
+)
+
+// fixtureGiftManager implements the Giftmanager interface with gifts recorded in a JSON
+// file instead of the Telegram API, for reproducible test runs. The file is a JSON array
+// of tg.StarGift objects with the Go field names as keys, e.g.
+// [{"ID": 1, "Stars": 100, "Title": "Rocket", "Limited": true, "AvailabilityTotal": 1000}].
+// It is read on every request, so editing it while running simulates catalog changes.
+type fixtureGiftManager struct {
+	// path is the fixture file with the recorded gifts
+	path string
+}
+
+// NewFixtureGiftManager creates a gift manager returning the gifts of a fixture file.
+//
+// Parameters:
+//   - path: JSON file with the recorded gifts
+//
+// Returns:
+//   - *fixtureGiftManager: gift manager reading the fixture
+func NewFixtureGiftManager(path string) *fixtureGiftManager {
+	return &fixtureGiftManager{path: path}
+}
+
+// GetAvailableGifts returns the gifts recorded in the fixture file.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//
+// Returns:
+//   - []*tg.StarGift: gifts of the fixture
+//   - error: read or parse error of the fixture file
+func (fm *fixtureGiftManager) GetAvailableGifts(ctx context.Context) ([]*tg.StarGift, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(fm.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read gift fixture")
+	}
+
+	var gifts []*tg.StarGift
+	if err := json.Unmarshal(data, &gifts); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to parse gift fixture %s", fm.path))
+	}
+
+	giftList := make([]*tg.StarGift, 0, len(gifts))
+	for _, gift := range gifts {
+		if gift == nil {
+			continue
+		}
+		// The conditional fields like Title are only visible with their flags set
+		gift.SetFlags()
+		giftList = append(giftList, gift)
+	}
+	return giftList, nil
+}
@@ -0,0 +1,95 @@
+package giftManager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFixture(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "gifts.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestFixtureGiftManager_GetAvailableGifts(t *testing.T) {
+	var _ giftInterfaces.Giftmanager = &fixtureGiftManager{}
+
+	t.Run("подарки из фикстуры", func(t *testing.T) {
+		path := writeFixture(t, `[
+			{"ID": 1, "Stars": 100, "ConvertStars": 85, "Title": "Rocket", "Limited": true, "AvailabilityRemains": 250, "AvailabilityTotal": 1000},
+			{"ID": 2, "Stars": 15, "Sticker": null},
+			null
+		]`)
+
+		gifts, err := NewFixtureGiftManager(path).GetAvailableGifts(context.Background())
+
+		require.NoError(t, err)
+		require.Len(t, gifts, 2)
+
+		assert.Equal(t, int64(1), gifts[0].ID)
+		assert.Equal(t, int64(100), gifts[0].Stars)
+		assert.Equal(t, int64(85), gifts[0].GetConvertStars())
+		assert.True(t, gifts[0].Limited)
+		title, ok := gifts[0].GetTitle()
+		assert.True(t, ok)
+		assert.Equal(t, "Rocket", title)
+		remains, ok := gifts[0].GetAvailabilityRemains()
+		assert.True(t, ok)
+		assert.Equal(t, 250, remains)
+		total, ok := gifts[0].GetAvailabilityTotal()
+		assert.True(t, ok)
+		assert.Equal(t, 1000, total)
+
+		assert.Equal(t, int64(2), gifts[1].ID)
+		_, ok = gifts[1].GetTitle()
+		assert.False(t, ok)
+	})
+
+	t.Run("изменения файла видны при следующем запросе", func(t *testing.T) {
+		path := writeFixture(t, `[{"ID": 1, "Stars": 100}]`)
+		manager := NewFixtureGiftManager(path)
+
+		gifts, err := manager.GetAvailableGifts(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, gifts, 1)
+
+		require.NoError(t, os.WriteFile(path, []byte(`[{"ID": 1, "Stars": 100}, {"ID": 2, "Stars": 500}]`), 0o644))
+		gifts, err = manager.GetAvailableGifts(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, gifts, 2)
+	})
+
+	t.Run("отсутствующий файл", func(t *testing.T) {
+		_, err := NewFixtureGiftManager(filepath.Join(t.TempDir(), "missing.json")).GetAvailableGifts(context.Background())
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read gift fixture")
+	})
+
+	t.Run("некорректный JSON", func(t *testing.T) {
+		path := writeFixture(t, `{"ID": 1}`)
+
+		_, err := NewFixtureGiftManager(path).GetAvailableGifts(context.Background())
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse gift fixture")
+	})
+
+	t.Run("отмененный контекст", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := NewFixtureGiftManager(writeFixture(t, `[]`)).GetAvailableGifts(ctx)
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -5,7 +5,10 @@ package giftManager
 
 import (
 	"context"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/pkg/errors"
+	"gift-buyer/pkg/tracing"
+	"time"
 
 	"github.com/gotd/td/tg"
 )
@@ -16,6 +19,9 @@ import (
 type giftManagerImpl struct {
 	// api is the Telegram client used for API communication
 	api *tg.Client
+
+	// rateLimiter limits the gift catalog requests (optional)
+	rateLimiter giftInterfaces.RateLimiter
 }
 
 // NewGiftManager creates a new GiftManager instance with the specified Telegram API client.
@@ -30,6 +36,11 @@ func NewGiftManager(api *tg.Client) *giftManagerImpl {
 	return &giftManagerImpl{api: api}
 }
 
+// SetRateLimiter sets the rate limiter acquired before every gift catalog request.
+func (gm *giftManagerImpl) SetRateLimiter(rateLimiter giftInterfaces.RateLimiter) {
+	gm.rateLimiter = rateLimiter
+}
+
 // GetAvailableGifts retrieves all currently available star gifts from Telegram.
 // It makes an API call to fetch the gift catalog and parses the response
 // to extract individual StarGift objects.
@@ -51,7 +62,16 @@ func NewGiftManager(api *tg.Client) *giftManagerImpl {
 //   - Unexpected response type from the API
 //   - Context cancellation or timeout
 func (gm *giftManagerImpl) GetAvailableGifts(ctx context.Context) ([]*tg.StarGift, error) {
+	if gm.rateLimiter != nil {
+		if err := gm.rateLimiter.Acquire(ctx); err != nil {
+			return nil, errors.Wrap(err, "failed to wait for rate limit")
+		}
+	}
+
+	start := time.Now()
+	ctx, span := tracing.StartSpan(ctx, "api.payments.getStarGifts")
 	gifts, err := gm.api.PaymentsGetStarGifts(ctx, 0)
+	tracing.Finish(span, start, "", err)
 	if err != nil {
 		return nil, err
 	}
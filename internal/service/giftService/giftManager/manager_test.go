@@ -1,6 +1,8 @@
 package giftManager
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/gotd/td/tg"
@@ -85,3 +87,23 @@ func TestGiftManagerImpl_FieldAccess(t *testing.T) {
 	impl.api = newClient
 	assert.Equal(t, newClient, impl.api)
 }
+
+// failingRateLimiter refuses every token
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Acquire(ctx context.Context) error {
+	return errors.New("rate limit exceeded")
+}
+
+func (failingRateLimiter) Close() {}
+
+func TestGiftManagerImpl_GetAvailableGifts_RateLimit(t *testing.T) {
+	manager := NewGiftManager(nil)
+	manager.SetRateLimiter(failingRateLimiter{})
+
+	// The limiter is acquired before the (nil) client is used
+	gifts, err := manager.GetAvailableGifts(context.Background())
+
+	assert.Nil(t, gifts)
+	assert.ErrorContains(t, err, "failed to wait for rate limit")
+}
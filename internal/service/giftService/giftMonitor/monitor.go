@@ -9,11 +9,29 @@ import (
 	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/internal/service/giftService/giftTypes"
 	"gift-buyer/pkg/errors"
+	"gift-buyer/pkg/metrics"
+	"gift-buyer/pkg/tracing"
+	"math/rand"
 
 	"sync"
 	"time"
+
+	"github.com/gotd/td/tg"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// recheckValidator is implemented by validators that can ask for a gift to be
+// validated again on the next cycle instead of being cached as processed.
+type recheckValidator interface {
+	ShouldRecheck(gift *tg.StarGift) bool
+}
+
+// supplyDropValidator is implemented by validators that ask for a cached gift to be
+// evaluated again once its remaining supply crosses a criteria threshold.
+type supplyDropValidator interface {
+	CrossedRemainsThreshold(cached, fresh *tg.StarGift) bool
+}
+
 // giftMonitorImpl implements the GiftMonitor interface for monitoring new gifts.
 // It periodically checks for new gifts, validates them against criteria,
 // and manages caching to avoid duplicate processing.
@@ -40,7 +58,8 @@ type giftMonitorImpl struct {
 	// paused indicates if monitoring is currently paused
 	paused bool
 
-	// firstRun indicates if the monitor is running for the first time
+	// firstRun indicates if the monitor is running for the first time. Like snapshotSent it
+	// is only accessed by one check at a time, Start never runs checks concurrently
 	firstRun bool
 
 	// mu protects the paused field from concurrent access
@@ -48,8 +67,50 @@ type giftMonitorImpl struct {
 
 	// testMode indicates if the monitor is running in test mode
 	testMode bool
+
+	// startupSnapshot enables a one-time summary notification on the first gift check
+	startupSnapshot bool
+
+	// snapshotSent indicates that the startup snapshot has already been handled
+	snapshotSent bool
+
+	// sessionState remembers gifts claimed for purchase across restarts (optional)
+	sessionState giftInterfaces.SessionState
+
+	// burstRemaining is the number of startup burst checks not yet run
+	burstRemaining int
+
+	// burstInterval is the delay between startup burst checks
+	burstInterval time.Duration
+
+	// burstStarted indicates that the first burst check has already been scheduled
+	burstStarted bool
+
+	// tickTime is the regular interval between gift checks
+	tickTime time.Duration
+
+	// maxBackoff caps the interval between checks while they keep failing (0 disables the backoff)
+	maxBackoff time.Duration
+
+	// consecutiveErrors counts the checks that failed in a row
+	consecutiveErrors int
+
+	// jitter returns a random number in [0, 1) used to spread backoff delays
+	jitter func() float64
+
+	// fetchTimeout bounds a single gift catalog request (0 to disable)
+	fetchTimeout time.Duration
+
+	// recoverPanics skips gifts that make the validator panic instead of crashing the monitor
+	recoverPanics bool
 }
 
+// errFirstRun is returned by the first check, which only fills the cache
+var errFirstRun = errors.New("first run")
+
+// backoffJitter is the random spread of backoff delays as a fraction of the delay
+const backoffJitter = 0.2
+
 // NewGiftMonitor creates a new GiftMonitor instance with the specified dependencies.
 // The monitor will check for new gifts at the specified interval and process
 // them through the validation and notification pipeline.
@@ -60,6 +121,8 @@ type giftMonitorImpl struct {
 //   - validator: gift validator for eligibility checking
 //   - notification: notification service for sending alerts
 //   - tickTime: interval between gift checks
+//   - maxBackoff: maximum interval between checks while they keep failing, 0 disables the backoff
+//   - startupSnapshot: send a summary of available and matching gifts on the first check
 //
 // Returns:
 //   - giftInterfaces.GiftMonitor: configured gift monitor instance
@@ -69,9 +132,11 @@ func NewGiftMonitor(
 	validator giftInterfaces.GiftValidator,
 	notification giftInterfaces.NotificationService,
 	tickTime time.Duration,
+	maxBackoff time.Duration,
 	errorLogsWriter giftInterfaces.ErrorLogger,
 	infoLogsWriter giftInterfaces.InfoLogger,
 	testMode bool,
+	startupSnapshot bool,
 ) *giftMonitorImpl {
 	return &giftMonitorImpl{
 		cache:           cache,
@@ -79,10 +144,14 @@ func NewGiftMonitor(
 		validator:       validator,
 		notification:    notification,
 		ticker:          time.NewTicker(tickTime),
+		tickTime:        tickTime,
+		maxBackoff:      maxBackoff,
+		jitter:          rand.Float64,
 		firstRun:        true,
 		errorLogsWriter: errorLogsWriter,
 		infoLogsWriter:  infoLogsWriter,
 		testMode:        testMode,
+		startupSnapshot: startupSnapshot,
 	}
 }
 
@@ -107,31 +176,41 @@ func NewGiftMonitor(
 func (gm *giftMonitorImpl) Start(ctx context.Context) ([]*giftTypes.GiftRequire, error) {
 	resultCh := make(chan []*giftTypes.GiftRequire, 10)
 	errCh := make(chan error, 10)
+	okCh := make(chan struct{}, 10)
+	burst := gm.nextBurst()
+
+	// checking is set while a check runs. Checks never overlap: a second check would walk
+	// the cache before the first one filled it and report the whole catalog as new
+	checking := false
+	startCheck := func() {
+		if checking || gm.IsPaused() {
+			return
+		}
+		checking = true
+		go gm.runCheck(ctx, resultCh, errCh, okCh)
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
+		case <-burst:
+			gm.burstRemaining--
+			burst = gm.nextBurst()
+			startCheck()
 		case <-gm.ticker.C:
-			if gm.IsPaused() {
-				continue
-			}
-
-			go func() {
-				newGifts, err := gm.checkForNewGifts(ctx)
-				if err != nil {
-					errCh <- err
-					return
-				}
-				if len(newGifts) == 0 {
-					gm.infoLogsWriter.LogInfo("no new gifts found")
-					return
-				}
-				resultCh <- newGifts
-			}()
+			startCheck()
+		case <-okCh:
+			checking = false
+			gm.resetBackoff()
 		case newGifts := <-resultCh:
+			gm.resetBackoff()
 			return newGifts, nil
 		case err := <-errCh:
+			checking = false
+			if !errors.Is(err, errFirstRun) {
+				gm.backoff()
+			}
 			if !gm.IsPaused() {
 				if notifErr := gm.notification.SendErrorNotification(ctx, err); notifErr != nil {
 					gm.errorLogsWriter.LogError(notifErr.Error())
@@ -143,6 +222,96 @@ func (gm *giftMonitorImpl) Start(ctx context.Context) ([]*giftTypes.GiftRequire,
 	}
 }
 
+// runCheck checks for new gifts and reports the outcome to the Start loop.
+func (gm *giftMonitorImpl) runCheck(ctx context.Context, resultCh chan<- []*giftTypes.GiftRequire, errCh chan<- error, okCh chan<- struct{}) {
+	start := time.Now()
+	ctx, span := tracing.StartSpan(ctx, "monitor.check")
+	newGifts, err := gm.checkForNewGifts(ctx)
+	span.SetAttributes(attribute.Int("gifts.found", len(newGifts)))
+	tracing.Finish(span, start, "", err)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	if len(newGifts) == 0 {
+		gm.infoLogsWriter.LogInfo("no new gifts found")
+		okCh <- struct{}{}
+		return
+	}
+	resultCh <- newGifts
+}
+
+// backoff stretches the interval before the next check after a failed one. The interval
+// doubles with every consecutive failure up to maxBackoff and is randomly spread so that
+// several instances don't poll in lockstep.
+func (gm *giftMonitorImpl) backoff() {
+	if gm.maxBackoff <= 0 {
+		return
+	}
+
+	gm.consecutiveErrors++
+	delay := gm.backoffDelay(gm.consecutiveErrors)
+	gm.ticker.Reset(delay)
+	gm.infoLogsWriter.LogInfo(fmt.Sprintf("gift check failed %d times in a row, next check in %s", gm.consecutiveErrors, delay))
+}
+
+// backoffDelay returns the jittered interval after the given number of consecutive failures.
+func (gm *giftMonitorImpl) backoffDelay(failures int) time.Duration {
+	delay := gm.tickTime
+	for i := 0; i < failures && delay < gm.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > gm.maxBackoff {
+		delay = gm.maxBackoff
+	}
+
+	spread := 1 + backoffJitter*(2*gm.jitter()-1)
+	return time.Duration(float64(delay) * spread)
+}
+
+// resetBackoff restores the regular check interval after a successful check.
+func (gm *giftMonitorImpl) resetBackoff() {
+	if gm.consecutiveErrors == 0 {
+		return
+	}
+
+	gm.consecutiveErrors = 0
+	gm.ticker.Reset(gm.tickTime)
+	gm.infoLogsWriter.LogInfo("gift check succeeded, back to the regular interval")
+}
+
+// nextBurst schedules the next startup burst check. The first check of the burst fires
+// immediately, the following ones after the burst interval. It returns nil once the
+// burst is over so that only the ticker drives the checks.
+func (gm *giftMonitorImpl) nextBurst() <-chan time.Time {
+	if gm.burstRemaining <= 0 {
+		return nil
+	}
+
+	if !gm.burstStarted {
+		gm.burstStarted = true
+		fired := make(chan time.Time, 1)
+		fired <- time.Now()
+		return fired
+	}
+	return time.After(gm.burstInterval)
+}
+
+// SetInitialCheckBurst enables a burst of rapid checks when monitoring starts,
+// so a fresh start doesn't wait a full tick before the first check.
+//
+// Parameters:
+//   - count: number of burst checks, the first one runs immediately (0 disables the burst)
+//   - interval: delay between burst checks
+func (gm *giftMonitorImpl) SetInitialCheckBurst(count int, interval time.Duration) {
+	if count <= 0 {
+		return
+	}
+	gm.burstRemaining = count
+	gm.burstInterval = interval
+	gm.infoLogsWriter.LogInfo(fmt.Sprintf("initial check burst enabled: %d checks every %s", count, interval))
+}
+
 // checkForNewGifts retrieves current gifts and identifies new eligible ones.
 // It compares the current gift list against the cache to find new gifts,
 // validates them against criteria, and updates the cache.
@@ -154,21 +323,41 @@ func (gm *giftMonitorImpl) Start(ctx context.Context) ([]*giftTypes.GiftRequire,
 //   - map[*tg.StarGift]int64: map of new eligible gifts to purchase quantities
 //   - error: API communication error or validation error
 func (gm *giftMonitorImpl) checkForNewGifts(ctx context.Context) ([]*giftTypes.GiftRequire, error) {
-	currentGifts, err := gm.manager.GetAvailableGifts(ctx)
+	currentGifts, err := gm.fetchGifts(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if gm.startupSnapshot && !gm.snapshotSent {
+		gm.snapshotSent = true
+		gm.sendStartupSnapshot(ctx, currentGifts)
+	}
+
 	newValidGifts := make([]*giftTypes.GiftRequire, 0, len(currentGifts))
 
 	for _, gift := range currentGifts {
-		if gm.cache.HasGift(gift.ID) {
+		cached := gm.cache.HasGift(gift.ID)
+		if cached && gm.soldOut(ctx, gift) {
+			continue
+		}
+		if cached && !gm.supplyDropped(gift) {
 			continue
 		}
-		if giftRequire, ok := gm.validator.IsEligible(gift); ok {
+		if !cached {
+			metrics.GiftsSeen.Inc()
+		}
+		if gm.sessionState != nil && gm.sessionState.IsClaimed(gift.ID) {
+			gm.infoLogsWriter.LogInfo(fmt.Sprintf("gift id %d already claimed in this session", gift.ID))
+			gm.cache.SetGift(gift.ID, gift)
+			continue
+		}
+		if giftRequire, ok := gm.validateGift(gift); ok {
 			gm.infoLogsWriter.LogInfo(fmt.Sprintf("gift id %d is valid", gift.ID))
 			giftRequire.Gift = gift
 			newValidGifts = append(newValidGifts, giftRequire)
+		} else if gm.shouldRecheck(gift) {
+			gm.infoLogsWriter.LogInfo(fmt.Sprintf("gift id %d has no availability data yet, rechecking next cycle", gift.ID))
+			continue
 		}
 
 		gm.cache.SetGift(gift.ID, gift)
@@ -176,12 +365,176 @@ func (gm *giftMonitorImpl) checkForNewGifts(ctx context.Context) ([]*giftTypes.G
 
 	if gm.firstRun && !gm.testMode {
 		gm.firstRun = false
-		return nil, errors.Wrap(errors.New("first run"), "touch grass")
+		return nil, errors.Wrap(errFirstRun, "touch grass")
+	}
+
+	if gm.sessionState != nil {
+		for _, giftRequire := range newValidGifts {
+			gm.sessionState.Claim(giftRequire.Gift.ID)
+		}
 	}
 
 	return newValidGifts, nil
 }
 
+// fetchGifts requests the gift catalog, bounded by the fetch timeout so a hung request
+// doesn't stall the check. A timed out request fails with ErrGiftFetchTimeout and goes
+// through the backoff like any other failed check.
+func (gm *giftMonitorImpl) fetchGifts(ctx context.Context) ([]*tg.StarGift, error) {
+	if gm.fetchTimeout <= 0 {
+		return gm.manager.GetAvailableGifts(ctx)
+	}
+
+	fetchCtx, cancel := context.WithTimeoutCause(ctx, gm.fetchTimeout, errors.ErrGiftFetchTimeout)
+	defer cancel()
+
+	gifts, err := gm.manager.GetAvailableGifts(fetchCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(fetchCtx), errors.ErrGiftFetchTimeout) {
+		return nil, errors.Wrap(errors.ErrGiftFetchTimeout, fmt.Sprintf("no response within %s", gm.fetchTimeout))
+	}
+	return gifts, err
+}
+
+// SetGiftFetchTimeout sets the timeout of a single gift catalog request.
+//
+// Parameters:
+//   - timeout: maximum duration of a request, 0 to disable the timeout
+func (gm *giftMonitorImpl) SetGiftFetchTimeout(timeout time.Duration) {
+	gm.fetchTimeout = timeout
+}
+
+// SetSkipFirstRunWithCache disables the first run suppression when the cache restored
+// from disk already holds the gift baseline. The baseline then plays the role of the
+// first run, so a restart doesn't delay buying by one cycle. The cache must be unbounded:
+// gifts evicted before the restart are missing from the baseline and look new.
+//
+// Parameters:
+//   - enabled: skip the first run when the cache is already populated
+func (gm *giftMonitorImpl) SetSkipFirstRunWithCache(enabled bool) {
+	if !enabled || !gm.firstRun {
+		return
+	}
+
+	cached := len(gm.cache.GetAllGifts())
+	if cached == 0 {
+		return
+	}
+
+	gm.firstRun = false
+	gm.infoLogsWriter.LogInfo(fmt.Sprintf("cache already holds %d gifts, skipping first run", cached))
+}
+
+// SetRecoverValidatorPanics makes the monitor recover from validator panics caused by
+// malformed API data. The offending gift is logged and skipped and monitoring continues.
+//
+// Parameters:
+//   - enabled: recover from validator panics
+func (gm *giftMonitorImpl) SetRecoverValidatorPanics(enabled bool) {
+	gm.recoverPanics = enabled
+}
+
+// SetSessionState sets the persisted session state used to skip gifts
+// already claimed for purchase before a restart.
+func (gm *giftMonitorImpl) SetSessionState(state giftInterfaces.SessionState) {
+	gm.sessionState = state
+}
+
+// validateGift checks the gift against the purchase criteria. With panic recovery enabled
+// a panic caused by malformed API data never stops the monitoring loop: the gift that makes
+// the validator panic is logged and treated as not eligible.
+//
+// Parameters:
+//   - gift: the star gift to validate
+//
+// Returns:
+//   - *giftTypes.GiftRequire: purchase requirements if the gift is eligible
+//   - bool: true if the gift meets the criteria, false otherwise
+func (gm *giftMonitorImpl) validateGift(gift *tg.StarGift) (giftRequire *giftTypes.GiftRequire, ok bool) {
+	if !gm.recoverPanics {
+		return gm.validator.IsEligible(gift)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			gm.errorLogsWriter.LogError(fmt.Sprintf("validator panic on gift id %d, skipping: %v", gift.GetID(), r))
+			giftRequire, ok = nil, false
+		}
+	}()
+
+	return gm.validator.IsEligible(gift)
+}
+
+// shouldRecheck reports whether the validator asks to validate the gift again on the next cycle.
+func (gm *giftMonitorImpl) shouldRecheck(gift *tg.StarGift) bool {
+	validator, ok := gm.validator.(recheckValidator)
+	return ok && validator.ShouldRecheck(gift)
+}
+
+// supplyDropped reports whether the remaining supply of a cached gift crossed a criteria
+// threshold since it was cached, so the gift is evaluated again.
+func (gm *giftMonitorImpl) supplyDropped(gift *tg.StarGift) bool {
+	validator, ok := gm.validator.(supplyDropValidator)
+	if !ok {
+		return false
+	}
+
+	cached, err := gm.cache.GetGift(gift.ID)
+	if err != nil || !validator.CrossedRemainsThreshold(cached, gift) {
+		return false
+	}
+
+	remains, _ := gift.GetAvailabilityRemains()
+	gm.infoLogsWriter.LogInfo(fmt.Sprintf("gift id %d remaining supply dropped to %d, evaluating again", gift.ID, remains))
+	return true
+}
+
+// soldOut reports whether a cached gift sold out since it was cached. The transition
+// is notified and the cache is updated, so the notification is sent only once.
+func (gm *giftMonitorImpl) soldOut(ctx context.Context, gift *tg.StarGift) bool {
+	if !gift.SoldOut {
+		return false
+	}
+
+	cached, err := gm.cache.GetGift(gift.ID)
+	if err != nil || cached == nil || cached.SoldOut {
+		return false
+	}
+
+	gm.infoLogsWriter.LogInfo(fmt.Sprintf("gift id %d sold out", gift.ID))
+	if err := gm.notification.SendSoldOutNotification(ctx, gift); err != nil {
+		gm.errorLogsWriter.LogError(err.Error())
+	}
+	gm.cache.SetGift(gift.ID, gift)
+	return true
+}
+
+// sendStartupSnapshot notifies how many gifts are available and how many of them
+// match the configured criteria right now. Nothing is bought and the cache is not
+// touched, so the preseed of the first run stays intact.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - gifts: gifts currently available in the store
+func (gm *giftMonitorImpl) sendStartupSnapshot(ctx context.Context, gifts []*tg.StarGift) {
+	available, matching := gm.snapshotCounts(gifts)
+	gm.infoLogsWriter.LogInfo(fmt.Sprintf("startup snapshot: %d gifts available, %d match criteria", available, matching))
+
+	if err := gm.notification.SendStartupSnapshot(ctx, available, matching); err != nil {
+		gm.errorLogsWriter.LogError(err.Error())
+	}
+}
+
+// snapshotCounts returns the number of available gifts and how many of them match the criteria.
+func (gm *giftMonitorImpl) snapshotCounts(gifts []*tg.StarGift) (available, matching int) {
+	for _, gift := range gifts {
+		available++
+		if _, ok := gm.validateGift(gift); ok {
+			matching++
+		}
+	}
+	return available, matching
+}
+
 // Pause pauses the gift monitoring process.
 // It stops the monitoring goroutine and prevents new gifts from being discovered.
 func (gm *giftMonitorImpl) Pause() {
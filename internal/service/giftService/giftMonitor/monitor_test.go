@@ -3,15 +3,19 @@ package giftMonitor
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"gift-buyer/internal/infrastructure/logsWriter/logTypes"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
 
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockGiftCache is a mock implementation of GiftCache interface
@@ -105,6 +109,23 @@ func (m *MockNotificationService) SendUpdateNotification(ctx context.Context, ve
 	return args.Error(0)
 }
 
+func (m *MockNotificationService) SendStartupSnapshot(ctx context.Context, available, matching int) error {
+	args := m.Called(ctx, available, matching)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) SendLimitReachedNotification(ctx context.Context, limit string, max int64) error {
+	args := m.Called(ctx, limit, max)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) SendSoldOutNotification(ctx context.Context, gift *tg.StarGift) error {
+	args := m.Called(ctx, gift)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) Close() {}
+
 // MockLogsWriter для тестирования
 type MockLogsWriter struct{}
 
@@ -127,7 +148,7 @@ func TestNewGiftMonitor(t *testing.T) {
 	mockInfoWriter := &MockLogsWriter{}
 	tickTime := time.Second
 
-	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, tickTime, mockErrorWriter, mockInfoWriter, true)
+	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, tickTime, 0, mockErrorWriter, mockInfoWriter, true, false)
 
 	assert.NotNil(t, monitor)
 
@@ -147,7 +168,7 @@ func TestGiftMonitor_Start_FirstRun(t *testing.T) {
 	mockErrorWriter := &MockLogsWriter{}
 	mockInfoWriter := &MockLogsWriter{}
 
-	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Millisecond*10, mockErrorWriter, mockInfoWriter, true)
+	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Millisecond*10, 0, mockErrorWriter, mockInfoWriter, true, false)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
 	defer cancel()
 
@@ -157,7 +178,7 @@ func TestGiftMonitor_Start_FirstRun(t *testing.T) {
 	currentGifts := []*tg.StarGift{gift1, gift2}
 
 	// First run - should just add all gifts to cache and return "touch grass" error
-	mockManager.On("GetAvailableGifts", mock.AnythingOfType("*context.timerCtx")).Return(currentGifts, nil)
+	mockManager.On("GetAvailableGifts", mock.Anything).Return(currentGifts, nil)
 	// On first run, gifts are processed but then firstRun error is returned
 	mockCache.On("HasGift", int64(1)).Return(false).Once()
 	mockCache.On("HasGift", int64(2)).Return(false).Once()
@@ -211,7 +232,7 @@ func TestGiftMonitor_Start_SecondRunWithNewGifts(t *testing.T) {
 	currentGifts := []*tg.StarGift{gift1, gift2}
 
 	// Setup mocks for new gifts
-	mockManager.On("GetAvailableGifts", mock.AnythingOfType("*context.timerCtx")).Return(currentGifts, nil)
+	mockManager.On("GetAvailableGifts", mock.Anything).Return(currentGifts, nil)
 	mockCache.On("HasGift", int64(1)).Return(false)
 	mockCache.On("HasGift", int64(2)).Return(false)
 	mockValidator.On("IsEligible", gift1).Return(&giftTypes.GiftRequire{CountForBuy: 10, ReceiverType: []int{1}}, true)
@@ -252,7 +273,7 @@ func TestGiftMonitor_Start_ContextCancelled(t *testing.T) {
 	mockErrorWriter := &MockLogsWriter{}
 	mockInfoWriter := &MockLogsWriter{}
 
-	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Second, mockErrorWriter, mockInfoWriter, true)
+	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Second, 0, mockErrorWriter, mockInfoWriter, true, false)
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // Cancel immediately
 
@@ -271,12 +292,12 @@ func TestGiftMonitor_Start_NoNewGifts(t *testing.T) {
 	mockErrorWriter := &MockLogsWriter{}
 	mockInfoWriter := &MockLogsWriter{}
 
-	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Millisecond*10, mockErrorWriter, mockInfoWriter, true)
+	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Millisecond*10, 0, mockErrorWriter, mockInfoWriter, true, false)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
 	defer cancel()
 
 	// Setup mocks to return no new gifts - should timeout without error notifications since first run logic is disabled
-	mockManager.On("GetAvailableGifts", mock.AnythingOfType("*context.timerCtx")).Return([]*tg.StarGift{}, nil)
+	mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{}, nil)
 
 	// Start monitoring - it should continue until context timeout
 	newGifts, err := monitor.Start(ctx)
@@ -469,7 +490,7 @@ func TestGiftMonitor_PauseResumeIsPaused(t *testing.T) {
 	mockErrorWriter := &MockLogsWriter{}
 	mockInfoWriter := &MockLogsWriter{}
 
-	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Second, mockErrorWriter, mockInfoWriter, true)
+	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Second, 0, mockErrorWriter, mockInfoWriter, true, false)
 
 	// Initially should not be paused
 	assert.False(t, monitor.IsPaused())
@@ -538,7 +559,7 @@ func TestGiftMonitor_ConcurrentPauseResume(t *testing.T) {
 	mockErrorWriter := &MockLogsWriter{}
 	mockInfoWriter := &MockLogsWriter{}
 
-	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Second, mockErrorWriter, mockInfoWriter, true)
+	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Second, 0, mockErrorWriter, mockInfoWriter, true, false)
 
 	// Test concurrent access to pause/resume methods
 	var wg sync.WaitGroup
@@ -567,3 +588,628 @@ func TestGiftMonitor_ConcurrentPauseResume(t *testing.T) {
 	finalState := monitor.IsPaused()
 	assert.Equal(t, finalState, monitor.IsPaused())
 }
+
+func TestGiftMonitor_StartupSnapshot(t *testing.T) {
+	mockCache := new(MockGiftCache)
+	mockManager := new(MockGiftManager)
+	mockValidator := new(MockGiftValidator)
+	mockNotification := new(MockNotificationService)
+	mockErrorWriter := &MockLogsWriter{}
+	mockInfoWriter := &MockLogsWriter{}
+
+	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Second, 0, mockErrorWriter, mockInfoWriter, false, true)
+
+	ctx := context.Background()
+
+	gift1 := &tg.StarGift{ID: 1, Stars: 100}
+	gift2 := &tg.StarGift{ID: 2, Stars: 200}
+	gift3 := &tg.StarGift{ID: 3, Stars: 300}
+	currentGifts := []*tg.StarGift{gift1, gift2, gift3}
+
+	mockManager.On("GetAvailableGifts", ctx).Return(currentGifts, nil)
+	mockValidator.On("IsEligible", gift1).Return(&giftTypes.GiftRequire{CountForBuy: 1}, true)
+	mockValidator.On("IsEligible", gift2).Return(nil, false)
+	mockValidator.On("IsEligible", gift3).Return(&giftTypes.GiftRequire{CountForBuy: 1}, true)
+	mockCache.On("HasGift", mock.AnythingOfType("int64")).Return(false)
+	mockCache.On("SetGift", mock.AnythingOfType("int64"), mock.Anything).Return()
+	mockNotification.On("SendStartupSnapshot", ctx, 3, 2).Return(nil).Once()
+
+	// First run preseeds the cache: nothing is bought
+	newGifts, err := monitor.checkForNewGifts(ctx)
+	assert.Error(t, err)
+	assert.Nil(t, newGifts)
+
+	// The snapshot is sent only once
+	mockCache.ExpectedCalls = nil
+	mockCache.On("HasGift", mock.AnythingOfType("int64")).Return(true)
+	_, err = monitor.checkForNewGifts(ctx)
+	assert.NoError(t, err)
+
+	mockNotification.AssertExpectations(t)
+	mockNotification.AssertNumberOfCalls(t, "SendStartupSnapshot", 1)
+}
+
+func TestGiftMonitor_StartupSnapshotDisabled(t *testing.T) {
+	mockCache := new(MockGiftCache)
+	mockManager := new(MockGiftManager)
+	mockValidator := new(MockGiftValidator)
+	mockNotification := new(MockNotificationService)
+	mockErrorWriter := &MockLogsWriter{}
+	mockInfoWriter := &MockLogsWriter{}
+
+	monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, mockNotification, time.Second, 0, mockErrorWriter, mockInfoWriter, true, false)
+
+	ctx := context.Background()
+	mockManager.On("GetAvailableGifts", ctx).Return([]*tg.StarGift{}, nil)
+
+	_, err := monitor.checkForNewGifts(ctx)
+	assert.NoError(t, err)
+
+	mockNotification.AssertNotCalled(t, "SendStartupSnapshot", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// panickingValidator panics for the configured gift ID and accepts every other gift
+type panickingValidator struct {
+	panicID int64
+}
+
+func (v *panickingValidator) IsEligible(gift *tg.StarGift) (*giftTypes.GiftRequire, bool) {
+	if gift.ID == v.panicID {
+		panic("malformed gift")
+	}
+	return &giftTypes.GiftRequire{CountForBuy: 1, ReceiverType: []int{1}}, true
+}
+
+// recordingLogsWriter collects logged errors
+type recordingLogsWriter struct {
+	MockLogsWriter
+	mu     sync.Mutex
+	errors []string
+}
+
+func (w *recordingLogsWriter) LogError(message string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errors = append(w.errors, message)
+}
+
+func TestGiftMonitor_CheckForNewGifts_ValidatorPanic(t *testing.T) {
+	mockCache := new(MockGiftCache)
+	mockManager := new(MockGiftManager)
+	mockNotification := new(MockNotificationService)
+	errorWriter := &recordingLogsWriter{}
+
+	monitor := NewGiftMonitor(mockCache, mockManager, &panickingValidator{panicID: 2}, mockNotification, time.Second, 0, errorWriter, &MockLogsWriter{}, true, false)
+	monitor.SetRecoverValidatorPanics(true)
+
+	ctx := context.Background()
+
+	gift1 := &tg.StarGift{ID: 1, Stars: 100}
+	gift2 := &tg.StarGift{ID: 2, Stars: 200}
+	gift3 := &tg.StarGift{ID: 3, Stars: 300}
+
+	mockManager.On("GetAvailableGifts", ctx).Return([]*tg.StarGift{gift1, gift2, gift3}, nil)
+	mockCache.On("HasGift", mock.AnythingOfType("int64")).Return(false)
+	mockCache.On("SetGift", mock.AnythingOfType("int64"), mock.Anything).Return()
+
+	newGifts, err := monitor.checkForNewGifts(ctx)
+
+	assert.NoError(t, err)
+	assert.Len(t, newGifts, 2, "Остальные подарки должны быть обработаны")
+	assert.Same(t, gift1, newGifts[0].Gift)
+	assert.Same(t, gift3, newGifts[1].Gift)
+
+	// The broken gift is still cached so it is not validated again on every tick
+	mockCache.AssertCalled(t, "SetGift", int64(2), gift2)
+
+	assert.Len(t, errorWriter.errors, 1)
+	assert.Contains(t, errorWriter.errors[0], "gift id 2")
+}
+
+func TestGiftMonitor_CheckForNewGifts_ValidatorPanicNotRecovered(t *testing.T) {
+	mockCache := new(MockGiftCache)
+	mockManager := new(MockGiftManager)
+	monitor := NewGiftMonitor(mockCache, mockManager, &panickingValidator{panicID: 2}, new(MockNotificationService), time.Second, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+
+	ctx := context.Background()
+	mockManager.On("GetAvailableGifts", ctx).Return([]*tg.StarGift{{ID: 2, Stars: 200}}, nil)
+	mockCache.On("HasGift", mock.AnythingOfType("int64")).Return(false)
+	mockCache.On("SetGift", mock.AnythingOfType("int64"), mock.Anything).Return()
+
+	// Without the option the panic is not swallowed
+	assert.Panics(t, func() { _, _ = monitor.checkForNewGifts(ctx) })
+}
+
+// recheckingValidator rejects every gift and asks to recheck the configured one
+type recheckingValidator struct {
+	recheckID int64
+}
+
+func (v *recheckingValidator) IsEligible(gift *tg.StarGift) (*giftTypes.GiftRequire, bool) {
+	return nil, false
+}
+
+func (v *recheckingValidator) ShouldRecheck(gift *tg.StarGift) bool {
+	return gift.ID == v.recheckID
+}
+
+func TestGiftMonitor_CheckForNewGifts_RecheckNotCached(t *testing.T) {
+	mockCache := new(MockGiftCache)
+	mockManager := new(MockGiftManager)
+
+	monitor := NewGiftMonitor(mockCache, mockManager, &recheckingValidator{recheckID: 1}, new(MockNotificationService), time.Second, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+
+	ctx := context.Background()
+	gift1 := &tg.StarGift{ID: 1, Stars: 100, Limited: true}
+	gift2 := &tg.StarGift{ID: 2, Stars: 200}
+
+	mockManager.On("GetAvailableGifts", ctx).Return([]*tg.StarGift{gift1, gift2}, nil)
+	mockCache.On("HasGift", mock.AnythingOfType("int64")).Return(false)
+	mockCache.On("SetGift", int64(2), gift2).Return()
+
+	newGifts, err := monitor.checkForNewGifts(ctx)
+
+	assert.NoError(t, err)
+	assert.Empty(t, newGifts)
+	mockCache.AssertNotCalled(t, "SetGift", int64(1), gift1)
+	mockCache.AssertCalled(t, "SetGift", int64(2), gift2)
+}
+
+func TestGiftMonitor_SkipFirstRunWithCache(t *testing.T) {
+	newMonitor := func(cached map[int64]*tg.StarGift, skip bool) (*giftMonitorImpl, *MockGiftManager, *MockGiftValidator) {
+		mockCache := new(MockGiftCache)
+		mockManager := new(MockGiftManager)
+		mockValidator := new(MockGiftValidator)
+
+		baseline := &tg.StarGift{ID: 1, Stars: 100}
+		fresh := &tg.StarGift{ID: 2, Stars: 200}
+
+		mockCache.On("GetAllGifts").Return(cached)
+		mockCache.On("HasGift", int64(1)).Return(len(cached) > 0)
+		mockCache.On("HasGift", int64(2)).Return(false)
+		mockCache.On("SetGift", mock.Anything, mock.Anything).Return()
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{baseline, fresh}, nil)
+		mockValidator.On("IsEligible", baseline).Return(&giftTypes.GiftRequire{CountForBuy: 1}, true)
+		mockValidator.On("IsEligible", fresh).Return(&giftTypes.GiftRequire{CountForBuy: 1}, true)
+
+		monitor := NewGiftMonitor(mockCache, mockManager, mockValidator, new(MockNotificationService), time.Hour, 0, &MockLogsWriter{}, &MockLogsWriter{}, false, false)
+		monitor.SetSkipFirstRunWithCache(skip)
+		return monitor, mockManager, mockValidator
+	}
+
+	t.Run("перезапуск с заполненным кэшем без подавления", func(t *testing.T) {
+		monitor, _, _ := newMonitor(map[int64]*tg.StarGift{1: {ID: 1}}, true)
+
+		newGifts, err := monitor.checkForNewGifts(context.Background())
+
+		assert.NoError(t, err)
+		assert.Len(t, newGifts, 1)
+		assert.Equal(t, int64(2), newGifts[0].Gift.ID)
+	})
+
+	t.Run("пустой кэш - первый цикл подавляется", func(t *testing.T) {
+		monitor, _, _ := newMonitor(map[int64]*tg.StarGift{}, true)
+
+		newGifts, err := monitor.checkForNewGifts(context.Background())
+		assert.Error(t, err)
+		assert.Nil(t, newGifts)
+
+		newGifts, err = monitor.checkForNewGifts(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, newGifts, 2)
+	})
+
+	t.Run("без флага первый цикл подавляется", func(t *testing.T) {
+		monitor, _, _ := newMonitor(map[int64]*tg.StarGift{1: {ID: 1}}, false)
+
+		_, err := monitor.checkForNewGifts(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+func TestGiftMonitor_InitialCheckBurst(t *testing.T) {
+	t.Run("серия проверок сразу при запуске", func(t *testing.T) {
+		mockManager := new(MockGiftManager)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{}, nil)
+
+		monitor := NewGiftMonitor(new(MockGiftCache), mockManager, new(MockGiftValidator), new(MockNotificationService), time.Hour, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+		monitor.SetInitialCheckBurst(3, 10*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		_, err := monitor.Start(ctx)
+
+		assert.Equal(t, context.DeadlineExceeded, err)
+		// The hourly ticker never fired, so every check came from the burst
+		mockManager.AssertNumberOfCalls(t, "GetAvailableGifts", 3)
+		assert.Nil(t, monitor.nextBurst())
+	})
+
+	t.Run("первая проверка не ждет интервал", func(t *testing.T) {
+		mockManager := new(MockGiftManager)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{}, nil)
+
+		monitor := NewGiftMonitor(new(MockGiftCache), mockManager, new(MockGiftValidator), new(MockNotificationService), time.Hour, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+		monitor.SetInitialCheckBurst(1, time.Hour)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, _ = monitor.Start(ctx)
+
+		mockManager.AssertNumberOfCalls(t, "GetAvailableGifts", 1)
+	})
+
+	t.Run("обычный интервал после серии", func(t *testing.T) {
+		mockManager := new(MockGiftManager)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{}, nil)
+
+		monitor := NewGiftMonitor(new(MockGiftCache), mockManager, new(MockGiftValidator), new(MockNotificationService), 100*time.Millisecond, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+		monitor.SetInitialCheckBurst(2, 5*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, _ = monitor.Start(ctx)
+		mockManager.AssertNumberOfCalls(t, "GetAvailableGifts", 2)
+
+		// Once the burst is over, only the ticker drives the checks
+		ctx, cancel = context.WithTimeout(context.Background(), 120*time.Millisecond)
+		defer cancel()
+		_, _ = monitor.Start(ctx)
+		mockManager.AssertNumberOfCalls(t, "GetAvailableGifts", 3)
+	})
+
+	t.Run("медленная проверка не перекрывается следующей", func(t *testing.T) {
+		manager := &slowManager{delay: 50 * time.Millisecond, gifts: []*tg.StarGift{{ID: 1}, {ID: 2}}}
+		mockValidator := new(MockGiftValidator)
+		mockValidator.On("IsEligible", mock.Anything).Return(&giftTypes.GiftRequire{CountForBuy: 1}, true)
+		mockNotification := new(MockNotificationService)
+		mockNotification.On("SendErrorNotification", mock.Anything, mock.Anything).Return(nil)
+
+		monitor := NewGiftMonitor(&memoryCache{gifts: map[int64]*tg.StarGift{}}, manager, mockValidator, mockNotification, time.Hour, 0, &MockLogsWriter{}, &MockLogsWriter{}, false, false)
+		monitor.SetInitialCheckBurst(3, 5*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		newGifts, err := monitor.Start(ctx)
+
+		// The burst checks fired while the first check was still fetching are skipped,
+		// so the existing catalog is never reported as new
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Empty(t, newGifts)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&manager.calls))
+	})
+
+	t.Run("отключено без количества", func(t *testing.T) {
+		mockManager := new(MockGiftManager)
+
+		monitor := NewGiftMonitor(new(MockGiftCache), mockManager, new(MockGiftValidator), new(MockNotificationService), time.Hour, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+		monitor.SetInitialCheckBurst(0, time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+		_, _ = monitor.Start(ctx)
+
+		mockManager.AssertNotCalled(t, "GetAvailableGifts", mock.Anything)
+	})
+}
+
+func TestGiftMonitor_BackoffDelay(t *testing.T) {
+	monitor := NewGiftMonitor(new(MockGiftCache), new(MockGiftManager), new(MockGiftValidator), new(MockNotificationService), 10*time.Millisecond, 100*time.Millisecond, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+	monitor.jitter = func() float64 { return 0.5 }
+
+	expected := []time.Duration{20, 40, 80, 100, 100}
+	for i, want := range expected {
+		assert.Equal(t, want*time.Millisecond, monitor.backoffDelay(i+1), "failure %d", i+1)
+	}
+
+	// Jitter spreads the delay by up to 20% in both directions
+	monitor.jitter = func() float64 { return 0 }
+	assert.InDelta(t, float64(16*time.Millisecond), float64(monitor.backoffDelay(1)), float64(time.Microsecond))
+	monitor.jitter = func() float64 { return 1 }
+	assert.InDelta(t, float64(24*time.Millisecond), float64(monitor.backoffDelay(1)), float64(time.Microsecond))
+}
+
+func TestGiftMonitor_BackoffOnRepeatedErrors(t *testing.T) {
+	var mu sync.Mutex
+	var calls []time.Time
+
+	mockManager := new(MockGiftManager)
+	mockManager.On("GetAvailableGifts", mock.Anything).
+		Run(func(args mock.Arguments) {
+			mu.Lock()
+			calls = append(calls, time.Now())
+			mu.Unlock()
+		}).
+		Return(nil, assert.AnError)
+	mockNotification := new(MockNotificationService)
+	mockNotification.On("SendErrorNotification", mock.Anything, mock.Anything).Return(nil)
+
+	monitor := NewGiftMonitor(new(MockGiftCache), mockManager, new(MockGiftValidator), mockNotification, 10*time.Millisecond, time.Second, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+	monitor.jitter = func() float64 { return 0.5 }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	_, err := monitor.Start(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Without the backoff the 10ms ticker would check about 40 times
+	require.GreaterOrEqual(t, len(calls), 4)
+	assert.Less(t, len(calls), 10)
+	for i := 2; i < len(calls); i++ {
+		previous := calls[i-1].Sub(calls[i-2])
+		current := calls[i].Sub(calls[i-1])
+		assert.Greater(t, current, previous, "delay after failure %d should grow", i)
+	}
+}
+
+func TestGiftMonitor_BackoffResetAfterSuccess(t *testing.T) {
+	var checks int32
+	mockManager := new(MockGiftManager)
+	mockManager.On("GetAvailableGifts", mock.Anything).
+		Run(func(args mock.Arguments) { atomic.AddInt32(&checks, 1) }).
+		Return(nil, assert.AnError).Twice()
+	mockManager.On("GetAvailableGifts", mock.Anything).
+		Run(func(args mock.Arguments) { atomic.AddInt32(&checks, 1) }).
+		Return([]*tg.StarGift{}, nil)
+	mockNotification := new(MockNotificationService)
+	mockNotification.On("SendErrorNotification", mock.Anything, mock.Anything).Return(nil)
+
+	monitor := NewGiftMonitor(new(MockGiftCache), mockManager, new(MockGiftValidator), mockNotification, 10*time.Millisecond, time.Second, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, _ = monitor.Start(ctx)
+
+	assert.Equal(t, 0, monitor.consecutiveErrors)
+	// Back on the regular interval the monitor keeps polling every 10ms
+	assert.Greater(t, int(atomic.LoadInt32(&checks)), 5)
+}
+
+func TestGiftMonitor_BackoffDisabled(t *testing.T) {
+	var checks int32
+	mockManager := new(MockGiftManager)
+	mockManager.On("GetAvailableGifts", mock.Anything).
+		Run(func(args mock.Arguments) { atomic.AddInt32(&checks, 1) }).
+		Return(nil, assert.AnError)
+	mockNotification := new(MockNotificationService)
+	mockNotification.On("SendErrorNotification", mock.Anything, mock.Anything).Return(nil)
+
+	monitor := NewGiftMonitor(new(MockGiftCache), mockManager, new(MockGiftValidator), mockNotification, 10*time.Millisecond, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _ = monitor.Start(ctx)
+
+	assert.Equal(t, 0, monitor.consecutiveErrors)
+	assert.Greater(t, int(atomic.LoadInt32(&checks)), 5)
+}
+
+// supplyDropValidatorMock is eligible for gifts below the remains threshold and reports
+// gifts whose remains crossed it since they were cached
+type supplyDropValidatorMock struct {
+	threshold int
+}
+
+func (v *supplyDropValidatorMock) IsEligible(gift *tg.StarGift) (*giftTypes.GiftRequire, bool) {
+	remains, ok := gift.GetAvailabilityRemains()
+	if !ok || remains >= v.threshold {
+		return nil, false
+	}
+	return &giftTypes.GiftRequire{Gift: gift, CountForBuy: 1}, true
+}
+
+func (v *supplyDropValidatorMock) CrossedRemainsThreshold(cached, fresh *tg.StarGift) bool {
+	previous, _ := cached.GetAvailabilityRemains()
+	remains, _ := fresh.GetAvailabilityRemains()
+	return previous >= v.threshold && remains < v.threshold
+}
+
+func TestGiftMonitor_CheckForNewGifts_SupplyDrop(t *testing.T) {
+	newGift := func(remains int) *tg.StarGift {
+		gift := &tg.StarGift{ID: 1, Stars: 100, Limited: true}
+		gift.SetAvailabilityRemains(remains)
+		gift.SetAvailabilityTotal(1000)
+		return gift
+	}
+
+	t.Run("остаток кэшированного подарка опустился ниже порога", func(t *testing.T) {
+		mockCache := new(MockGiftCache)
+		mockManager := new(MockGiftManager)
+		monitor := NewGiftMonitor(mockCache, mockManager, &supplyDropValidatorMock{threshold: 100}, new(MockNotificationService), time.Second, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+
+		fresh := newGift(80)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{fresh}, nil)
+		mockCache.On("HasGift", int64(1)).Return(true)
+		mockCache.On("GetGift", int64(1)).Return(newGift(500), nil)
+		mockCache.On("SetGift", int64(1), fresh).Return()
+
+		newGifts, err := monitor.checkForNewGifts(context.Background())
+
+		assert.NoError(t, err)
+		require.Len(t, newGifts, 1)
+		assert.Equal(t, fresh, newGifts[0].Gift)
+		mockCache.AssertCalled(t, "SetGift", int64(1), fresh)
+	})
+
+	t.Run("остаток уменьшился, но порог не пересечен", func(t *testing.T) {
+		mockCache := new(MockGiftCache)
+		mockManager := new(MockGiftManager)
+		monitor := NewGiftMonitor(mockCache, mockManager, &supplyDropValidatorMock{threshold: 100}, new(MockNotificationService), time.Second, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{newGift(300)}, nil)
+		mockCache.On("HasGift", int64(1)).Return(true)
+		mockCache.On("GetGift", int64(1)).Return(newGift(500), nil)
+
+		newGifts, err := monitor.checkForNewGifts(context.Background())
+
+		assert.NoError(t, err)
+		assert.Empty(t, newGifts)
+		mockCache.AssertNotCalled(t, "SetGift", mock.Anything, mock.Anything)
+	})
+
+	t.Run("валидатор без порогов не читает кэш", func(t *testing.T) {
+		mockCache := new(MockGiftCache)
+		mockManager := new(MockGiftManager)
+		monitor := NewGiftMonitor(mockCache, mockManager, new(MockGiftValidator), new(MockNotificationService), time.Second, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{newGift(80)}, nil)
+		mockCache.On("HasGift", int64(1)).Return(true)
+
+		newGifts, err := monitor.checkForNewGifts(context.Background())
+
+		assert.NoError(t, err)
+		assert.Empty(t, newGifts)
+		mockCache.AssertNotCalled(t, "GetGift", mock.Anything)
+	})
+}
+
+// hangingManager never answers, the request only ends with its context
+type hangingManager struct {
+	calls int32
+}
+
+func (m *hangingManager) GetAvailableGifts(ctx context.Context) ([]*tg.StarGift, error) {
+	atomic.AddInt32(&m.calls, 1)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// slowManager answers with the gifts after a delay
+type slowManager struct {
+	delay time.Duration
+	gifts []*tg.StarGift
+	calls int32
+}
+
+func (m *slowManager) GetAvailableGifts(ctx context.Context) ([]*tg.StarGift, error) {
+	atomic.AddInt32(&m.calls, 1)
+	select {
+	case <-time.After(m.delay):
+		return m.gifts, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestGiftMonitor_GiftFetchTimeout(t *testing.T) {
+	newMonitor := func(manager giftInterfaces.Giftmanager, notification *MockNotificationService) *giftMonitorImpl {
+		monitor := NewGiftMonitor(new(MockGiftCache), manager, new(MockGiftValidator), notification, 10*time.Millisecond, time.Second, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+		monitor.SetGiftFetchTimeout(20 * time.Millisecond)
+		return monitor
+	}
+
+	t.Run("зависший запрос завершается по таймауту", func(t *testing.T) {
+		monitor := newMonitor(&hangingManager{}, new(MockNotificationService))
+
+		start := time.Now()
+		gifts, err := monitor.checkForNewGifts(context.Background())
+
+		assert.ErrorIs(t, err, errors.ErrGiftFetchTimeout)
+		assert.Nil(t, gifts)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("отмена родительского контекста не считается таймаутом", func(t *testing.T) {
+		monitor := newMonitor(&hangingManager{}, new(MockNotificationService))
+		monitor.SetGiftFetchTimeout(time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		_, err := monitor.checkForNewGifts(ctx)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.NotErrorIs(t, err, errors.ErrGiftFetchTimeout)
+	})
+
+	t.Run("таймаут уходит в backoff, а не останавливает мониторинг", func(t *testing.T) {
+		manager := &hangingManager{}
+		mockNotification := new(MockNotificationService)
+		mockNotification.On("SendErrorNotification", mock.Anything, mock.MatchedBy(func(err error) bool {
+			return errors.Is(err, errors.ErrGiftFetchTimeout)
+		})).Return(nil)
+		monitor := newMonitor(manager, mockNotification)
+		monitor.jitter = func() float64 { return 0.5 }
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		_, err := monitor.Start(ctx)
+
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Greater(t, monitor.consecutiveErrors, 1)
+		// The checks keep running with the stretched interval
+		assert.Greater(t, int(atomic.LoadInt32(&manager.calls)), 1)
+		mockNotification.AssertCalled(t, "SendErrorNotification", mock.Anything, mock.Anything)
+	})
+}
+
+// memoryCache is a map backed gift cache
+type memoryCache struct {
+	gifts map[int64]*tg.StarGift
+}
+
+func (c *memoryCache) SetGift(id int64, gift *tg.StarGift)    { c.gifts[id] = gift }
+func (c *memoryCache) GetGift(id int64) (*tg.StarGift, error) { return c.gifts[id], nil }
+func (c *memoryCache) GetAllGifts() map[int64]*tg.StarGift    { return c.gifts }
+func (c *memoryCache) HasGift(id int64) bool                  { _, ok := c.gifts[id]; return ok }
+func (c *memoryCache) DeleteGift(id int64)                    { delete(c.gifts, id) }
+func (c *memoryCache) Clear()                                 { c.gifts = make(map[int64]*tg.StarGift) }
+
+func TestGiftMonitor_SoldOutNotification(t *testing.T) {
+	newGift := func(soldOut bool) *tg.StarGift {
+		gift := &tg.StarGift{ID: 1, Stars: 100, Limited: true, SoldOut: soldOut}
+		gift.SetAvailabilityTotal(1000)
+		return gift
+	}
+
+	t.Run("подарок из кэша распродан, уведомление один раз", func(t *testing.T) {
+		cache := &memoryCache{gifts: map[int64]*tg.StarGift{1: newGift(false)}}
+		mockManager := new(MockGiftManager)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{newGift(true)}, nil)
+		mockNotification := new(MockNotificationService)
+		mockNotification.On("SendSoldOutNotification", mock.Anything, mock.Anything).Return(nil)
+		monitor := NewGiftMonitor(cache, mockManager, new(MockGiftValidator), mockNotification, time.Second, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+
+		for i := 0; i < 3; i++ {
+			newGifts, err := monitor.checkForNewGifts(context.Background())
+			assert.NoError(t, err)
+			assert.Empty(t, newGifts)
+		}
+
+		mockNotification.AssertNumberOfCalls(t, "SendSoldOutNotification", 1)
+		assert.True(t, cache.gifts[1].SoldOut)
+	})
+
+	t.Run("доступный подарок не вызывает уведомление", func(t *testing.T) {
+		cache := &memoryCache{gifts: map[int64]*tg.StarGift{1: newGift(false)}}
+		mockManager := new(MockGiftManager)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{newGift(false)}, nil)
+		mockNotification := new(MockNotificationService)
+		monitor := NewGiftMonitor(cache, mockManager, new(MockGiftValidator), mockNotification, time.Second, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+
+		_, err := monitor.checkForNewGifts(context.Background())
+
+		assert.NoError(t, err)
+		mockNotification.AssertNotCalled(t, "SendSoldOutNotification", mock.Anything, mock.Anything)
+	})
+
+	t.Run("новый уже распроданный подарок не вызывает уведомление", func(t *testing.T) {
+		cache := &memoryCache{gifts: map[int64]*tg.StarGift{}}
+		mockManager := new(MockGiftManager)
+		mockManager.On("GetAvailableGifts", mock.Anything).Return([]*tg.StarGift{newGift(true)}, nil)
+		mockValidator := new(MockGiftValidator)
+		mockValidator.On("IsEligible", mock.Anything).Return(nil, false)
+		mockNotification := new(MockNotificationService)
+		monitor := NewGiftMonitor(cache, mockManager, mockValidator, mockNotification, time.Second, 0, &MockLogsWriter{}, &MockLogsWriter{}, true, false)
+
+		_, err := monitor.checkForNewGifts(context.Background())
+
+		assert.NoError(t, err)
+		mockNotification.AssertNotCalled(t, "SendSoldOutNotification", mock.Anything, mock.Anything)
+		assert.True(t, cache.HasGift(1))
+	})
+}
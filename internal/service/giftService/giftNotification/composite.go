@@ -0,0 +1,114 @@
+package giftNotification
+
+import (
+	"context"
+	"fmt"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+	"gift-buyer/pkg/errors"
+	"strings"
+
+	"github.com/gotd/td/tg"
+)
+
+// CompositeNotificationService fans every notification out to several
+// notification backends, e.g. the Telegram bot and a Discord webhook.
+type CompositeNotificationService struct {
+	services []giftInterfaces.NotificationService
+}
+
+// NewCompositeNotificationService creates a notification service delivering
+// every notification to all of the specified services.
+//
+// Parameters:
+//   - services: notification backends in delivery order
+//
+// Returns:
+//   - *CompositeNotificationService: composite notification service
+func NewCompositeNotificationService(services ...giftInterfaces.NotificationService) *CompositeNotificationService {
+	return &CompositeNotificationService{services: services}
+}
+
+// SendNewGiftNotification notifies every backend about a newly discovered gift.
+func (cs *CompositeNotificationService) SendNewGiftNotification(ctx context.Context, gift *tg.StarGift) error {
+	return cs.each(func(service giftInterfaces.NotificationService) error {
+		return service.SendNewGiftNotification(ctx, gift)
+	})
+}
+
+// SendBuyStatus sends the purchase status to every backend.
+func (cs *CompositeNotificationService) SendBuyStatus(ctx context.Context, status string, err error) error {
+	return cs.each(func(service giftInterfaces.NotificationService) error {
+		return service.SendBuyStatus(ctx, status, err)
+	})
+}
+
+// SendErrorNotification sends the error to every backend.
+func (cs *CompositeNotificationService) SendErrorNotification(ctx context.Context, err error) error {
+	return cs.each(func(service giftInterfaces.NotificationService) error {
+		return service.SendErrorNotification(ctx, err)
+	})
+}
+
+// SetBot reports whether at least one backend can deliver notifications.
+func (cs *CompositeNotificationService) SetBot() bool {
+	for _, service := range cs.services {
+		if service.SetBot() {
+			return true
+		}
+	}
+	return false
+}
+
+// SendUpdateNotification sends the update notification to every backend.
+func (cs *CompositeNotificationService) SendUpdateNotification(ctx context.Context, version, message string) error {
+	return cs.each(func(service giftInterfaces.NotificationService) error {
+		return service.SendUpdateNotification(ctx, version, message)
+	})
+}
+
+// SendStartupSnapshot sends the startup snapshot to every backend.
+func (cs *CompositeNotificationService) SendStartupSnapshot(ctx context.Context, available, matching int) error {
+	return cs.each(func(service giftInterfaces.NotificationService) error {
+		return service.SendStartupSnapshot(ctx, available, matching)
+	})
+}
+
+// SendLimitReachedNotification sends the limit notification to every backend.
+func (cs *CompositeNotificationService) SendLimitReachedNotification(ctx context.Context, limit string, max int64) error {
+	return cs.each(func(service giftInterfaces.NotificationService) error {
+		return service.SendLimitReachedNotification(ctx, limit, max)
+	})
+}
+
+// SendSoldOutNotification sends the sold out notification to every backend.
+func (cs *CompositeNotificationService) SendSoldOutNotification(ctx context.Context, gift *tg.StarGift) error {
+	return cs.each(func(service giftInterfaces.NotificationService) error {
+		return service.SendSoldOutNotification(ctx, gift)
+	})
+}
+
+// Close closes every backend.
+func (cs *CompositeNotificationService) Close() {
+	for _, service := range cs.services {
+		service.Close()
+	}
+}
+
+// each calls send for every backend, so a failing backend never prevents
+// delivery through the others.
+//
+// Returns:
+//   - error: errors of the failed backends combined, nil if all succeeded
+func (cs *CompositeNotificationService) each(send func(service giftInterfaces.NotificationService) error) error {
+	var failed []string
+	for _, service := range cs.services {
+		if err := send(service); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("%d of %d notification backends failed: %s", len(failed), len(cs.services), strings.Join(failed, "; ")))
+}
@@ -0,0 +1,109 @@
+package giftNotification
+
+import (
+	"context"
+	"fmt"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+	"gift-buyer/pkg/errors"
+	"sync"
+	"time"
+)
+
+// DedupNotificationService suppresses repeated identical error notifications. The first
+// occurrence of an error is sent right away, repeats within the cooldown are only
+// counted, and the first repeat after the cooldown is sent as a "still failing" summary
+// with the number of occurrences. Other notifications are passed through unchanged.
+type DedupNotificationService struct {
+	giftInterfaces.NotificationService
+
+	// cooldown is the minimum interval between two notifications of the same error
+	cooldown time.Duration
+
+	// errors tracks the recent errors by message
+	errors map[string]*errorOccurrences
+	mu     sync.Mutex
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// errorOccurrences tracks the notifications of a single error message.
+type errorOccurrences struct {
+	// lastSent is when the error was last notified
+	lastSent time.Time
+
+	// lastSeen is when the error last occurred
+	lastSeen time.Time
+
+	// suppressed is the number of occurrences since the last notification
+	suppressed int
+}
+
+// NewDedupNotificationService wraps a notification service with error deduplication.
+//
+// Parameters:
+//   - service: notification service delivering the notifications
+//   - cooldown: minimum interval between two notifications of the same error
+//
+// Returns:
+//   - *DedupNotificationService: deduplicating notification service
+func NewDedupNotificationService(service giftInterfaces.NotificationService, cooldown time.Duration) *DedupNotificationService {
+	return &DedupNotificationService{
+		NotificationService: service,
+		cooldown:            cooldown,
+		errors:              make(map[string]*errorOccurrences),
+		now:                 time.Now,
+	}
+}
+
+// SendErrorNotification sends the error unless the same error was notified within
+// the cooldown. A failed delivery isn't counted as sent, so the next occurrence retries.
+func (ds *DedupNotificationService) SendErrorNotification(ctx context.Context, err error) error {
+	if err == nil {
+		return ds.NotificationService.SendErrorNotification(ctx, err)
+	}
+
+	key := err.Error()
+	now := ds.now()
+
+	ds.mu.Lock()
+	ds.forgetQuiet(now)
+	occurrences, ok := ds.errors[key]
+	if !ok {
+		occurrences = &errorOccurrences{}
+		ds.errors[key] = occurrences
+	}
+	occurrences.lastSeen = now
+	if ok && now.Sub(occurrences.lastSent) < ds.cooldown {
+		occurrences.suppressed++
+		ds.mu.Unlock()
+		return nil
+	}
+	// Claim the notification before sending so concurrent repeats are suppressed
+	repeats, previous := occurrences.suppressed, occurrences.lastSent
+	occurrences.lastSent, occurrences.suppressed = now, 0
+	ds.mu.Unlock()
+
+	toSend := err
+	if repeats > 0 {
+		toSend = errors.Wrap(err, fmt.Sprintf("still failing (%d times)", repeats+1))
+	}
+	if sendErr := ds.NotificationService.SendErrorNotification(ctx, toSend); sendErr != nil {
+		ds.mu.Lock()
+		occurrences.lastSent = previous
+		occurrences.suppressed += repeats + 1
+		ds.mu.Unlock()
+		return sendErr
+	}
+	return nil
+}
+
+// forgetQuiet drops the errors that didn't occur for a whole cooldown, so an error
+// coming back later is notified as a new one. The caller must hold mu.
+func (ds *DedupNotificationService) forgetQuiet(now time.Time) {
+	for key, occurrences := range ds.errors {
+		if now.Sub(occurrences.lastSeen) >= ds.cooldown {
+			delete(ds.errors, key)
+		}
+	}
+}
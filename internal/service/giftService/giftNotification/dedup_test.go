@@ -0,0 +1,130 @@
+package giftNotification
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorRecorder records the error notifications, other notifications go to the embedded service
+type errorRecorder struct {
+	giftInterfaces.NotificationService
+
+	mu       sync.Mutex
+	messages []string
+	fail     bool
+}
+
+func (r *errorRecorder) SendErrorNotification(ctx context.Context, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("chat unavailable")
+	}
+	r.messages = append(r.messages, err.Error())
+	return nil
+}
+
+func (r *errorRecorder) sent() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.messages...)
+}
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	current time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.current
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.current = c.current.Add(d)
+}
+
+func newTestDedup(cooldown time.Duration) (*DedupNotificationService, *errorRecorder, *fakeClock) {
+	recorder := &errorRecorder{}
+	clock := &fakeClock{current: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	dedup := NewDedupNotificationService(recorder, cooldown)
+	dedup.now = clock.now
+	return dedup, recorder, clock
+}
+
+func TestDedupNotificationService(t *testing.T) {
+	ctx := context.Background()
+	apiErr := errors.New("FLOOD_WAIT")
+
+	var _ giftInterfaces.NotificationService = &DedupNotificationService{}
+
+	t.Run("повторы в пределах окна подавляются", func(t *testing.T) {
+		dedup, recorder, clock := newTestDedup(time.Minute)
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, dedup.SendErrorNotification(ctx, apiErr))
+			clock.advance(2 * time.Second)
+		}
+
+		assert.Equal(t, []string{"FLOOD_WAIT"}, recorder.sent())
+	})
+
+	t.Run("сводка после окна", func(t *testing.T) {
+		dedup, recorder, clock := newTestDedup(time.Minute)
+
+		// One error every 10 seconds for 3 minutes
+		for i := 0; i < 19; i++ {
+			require.NoError(t, dedup.SendErrorNotification(ctx, apiErr))
+			clock.advance(10 * time.Second)
+		}
+
+		assert.Equal(t, []string{
+			"FLOOD_WAIT",
+			"still failing (6 times): FLOOD_WAIT",
+			"still failing (6 times): FLOOD_WAIT",
+			"still failing (6 times): FLOOD_WAIT",
+		}, recorder.sent())
+	})
+
+	t.Run("разные ошибки не влияют друг на друга", func(t *testing.T) {
+		dedup, recorder, _ := newTestDedup(time.Minute)
+
+		require.NoError(t, dedup.SendErrorNotification(ctx, apiErr))
+		require.NoError(t, dedup.SendErrorNotification(ctx, errors.New("timeout")))
+		require.NoError(t, dedup.SendErrorNotification(ctx, apiErr))
+		require.NoError(t, dedup.SendErrorNotification(ctx, errors.New("timeout")))
+
+		assert.Equal(t, []string{"FLOOD_WAIT", "timeout"}, recorder.sent())
+	})
+
+	t.Run("неудачная отправка повторяется", func(t *testing.T) {
+		dedup, recorder, _ := newTestDedup(time.Minute)
+		recorder.fail = true
+
+		assert.Error(t, dedup.SendErrorNotification(ctx, apiErr))
+
+		recorder.fail = false
+		require.NoError(t, dedup.SendErrorNotification(ctx, apiErr))
+
+		assert.Equal(t, []string{"still failing (2 times): FLOOD_WAIT"}, recorder.sent())
+	})
+
+	t.Run("ошибка после затишья отправляется заново", func(t *testing.T) {
+		dedup, recorder, clock := newTestDedup(time.Minute)
+
+		require.NoError(t, dedup.SendErrorNotification(ctx, apiErr))
+		clock.advance(10 * time.Second)
+		require.NoError(t, dedup.SendErrorNotification(ctx, apiErr))
+		clock.advance(5 * time.Minute)
+		require.NoError(t, dedup.SendErrorNotification(ctx, apiErr))
+
+		assert.Equal(t, []string{"FLOOD_WAIT", "FLOOD_WAIT"}, recorder.sent())
+	})
+}
@@ -0,0 +1,209 @@
+package giftNotification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+	"gift-buyer/pkg/errors"
+	"net/http"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+const (
+	// discordContentLimit is the maximum length of a Discord message content
+	discordContentLimit = 2000
+
+	// discordGiftColor is the embed color of new gift notifications
+	discordGiftColor = 0x5865F2
+)
+
+// discordPayload is the JSON body of a Discord webhook request.
+type discordPayload struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds,omitempty"`
+}
+
+// discordEmbed is a rich message block of a Discord webhook request.
+type discordEmbed struct {
+	Title     string              `json:"title"`
+	Color     int                 `json:"color,omitempty"`
+	Fields    []discordEmbedField `json:"fields,omitempty"`
+	Timestamp string              `json:"timestamp,omitempty"`
+}
+
+// discordEmbedField is a name-value pair shown in a Discord embed.
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// DiscordNotifier implements the NotificationService interface by posting
+// notifications to a Discord channel through a webhook.
+type DiscordNotifier struct {
+	// webhookURL is the Discord webhook notifications are posted to
+	webhookURL string
+
+	// client sends the webhook requests
+	client *http.Client
+
+	// catalog holds the notification strings of the configured language
+	catalog messageCatalog
+
+	// thousandsSeparator groups the digits of formatted numbers
+	thousandsSeparator string
+
+	// errorLogsWriter logs failed deliveries
+	errorLogsWriter giftInterfaces.ErrorLogger
+}
+
+// NewDiscordNotifier creates a notifier posting to the specified Discord webhook.
+//
+// Parameters:
+//   - webhookURL: Discord webhook URL
+//   - language: notification language, English when empty or unknown
+//   - thousandsSeparator: digit group separator of numbers, the default one when empty
+//   - errorLogsWriter: logger for delivery errors
+//
+// Returns:
+//   - *DiscordNotifier: configured Discord notifier
+func NewDiscordNotifier(webhookURL, language, thousandsSeparator string, errorLogsWriter giftInterfaces.ErrorLogger) *DiscordNotifier {
+	if thousandsSeparator == "" {
+		thousandsSeparator = defaultThousandsSeparator
+	}
+	return &DiscordNotifier{
+		webhookURL:         webhookURL,
+		client:             &http.Client{Timeout: 10 * time.Second},
+		catalog:            catalogFor(language),
+		thousandsSeparator: thousandsSeparator,
+		errorLogsWriter:    errorLogsWriter,
+	}
+}
+
+// SendNewGiftNotification posts an embed with the details of a newly discovered gift.
+func (dn *DiscordNotifier) SendNewGiftNotification(ctx context.Context, gift *tg.StarGift) error {
+	giftTitle, hasTitle := gift.GetTitle()
+	if !hasTitle {
+		giftTitle = dn.catalog.unknownGift
+	}
+
+	supply, _ := gift.GetAvailabilityTotal()
+	available := supply
+	if gift.Limited {
+		available, _ = gift.GetAvailabilityRemains()
+	}
+
+	return dn.post(ctx, discordPayload{
+		Embeds: []discordEmbed{{
+			Title: fmt.Sprintf(dn.catalog.discordNewGift, giftTitle, gift.GetID()),
+			Color: discordGiftColor,
+			Fields: []discordEmbedField{
+				{Name: dn.catalog.discordTotalAmount, Value: dn.formatNumber(supply), Inline: true},
+				{Name: dn.catalog.discordAvailableAmount, Value: dn.formatNumber(available), Inline: true},
+				{Name: dn.catalog.discordPrice, Value: dn.formatNumber(int(gift.GetStars())) + " ⭐️", Inline: true},
+				{Name: dn.catalog.discordConvertPrice, Value: dn.formatNumber(int(gift.GetConvertStars())) + " ⭐️", Inline: true},
+			},
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}},
+	})
+}
+
+// SendBuyStatus posts the status of a purchase operation.
+func (dn *DiscordNotifier) SendBuyStatus(ctx context.Context, status string, err error) error {
+	if err != nil {
+		return dn.postText(ctx, fmt.Sprintf(dn.catalog.buyStatusError, status, err.Error()))
+	}
+	return dn.postText(ctx, fmt.Sprintf(dn.catalog.buyStatusSuccess, status))
+}
+
+// SendErrorNotification posts an error.
+func (dn *DiscordNotifier) SendErrorNotification(ctx context.Context, err error) error {
+	return dn.postText(ctx, err.Error())
+}
+
+// SetBot reports whether the webhook is configured.
+func (dn *DiscordNotifier) SetBot() bool {
+	return dn.webhookURL != ""
+}
+
+// SendUpdateNotification posts a notification about a new version.
+func (dn *DiscordNotifier) SendUpdateNotification(ctx context.Context, version, message string) error {
+	return dn.postText(ctx, fmt.Sprintf(dn.catalog.update, version, message))
+}
+
+// SendStartupSnapshot posts the summary of the gift store taken on startup.
+func (dn *DiscordNotifier) SendStartupSnapshot(ctx context.Context, available, matching int) error {
+	return dn.postText(ctx, fmt.Sprintf(dn.catalog.startupSnapshot,
+		dn.formatNumber(available),
+		dn.formatNumber(matching),
+	))
+}
+
+// SendLimitReachedNotification posts a notification that a purchase limit was reached.
+func (dn *DiscordNotifier) SendLimitReachedNotification(ctx context.Context, limit string, max int64) error {
+	return dn.postText(ctx, fmt.Sprintf(dn.catalog.limitReached, limit, dn.formatNumber(int(max))))
+}
+
+// SendSoldOutNotification posts a notification that a gift sold out.
+func (dn *DiscordNotifier) SendSoldOutNotification(ctx context.Context, gift *tg.StarGift) error {
+	return dn.postText(ctx, soldOutMessage(gift, dn.catalog, dn.thousandsSeparator))
+}
+
+// Close does nothing, the webhook holds no resources.
+func (dn *DiscordNotifier) Close() {}
+
+// postText posts a plain text message, truncated to the Discord content limit.
+func (dn *DiscordNotifier) postText(ctx context.Context, message string) error {
+	if runes := []rune(message); len(runes) > discordContentLimit {
+		message = string(runes[:discordContentLimit-1]) + "…"
+	}
+	return dn.post(ctx, discordPayload{Content: message})
+}
+
+// post sends the payload to the webhook.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - payload: webhook request body
+//
+// Returns:
+//   - error: encoding, network or non-2xx response error
+func (dn *DiscordNotifier) post(ctx context.Context, payload discordPayload) error {
+	if dn.webhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode discord payload")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dn.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create discord request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := dn.client.Do(req)
+	if err != nil {
+		dn.errorLogsWriter.LogError(fmt.Sprintf("Failed to send discord notification: %v", err))
+		return errors.Wrap(err, "failed to send discord notification")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := errors.New(fmt.Sprintf("discord webhook returned status %d", resp.StatusCode))
+		dn.errorLogsWriter.LogError(fmt.Sprintf("Failed to send discord notification: %v", err))
+		return err
+	}
+	return nil
+}
+
+// formatNumber formats integers with the configured thousands separator.
+func (dn *DiscordNotifier) formatNumber(num int) string {
+	return groupDigits(num, dn.thousandsSeparator)
+}
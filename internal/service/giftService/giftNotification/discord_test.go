@@ -0,0 +1,194 @@
+package giftNotification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"gift-buyer/internal/config"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookRecorder is a fake Discord webhook recording the received payloads
+type webhookRecorder struct {
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+	status   int
+}
+
+func (w *webhookRecorder) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var payload map[string]interface{}
+	if r.Method == http.MethodPost && r.Header.Get("Content-Type") == "application/json" {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+	}
+
+	w.mu.Lock()
+	w.payloads = append(w.payloads, payload)
+	w.mu.Unlock()
+
+	if w.status != 0 {
+		rw.WriteHeader(w.status)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (w *webhookRecorder) received() []map[string]interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]map[string]interface{}(nil), w.payloads...)
+}
+
+func TestDiscordNotifier_NewGiftPayload(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	gift := &tg.StarGift{ID: 42, Stars: 1500, ConvertStars: 1200, Limited: true}
+	gift.SetTitle("Rocket")
+	gift.SetAvailabilityTotal(10000)
+	gift.SetAvailabilityRemains(2500)
+
+	notifier := NewDiscordNotifier(server.URL, "", "", &MockLogsWriter{})
+	require.NoError(t, notifier.SendNewGiftNotification(context.Background(), gift))
+
+	payloads := recorder.received()
+	require.Len(t, payloads, 1)
+	assert.NotContains(t, payloads[0], "content")
+
+	embeds, ok := payloads[0]["embeds"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, embeds, 1)
+	embed := embeds[0].(map[string]interface{})
+	assert.Equal(t, "🎁 New gift detected: Rocket (42)", embed["title"])
+	assert.NotEmpty(t, embed["timestamp"])
+
+	fields := make(map[string]string)
+	for _, field := range embed["fields"].([]interface{}) {
+		f := field.(map[string]interface{})
+		fields[f["name"].(string)] = f["value"].(string)
+	}
+	assert.Equal(t, map[string]string{
+		"Total amount":     "10,000",
+		"Available amount": "2,500",
+		"Price":            "1,500 ⭐️",
+		"Convert price":    "1,200 ⭐️",
+	}, fields)
+}
+
+func TestDiscordNotifier_Russian(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	gift := &tg.StarGift{ID: 42, Stars: 1500, ConvertStars: 1200}
+	gift.SetAvailabilityTotal(10000)
+
+	notifier := NewDiscordNotifier(server.URL, config.LanguageRussian, " ", &MockLogsWriter{})
+	require.NoError(t, notifier.SendNewGiftNotification(context.Background(), gift))
+	require.NoError(t, notifier.SendBuyStatus(context.Background(), "3/3", errors.New("нет звезд")))
+	require.NoError(t, notifier.SendUpdateNotification(context.Background(), "v2.0.0", "исправления"))
+	require.NoError(t, notifier.SendLimitReachedNotification(context.Background(), "max_buy_count", 1000))
+
+	payloads := recorder.received()
+	require.Len(t, payloads, 4)
+
+	embed := payloads[0]["embeds"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "🎁 Найден новый подарок: Неизвестный подарок (42)", embed["title"])
+	fields := make(map[string]string)
+	for _, field := range embed["fields"].([]interface{}) {
+		f := field.(map[string]interface{})
+		fields[f["name"].(string)] = f["value"].(string)
+	}
+	assert.Equal(t, map[string]string{
+		"Всего":            "10 000",
+		"Доступно":         "10 000",
+		"Цена":             "1 500 ⭐️",
+		"Цена конвертации": "1 200 ⭐️",
+	}, fields)
+
+	assert.Equal(t, "📊 Статус покупки: 3/3\n❌ Ошибка: нет звезд", payloads[1]["content"])
+	assert.Equal(t, "🆕 Доступна новая версия: v2.0.0\nисправления", payloads[2]["content"])
+	assert.Equal(t, "🛑 Достигнут лимит: max_buy_count (1 000)\nБольше подарки покупаться не будут", payloads[3]["content"])
+}
+
+func TestDiscordNotifier_TextPayload(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	notifier := NewDiscordNotifier(server.URL, "", "", &MockLogsWriter{})
+	require.NoError(t, notifier.SendBuyStatus(context.Background(), "3/3 bought", nil))
+	require.NoError(t, notifier.SendErrorNotification(context.Background(), errors.New(strings.Repeat("x", 3000))))
+
+	payloads := recorder.received()
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "📊 Buy Status: 3/3 bought\n✅ Success", payloads[0]["content"])
+	assert.NotContains(t, payloads[0], "embeds")
+	// Long messages are truncated to the Discord content limit
+	assert.Len(t, []rune(payloads[1]["content"].(string)), discordContentLimit)
+}
+
+func TestDiscordNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(&webhookRecorder{status: http.StatusTooManyRequests})
+	defer server.Close()
+
+	notifier := NewDiscordNotifier(server.URL, "", "", &MockLogsWriter{})
+	err := notifier.SendBuyStatus(context.Background(), "done", nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+}
+
+func TestDiscordNotifier_NotConfigured(t *testing.T) {
+	notifier := NewDiscordNotifier("", "", "", &MockLogsWriter{})
+
+	assert.False(t, notifier.SetBot())
+	assert.NoError(t, notifier.SendBuyStatus(context.Background(), "done", nil))
+}
+
+func TestCompositeNotificationService(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	botSender := &fakeSender{}
+	telegram := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, &MockLogsWriter{})
+	telegram.botSender = botSender
+
+	var _ giftInterfaces.NotificationService = &CompositeNotificationService{}
+
+	t.Run("уведомление во все каналы", func(t *testing.T) {
+		composite := NewCompositeNotificationService(telegram, NewDiscordNotifier(server.URL, "", "", &MockLogsWriter{}))
+
+		assert.True(t, composite.SetBot())
+		require.NoError(t, composite.SendStartupSnapshot(context.Background(), 12, 3))
+
+		assert.Len(t, botSender.sent(), 1)
+		require.Len(t, recorder.received(), 1)
+		assert.Contains(t, recorder.received()[0]["content"], "Available gifts: 12")
+	})
+
+	t.Run("сбой одного канала не мешает остальным", func(t *testing.T) {
+		failing := httptest.NewServer(&webhookRecorder{status: http.StatusInternalServerError})
+		defer failing.Close()
+
+		before := len(botSender.sent())
+		composite := NewCompositeNotificationService(NewDiscordNotifier(failing.URL, "", "", &MockLogsWriter{}), telegram)
+
+		err := composite.SendLimitReachedNotification(context.Background(), "max_buy_count", 10)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "1 of 2 notification backends failed")
+		assert.Len(t, botSender.sent(), before+1)
+	})
+}
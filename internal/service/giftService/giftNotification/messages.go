@@ -0,0 +1,114 @@
+package giftNotification
+
+import (
+	"gift-buyer/internal/config"
+	"strings"
+)
+
+// messageCatalog holds the notification strings of one language.
+type messageCatalog struct {
+	// unknownGift replaces the title of a gift without one
+	unknownGift string
+
+	// newGift formats a new gift notification: title, ID, total amount, available amount,
+	// available percentage, update time, price and convert price
+	newGift string
+
+	// discordNewGift formats the embed title of a Discord new gift notification: title and ID,
+	// the discord fields name the embed fields with the amounts and prices of the gift
+	discordNewGift         string
+	discordTotalAmount     string
+	discordAvailableAmount string
+	discordPrice           string
+	discordConvertPrice    string
+
+	// buyStatusError and buyStatusSuccess format a purchase status with and without an error
+	buyStatusError   string
+	buyStatusSuccess string
+
+	// update formats a new version notification: version and release notes
+	update string
+
+	// startupSnapshot formats the startup summary: available and matching gift counts
+	startupSnapshot string
+
+	// limitReached formats a reached purchase limit: limit name and value
+	limitReached string
+
+	// soldOut formats a sold out gift: title, ID and price; soldOutTotal appends the
+	// total amount of a limited gift
+	soldOut      string
+	soldOutTotal string
+
+	// connection formats a connection event, connectionError appends its error
+	connection      string
+	connectionError string
+
+	// notificationLimit formats the final message sent when the notification cap is reached
+	notificationLimit string
+}
+
+// messages is the notification catalog keyed by language.
+var messages = map[string]messageCatalog{
+	config.LanguageEnglish: {
+		unknownGift: "Unknown Gift",
+		newGift: `🎁 New gift detected!
+%s (%d)
+
+🎯 Total amount: %s
+❓ Available amount: %d (%.0f%%, updated at %s UTC)
+
+💎 Price: %s ⭐️
+♻️ Convert price: %s ⭐️`,
+		discordNewGift:         "🎁 New gift detected: %s (%d)",
+		discordTotalAmount:     "Total amount",
+		discordAvailableAmount: "Available amount",
+		discordPrice:           "Price",
+		discordConvertPrice:    "Convert price",
+		buyStatusError:         "📊 Buy Status: %s\n❌ Error: %s",
+		buyStatusSuccess:       "📊 Buy Status: %s\n✅ Success",
+		update:                 "🆕 New version available: %s\n%s",
+		startupSnapshot:        "📸 Startup snapshot\n🎁 Available gifts: %s\n🎯 Matching criteria: %s",
+		limitReached:           "🛑 Limit reached: %s (%s)\nNo more gifts will be bought",
+		soldOut:                "🚫 Sold out: %s (%d)\n💰 Price: %s ⭐️",
+		soldOutTotal:           "\n📦 Total amount: %s",
+		connection:             "🔌 Connection: %s",
+		connectionError:        "%s\n❌ Error: %s",
+		notificationLimit:      "🔕 Notification limit reached (%s)\nFurther notifications are suppressed, buying continues",
+	},
+	config.LanguageRussian: {
+		unknownGift: "Неизвестный подарок",
+		newGift: `🎁 Найден новый подарок!
+%s (%d)
+
+🎯 Всего: %s
+❓ Доступно: %d (%.0f%%, обновлено в %s UTC)
+
+💎 Цена: %s ⭐️
+♻️ Цена конвертации: %s ⭐️`,
+		discordNewGift:         "🎁 Найден новый подарок: %s (%d)",
+		discordTotalAmount:     "Всего",
+		discordAvailableAmount: "Доступно",
+		discordPrice:           "Цена",
+		discordConvertPrice:    "Цена конвертации",
+		buyStatusError:         "📊 Статус покупки: %s\n❌ Ошибка: %s",
+		buyStatusSuccess:       "📊 Статус покупки: %s\n✅ Успешно",
+		update:                 "🆕 Доступна новая версия: %s\n%s",
+		startupSnapshot:        "📸 Состояние при запуске\n🎁 Доступно подарков: %s\n🎯 Подходят под критерии: %s",
+		limitReached:           "🛑 Достигнут лимит: %s (%s)\nБольше подарки покупаться не будут",
+		soldOut:                "🚫 Распродан: %s (%d)\n💰 Цена: %s ⭐️",
+		soldOutTotal:           "\n📦 Всего: %s",
+		connection:             "🔌 Соединение: %s",
+		connectionError:        "%s\n❌ Ошибка: %s",
+		notificationLimit:      "🔕 Достигнут лимит уведомлений (%s)\nДальнейшие уведомления не отправляются, покупка продолжается",
+	},
+}
+
+// catalogFor returns the notification strings of the language, English for an empty
+// or unknown language.
+func catalogFor(language string) messageCatalog {
+	if catalog, ok := messages[strings.ToLower(strings.TrimSpace(language))]; ok {
+		return catalog
+	}
+	return messages[config.LanguageEnglish]
+}
@@ -5,28 +5,31 @@ package giftNotification
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/binary"
 	"fmt"
 	"gift-buyer/internal/config"
 	"gift-buyer/internal/service/giftService/giftInterfaces"
-	mathRand "math/rand"
+	"gift-buyer/pkg/errors"
+	"gift-buyer/pkg/utils"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/gotd/td/tg"
 )
 
-// cryptoRandomInt63 генерирует криптографически стойкое случайное число
-func cryptoRandomInt63() int64 {
-	var randomBytes [8]byte
-	if _, err := rand.Read(randomBytes[:]); err != nil {
-		// Fallback на math/rand если crypto/rand недоступен
-		return mathRand.Int63()
-	}
-	// Безопасное преобразование с маскированием старшего бита
-	val := binary.BigEndian.Uint64(randomBytes[:])
-	return int64(val >> 1) // Сдвиг вправо гарантирует положительное значение
+// defaultRetryJitter is the notification retry jitter used when none is configured
+const defaultRetryJitter = 0.3
+
+// defaultThousandsSeparator groups the digits of numbers when no separator is configured
+const defaultThousandsSeparator = ","
+
+// messageSender is the subset of the Telegram client used to deliver notifications.
+type messageSender interface {
+	MessagesSendMessage(ctx context.Context, request *tg.MessagesSendMessageRequest) (tg.UpdatesClass, error)
 }
 
 // NotificationServiceImpl implements the NotificationService interface for sending
@@ -36,28 +39,143 @@ type notificationServiceImpl struct {
 	// Bot is the Telegram bot client used for sending notifications
 	Bot *tg.Client
 
+	// User is the main Telegram user client used in self notification mode
+	User *tg.Client
+
 	// Config contains Telegram settings including notification chat ID
 	Config *config.TgSettings
 
+	// botSender and userSender deliver messages on behalf of the bot and the user account
+	botSender, userSender messageSender
+
 	// logsWriter is used to write logs to a file
 	errorLogsWriter giftInterfaces.ErrorLogger
+
+	// rateLimiter limits notification sends independently of purchase API calls (optional)
+	rateLimiter giftInterfaces.RateLimiter
+
+	// maxNotifications caps the notifications sent during the run (0 for unlimited)
+	maxNotifications int64
+
+	// sentNotifications counts the notifications admitted by the cap
+	sentNotifications int64
+
+	// fallbackThreshold is the number of notifications failing in a row after which
+	// notifications are written to the logs instead of being lost (0 to disable)
+	fallbackThreshold int64
+
+	// fallbackFile is an optional file notifications are appended to in fallback mode
+	fallbackFile string
+
+	// fallbackMu serializes writes to the fallback file
+	fallbackMu sync.Mutex
+
+	// consecutiveFailures counts the notifications that failed in a row
+	consecutiveFailures int64
+
+	// sleep waits for a retry delay unless the context is cancelled
+	sleep func(ctx context.Context, delay time.Duration) error
+
+	// giftTemplate renders new gift notifications (nil for the built-in format)
+	giftTemplate *template.Template
+
+	// catalog holds the notification strings of the configured language
+	catalog messageCatalog
+
+	// thousandsSeparator groups the digits of formatted numbers
+	thousandsSeparator string
 }
 
-// NewNotification creates a new NotificationService instance with the specified bot client and configuration.
-// The service will use the bot to send notifications to the configured chat.
+// giftTemplateData holds the gift fields available to the notification template.
+type giftTemplateData struct {
+	Title        string
+	ID           int64
+	Price        int64
+	ConvertPrice int64
+	Supply       int
+	Available    int
+	Percentage   float64
+	UpdatedAt    string
+}
+
+// NewNotification creates a new NotificationService instance with the specified clients and configuration.
+// Depending on the configured notification mode the service sends notifications either through
+// the bot to the configured chat or from the user account to Saved Messages.
 //
 // Parameters:
-//   - bot: configured Telegram bot client for sending messages
-//   - config: Telegram settings containing notification chat ID and other parameters
+//   - bot: configured Telegram bot client for sending messages (bot mode)
+//   - user: main Telegram user client for sending messages to Saved Messages (self mode)
+//   - config: Telegram settings containing notification mode, chat ID and other parameters
 //
 // Returns:
 //   - giftInterfaces.NotificationService: configured notification service instance
-func NewNotification(bot *tg.Client, config *config.TgSettings, errorLogsWriter giftInterfaces.ErrorLogger) *notificationServiceImpl {
-	return &notificationServiceImpl{
-		Bot:             bot,
-		Config:          config,
-		errorLogsWriter: errorLogsWriter,
+func NewNotification(bot, user *tg.Client, config *config.TgSettings, errorLogsWriter giftInterfaces.ErrorLogger) *notificationServiceImpl {
+	ns := &notificationServiceImpl{
+		Bot:                bot,
+		User:               user,
+		Config:             config,
+		errorLogsWriter:    errorLogsWriter,
+		sleep:              sleepContext,
+		catalog:            catalogFor(""),
+		thousandsSeparator: defaultThousandsSeparator,
+	}
+	if config != nil {
+		ns.catalog = catalogFor(config.Language)
+		if config.ThousandsSeparator != "" {
+			ns.thousandsSeparator = config.ThousandsSeparator
+		}
+	}
+	if bot != nil {
+		ns.botSender = bot
 	}
+	if user != nil {
+		ns.userSender = user
+	}
+	if config != nil && config.NotificationTemplate != "" {
+		tmpl, err := template.New("gift").Parse(config.NotificationTemplate)
+		if err != nil {
+			errorLogsWriter.LogError(fmt.Sprintf("failed to parse notification template, using the default format: %v", err))
+		} else {
+			ns.giftTemplate = tmpl
+		}
+	}
+	return ns
+}
+
+// selfMode reports whether notifications are delivered to the user's Saved Messages.
+func (ns *notificationServiceImpl) selfMode() bool {
+	return ns.Config != nil && ns.Config.NotificationMode == config.NotificationModeSelf
+}
+
+// targets returns the client and peers used to deliver notifications in the configured mode.
+// In bot mode notifications go to NotificationChatID and every NotificationChatIDs entry.
+// A nil sender means notifications are not configured.
+func (ns *notificationServiceImpl) targets() (messageSender, []tg.InputPeerClass) {
+	if ns.selfMode() {
+		if ns.userSender == nil {
+			return nil, nil
+		}
+		return ns.userSender, []tg.InputPeerClass{&tg.InputPeerSelf{}}
+	}
+
+	if ns.botSender == nil || ns.Config == nil {
+		return nil, nil
+	}
+
+	chatIDs := append([]int64{ns.Config.NotificationChatID}, ns.Config.NotificationChatIDs...)
+	seen := make(map[int64]bool, len(chatIDs))
+	peers := make([]tg.InputPeerClass, 0, len(chatIDs))
+	for _, chatID := range chatIDs {
+		if chatID == 0 || seen[chatID] {
+			continue
+		}
+		seen[chatID] = true
+		peers = append(peers, &tg.InputPeerUser{UserID: chatID})
+	}
+	if len(peers) == 0 {
+		return nil, nil
+	}
+	return ns.botSender, peers
 }
 
 // sendNotification sends a message to the configured notification chat with retry logic.
@@ -67,8 +185,22 @@ func NewNotification(bot *tg.Client, config *config.TgSettings, errorLogsWriter
 //   - Maximum 3 retry attempts
 //   - Special handling for FLOOD_WAIT errors with 5-second delay
 //   - Exponential backoff for other errors (2, 4, 6 seconds)
+//   - Every delay is jittered so concurrent sends don't retry in lockstep
+//   - Every attempt takes a token from the notification rate limiter, if configured
+//   - Retry delays are aborted as soon as the context is cancelled
 //   - Logs errors and continues operation on failure
 //
+// With several notification chats the message is delivered to all of them concurrently,
+// each with its own retries and FLOOD_WAIT handling, so one failing chat doesn't delay
+// the others. The notification only fails when every chat failed.
+//
+// Once the notification cap is reached a single final message is sent instead
+// and every later notification is silently dropped.
+//
+// When the fallback is enabled and notifications keep failing, they are written
+// to the error logs (and the fallback file) instead. Each notification in fallback
+// mode still makes a single delivery attempt, so delivery resumes once it recovers.
+//
 // Parameters:
 //   - ctx: context for request cancellation and timeout control
 //   - message: the message text to send
@@ -76,32 +208,89 @@ func NewNotification(bot *tg.Client, config *config.TgSettings, errorLogsWriter
 // Returns:
 //   - error: notification sending error after all retries exhausted
 func (ns *notificationServiceImpl) sendNotification(ctx context.Context, message string) error {
-	if ns.Bot == nil || ns.Config == nil || ns.Config.NotificationChatID == 0 {
+	sender, peers := ns.targets()
+	if sender == nil {
 		ns.errorLogsWriter.LogError("Bot client or notification chat ID not configured")
 		return nil
 	}
 
+	if ns.maxNotifications > 0 {
+		switch sent := atomic.AddInt64(&ns.sentNotifications, 1); {
+		case sent == ns.maxNotifications+1:
+			ns.errorLogsWriter.LogError(fmt.Sprintf("Notification limit of %d reached, further notifications are suppressed", ns.maxNotifications))
+			message = fmt.Sprintf(ns.catalog.notificationLimit, ns.formatNumber(int(ns.maxNotifications)))
+		case sent > ns.maxNotifications+1:
+			return nil
+		}
+	}
+
 	maxRetries := 3
+	if ns.inFallback() {
+		maxRetries = 1
+	}
+	return ns.recordDelivery(ctx, message, ns.deliverAll(ctx, sender, peers, message, maxRetries))
+}
+
+// deliverAll delivers the message to every peer concurrently.
+//
+// Returns:
+//   - error: nil if at least one peer received the message, otherwise the delivery error
+func (ns *notificationServiceImpl) deliverAll(ctx context.Context, sender messageSender, peers []tg.InputPeerClass, message string, maxRetries int) error {
+	if len(peers) == 1 {
+		return ns.deliver(ctx, sender, peers[0], message, maxRetries)
+	}
+
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer tg.InputPeerClass) {
+			defer wg.Done()
+			errs[i] = ns.deliver(ctx, sender, peer, message, maxRetries)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	var lastErr error
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	return errors.Wrap(lastErr, fmt.Sprintf("notification failed for all %d chats", len(peers)))
+}
+
+// deliver sends the message with up to maxRetries attempts, see sendNotification.
+func (ns *notificationServiceImpl) deliver(ctx context.Context, sender messageSender, peer tg.InputPeerClass, message string, maxRetries int) error {
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		_, err := ns.Bot.MessagesSendMessage(ctx, &tg.MessagesSendMessageRequest{
-			Peer: &tg.InputPeerUser{
-				UserID: ns.Config.NotificationChatID,
-			},
+		if ns.rateLimiter != nil {
+			if err := ns.rateLimiter.Acquire(ctx); err != nil {
+				return err
+			}
+		}
+
+		_, err := sender.MessagesSendMessage(ctx, &tg.MessagesSendMessageRequest{
+			Peer:     peer,
 			Message:  message,
-			RandomID: cryptoRandomInt63(),
+			RandomID: utils.CryptoRandomInt63(),
 		})
 
 		if err == nil {
 			return nil
 		}
 
-		if strings.Contains(err.Error(), "FLOOD_WAIT") {
-			time.Sleep(5 * time.Second)
+		if strings.Contains(err.Error(), "FLOOD_WAIT") && attempt < maxRetries-1 {
+			if err := ns.sleep(ctx, ns.retryDelay(5*time.Second)); err != nil {
+				return err
+			}
 			continue
 		}
 
 		if attempt < maxRetries-1 {
-			time.Sleep(time.Duration(attempt+1) * 2 * time.Second)
+			if err := ns.sleep(ctx, ns.retryDelay(time.Duration(attempt+1)*2*time.Second)); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -112,6 +301,133 @@ func (ns *notificationServiceImpl) sendNotification(ctx context.Context, message
 	return nil
 }
 
+// inFallback reports whether notifications are currently written to the logs.
+func (ns *notificationServiceImpl) inFallback() bool {
+	return ns.fallbackThreshold > 0 && atomic.LoadInt64(&ns.consecutiveFailures) >= ns.fallbackThreshold
+}
+
+// recordDelivery tracks consecutive delivery failures and switches to or from the log fallback.
+// A notification handled by the fallback is not reported as an error.
+//
+// Parameters:
+//   - ctx: context of the delivery
+//   - message: the delivered message
+//   - err: delivery error, nil on success
+//
+// Returns:
+//   - error: delivery error unless the message was written to the fallback
+func (ns *notificationServiceImpl) recordDelivery(ctx context.Context, message string, err error) error {
+	if ns.fallbackThreshold <= 0 {
+		return err
+	}
+
+	if err == nil {
+		if atomic.SwapInt64(&ns.consecutiveFailures, 0) >= ns.fallbackThreshold {
+			ns.errorLogsWriter.LogError("Notification delivery recovered, leaving log fallback")
+		}
+		return nil
+	}
+	if ctx.Err() != nil {
+		return err
+	}
+
+	failures := atomic.AddInt64(&ns.consecutiveFailures, 1)
+	if failures < ns.fallbackThreshold {
+		return err
+	}
+	if failures == ns.fallbackThreshold {
+		ns.errorLogsWriter.LogError(fmt.Sprintf("Notification delivery failed %d times in a row, falling back to logs", failures))
+	}
+
+	ns.writeFallback(message)
+	return nil
+}
+
+// writeFallback writes an undelivered notification to the error logs and the fallback file.
+func (ns *notificationServiceImpl) writeFallback(message string) {
+	ns.errorLogsWriter.LogError(fmt.Sprintf("Undelivered notification: %s", message))
+
+	if ns.fallbackFile == "" {
+		return
+	}
+
+	ns.fallbackMu.Lock()
+	defer ns.fallbackMu.Unlock()
+
+	file, err := os.OpenFile(ns.fallbackFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		ns.errorLogsWriter.LogError(fmt.Sprintf("Failed to open notification fallback file: %v", err))
+		return
+	}
+	defer file.Close()
+
+	entry := fmt.Sprintf("[%s] %s\n\n", time.Now().UTC().Format(time.RFC3339), message)
+	if _, err := file.WriteString(entry); err != nil {
+		ns.errorLogsWriter.LogError(fmt.Sprintf("Failed to write notification fallback file: %v", err))
+	}
+}
+
+// sleepContext waits for the delay or until the context is cancelled.
+//
+// Returns:
+//   - error: context error if the context was cancelled before the delay elapsed
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// SetRateLimiter sets a dedicated rate limiter for notification sends, so that
+// purchases saturating their own limiter never starve notifications.
+func (ns *notificationServiceImpl) SetRateLimiter(rateLimiter giftInterfaces.RateLimiter) {
+	ns.rateLimiter = rateLimiter
+}
+
+// Close stops the dedicated notification rate limiter, if any.
+func (ns *notificationServiceImpl) Close() {
+	if ns.rateLimiter != nil {
+		ns.rateLimiter.Close()
+	}
+}
+
+// SetMaxNotifications caps the number of notifications sent during the run.
+//
+// Parameters:
+//   - max: maximum number of notifications, 0 for unlimited
+func (ns *notificationServiceImpl) SetMaxNotifications(max int64) {
+	ns.maxNotifications = max
+}
+
+// SetFallback enables writing notifications to the logs once delivery keeps failing.
+//
+// Parameters:
+//   - threshold: number of notifications failing in a row that enables the fallback, 0 to disable
+//   - file: optional file undelivered notifications are appended to, empty for logs only
+func (ns *notificationServiceImpl) SetFallback(threshold int64, file string) {
+	ns.fallbackThreshold = threshold
+	ns.fallbackFile = file
+}
+
+// retryDelay applies the configured jitter to the base retry delay.
+func (ns *notificationServiceImpl) retryDelay(base time.Duration) time.Duration {
+	jitter := defaultRetryJitter
+	if ns.Config != nil {
+		switch {
+		case ns.Config.NotificationRetryJitter < 0:
+			return base
+		case ns.Config.NotificationRetryJitter > 0:
+			jitter = ns.Config.NotificationRetryJitter
+		}
+	}
+	return utils.JitterDelay(base, jitter)
+}
+
 // SendNewGiftNotification sends a formatted notification about a newly discovered gift.
 // It creates a detailed message including gift information, pricing, availability,
 // and current timestamp for tracking purposes.
@@ -132,7 +448,7 @@ func (ns *notificationServiceImpl) sendNotification(ctx context.Context, message
 func (ns *notificationServiceImpl) SendNewGiftNotification(ctx context.Context, gift *tg.StarGift) error {
 	giftTitle, hasTitle := gift.GetTitle()
 	if !hasTitle {
-		giftTitle = "Unknown Gift"
+		giftTitle = ns.catalog.unknownGift
 	}
 
 	giftID := gift.GetID()
@@ -158,22 +474,33 @@ func (ns *notificationServiceImpl) SendNewGiftNotification(ctx context.Context,
 
 	currentTime := time.Now().UTC().Format("02-01-2006 15:04:05")
 
-	message := fmt.Sprintf(`🎁 New gift detected!
-%s (%d)
-
-🎯 Total amount: %s
-❓ Available amount: %d (%.0f%%, updated at %s UTC)
+	if ns.giftTemplate != nil {
+		var rendered strings.Builder
+		err := ns.giftTemplate.Execute(&rendered, giftTemplateData{
+			Title:        giftTitle,
+			ID:           giftID,
+			Price:        giftPrice,
+			ConvertPrice: convertPrice,
+			Supply:       giftSupply,
+			Available:    availableAmount,
+			Percentage:   percentage,
+			UpdatedAt:    currentTime,
+		})
+		if err == nil {
+			return ns.sendNotification(ctx, rendered.String())
+		}
+		ns.errorLogsWriter.LogError(fmt.Sprintf("failed to render notification template, using the default format: %v", err))
+	}
 
-💎 Price: %s ⭐️
-♻️ Convert price: %s ⭐️`,
+	message := fmt.Sprintf(ns.catalog.newGift,
 		giftTitle,
 		giftID,
-		formatNumber(giftSupply),
+		ns.formatNumber(giftSupply),
 		availableAmount,
 		percentage,
 		currentTime,
-		formatNumber(int(giftPrice)),
-		formatNumber(int(convertPrice)),
+		ns.formatNumber(int(giftPrice)),
+		ns.formatNumber(int(convertPrice)),
 	)
 
 	return ns.sendNotification(ctx, message)
@@ -193,9 +520,9 @@ func (ns *notificationServiceImpl) SendNewGiftNotification(ctx context.Context,
 func (ns *notificationServiceImpl) SendBuyStatus(ctx context.Context, status string, err error) error {
 	var message string
 	if err != nil {
-		message = fmt.Sprintf("📊 Buy Status: %s\n❌ Error: %s", status, err.Error())
+		message = fmt.Sprintf(ns.catalog.buyStatusError, status, err.Error())
 	} else {
-		message = fmt.Sprintf("📊 Buy Status: %s\n✅ Success", status)
+		message = fmt.Sprintf(ns.catalog.buyStatusSuccess, status)
 	}
 
 	return ns.sendNotification(ctx, message)
@@ -206,45 +533,127 @@ func (ns *notificationServiceImpl) SendErrorNotification(ctx context.Context, er
 	return ns.sendNotification(ctx, err.Error())
 }
 
-// SetBot sets the bot client
+// SetBot reports whether a client is available to deliver notifications in the configured mode
 func (ns *notificationServiceImpl) SetBot() bool {
-	return ns.Bot != nil
+	if ns.selfMode() {
+		return ns.userSender != nil
+	}
+	return ns.botSender != nil
 }
 
 func (ns *notificationServiceImpl) SendUpdateNotification(ctx context.Context, version, message string) error {
-	return ns.sendNotification(ctx, fmt.Sprintf("🆕 New version available: %s\n%s", version, message))
+	return ns.sendNotification(ctx, fmt.Sprintf(ns.catalog.update, version, message))
 }
 
-// formatNumber formats integers with comma separators for better readability.
-// It adds commas every three digits to make large numbers easier to read.
+// SendStartupSnapshot sends a summary of how many gifts are available on startup
+// and how many of them match the configured criteria.
 //
-// Examples:
+// Parameters:
+//   - ctx: context for request cancellation and timeout control
+//   - available: number of gifts currently available
+//   - matching: number of available gifts matching the purchase criteria
+//
+// Returns:
+//   - error: notification sending error
+func (ns *notificationServiceImpl) SendStartupSnapshot(ctx context.Context, available, matching int) error {
+	return ns.sendNotification(ctx, fmt.Sprintf(ns.catalog.startupSnapshot,
+		ns.formatNumber(available),
+		ns.formatNumber(matching),
+	))
+}
+
+// SendLimitReachedNotification sends a notification that a purchase limit was reached
+// and no more gifts will be bought until it is raised or the application is restarted.
+//
+// Parameters:
+//   - ctx: context for request cancellation and timeout control
+//   - limit: human-readable name of the reached limit
+//   - max: configured value of the limit
+//
+// Returns:
+//   - error: notification sending error
+func (ns *notificationServiceImpl) SendLimitReachedNotification(ctx context.Context, limit string, max int64) error {
+	return ns.sendNotification(ctx, fmt.Sprintf(ns.catalog.limitReached, limit, ns.formatNumber(int(max))))
+}
+
+// SendSoldOutNotification sends a notification that a gift seen earlier sold out.
+//
+// Parameters:
+//   - ctx: context for request cancellation and timeout control
+//   - gift: the gift that sold out
+//
+// Returns:
+//   - error: notification sending error
+func (ns *notificationServiceImpl) SendSoldOutNotification(ctx context.Context, gift *tg.StarGift) error {
+	return ns.sendNotification(ctx, soldOutMessage(gift, ns.catalog, ns.thousandsSeparator))
+}
+
+// soldOutMessage describes a gift that sold out, with its total supply for limited gifts.
+func soldOutMessage(gift *tg.StarGift, catalog messageCatalog, separator string) string {
+	title, ok := gift.GetTitle()
+	if !ok {
+		title = catalog.unknownGift
+	}
+	message := fmt.Sprintf(catalog.soldOut, title, gift.GetID(), groupDigits(int(gift.GetStars()), separator))
+	if total, ok := gift.GetAvailabilityTotal(); ok {
+		message += fmt.Sprintf(catalog.soldOutTotal, groupDigits(total, separator))
+	}
+	return message
+}
+
+// SendReconnectNotification sends a notification about a connection lifecycle event
+// such as a detected disconnect or the result of a reconnect attempt.
+//
+// Parameters:
+//   - ctx: context for request cancellation
+//   - event: description of the reconnect event
+//   - err: error that caused or ended the event, nil if none
+//
+// Returns:
+//   - error: notification sending error
+func (ns *notificationServiceImpl) SendReconnectNotification(ctx context.Context, event string, err error) error {
+	message := fmt.Sprintf(ns.catalog.connection, event)
+	if err != nil {
+		message = fmt.Sprintf(ns.catalog.connectionError, message, err.Error())
+	}
+	return ns.sendNotification(ctx, message)
+}
+
+// formatNumber formats integers with the configured thousands separator for better readability.
+//
+// Examples with the default separator:
 //   - 1000 -> "1,000"
 //   - 1234567 -> "1,234,567"
-//   - 0 -> "0"
+//   - -1500 -> "-1,500"
 //
 // Parameters:
 //   - num: the integer to format
 //
 // Returns:
-//   - string: formatted number with comma separators
-func formatNumber(num int) string {
-	if num == 0 {
-		return "0"
-	}
+//   - string: formatted number with thousands separators
+func (ns *notificationServiceImpl) formatNumber(num int) string {
+	return groupDigits(num, ns.thousandsSeparator)
+}
 
-	str := fmt.Sprintf("%d", num)
+// groupDigits inserts the separator every three digits of num, keeping the sign in front.
+func groupDigits(num int, separator string) string {
+	str := strconv.Itoa(num)
+	sign := ""
+	if strings.HasPrefix(str, "-") {
+		sign, str = "-", str[1:]
+	}
 	if len(str) <= 3 {
-		return str
+		return sign + str
 	}
 
-	result := ""
+	var result strings.Builder
+	result.WriteString(sign)
 	for i, digit := range str {
 		if i > 0 && (len(str)-i)%3 == 0 {
-			result += ","
+			result.WriteString(separator)
 		}
-		result += string(digit)
+		result.WriteRune(digit)
 	}
 
-	return result
+	return result.String()
 }
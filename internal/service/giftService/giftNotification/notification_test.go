@@ -1,12 +1,23 @@
 package giftNotification
 
 import (
+	"context"
+	"errors"
 	"gift-buyer/internal/config"
 	"gift-buyer/internal/infrastructure/logsWriter/logTypes"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
+	"gift-buyer/internal/service/giftService/rateLimiter"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockLogsWriter для тестирования
@@ -30,7 +41,7 @@ func TestNewNotification(t *testing.T) {
 	}
 	mockLogsWriter := &MockLogsWriter{}
 
-	service := NewNotification(mockClient, mockConfig, mockLogsWriter)
+	service := NewNotification(mockClient, nil, mockConfig, mockLogsWriter)
 
 	assert.NotNil(t, service)
 }
@@ -43,7 +54,7 @@ func TestNotificationService_Interface_Compliance(t *testing.T) {
 	}
 	mockLogsWriter := &MockLogsWriter{}
 
-	service := NewNotification(mockClient, mockConfig, mockLogsWriter)
+	service := NewNotification(mockClient, nil, mockConfig, mockLogsWriter)
 
 	// Verify that the service implements the NotificationService interface
 	// This is a compile-time check, but we can also verify at runtime
@@ -58,7 +69,7 @@ func TestNotificationService_Structure(t *testing.T) {
 	}
 	mockLogsWriter := &MockLogsWriter{}
 
-	service := NewNotification(mockClient, mockConfig, mockLogsWriter)
+	service := NewNotification(mockClient, nil, mockConfig, mockLogsWriter)
 
 	// Cast to concrete type to verify internal structure
 	assert.Equal(t, mockClient, service.Bot)
@@ -73,7 +84,7 @@ func TestNotificationService_NilClient(t *testing.T) {
 	mockLogsWriter := &MockLogsWriter{}
 
 	// Test with nil client - should not panic during creation
-	service := NewNotification(nil, mockConfig, mockLogsWriter)
+	service := NewNotification(nil, nil, mockConfig, mockLogsWriter)
 	assert.NotNil(t, service)
 
 	// Cast to concrete type to verify nil client is stored
@@ -86,10 +97,687 @@ func TestNotificationService_NilConfig(t *testing.T) {
 	mockLogsWriter := &MockLogsWriter{}
 
 	// Test with nil config - should not panic during creation
-	service := NewNotification(mockClient, nil, mockLogsWriter)
+	service := NewNotification(mockClient, nil, nil, mockLogsWriter)
 	assert.NotNil(t, service)
 
 	// Cast to concrete type to verify nil config is stored
 	assert.Equal(t, mockClient, service.Bot)
 	assert.Nil(t, service.Config)
 }
+
+// fakeSender records every message sent through it
+type fakeSender struct {
+	mu       sync.Mutex
+	requests []*tg.MessagesSendMessageRequest
+	err      error
+}
+
+func (f *fakeSender) MessagesSendMessage(ctx context.Context, request *tg.MessagesSendMessageRequest) (tg.UpdatesClass, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, request)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &tg.Updates{}, nil
+}
+
+func (f *fakeSender) sent() []*tg.MessagesSendMessageRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*tg.MessagesSendMessageRequest(nil), f.requests...)
+}
+
+func TestNotificationService_BotMode(t *testing.T) {
+	botSender := &fakeSender{}
+	userSender := &fakeSender{}
+	service := NewNotification(nil, nil, &config.TgSettings{
+		NotificationChatID: 12345,
+		NotificationMode:   config.NotificationModeBot,
+	}, &MockLogsWriter{})
+	service.botSender = botSender
+	service.userSender = userSender
+
+	assert.True(t, service.SetBot())
+	assert.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+
+	assert.Empty(t, userSender.sent())
+	requests := botSender.sent()
+	if assert.Len(t, requests, 1) {
+		peer, ok := requests[0].Peer.(*tg.InputPeerUser)
+		assert.True(t, ok)
+		assert.Equal(t, int64(12345), peer.UserID)
+	}
+}
+
+func TestNotificationService_DefaultModeIsBot(t *testing.T) {
+	botSender := &fakeSender{}
+	userSender := &fakeSender{}
+	service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, &MockLogsWriter{})
+	service.botSender = botSender
+	service.userSender = userSender
+
+	assert.NoError(t, service.SendErrorNotification(context.Background(), assert.AnError))
+
+	assert.Len(t, botSender.sent(), 1)
+	assert.Empty(t, userSender.sent())
+}
+
+func TestNotificationService_SelfMode(t *testing.T) {
+	botSender := &fakeSender{}
+	userSender := &fakeSender{}
+	service := NewNotification(nil, nil, &config.TgSettings{
+		NotificationMode: config.NotificationModeSelf,
+	}, &MockLogsWriter{})
+	service.botSender = botSender
+	service.userSender = userSender
+
+	assert.True(t, service.SetBot())
+	assert.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+
+	assert.Empty(t, botSender.sent())
+	requests := userSender.sent()
+	if assert.Len(t, requests, 1) {
+		_, ok := requests[0].Peer.(*tg.InputPeerSelf)
+		assert.True(t, ok)
+	}
+}
+
+func TestNotificationService_SelfModeWithoutUserClient(t *testing.T) {
+	botSender := &fakeSender{}
+	service := NewNotification(nil, nil, &config.TgSettings{
+		NotificationChatID: 12345,
+		NotificationMode:   config.NotificationModeSelf,
+	}, &MockLogsWriter{})
+	service.botSender = botSender
+
+	assert.False(t, service.SetBot())
+	assert.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+	assert.Empty(t, botSender.sent())
+}
+
+func TestNotificationService_RetryDelayJitter(t *testing.T) {
+	base := 2 * time.Second
+
+	t.Run("configured jitter", func(t *testing.T) {
+		service := NewNotification(nil, nil, &config.TgSettings{NotificationRetryJitter: 0.5}, &MockLogsWriter{})
+
+		distinct := make(map[time.Duration]struct{})
+		for i := 0; i < 200; i++ {
+			delay := service.retryDelay(base)
+			assert.GreaterOrEqual(t, delay, base/2)
+			assert.LessOrEqual(t, delay, base*3/2)
+			distinct[delay] = struct{}{}
+		}
+		assert.Greater(t, len(distinct), 1, "retry delays should not be identical")
+	})
+
+	t.Run("default jitter", func(t *testing.T) {
+		service := NewNotification(nil, nil, &config.TgSettings{}, &MockLogsWriter{})
+
+		minDelay := time.Duration(float64(base) * (1 - defaultRetryJitter))
+		maxDelay := time.Duration(float64(base) * (1 + defaultRetryJitter))
+		for i := 0; i < 200; i++ {
+			delay := service.retryDelay(base)
+			assert.GreaterOrEqual(t, delay, minDelay)
+			assert.LessOrEqual(t, delay, maxDelay)
+		}
+	})
+
+	t.Run("negative jitter disables it", func(t *testing.T) {
+		service := NewNotification(nil, nil, &config.TgSettings{NotificationRetryJitter: -1}, &MockLogsWriter{})
+
+		for i := 0; i < 50; i++ {
+			assert.Equal(t, base, service.retryDelay(base))
+		}
+	})
+}
+
+// countingLimiter counts the tokens taken from the wrapped limiter and whether it was closed
+type countingLimiter struct {
+	giftInterfaces.RateLimiter
+	acquired atomic.Int64
+	closed   atomic.Bool
+}
+
+func (l *countingLimiter) Acquire(ctx context.Context) error {
+	if err := l.RateLimiter.Acquire(ctx); err != nil {
+		return err
+	}
+	l.acquired.Add(1)
+	return nil
+}
+
+func (l *countingLimiter) Close() {
+	l.closed.Store(true)
+	l.RateLimiter.Close()
+}
+
+func TestNotificationService_SeparateRateLimiter(t *testing.T) {
+	purchaseLimiter := rateLimiter.NewRateLimiter(5)
+	defer purchaseLimiter.Close()
+	notificationLimiter := &countingLimiter{RateLimiter: rateLimiter.NewRateLimiter(5)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Heavy purchase load drains the purchase limiter and keeps it drained
+	for i := 0; i < 5; i++ {
+		require.NoError(t, purchaseLimiter.Acquire(ctx))
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for purchaseLimiter.Acquire(ctx) == nil {
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	botSender := &fakeSender{}
+	service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, &MockLogsWriter{})
+	service.botSender = botSender
+	service.SetRateLimiter(notificationLimiter)
+
+	sendCtx, sendCancel := context.WithTimeout(context.Background(), time.Second)
+	defer sendCancel()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, service.SendBuyStatus(sendCtx, "done", nil))
+	}
+	assert.Len(t, botSender.sent(), 3)
+	assert.Equal(t, int64(3), notificationLimiter.acquired.Load(), "every send takes a token of the notification limiter")
+
+	service.Close()
+	assert.True(t, notificationLimiter.closed.Load())
+}
+
+func TestNotificationService_RateLimiterCancelled(t *testing.T) {
+	limiter := rateLimiter.NewRateLimiter(0)
+	defer limiter.Close()
+
+	botSender := &fakeSender{}
+	service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, &MockLogsWriter{})
+	service.botSender = botSender
+	service.SetRateLimiter(limiter)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, service.SendBuyStatus(ctx, "done", nil), context.DeadlineExceeded)
+	assert.Empty(t, botSender.sent())
+}
+
+func TestNotificationService_CancelledDuringRetry(t *testing.T) {
+	for _, sendErr := range []error{errors.New("network error"), errors.New("FLOOD_WAIT_30")} {
+		t.Run(sendErr.Error(), func(t *testing.T) {
+			botSender := &fakeSender{err: sendErr}
+			service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, &MockLogsWriter{})
+			service.botSender = botSender
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				// Cancel once the first attempt failed and the retry delay started
+				for len(botSender.sent()) == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				cancel()
+			}()
+
+			start := time.Now()
+			err := service.sendNotification(ctx, "message")
+
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+			assert.Len(t, botSender.sent(), 1)
+		})
+	}
+}
+
+func TestNotificationService_MaxNotifications(t *testing.T) {
+	t.Run("уведомления прекращаются после лимита", func(t *testing.T) {
+		botSender := &fakeSender{}
+		service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, &MockLogsWriter{})
+		service.botSender = botSender
+		service.SetMaxNotifications(3)
+
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+		}
+
+		requests := botSender.sent()
+		if assert.Len(t, requests, 4) {
+			for _, request := range requests[:3] {
+				assert.Contains(t, request.Message, "Buy Status")
+			}
+			assert.Contains(t, requests[3].Message, "Notification limit reached")
+		}
+	})
+
+	t.Run("финальное сообщение отправляется один раз при конкурентных отправках", func(t *testing.T) {
+		botSender := &fakeSender{}
+		service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, &MockLogsWriter{})
+		service.botSender = botSender
+		service.SetMaxNotifications(5)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = service.SendErrorNotification(context.Background(), assert.AnError)
+			}()
+		}
+		wg.Wait()
+
+		requests := botSender.sent()
+		assert.Len(t, requests, 6)
+		final := 0
+		for _, request := range requests {
+			if strings.Contains(request.Message, "Notification limit reached") {
+				final++
+			}
+		}
+		assert.Equal(t, 1, final)
+	})
+
+	t.Run("без лимита по умолчанию", func(t *testing.T) {
+		botSender := &fakeSender{}
+		service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, &MockLogsWriter{})
+		service.botSender = botSender
+
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+		}
+
+		assert.Len(t, botSender.sent(), 10)
+	})
+}
+
+// recordingLogsWriter records the logged errors
+type recordingLogsWriter struct {
+	MockLogsWriter
+	mu     sync.Mutex
+	errors []string
+}
+
+func (r *recordingLogsWriter) LogError(message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, message)
+}
+
+func (r *recordingLogsWriter) logged(substr string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, message := range r.errors {
+		if strings.Contains(message, substr) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestNotificationService_Fallback(t *testing.T) {
+	botSender := &fakeSender{err: errors.New("bot unreachable")}
+	logsWriter := &recordingLogsWriter{}
+	fallbackFile := filepath.Join(t.TempDir(), "notifications.log")
+
+	service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, logsWriter)
+	service.botSender = botSender
+	service.sleep = func(ctx context.Context, delay time.Duration) error { return nil }
+	service.SetFallback(2, fallbackFile)
+
+	ctx := context.Background()
+
+	// Below the threshold the failure is reported as usual
+	assert.Error(t, service.SendBuyStatus(ctx, "first", nil))
+	assert.Len(t, botSender.sent(), 3)
+	assert.Equal(t, 0, logsWriter.logged("Undelivered notification"))
+
+	// The second failure in a row engages the fallback
+	assert.NoError(t, service.SendBuyStatus(ctx, "second", nil))
+	assert.Equal(t, 1, logsWriter.logged("falling back to logs"))
+	assert.Equal(t, 1, logsWriter.logged("Undelivered notification"))
+
+	// In fallback mode a single attempt is made per notification
+	assert.NoError(t, service.SendBuyStatus(ctx, "third", nil))
+	assert.Len(t, botSender.sent(), 7)
+	assert.Equal(t, 2, logsWriter.logged("Undelivered notification"))
+
+	data, err := os.ReadFile(fallbackFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "second")
+	assert.Contains(t, string(data), "third")
+	assert.NotContains(t, string(data), "first")
+
+	// Delivery resumes once the bot recovers
+	botSender.mu.Lock()
+	botSender.err = nil
+	botSender.mu.Unlock()
+
+	assert.NoError(t, service.SendBuyStatus(ctx, "fourth", nil))
+	assert.Equal(t, 1, logsWriter.logged("recovered"))
+	assert.False(t, service.inFallback())
+
+	assert.NoError(t, service.SendBuyStatus(ctx, "fifth", nil))
+	assert.Len(t, botSender.sent(), 9)
+	assert.Equal(t, 2, logsWriter.logged("Undelivered notification"))
+	assert.Equal(t, 1, logsWriter.logged("recovered"))
+}
+
+func TestNotificationService_FallbackDisabled(t *testing.T) {
+	botSender := &fakeSender{err: errors.New("bot unreachable")}
+	logsWriter := &recordingLogsWriter{}
+
+	service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, logsWriter)
+	service.botSender = botSender
+	service.sleep = func(ctx context.Context, delay time.Duration) error { return nil }
+
+	for i := 0; i < 5; i++ {
+		assert.Error(t, service.SendBuyStatus(context.Background(), "status", nil))
+	}
+	assert.Len(t, botSender.sent(), 15)
+	assert.Equal(t, 0, logsWriter.logged("Undelivered notification"))
+}
+
+func TestNotificationService_GiftTemplate(t *testing.T) {
+	gift := &tg.StarGift{ID: 42, Stars: 1500, ConvertStars: 1200, Limited: true}
+	gift.SetTitle("Ракета")
+	gift.SetAvailabilityTotal(1000)
+	gift.SetAvailabilityRemains(250)
+
+	t.Run("пользовательский шаблон", func(t *testing.T) {
+		botSender := &fakeSender{}
+		service := NewNotification(nil, nil, &config.TgSettings{
+			NotificationChatID:   12345,
+			NotificationTemplate: "{{.Title}} #{{.ID}}: {{.Price}}/{{.ConvertPrice}} ⭐️, {{.Available}} из {{.Supply}} ({{printf \"%.0f\" .Percentage}}%)",
+		}, &MockLogsWriter{})
+		service.botSender = botSender
+
+		require.NoError(t, service.SendNewGiftNotification(context.Background(), gift))
+
+		requests := botSender.sent()
+		require.Len(t, requests, 1)
+		assert.Equal(t, "Ракета #42: 1500/1200 ⭐️, 250 из 1000 (25%)", requests[0].Message)
+	})
+
+	t.Run("некорректный шаблон", func(t *testing.T) {
+		botSender := &fakeSender{}
+		logs := &recordingLogsWriter{}
+		service := NewNotification(nil, nil, &config.TgSettings{
+			NotificationChatID:   12345,
+			NotificationTemplate: "{{.Title",
+		}, logs)
+		service.botSender = botSender
+
+		assert.Nil(t, service.giftTemplate)
+		assert.Equal(t, 1, logs.logged("failed to parse notification template"))
+
+		require.NoError(t, service.SendNewGiftNotification(context.Background(), gift))
+
+		requests := botSender.sent()
+		require.Len(t, requests, 1)
+		assert.Contains(t, requests[0].Message, "🎁 New gift detected!")
+		assert.Contains(t, requests[0].Message, "Ракета (42)")
+	})
+
+	t.Run("ошибка при подстановке", func(t *testing.T) {
+		botSender := &fakeSender{}
+		logs := &recordingLogsWriter{}
+		service := NewNotification(nil, nil, &config.TgSettings{
+			NotificationChatID:   12345,
+			NotificationTemplate: "{{.Unknown}}",
+		}, logs)
+		service.botSender = botSender
+
+		require.NoError(t, service.SendNewGiftNotification(context.Background(), gift))
+
+		requests := botSender.sent()
+		require.Len(t, requests, 1)
+		assert.Contains(t, requests[0].Message, "🎁 New gift detected!")
+		assert.Equal(t, 1, logs.logged("failed to render notification template"))
+	})
+}
+
+// chatSender fails every message sent to the listed chats
+type chatSender struct {
+	mu      sync.Mutex
+	failing map[int64]error
+	sent    map[int64]int
+}
+
+func (c *chatSender) MessagesSendMessage(ctx context.Context, request *tg.MessagesSendMessageRequest) (tg.UpdatesClass, error) {
+	chatID := request.Peer.(*tg.InputPeerUser).UserID
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent[chatID]++
+	if err := c.failing[chatID]; err != nil {
+		return nil, err
+	}
+	return &tg.Updates{}, nil
+}
+
+func (c *chatSender) count(chatID int64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sent[chatID]
+}
+
+func TestNotificationService_MultipleChats(t *testing.T) {
+	newService := func(sender *chatSender) *notificationServiceImpl {
+		service := NewNotification(nil, nil, &config.TgSettings{
+			NotificationChatID:  1,
+			NotificationChatIDs: []int64{2, 3, 1},
+		}, &MockLogsWriter{})
+		service.botSender = sender
+		service.sleep = func(ctx context.Context, delay time.Duration) error { return nil }
+		return service
+	}
+
+	t.Run("все чаты получают уведомление", func(t *testing.T) {
+		sender := &chatSender{sent: make(map[int64]int)}
+		service := newService(sender)
+
+		require.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+
+		// The legacy chat listed again in the slice gets the message only once
+		assert.Equal(t, 1, sender.count(1))
+		assert.Equal(t, 1, sender.count(2))
+		assert.Equal(t, 1, sender.count(3))
+	})
+
+	t.Run("частичный сбой", func(t *testing.T) {
+		sender := &chatSender{
+			sent: make(map[int64]int),
+			failing: map[int64]error{
+				2: errors.New("FLOOD_WAIT_30"),
+				3: errors.New("chat not found"),
+			},
+		}
+		service := newService(sender)
+
+		require.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+
+		assert.Equal(t, 1, sender.count(1))
+		// Failing chats are retried independently of the healthy one
+		assert.Equal(t, 3, sender.count(2))
+		assert.Equal(t, 3, sender.count(3))
+	})
+
+	t.Run("сбой во всех чатах", func(t *testing.T) {
+		sender := &chatSender{
+			sent: make(map[int64]int),
+			failing: map[int64]error{
+				1: errors.New("bot blocked"),
+				2: errors.New("bot blocked"),
+				3: errors.New("bot blocked"),
+			},
+		}
+		service := newService(sender)
+
+		err := service.SendBuyStatus(context.Background(), "done", nil)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "all 3 chats")
+	})
+
+	t.Run("только дополнительные чаты", func(t *testing.T) {
+		sender := &chatSender{sent: make(map[int64]int)}
+		service := NewNotification(nil, nil, &config.TgSettings{NotificationChatIDs: []int64{7}}, &MockLogsWriter{})
+		service.botSender = sender
+
+		require.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+		assert.Equal(t, 1, sender.count(7))
+	})
+}
+
+func TestNotificationService_SoldOut(t *testing.T) {
+	botSender := &fakeSender{}
+	service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345}, &MockLogsWriter{})
+	service.botSender = botSender
+
+	gift := &tg.StarGift{ID: 42, Stars: 2500, Limited: true, SoldOut: true}
+	gift.SetTitle("Rocket")
+	gift.SetAvailabilityTotal(10000)
+
+	require.NoError(t, service.SendSoldOutNotification(context.Background(), gift))
+
+	requests := botSender.sent()
+	require.Len(t, requests, 1)
+	assert.Contains(t, requests[0].Message, "Sold out: Rocket (42)")
+	assert.Contains(t, requests[0].Message, "2,500")
+	assert.Contains(t, requests[0].Message, "Total amount: 10,000")
+}
+
+func TestNotificationService_Language(t *testing.T) {
+	newService := func(language string) (*notificationServiceImpl, *fakeSender) {
+		botSender := &fakeSender{}
+		service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345, Language: language}, &MockLogsWriter{})
+		service.botSender = botSender
+		return service, botSender
+	}
+	gift := &tg.StarGift{ID: 42, Stars: 2500, ConvertStars: 2000}
+
+	t.Run("русские сообщения", func(t *testing.T) {
+		service, botSender := newService(config.LanguageRussian)
+
+		require.NoError(t, service.SendNewGiftNotification(context.Background(), gift))
+		require.NoError(t, service.SendBuyStatus(context.Background(), "готово", nil))
+		require.NoError(t, service.SendBuyStatus(context.Background(), "сбой", assert.AnError))
+		require.NoError(t, service.SendUpdateNotification(context.Background(), "v2.0.0", "исправления"))
+
+		requests := botSender.sent()
+		require.Len(t, requests, 4)
+		assert.Contains(t, requests[0].Message, "Найден новый подарок")
+		assert.Contains(t, requests[0].Message, "Неизвестный подарок (42)")
+		assert.Contains(t, requests[1].Message, "Статус покупки: готово")
+		assert.Contains(t, requests[1].Message, "Успешно")
+		assert.Contains(t, requests[2].Message, "Ошибка: "+assert.AnError.Error())
+		assert.Contains(t, requests[3].Message, "Доступна новая версия: v2.0.0")
+	})
+
+	t.Run("неизвестный язык заменяется английским", func(t *testing.T) {
+		service, botSender := newService("de")
+
+		require.NoError(t, service.SendNewGiftNotification(context.Background(), gift))
+		require.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+
+		requests := botSender.sent()
+		require.Len(t, requests, 2)
+		assert.Contains(t, requests[0].Message, "New gift detected!")
+		assert.Contains(t, requests[0].Message, "Unknown Gift (42)")
+		assert.Contains(t, requests[1].Message, "Buy Status: done")
+	})
+
+	t.Run("служебные сообщения на обоих языках", func(t *testing.T) {
+		soldOut := &tg.StarGift{ID: 42, Stars: 2500, Limited: true, SoldOut: true}
+		soldOut.SetAvailabilityTotal(10000)
+
+		for language, expected := range map[string][]string{
+			config.LanguageEnglish: {
+				"Startup snapshot", "Available gifts: 120", "Matching criteria: 3",
+				"Limit reached: max_buy_count (10)", "No more gifts will be bought",
+				"Sold out: Unknown Gift (42)", "Price: 2,500", "Total amount: 10,000",
+				"Connection: reconnected", "Connection: reconnect failed", "Error: " + assert.AnError.Error(),
+				"Notification limit reached (6)",
+			},
+			config.LanguageRussian: {
+				"Состояние при запуске", "Доступно подарков: 120", "Подходят под критерии: 3",
+				"Достигнут лимит: max_buy_count (10)", "Больше подарки покупаться не будут",
+				"Распродан: Неизвестный подарок (42)", "Цена: 2,500", "Всего: 10,000",
+				"Соединение: reconnected", "Соединение: reconnect failed", "Ошибка: " + assert.AnError.Error(),
+				"Достигнут лимит уведомлений (6)",
+			},
+		} {
+			service, botSender := newService(language)
+			service.SetMaxNotifications(6)
+
+			require.NoError(t, service.SendStartupSnapshot(context.Background(), 120, 3))
+			require.NoError(t, service.SendLimitReachedNotification(context.Background(), "max_buy_count", 10))
+			require.NoError(t, service.SendSoldOutNotification(context.Background(), soldOut))
+			require.NoError(t, service.SendReconnectNotification(context.Background(), "reconnected", nil))
+			require.NoError(t, service.SendReconnectNotification(context.Background(), "reconnect failed", assert.AnError))
+			require.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+			require.NoError(t, service.SendBuyStatus(context.Background(), "done", nil))
+
+			var sent strings.Builder
+			for _, request := range botSender.sent() {
+				sent.WriteString(request.Message + "\n")
+			}
+			for _, text := range expected {
+				assert.Contains(t, sent.String(), text, language)
+			}
+		}
+	})
+
+	t.Run("язык без учета регистра", func(t *testing.T) {
+		assert.Equal(t, messages[config.LanguageRussian], catalogFor(" RU "))
+		assert.Equal(t, messages[config.LanguageEnglish], catalogFor(""))
+	})
+}
+
+func TestNotificationService_FormatNumber(t *testing.T) {
+	tests := []struct {
+		name      string
+		separator string
+		num       int
+		expected  string
+	}{
+		{"запятая по умолчанию", "", 1234567, "1,234,567"},
+		{"запятая", ",", 1000, "1,000"},
+		{"пробел", " ", 1234567, "1 234 567"},
+		{"точка", ".", 25000, "25.000"},
+		{"короткое число", " ", 999, "999"},
+		{"ноль", ",", 0, "0"},
+		{"отрицательное короткое", ",", -100, "-100"},
+		{"отрицательное", ",", -1500, "-1,500"},
+		{"отрицательное с пробелом", " ", -123456789, "-123 456 789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewNotification(nil, nil, &config.TgSettings{ThousandsSeparator: tt.separator}, &MockLogsWriter{})
+
+			assert.Equal(t, tt.expected, service.formatNumber(tt.num))
+		})
+	}
+
+	t.Run("разделитель в уведомлении", func(t *testing.T) {
+		botSender := &fakeSender{}
+		service := NewNotification(nil, nil, &config.TgSettings{NotificationChatID: 12345, ThousandsSeparator: " "}, &MockLogsWriter{})
+		service.botSender = botSender
+
+		gift := &tg.StarGift{ID: 42, Stars: 2500, SoldOut: true}
+		gift.SetTitle("Rocket")
+		require.NoError(t, service.SendSoldOutNotification(context.Background(), gift))
+
+		requests := botSender.sent()
+		require.Len(t, requests, 1)
+		assert.Contains(t, requests[0].Message, "2 500")
+	})
+}
@@ -1,17 +1,45 @@
 package giftTypes
 
-import "github.com/gotd/td/tg"
+import (
+	"context"
+
+	"github.com/gotd/td/tg"
+)
 
 type GiftResult struct {
 	GiftID  int64
 	Success bool
 	Err     error
+
+	// Simulated marks a successful purchase made in dry run mode
+	Simulated bool
 }
 
 type GiftSummary struct {
 	GiftID    int64
 	Requested int64
 	Success   int64
+
+	// Simulated counts purchases made in dry run mode, they are not included in Success
+	Simulated int64
+}
+
+// ReceiverShare is the weight of a receiver in a weighted receiver distribution
+type ReceiverShare struct {
+	// Receiver is the receiver as configured in the receiver lists
+	Receiver string
+
+	// Weight is the relative number of units the receiver gets
+	Weight int
+}
+
+// PurchaseStage is one step of a staged purchase
+type PurchaseStage struct {
+	// Count is the number of units bought in the stage
+	Count int64
+
+	// RequirePreviousSuccess runs the stage only if every unit of the previous stage was bought
+	RequirePreviousSuccess bool
 }
 
 type GiftRequire struct {
@@ -20,4 +48,102 @@ type GiftRequire struct {
 	ReceiverType []int
 	CountForBuy  int64
 	Hide         bool
+
+	// Upgrade includes the upgrade of the gift in the purchase
+	Upgrade bool
+
+	// BuyMaxAffordable makes the buyer set CountForBuy at buy time to as many units as
+	// the stars balance allows, capped by the global purchase limit
+	BuyMaxAffordable bool
+
+	// StarBudget caps the stars a max-affordable purchase spends (0 for no budget)
+	StarBudget int64
+
+	// CriteriaIndex is the position of the matched criteria in the config (0 is the first)
+	CriteriaIndex int
+
+	// BroadcastToAllReceivers makes the batch buy one gift for every configured receiver
+	BroadcastToAllReceivers bool
+
+	// BuyForAllReceiverTypes makes the batch buy CountForBuy gifts for every receiver type
+	BuyForAllReceiverTypes bool
+
+	// ReceiverDistribution distributes the units to receivers proportionally to their weights
+	ReceiverDistribution []ReceiverShare
+
+	// SkipNotify disables the new gift notification for this gift
+	SkipNotify bool
+
+	// SkipBuy disables buying this gift, it is only notified about
+	SkipBuy bool
+
+	// Stages buys the gift in sequential stages instead of all CountForBuy units at once.
+	// CountForBuy is the sum of the stage counts
+	Stages []PurchaseStage
+
+	// ReceiversSaturated is set to 1 once every receiver reached its per-receiver cap,
+	// so the remaining units of the batch are skipped. It must only be accessed atomically.
+	ReceiversSaturated int32
+
+	// MaxPerGift caps the units of this gift ID bought in total across all batches,
+	// taken from the criteria Count (0 for no cap)
+	MaxPerGift int64
+
+	// ReceiverCursor counts invoices created for this batch without a receiver slot bound
+	// by WithReceiverSlot and drives their receiver rotation. It must only be accessed atomically.
+	ReceiverCursor int64
+}
+
+// receiverSlotKey is the key of the receiver slot stored in a context.
+type receiverSlotKey struct{}
+
+// WithReceiverSlot binds a receiver slot, the position of the unit in its batch, to a
+// single unit. Every invoice created with the returned context goes to the receiver of
+// that slot, so the retries of a unit never take the receiver of another unit.
+//
+// Parameters:
+//   - ctx: context of the unit
+//   - slot: position of the unit in the batch, starting at 0
+//
+// Returns:
+//   - context.Context: context carrying the receiver slot of the unit
+func WithReceiverSlot(ctx context.Context, slot int64) context.Context {
+	return context.WithValue(ctx, receiverSlotKey{}, slot)
+}
+
+// ReceiverSlot returns the receiver slot bound to the context by WithReceiverSlot.
+//
+// Returns:
+//   - int64: receiver slot of the unit
+//   - bool: false if the context carries no receiver slot
+func ReceiverSlot(ctx context.Context) (int64, bool) {
+	slot, ok := ctx.Value(receiverSlotKey{}).(int64)
+	return slot, ok
+}
+
+// reservationKey is the key of the receiver reservation stored in a context.
+type reservationKey struct{}
+
+// Reservation is the per-receiver cap unit taken by the invoice of a purchase attempt.
+// It is given back when the attempt fails, so that the receiver can still get the gift.
+type Reservation struct {
+	// Receiver identifies the reserved receiver of the gift, empty while nothing is reserved
+	Receiver string
+}
+
+// WithReservation returns a context recording the receiver reservation of one purchase attempt.
+//
+// Parameters:
+//   - ctx: context of the attempt
+//
+// Returns:
+//   - context.Context: context carrying an empty reservation
+func WithReservation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reservationKey{}, &Reservation{})
+}
+
+// AttemptReservation returns the reservation recorded by the context, nil if it has none.
+func AttemptReservation(ctx context.Context) *Reservation {
+	reservation, _ := ctx.Value(reservationKey{}).(*Reservation)
+	return reservation
 }
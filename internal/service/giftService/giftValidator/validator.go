@@ -6,6 +6,10 @@ package giftValidator
 import (
 	"gift-buyer/internal/config"
 	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/logger"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gotd/td/tg"
 )
@@ -18,13 +22,36 @@ type giftValidatorImpl struct {
 	// criteria contains the list of validation criteria for gift purchases
 	criteria []config.Criterias
 
+	// releaseWindows holds the parsed BuyAfter and BuyBefore bounds of every criteria
+	releaseWindows []releaseWindow
+
 	// totalStarCap is the maximum total stars that can be spent across all gifts
 	totalStarCap int64
 
 	// testMode enables test mode which bypasses certain validations
 	testMode bool
+
+	// now returns the current time used for criteria active windows
+	now func() time.Time
+
+	// missingAvailabilityPolicy defines how limited gifts without availability data are handled
+	missingAvailabilityPolicy string
+
+	// scamDetection configures the heuristics rejecting suspicious gifts
+	scamDetection config.ScamDetection
+
+	// mu protects the criteria and gift parameters from concurrent reloads
+	mu sync.RWMutex
+}
+
+// releaseWindow is the release window of a criteria, zero bounds are open
+type releaseWindow struct {
+	after, before time.Time
 }
 
+// windowLayout is the time format of criteria active window bounds
+const windowLayout = "15:04"
+
 // NewGiftValidator creates a new GiftValidator instance with the specified criteria.
 // The validator will use the provided criteria to evaluate gift eligibility.
 //
@@ -37,12 +64,17 @@ type giftValidatorImpl struct {
 //   - giftInterfaces.GiftValidator: configured gift validator instance
 func NewGiftValidator(criterias []config.Criterias, giftParam config.GiftParam) *giftValidatorImpl {
 	return &giftValidatorImpl{
-		criteria:      criterias,
-		totalStarCap:  giftParam.TotalStarCap,
-		premium:       giftParam.OnlyPremium,
-		testMode:      giftParam.TestMode,
-		limitedStatus: giftParam.LimitedStatus,
-		releaseBy:     giftParam.ReleaseBy,
+		criteria:       criterias,
+		releaseWindows: parseReleaseWindows(criterias),
+		totalStarCap:   giftParam.TotalStarCap,
+		premium:        giftParam.OnlyPremium,
+		testMode:       giftParam.TestMode,
+		limitedStatus:  giftParam.LimitedStatus,
+		releaseBy:      giftParam.ReleaseBy,
+		now:            time.Now,
+
+		missingAvailabilityPolicy: giftParam.MissingAvailabilityPolicy,
+		scamDetection:             giftParam.ScamDetection,
 	}
 }
 
@@ -52,9 +84,15 @@ func NewGiftValidator(criterias []config.Criterias, giftParam config.GiftParam)
 //
 // The validation process checks:
 //   - Gift is not sold out
+//   - Gift doesn't look like a scam (if scam detection is enabled)
+//   - Criteria is inside one of its active windows (if any are configured)
+//   - Criteria release window is open (if bounds are configured)
 //   - Price falls within configured range
+//   - Sticker is one of the configured sticker IDs (if any are configured)
+//   - Remaining supply is below the criteria threshold (if one is configured)
 //   - Supply meets minimum requirements (unless in test mode)
 //   - Total star cap is not exceeded (unless in test mode)
+//   - Criteria star budget is not exceeded (unless in test mode)
 //
 // Parameters:
 //   - gift: the star gift to validate against criteria
@@ -63,6 +101,9 @@ func NewGiftValidator(criterias []config.Criterias, giftParam config.GiftParam)
 //   - int64: number of gifts to purchase if eligible (0 if not eligible)
 //   - bool: true if the gift meets any criteria, false otherwise
 func (gv *giftValidatorImpl) IsEligible(gift *tg.StarGift) (*giftTypes.GiftRequire, bool) {
+	gv.mu.RLock()
+	defer gv.mu.RUnlock()
+
 	if gift.SoldOut {
 		return nil, false
 	}
@@ -79,20 +120,188 @@ func (gv *giftValidatorImpl) IsEligible(gift *tg.StarGift) (*giftTypes.GiftRequi
 		return nil, false
 	}
 
-	for _, criteria := range gv.criteria {
-		if gv.priceValid(criteria, gift) && gv.supplyValid(criteria, gift) && gv.starCapValidation(gift) {
-			return &giftTypes.GiftRequire{
-				Gift:         gift,
-				ReceiverType: criteria.ReceiverType,
-				CountForBuy:  criteria.Count,
-				Hide:         criteria.Hide,
-			}, true
+	if gv.suspicious(gift) {
+		return nil, false
+	}
+
+	now := gv.now().UTC()
+	for index, criteria := range gv.criteria {
+		if !gv.criteriaActive(criteria, now) || !gv.releaseWindowOpen(index, now) {
+			continue
+		}
+		if gv.priceValid(criteria, gift) && gv.stickerValid(criteria, gift) && gv.titleValid(criteria, gift) && gv.attributeRarityValidation(criteria, gift) && gv.remainsValid(criteria, gift) && gv.supplyValid(criteria, gift) && gv.starCapValidation(gift) && gv.budgetValid(criteria, gift) {
+			require := &giftTypes.GiftRequire{
+				Gift:          gift,
+				ReceiverType:  criteria.ReceiverType,
+				CountForBuy:   criteria.Count,
+				Hide:          criteria.Hide,
+				Upgrade:       criteria.Upgrade,
+				CriteriaIndex: index,
+
+				BroadcastToAllReceivers: criteria.BroadcastToAllReceivers,
+				BuyForAllReceiverTypes:  criteria.BuyForAllReceiverTypes,
+
+				SkipNotify: !criteria.Notify,
+				SkipBuy:    !criteria.Buy,
+			}
+			if len(criteria.Stages) > 0 {
+				require.Stages, require.CountForBuy = purchaseStages(criteria.Stages)
+			} else if criteria.Count == config.CountMaxAffordable {
+				require.BuyMaxAffordable, require.CountForBuy = true, 0
+				require.StarBudget = criteria.StarBudget
+			}
+			require.MaxPerGift = perGiftCap(criteria, require.CountForBuy)
+			for _, share := range criteria.ReceiverDistribution {
+				require.ReceiverDistribution = append(require.ReceiverDistribution, giftTypes.ReceiverShare{
+					Receiver: share.Username,
+					Weight:   share.Count,
+				})
+			}
+			return require, true
 		}
 	}
 
 	return nil, false
 }
 
+// purchaseStages converts the configured stages into purchase stages.
+//
+// Parameters:
+//   - stages: configured criteria stages
+//
+// Returns:
+//   - []giftTypes.PurchaseStage: purchase stages in order
+//   - int64: total number of units of all stages
+func purchaseStages(stages []config.StageParams) ([]giftTypes.PurchaseStage, int64) {
+	result := make([]giftTypes.PurchaseStage, 0, len(stages))
+	total := int64(0)
+	for _, stage := range stages {
+		result = append(result, giftTypes.PurchaseStage{
+			Count:                  stage.Count,
+			RequirePreviousSuccess: stage.Condition != config.StageConditionAlways,
+		})
+		total += stage.Count
+	}
+	return result, total
+}
+
+// Update replaces the validation criteria and gift parameters at runtime.
+// It is used to apply a reloaded configuration without restarting the service.
+//
+// Parameters:
+//   - criterias: new purchase criteria
+//   - giftParam: new gift parameters
+func (gv *giftValidatorImpl) Update(criterias []config.Criterias, giftParam config.GiftParam) {
+	gv.mu.Lock()
+	defer gv.mu.Unlock()
+
+	gv.criteria = criterias
+	gv.releaseWindows = parseReleaseWindows(criterias)
+	gv.totalStarCap = giftParam.TotalStarCap
+	gv.premium = giftParam.OnlyPremium
+	gv.testMode = giftParam.TestMode
+	gv.limitedStatus = giftParam.LimitedStatus
+	gv.releaseBy = giftParam.ReleaseBy
+	gv.missingAvailabilityPolicy = giftParam.MissingAvailabilityPolicy
+	gv.scamDetection = giftParam.ScamDetection
+}
+
+// criteriaActive checks if the criteria is active at the given time.
+// Criteria without active windows are always active. Windows with malformed
+// bounds never match, so a typo disables the criteria instead of enabling it around the clock.
+//
+// Parameters:
+//   - criteria: the criteria containing active windows
+//   - now: current time in UTC
+//
+// Returns:
+//   - bool: true if the criteria has no windows or now falls inside one of them
+func (gv *giftValidatorImpl) criteriaActive(criteria config.Criterias, now time.Time) bool {
+	if len(criteria.ActiveWindows) == 0 {
+		return true
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	for _, window := range criteria.ActiveWindows {
+		start, err := time.Parse(windowLayout, window.Start)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(windowLayout, window.End)
+		if err != nil {
+			continue
+		}
+
+		startMinute := start.Hour()*60 + start.Minute()
+		endMinute := end.Hour()*60 + end.Minute()
+		if startMinute <= endMinute {
+			if minute >= startMinute && minute < endMinute {
+				return true
+			}
+		} else if minute >= startMinute || minute < endMinute {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseReleaseWindows parses the BuyAfter and BuyBefore bounds of every criteria.
+// A malformed bound is logged and left open.
+//
+// Parameters:
+//   - criterias: criteria with the release window bounds
+//
+// Returns:
+//   - []releaseWindow: release window of every criteria, in criteria order
+func parseReleaseWindows(criterias []config.Criterias) []releaseWindow {
+	windows := make([]releaseWindow, len(criterias))
+	for i, criteria := range criterias {
+		windows[i].after = parseReleaseBound(i, "buy_after", criteria.BuyAfter)
+		windows[i].before = parseReleaseBound(i, "buy_before", criteria.BuyBefore)
+	}
+	return windows
+}
+
+// parseReleaseBound parses an RFC3339 release window bound, an empty or malformed
+// bound is returned as the zero time.
+func parseReleaseBound(index int, name, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	bound, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		logger.GlobalLogger.Warnf("Ignoring invalid %s %q of criteria %d: %v", name, value, index+1, err)
+		return time.Time{}
+	}
+	return bound.UTC()
+}
+
+// releaseWindowOpen checks if the release window of the criteria is open at the given time.
+// The window includes BuyAfter and excludes BuyBefore.
+//
+// Parameters:
+//   - index: criteria index
+//   - now: current time in UTC
+//
+// Returns:
+//   - bool: true if now falls inside the release window or the criteria has none
+func (gv *giftValidatorImpl) releaseWindowOpen(index int, now time.Time) bool {
+	if index >= len(gv.releaseWindows) {
+		return true
+	}
+
+	window := gv.releaseWindows[index]
+	if !window.after.IsZero() && now.Before(window.after) {
+		return false
+	}
+	if !window.before.IsZero() && !now.Before(window.before) {
+		return false
+	}
+	return true
+}
+
 // priceValid checks if the gift price falls within the specified criteria range.
 //
 // Parameters:
@@ -110,6 +319,125 @@ func (gv *giftValidatorImpl) priceValid(criteria config.Criterias, gift *tg.Star
 	return false
 }
 
+// stickerValid checks if the gift sticker is one of the criteria sticker IDs.
+// Criteria without sticker IDs accept any gift.
+//
+// Parameters:
+//   - criteria: the criteria containing sticker IDs
+//   - gift: the star gift to validate
+//
+// Returns:
+//   - bool: true if there is no sticker filter or the gift sticker matches it
+func (gv *giftValidatorImpl) stickerValid(criteria config.Criterias, gift *tg.StarGift) bool {
+	if len(criteria.StickerIDs) == 0 {
+		return true
+	}
+
+	sticker, ok := gift.Sticker.(*tg.Document)
+	if !ok {
+		return false
+	}
+	for _, id := range criteria.StickerIDs {
+		if sticker.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// titleValid checks the gift title against the criteria title allowlist and blocklist.
+// Matching is a case-insensitive substring match. A gift without a title fails a
+// non-empty allowlist and never matches the blocklist.
+//
+// Parameters:
+//   - criteria: the criteria containing the title lists
+//   - gift: the star gift to validate
+//
+// Returns:
+//   - bool: true if the title is allowed and not blocked
+func (gv *giftValidatorImpl) titleValid(criteria config.Criterias, gift *tg.StarGift) bool {
+	if len(criteria.TitleAllowlist) == 0 && len(criteria.TitleBlocklist) == 0 {
+		return true
+	}
+
+	title, hasTitle := gift.GetTitle()
+	title = strings.ToLower(title)
+
+	if hasTitle && titleMatches(title, criteria.TitleBlocklist) {
+		return false
+	}
+	if len(criteria.TitleAllowlist) == 0 {
+		return true
+	}
+	return hasTitle && titleMatches(title, criteria.TitleAllowlist)
+}
+
+// titleMatches reports whether the lowercase title contains any of the entries.
+func titleMatches(title string, entries []string) bool {
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry != "" && strings.Contains(title, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeRarityValidation checks if any model, backdrop or pattern attribute of the gift
+// has a rarity inside the criteria rarity range.
+//
+// Parameters:
+//   - criteria: the criteria containing the rarity range
+//   - gift: the star gift to validate
+//
+// Returns:
+//   - bool: true if there is no rarity filter or an attribute rarity is in range
+func (gv *giftValidatorImpl) attributeRarityValidation(criteria config.Criterias, gift *tg.StarGift) bool {
+	if criteria.MinRarityPermille <= 0 && criteria.MaxRarityPermille <= 0 {
+		return true
+	}
+	return rarityInRange(criteria, giftAttributes(gift))
+}
+
+// rarityInRange checks if any of the attributes has a rarity inside the criteria rarity range.
+func rarityInRange(criteria config.Criterias, attributes []tg.StarGiftAttributeClass) bool {
+	for _, attribute := range attributes {
+		rarity, ok := attributeRarity(attribute)
+		if !ok {
+			continue
+		}
+		if criteria.MinRarityPermille > 0 && rarity < criteria.MinRarityPermille {
+			continue
+		}
+		if criteria.MaxRarityPermille > 0 && rarity > criteria.MaxRarityPermille {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// giftAttributes returns the attributes of the gift.
+// Attributes are only assigned when a gift is upgraded to a unique gift, so a
+// gift from the catalog has no attribute data and nil is returned.
+func giftAttributes(gift *tg.StarGift) []tg.StarGiftAttributeClass {
+	return nil
+}
+
+// attributeRarity returns the rarity per mille of a model, backdrop or pattern attribute.
+func attributeRarity(attribute tg.StarGiftAttributeClass) (int, bool) {
+	switch a := attribute.(type) {
+	case *tg.StarGiftAttributeModel:
+		return a.RarityPermille, true
+	case *tg.StarGiftAttributeBackdrop:
+		return a.RarityPermille, true
+	case *tg.StarGiftAttributePattern:
+		return a.RarityPermille, true
+	default:
+		return 0, false
+	}
+}
+
 // supplyValid checks if the gift supply meets the minimum requirements.
 // In test mode, this validation is bypassed and always returns true.
 //
@@ -118,6 +446,9 @@ func (gv *giftValidatorImpl) priceValid(criteria config.Criterias, gift *tg.Star
 //   - Remaining supply is greater than 0
 //   - Total supply is not greater than the maximum allowed supply
 //
+// Limited gifts without availability data are accepted only with the
+// "eligible" missing availability policy.
+//
 // For unlimited gifts, it always returns true.
 //
 // Parameters:
@@ -132,16 +463,17 @@ func (gv *giftValidatorImpl) supplyValid(criteria config.Criterias, gift *tg.Sta
 	}
 
 	if gift.Limited {
-		remains, hasRemains := gift.GetAvailabilityRemains()
-		if !hasRemains || remains <= 0 {
-			return false
+		if missingAvailability(gift) {
+			return gv.missingAvailabilityPolicy == config.MissingAvailabilityEligible
 		}
 
-		totalSupply, hasTotalSupply := gift.GetAvailabilityTotal()
-		if !hasTotalSupply {
+		remains, _ := gift.GetAvailabilityRemains()
+		if remains <= 0 {
 			return false
 		}
 
+		totalSupply, _ := gift.GetAvailabilityTotal()
+
 		if int64(totalSupply) <= criteria.TotalSupply {
 			return true
 		}
@@ -172,6 +504,146 @@ func (gv *giftValidatorImpl) starCapValidation(gift *tg.StarGift) bool {
 	return (price * int64(giftSupply)) <= gv.totalStarCap
 }
 
+// budgetValid checks if the purchase of the criteria fits into its star budget: the
+// total of all stages for staged criteria, and at least one unit for max-affordable
+// criteria, whose count the buyer caps by the budget at buy time.
+// It complements the global star cap, so the tighter of both limits applies.
+// In test mode, this validation is bypassed and always returns true.
+//
+// Parameters:
+//   - criteria: the criteria containing the star budget and purchase count
+//   - gift: the star gift to validate
+//
+// Returns:
+//   - bool: true if the criteria has no budget or the purchase fits into it
+func (gv *giftValidatorImpl) budgetValid(criteria config.Criterias, gift *tg.StarGift) bool {
+	if gv.testMode || criteria.StarBudget <= 0 {
+		return true
+	}
+
+	count := criteria.Count
+	if len(criteria.Stages) > 0 {
+		_, count = purchaseStages(criteria.Stages)
+	} else if count == config.CountMaxAffordable {
+		count = 1
+	}
+	return gift.GetStars()*count <= criteria.StarBudget
+}
+
+// remainsValid checks if the remaining supply of the gift is below the criteria threshold.
+// Criteria without a threshold accept any gift; gifts without remaining supply data
+// never match a threshold.
+//
+// Parameters:
+//   - criteria: the criteria containing the remains threshold
+//   - gift: the star gift to validate
+//
+// Returns:
+//   - bool: true if there is no threshold or the remaining supply is below it
+func (gv *giftValidatorImpl) remainsValid(criteria config.Criterias, gift *tg.StarGift) bool {
+	if criteria.RemainsBelow <= 0 {
+		return true
+	}
+
+	remains, ok := gift.GetAvailabilityRemains()
+	return ok && int64(remains) < criteria.RemainsBelow
+}
+
+// CrossedRemainsThreshold reports whether the remaining supply of a gift dropped below
+// the remains threshold of any criteria since it was cached, so that the gift must be
+// evaluated again even though it was already seen.
+//
+// Parameters:
+//   - cached: the gift as it was cached
+//   - fresh: the freshly fetched gift
+//
+// Returns:
+//   - bool: true if the fresh remaining supply crossed a criteria threshold
+func (gv *giftValidatorImpl) CrossedRemainsThreshold(cached, fresh *tg.StarGift) bool {
+	gv.mu.RLock()
+	defer gv.mu.RUnlock()
+
+	remains, ok := fresh.GetAvailabilityRemains()
+	if !ok {
+		return false
+	}
+	previous, hadRemains := cached.GetAvailabilityRemains()
+
+	for _, criteria := range gv.criteria {
+		if criteria.RemainsBelow <= 0 || int64(remains) >= criteria.RemainsBelow {
+			continue
+		}
+		if !hadRemains || int64(previous) >= criteria.RemainsBelow {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldRecheck reports whether a gift that is not eligible now should be validated
+// again on the next cycle instead of being marked as processed. This is the case for
+// limited gifts whose availability data hasn't arrived yet under the "recheck" policy.
+//
+// Parameters:
+//   - gift: the star gift to check
+//
+// Returns:
+//   - bool: true if the gift should be validated again on the next cycle
+func (gv *giftValidatorImpl) ShouldRecheck(gift *tg.StarGift) bool {
+	gv.mu.RLock()
+	defer gv.mu.RUnlock()
+
+	if gv.missingAvailabilityPolicy != config.MissingAvailabilityRecheck || gv.testMode {
+		return false
+	}
+	return gift.Limited && !gift.SoldOut && missingAvailability(gift)
+}
+
+// missingAvailability reports whether the gift lacks remaining or total availability data.
+func missingAvailability(gift *tg.StarGift) bool {
+	_, hasRemains := gift.GetAvailabilityRemains()
+	_, hasTotal := gift.GetAvailabilityTotal()
+	return !hasRemains || !hasTotal
+}
+
+// suspicious reports whether the gift matches one of the scam detection heuristics:
+//   - the convert price is zero while the purchase price is at least ZeroConvertMinPrice
+//   - the convert price is below MinConvertRatio of the purchase price
+//   - a limited gift reports more remaining units than its total supply
+//
+// Test mode doesn't bypass the scam detection.
+//
+// Parameters:
+//   - gift: the star gift to check
+//
+// Returns:
+//   - bool: true if scam detection is enabled and the gift looks suspicious
+func (gv *giftValidatorImpl) suspicious(gift *tg.StarGift) bool {
+	detection := gv.scamDetection
+	if !detection.Enabled {
+		return false
+	}
+
+	price := gift.GetStars()
+	convert := gift.GetConvertStars()
+	if convert <= 0 && price >= detection.ZeroConvertMinPrice {
+		return true
+	}
+	if detection.MinConvertRatio > 0 && float64(convert) < float64(price)*detection.MinConvertRatio {
+		return true
+	}
+
+	if gift.Limited {
+		remains, hasRemains := gift.GetAvailabilityRemains()
+		total, hasTotal := gift.GetAvailabilityTotal()
+		if hasRemains && hasTotal && remains > total {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (gv *giftValidatorImpl) releaseByValidation(gift *tg.StarGift) bool {
 	_, hasReleasedBy := gift.GetReleasedBy()
 
@@ -189,3 +661,29 @@ func (gv *giftValidatorImpl) premiumValidation(gift *tg.StarGift) bool {
 
 	return true
 }
+
+// perGiftCap returns how many units of one gift the criteria buys in total: the purchase
+// count, multiplied by the receiver types when every type gets the count. Broadcast
+// criteria buy one unit per receiver and are not capped.
+//
+// Parameters:
+//   - criteria: matched criteria
+//   - count: units bought per purchase, the criteria Count or the sum of its stages
+//
+// Returns:
+//   - int64: per-gift cap, 0 for no cap
+func perGiftCap(criteria config.Criterias, count int64) int64 {
+	if criteria.BroadcastToAllReceivers {
+		return 0
+	}
+	if criteria.BuyForAllReceiverTypes {
+		types := make(map[int]bool, len(criteria.ReceiverType))
+		for _, receiverType := range criteria.ReceiverType {
+			types[receiverType] = true
+		}
+		if len(types) > 1 {
+			count *= int64(len(types))
+		}
+	}
+	return count
+}
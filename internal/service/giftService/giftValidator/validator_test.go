@@ -2,10 +2,13 @@ package giftValidator
 
 import (
 	"gift-buyer/internal/config"
+	"gift-buyer/internal/service/giftService/giftTypes"
 	"testing"
+	"time"
 
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewGiftValidator(t *testing.T) {
@@ -200,3 +203,682 @@ func TestGiftValidator_StarCapValidation_TestMode(t *testing.T) {
 	// In test mode, star cap validation should always pass
 	assert.True(t, validator.starCapValidation(gift))
 }
+
+func TestGiftValidator_IsEligible_ActiveWindows(t *testing.T) {
+	criterias := []config.Criterias{
+		{
+			MinPrice: 100, MaxPrice: 1000, Count: 50, ReceiverType: []int{1},
+			ActiveWindows: []config.ActiveWindow{{Start: "12:00", End: "13:00"}},
+		},
+		{
+			MinPrice: 100, MaxPrice: 1000, Count: 1, ReceiverType: []int{0},
+		},
+	}
+	giftParam := config.GiftParam{TestMode: true, LimitedStatus: true}
+
+	validator := NewGiftValidator(criterias, giftParam)
+	gift := &tg.StarGift{ID: 1, Stars: 500, Limited: true}
+
+	// Inside the drop window the high-spend criteria matches first
+	validator.now = func() time.Time { return time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC) }
+	result, eligible := validator.IsEligible(gift)
+	assert.True(t, eligible)
+	assert.Equal(t, int64(50), result.CountForBuy)
+
+	// Outside the window only the always-active criteria is used
+	validator.now = func() time.Time { return time.Date(2025, 1, 1, 13, 0, 0, 0, time.UTC) }
+	result, eligible = validator.IsEligible(gift)
+	assert.True(t, eligible)
+	assert.Equal(t, int64(1), result.CountForBuy)
+}
+
+func TestGiftValidator_IsEligible_AllCriteriaInactive(t *testing.T) {
+	criterias := []config.Criterias{
+		{
+			MinPrice: 100, MaxPrice: 1000, Count: 5,
+			ActiveWindows: []config.ActiveWindow{{Start: "08:00", End: "09:00"}},
+		},
+	}
+	validator := NewGiftValidator(criterias, config.GiftParam{TestMode: true, LimitedStatus: true})
+	validator.now = func() time.Time { return time.Date(2025, 1, 1, 20, 0, 0, 0, time.UTC) }
+
+	result, eligible := validator.IsEligible(&tg.StarGift{ID: 1, Stars: 500, Limited: true})
+	assert.False(t, eligible)
+	assert.Nil(t, result)
+}
+
+func TestGiftValidator_CriteriaActive(t *testing.T) {
+	validator := NewGiftValidator([]config.Criterias{}, config.GiftParam{})
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	// No windows: always active
+	assert.True(t, validator.criteriaActive(config.Criterias{}, at(3, 0)))
+
+	daytime := config.Criterias{ActiveWindows: []config.ActiveWindow{{Start: "09:30", End: "18:00"}}}
+	assert.False(t, validator.criteriaActive(daytime, at(9, 29)))
+	assert.True(t, validator.criteriaActive(daytime, at(9, 30)))
+	assert.True(t, validator.criteriaActive(daytime, at(17, 59)))
+	assert.False(t, validator.criteriaActive(daytime, at(18, 0)))
+
+	// Window spanning midnight
+	night := config.Criterias{ActiveWindows: []config.ActiveWindow{{Start: "22:00", End: "02:00"}}}
+	assert.True(t, validator.criteriaActive(night, at(23, 0)))
+	assert.True(t, validator.criteriaActive(night, at(1, 59)))
+	assert.False(t, validator.criteriaActive(night, at(2, 0)))
+	assert.False(t, validator.criteriaActive(night, at(12, 0)))
+
+	// Several windows
+	multi := config.Criterias{ActiveWindows: []config.ActiveWindow{
+		{Start: "06:00", End: "07:00"},
+		{Start: "20:00", End: "21:00"},
+	}}
+	assert.True(t, validator.criteriaActive(multi, at(20, 15)))
+	assert.False(t, validator.criteriaActive(multi, at(8, 0)))
+
+	// Malformed window never matches
+	broken := config.Criterias{ActiveWindows: []config.ActiveWindow{{Start: "noon", End: "13:00"}}}
+	assert.False(t, validator.criteriaActive(broken, at(12, 30)))
+}
+
+func TestGiftValidator_IsEligible_CriteriaIndex(t *testing.T) {
+	criterias := []config.Criterias{
+		{MinPrice: 1000, MaxPrice: 2000, Count: 1},
+		{MinPrice: 100, MaxPrice: 1000, Count: 2},
+	}
+	validator := NewGiftValidator(criterias, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+	result, eligible := validator.IsEligible(&tg.StarGift{ID: 1, Stars: 500, Limited: true})
+	assert.True(t, eligible)
+	assert.Equal(t, 1, result.CriteriaIndex)
+}
+
+func TestGiftValidator_MissingAvailabilityPolicy(t *testing.T) {
+	criterias := []config.Criterias{
+		{MinPrice: 100, MaxPrice: 1000, TotalSupply: 5000, Count: 1},
+	}
+	newValidator := func(policy string) *giftValidatorImpl {
+		return NewGiftValidator(criterias, config.GiftParam{
+			TotalStarCap:              1000000000,
+			LimitedStatus:             true,
+			MissingAvailabilityPolicy: policy,
+		})
+	}
+	// Limited gift whose availability data hasn't arrived yet
+	noAvailability := func() *tg.StarGift {
+		return &tg.StarGift{ID: 1, Stars: 500, Limited: true}
+	}
+	withAvailability := func() *tg.StarGift {
+		gift := &tg.StarGift{ID: 2, Stars: 500, Limited: true}
+		gift.SetAvailabilityRemains(10)
+		gift.SetAvailabilityTotal(1000)
+		return gift
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		for _, policy := range []string{"", config.MissingAvailabilitySkip} {
+			validator := newValidator(policy)
+
+			_, eligible := validator.IsEligible(noAvailability())
+			assert.False(t, eligible)
+			assert.False(t, validator.ShouldRecheck(noAvailability()))
+
+			_, eligible = validator.IsEligible(withAvailability())
+			assert.True(t, eligible)
+		}
+	})
+
+	t.Run("eligible", func(t *testing.T) {
+		validator := newValidator(config.MissingAvailabilityEligible)
+
+		result, eligible := validator.IsEligible(noAvailability())
+		assert.True(t, eligible)
+		assert.Equal(t, int64(1), result.CountForBuy)
+		assert.False(t, validator.ShouldRecheck(noAvailability()))
+	})
+
+	t.Run("recheck", func(t *testing.T) {
+		validator := newValidator(config.MissingAvailabilityRecheck)
+
+		_, eligible := validator.IsEligible(noAvailability())
+		assert.False(t, eligible)
+		assert.True(t, validator.ShouldRecheck(noAvailability()))
+
+		// Gifts with availability data and sold out gifts are never rechecked
+		assert.False(t, validator.ShouldRecheck(withAvailability()))
+		soldOut := noAvailability()
+		soldOut.SoldOut = true
+		assert.False(t, validator.ShouldRecheck(soldOut))
+	})
+}
+
+func TestGiftValidator_Update(t *testing.T) {
+	validator := NewGiftValidator(
+		[]config.Criterias{{MinPrice: 100, MaxPrice: 1000, Count: 1}},
+		config.GiftParam{TestMode: true},
+	)
+	gift := &tg.StarGift{ID: 1, Stars: 2000}
+
+	_, ok := validator.IsEligible(gift)
+	assert.False(t, ok)
+
+	validator.Update(
+		[]config.Criterias{{MinPrice: 1000, MaxPrice: 5000, Count: 3}},
+		config.GiftParam{TestMode: true, TotalStarCap: 42},
+	)
+
+	require, ok := validator.IsEligible(gift)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), require.CountForBuy)
+	assert.Equal(t, int64(42), validator.totalStarCap)
+}
+
+func TestGiftValidator_IsEligible_StickerIDs(t *testing.T) {
+	criterias := []config.Criterias{
+		{MinPrice: 100, MaxPrice: 1000, Count: 1, StickerIDs: []int64{111, 222}},
+		{MinPrice: 100, MaxPrice: 1000, Count: 2},
+	}
+	validator := NewGiftValidator(criterias, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+	newGift := func(sticker tg.DocumentClass) *tg.StarGift {
+		return &tg.StarGift{ID: 1, Stars: 500, Limited: true, Sticker: sticker}
+	}
+
+	t.Run("совпадающий стикер", func(t *testing.T) {
+		result, eligible := validator.IsEligible(newGift(&tg.Document{ID: 222}))
+		assert.True(t, eligible)
+		assert.Equal(t, 0, result.CriteriaIndex)
+	})
+
+	t.Run("несовпадающий стикер проверяется следующим критерием", func(t *testing.T) {
+		result, eligible := validator.IsEligible(newGift(&tg.Document{ID: 333}))
+		assert.True(t, eligible)
+		assert.Equal(t, 1, result.CriteriaIndex)
+	})
+
+	t.Run("пустой документ не совпадает", func(t *testing.T) {
+		result, eligible := validator.IsEligible(newGift(&tg.DocumentEmpty{ID: 111}))
+		assert.True(t, eligible)
+		assert.Equal(t, 1, result.CriteriaIndex)
+	})
+
+	t.Run("без подходящих критериев подарок отклоняется", func(t *testing.T) {
+		only := NewGiftValidator(criterias[:1], config.GiftParam{TestMode: true, LimitedStatus: true})
+
+		_, eligible := only.IsEligible(newGift(&tg.Document{ID: 333}))
+		assert.False(t, eligible)
+
+		_, eligible = only.IsEligible(newGift(&tg.Document{ID: 111}))
+		assert.True(t, eligible)
+	})
+}
+
+func TestGiftValidator_IsEligible_AttributeRarity(t *testing.T) {
+	gift := &tg.StarGift{ID: 1, Stars: 500, Limited: true}
+
+	t.Run("без диапазона редкости подарок проходит", func(t *testing.T) {
+		validator := NewGiftValidator([]config.Criterias{{MinPrice: 100, MaxPrice: 1000, Count: 1}}, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+		_, eligible := validator.IsEligible(gift)
+		assert.True(t, eligible)
+	})
+
+	t.Run("подарок без атрибутов не проходит при заданном диапазоне", func(t *testing.T) {
+		criterias := []config.Criterias{
+			{MinPrice: 100, MaxPrice: 1000, Count: 1, MaxRarityPermille: 20},
+			{MinPrice: 100, MaxPrice: 1000, Count: 2},
+		}
+		validator := NewGiftValidator(criterias, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+		result, eligible := validator.IsEligible(gift)
+		assert.True(t, eligible)
+		assert.Equal(t, 1, result.CriteriaIndex)
+
+		only := NewGiftValidator(criterias[:1], config.GiftParam{TestMode: true, LimitedStatus: true})
+		_, eligible = only.IsEligible(gift)
+		assert.False(t, eligible)
+	})
+}
+
+func TestGiftValidator_IsEligible_TitleLists(t *testing.T) {
+	newGift := func(title string) *tg.StarGift {
+		gift := &tg.StarGift{ID: 1, Stars: 500, Limited: true}
+		if title != "" {
+			gift.SetTitle(title)
+		}
+		return gift
+	}
+	newValidator := func(criteria config.Criterias) *giftValidatorImpl {
+		criteria.MinPrice, criteria.MaxPrice, criteria.Count = 100, 1000, 1
+		return NewGiftValidator([]config.Criterias{criteria}, config.GiftParam{TestMode: true, LimitedStatus: true})
+	}
+
+	tests := []struct {
+		name     string
+		criteria config.Criterias
+		title    string
+		expected bool
+	}{
+		{name: "без списков", title: "Plush Pepe", expected: true},
+		{name: "совпадение с allowlist без учета регистра", criteria: config.Criterias{TitleAllowlist: []string{"pepe"}}, title: "Plush Pepe", expected: true},
+		{name: "нет совпадения с allowlist", criteria: config.Criterias{TitleAllowlist: []string{"heart"}}, title: "Plush Pepe", expected: false},
+		{name: "совпадение с blocklist", criteria: config.Criterias{TitleBlocklist: []string{"PLUSH"}}, title: "Plush Pepe", expected: false},
+		{name: "blocklist важнее allowlist", criteria: config.Criterias{TitleAllowlist: []string{"pepe"}, TitleBlocklist: []string{"plush"}}, title: "Plush Pepe", expected: false},
+		{name: "без названия не проходит allowlist", criteria: config.Criterias{TitleAllowlist: []string{"pepe"}}, expected: false},
+		{name: "без названия проходит blocklist", criteria: config.Criterias{TitleBlocklist: []string{"pepe"}}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, eligible := newValidator(tt.criteria).IsEligible(newGift(tt.title))
+			assert.Equal(t, tt.expected, eligible)
+		})
+	}
+}
+
+func TestGiftValidator_IsEligible_NotifyBuyFlags(t *testing.T) {
+	criterias := []config.Criterias{
+		{MinPrice: 100, MaxPrice: 200, Count: 1, Notify: true, Buy: false},
+		{MinPrice: 201, MaxPrice: 300, Count: 1, Notify: false, Buy: true},
+	}
+	validator := NewGiftValidator(criterias, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+	result, eligible := validator.IsEligible(&tg.StarGift{ID: 1, Stars: 150, Limited: true})
+	assert.True(t, eligible)
+	assert.False(t, result.SkipNotify)
+	assert.True(t, result.SkipBuy)
+
+	result, eligible = validator.IsEligible(&tg.StarGift{ID: 2, Stars: 250, Limited: true})
+	assert.True(t, eligible)
+	assert.True(t, result.SkipNotify)
+	assert.False(t, result.SkipBuy)
+}
+
+func TestGiftValidator_IsEligible_Upgrade(t *testing.T) {
+	criterias := []config.Criterias{
+		{MinPrice: 100, MaxPrice: 200, Count: 1, Upgrade: true},
+		{MinPrice: 201, MaxPrice: 300, Count: 1},
+	}
+	validator := NewGiftValidator(criterias, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+	result, eligible := validator.IsEligible(&tg.StarGift{ID: 1, Stars: 150, Limited: true})
+	assert.True(t, eligible)
+	assert.True(t, result.Upgrade)
+
+	result, eligible = validator.IsEligible(&tg.StarGift{ID: 2, Stars: 250, Limited: true})
+	assert.True(t, eligible)
+	assert.False(t, result.Upgrade)
+}
+
+func TestGiftValidator_IsEligible_ReceiverDistribution(t *testing.T) {
+	criterias := []config.Criterias{{
+		MinPrice: 100, MaxPrice: 1000, Count: 4,
+		ReceiverDistribution: []config.DistributionParams{{Username: "alice", Count: 3}, {Username: "bob", Count: 1}},
+	}}
+	validator := NewGiftValidator(criterias, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+	result, eligible := validator.IsEligible(&tg.StarGift{ID: 1, Stars: 500, Limited: true})
+	assert.True(t, eligible)
+	assert.Equal(t, []giftTypes.ReceiverShare{{Receiver: "alice", Weight: 3}, {Receiver: "bob", Weight: 1}}, result.ReceiverDistribution)
+}
+
+func TestRarityInRange(t *testing.T) {
+	attributes := []tg.StarGiftAttributeClass{
+		&tg.StarGiftAttributeModel{Name: "model", RarityPermille: 150},
+		&tg.StarGiftAttributeBackdrop{Name: "backdrop", RarityPermille: 40},
+		&tg.StarGiftAttributePattern{Name: "pattern", RarityPermille: 5},
+	}
+
+	tests := []struct {
+		name     string
+		min, max int
+		expected bool
+	}{
+		{name: "редкий узор", max: 10, expected: true},
+		{name: "диапазон фона", min: 30, max: 50, expected: true},
+		{name: "только нижняя граница", min: 100, expected: true},
+		{name: "нет атрибута в диапазоне", min: 10, max: 30, expected: false},
+		{name: "слишком высокая нижняя граница", min: 200, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			criteria := config.Criterias{MinRarityPermille: tt.min, MaxRarityPermille: tt.max}
+			assert.Equal(t, tt.expected, rarityInRange(criteria, attributes))
+		})
+	}
+
+	assert.False(t, rarityInRange(config.Criterias{MaxRarityPermille: 10}, nil))
+}
+
+func TestGiftValidator_ScamDetection(t *testing.T) {
+	criterias := []config.Criterias{
+		{MinPrice: 1, MaxPrice: 100000, TotalSupply: 100000, Count: 1},
+	}
+	newValidator := func(detection config.ScamDetection) *giftValidatorImpl {
+		return NewGiftValidator(criterias, config.GiftParam{
+			TotalStarCap:  1000000000,
+			LimitedStatus: true,
+			ScamDetection: detection,
+		})
+	}
+	newGift := func(stars, convert int64, remains, total int) *tg.StarGift {
+		gift := &tg.StarGift{ID: 1, Stars: stars, ConvertStars: convert, Limited: true}
+		gift.SetAvailabilityRemains(remains)
+		gift.SetAvailabilityTotal(total)
+		return gift
+	}
+
+	enabled := config.ScamDetection{Enabled: true, ZeroConvertMinPrice: 100, MinConvertRatio: 0.5}
+
+	tests := []struct {
+		name     string
+		gift     *tg.StarGift
+		eligible bool
+	}{
+		{"обычный подарок проходит", newGift(500, 400, 100, 1000), true},
+		{"нулевая конвертация при высокой цене", newGift(5000, 0, 100, 1000), false},
+		{"дешевый неконвертируемый подарок отклоняется по соотношению", newGift(50, 0, 100, 1000), false},
+		{"конвертация ниже порога", newGift(1000, 100, 100, 1000), false},
+		{"осталось больше, чем выпущено", newGift(500, 400, 2000, 1000), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, eligible := newValidator(enabled).IsEligible(tt.gift)
+			assert.Equal(t, tt.eligible, eligible)
+		})
+	}
+
+	t.Run("дешевый неконвертируемый подарок без проверки соотношения", func(t *testing.T) {
+		validator := newValidator(config.ScamDetection{Enabled: true, ZeroConvertMinPrice: 100})
+
+		_, eligible := validator.IsEligible(newGift(50, 0, 100, 1000))
+		assert.True(t, eligible)
+	})
+
+	t.Run("выключенная проверка пропускает подозрительные подарки", func(t *testing.T) {
+		validator := newValidator(config.ScamDetection{})
+
+		_, eligible := validator.IsEligible(newGift(5000, 0, 100, 1000))
+		assert.True(t, eligible)
+	})
+
+	t.Run("тестовый режим не отключает проверку", func(t *testing.T) {
+		validator := NewGiftValidator(criterias, config.GiftParam{TestMode: true, LimitedStatus: true, ScamDetection: enabled})
+
+		_, eligible := validator.IsEligible(newGift(5000, 0, 100, 1000))
+		assert.False(t, eligible)
+	})
+}
+
+func TestGiftValidator_IsEligible_StarBudget(t *testing.T) {
+	newGift := func(stars int64) *tg.StarGift {
+		gift := &tg.StarGift{ID: 1, Stars: stars, Limited: true}
+		gift.SetAvailabilityRemains(10)
+		gift.SetAvailabilityTotal(100)
+		return gift
+	}
+	giftParam := config.GiftParam{TotalStarCap: 1000000, LimitedStatus: true}
+
+	t.Run("проходит глобальный лимит, но превышает бюджет критерия", func(t *testing.T) {
+		validator := NewGiftValidator([]config.Criterias{
+			{MinPrice: 1, MaxPrice: 1000, TotalSupply: 1000, Count: 5, StarBudget: 2000},
+		}, giftParam)
+
+		// 500 * 100 supply = 50000 fits the global cap, 500 * 5 = 2500 exceeds the budget
+		_, eligible := validator.IsEligible(newGift(500))
+		assert.False(t, eligible)
+
+		_, eligible = validator.IsEligible(newGift(400))
+		assert.True(t, eligible)
+	})
+
+	t.Run("бюджет в пределах, но превышен глобальный лимит", func(t *testing.T) {
+		validator := NewGiftValidator([]config.Criterias{
+			{MinPrice: 1, MaxPrice: 1000, TotalSupply: 1000, Count: 1, StarBudget: 1000000},
+		}, config.GiftParam{TotalStarCap: 10000, LimitedStatus: true})
+
+		_, eligible := validator.IsEligible(newGift(500))
+		assert.False(t, eligible)
+	})
+
+	t.Run("превышение бюджета переходит к следующему критерию", func(t *testing.T) {
+		validator := NewGiftValidator([]config.Criterias{
+			{MinPrice: 1, MaxPrice: 1000, TotalSupply: 1000, Count: 5, StarBudget: 2000},
+			{MinPrice: 1, MaxPrice: 1000, TotalSupply: 1000, Count: 2, StarBudget: 2000},
+		}, giftParam)
+
+		result, eligible := validator.IsEligible(newGift(500))
+		assert.True(t, eligible)
+		assert.Equal(t, 1, result.CriteriaIndex)
+	})
+
+	t.Run("без бюджета ограничивает только глобальный лимит", func(t *testing.T) {
+		validator := NewGiftValidator([]config.Criterias{
+			{MinPrice: 1, MaxPrice: 1000, TotalSupply: 1000, Count: 100},
+		}, giftParam)
+
+		_, eligible := validator.IsEligible(newGift(500))
+		assert.True(t, eligible)
+	})
+
+	t.Run("бюджет этапов считается по их сумме", func(t *testing.T) {
+		validator := NewGiftValidator([]config.Criterias{
+			{MinPrice: 1, MaxPrice: 1000, TotalSupply: 1000, StarBudget: 2000, Stages: []config.StageParams{
+				{Count: 1},
+				{Count: 4, Condition: config.StageConditionAlways},
+			}},
+		}, giftParam)
+
+		// 500 * 5 staged units = 2500 exceeds the budget
+		_, eligible := validator.IsEligible(newGift(500))
+		assert.False(t, eligible)
+
+		_, eligible = validator.IsEligible(newGift(400))
+		assert.True(t, eligible)
+	})
+
+	t.Run("максимум по балансу требует бюджета хотя бы на один подарок", func(t *testing.T) {
+		validator := NewGiftValidator([]config.Criterias{
+			{MinPrice: 1, MaxPrice: 1000, TotalSupply: 1000, Count: config.CountMaxAffordable, StarBudget: 500},
+		}, giftParam)
+
+		_, eligible := validator.IsEligible(newGift(600))
+		assert.False(t, eligible)
+
+		result, eligible := validator.IsEligible(newGift(400))
+		require.True(t, eligible)
+		assert.True(t, result.BuyMaxAffordable)
+		assert.Equal(t, int64(500), result.StarBudget)
+	})
+
+	t.Run("тестовый режим пропускает проверку бюджета", func(t *testing.T) {
+		validator := NewGiftValidator([]config.Criterias{
+			{MinPrice: 1, MaxPrice: 1000, TotalSupply: 1000, Count: 5, StarBudget: 10},
+		}, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+		_, eligible := validator.IsEligible(newGift(500))
+		assert.True(t, eligible)
+	})
+}
+
+func TestGiftValidator_IsEligible_Stages(t *testing.T) {
+	validator := NewGiftValidator([]config.Criterias{
+		{MinPrice: 100, MaxPrice: 1000, Count: 50, Stages: []config.StageParams{
+			{Count: 1},
+			{Count: 4, Condition: config.StageConditionPreviousSucceeded},
+			{Count: 10, Condition: config.StageConditionAlways},
+			{Count: 5},
+		}},
+	}, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+	result, eligible := validator.IsEligible(&tg.StarGift{ID: 1, Stars: 500, Limited: true})
+
+	assert.True(t, eligible)
+	assert.Equal(t, int64(20), result.CountForBuy)
+	assert.Equal(t, []giftTypes.PurchaseStage{
+		{Count: 1, RequirePreviousSuccess: true},
+		{Count: 4, RequirePreviousSuccess: true},
+		{Count: 10, RequirePreviousSuccess: false},
+		{Count: 5, RequirePreviousSuccess: true},
+	}, result.Stages)
+}
+
+func TestGiftValidator_IsEligible_MaxAffordable(t *testing.T) {
+	validator := NewGiftValidator([]config.Criterias{
+		{MinPrice: 100, MaxPrice: 1000, Count: config.CountMaxAffordable},
+	}, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+	result, eligible := validator.IsEligible(&tg.StarGift{ID: 1, Stars: 500, Limited: true})
+
+	assert.True(t, eligible)
+	assert.True(t, result.BuyMaxAffordable)
+	// The count is set by the buyer from the balance
+	assert.Equal(t, int64(0), result.CountForBuy)
+	assert.Equal(t, int64(0), result.MaxPerGift)
+}
+
+func TestGiftValidator_IsEligible_MaxPerGift(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria config.Criterias
+		expected int64
+	}{
+		{name: "количество критерия", criteria: config.Criterias{Count: 3, ReceiverType: []int{1, 2}}, expected: 3},
+		{name: "для каждого типа получателя", criteria: config.Criterias{Count: 3, ReceiverType: []int{1, 2, 2}, BuyForAllReceiverTypes: true}, expected: 6},
+		{name: "сумма этапов", criteria: config.Criterias{Count: 50, Stages: []config.StageParams{{Count: 1}, {Count: 4}}}, expected: 5},
+		{name: "всем получателям без ограничения", criteria: config.Criterias{Count: 3, BroadcastToAllReceivers: true}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.criteria.MinPrice, tt.criteria.MaxPrice = 100, 1000
+			validator := NewGiftValidator([]config.Criterias{tt.criteria}, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+			result, eligible := validator.IsEligible(&tg.StarGift{ID: 1, Stars: 500, Limited: true})
+
+			require.True(t, eligible)
+			assert.Equal(t, tt.expected, result.MaxPerGift)
+		})
+	}
+}
+
+func TestGiftValidator_RemainsBelow(t *testing.T) {
+	newGift := func(remains int) *tg.StarGift {
+		gift := &tg.StarGift{ID: 1, Stars: 500, Limited: true}
+		gift.SetAvailabilityRemains(remains)
+		gift.SetAvailabilityTotal(100)
+		return gift
+	}
+	validator := NewGiftValidator([]config.Criterias{
+		{MinPrice: 1, MaxPrice: 1000, TotalSupply: 1000, Count: 1, RemainsBelow: 20},
+	}, config.GiftParam{TotalStarCap: 1000000, LimitedStatus: true})
+
+	t.Run("остаток выше порога", func(t *testing.T) {
+		_, eligible := validator.IsEligible(newGift(50))
+		assert.False(t, eligible)
+	})
+
+	t.Run("остаток ниже порога", func(t *testing.T) {
+		_, eligible := validator.IsEligible(newGift(19))
+		assert.True(t, eligible)
+	})
+
+	t.Run("остаток пересек порог", func(t *testing.T) {
+		assert.True(t, validator.CrossedRemainsThreshold(newGift(50), newGift(15)))
+		assert.True(t, validator.CrossedRemainsThreshold(&tg.StarGift{ID: 1, Limited: true}, newGift(15)))
+	})
+
+	t.Run("остаток уже был ниже порога", func(t *testing.T) {
+		assert.False(t, validator.CrossedRemainsThreshold(newGift(18), newGift(15)))
+	})
+
+	t.Run("остаток уменьшился, но выше порога", func(t *testing.T) {
+		assert.False(t, validator.CrossedRemainsThreshold(newGift(50), newGift(30)))
+	})
+
+	t.Run("критерии без порога", func(t *testing.T) {
+		validator := NewGiftValidator([]config.Criterias{
+			{MinPrice: 1, MaxPrice: 1000, TotalSupply: 1000, Count: 1},
+		}, config.GiftParam{TotalStarCap: 1000000, LimitedStatus: true})
+
+		assert.False(t, validator.CrossedRemainsThreshold(newGift(50), newGift(1)))
+		_, eligible := validator.IsEligible(newGift(50))
+		assert.True(t, eligible)
+	})
+}
+
+func TestGiftValidator_IsEligible_ReleaseWindow(t *testing.T) {
+	newValidator := func(buyAfter, buyBefore string) *giftValidatorImpl {
+		return NewGiftValidator([]config.Criterias{
+			{MinPrice: 100, MaxPrice: 1000, Count: 1, BuyAfter: buyAfter, BuyBefore: buyBefore},
+		}, config.GiftParam{TestMode: true, LimitedStatus: true})
+	}
+	at := func(hour int) func() time.Time {
+		return func() time.Time { return time.Date(2025, 7, 1, hour, 0, 0, 0, time.UTC) }
+	}
+	gift := &tg.StarGift{ID: 1, Stars: 500, Limited: true}
+
+	t.Run("до открытия окна", func(t *testing.T) {
+		validator := newValidator("2025-07-01T12:00:00Z", "2025-07-01T18:00:00Z")
+		validator.now = at(11)
+
+		result, eligible := validator.IsEligible(gift)
+		assert.False(t, eligible)
+		assert.Nil(t, result)
+	})
+
+	t.Run("внутри окна", func(t *testing.T) {
+		validator := newValidator("2025-07-01T12:00:00Z", "2025-07-01T18:00:00Z")
+
+		validator.now = at(12)
+		_, eligible := validator.IsEligible(gift)
+		assert.True(t, eligible)
+
+		validator.now = at(17)
+		_, eligible = validator.IsEligible(gift)
+		assert.True(t, eligible)
+	})
+
+	t.Run("после закрытия окна", func(t *testing.T) {
+		validator := newValidator("2025-07-01T12:00:00Z", "2025-07-01T18:00:00Z")
+		validator.now = at(18)
+
+		_, eligible := validator.IsEligible(gift)
+		assert.False(t, eligible)
+	})
+
+	t.Run("часовой пояс в границе", func(t *testing.T) {
+		validator := newValidator("2025-07-01T15:00:00+03:00", "")
+		validator.now = at(12)
+
+		_, eligible := validator.IsEligible(gift)
+		assert.True(t, eligible)
+	})
+
+	t.Run("неверная граница игнорируется", func(t *testing.T) {
+		validator := newValidator("tomorrow", "2025-07-01T18:00:00Z")
+		assert.True(t, validator.releaseWindows[0].after.IsZero())
+
+		validator.now = at(1)
+		_, eligible := validator.IsEligible(gift)
+		assert.True(t, eligible)
+
+		validator.now = at(19)
+		_, eligible = validator.IsEligible(gift)
+		assert.False(t, eligible)
+	})
+
+	t.Run("окно обновляется при перезагрузке", func(t *testing.T) {
+		validator := newValidator("", "")
+		validator.now = at(11)
+		validator.Update([]config.Criterias{
+			{MinPrice: 100, MaxPrice: 1000, Count: 1, BuyAfter: "2025-07-01T12:00:00Z"},
+		}, config.GiftParam{TestMode: true, LimitedStatus: true})
+
+		_, eligible := validator.IsEligible(gift)
+		assert.False(t, eligible)
+	})
+}
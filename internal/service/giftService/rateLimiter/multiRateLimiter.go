@@ -0,0 +1,115 @@
+package rateLimiter
+
+import (
+	"context"
+	"sync"
+)
+
+// Rate limit buckets of the Telegram methods that are limited separately
+const (
+	// BucketDefault limits calls not tied to a method bucket
+	BucketDefault = "default"
+
+	// BucketPaymentForm limits payments.getPaymentForm
+	BucketPaymentForm = "payment_form"
+
+	// BucketSendPayment limits payments.sendStarsForm
+	BucketSendPayment = "send_payment"
+
+	// BucketGetGifts limits payments.getStarGifts
+	BucketGetGifts = "get_gifts"
+)
+
+// Limiter is a single rate limit bucket.
+type Limiter interface {
+	Acquire(ctx context.Context) error
+	Close()
+}
+
+// MultiRateLimiter holds a token bucket per Telegram method, so that methods Telegram
+// limits differently don't throttle each other. Buckets are created on first use.
+type MultiRateLimiter struct {
+	// defaultRPS is the rate of buckets without a configured rate
+	defaultRPS int
+
+	// limits are the configured requests per second of the named buckets
+	limits map[string]int
+
+	buckets map[string]Limiter
+	mu      sync.Mutex
+	closed  bool
+}
+
+// NewMultiRateLimiter creates a rate limiter with a bucket per method.
+//
+// Parameters:
+//   - defaultRPS: requests per second of buckets missing from limits
+//   - limits: requests per second of the named buckets, 0 or less means unlimited
+//
+// Returns:
+//   - *MultiRateLimiter: rate limiter creating its buckets on first use
+func NewMultiRateLimiter(defaultRPS int, limits map[string]int) *MultiRateLimiter {
+	return &MultiRateLimiter{
+		defaultRPS: defaultRPS,
+		limits:     limits,
+		buckets:    make(map[string]Limiter),
+	}
+}
+
+// Bucket returns the rate limiter of the named bucket. Every call with the same name
+// returns the same bucket.
+//
+// Parameters:
+//   - name: one of the Bucket* names
+//
+// Returns:
+//   - Limiter: the bucket, unlimited when its rate is 0 or less
+func (m *MultiRateLimiter) Bucket(name string) Limiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if bucket, ok := m.buckets[name]; ok {
+		return bucket
+	}
+
+	rps, ok := m.limits[name]
+	if !ok {
+		rps = m.defaultRPS
+	}
+
+	var bucket Limiter = unlimited{}
+	if rps > 0 {
+		limiter := NewRateLimiter(rps)
+		if m.closed {
+			limiter.Close()
+		}
+		bucket = limiter
+	}
+	m.buckets[name] = bucket
+	return bucket
+}
+
+// Acquire acquires a token from the default bucket.
+func (m *MultiRateLimiter) Acquire(ctx context.Context) error {
+	return m.Bucket(BucketDefault).Acquire(ctx)
+}
+
+// Close stops every bucket.
+func (m *MultiRateLimiter) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	for _, bucket := range m.buckets {
+		bucket.Close()
+	}
+}
+
+// unlimited is a bucket that never waits
+type unlimited struct{}
+
+func (unlimited) Acquire(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (unlimited) Close() {}
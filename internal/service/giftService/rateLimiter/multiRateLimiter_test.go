@@ -0,0 +1,95 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exhausted reports whether the bucket has no token available within a short wait
+func exhausted(bucket Limiter) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	return bucket.Acquire(ctx) != nil
+}
+
+func TestMultiRateLimiter(t *testing.T) {
+	t.Run("каждый метод ограничивается независимо", func(t *testing.T) {
+		limiter := NewMultiRateLimiter(1, map[string]int{BucketPaymentForm: 2, BucketSendPayment: 1})
+		defer limiter.Close()
+
+		paymentForm := limiter.Bucket(BucketPaymentForm)
+		sendPayment := limiter.Bucket(BucketSendPayment)
+		getGifts := limiter.Bucket(BucketGetGifts)
+
+		for i := 0; i < 2; i++ {
+			require.NoError(t, paymentForm.Acquire(context.Background()))
+		}
+		assert.True(t, exhausted(paymentForm))
+
+		// Other buckets still have their own tokens
+		require.NoError(t, sendPayment.Acquire(context.Background()))
+		assert.True(t, exhausted(sendPayment))
+		require.NoError(t, getGifts.Acquire(context.Background()))
+		assert.True(t, exhausted(getGifts))
+	})
+
+	t.Run("одно имя возвращает один и тот же лимит", func(t *testing.T) {
+		limiter := NewMultiRateLimiter(5, nil)
+		defer limiter.Close()
+
+		assert.Same(t, limiter.Bucket(BucketGetGifts), limiter.Bucket(BucketGetGifts))
+		assert.NotSame(t, limiter.Bucket(BucketGetGifts), limiter.Bucket(BucketPaymentForm))
+	})
+
+	t.Run("ненастроенный метод использует общий лимит", func(t *testing.T) {
+		limiter := NewMultiRateLimiter(3, map[string]int{BucketPaymentForm: 10})
+		defer limiter.Close()
+
+		bucket, ok := limiter.Bucket(BucketSendPayment).(*rateLimiterImpl)
+		require.True(t, ok)
+		assert.Equal(t, 3, bucket.maxTokens)
+
+		bucket, ok = limiter.Bucket(BucketPaymentForm).(*rateLimiterImpl)
+		require.True(t, ok)
+		assert.Equal(t, 10, bucket.maxTokens)
+	})
+
+	t.Run("нулевой лимит не ограничивает", func(t *testing.T) {
+		limiter := NewMultiRateLimiter(0, map[string]int{BucketPaymentForm: 0})
+		defer limiter.Close()
+
+		for i := 0; i < 100; i++ {
+			require.NoError(t, limiter.Bucket(BucketPaymentForm).Acquire(context.Background()))
+			require.NoError(t, limiter.Acquire(context.Background()))
+		}
+
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, limiter.Bucket(BucketGetGifts).Acquire(cancelled), context.Canceled)
+	})
+
+	t.Run("Acquire использует общий лимит", func(t *testing.T) {
+		limiter := NewMultiRateLimiter(1, map[string]int{BucketDefault: 1})
+		defer limiter.Close()
+
+		require.NoError(t, limiter.Acquire(context.Background()))
+		assert.True(t, exhausted(limiter.Bucket(BucketDefault)))
+	})
+
+	t.Run("Close останавливает все лимиты", func(t *testing.T) {
+		limiter := NewMultiRateLimiter(5, nil)
+		paymentForm := limiter.Bucket(BucketPaymentForm).(*rateLimiterImpl)
+		getGifts := limiter.Bucket(BucketGetGifts).(*rateLimiterImpl)
+
+		limiter.Close()
+		late := limiter.Bucket(BucketSendPayment).(*rateLimiterImpl)
+
+		assert.True(t, paymentForm.closed)
+		assert.True(t, getGifts.closed)
+		assert.True(t, late.closed)
+	})
+}
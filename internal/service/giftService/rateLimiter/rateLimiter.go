@@ -2,10 +2,24 @@ package rateLimiter
 
 import (
 	"context"
+	"regexp"
+	"strconv"
 	"sync"
 	"time"
 )
 
+const (
+	// penaltyDivisor is how many times the refill rate is lowered while penalized
+	penaltyDivisor = 4
+
+	// maxRefillInterval bounds the refill interval of a penalized limiter
+	maxRefillInterval = 10 * time.Second
+)
+
+// floodWaitPattern matches the wait seconds of a FLOOD_WAIT error, e.g.
+// "rpc error code 420: FLOOD_WAIT (12)" or "FLOOD_WAIT_12"
+var floodWaitPattern = regexp.MustCompile(`FLOOD_WAIT[_ ]\(?(\d+)\)?`)
+
 // rateLimiter implements a token bucket rate limiter for API calls
 type rateLimiterImpl struct {
 	tokens    chan struct{}
@@ -13,6 +27,17 @@ type rateLimiterImpl struct {
 	maxTokens int
 	mu        sync.Mutex
 	closed    bool
+
+	// interval is the current refill interval, longer than the base one while penalized
+	interval time.Duration
+
+	// penaltyUntil is the end of the penalty window, the rate then recovers linearly
+	// over the penalty duration; zero when not penalized
+	penaltyUntil time.Time
+	penalty      time.Duration
+
+	// now returns the current time, replaceable in tests
+	now func() time.Time
 }
 
 // newRateLimiter creates a new rate limiter with specified rate (requests per second)
@@ -28,6 +53,10 @@ func NewRateLimiter(rps int) *rateLimiterImpl {
 		tokens:    make(chan struct{}, rps),
 		ticker:    ticker,
 		maxTokens: rps,
+		now:       time.Now,
+	}
+	if rps > 0 {
+		rl.interval = time.Second / time.Duration(rps)
 	}
 
 	// Заполняем канал начальными токенами
@@ -64,10 +93,106 @@ func (rl *rateLimiterImpl) refillTokens() {
 		default:
 			// Bucket is full, skip
 		}
+		rl.adjustRate()
 		rl.mu.Unlock()
 	}
 }
 
+// Penalize lowers the refill rate after Telegram answered with FLOOD_WAIT. The tokens
+// left in the bucket are dropped and the rate is divided by penaltyDivisor for the wait
+// duration, then it recovers linearly to the configured rate over the same duration.
+// A new penalty extends the current one. Limiters without a rate ignore penalties.
+//
+// Parameters:
+//   - wait: wait duration requested by Telegram
+func (rl *rateLimiterImpl) Penalize(wait time.Duration) {
+	if rl.maxTokens <= 0 || wait <= 0 {
+		return
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.closed {
+		return
+	}
+
+	for drained := false; !drained; {
+		select {
+		case <-rl.tokens:
+		default:
+			drained = true
+		}
+	}
+
+	if until := rl.now().Add(wait); until.After(rl.penaltyUntil) {
+		rl.penaltyUntil = until
+		rl.penalty = wait
+	}
+	rl.adjustRate()
+}
+
+// adjustRate updates the refill interval to the current point of the penalty and its
+// recovery. Must be called with mu held.
+func (rl *rateLimiterImpl) adjustRate() {
+	interval := rl.currentInterval()
+	if interval != rl.interval {
+		rl.interval = interval
+		rl.ticker.Reset(interval)
+	}
+}
+
+// currentInterval returns the refill interval: the base interval multiplied by
+// penaltyDivisor during the penalty, decreasing linearly back to the base interval
+// while recovering.
+func (rl *rateLimiterImpl) currentInterval() time.Duration {
+	base := time.Second / time.Duration(rl.maxTokens)
+	if rl.penaltyUntil.IsZero() {
+		return base
+	}
+
+	now := rl.now()
+	baseRate := float64(rl.maxTokens)
+	rate := baseRate / penaltyDivisor
+	if now.After(rl.penaltyUntil) {
+		recovered := float64(now.Sub(rl.penaltyUntil)) / float64(rl.penalty)
+		if recovered >= 1 {
+			rl.penaltyUntil, rl.penalty = time.Time{}, 0
+			return base
+		}
+		rate += (baseRate - rate) * recovered
+	}
+
+	interval := time.Duration(float64(time.Second) / rate)
+	if interval > maxRefillInterval {
+		interval = maxRefillInterval
+	}
+	return interval
+}
+
+// FloodWait extracts the wait duration of a FLOOD_WAIT error from its message, so it
+// also works for errors wrapped into strings.
+//
+// Parameters:
+//   - err: API error
+//
+// Returns:
+//   - time.Duration: wait duration requested by Telegram
+//   - bool: true if err is a FLOOD_WAIT error
+func FloodWait(err error) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	match := floodWaitPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0, false
+	}
+	seconds, convErr := strconv.Atoi(match[1])
+	if convErr != nil {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
 func (rl *rateLimiterImpl) Close() {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
@@ -2,6 +2,7 @@ package rateLimiter
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -257,3 +258,127 @@ func TestRateLimiter_Performance(t *testing.T) {
 		assert.True(t, elapsed < 100*time.Millisecond, "elapsed time: %v", elapsed)
 	})
 }
+
+// drain takes every token currently in the bucket
+func drain(rl *rateLimiterImpl) {
+	for {
+		select {
+		case <-rl.tokens:
+		default:
+			return
+		}
+	}
+}
+
+// acquireDuration returns how long acquiring count tokens takes
+func acquireDuration(t *testing.T, rl *rateLimiterImpl, count int) time.Duration {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	for i := 0; i < count; i++ {
+		require.NoError(t, rl.Acquire(ctx))
+	}
+	return time.Since(start)
+}
+
+func TestRateLimiter_Penalize(t *testing.T) {
+	t.Run("штраф замедляет выдачу токенов и скорость восстанавливается", func(t *testing.T) {
+		rl := NewRateLimiter(50)
+		defer rl.Close()
+
+		drain(rl)
+		normal := acquireDuration(t, rl, 3)
+
+		rl.Penalize(300 * time.Millisecond)
+		assert.Empty(t, rl.tokens, "оставшиеся токены сбрасываются")
+		penalized := acquireDuration(t, rl, 3)
+
+		assert.Less(t, normal, 150*time.Millisecond)
+		assert.GreaterOrEqual(t, penalized, 200*time.Millisecond)
+
+		// Penalty and the linear recovery take the penalty duration each
+		time.Sleep(700 * time.Millisecond)
+		drain(rl)
+		recovered := acquireDuration(t, rl, 3)
+		assert.Less(t, recovered, 150*time.Millisecond)
+	})
+
+	t.Run("интервал пополнения во время штрафа и восстановления", func(t *testing.T) {
+		rl := NewRateLimiter(100)
+		defer rl.Close()
+		now := time.Now()
+		rl.now = func() time.Time { return now }
+
+		rl.Penalize(time.Second)
+		rl.mu.Lock()
+		assert.Equal(t, 40*time.Millisecond, rl.interval)
+		rl.mu.Unlock()
+
+		// Halfway through the recovery the rate is 25 + (100-25)/2 = 62.5 per second.
+		// The clock is moved under the lock, the refill loop reads it too
+		rl.mu.Lock()
+		now = now.Add(1500 * time.Millisecond)
+		assert.Equal(t, 16*time.Millisecond, rl.currentInterval())
+
+		now = now.Add(time.Second)
+		assert.Equal(t, 10*time.Millisecond, rl.currentInterval())
+		assert.True(t, rl.penaltyUntil.IsZero())
+		rl.mu.Unlock()
+	})
+
+	t.Run("новый штраф продлевает текущий", func(t *testing.T) {
+		rl := NewRateLimiter(10)
+		defer rl.Close()
+		now := time.Now()
+		rl.now = func() time.Time { return now }
+
+		rl.Penalize(5 * time.Second)
+		rl.Penalize(time.Second)
+		rl.mu.Lock()
+		assert.Equal(t, now.Add(5*time.Second), rl.penaltyUntil)
+		rl.mu.Unlock()
+
+		rl.Penalize(10 * time.Second)
+		rl.mu.Lock()
+		assert.Equal(t, now.Add(10*time.Second), rl.penaltyUntil)
+		assert.Equal(t, 10*time.Second, rl.penalty)
+		rl.mu.Unlock()
+	})
+
+	t.Run("ограничитель без скорости и закрытый ограничитель игнорируют штраф", func(t *testing.T) {
+		unlimited := NewRateLimiter(0)
+		defer unlimited.Close()
+		unlimited.Penalize(time.Second)
+		assert.True(t, unlimited.penaltyUntil.IsZero())
+
+		closed := NewRateLimiter(10)
+		closed.Close()
+		closed.Penalize(time.Second)
+		assert.True(t, closed.penaltyUntil.IsZero())
+	})
+}
+
+func TestFloodWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "ошибка rpc", err: errors.New("rpc error code 420: FLOOD_WAIT (12)"), expected: 12 * time.Second, ok: true},
+		{name: "обернутая ошибка", err: errors.New("failed to get payment form: rpc error code 420: FLOOD_WAIT (3)"), expected: 3 * time.Second, ok: true},
+		{name: "ошибка с подчеркиванием", err: errors.New("FLOOD_WAIT_30"), expected: 30 * time.Second, ok: true},
+		{name: "другая ошибка", err: errors.New("rpc error code 400: STARGIFT_USAGE_LIMITED"), ok: false},
+		{name: "нет ошибки", err: nil, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := FloodWait(tt.err)
+
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, wait)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package usecase
+
+import "gift-buyer/internal/infrastructure/controlServer"
+
+// controlController adapts the gift service to the HTTP control API. It triggers the
+// same actions as the gRPC control API and additionally reports the service status.
+type controlController struct {
+	grpcController
+}
+
+// Status reports the pause state and the purchase count of the service.
+func (c *controlController) Status() controlServer.Status {
+	status := c.service.Status()
+	return controlServer.Status{
+		Paused:       status.Paused,
+		BuyingPaused: status.BuyingPaused,
+		Bought:       status.Bought,
+		MaxBuyCount:  status.MaxBuyCount,
+	}
+}
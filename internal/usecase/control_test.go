@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"gift-buyer/internal/config"
+	"gift-buyer/internal/infrastructure/controlServer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// discardLogs drops the log messages of the control server
+type discardLogs struct{}
+
+func (discardLogs) LogInfo(message string)  {}
+func (discardLogs) LogError(message string) {}
+
+func TestControlController(t *testing.T) {
+	t.Run("статус", func(t *testing.T) {
+		impl := NewUseCase(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil).(*useCaseImpl)
+		impl.buyingPaused.Store(true)
+		controller := &controlController{grpcController{service: impl}}
+
+		assert.Equal(t, controlServer.Status{BuyingPaused: true}, controller.Status())
+	})
+
+	t.Run("остановка", func(t *testing.T) {
+		impl := NewUseCase(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil).(*useCaseImpl)
+		controller := &controlController{grpcController{service: impl}}
+
+		controller.Stop()
+
+		select {
+		case <-impl.StopRequested():
+		case <-time.After(time.Second):
+			t.Fatal("stop was not requested")
+		}
+	})
+}
+
+func TestFactory_StartControlServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	factory := NewFactory(&config.SoftConfig{ControlPort: port, ControlToken: "secret"})
+	impl := NewUseCase(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil).(*useCaseImpl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.startControlServer(ctx, impl, discardLogs{}, discardLogs{})
+
+	request, err := http.NewRequest(http.MethodGet, "http://"+listener.Addr().String()+"/status", nil)
+	require.NoError(t, err)
+	request.Header.Set("Authorization", "Bearer secret")
+
+	var response *http.Response
+	require.Eventually(t, func() bool {
+		response, err = http.DefaultClient.Do(request)
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var status controlServer.Status
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&status))
+	assert.Equal(t, controlServer.Status{}, status)
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"fmt"
 	"gift-buyer/internal/config"
+	"gift-buyer/internal/infrastructure/controlServer"
 	"gift-buyer/internal/infrastructure/gitVersion"
+	"gift-buyer/internal/infrastructure/grpcServer"
+	"gift-buyer/internal/infrastructure/healthServer"
+	"gift-buyer/internal/infrastructure/heartbeat"
 	"gift-buyer/internal/infrastructure/logsWriter"
 	"gift-buyer/internal/infrastructure/logsWriter/logFormatter"
 	"gift-buyer/internal/infrastructure/logsWriter/writer"
@@ -12,19 +16,29 @@ import (
 	"gift-buyer/internal/service/authService/apiChecker"
 	"gift-buyer/internal/service/authService/sessions"
 	"gift-buyer/internal/service/giftService/accountManager"
+	"gift-buyer/internal/service/giftService/cache/balanceCache"
 	"gift-buyer/internal/service/giftService/cache/giftCache"
 	"gift-buyer/internal/service/giftService/cache/idCache"
+	"gift-buyer/internal/service/giftService/cache/sessionState"
+	"gift-buyer/internal/service/giftService/catalogSnapshot"
 	"gift-buyer/internal/service/giftService/giftBuyer"
+	"gift-buyer/internal/service/giftService/giftBuyer/accountPool"
 	"gift-buyer/internal/service/giftService/giftBuyer/atomicCounter"
+	"gift-buyer/internal/service/giftService/giftBuyer/attemptPacer"
 	"gift-buyer/internal/service/giftService/giftBuyer/giftBuyerMonitoring"
 	"gift-buyer/internal/service/giftService/giftBuyer/invoiceCreator"
 	"gift-buyer/internal/service/giftService/giftBuyer/paymentProcessor"
 	"gift-buyer/internal/service/giftService/giftBuyer/purchaseProcessor"
+	"gift-buyer/internal/service/giftService/giftBuyer/purchaseScheduler"
+	"gift-buyer/internal/service/giftService/giftInterfaces"
 	"gift-buyer/internal/service/giftService/giftManager"
 	"gift-buyer/internal/service/giftService/giftMonitor"
 	"gift-buyer/internal/service/giftService/giftNotification"
 	"gift-buyer/internal/service/giftService/giftValidator"
 	"gift-buyer/internal/service/giftService/rateLimiter"
+	"gift-buyer/pkg/logger"
+	"gift-buyer/pkg/tracing"
+	"math/rand"
 	"time"
 
 	"github.com/gotd/td/tg"
@@ -36,8 +50,25 @@ import (
 type Factory struct {
 	// cfg contains the software configuration for the gift buying system
 	cfg *config.SoftConfig
+
+	// build performs a single initialization attempt
+	build func() (UseCase, error)
+
+	// initRetryDelay is the delay before the first initialization retry, doubled on every next one
+	initRetryDelay time.Duration
+
+	// configPath is the configuration file reloaded through the gRPC API (optional)
+	configPath string
 }
 
+const (
+	// defaultInitRetryDelay is the delay before the first initialization retry
+	defaultInitRetryDelay = 2 * time.Second
+
+	// maxInitRetryDelay caps the backoff between initialization retries
+	maxInitRetryDelay = 30 * time.Second
+)
+
 // NewFactory creates a new Factory instance with the specified configuration.
 // The factory will use this configuration to initialize all system components.
 //
@@ -47,7 +78,15 @@ type Factory struct {
 // Returns:
 //   - *Factory: configured factory instance ready to create the gift buying system
 func NewFactory(cfg *config.SoftConfig) *Factory {
-	return &Factory{cfg: cfg}
+	f := &Factory{cfg: cfg, initRetryDelay: defaultInitRetryDelay}
+	f.build = f.createSystem
+	return f
+}
+
+// SetConfigPath sets the configuration file read again when a reload is requested
+// through the gRPC API.
+func (f *Factory) SetConfigPath(path string) {
+	f.configPath = path
 }
 
 // CreateSystem creates and initializes the complete gift buying system.
@@ -61,6 +100,9 @@ func NewFactory(cfg *config.SoftConfig) *Factory {
 //  4. Initializes all service components (validator, manager, cache, etc.)
 //  5. Wires components together into the main service
 //
+// Parameters:
+//   - ctx: context for cancellation, cancelling it stops waiting for the next retry
+//
 // Returns:
 //   - GiftService: fully configured and ready-to-use gift buying service
 //   - error: initialization error, authentication failure, or configuration error
@@ -70,7 +112,37 @@ func NewFactory(cfg *config.SoftConfig) *Factory {
 //   - Bot client initialization errors
 //   - Network connectivity issues
 //   - Invalid configuration parameters
-func (f *Factory) CreateSystem() (UseCase, error) {
+//
+// When InitRetries is set, a failed initialization is retried from scratch with
+// exponential backoff, and the last error is returned once all attempts are used.
+func (f *Factory) CreateSystem(ctx context.Context) (UseCase, error) {
+	delay := f.initRetryDelay
+	for attempt := 0; ; attempt++ {
+		service, err := f.build()
+		if err == nil {
+			return service, nil
+		}
+		if attempt >= f.cfg.InitRetries {
+			return nil, err
+		}
+
+		logger.GlobalLogger.Warnf("System initialization failed (attempt %d/%d): %v, retrying in %v",
+			attempt+1, f.cfg.InitRetries+1, err, delay)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("system initialization aborted after %v: %w", err, ctx.Err())
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxInitRetryDelay {
+			delay = maxInitRetryDelay
+		}
+	}
+}
+
+// createSystem performs a single initialization attempt of the gift buying system.
+func (f *Factory) createSystem() (UseCase, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	tickerInterval := f.cfg.Ticker
@@ -80,23 +152,28 @@ func (f *Factory) CreateSystem() (UseCase, error) {
 
 	infoWriter := writer.NewLogsWriter("info", logFormatter.NewLogFormatter("info"))
 	errorWriter := writer.NewLogsWriter("error", logFormatter.NewLogFormatter("error"))
+	if f.cfg.MaxLogSizeMB > 0 {
+		maxLogSize := int64(f.cfg.MaxLogSizeMB * 1024 * 1024)
+		infoWriter.SetRotation(maxLogSize, f.cfg.MaxLogBackups)
+		errorWriter.SetRotation(maxLogSize, f.cfg.MaxLogBackups)
+	}
 	infoLogsHelper := logsWriter.NewLogger(infoWriter, f.cfg.LogFlag)
 	errorLogsHelper := logsWriter.NewLogger(errorWriter, f.cfg.LogFlag)
 
 	sessionManager := sessions.NewSessionManager(&f.cfg.TgSettings)
 	authManager := authService.NewAuthManager(sessionManager, nil, &f.cfg.TgSettings, infoLogsHelper, errorLogsHelper)
+	authManager.SetGlobalCancel(cancel)
 	api, err := authManager.InitClient(ctx)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
 
-	apiChecker := apiChecker.NewApiChecker(api, time.NewTicker(time.Duration(tickerInterval*1000)*time.Millisecond))
-	authManager.SetApiChecker(apiChecker)
+	authManager.SetApiChecker(apiChecker.NewApiChecker(api, time.NewTicker(time.Duration(tickerInterval*1000)*time.Millisecond)))
 	authManager.RunApiChecker(ctx)
 
 	var botClient *tg.Client
-	if f.cfg.TgSettings.TgBotKey != "" {
+	if f.cfg.TgSettings.TgBotKey != "" && f.cfg.TgSettings.NotificationMode != config.NotificationModeSelf {
 		botClient, err = authManager.InitBotClient(ctx)
 		if err != nil {
 			cancel()
@@ -104,21 +181,122 @@ func (f *Factory) CreateSystem() (UseCase, error) {
 		}
 	}
 
+	if f.cfg.Tracing.Enabled && f.cfg.Tracing.Endpoint != "" {
+		f.startTracing(ctx)
+	}
+
 	validator := giftValidator.NewGiftValidator(f.cfg.Criterias, f.cfg.GiftParam)
-	manager := giftManager.NewGiftManager(api)
-	cache := giftCache.NewGiftCache()
-	userCache := idCache.NewIDCache()
-	notification := giftNotification.NewNotification(botClient, &f.cfg.TgSettings, errorLogsHelper)
-	monitor := giftMonitor.NewGiftMonitor(cache, manager, validator, notification, time.Duration(tickerInterval*1000)*time.Millisecond, errorLogsHelper, infoLogsHelper, f.cfg.GiftParam.TestMode)
+	apiManager := giftManager.NewGiftManager(api)
+	var manager giftInterfaces.Giftmanager = apiManager
+	if f.cfg.FixtureGiftsPath != "" && f.cfg.GiftParam.TestMode {
+		logger.GlobalLogger.Infof("Test mode: gifts are read from the fixture %s", f.cfg.FixtureGiftsPath)
+		manager = giftManager.NewFixtureGiftManager(f.cfg.FixtureGiftsPath)
+	}
+	var cache giftInterfaces.GiftCache
+	if f.cfg.CacheBackend == config.CacheBackendSQLite {
+		sqliteCache, err := giftCache.NewSQLiteGiftCache(giftCache.DefaultSQLiteFile)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to open gift cache: %w", err)
+		}
+		cache = sqliteCache
+	} else {
+		cache = giftCache.NewGiftCacheWithLimitAndTTL(f.cfg.MaxCachedGifts, time.Duration(f.cfg.CacheEvictionWindow*1000)*time.Millisecond, time.Duration(f.cfg.CacheTTL*1000)*time.Millisecond)
+	}
+	notification := giftNotification.NewNotification(botClient, api, &f.cfg.TgSettings, errorLogsHelper)
+	if f.cfg.NotificationRateLimit > 0 {
+		notification.SetRateLimiter(rateLimiter.NewRateLimiter(f.cfg.NotificationRateLimit))
+	}
+	notification.SetMaxNotifications(f.cfg.MaxNotificationsPerRun)
+	notification.SetFallback(f.cfg.NotificationFallbackThreshold, f.cfg.NotificationFallbackFile)
+	authManager.SetReconnectNotifier(notification)
+	var notifier giftInterfaces.NotificationService = notification
+	if f.cfg.TgSettings.DiscordWebhookURL != "" {
+		notifier = giftNotification.NewCompositeNotificationService(notification, giftNotification.NewDiscordNotifier(f.cfg.TgSettings.DiscordWebhookURL, f.cfg.TgSettings.Language, f.cfg.TgSettings.ThousandsSeparator, errorLogsHelper))
+	}
+	if f.cfg.ErrorNotifyCooldown > 0 {
+		notifier = giftNotification.NewDedupNotificationService(notifier, time.Duration(f.cfg.ErrorNotifyCooldown*1000)*time.Millisecond)
+	}
+	maxBackoff := f.cfg.MaxMonitorBackoff
+	if maxBackoff == 0 {
+		maxBackoff = 30
+	}
+	monitor := giftMonitor.NewGiftMonitor(cache, manager, validator, notifier, time.Duration(tickerInterval*1000)*time.Millisecond, time.Duration(maxBackoff*1000)*time.Millisecond, errorLogsHelper, infoLogsHelper, f.cfg.GiftParam.TestMode, f.cfg.StartupSnapshotNotification)
+	monitor.SetSkipFirstRunWithCache(f.cfg.SkipFirstRunWithCache)
+	monitor.SetRecoverValidatorPanics(f.cfg.RecoverValidatorPanics)
+	fetchTimeout := f.cfg.GiftFetchTimeout
+	if fetchTimeout == 0 {
+		fetchTimeout = 10
+	}
+	monitor.SetGiftFetchTimeout(time.Duration(fetchTimeout*1000) * time.Millisecond)
+	monitor.SetInitialCheckBurst(f.cfg.InitialCheckBurst.Count, time.Duration(f.cfg.InitialCheckBurst.Interval*1000)*time.Millisecond)
 	authManager.SetMonitor(monitor)
-	rl := rateLimiter.NewRateLimiter(f.cfg.RPCRateLimit)
+	var state giftInterfaces.SessionState
 	counter := atomicCounter.NewAtomicCounter(f.cfg.MaxBuyCount)
-	invoiceCreator := invoiceCreator.NewInvoiceCreator(f.cfg.Receiver.UserReceiverID, f.cfg.Receiver.ChannelReceiverID, userCache)
-	paymentProcessor := paymentProcessor.NewPaymentProcessor(api, invoiceCreator, rl)
-	purchaseProcessor := purchaseProcessor.NewPurchaseProcessor(api, paymentProcessor)
-	monitorProcessor := giftBuyerMonitoring.NewGiftBuyerMonitoring(api, notification, infoLogsHelper, errorLogsHelper)
-	accountManager := accountManager.NewAccountManager(api, f.cfg.Receiver.UserReceiverID, f.cfg.Receiver.ChannelReceiverID, userCache, userCache)
-	buyer := giftBuyer.NewGiftBuyer(api, f.cfg.Receiver.UserReceiverID, f.cfg.Receiver.ChannelReceiverID, manager, notification, f.cfg.MaxBuyCount, f.cfg.RetryCount, f.cfg.RetryDelay, f.cfg.Prioritization, userCache, f.cfg.ConcurrencyGiftCount, rl, f.cfg.ConcurrentOperations, invoiceCreator, purchaseProcessor, monitorProcessor, counter, errorLogsHelper)
+	if f.cfg.SessionStateFile != "" {
+		restored := sessionState.NewSessionState(f.cfg.SessionStateFile, time.Duration(f.cfg.SessionStateTTL*1000)*time.Millisecond)
+		counter = atomicCounter.NewAtomicCounterFrom(f.cfg.MaxBuyCount, restored.BoughtCount())
+		monitor.SetSessionState(restored)
+		state = restored
+	}
+	monitorProcessor := giftBuyerMonitoring.NewGiftBuyerMonitoring(api, notifier, infoLogsHelper, errorLogsHelper)
+	monitorProcessor.SetProgressInterval(time.Duration(f.cfg.ProgressNotificationInterval*1000) * time.Millisecond)
+	shared := sharedPurchase{
+		manager:          manager,
+		notifier:         notifier,
+		validator:        validator,
+		monitorProcessor: monitorProcessor,
+		counter:          counter,
+		giftCounts:       atomicCounter.NewCapCounter(),
+		receiverCounts:   atomicCounter.NewCapCounter(),
+		state:            state,
+		errorLogs:        errorLogsHelper,
+	}
+	primary := f.createAccountPurchase(api, shared)
+	apiManager.SetRateLimiter(primary.rateLimiter.Bucket(rateLimiter.BucketGetGifts))
+	buyers := []giftInterfaces.GiftBuyer{primary.buyer}
+	receivers := accountManagers{primary.accountManager}
+	warmers := paymentWarmers{primary.warmer}
+	announcers := limitAnnouncers{primary.announcer}
+	apiHealth := apiMonitors{authManager}
+	for _, account := range f.cfg.AccountSettings() {
+		// Every account reconnects on its own, a failed reconnect stops the service like it
+		// does for the main account
+		accountAuth := authService.NewAuthManager(sessions.NewSessionManager(&account), nil, &account, infoLogsHelper, errorLogsHelper)
+		accountAuth.SetGlobalCancel(cancel)
+		accountAPI, err := accountAuth.InitClient(ctx)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to init account %s: %w", account.Phone, err)
+		}
+		accountAuth.SetApiChecker(apiChecker.NewApiChecker(accountAPI, time.NewTicker(time.Duration(tickerInterval*1000)*time.Millisecond)))
+		accountAuth.SetReconnectNotifier(notification)
+		accountAuth.RunApiChecker(ctx)
+		apiHealth = append(apiHealth, accountAuth)
+
+		purchase := f.createAccountPurchase(accountAPI, shared)
+		buyers = append(buyers, purchase.buyer)
+		receivers = append(receivers, purchase.accountManager)
+		warmers = append(warmers, purchase.warmer)
+		announcers = append(announcers, purchase.announcer)
+	}
+	var purchaser giftInterfaces.GiftBuyer = primary.buyer
+	if len(buyers) > 1 {
+		purchaser = accountPool.NewAccountPool(buyers...)
+		logger.GlobalLogger.Infof("Buying with %d accounts", len(buyers))
+	}
+	if f.cfg.PurchaseSchedule.PerMinute > 0 {
+		purchaser = purchaseScheduler.NewPurchaseScheduler(purchaser, f.cfg.PurchaseSchedule.PerMinute)
+	}
+	if f.cfg.CatalogSnapshotInterval > 0 {
+		snapshotDir := f.cfg.CatalogSnapshotDir
+		if snapshotDir == "" {
+			snapshotDir = "catalog_snapshots"
+		}
+		snapshotter := catalogSnapshot.NewCatalogSnapshotter(manager, snapshotDir, time.Duration(f.cfg.CatalogSnapshotInterval*1000)*time.Millisecond, infoLogsHelper, errorLogsHelper)
+		go snapshotter.Run(ctx)
+	}
+	updateAsset := gitVersion.PlatformAssetName(f.cfg.RepoName)
 	gitVersion := gitVersion.NewGitVersionController(f.cfg.RepoOwner, f.cfg.RepoName, f.cfg.ApiLink)
 
 	updateInterval := f.cfg.UpdateTicker
@@ -130,16 +308,198 @@ func (f *Factory) CreateSystem() (UseCase, error) {
 		manager,
 		validator,
 		cache,
-		notification,
+		notifier,
 		monitor,
-		buyer,
+		purchaser,
 		ctx,
 		cancel,
 		api,
-		accountManager,
+		receivers,
 		gitVersion,
 		time.NewTicker(time.Duration(updateInterval)*time.Second),
+		state,
 	)
+	monitorProcessor.SetSuccessThreshold(f.cfg.BatchSuccessThreshold, f.cfg.BatchThresholdAction, service.PauseBuying)
+	service.(*useCaseImpl).setCounter(counter)
+	service.(*useCaseImpl).setLimitAnnouncer(announcers)
+	if f.cfg.AutoUpdate {
+		service.(*useCaseImpl).setAutoUpdate(updateAsset)
+	}
+	if f.cfg.SessionReportFile != "" {
+		stats := giftBuyerMonitoring.NewSessionStats()
+		monitorProcessor.SetSessionStats(stats)
+		service.(*useCaseImpl).setSessionReport(stats, f.cfg.SessionReportFile)
+	}
+	if f.cfg.DiscoveryWebhookURL != "" {
+		service.(*useCaseImpl).setDiscoveryWebhook(newWebhookClient(f.cfg.DiscoveryWebhookURL))
+	}
+	if len(f.cfg.WarmGiftIDs) > 0 {
+		service.(*useCaseImpl).setPaymentWarmer(warmers, f.cfg.WarmGiftIDs)
+	}
+	if f.cfg.HeartbeatURL != "" {
+		f.startHeartbeat(ctx, service.(*useCaseImpl), balanceCache.NewBalanceCache(api))
+	}
+	if f.cfg.HealthPort > 0 {
+		f.startHealthServer(ctx, service.(*useCaseImpl), apiHealth.LastApiSuccess, infoLogsHelper)
+	}
+	if f.cfg.GRPC.Enabled {
+		f.startGrpcServer(ctx, service.(*useCaseImpl), monitorProcessor, infoLogsHelper, errorLogsHelper)
+	}
+	if f.cfg.ControlPort > 0 {
+		f.startControlServer(ctx, service.(*useCaseImpl), infoLogsHelper, errorLogsHelper)
+	}
 
 	return service, nil
 }
+
+// sharedPurchase holds the purchase components shared by the buyers of all accounts.
+type sharedPurchase struct {
+	manager          giftInterfaces.Giftmanager
+	notifier         giftInterfaces.NotificationService
+	validator        giftInterfaces.GiftValidator
+	monitorProcessor giftInterfaces.MonitorProcessor
+	counter          giftInterfaces.Counter
+	giftCounts       giftInterfaces.CapCounter
+	receiverCounts   giftInterfaces.CapCounter
+	state            giftInterfaces.SessionState
+	errorLogs        giftInterfaces.ErrorLogger
+}
+
+// accountPurchase holds the purchase components bound to the session of one account.
+type accountPurchase struct {
+	buyer          giftInterfaces.GiftBuyer
+	accountManager giftInterfaces.AccountManager
+	warmer         paymentWarmer
+	announcer      limitAnnouncer
+	rateLimiter    *rateLimiter.MultiRateLimiter
+}
+
+// createAccountPurchase creates the buyer of one account. Receiver IDs, rate limits and
+// the balance are per session, while the purchase limit, the per-gift and per-receiver caps,
+// session state, notifications and batch reporting are shared by all accounts.
+//
+// Parameters:
+//   - api: Telegram client of the account
+//   - shared: components shared by all accounts
+//
+// Returns:
+//   - accountPurchase: buyer, receiver resolver and purchase path warmer of the account
+func (f *Factory) createAccountPurchase(api *tg.Client, shared sharedPurchase) accountPurchase {
+	userCache := idCache.NewIDCache()
+	rl := rateLimiter.NewMultiRateLimiter(f.cfg.RPCRateLimit, f.cfg.RPCRateLimits)
+	invoiceCreator := invoiceCreator.NewInvoiceCreator(f.cfg.Receiver.UserReceiverID, f.cfg.Receiver.ChannelReceiverID, userCache, f.cfg.RotateReceivers)
+	invoiceCreator.SetMaxPerReceiver(f.cfg.MaxGiftsPerReceiver)
+	invoiceCreator.SetReceiverCounts(shared.receiverCounts)
+	paymentProcessor := paymentProcessor.NewPaymentProcessor(api, invoiceCreator, rl.Bucket(rateLimiter.BucketPaymentForm))
+	processor := purchaseProcessor.NewPurchaseProcessor(api, paymentProcessor, f.cfg.VerifyPurchase, time.Duration(f.cfg.VerifyPurchaseTimeout*1000)*time.Millisecond)
+	processor.SetRateLimiter(rl.Bucket(rateLimiter.BucketSendPayment))
+	processor.SetBalanceReserve(f.cfg.BalanceReserve)
+	if f.cfg.VerboseApiLogging {
+		paymentProcessor.SetVerboseLogging(logger.GlobalLogger.Debugf)
+		processor.SetVerboseLogging(logger.GlobalLogger.Debugf)
+	}
+	var purchaser giftInterfaces.PurchaseProcessor = processor
+	if f.cfg.CircuitBreakerThreshold > 0 {
+		purchaser = purchaseProcessor.NewCircuitBreaker(purchaser, f.cfg.CircuitBreakerThreshold, time.Duration(f.cfg.CircuitBreakerCooldown*1000)*time.Millisecond)
+	}
+	accountManager := accountManager.NewAccountManager(api, f.cfg.Receiver.UserReceiverID, f.cfg.Receiver.ChannelReceiverID, userCache, userCache)
+	accountManager.SetBatchResolve(f.cfg.BatchResolveReceivers)
+	buyer := giftBuyer.NewGiftBuyer(api, f.cfg.Receiver.UserReceiverID, f.cfg.Receiver.ChannelReceiverID, shared.manager, shared.notifier, f.cfg.MaxBuyCount, f.cfg.RetryCount, f.cfg.RetryDelay, f.cfg.Prioritization, userCache, f.cfg.ConcurrencyGiftCount, rl, f.cfg.ConcurrentOperations, invoiceCreator, purchaser, shared.monitorProcessor, shared.counter, shared.errorLogs, f.cfg.MaxConcurrentBatches, f.cfg.GiftParam.DryRun)
+	if shared.state != nil {
+		buyer.SetSessionState(shared.state)
+	}
+	buyer.SetGiftCounts(shared.giftCounts)
+	buyer.SetNotifyOnLimitReached(f.cfg.NotifyOnLimitReached)
+	buyer.SetMinAvailabilityAtBuy(f.cfg.GiftParam.MinAvailabilityAtBuy)
+	if f.cfg.GiftParam.RevalidateBeforeBuy {
+		buyer.SetRevalidator(shared.validator)
+	}
+	buyer.SetBatchTimeout(time.Duration(f.cfg.BatchTimeout*1000) * time.Millisecond)
+	buyer.SetPurchaseSpacing(time.Duration(f.cfg.PurchaseSpacingMs) * time.Millisecond)
+	buyer.SetLaunchJitter(time.Duration(f.cfg.LaunchJitterMs) * time.Millisecond)
+	buyer.SetDrainOnShutdown(f.cfg.DrainOnShutdown)
+	if f.cfg.CheckBalanceBeforeBuy {
+		buyer.SetBalanceCache(balanceCache.NewBalanceCache(api))
+	}
+	if f.cfg.MaxAttemptsPerSecond > 0 {
+		buyer.SetAttemptPacer(attemptPacer.NewAttemptPacer(f.cfg.MaxAttemptsPerSecond))
+	}
+	buyer.SetPriorityByCriteriaOrder(f.cfg.PriorityByCriteriaOrder)
+	buyer.SetPriorityStrategy(f.cfg.PriorityStrategy)
+	if f.cfg.ShuffleEqualPriority {
+		buyer.SetShuffleEqualPriority(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	return accountPurchase{buyer: buyer, accountManager: accountManager, warmer: paymentProcessor, announcer: buyer, rateLimiter: rl}
+}
+
+// startHeartbeat posts the health of the service to HeartbeatURL until the context is cancelled.
+func (f *Factory) startHeartbeat(ctx context.Context, service *useCaseImpl, balance balanceSource) {
+	interval := f.cfg.HeartbeatInterval
+	if interval <= 0 {
+		interval = 60
+	}
+
+	service.setBalanceSource(balance)
+	reporter := heartbeat.NewReporter(f.cfg.HeartbeatURL, time.Duration(interval*1000)*time.Millisecond, heartbeat.StatusFunc(service.healthStatus))
+	logger.GlobalLogger.Infof("Sending heartbeats to %s every %v", f.cfg.HeartbeatURL, time.Duration(interval*1000)*time.Millisecond)
+	go reporter.Run(ctx)
+}
+
+// startHealthServer serves the /healthz endpoint on HealthPort until the context is cancelled.
+func (f *Factory) startHealthServer(ctx context.Context, service *useCaseImpl, lastSuccess func() time.Time, infoLogsWriter healthServer.InfoLogger) {
+	server := healthServer.NewHealthServer(fmt.Sprintf(":%d", f.cfg.HealthPort), lastSuccess, service.isMonitorRunning, healthServer.DefaultMaxStaleness, infoLogsWriter)
+	go func() {
+		if err := server.Start(ctx); err != nil {
+			logger.GlobalLogger.Errorf("Health endpoint error: %v", err)
+		}
+	}()
+}
+
+// startGrpcServer serves the gRPC control and event stream API on GRPC.Port until the
+// context is cancelled. Found gifts and purchase results are streamed to its clients.
+func (f *Factory) startGrpcServer(ctx context.Context, service *useCaseImpl, results *giftBuyerMonitoring.GiftBuyerMonitoringImpl, infoLogsWriter grpcServer.InfoLogger, errorLogsWriter grpcServer.ErrorLogger) {
+	controller := &grpcController{service: service, configPath: f.configPath}
+	server := grpcServer.NewGrpcServer(fmt.Sprintf("127.0.0.1:%d", f.cfg.GRPC.Port), f.cfg.ControlToken, controller, infoLogsWriter, errorLogsWriter)
+	service.setEventPublisher(server)
+	results.AddResultRecorder(purchaseEvents{events: server})
+	go func() {
+		if err := server.Start(ctx); err != nil {
+			logger.GlobalLogger.Errorf("gRPC control API error: %v", err)
+		}
+	}()
+}
+
+// startControlServer serves the HTTP control API on 127.0.0.1:ControlPort until the
+// context is cancelled.
+func (f *Factory) startControlServer(ctx context.Context, service *useCaseImpl, infoLogsWriter controlServer.InfoLogger, errorLogsWriter controlServer.ErrorLogger) {
+	controller := &controlController{grpcController{service: service, configPath: f.configPath}}
+	server := controlServer.NewControlServer(fmt.Sprintf("127.0.0.1:%d", f.cfg.ControlPort), f.cfg.ControlToken, controller, infoLogsWriter, errorLogsWriter)
+	go func() {
+		if err := server.Start(ctx); err != nil {
+			logger.GlobalLogger.Errorf("Control API error: %v", err)
+		}
+	}()
+}
+
+// startTracing installs the OpenTelemetry span exporter and shuts it down,
+// exporting the remaining spans, once the context is cancelled.
+func (f *Factory) startTracing(ctx context.Context) {
+	serviceName := f.cfg.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = "gift-buyer"
+	}
+
+	provider := tracing.NewTracerProvider(tracing.NewOTLPExporter(f.cfg.Tracing.Endpoint, serviceName))
+	provider.Install()
+	logger.GlobalLogger.Infof("Exporting traces to %s", f.cfg.Tracing.Endpoint)
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(shutdownCtx); err != nil {
+			logger.GlobalLogger.Warnf("Failed to export remaining traces: %v", err)
+		}
+	}()
+}
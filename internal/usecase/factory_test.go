@@ -1,8 +1,11 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"gift-buyer/internal/config"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -296,3 +299,85 @@ func TestFactory_ZeroValues(t *testing.T) {
 	assert.Equal(t, []string{"0"}, factory.cfg.Receiver.ChannelReceiverID)
 	assert.Equal(t, 0.0, factory.cfg.Ticker)
 }
+
+func TestFactory_CreateSystemInitRetries(t *testing.T) {
+	newFactory := func(retries int, failures int) (*Factory, *int) {
+		factory := NewFactory(&config.SoftConfig{InitRetries: retries})
+		factory.initRetryDelay = time.Millisecond
+
+		attempts := 0
+		factory.build = func() (UseCase, error) {
+			attempts++
+			if attempts <= failures {
+				return nil, errors.New("network is unreachable")
+			}
+			return &useCaseImpl{}, nil
+		}
+		return factory, &attempts
+	}
+
+	t.Run("первая попытка падает, вторая запускает систему", func(t *testing.T) {
+		factory := NewFactory(&config.SoftConfig{InitRetries: 1})
+		factory.initRetryDelay = time.Millisecond
+
+		started := &useCaseImpl{}
+		attempts := 0
+		factory.build = func() (UseCase, error) {
+			attempts++
+			if attempts == 1 {
+				return nil, errors.New("dial tcp: i/o timeout")
+			}
+			return started, nil
+		}
+
+		service, err := factory.CreateSystem(context.Background())
+
+		assert.NoError(t, err)
+		assert.Same(t, started, service)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("временный сбой, затем успешный запуск", func(t *testing.T) {
+		factory, attempts := newFactory(3, 2)
+
+		service, err := factory.CreateSystem(context.Background())
+
+		assert.NoError(t, err)
+		assert.NotNil(t, service)
+		assert.Equal(t, 3, *attempts)
+	})
+
+	t.Run("все попытки исчерпаны", func(t *testing.T) {
+		factory, attempts := newFactory(2, 10)
+
+		service, err := factory.CreateSystem(context.Background())
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "network is unreachable")
+		assert.Nil(t, service)
+		assert.Equal(t, 3, *attempts)
+	})
+
+	t.Run("отмена контекста прерывает ожидание повтора", func(t *testing.T) {
+		factory, attempts := newFactory(3, 10)
+		factory.initRetryDelay = time.Hour
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		service, err := factory.CreateSystem(ctx)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "network is unreachable")
+		assert.Nil(t, service)
+		assert.Equal(t, 1, *attempts)
+	})
+
+	t.Run("без повторов по умолчанию", func(t *testing.T) {
+		factory, attempts := newFactory(0, 1)
+
+		_, err := factory.CreateSystem(context.Background())
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, *attempts)
+	})
+}
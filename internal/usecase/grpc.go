@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"gift-buyer/internal/config"
+	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
+)
+
+// grpcController adapts the gift service to the gRPC control API.
+type grpcController struct {
+	service *useCaseImpl
+
+	// configPath is the configuration file read again on reload, empty disables reloading
+	configPath string
+}
+
+func (c *grpcController) Pause()       { c.service.Pause() }
+func (c *grpcController) Resume()      { c.service.Resume() }
+func (c *grpcController) PauseBuying() { c.service.PauseBuying() }
+
+// Reload reads the configuration file again and applies it to the running service.
+func (c *grpcController) Reload() error {
+	if c.configPath == "" {
+		return errors.New("configuration path is not set, reloading is not available")
+	}
+
+	cfg, err := config.LoadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+	return c.service.Reload(&cfg.SoftConfig)
+}
+
+// Stop triggers the same graceful shutdown as a termination signal.
+func (c *grpcController) Stop() {
+	c.service.requestStop()
+}
+
+// purchaseEvents hands the result of every purchase attempt to the gRPC event stream.
+type purchaseEvents struct {
+	events eventPublisher
+}
+
+// RecordResult publishes the purchase result, a failure without an error is reported
+// with a generic one so that the event still reads as failed.
+func (p purchaseEvents) RecordResult(result giftTypes.GiftResult, stars int64) {
+	var err error
+	if !result.Success {
+		err = result.Err
+		if err == nil {
+			err = errors.New("purchase failed")
+		}
+	}
+	p.events.PurchaseResult(result.GiftID, stars, err)
+}
@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gift-buyer/internal/service/giftService/giftTypes"
+
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// publishedEvent is an event handed to the recordingPublisher
+type publishedEvent struct {
+	found  bool
+	giftID int64
+	stars  int64
+	err    error
+}
+
+// recordingPublisher records the published events in order
+type recordingPublisher struct {
+	events []publishedEvent
+}
+
+func (p *recordingPublisher) GiftFound(giftID, stars int64) {
+	p.events = append(p.events, publishedEvent{found: true, giftID: giftID, stars: stars})
+}
+
+func (p *recordingPublisher) PurchaseResult(giftID, stars int64, err error) {
+	p.events = append(p.events, publishedEvent{giftID: giftID, stars: stars, err: err})
+}
+
+func TestUseCaseImpl_PublishDiscovered(t *testing.T) {
+	publisher := &recordingPublisher{}
+	impl := &useCaseImpl{}
+	impl.publishDiscovered([]*giftTypes.GiftRequire{{Gift: &tg.StarGift{ID: 1, Stars: 100}}})
+
+	impl.setEventPublisher(publisher)
+	impl.publishDiscovered([]*giftTypes.GiftRequire{
+		{Gift: &tg.StarGift{ID: 1, Stars: 100}, ReceiverType: []int{1}},
+		{Gift: &tg.StarGift{ID: 1, Stars: 100}, ReceiverType: []int{2}},
+		{Gift: &tg.StarGift{ID: 2, Stars: 300}},
+	})
+
+	assert.Equal(t, []publishedEvent{
+		{found: true, giftID: 1, stars: 100},
+		{found: true, giftID: 2, stars: 300},
+	}, publisher.events)
+}
+
+func TestPurchaseEvents_RecordResult(t *testing.T) {
+	publisher := &recordingPublisher{}
+	events := purchaseEvents{events: publisher}
+
+	events.RecordResult(giftTypes.GiftResult{GiftID: 1, Success: true}, 100)
+	events.RecordResult(giftTypes.GiftResult{GiftID: 1, Success: true, Simulated: true}, 100)
+	events.RecordResult(giftTypes.GiftResult{GiftID: 2, Err: errors.New("BALANCE_TOO_LOW")}, 300)
+	events.RecordResult(giftTypes.GiftResult{GiftID: 3}, 50)
+
+	require.Len(t, publisher.events, 4)
+	assert.NoError(t, publisher.events[0].err)
+	assert.NoError(t, publisher.events[1].err)
+	assert.EqualError(t, publisher.events[2].err, "BALANCE_TOO_LOW")
+	assert.Equal(t, int64(300), publisher.events[2].stars)
+	assert.Error(t, publisher.events[3].err, "a failure without an error is still a failure")
+}
+
+func TestGrpcController(t *testing.T) {
+	t.Run("остановка", func(t *testing.T) {
+		impl := NewUseCase(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil).(*useCaseImpl)
+		controller := &grpcController{service: impl}
+
+		controller.Stop()
+		controller.Stop()
+
+		select {
+		case <-impl.StopRequested():
+		case <-time.After(time.Second):
+			t.Fatal("stop was not requested")
+		}
+	})
+
+	t.Run("перезагрузка без пути к конфигурации", func(t *testing.T) {
+		controller := &grpcController{service: &useCaseImpl{}}
+
+		err := controller.Reload()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "configuration path is not set")
+	})
+
+	t.Run("перезагрузка отсутствующего файла", func(t *testing.T) {
+		controller := &grpcController{service: &useCaseImpl{}, configPath: filepath.Join(t.TempDir(), "config.json")}
+
+		assert.Error(t, controller.Reload())
+	})
+}
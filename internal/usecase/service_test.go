@@ -2,14 +2,23 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"gift-buyer/internal/config"
 	gittypes "gift-buyer/internal/infrastructure/gitVersion/gitTypes"
+	"gift-buyer/internal/service/giftService/giftBuyer/atomicCounter"
+	"gift-buyer/internal/service/giftService/giftBuyer/giftBuyerMonitoring"
 	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/internal/service/giftService/giftValidator"
+	"gift-buyer/pkg/errors"
 
 	"github.com/gotd/td/tg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Мок для BalanceCache
@@ -19,6 +28,9 @@ type MockBalanceCache struct{}
 func (m *MockBalanceCache) SetBalance(balance int64)    {}
 func (m *MockBalanceCache) GetBalance() int64           { return 0 }
 func (m *MockBalanceCache) TrimBalance(deduction int64) {}
+func (m *MockBalanceCache) RefreshBalance(ctx context.Context) error {
+	return nil
+}
 
 // MockAccountManager для тестирования SetIds
 type MockAccountManager struct{}
@@ -41,6 +53,10 @@ type MockGitVersionController struct {
 	latestVersion  *gittypes.GitHubRelease
 	compareResult  bool
 	shouldError    bool
+
+	// downloadErr fails DownloadAsset, downloads counts its calls
+	downloadErr error
+	downloads   int
 }
 
 func (m *MockGitVersionController) GetCurrentVersion() (string, error) {
@@ -64,6 +80,11 @@ func (m *MockGitVersionController) CompareVersions(current, latest string) (bool
 	return m.compareResult, nil
 }
 
+func (m *MockGitVersionController) DownloadAsset(release *gittypes.GitHubRelease, assetName string) (string, error) {
+	m.downloads++
+	return "", m.downloadErr
+}
+
 // MockNotificationService для тестирования
 type MockNotificationService struct{}
 
@@ -87,10 +108,24 @@ func (m *MockNotificationService) SendUpdateNotification(ctx context.Context, ve
 	return nil
 }
 
+func (m *MockNotificationService) SendStartupSnapshot(ctx context.Context, available, matching int) error {
+	return nil
+}
+
+func (m *MockNotificationService) SendLimitReachedNotification(ctx context.Context, limit string, max int64) error {
+	return nil
+}
+
+func (m *MockNotificationService) SendSoldOutNotification(ctx context.Context, gift *tg.StarGift) error {
+	return nil
+}
+
+func (m *MockNotificationService) Close() {}
+
 // MockGiftMonitor для тестирования
 type MockGiftMonitor struct{}
 
-func (m *MockGiftMonitor) Start(ctx context.Context) (map[*tg.StarGift]*giftTypes.GiftRequire, error) {
+func (m *MockGiftMonitor) Start(ctx context.Context) ([]*giftTypes.GiftRequire, error) {
 	return nil, ctx.Err()
 }
 
@@ -109,7 +144,7 @@ func TestNewUseCase(t *testing.T) {
 	defer ticker.Stop()
 
 	// Create nil dependencies for testing constructor
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 	assert.NotNil(t, service)
 
 	// Verify it implements the interface
@@ -123,7 +158,7 @@ func TestUseCaseImpl_Structure(t *testing.T) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 	impl, ok := service.(*useCaseImpl)
 	assert.True(t, ok)
 	assert.Equal(t, ctx, impl.ctx)
@@ -135,7 +170,7 @@ func TestUseCaseImpl_StartMethod(t *testing.T) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 
 	// Test that Start method exists and can be called
 	assert.NotPanics(t, func() {
@@ -151,7 +186,7 @@ func TestUseCaseImpl_StopMethod(t *testing.T) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 
 	// Test that Stop method exists and can be called
 	assert.NotPanics(t, func() {
@@ -159,13 +194,47 @@ func TestUseCaseImpl_StopMethod(t *testing.T) {
 	})
 }
 
+func TestUseCaseImpl_SessionReport(t *testing.T) {
+	t.Run("отчет записывается при остановке", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session_report.json")
+		ctx, cancel := context.WithCancel(context.Background())
+		service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, nil, nil).(*useCaseImpl)
+
+		stats := giftBuyerMonitoring.NewSessionStats()
+		service.setSessionReport(stats, path)
+		stats.RecordSeen(2)
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 1, Success: true}, 100)
+		stats.RecordResult(giftTypes.GiftResult{GiftID: 2, Success: false, Err: errors.New("flood wait")}, 200)
+
+		service.Stop()
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var report giftBuyerMonitoring.SessionReport
+		require.NoError(t, json.Unmarshal(data, &report))
+		assert.Equal(t, int64(2), report.GiftsSeen)
+		assert.Equal(t, map[int64]int64{1: 1}, report.Bought)
+		assert.Equal(t, int64(1), report.Failures)
+		assert.Equal(t, "flood wait", report.MostFrequentError)
+		assert.Equal(t, int64(100), report.StarsSpent)
+	})
+
+	t.Run("ошибка записи не мешает остановке", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, nil, nil).(*useCaseImpl)
+		service.setSessionReport(giftBuyerMonitoring.NewSessionStats(), filepath.Join(t.TempDir(), "missing", "report.json"))
+
+		assert.NotPanics(t, service.Stop)
+	})
+}
+
 func TestUseCaseImpl_MethodSignatures(t *testing.T) {
 	ctx := context.Background()
 	cancel := func() {}
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 
 	// Verify Start signature
 	start := service.Start
@@ -182,7 +251,7 @@ func TestUseCaseImpl_ContextCancellation(t *testing.T) {
 	defer ticker.Stop()
 	cancel() // Cancel immediately
 
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 
 	// Test that cancelled context doesn't cause panic
 	assert.NotPanics(t, func() {
@@ -198,7 +267,7 @@ func TestUseCaseImpl_TypeAssertions(t *testing.T) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 
 	// Test type assertions
 	impl, ok := service.(*useCaseImpl)
@@ -215,7 +284,7 @@ func TestUseCaseImpl_InterfaceCompliance(t *testing.T) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 
 	// Verify that the service implements the UseCase interface
 	_, ok := service.(UseCase)
@@ -259,19 +328,89 @@ func TestUseCaseImpl_SetIds_Success(t *testing.T) {
 
 	mockAccountManager := &MockAccountManager{}
 
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, mockAccountManager, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, mockAccountManager, nil, ticker, nil)
 
 	err := service.SetIds(ctx)
 	assert.NoError(t, err)
 }
 
+// failingAccountManager fails to resolve the receivers
+type failingAccountManager struct {
+	MockAccountManager
+}
+
+func (m *failingAccountManager) SetIds(ctx context.Context) error {
+	return assert.AnError
+}
+
+// recordingWarmer records the gifts it was asked to prepare
+type recordingWarmer struct {
+	giftIDs []int64
+	err     error
+}
+
+func (w *recordingWarmer) Warm(giftIDs []int64) error {
+	w.giftIDs = giftIDs
+	return w.err
+}
+
+func TestUseCaseImpl_SetIds_WarmsPurchasePath(t *testing.T) {
+	ctx := context.Background()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	t.Run("прогрев после загрузки получателей", func(t *testing.T) {
+		warmer := &recordingWarmer{}
+		service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, func() {}, nil, &MockAccountManager{}, nil, ticker, nil)
+		service.(*useCaseImpl).setPaymentWarmer(warmer, []int64{1, 2})
+
+		assert.NoError(t, service.SetIds(ctx))
+		assert.Equal(t, []int64{1, 2}, warmer.giftIDs)
+	})
+
+	t.Run("ошибка прогрева не останавливает запуск", func(t *testing.T) {
+		warmer := &recordingWarmer{err: assert.AnError}
+		service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, func() {}, nil, &MockAccountManager{}, nil, ticker, nil)
+		service.(*useCaseImpl).setPaymentWarmer(warmer, []int64{1})
+
+		assert.NoError(t, service.SetIds(ctx))
+	})
+
+	t.Run("без получателей прогрев не выполняется", func(t *testing.T) {
+		warmer := &recordingWarmer{}
+		service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, func() {}, nil, &failingAccountManager{}, nil, ticker, nil)
+		service.(*useCaseImpl).setPaymentWarmer(warmer, []int64{1})
+
+		assert.ErrorIs(t, service.SetIds(ctx), assert.AnError)
+		assert.Nil(t, warmer.giftIDs)
+	})
+}
+
+func TestAccountManagers_SetIds(t *testing.T) {
+	assert.NoError(t, accountManagers{&MockAccountManager{}, &MockAccountManager{}}.SetIds(context.Background()))
+	assert.NoError(t, accountManagers{}.SetIds(context.Background()))
+}
+
+// fixedApiMonitor reports a fixed last successful API check
+type fixedApiMonitor time.Time
+
+func (m fixedApiMonitor) LastApiSuccess() time.Time { return time.Time(m) }
+
+func TestApiMonitors_LastApiSuccess(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, now.Add(-time.Minute), apiMonitors{fixedApiMonitor(now), fixedApiMonitor(now.Add(-time.Minute))}.LastApiSuccess())
+	assert.True(t, apiMonitors{fixedApiMonitor(now), fixedApiMonitor(time.Time{})}.LastApiSuccess().IsZero(), "an account that never connected keeps the service unhealthy")
+	assert.True(t, apiMonitors{}.LastApiSuccess().IsZero())
+}
+
 func TestUseCaseImpl_SetIds_WithNilAccountManager(t *testing.T) {
 	ctx := context.Background()
 	cancel := func() {}
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 
 	// This should panic due to nil pointer dereference
 	assert.Panics(t, func() {
@@ -293,7 +432,7 @@ func TestUseCaseImpl_CheckForUpdates_NoUpdateAvailable(t *testing.T) {
 
 	mockNotification := &MockNotificationService{}
 
-	service := NewUseCase(nil, nil, nil, mockNotification, nil, nil, ctx, cancel, nil, nil, mockGitVersion, ticker)
+	service := NewUseCase(nil, nil, nil, mockNotification, nil, nil, ctx, cancel, nil, nil, mockGitVersion, ticker, nil)
 
 	// Start CheckForUpdates in a goroutine
 	go service.CheckForUpdates()
@@ -322,7 +461,7 @@ func TestUseCaseImpl_CheckForUpdates_UpdateAvailable(t *testing.T) {
 
 	mockNotification := &MockNotificationService{}
 
-	service := NewUseCase(nil, nil, nil, mockNotification, nil, nil, ctx, cancel, nil, nil, mockGitVersion, ticker)
+	service := NewUseCase(nil, nil, nil, mockNotification, nil, nil, ctx, cancel, nil, nil, mockGitVersion, ticker, nil)
 
 	// Start CheckForUpdates in a goroutine
 	go service.CheckForUpdates()
@@ -351,7 +490,7 @@ func TestUseCaseImpl_CheckForUpdates_WithErrors(t *testing.T) {
 
 	mockNotification := &MockNotificationService{}
 
-	service := NewUseCase(nil, nil, nil, mockNotification, nil, nil, ctx, cancel, nil, nil, mockGitVersion, ticker)
+	service := NewUseCase(nil, nil, nil, mockNotification, nil, nil, ctx, cancel, nil, nil, mockGitVersion, ticker, nil)
 
 	// Start CheckForUpdates in a goroutine
 	go service.CheckForUpdates()
@@ -380,7 +519,7 @@ func TestUseCaseImpl_CheckForUpdates_ContextCancellation(t *testing.T) {
 
 	mockNotification := &MockNotificationService{}
 
-	service := NewUseCase(nil, nil, nil, mockNotification, nil, nil, ctx, cancel, nil, nil, mockGitVersion, ticker)
+	service := NewUseCase(nil, nil, nil, mockNotification, nil, nil, ctx, cancel, nil, nil, mockGitVersion, ticker, nil)
 
 	// Start CheckForUpdates in a goroutine
 	go service.CheckForUpdates()
@@ -400,7 +539,7 @@ func TestUseCaseImpl_Start_Integration(t *testing.T) {
 	defer ticker.Stop()
 
 	// Create a minimal service for integration testing
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 
 	// Verify service creation works
 	assert.NotNil(t, service)
@@ -411,7 +550,7 @@ func TestUseCaseImpl_Stop_Integration(t *testing.T) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker)
+	service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, ticker, nil)
 
 	// Stop should work even with nil dependencies
 	assert.NotPanics(t, func() {
@@ -426,3 +565,227 @@ func TestUseCaseImpl_Stop_Integration(t *testing.T) {
 		assert.Fail(t, "Context should have been cancelled")
 	}
 }
+
+func TestUseCaseImpl_PauseBuyingAndResume(t *testing.T) {
+	impl := &useCaseImpl{monitor: &MockGiftMonitor{}}
+
+	impl.PauseBuying()
+	assert.True(t, impl.buyingPaused.Load())
+
+	impl.Resume()
+	assert.False(t, impl.buyingPaused.Load())
+}
+
+func TestUseCaseImpl_Status(t *testing.T) {
+	impl := &useCaseImpl{monitor: &MockGiftMonitor{}}
+	assert.Equal(t, Status{}, impl.Status())
+
+	impl.setCounter(atomicCounter.NewAtomicCounterFrom(10, 4))
+	impl.PauseBuying()
+
+	assert.Equal(t, Status{BuyingPaused: true, Bought: 4, MaxBuyCount: 10}, impl.Status())
+}
+
+func TestUseCaseImpl_Reload(t *testing.T) {
+	t.Run("обновление критериев валидатора", func(t *testing.T) {
+		validator := giftValidator.NewGiftValidator(nil, config.GiftParam{TestMode: true})
+		impl := &useCaseImpl{validator: validator}
+		gift := &tg.StarGift{ID: 1, Stars: 500}
+
+		_, ok := validator.IsEligible(gift)
+		assert.False(t, ok)
+
+		err := impl.Reload(&config.SoftConfig{
+			Criterias: []config.Criterias{{MinPrice: 100, MaxPrice: 1000, Count: 1}},
+			GiftParam: config.GiftParam{TestMode: true},
+		})
+		assert.NoError(t, err)
+
+		_, ok = validator.IsEligible(gift)
+		assert.True(t, ok)
+	})
+
+	t.Run("валидатор без поддержки перезагрузки", func(t *testing.T) {
+		impl := &useCaseImpl{}
+		assert.Error(t, impl.Reload(&config.SoftConfig{}))
+	})
+
+	t.Run("обновление лимита покупок", func(t *testing.T) {
+		counter := atomicCounter.NewAtomicCounter(1)
+		impl := &useCaseImpl{validator: giftValidator.NewGiftValidator(nil, config.GiftParam{})}
+		impl.setCounter(counter)
+		assert.True(t, counter.TryIncrement())
+		assert.False(t, counter.TryIncrement())
+
+		assert.NoError(t, impl.Reload(&config.SoftConfig{MaxBuyCount: 2}))
+
+		assert.Equal(t, int64(2), counter.GetMax())
+		assert.True(t, counter.TryIncrement())
+	})
+
+	t.Run("сброс объявления о лимите", func(t *testing.T) {
+		announcer := &recordingAnnouncer{}
+		impl := &useCaseImpl{validator: giftValidator.NewGiftValidator(nil, config.GiftParam{})}
+		impl.setLimitAnnouncer(limitAnnouncers{announcer, announcer})
+
+		assert.NoError(t, impl.Reload(&config.SoftConfig{MaxBuyCount: 2}))
+
+		assert.Equal(t, 2, announcer.resets)
+	})
+}
+
+// recordingAnnouncer counts the resets of the limit announcement
+type recordingAnnouncer struct {
+	resets int
+}
+
+func (ra *recordingAnnouncer) ResetLimitReached() {
+	ra.resets++
+}
+
+func TestUseCaseImpl_HealthStatus(t *testing.T) {
+	t.Run("без счетчика и баланса", func(t *testing.T) {
+		impl := &useCaseImpl{monitor: &MockGiftMonitor{}}
+
+		status := impl.healthStatus(context.Background())
+
+		assert.False(t, status.Paused)
+		assert.Nil(t, status.Balance)
+		assert.Empty(t, status.LastError)
+		assert.True(t, status.LastErrorAt.IsZero())
+	})
+
+	t.Run("покупки, баланс, пауза и последняя ошибка", func(t *testing.T) {
+		counter := atomicCounter.NewAtomicCounterFrom(5, 2)
+		impl := &useCaseImpl{monitor: &MockGiftMonitor{}}
+		impl.setCounter(counter)
+		impl.setBalanceSource(&MockBalanceCache{})
+		impl.PauseBuying()
+		impl.recordError(assert.AnError)
+
+		status := impl.healthStatus(context.Background())
+
+		assert.True(t, status.BuyingPaused)
+		assert.Equal(t, int64(2), status.Purchases)
+		assert.Equal(t, int64(5), status.MaxPurchases)
+		if assert.NotNil(t, status.Balance) {
+			assert.Equal(t, int64(0), *status.Balance)
+		}
+		assert.Equal(t, assert.AnError.Error(), status.LastError)
+		assert.False(t, status.LastErrorAt.IsZero())
+	})
+}
+
+func TestSplitByAction(t *testing.T) {
+	tests := []struct {
+		name       string
+		skipNotify bool
+		skipBuy    bool
+		notified   bool
+		bought     bool
+	}{
+		{name: "уведомление и покупка", notified: true, bought: true},
+		{name: "только уведомление", skipBuy: true, notified: true},
+		{name: "только покупка", skipNotify: true, bought: true},
+		{name: "ни уведомления, ни покупки", skipNotify: true, skipBuy: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := &giftTypes.GiftRequire{
+				Gift:       &tg.StarGift{ID: 1},
+				SkipNotify: tt.skipNotify,
+				SkipBuy:    tt.skipBuy,
+			}
+			other := &giftTypes.GiftRequire{Gift: &tg.StarGift{ID: 2}}
+
+			toNotify, toBuy := splitByAction([]*giftTypes.GiftRequire{require, other})
+
+			assert.Equal(t, tt.notified, containsRequire(toNotify, require))
+			assert.Equal(t, tt.bought, containsRequire(toBuy, require))
+			assert.True(t, containsRequire(toNotify, other))
+			assert.True(t, containsRequire(toBuy, other))
+		})
+	}
+}
+
+func containsRequire(gifts []*giftTypes.GiftRequire, require *giftTypes.GiftRequire) bool {
+	for _, gift := range gifts {
+		if gift == require {
+			return true
+		}
+	}
+	return false
+}
+
+func TestUseCaseImpl_AutoUpdate(t *testing.T) {
+	newService := func(gitVersion *MockGitVersionController) *useCaseImpl {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		return NewUseCase(nil, nil, nil, &MockNotificationService{}, nil, nil, ctx, cancel, nil, nil, gitVersion, nil, nil).(*useCaseImpl)
+	}
+	release := &gittypes.GitHubRelease{TagName: "v1.1.0", Body: "New version available"}
+
+	t.Run("без автообновления только уведомление", func(t *testing.T) {
+		gitVersion := &MockGitVersionController{currentVersion: "1.0.0", latestVersion: release, compareResult: true}
+		service := newService(gitVersion)
+
+		assert.NoError(t, service.checkNewUpdates())
+		assert.Equal(t, 0, gitVersion.downloads)
+	})
+
+	t.Run("ошибка загрузки не запрашивает перезапуск", func(t *testing.T) {
+		gitVersion := &MockGitVersionController{currentVersion: "1.0.0", latestVersion: release, compareResult: true, downloadErr: assert.AnError}
+		service := newService(gitVersion)
+		service.setAutoUpdate("gift-buyer-linux-amd64")
+
+		err := service.checkNewUpdates()
+
+		assert.ErrorContains(t, err, "failed to download update")
+		assert.Equal(t, 1, gitVersion.downloads)
+		assert.Empty(t, service.stagedVersion)
+		select {
+		case <-service.RestartRequested():
+			t.Fatal("restart requested after a failed update")
+		default:
+		}
+	})
+
+	t.Run("нет новой версии", func(t *testing.T) {
+		gitVersion := &MockGitVersionController{currentVersion: "1.1.0", latestVersion: release}
+		service := newService(gitVersion)
+		service.setAutoUpdate("gift-buyer-linux-amd64")
+
+		assert.NoError(t, service.checkNewUpdates())
+		assert.Equal(t, 0, gitVersion.downloads)
+	})
+}
+
+func TestStageBinary(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "gift-buyer")
+	downloaded := filepath.Join(dir, "download")
+	require.NoError(t, os.WriteFile(target, []byte("old binary"), 0755))
+	require.NoError(t, os.WriteFile(downloaded, []byte("new binary"), 0600))
+
+	require.NoError(t, stageBinary(downloaded, target))
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "new binary", string(data))
+
+	backup, err := os.ReadFile(target + ".old")
+	require.NoError(t, err)
+	assert.Equal(t, "old binary", string(backup))
+
+	_, err = os.Stat(target + ".new")
+	assert.True(t, os.IsNotExist(err))
+
+	t.Run("отсутствующий файл не трогает исполняемый", func(t *testing.T) {
+		assert.Error(t, stageBinary(filepath.Join(dir, "missing"), target))
+
+		data, err := os.ReadFile(target)
+		require.NoError(t, err)
+		assert.Equal(t, "new binary", string(data))
+	})
+}
@@ -3,10 +3,19 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"gift-buyer/internal/config"
 	"gift-buyer/internal/infrastructure/gitVersion/gitInterfaces"
+	gittypes "gift-buyer/internal/infrastructure/gitVersion/gitTypes"
+	"gift-buyer/internal/infrastructure/heartbeat"
 	"gift-buyer/internal/service/giftService/giftInterfaces"
+	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
 	"gift-buyer/pkg/logger"
+	"io"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gotd/td/tg"
@@ -25,8 +34,145 @@ type UseCase interface {
 	// SetIds sets the IDs of the accounts
 	SetIds(ctx context.Context) error
 
-	// CheckForUpdates checks for updates and sends a notification if available
+	// CheckForUpdates checks for updates and sends a notification if available.
+	// With auto update enabled the new version is also installed.
 	CheckForUpdates()
+
+	// RestartRequested returns a channel closed once an installed update needs a restart.
+	RestartRequested() <-chan struct{}
+
+	// StopRequested returns a channel closed once a stop is requested through the gRPC API.
+	StopRequested() <-chan struct{}
+
+	// Done returns a channel closed once the service stops on its own, e.g. after
+	// reconnecting to Telegram failed.
+	Done() <-chan struct{}
+
+	// Pause pauses gift monitoring and buying.
+	Pause()
+
+	// Resume resumes gift monitoring and buying.
+	Resume()
+
+	// PauseBuying keeps monitoring and notifications running but stops buying new gifts.
+	PauseBuying()
+
+	// Reload applies the criteria, gift parameters and MaxBuyCount of the reloaded configuration.
+	Reload(cfg *config.SoftConfig) error
+
+	// Status returns the pause state and the purchase count of the service.
+	Status() Status
+}
+
+// Status is a snapshot of the pause state and the purchase count of the service.
+type Status struct {
+	// Paused reports whether gift monitoring is paused
+	Paused bool
+
+	// BuyingPaused reports whether buying is paused while monitoring continues
+	BuyingPaused bool
+
+	// Bought is the number of gifts bought in the current session
+	Bought int64
+
+	// MaxBuyCount is the purchase limit
+	MaxBuyCount int64
+}
+
+// reloadableValidator is implemented by validators that support updating criteria at runtime
+type reloadableValidator interface {
+	Update(criterias []config.Criterias, giftParam config.GiftParam)
+}
+
+// resizableCounter is implemented by purchase counters whose maximum can be changed at runtime
+type resizableCounter interface {
+	Get() int64
+	GetMax() int64
+	SetMax(max int64)
+}
+
+// balanceSource provides the stars balance reported in heartbeats
+type balanceSource interface {
+	RefreshBalance(ctx context.Context) error
+	GetBalance() int64
+}
+
+// paymentWarmer is implemented by payment processors that can prepare the purchase path in advance
+type paymentWarmer interface {
+	Warm(giftIDs []int64) error
+}
+
+// eventPublisher receives the gift and purchase events streamed by the gRPC API
+type eventPublisher interface {
+	GiftFound(giftID, stars int64)
+	PurchaseResult(giftID, stars int64, err error)
+}
+
+// limitAnnouncer announces the purchase count cap once and can be re-armed
+type limitAnnouncer interface {
+	ResetLimitReached()
+}
+
+// sessionReporter collects the stats of the session and writes them as a report at shutdown
+type sessionReporter interface {
+	RecordSeen(count int)
+	WriteReport(path string) error
+}
+
+// accountManagers resolves the receivers in the session of every account
+type accountManagers []giftInterfaces.AccountManager
+
+// SetIds resolves the receivers of every account, stopping at the first failure.
+func (am accountManagers) SetIds(ctx context.Context) error {
+	for _, manager := range am {
+		if err := manager.SetIds(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// paymentWarmers prepares the purchase path in the session of every account
+type paymentWarmers []paymentWarmer
+
+// Warm prepares the purchase path of every account, stopping at the first failure.
+func (pw paymentWarmers) Warm(giftIDs []int64) error {
+	for _, warmer := range pw {
+		if err := warmer.Warm(giftIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitAnnouncers re-arms the limit announcement of every account
+type limitAnnouncers []limitAnnouncer
+
+// ResetLimitReached re-arms the limit announcement of every account.
+func (la limitAnnouncers) ResetLimitReached() {
+	for _, announcer := range la {
+		announcer.ResetLimitReached()
+	}
+}
+
+// apiMonitor reports the last successful API check of one account
+type apiMonitor interface {
+	LastApiSuccess() time.Time
+}
+
+// apiMonitors reports the API health of every account
+type apiMonitors []apiMonitor
+
+// LastApiSuccess returns the oldest last successful API check of all accounts, so the
+// service is reported unhealthy as soon as any account loses its connection.
+func (am apiMonitors) LastApiSuccess() time.Time {
+	var oldest time.Time
+	for i, monitor := range am {
+		if last := monitor.LastApiSuccess(); i == 0 || last.Before(oldest) {
+			oldest = last
+		}
+	}
+	return oldest
 }
 
 // useCaseImpl implements the UseCase interface and orchestrates all gift buying operations.
@@ -71,6 +217,57 @@ type useCaseImpl struct {
 	updateTicker            *time.Ticker
 	lastNotificationVersion string
 	subFlag                 bool
+
+	// updateAsset is the release asset installed by CheckForUpdates, empty disables auto update
+	updateAsset string
+
+	// stagedVersion is the release tag already installed and waiting for the restart
+	stagedVersion string
+
+	// restartCh is closed once an installed update needs a restart
+	restartCh   chan struct{}
+	restartOnce sync.Once
+
+	// stopCh is closed once a stop is requested through the gRPC API
+	stopCh   chan struct{}
+	stopOnce sync.Once
+
+	// sessionState prevents repeated new gift notifications across restarts (optional)
+	sessionState giftInterfaces.SessionState
+
+	// buyingPaused stops buying discovered gifts while monitoring continues
+	buyingPaused atomic.Bool
+
+	// monitorRunning reports whether the monitoring loop of Start is running
+	monitorRunning atomic.Bool
+
+	// warmer prepares the purchase path of warmGiftIDs once the receivers are resolved (optional)
+	warmer      paymentWarmer
+	warmGiftIDs []int64
+
+	// counter receives the reloaded MaxBuyCount (optional)
+	counter resizableCounter
+
+	// limitAnnouncer is re-armed on reload so the reloaded cap is announced again (optional)
+	limitAnnouncer limitAnnouncer
+
+	// balance provides the stars balance reported in heartbeats (optional)
+	balance balanceSource
+
+	// discoveryWebhook receives every batch of newly discovered eligible gifts (optional)
+	discoveryWebhook *webhookClient
+
+	// events receives every newly discovered eligible gift (optional)
+	events eventPublisher
+
+	// sessionReport is written to sessionReportFile when the service stops (optional)
+	sessionReport     sessionReporter
+	sessionReportFile string
+
+	// lastError and lastErrorAt describe the last failed gift check, reported in heartbeats
+	lastError   string
+	lastErrorAt time.Time
+	errMu       sync.Mutex
 }
 
 // NewUseCase creates a new UseCase instance with all required dependencies.
@@ -86,6 +283,7 @@ type useCaseImpl struct {
 //   - ctx: context for cancellation control
 //   - cancel: cancel function for graceful shutdown
 //   - api: Telegram API client
+//   - sessionState: persisted session state for notification deduplication (nil to disable)
 //
 // Returns:
 //   - GiftService: configured gift service ready for operation
@@ -102,6 +300,7 @@ func NewUseCase(
 	accountManager giftInterfaces.AccountManager,
 	gitVersion gitInterfaces.GitVersionController,
 	updateTicker *time.Ticker,
+	sessionState giftInterfaces.SessionState,
 ) UseCase {
 	return &useCaseImpl{
 		manager:        manager,
@@ -117,6 +316,9 @@ func NewUseCase(
 		gitVersion:     gitVersion,
 		updateTicker:   updateTicker,
 		subFlag:        false,
+		sessionState:   sessionState,
+		restartCh:      make(chan struct{}),
+		stopCh:         make(chan struct{}),
 	}
 }
 
@@ -133,6 +335,9 @@ func NewUseCase(
 //
 // This method blocks until the service is stopped or context is cancelled.
 func (tc *useCaseImpl) Start() {
+	tc.monitorRunning.Store(true)
+	defer tc.monitorRunning.Store(false)
+
 	for {
 		select {
 		case <-tc.ctx.Done():
@@ -147,23 +352,44 @@ func (tc *useCaseImpl) Start() {
 					return
 				}
 				logger.GlobalLogger.Error("Error checking for new gifts", "error", err)
+				tc.recordError(err)
 				continue
 			}
 
 			if len(newGifts) > 0 {
 				logger.GlobalLogger.Infof("Found %d new gift types to process", len(newGifts))
+				if tc.sessionReport != nil {
+					tc.sessionReport.RecordSeen(len(newGifts))
+				}
+				tc.forwardDiscovered(newGifts)
+				tc.publishDiscovered(newGifts)
+				toNotify, toBuy := splitByAction(newGifts)
 				tc.wg.Add(2)
 				go func() {
 					defer tc.wg.Done()
-					for _, require := range newGifts {
+					for _, require := range toNotify {
+						if tc.sessionState != nil && tc.sessionState.IsNotified(require.Gift.ID) {
+							continue
+						}
 						if err := tc.notification.SendNewGiftNotification(tc.ctx, require.Gift); err != nil {
 							logger.GlobalLogger.Errorf("Error sending notification: %v, gift_id: %d, count: %d", err, require.Gift.ID, require.CountForBuy)
+							continue
+						}
+						if tc.sessionState != nil {
+							tc.sessionState.MarkNotified(require.Gift.ID)
 						}
 					}
 				}()
 				go func() {
 					defer tc.wg.Done()
-					tc.buyer.BuyGift(tc.ctx, newGifts)
+					if len(toBuy) == 0 {
+						return
+					}
+					if tc.buyingPaused.Load() {
+						logger.GlobalLogger.Infof("Buying is paused, skipping %d gift types", len(toBuy))
+						return
+					}
+					tc.buyer.BuyGift(tc.ctx, toBuy)
 				}()
 
 				continue
@@ -172,9 +398,72 @@ func (tc *useCaseImpl) Start() {
 	}
 }
 
+// forwardDiscovered posts the discovered gifts to the discovery webhook in the
+// background, so a slow endpoint never delays buying.
+//
+// Parameters:
+//   - gifts: newly discovered eligible gifts
+func (tc *useCaseImpl) forwardDiscovered(gifts []*giftTypes.GiftRequire) {
+	if tc.discoveryWebhook == nil {
+		return
+	}
+
+	tc.wg.Add(1)
+	go func() {
+		defer tc.wg.Done()
+		if err := tc.discoveryWebhook.send(tc.ctx, gifts); err != nil && tc.ctx.Err() == nil {
+			logger.GlobalLogger.Warnf("Failed to forward %d discovered gifts to webhook: %v", len(gifts), err)
+		}
+	}()
+}
+
+// publishDiscovered publishes every discovered gift once to the event stream, a gift
+// may be split into several entries, e.g. one per receiver type.
+//
+// Parameters:
+//   - gifts: newly discovered eligible gifts
+func (tc *useCaseImpl) publishDiscovered(gifts []*giftTypes.GiftRequire) {
+	if tc.events == nil {
+		return
+	}
+
+	published := make(map[int64]bool, len(gifts))
+	for _, require := range gifts {
+		if published[require.Gift.ID] {
+			continue
+		}
+		published[require.Gift.ID] = true
+		tc.events.GiftFound(require.Gift.ID, require.Gift.Stars)
+	}
+}
+
+// splitByAction splits the discovered gifts into the ones to notify about and the
+// ones to buy, following the notify and buy flags of their matched criteria.
+//
+// Parameters:
+//   - gifts: newly discovered eligible gifts
+//
+// Returns:
+//   - []*giftTypes.GiftRequire: gifts to send a new gift notification for
+//   - []*giftTypes.GiftRequire: gifts to buy
+func splitByAction(gifts []*giftTypes.GiftRequire) ([]*giftTypes.GiftRequire, []*giftTypes.GiftRequire) {
+	toNotify := make([]*giftTypes.GiftRequire, 0, len(gifts))
+	toBuy := make([]*giftTypes.GiftRequire, 0, len(gifts))
+	for _, require := range gifts {
+		if !require.SkipNotify {
+			toNotify = append(toNotify, require)
+		}
+		if !require.SkipBuy {
+			toBuy = append(toBuy, require)
+		}
+	}
+	return toNotify, toBuy
+}
+
 // Stop gracefully shuts down the gift service.
 // It cancels the service context and waits for all goroutines to complete
-// before returning, ensuring clean shutdown of all components.
+// before returning, ensuring clean shutdown of all components. The session
+// report, if enabled, is written last so it includes the drained purchases.
 func (tc *useCaseImpl) Stop() {
 	if tc.cancel != nil {
 		tc.cancel()
@@ -184,10 +473,197 @@ func (tc *useCaseImpl) Stop() {
 	if tc.buyer != nil {
 		tc.buyer.Close()
 	}
+
+	if tc.notification != nil {
+		tc.notification.Close()
+	}
+
+	if tc.sessionReport != nil {
+		if err := tc.sessionReport.WriteReport(tc.sessionReportFile); err != nil {
+			logger.GlobalLogger.Errorf("Failed to write session report: %v", err)
+		} else {
+			logger.GlobalLogger.Infof("Session report written to %s", tc.sessionReportFile)
+		}
+	}
+}
+
+// Pause pauses gift monitoring, which also stops buying new gifts.
+func (tc *useCaseImpl) Pause() {
+	tc.monitor.Pause()
 }
 
+// Resume resumes gift monitoring and buying.
+func (tc *useCaseImpl) Resume() {
+	tc.buyingPaused.Store(false)
+	tc.monitor.Resume()
+}
+
+// PauseBuying stops buying discovered gifts while monitoring and notifications continue.
+func (tc *useCaseImpl) PauseBuying() {
+	tc.buyingPaused.Store(true)
+	logger.GlobalLogger.Info("Gift buying paused")
+}
+
+// Status returns the pause state and the purchase count of the service.
+func (tc *useCaseImpl) Status() Status {
+	status := Status{
+		Paused:       tc.monitor != nil && tc.monitor.IsPaused(),
+		BuyingPaused: tc.buyingPaused.Load(),
+	}
+	if tc.counter != nil {
+		status.Bought = tc.counter.Get()
+		status.MaxBuyCount = tc.counter.GetMax()
+	}
+	return status
+}
+
+// Reload applies the criteria and gift parameters of the reloaded configuration
+// to the validator and MaxBuyCount to the purchase counter, and re-arms the limit
+// reached announcement for the reloaded cap. Other settings require a restart.
+//
+// Parameters:
+//   - cfg: reloaded configuration
+//
+// Returns:
+//   - error: if the validator doesn't support reloading
+func (tc *useCaseImpl) Reload(cfg *config.SoftConfig) error {
+	validator, ok := tc.validator.(reloadableValidator)
+	if !ok {
+		return errors.New("validator does not support reloading")
+	}
+
+	validator.Update(cfg.Criterias, cfg.GiftParam)
+	if tc.counter != nil {
+		tc.counter.SetMax(cfg.MaxBuyCount)
+	}
+	if tc.limitAnnouncer != nil {
+		tc.limitAnnouncer.ResetLimitReached()
+	}
+	logger.GlobalLogger.Infof("Configuration reloaded: %d criterias, max buy count %d", len(cfg.Criterias), cfg.MaxBuyCount)
+	return nil
+}
+
+// SetIds resolves the receiver accounts and then prepares the purchase path of the
+// anticipated gifts. A failed preparation is logged and only costs the warm path.
 func (tc *useCaseImpl) SetIds(ctx context.Context) error {
-	return tc.accountManager.SetIds(ctx)
+	if err := tc.accountManager.SetIds(ctx); err != nil {
+		return err
+	}
+
+	if tc.warmer != nil && len(tc.warmGiftIDs) > 0 {
+		if err := tc.warmer.Warm(tc.warmGiftIDs); err != nil {
+			logger.GlobalLogger.Warnf("Failed to warm purchase path: %v", err)
+		} else {
+			logger.GlobalLogger.Infof("Purchase path warmed for %d gifts", len(tc.warmGiftIDs))
+		}
+	}
+	return nil
+}
+
+// recordError remembers the error as the last one reported in heartbeats.
+func (tc *useCaseImpl) recordError(err error) {
+	tc.errMu.Lock()
+	defer tc.errMu.Unlock()
+	tc.lastError = err.Error()
+	tc.lastErrorAt = time.Now()
+}
+
+// healthStatus reports the current health of the service for heartbeats. The balance
+// is refreshed on every call and left out when it can't be fetched.
+func (tc *useCaseImpl) healthStatus(ctx context.Context) heartbeat.Status {
+	current := tc.Status()
+	status := heartbeat.Status{
+		Paused:       current.Paused,
+		BuyingPaused: current.BuyingPaused,
+		Purchases:    current.Bought,
+		MaxPurchases: current.MaxBuyCount,
+	}
+
+	if tc.balance != nil {
+		if err := tc.balance.RefreshBalance(ctx); err != nil {
+			logger.GlobalLogger.Warnf("Failed to refresh balance for heartbeat: %v", err)
+		} else {
+			balance := tc.balance.GetBalance()
+			status.Balance = &balance
+		}
+	}
+
+	tc.errMu.Lock()
+	status.LastError, status.LastErrorAt = tc.lastError, tc.lastErrorAt
+	tc.errMu.Unlock()
+
+	return status
+}
+
+// isMonitorRunning reports whether the monitoring loop is running, for health probes.
+func (tc *useCaseImpl) isMonitorRunning() bool {
+	return tc.monitorRunning.Load()
+}
+
+// setBalanceSource sets the source of the stars balance reported in heartbeats.
+func (tc *useCaseImpl) setBalanceSource(balance balanceSource) {
+	tc.balance = balance
+}
+
+// setDiscoveryWebhook sets the webhook receiving the newly discovered eligible gifts.
+func (tc *useCaseImpl) setDiscoveryWebhook(webhook *webhookClient) {
+	tc.discoveryWebhook = webhook
+}
+
+// setEventPublisher sets the event stream receiving the newly discovered eligible gifts.
+func (tc *useCaseImpl) setEventPublisher(events eventPublisher) {
+	tc.events = events
+}
+
+// setSessionReport enables the session report written to path when the service stops.
+func (tc *useCaseImpl) setSessionReport(report sessionReporter, path string) {
+	tc.sessionReport = report
+	tc.sessionReportFile = path
+}
+
+// setCounter sets the purchase counter receiving the reloaded MaxBuyCount.
+func (tc *useCaseImpl) setCounter(counter resizableCounter) {
+	tc.counter = counter
+}
+
+// setLimitAnnouncer sets the limit announcement re-armed on reload.
+func (tc *useCaseImpl) setLimitAnnouncer(announcer limitAnnouncer) {
+	tc.limitAnnouncer = announcer
+}
+
+// setPaymentWarmer enables preparing the purchase path of the anticipated gifts in SetIds.
+func (tc *useCaseImpl) setPaymentWarmer(warmer paymentWarmer, giftIDs []int64) {
+	tc.warmer = warmer
+	tc.warmGiftIDs = giftIDs
+}
+
+// setAutoUpdate enables installing new releases in CheckForUpdates.
+//
+// Parameters:
+//   - assetName: release asset of the running platform
+func (tc *useCaseImpl) setAutoUpdate(assetName string) {
+	tc.updateAsset = assetName
+}
+
+// RestartRequested returns a channel closed once an installed update needs a restart.
+// Without auto update the channel is never closed.
+func (tc *useCaseImpl) RestartRequested() <-chan struct{} {
+	return tc.restartCh
+}
+
+// StopRequested returns a channel closed once a stop is requested through the gRPC API.
+func (tc *useCaseImpl) StopRequested() <-chan struct{} {
+	return tc.stopCh
+}
+
+// requestStop asks the application to stop gracefully, the same way as a termination signal.
+func (tc *useCaseImpl) requestStop() {
+	tc.stopOnce.Do(func() { close(tc.stopCh) })
+}
+
+// Done returns a channel closed once the service context is cancelled.
+func (tc *useCaseImpl) Done() <-chan struct{} {
+	return tc.ctx.Done()
 }
 
 func (tc *useCaseImpl) CheckForUpdates() {
@@ -231,5 +707,97 @@ func (tc *useCaseImpl) checkNewUpdates() error {
 		}
 		tc.lastNotificationVersion = remoteVersion.TagName
 	}
+
+	if ok && tc.updateAsset != "" && tc.stagedVersion != remoteVersion.TagName {
+		if err := tc.installUpdate(remoteVersion); err != nil {
+			logger.GlobalLogger.Errorf("Error installing update: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// installUpdate downloads the release asset of the running platform, replaces the
+// running executable with it and requests a restart.
+//
+// Parameters:
+//   - release: the release to install
+//
+// Returns:
+//   - error: download, checksum or replacement error, the running binary is kept
+func (tc *useCaseImpl) installUpdate(release *gittypes.GitHubRelease) error {
+	downloaded, err := tc.gitVersion.DownloadAsset(release, tc.updateAsset)
+	if err != nil {
+		return errors.Wrap(err, "failed to download update")
+	}
+	defer os.Remove(downloaded)
+
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "failed to locate executable")
+	}
+	if err := stageBinary(downloaded, executable); err != nil {
+		return errors.Wrap(err, "failed to replace executable")
+	}
+
+	tc.stagedVersion = release.TagName
+	logger.GlobalLogger.Infof("Update %s installed, restarting", release.TagName)
+	tc.restartOnce.Do(func() { close(tc.restartCh) })
+	return nil
+}
+
+// stageBinary replaces the target executable with the downloaded binary. The binary is
+// copied next to the target first so that the final rename stays on one file system,
+// and the replaced executable is kept as "<target>.old". A running executable can be
+// renamed on every supported platform, the process keeps running the old file until
+// it restarts.
+//
+// Parameters:
+//   - downloaded: path of the verified new binary
+//   - target: path of the executable to replace
+//
+// Returns:
+//   - error: copy or rename error, the target is restored when the swap fails
+func stageBinary(downloaded, target string) error {
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+
+	staged := target + ".new"
+	if err := copyFile(downloaded, staged); err != nil {
+		os.Remove(staged)
+		return err
+	}
+
+	backup := target + ".old"
+	os.Remove(backup)
+	if err := os.Rename(target, backup); err != nil {
+		os.Remove(staged)
+		return err
+	}
+	if err := os.Rename(staged, target); err != nil {
+		os.Rename(backup, target)
+		os.Remove(staged)
+		return err
+	}
 	return nil
 }
+
+// copyFile copies src to a new executable file dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
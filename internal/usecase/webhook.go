@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gift-buyer/internal/service/giftService/giftTypes"
+	"gift-buyer/pkg/errors"
+	"net/http"
+	"time"
+)
+
+const (
+	// webhookTimeout bounds a single discovery webhook request
+	webhookTimeout = 10 * time.Second
+
+	// webhookAttempts is the number of attempts to deliver a discovery webhook
+	webhookAttempts = 3
+
+	// webhookRetryDelay is the delay before the first retry, doubled on every next one
+	webhookRetryDelay = time.Second
+)
+
+// giftSummary is the description of a discovered gift sent to the discovery webhook.
+type giftSummary struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+
+	// Price is the price of the gift in stars
+	Price int64 `json:"price"`
+
+	// Supply is the total supply of a limited gift, 0 for unlimited gifts
+	Supply int `json:"supply"`
+
+	// CountForBuy is the number of units the matched criteria buy
+	CountForBuy int64 `json:"count_for_buy"`
+}
+
+// webhookClient posts the newly discovered eligible gifts to an external endpoint.
+type webhookClient struct {
+	// url receives the discovered gifts
+	url string
+
+	// client sends the requests, its timeout bounds every attempt
+	client *http.Client
+
+	// attempts is the number of delivery attempts, retryDelay the delay before the first retry
+	attempts   int
+	retryDelay time.Duration
+}
+
+// newWebhookClient creates a discovery webhook client.
+//
+// Parameters:
+//   - url: URL the discovered gifts are posted to
+//
+// Returns:
+//   - *webhookClient: client retrying failed deliveries
+func newWebhookClient(url string) *webhookClient {
+	return &webhookClient{
+		url:        url,
+		client:     &http.Client{Timeout: webhookTimeout},
+		attempts:   webhookAttempts,
+		retryDelay: webhookRetryDelay,
+	}
+}
+
+// send posts the gifts as a JSON array of summaries. Failed deliveries are retried with
+// a doubling delay, except for 4xx responses that won't succeed on a retry.
+//
+// Parameters:
+//   - ctx: context cancelling the delivery and its retries
+//   - gifts: newly discovered eligible gifts
+//
+// Returns:
+//   - error: encoding error or the error of the last attempt
+func (w *webhookClient) send(ctx context.Context, gifts []*giftTypes.GiftRequire) error {
+	summaries := make([]giftSummary, 0, len(gifts))
+	for _, require := range gifts {
+		title, _ := require.Gift.GetTitle()
+		supply, _ := require.Gift.GetAvailabilityTotal()
+		summaries = append(summaries, giftSummary{
+			ID:          require.Gift.ID,
+			Title:       title,
+			Price:       require.Gift.Stars,
+			Supply:      supply,
+			CountForBuy: require.CountForBuy,
+		})
+	}
+	body, err := json.Marshal(summaries)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode discovered gifts")
+	}
+
+	delay := w.retryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post performs a single delivery attempt.
+//
+// Returns:
+//   - bool: whether a failed attempt is worth retrying
+//   - error: network error or non-2xx response
+func (w *webhookClient) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to create discovery webhook request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, errors.Wrap(err, "failed to send discovery webhook")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, errors.New(fmt.Sprintf("discovery webhook returned status %d", resp.StatusCode))
+	}
+	return true, nil
+}
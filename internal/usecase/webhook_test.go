@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gift-buyer/internal/service/giftService/giftTypes"
+
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookEndpoint starts a server answering with the given statuses in order, then 200,
+// and capturing the bodies of the requests
+func webhookEndpoint(t *testing.T, statuses ...int) (*httptest.Server, *int32, chan []map[string]interface{}) {
+	t.Helper()
+
+	var calls int32
+	bodies := make(chan []map[string]interface{}, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var body []map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies <- body
+
+		if call := atomic.AddInt32(&calls, 1); int(call) <= len(statuses) {
+			w.WriteHeader(statuses[call-1])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls, bodies
+}
+
+func testWebhook(url string) *webhookClient {
+	webhook := newWebhookClient(url)
+	webhook.retryDelay = time.Millisecond
+	return webhook
+}
+
+func discoveredGifts() []*giftTypes.GiftRequire {
+	limited := &tg.StarGift{ID: 1, Stars: 5000, Limited: true}
+	limited.SetTitle("Rocket")
+	limited.SetAvailabilityTotal(10000)
+	limited.SetAvailabilityRemains(120)
+
+	return []*giftTypes.GiftRequire{
+		{Gift: limited, CountForBuy: 3},
+		{Gift: &tg.StarGift{ID: 2, Stars: 15}, CountForBuy: 1},
+	}
+}
+
+func TestWebhookClient_Send(t *testing.T) {
+	t.Run("сводка подарков в теле запроса", func(t *testing.T) {
+		server, _, bodies := webhookEndpoint(t)
+
+		require.NoError(t, testWebhook(server.URL).send(context.Background(), discoveredGifts()))
+
+		assert.Equal(t, []map[string]interface{}{
+			{"id": float64(1), "title": "Rocket", "price": float64(5000), "supply": float64(10000), "count_for_buy": float64(3)},
+			{"id": float64(2), "title": "", "price": float64(15), "supply": float64(0), "count_for_buy": float64(1)},
+		}, <-bodies)
+	})
+
+	t.Run("повтор после ошибки сервера", func(t *testing.T) {
+		server, calls, _ := webhookEndpoint(t, http.StatusBadGateway, http.StatusTooManyRequests)
+
+		assert.NoError(t, testWebhook(server.URL).send(context.Background(), discoveredGifts()))
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+	})
+
+	t.Run("попытки заканчиваются", func(t *testing.T) {
+		server, calls, _ := webhookEndpoint(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+
+		err := testWebhook(server.URL).send(context.Background(), discoveredGifts())
+
+		assert.ErrorContains(t, err, "status 500")
+		assert.Equal(t, int32(webhookAttempts), atomic.LoadInt32(calls))
+	})
+
+	t.Run("ошибка клиента не повторяется", func(t *testing.T) {
+		server, calls, _ := webhookEndpoint(t, http.StatusBadRequest)
+
+		err := testWebhook(server.URL).send(context.Background(), discoveredGifts())
+
+		assert.ErrorContains(t, err, "status 400")
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+
+	t.Run("таймаут медленного сервера", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		webhook := testWebhook(server.URL)
+		webhook.client.Timeout = 20 * time.Millisecond
+		webhook.attempts = 1
+
+		start := time.Now()
+		assert.Error(t, webhook.send(context.Background(), discoveredGifts()))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestUseCaseImpl_ForwardDiscovered(t *testing.T) {
+	t.Run("медленный сервер не блокирует обработку", func(t *testing.T) {
+		release := make(chan struct{})
+		received := make(chan struct{}, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received <- struct{}{}
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, nil, nil).(*useCaseImpl)
+		service.setDiscoveryWebhook(testWebhook(server.URL))
+
+		start := time.Now()
+		service.forwardDiscovered(discoveredGifts())
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+		<-received
+		// Stopping the service cancels the pending delivery
+		cancel()
+		service.wg.Wait()
+	})
+
+	t.Run("без вебхука ничего не отправляется", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		service := NewUseCase(nil, nil, nil, nil, nil, nil, ctx, cancel, nil, nil, nil, nil, nil).(*useCaseImpl)
+
+		assert.NotPanics(t, func() { service.forwardDiscovered(discoveredGifts()) })
+		service.wg.Wait()
+	})
+}
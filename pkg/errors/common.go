@@ -88,6 +88,54 @@ var (
 	// Used when system components fail to initialize properly.
 	ErrFailedInit = New("failed to initialize")
 
+	// ErrAllReceiversSaturated indicates that every receiver reached its per-receiver cap.
+	// Used when no receiver can accept another unit of a gift.
+	ErrAllReceiversSaturated = New("all receivers saturated")
+
+	// ErrBalanceReserveReached indicates that a purchase would spend the balance reserve.
+	// Used when buying a gift would bring the stars balance below the configured reserve.
+	ErrBalanceReserveReached = New("balance reserve reached")
+
+	// ErrAvailabilityBelowThreshold indicates that too few gifts remain at buy time.
+	// Used when the live availability of a limited gift dropped below the configured minimum.
+	ErrAvailabilityBelowThreshold = New("availability below threshold")
+
+	// ErrNoLongerEligible indicates that a gift stopped matching the criteria before purchase.
+	// Used when the live gift data re-checked right before buying fails the criteria.
+	ErrNoLongerEligible = New("gift no longer eligible")
+
+	// ErrGiftCapReached indicates that the per-gift purchase cap of a gift is reached.
+	// Used when every unit of a gift allowed by its criteria Count was already bought.
+	ErrGiftCapReached = New("gift cap reached")
+
+	// ErrInsufficientBalance indicates that the stars balance can't cover a purchase.
+	// Used when the cached balance is lower than the gift price.
+	ErrInsufficientBalance = New("insufficient balance")
+
+	// ErrBatchTimeout indicates that a purchase batch ran out of time.
+	// Used to abort the remaining attempts of a batch that exceeded the configured timeout.
+	ErrBatchTimeout = New("purchase batch timed out")
+
+	// ErrBatchBelowThreshold indicates that a purchase batch bought too few gifts.
+	// Used when the share of bought gifts is below the configured success threshold.
+	ErrBatchBelowThreshold = New("batch below success threshold")
+
+	// ErrReceiverNotResolved indicates that a configured receiver can't be resolved.
+	// Used when neither users.getUsers nor contacts.resolveUsername returns the receiver.
+	ErrReceiverNotResolved = New("receiver not found or inaccessible")
+
+	// ErrGiftFetchTimeout indicates that the gift catalog request took too long.
+	// Used when a single getStarGifts call exceeds the configured fetch timeout.
+	ErrGiftFetchTimeout = New("gift catalog request timed out")
+
+	// ErrCircuitOpen indicates that purchases are paused after repeated payment failures.
+	// Used when the purchase circuit breaker rejects a call during its cooldown.
+	ErrCircuitOpen = New("purchases paused after repeated payment failures")
+
+	// ErrPurchaseUnconfirmed indicates that a payment went through but the bought gift
+	// didn't show up in time. Used to stop retries, which could buy the gift twice.
+	ErrPurchaseUnconfirmed = New("purchase paid but not confirmed")
+
 	// Blockchain and transaction errors
 
 	// ErrGasEstimation indicates failure to estimate transaction gas.
@@ -241,3 +289,16 @@ func Wrap(err error, context string) error {
 	}
 	return fmt.Errorf("%s: %w", context, err)
 }
+
+// Is reports whether any error in err's chain matches target.
+// This is a convenience wrapper around the standard errors.Is function.
+//
+// Parameters:
+//   - err: the error to inspect
+//   - target: the error to look for
+//
+// Returns:
+//   - bool: true if target is found in the error chain
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
@@ -110,6 +110,8 @@ func TestPredefinedErrors(t *testing.T) {
 		{"ErrConfigParse", ErrConfigParse, "failed to parse config"},
 		{"ErrConfigSave", ErrConfigSave, "failed to save config"},
 		{"ErrInvalidConfig", ErrInvalidConfig, "invalid configuration"},
+		{"ErrAllReceiversSaturated", ErrAllReceiversSaturated, "all receivers saturated"},
+		{"ErrBalanceReserveReached", ErrBalanceReserveReached, "balance reserve reached"},
 	}
 
 	for _, tt := range tests {
@@ -139,3 +141,11 @@ func TestMultipleWrapping(t *testing.T) {
 	assert.True(t, errors.Is(secondWrap, baseErr))
 	assert.True(t, errors.Is(secondWrap, firstWrap))
 }
+
+func TestIs(t *testing.T) {
+	wrapped := Wrap(Wrap(ErrAllReceiversSaturated, "failed to create invoice"), "failed to send stars form")
+
+	assert.True(t, Is(wrapped, ErrAllReceiversSaturated))
+	assert.False(t, Is(wrapped, ErrNotFound))
+	assert.False(t, Is(nil, ErrNotFound))
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"io"
 	"os"
 	"strings"
@@ -234,6 +235,53 @@ func (l logger) WithFields(fields Fields) Logger {
 	}
 }
 
+// RequestIDField is the log field carrying the ID of a single purchase attempt.
+const RequestIDField = "request_id"
+
+// WithRequestID returns a logger derived from the global logger that adds the
+// request ID to every message, so all log lines of one purchase attempt can be
+// correlated.
+//
+// Parameters:
+//   - id: request ID of the purchase attempt
+//
+// Returns:
+//   - Logger: global logger with the request_id field attached
+func WithRequestID(id string) Logger {
+	return GlobalLogger.WithFields(Fields{RequestIDField: id})
+}
+
+// contextKey is the key of the logger stored in a context.
+type contextKey struct{}
+
+// NewContext returns a copy of the context carrying the logger, so code further
+// down the call chain logs with the same fields.
+//
+// Parameters:
+//   - ctx: parent context
+//   - l: logger to carry
+//
+// Returns:
+//   - context.Context: context carrying the logger
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by the context, or the global logger
+// when the context carries none.
+//
+// Parameters:
+//   - ctx: context to read the logger from
+//
+// Returns:
+//   - Logger: logger of the context
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(contextKey{}).(Logger); ok {
+		return l
+	}
+	return GlobalLogger
+}
+
 // getEntry returns the appropriate logrus entry or logger for message output.
 // It handles the internal routing between field-enhanced entries and the base logger.
 //
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"bytes"
+	"context"
 	"strings"
 	"testing"
 
@@ -227,6 +228,66 @@ func TestWithFields(t *testing.T) {
 	assert.Contains(t, output, "user logged in")
 }
 
+// useBufferLogger replaces the global logger with one writing JSON to the returned
+// buffer and restores it when the test ends.
+func useBufferLogger(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logrusLogger := logrus.New()
+	logrusLogger.SetOutput(&buf)
+	logrusLogger.SetFormatter(&logrus.JSONFormatter{})
+	logrusLogger.SetLevel(logrus.DebugLevel)
+
+	previous := GlobalLogger
+	GlobalLogger = logger{Logger: logrusLogger}
+	t.Cleanup(func() { GlobalLogger = previous })
+	return &buf
+}
+
+func TestWithRequestID(t *testing.T) {
+	t.Run("request id appears in every line", func(t *testing.T) {
+		buf := useBufferLogger(t)
+
+		requestLogger := WithRequestID("abc123")
+		requestLogger.Info("attempt started")
+		requestLogger.WithFields(Fields{"gift_id": 42}).Errorf("attempt %d failed", 1)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 2)
+		for _, line := range lines {
+			assert.Contains(t, line, `"request_id":"abc123"`)
+		}
+		assert.Contains(t, lines[1], `"gift_id":42`)
+	})
+
+	t.Run("global logger is left untouched", func(t *testing.T) {
+		buf := useBufferLogger(t)
+
+		WithRequestID("abc123")
+		GlobalLogger.Info("unrelated message")
+
+		assert.NotContains(t, buf.String(), RequestIDField)
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	t.Run("logger carried by the context", func(t *testing.T) {
+		buf := useBufferLogger(t)
+
+		ctx := NewContext(context.Background(), WithRequestID("req-1"))
+		FromContext(ctx).Debug("from context")
+
+		assert.Contains(t, buf.String(), `"request_id":"req-1"`)
+	})
+
+	t.Run("global logger without one", func(t *testing.T) {
+		useBufferLogger(t)
+
+		assert.Equal(t, GlobalLogger, FromContext(context.Background()))
+	})
+}
+
 func TestLogLevels(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,167 @@
+// Package metrics exposes the gift buying counters and gauges in the Prometheus text
+// format. Instrumented code updates the package level metrics directly, they are
+// served by Handler once a metrics port is configured.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"gift-buyer/pkg/errors"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics of the gift buying service.
+var (
+	// GiftsSeen counts the gifts the monitor evaluated that were not cached yet
+	GiftsSeen = NewCounter("gifts_seen", "Number of new gifts evaluated by the monitor")
+
+	// GiftsBought counts the successfully bought gifts
+	GiftsBought = NewCounter("gifts_bought", "Number of successfully bought gifts")
+
+	// BuyFailures counts the failed purchase attempts
+	BuyFailures = NewCounter("buy_failures", "Number of failed purchase attempts")
+
+	// BuyRetries counts the purchase attempts retried after a failure
+	BuyRetries = NewCounter("buy_retries", "Number of retried purchase attempts")
+
+	// BalanceStars is the last known stars balance of the account
+	BalanceStars = NewGauge("balance_stars", "Last known stars balance")
+)
+
+// metric is a single sample written in the Prometheus text format.
+type metric interface {
+	write(w io.Writer)
+}
+
+var (
+	registry   = make(map[string]metric)
+	registryMu sync.Mutex
+)
+
+// register adds the metric to the registry served by Handler.
+func register(name string, m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = m
+}
+
+// Counter is a monotonically increasing metric.
+type Counter struct {
+	name  string
+	help  string
+	value atomic.Int64
+}
+
+// NewCounter creates a counter and registers it for Handler.
+func NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	register(name, c)
+	return c
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add increments the counter by delta, negative deltas are ignored.
+func (c *Counter) Add(delta int64) {
+	if delta > 0 {
+		c.value.Add(delta)
+	}
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() int64 {
+	return c.value.Load()
+}
+
+func (c *Counter) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.Value())
+}
+
+// Gauge is a metric that can go up and down.
+type Gauge struct {
+	name string
+	help string
+	bits atomic.Uint64
+}
+
+// NewGauge creates a gauge and registers it for Handler.
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	register(name, g)
+	return g
+}
+
+// Set replaces the value of the gauge.
+func (g *Gauge) Set(value float64) {
+	g.bits.Store(math.Float64bits(value))
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+func (g *Gauge) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", g.name, g.help, g.name, g.name, g.Value())
+}
+
+// Handler serves all registered metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryMu.Lock()
+		names := make([]string, 0, len(registry))
+		for name := range registry {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		metrics := make([]metric, 0, len(names))
+		for _, name := range names {
+			metrics = append(metrics, registry[name])
+		}
+		registryMu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, m := range metrics {
+			m.write(w)
+		}
+	})
+}
+
+// Serve serves the metrics on addr under /metrics until the context is cancelled.
+//
+// Parameters:
+//   - ctx: context stopping the server
+//   - addr: listen address, e.g. ":9090"
+//
+// Returns:
+//   - error: listen error, nil after a graceful shutdown
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrap(err, "metrics server failed")
+	}
+	return nil
+}
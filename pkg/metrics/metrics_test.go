@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T) string {
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("все метрики в ответе", func(t *testing.T) {
+		body := scrape(t)
+
+		for _, name := range []string{"gifts_seen", "gifts_bought", "buy_failures", "buy_retries", "balance_stars"} {
+			assert.Contains(t, body, "# TYPE "+name)
+		}
+		assert.Contains(t, body, "# TYPE gifts_bought counter")
+		assert.Contains(t, body, "# TYPE balance_stars gauge")
+	})
+
+	t.Run("значения метрик", func(t *testing.T) {
+		before := GiftsBought.Value()
+		GiftsBought.Inc()
+		GiftsBought.Add(2)
+		GiftsBought.Add(-5)
+		BalanceStars.Set(1250)
+
+		assert.Equal(t, before+3, GiftsBought.Value())
+		body := scrape(t)
+		assert.Contains(t, body, "balance_stars 1250\n")
+	})
+}
+
+func TestCustomMetrics(t *testing.T) {
+	counter := NewCounter("test_counter_total", "Test counter")
+	gauge := NewGauge("test_gauge", "Test gauge")
+	counter.Inc()
+	gauge.Set(0.5)
+
+	body := scrape(t)
+
+	assert.Contains(t, body, "# HELP test_counter_total Test counter\n")
+	assert.Contains(t, body, "test_counter_total 1\n")
+	assert.Contains(t, body, "test_gauge 0.5\n")
+}
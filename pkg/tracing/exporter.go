@@ -0,0 +1,237 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gift-buyer/pkg/errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanData is a finished span handed over to a SpanExporter.
+type SpanData struct {
+	Name          string
+	Scope         string
+	Kind          trace.SpanKind
+	TraceID       trace.TraceID
+	SpanID        trace.SpanID
+	ParentSpanID  trace.SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []attribute.KeyValue
+	StatusCode    codes.Code
+	StatusMessage string
+}
+
+// Attribute returns the value of the span attribute with the given key.
+func (sd SpanData) Attribute(key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range sd.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// SpanExporter delivers finished spans to a tracing backend.
+type SpanExporter interface {
+	// ExportSpans delivers a batch of finished spans.
+	ExportSpans(ctx context.Context, spans []SpanData) error
+
+	// Shutdown releases the exporter resources.
+	Shutdown(ctx context.Context) error
+}
+
+// otlpExporter sends spans to an OpenTelemetry collector with the OTLP/HTTP JSON protocol.
+type otlpExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter creates an exporter sending spans to an OTLP/HTTP endpoint,
+// e.g. "http://localhost:4318". The "/v1/traces" path is added when missing.
+//
+// Parameters:
+//   - endpoint: base URL of the OpenTelemetry collector
+//   - serviceName: service.name resource attribute of the exported spans
+//
+// Returns:
+//   - SpanExporter: OTLP exporter
+func NewOTLPExporter(endpoint, serviceName string) SpanExporter {
+	url := strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	return &otlpExporter{
+		url:         url,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ExportSpans posts the spans to the collector.
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode spans")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create span export request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to export spans")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New(fmt.Sprintf("span export returned status %d", resp.StatusCode))
+	}
+	return nil
+}
+
+// Shutdown has nothing to release.
+func (e *otlpExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// request builds the OTLP JSON request grouping the spans by instrumentation scope.
+func (e *otlpExporter) request(spans []SpanData) map[string]interface{} {
+	scopes := make(map[string][]interface{})
+	var order []string
+	for _, span := range spans {
+		if _, ok := scopes[span.Scope]; !ok {
+			order = append(order, span.Scope)
+		}
+		scopes[span.Scope] = append(scopes[span.Scope], otlpSpan(span))
+	}
+
+	scopeSpans := make([]interface{}, 0, len(order))
+	for _, scope := range order {
+		scopeSpans = append(scopeSpans, map[string]interface{}{
+			"scope": map[string]interface{}{"name": scope},
+			"spans": scopes[scope],
+		})
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes([]attribute.KeyValue{attribute.String("service.name", e.serviceName)}),
+				},
+				"scopeSpans": scopeSpans,
+			},
+		},
+	}
+}
+
+// otlpSpan converts a span to its OTLP JSON representation.
+func otlpSpan(span SpanData) map[string]interface{} {
+	result := map[string]interface{}{
+		"traceId":           span.TraceID.String(),
+		"spanId":            span.SpanID.String(),
+		"name":              span.Name,
+		"kind":              int(span.Kind),
+		"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+		"attributes":        otlpAttributes(span.Attributes),
+		"status": map[string]interface{}{
+			"code":    otlpStatusCode(span.StatusCode),
+			"message": span.StatusMessage,
+		},
+	}
+	if span.ParentSpanID.IsValid() {
+		result["parentSpanId"] = span.ParentSpanID.String()
+	}
+	return result
+}
+
+// otlpStatusCode maps the OpenTelemetry status code to the OTLP one,
+// which orders Ok and Error the other way round.
+func otlpStatusCode(code codes.Code) int {
+	switch code {
+	case codes.Ok:
+		return 1
+	case codes.Error:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// otlpAttributes converts attributes to their OTLP JSON representation.
+func otlpAttributes(attrs []attribute.KeyValue) []interface{} {
+	result := make([]interface{}, 0, len(attrs))
+	for _, attr := range attrs {
+		var value map[string]interface{}
+		switch attr.Value.Type() {
+		case attribute.BOOL:
+			value = map[string]interface{}{"boolValue": attr.Value.AsBool()}
+		case attribute.INT64:
+			// 64-bit integers are encoded as strings in OTLP JSON
+			value = map[string]interface{}{"intValue": strconv.FormatInt(attr.Value.AsInt64(), 10)}
+		case attribute.FLOAT64:
+			value = map[string]interface{}{"doubleValue": attr.Value.AsFloat64()}
+		default:
+			value = map[string]interface{}{"stringValue": attr.Value.Emit()}
+		}
+		result = append(result, map[string]interface{}{
+			"key":   string(attr.Key),
+			"value": value,
+		})
+	}
+	return result
+}
+
+// InMemoryExporter keeps exported spans in memory, e.g. for tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter creates an exporter keeping the spans in memory.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpans stores the spans.
+func (e *InMemoryExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// Shutdown has nothing to release.
+func (e *InMemoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Spans returns the exported spans.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset drops the exported spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
@@ -0,0 +1,350 @@
+// Package tracing emits OpenTelemetry spans around monitor cycles, purchase attempts
+// and Telegram API calls. Instrumented code always goes through the global OpenTelemetry
+// tracer provider, which is a no-op until a TracerProvider is installed with Install.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+const (
+	// scopeName is the instrumentation scope of every span emitted by the application
+	scopeName = "gift-buyer"
+
+	// defaultBatchSize is the number of finished spans that triggers an export
+	defaultBatchSize = 256
+
+	// defaultFlushInterval is the interval finished spans are exported at
+	defaultFlushInterval = 5 * time.Second
+)
+
+// Span attribute keys shared by the instrumented components.
+const (
+	// AttrGiftID is the ID of the gift the span is about
+	AttrGiftID = attribute.Key("gift.id")
+
+	// AttrOutcome is the result of the traced operation, e.g. "success" or "error"
+	AttrOutcome = attribute.Key("outcome")
+
+	// AttrDurationMs is the duration of the traced operation in milliseconds
+	AttrDurationMs = attribute.Key("duration_ms")
+)
+
+// Outcomes recorded in the AttrOutcome attribute.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// StartSpan starts a span through the global tracer provider.
+//
+// Parameters:
+//   - ctx: parent context, the span becomes a child of the span it carries
+//   - name: span name, e.g. "purchase.attempt"
+//   - attrs: initial span attributes
+//
+// Returns:
+//   - context.Context: context carrying the new span
+//   - trace.Span: the started span, finish it with Finish
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.GetTracerProvider().Tracer(scopeName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Finish records the outcome and the duration of the operation and ends the span.
+// A non-nil error marks the span as failed.
+//
+// Parameters:
+//   - span: span returned by StartSpan
+//   - start: time the traced operation started
+//   - outcome: outcome of the operation, OutcomeError is used when empty and err is set
+//   - err: error of the operation, nil on success
+func Finish(span trace.Span, start time.Time, outcome string, err error) {
+	if outcome == "" {
+		outcome = OutcomeSuccess
+		if err != nil {
+			outcome = OutcomeError
+		}
+	}
+
+	span.SetAttributes(
+		AttrOutcome.String(outcome),
+		AttrDurationMs.Int64(time.Since(start).Milliseconds()),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the trace ID of the span carried by the context, or an empty
+// string when there is none. It lets log lines be matched with their traces.
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// TracerProvider records spans and hands the finished ones to a SpanExporter in batches.
+type TracerProvider struct {
+	embedded.TracerProvider
+
+	exporter  SpanExporter
+	batchSize int
+
+	mu      sync.Mutex
+	pending []SpanData
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewTracerProvider creates a tracer provider exporting finished spans in the background.
+//
+// Parameters:
+//   - exporter: destination of the finished spans
+//
+// Returns:
+//   - *TracerProvider: tracer provider, stop it with Shutdown
+func NewTracerProvider(exporter SpanExporter) *TracerProvider {
+	tp := &TracerProvider{
+		exporter:  exporter,
+		batchSize: defaultBatchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go tp.run(defaultFlushInterval)
+	return tp
+}
+
+// Install makes the tracer provider the global OpenTelemetry tracer provider.
+func (tp *TracerProvider) Install() {
+	otel.SetTracerProvider(tp)
+}
+
+// Tracer returns a tracer creating spans of the given instrumentation scope.
+func (tp *TracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return &tracer{provider: tp, scope: name}
+}
+
+// ForceFlush exports all finished spans immediately.
+func (tp *TracerProvider) ForceFlush(ctx context.Context) error {
+	tp.mu.Lock()
+	spans := tp.pending
+	tp.pending = nil
+	tp.mu.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+	return tp.exporter.ExportSpans(ctx, spans)
+}
+
+// Shutdown stops the background export, exports the remaining spans and shuts the exporter down.
+func (tp *TracerProvider) Shutdown(ctx context.Context) error {
+	tp.stopOnce.Do(func() { close(tp.stop) })
+	<-tp.done
+
+	if err := tp.ForceFlush(ctx); err != nil {
+		return err
+	}
+	return tp.exporter.Shutdown(ctx)
+}
+
+// run exports finished spans every interval until the provider is shut down.
+func (tp *TracerProvider) run(interval time.Duration) {
+	defer close(tp.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tp.stop:
+			return
+		case <-ticker.C:
+			_ = tp.ForceFlush(context.Background())
+		}
+	}
+}
+
+// finish queues a finished span for export.
+func (tp *TracerProvider) finish(data SpanData) {
+	tp.mu.Lock()
+	tp.pending = append(tp.pending, data)
+	full := len(tp.pending) >= tp.batchSize
+	tp.mu.Unlock()
+
+	if full {
+		go tp.ForceFlush(context.Background())
+	}
+}
+
+// tracer creates recording spans of one instrumentation scope.
+type tracer struct {
+	embedded.Tracer
+
+	provider *TracerProvider
+	scope    string
+}
+
+// Start creates a span, as a child of the span carried by ctx when there is one.
+func (t *tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(opts...)
+
+	parent := trace.SpanContextFromContext(ctx)
+	if config.NewRoot() {
+		parent = trace.SpanContext{}
+	}
+
+	traceID := parent.TraceID()
+	if !parent.HasTraceID() {
+		_, _ = rand.Read(traceID[:])
+	}
+	var spanID trace.SpanID
+	_, _ = rand.Read(spanID[:])
+
+	start := config.Timestamp()
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	s := &span{
+		provider: t.provider,
+		spanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: trace.FlagsSampled,
+		}),
+		data: SpanData{
+			Name:         name,
+			Scope:        t.scope,
+			Kind:         config.SpanKind(),
+			TraceID:      traceID,
+			SpanID:       spanID,
+			ParentSpanID: parent.SpanID(),
+			Start:        start,
+			Attributes:   append([]attribute.KeyValue(nil), config.Attributes()...),
+		},
+	}
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+// span is a span recorded in memory until it ends.
+type span struct {
+	embedded.Span
+
+	provider    *TracerProvider
+	spanContext trace.SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// End finishes the span and queues it for export. Calls after the first one are ignored.
+func (s *span) End(options ...trace.SpanEndOption) {
+	config := trace.NewSpanEndConfig(options...)
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = config.Timestamp()
+	if s.data.End.IsZero() {
+		s.data.End = time.Now()
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	s.provider.finish(data)
+}
+
+// AddEvent is not recorded, only attributes and the status are exported.
+func (s *span) AddEvent(name string, options ...trace.EventOption) {}
+
+// AddLink is not recorded.
+func (s *span) AddLink(link trace.Link) {}
+
+// IsRecording reports whether the span still accepts changes.
+func (s *span) IsRecording() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.ended
+}
+
+// RecordError records the error message as the exception.message attribute.
+func (s *span) RecordError(err error, options ...trace.EventOption) {
+	if err == nil {
+		return
+	}
+	s.SetAttributes(attribute.String("exception.message", err.Error()))
+}
+
+// SpanContext returns the identifiers of the span.
+func (s *span) SpanContext() trace.SpanContext {
+	return s.spanContext
+}
+
+// SetStatus sets the status of the span.
+func (s *span) SetStatus(code codes.Code, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.StatusCode = code
+	s.data.StatusMessage = description
+}
+
+// SetName renames the span.
+func (s *span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Name = name
+}
+
+// SetAttributes sets span attributes, replacing existing attributes with the same key.
+func (s *span) SetAttributes(kv ...attribute.KeyValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+
+	for _, attr := range kv {
+		replaced := false
+		for i := range s.data.Attributes {
+			if s.data.Attributes[i].Key == attr.Key {
+				s.data.Attributes[i] = attr
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.data.Attributes = append(s.data.Attributes, attr)
+		}
+	}
+}
+
+// TracerProvider returns the provider that created the span.
+func (s *span) TracerProvider() trace.TracerProvider {
+	return s.provider
+}
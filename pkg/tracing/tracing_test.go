@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// simulatePurchase mimics the instrumented purchase flow: an attempt span wrapping an API call span
+func simulatePurchase(ctx context.Context, giftID int64, apiErr error) {
+	start := time.Now()
+	ctx, attempt := StartSpan(ctx, "purchase.attempt", AttrGiftID.Int64(giftID))
+
+	apiStart := time.Now()
+	_, call := StartSpan(ctx, "api.payments.sendStarsForm")
+	time.Sleep(2 * time.Millisecond)
+	Finish(call, apiStart, "", apiErr)
+
+	Finish(attempt, start, "", apiErr)
+}
+
+func TestTracerProvider_SimulatedPurchase(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	provider := NewTracerProvider(exporter)
+	provider.Install()
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	t.Run("успешная покупка", func(t *testing.T) {
+		exporter.Reset()
+
+		simulatePurchase(context.Background(), 42, nil)
+		require.NoError(t, provider.ForceFlush(context.Background()))
+
+		spans := exporter.Spans()
+		require.Len(t, spans, 2)
+		call, attempt := spans[0], spans[1]
+
+		assert.Equal(t, "purchase.attempt", attempt.Name)
+		assert.Equal(t, "api.payments.sendStarsForm", call.Name)
+		assert.Equal(t, attempt.TraceID, call.TraceID)
+		assert.Equal(t, attempt.SpanID, call.ParentSpanID)
+		assert.False(t, attempt.ParentSpanID.IsValid())
+
+		giftID, ok := attempt.Attribute(AttrGiftID)
+		require.True(t, ok)
+		assert.Equal(t, int64(42), giftID.AsInt64())
+
+		outcome, ok := attempt.Attribute(AttrOutcome)
+		require.True(t, ok)
+		assert.Equal(t, OutcomeSuccess, outcome.AsString())
+
+		duration, ok := call.Attribute(AttrDurationMs)
+		require.True(t, ok)
+		assert.GreaterOrEqual(t, duration.AsInt64(), int64(2))
+		assert.False(t, call.End.Before(call.Start))
+		assert.Equal(t, codes.Unset, attempt.StatusCode)
+	})
+
+	t.Run("неудачная покупка", func(t *testing.T) {
+		exporter.Reset()
+
+		simulatePurchase(context.Background(), 7, errors.New("BALANCE_TOO_LOW"))
+		require.NoError(t, provider.ForceFlush(context.Background()))
+
+		spans := exporter.Spans()
+		require.Len(t, spans, 2)
+		for _, span := range spans {
+			outcome, _ := span.Attribute(AttrOutcome)
+			assert.Equal(t, OutcomeError, outcome.AsString())
+			assert.Equal(t, codes.Error, span.StatusCode)
+			assert.Equal(t, "BALANCE_TOO_LOW", span.StatusMessage)
+		}
+	})
+
+	t.Run("идентификатор трассировки в контексте", func(t *testing.T) {
+		assert.Empty(t, TraceID(context.Background()))
+
+		ctx, span := otel.Tracer("test").Start(context.Background(), "cycle")
+		defer span.End()
+		assert.Len(t, TraceID(ctx), 32)
+		assert.Equal(t, span.SpanContext().TraceID().String(), TraceID(ctx))
+	})
+}
+
+func TestTracerProvider_EndTwice(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	provider := NewTracerProvider(exporter)
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	_, span := provider.Tracer("test").Start(context.Background(), "once")
+	span.End()
+	span.End()
+	span.SetName("ignored")
+
+	require.NoError(t, provider.ForceFlush(context.Background()))
+	spans := exporter.Spans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "once", spans[0].Name)
+}
+
+func TestOTLPExporter_Payload(t *testing.T) {
+	var payload map[string]interface{}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	provider := NewTracerProvider(NewOTLPExporter(server.URL, "gift-buyer-test"))
+	_, span := provider.Tracer(scopeName).Start(context.Background(), "monitor.check")
+	Finish(span, time.Now(), "", nil)
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	assert.Equal(t, "/v1/traces", path)
+
+	resourceSpans := payload["resourceSpans"].([]interface{})
+	require.Len(t, resourceSpans, 1)
+	resource := resourceSpans[0].(map[string]interface{})
+	serviceName := resource["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "service.name", serviceName["key"])
+	assert.Equal(t, "gift-buyer-test", serviceName["value"].(map[string]interface{})["stringValue"])
+
+	scopeSpans := resource["scopeSpans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, scopeName, scopeSpans["scope"].(map[string]interface{})["name"])
+	exported := scopeSpans["spans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "monitor.check", exported["name"])
+	assert.Len(t, exported["traceId"], 32)
+	assert.Len(t, exported["spanId"], 16)
+	assert.NotContains(t, exported, "parentSpanId")
+	assert.IsType(t, "", exported["startTimeUnixNano"])
+}
+
+func TestOTLPExporter_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewOTLPExporter(server.URL+"/v1/traces", "gift-buyer").ExportSpans(context.Background(), []SpanData{{Name: "span"}})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	mathRand "math/rand"
+	"time"
+)
+
+// JitterDelay spreads the base delay randomly within ±jitter (fraction of base),
+// so that concurrent retries do not fire in lockstep.
+// A non-positive jitter returns the base delay unchanged, jitter above 1 is capped at 1.
+func JitterDelay(base time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 || base <= 0 {
+		return base
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+
+	// global math/rand functions are safe for concurrent use
+	factor := 1 + jitter*(2*mathRand.Float64()-1)
+	return time.Duration(float64(base) * factor)
+}
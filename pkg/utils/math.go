@@ -16,6 +16,18 @@ func cryptoSeed() int64 {
 	return seed
 }
 
+// CryptoRandomInt63 генерирует криптографически стойкое случайное число
+func CryptoRandomInt63() int64 {
+	var randomBytes [8]byte
+	if _, err := rand.Read(randomBytes[:]); err != nil {
+		// Fallback на math/rand если crypto/rand недоступен
+		return mathRand.Int63()
+	}
+	// Безопасное преобразование с маскированием старшего бита
+	val := binary.BigEndian.Uint64(randomBytes[:])
+	return int64(val >> 1) // Сдвиг вправо гарантирует положительное значение
+}
+
 var fastRand = mathRand.New(mathRand.NewSource(cryptoSeed()))
 
 // selectRandomElementFast - максимально быстрый выбор случайного элемента